@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// Backfill sources
+const (
+	sourceRadar = "radar" // Cloudflare Radar traffic (traffic_series dataset) -> traffic history
+	sourceIODA  = "ioda"  // IODA BGP signal per ASN -> ASN availability history
+)
+
+// runBackfill implements `cli backfill --source radar[,ioda] --from 2024-01-01 [--to 2024-02-01]`
+// It fills hours missing from the history store, so a fresh instance has baselines at once
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	sources := fs.String("source", sourceRadar, "Comma-separated sources: radar (traffic), ioda (ASN availability)")
+	fromStr := fs.String("from", "", "First day to backfill (YYYY-MM-DD, UTC)")
+	toStr := fs.String("to", "", "Day after the last day to backfill (YYYY-MM-DD, UTC; default: now)")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	from, err := time.Parse("2006-01-02", *fromStr)
+	if err != nil {
+		log.Fatalf("--from must be a date like 2024-01-01")
+	}
+	to := time.Now().UTC().Truncate(time.Hour)
+	if *toStr != "" {
+		if to, err = time.Parse("2006-01-02", *toStr); err != nil {
+			log.Fatalf("--to must be a date like 2024-02-01")
+		}
+	}
+
+	store, err := monitor.OpenHistory(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open history: %v", err)
+	}
+	if store == nil {
+		log.Fatal("History is disabled (set history_path or history_dsn)")
+	}
+	defer store.Close()
+
+	// Data older than the retention window would be pruned on the next record
+	if oldest := time.Now().Add(-store.Retention()).Truncate(time.Hour); from.Before(oldest) {
+		log.Printf("⚠️  History keeps %d days; starting at %s (raise history_retention_days to keep more)",
+			int(store.Retention().Hours()/24), oldest.Format("2006-01-02 15:04"))
+		from = oldest
+	}
+	if !from.Before(to) {
+		log.Fatal("Nothing to backfill: --from is not before --to")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	for _, source := range strings.Split(*sources, ",") {
+		switch strings.TrimSpace(source) {
+		case sourceRadar:
+			backfillRadar(ctx, cfg, store, from, to)
+		case sourceIODA:
+			backfillIODA(ctx, cfg, store, from, to)
+		default:
+			log.Fatalf("Unknown source %q (use radar or ioda)", source)
+		}
+	}
+}
+
+func backfillRadar(ctx context.Context, cfg *config.Config, store *history.Store, from, to time.Time) {
+	log.Printf("📡 Backfilling traffic from Cloudflare Radar (%s to %s)...", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	tm := monitor.NewTrafficMonitor(cfg.CloudflareToken, cfg.CloudflareEmail, cfg.CloudflareKey)
+	tm.SetTokens(cfg.CloudflareTokenList())
+	tm.SetCountry(cfg.Country, cfg.CountryISO3, cfg.Thresholds())
+	tm.SetSeries(cfg.TrafficSeriesSettings())
+	points, err := tm.FetchRadarHistory(ctx, from, to)
+	if err != nil {
+		log.Fatalf("Radar backfill failed: %v", err)
+	}
+	added, err := store.ImportTraffic(points)
+	if err != nil {
+		log.Fatalf("Failed to store traffic history: %v", err)
+	}
+	fmt.Printf("✅ Radar: %d hourly traffic points fetched, %d new hours stored\n", len(points), added)
+}
+
+func backfillIODA(ctx context.Context, cfg *config.Config, store *history.Store, from, to time.Time) {
+	log.Printf("📡 Backfilling ASN availability from IODA for %d ASNs...", len(cfg.IranASNs))
+	client := &http.Client{Timeout: time.Minute}
+	total := 0
+	for _, asn := range cfg.IranASNs {
+		if ctx.Err() != nil {
+			break
+		}
+		buckets, err := monitor.FetchIODAHistory(ctx, client, asn, from, to)
+		if err != nil {
+			log.Printf("⚠️  IODA %s: %v", asn, err)
+			continue
+		}
+		added, err := store.ImportASNAvailability(asn, buckets)
+		if err != nil {
+			log.Fatalf("Failed to store availability of %s: %v", asn, err)
+		}
+		total += added
+	}
+	fmt.Printf("✅ IODA: %d new ASN-hours stored\n", total)
+}
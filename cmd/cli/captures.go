@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/netblocks/netblocks/internal/capture"
+)
+
+// runCaptures implements `cli captures [--server addr] captures-2024-05-01.jsonl.gz...`:
+// it prints the captured DNS exchanges in dig-like form
+func runCaptures(args []string) {
+	fs := flag.NewFlagSet("captures", flag.ExitOnError)
+	server := fs.String("server", "", "Only show exchanges with this server address")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("Usage: netblocks-cli captures [--server 1.2.3.4] <captures-YYYY-MM-DD.jsonl.gz>...")
+	}
+
+	for _, path := range fs.Args() {
+		err := capture.Read(path, func(rec capture.Record) error {
+			if *server != "" && rec.Server != *server {
+				return nil
+			}
+			fmt.Printf(";; %s  %s (%s)  %s  %dms\n", rec.Time.UTC().Format("2006-01-02 15:04:05"), rec.Server, rec.Name, rec.Reason, rec.RTT)
+			if rec.Error != "" {
+				fmt.Printf(";; error: %s\n", rec.Error)
+			}
+			if len(rec.Response) == 0 {
+				fmt.Println(";; no response")
+			} else {
+				msg := new(dns.Msg)
+				if err := msg.Unpack(rec.Response); err != nil {
+					fmt.Printf(";; undecodable response (%d bytes): %v\n", len(rec.Response), err)
+				} else {
+					fmt.Println(strings.TrimSpace(msg.String()))
+				}
+			}
+			fmt.Println()
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", path, err)
+		}
+	}
+}
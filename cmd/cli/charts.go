@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// heatmapHours is the period of the uptime heatmap, as on the instance
+const heatmapHours = 7 * 24
+
+// chartsHistory is the part of /api/v1/history the charts are rendered from
+type chartsHistory struct {
+	Traffic []history.TrafficPoint `json:"traffic"`
+	Uptime  *struct {
+		Rows  []string     `json:"rows"`
+		Hours []time.Time  `json:"hours"`
+		Cells [][3]float64 `json:"cells"` // Hour index, row index, percent available
+	} `json:"uptime"`
+	Annotations []models.Annotation  `json:"annotations"`
+	AtlasProbes []models.AtlasProbes `json:"atlas_probes"`
+}
+
+// runCharts implements `cli charts --from http://myserver/api/v1 --out ./charts/`:
+// it renders the charts from the status and history of a running instance, so
+// publishing can run on another machine than the measurements
+func runCharts(args []string) {
+	fs := flag.NewFlagSet("charts", flag.ExitOnError)
+	from := fs.String("from", "", "API base URL of the instance, e.g. http://myserver/api/v1 (or /api/v1/<country>)")
+	out := fs.String("out", "charts", "Directory the PNG files are written to")
+	period := fs.String("period", "24h", "Traffic chart period: 24h, 7d or 30d (7d and 30d need history on the instance)")
+	token := fs.String("token", "", "Access token sent as a Bearer token, if the instance requires one")
+	_ = fs.Parse(args)
+	if *from == "" {
+		log.Fatal("Usage: netblocks-cli charts --from http://myserver/api/v1 [--out ./charts/] [--period 7d]")
+	}
+	hours, err := monitor.ParseChartPeriod(*period)
+	if err != nil {
+		log.Fatalf("Invalid --period: %v", err)
+	}
+	base := strings.TrimSuffix(*from, "/")
+	client := &http.Client{Timeout: time.Minute}
+
+	var result models.MonitoringResult
+	if err := fetchAPI(client, base+"/status", *token, &result); err != nil {
+		log.Fatalf("Failed to fetch the status: %v", err)
+	}
+	// The heatmap covers 7 days, longer traffic periods need their own
+	historyPeriod := "7d"
+	if hours > heatmapHours {
+		historyPeriod = *period
+	}
+	var past chartsHistory
+	if err := fetchAPI(client, base+"/history?period="+historyPeriod, *token, &past); err != nil {
+		log.Printf("⚠️  No history from %s (%v): rendering the 24h charts only", base, err)
+	}
+
+	renderRemoteCharts(&result, &past, hours, *period)
+	if err := os.MkdirAll(*out, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", *out, err)
+	}
+	saveChartsToFiles(&result, strings.TrimSuffix(*out, "/"))
+}
+
+// fetchAPI decodes the JSON of an API endpoint into v
+func fetchAPI(client *http.Client, url, token string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// renderRemoteCharts renders the charts of a fetched status into its chart
+// buffers, as the monitor does for its own results
+func renderRemoteCharts(result *models.MonitoringResult, past *chartsHistory, hours int, period string) {
+	if traffic := result.TrafficData; traffic != nil {
+		var chartBuffer *bytes.Buffer
+		var err error
+		if hours > 24 {
+			chartBuffer, err = monitor.GenerateTrafficHistoryChart(past.Traffic, strings.ToLower(period), past.Annotations, past.AtlasProbes)
+			if err != nil {
+				log.Printf("⚠️  %s traffic chart unavailable, falling back to 24h: %v", period, err)
+			}
+		}
+		if chartBuffer == nil {
+			since := result.Timestamp.Add(-24 * time.Hour)
+			chartBuffer, err = monitor.GenerateTrafficChart(&monitor.TrafficData{
+				CurrentLevel:  traffic.CurrentLevel,
+				Trend24h:      traffic.Trend24h,
+				Timestamps:    traffic.Timestamps,
+				ChangePercent: traffic.ChangePercent,
+				Status:        traffic.Status,
+				StatusEmoji:   traffic.StatusEmoji,
+				LastUpdate:    traffic.LastUpdate,
+			}, annotationsSince(past.Annotations, since), probesSince(past.AtlasProbes, since))
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to generate traffic chart: %v", err)
+		}
+		traffic.ChartBuffer = chartBuffer
+	}
+
+	if len(result.ASTrafficData) > 0 {
+		chartBuffer, err := monitor.GenerateASNTrafficChart(result.ASTrafficData)
+		if err != nil {
+			log.Printf("⚠️  Failed to generate ASN traffic chart: %v", err)
+		}
+		for _, item := range result.ASTrafficData {
+			item.ChartBuffer = chartBuffer
+		}
+	}
+
+	if uptime := past.Uptime; uptime != nil && len(uptime.Hours) > 0 {
+		rows := make([]monitor.HeatmapRow, len(uptime.Rows))
+		for i, label := range uptime.Rows {
+			rows[i].Label = label
+		}
+		// A 30d history covers more than the heatmap's 7 days
+		first := max(0, len(uptime.Hours)-heatmapHours)
+		for _, cell := range uptime.Cells {
+			hour, row := int(cell[0]), int(cell[1])
+			if hour < first || hour >= len(uptime.Hours) || row < 0 || row >= len(rows) {
+				continue
+			}
+			// The API reports percentages; a bucket of 1000 samples keeps a tenth of a percent
+			rows[row].Buckets = append(rows[row].Buckets, history.Bucket{Hour: uptime.Hours[hour], Up: int(cell[2]*10 + 0.5), Total: 1000})
+		}
+		chartBuffer, err := monitor.GenerateUptimeHeatmap("ASN / DNS Availability (Last 7 Days)", rows, uptime.Hours[len(uptime.Hours)-1], len(uptime.Hours)-first)
+		if err != nil {
+			log.Printf("⚠️  Failed to generate uptime heatmap: %v", err)
+		}
+		result.UptimeChart = chartBuffer
+	}
+
+	statusImage, err := monitor.GenerateStatusImage(result, result.NationalScore)
+	if err != nil {
+		log.Printf("⚠️  Failed to generate status image: %v", err)
+	}
+	result.StatusImage = statusImage
+}
+
+// annotationsSince returns the annotations from since on
+func annotationsSince(notes []models.Annotation, since time.Time) []models.Annotation {
+	var kept []models.Annotation
+	for _, note := range notes {
+		if !note.Time.Before(since) {
+			kept = append(kept, note)
+		}
+	}
+	return kept
+}
+
+// probesSince returns the RIPE Atlas probe counts from since on
+func probesSince(probes []models.AtlasProbes, since time.Time) []models.AtlasProbes {
+	var kept []models.AtlasProbes
+	for _, count := range probes {
+		if !count.CheckedAt.Before(since) {
+			kept = append(kept, count)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/monitor"
+	"github.com/netblocks/netblocks/internal/telegram"
+)
+
+// Outcomes of a doctor check
+const (
+	checkOK   = "✅"
+	checkWarn = "⚠️ "
+	checkFail = "❌"
+)
+
+// egressResolvers are well-known public resolvers used to tell blocked DNS egress
+// apart from unreachable servers of the monitored country
+var egressResolvers = []config.DNSServer{
+	{Address: "1.1.1.1", Name: "Cloudflare DNS", Type: "recursive"},
+	{Address: "8.8.8.8", Name: "Google DNS", Type: "recursive"},
+}
+
+// doctor collects check outcomes and prints them with their fixes
+type doctor struct {
+	failed bool
+}
+
+func (d *doctor) report(outcome, name, detail, fix string) {
+	fmt.Printf("%s %-12s %s\n", outcome, name, detail)
+	if fix != "" {
+		fmt.Printf("   → %s\n", fix)
+	}
+	if outcome == checkFail {
+		d.failed = true
+	}
+}
+
+// runDoctor implements `cli doctor`: it checks the credentials and connectivity the
+// bot needs and prints how to fix each problem; the exit status is 1 if a check failed
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file")
+	_ = fs.Parse(args)
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// Same credential sources as the bot: environment variables fill in or override the file
+	if cfg.TelegramToken == "" {
+		cfg.TelegramToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+	}
+	if cfg.TelegramChannel == "" {
+		cfg.TelegramChannel = os.Getenv("TELEGRAM_CHANNEL")
+	}
+	if token := os.Getenv("CLOUDFLARE_TOKEN"); token != "" {
+		cfg.CloudflareToken = token
+	}
+	if tokens := os.Getenv("CLOUDFLARE_TOKENS"); tokens != "" {
+		cfg.CloudflareTokens = strings.Split(tokens, ",")
+	}
+	if email := os.Getenv("CLOUDFLARE_EMAIL"); email != "" {
+		cfg.CloudflareEmail = email
+	}
+	if key := os.Getenv("CLOUDFLARE_KEY"); key != "" {
+		cfg.CloudflareKey = key
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// The checks reuse the monitor and bot code, whose progress logs would bury the report
+	log.SetOutput(io.Discard)
+
+	fmt.Printf("🩺 NetBlocks doctor (%s)\n\n", *configPath)
+	d := &doctor{}
+	d.checkCloudflare(ctx, cfg)
+	d.checkTelegram(cfg)
+	if cfg.BGPBackend == monitor.BGPBackendBGPStream {
+		d.checkBGPStream(ctx, cfg)
+	} else {
+		d.checkRISLive(cfg)
+	}
+	if cfg.RPKI != nil {
+		d.checkRPKI(ctx, cfg)
+	}
+	d.checkDNS(ctx, cfg)
+
+	fmt.Println()
+	if d.failed {
+		fmt.Println("❌ Some checks failed - fix them before starting the bot")
+		os.Exit(1)
+	}
+	fmt.Println("✅ All required checks passed")
+}
+
+// checkCloudflare verifies every token (or the key) and that it may read Radar data
+func (d *doctor) checkCloudflare(ctx context.Context, cfg *config.Config) {
+	tokens := cfg.CloudflareTokenList()
+	if len(tokens) == 0 {
+		if cfg.CloudflareEmail == "" || cfg.CloudflareKey == "" {
+			d.report(checkWarn, "Cloudflare", "no credentials - traffic charts and traffic alerts are disabled",
+				"create an API token with the \"Radar: Read\" permission and set cloudflare_token (or CLOUDFLARE_TOKEN)")
+			return
+		}
+		d.checkCloudflareCredential(ctx, cfg, "Cloudflare", "")
+		return
+	}
+	for i, token := range tokens {
+		name := "Cloudflare"
+		if len(tokens) > 1 {
+			name = fmt.Sprintf("Cloudflare #%d", i+1)
+		}
+		d.checkCloudflareCredential(ctx, cfg, name, token)
+	}
+}
+
+// checkCloudflareCredential verifies a token (the legacy key if token is
+// empty) and that it may read Radar data
+func (d *doctor) checkCloudflareCredential(ctx context.Context, cfg *config.Config, name, token string) {
+	authorize := func(req *http.Request) {
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else {
+			req.Header.Set("X-Auth-Email", cfg.CloudflareEmail)
+			req.Header.Set("X-Auth-Key", cfg.CloudflareKey)
+		}
+	}
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	if token != "" {
+		status, body, err := cloudflareGet(ctx, client, "https://api.cloudflare.com/client/v4/user/tokens/verify", authorize)
+		if err != nil {
+			d.report(checkFail, name, fmt.Sprintf("api.cloudflare.com unreachable: %v", err), "check outbound HTTPS from this host")
+			return
+		}
+		var verify struct {
+			Result struct {
+				Status string `json:"status"`
+			} `json:"result"`
+		}
+		_ = json.Unmarshal(body, &verify)
+		if status != http.StatusOK || verify.Result.Status != "active" {
+			d.report(checkFail, name, fmt.Sprintf("token rejected (HTTP %d, status %q)", status, verify.Result.Status),
+				"the token is invalid, expired or revoked: create a new one in the Cloudflare dashboard (My Profile > API Tokens)")
+			return
+		}
+	}
+
+	// The series the monitor reads, so a token lacking access to the dataset fails here
+	series := cfg.TrafficSeriesSettings()
+	status, _, err := cloudflareGet(ctx, client,
+		monitor.RadarSeriesURL(monitor.RadarURL, series.Dataset, cfg.Country, "dateRange=1d&aggInterval="+series.Interval), authorize)
+	switch {
+	case err != nil:
+		d.report(checkFail, name, fmt.Sprintf("Radar API unreachable: %v", err), "check outbound HTTPS from this host")
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		d.report(checkFail, name, fmt.Sprintf("credentials lack access to Radar (HTTP %d)", status),
+			"edit the token and add the \"Radar: Read\" permission (or use an API token instead of the global key)")
+	case status != http.StatusOK:
+		d.report(checkWarn, name, fmt.Sprintf("Radar API returned HTTP %d", status), "retry later; see https://www.cloudflarestatus.com")
+	default:
+		d.report(checkOK, name, fmt.Sprintf("credentials valid, Radar data for %s readable", cfg.Country), "")
+	}
+}
+
+func cloudflareGet(ctx context.Context, client *http.Client, url string, authorize func(*http.Request)) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	authorize(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, body, err
+}
+
+// checkTelegram verifies the bot token and that the bot may post to every configured channel
+func (d *doctor) checkTelegram(cfg *config.Config) {
+	const name = "Telegram"
+	if cfg.TelegramToken == "" {
+		d.report(checkWarn, name, "no bot token - only the CLI and API can run",
+			"create a bot with @BotFather and set telegram_token (or TELEGRAM_BOT_TOKEN)")
+		return
+	}
+	username, channels, err := telegram.Diagnose(cfg.TelegramToken, cfg)
+	if err != nil {
+		d.report(checkFail, name, fmt.Sprintf("token rejected: %v", err),
+			"copy the token again from @BotFather (/token); check outbound HTTPS to api.telegram.org")
+		return
+	}
+	d.report(checkOK, name, "authorized as @"+username, "")
+	if len(channels) == 0 {
+		d.report(checkWarn, name, "no channel configured - only subscribers get updates",
+			"set telegram_channel (or TELEGRAM_CHANNEL) or telegram_channels")
+	}
+	for _, ch := range channels {
+		switch {
+		case !ch.CanPost:
+			d.report(checkFail, name, ch.ID+" - bot cannot post", ch.Problem)
+		case ch.Problem != "":
+			d.report(checkWarn, name, ch.ID+" - bot can post text only", ch.Problem)
+		default:
+			d.report(checkOK, name, ch.Describe(), "")
+		}
+	}
+}
+
+// checkRISLive connects to the RIS Live WebSocket
+func (d *doctor) checkRISLive(cfg *config.Config) {
+	const name = "RIS Live"
+	client, err := monitor.NewRISLiveClient(cfg.RISLiveURL)
+	if err != nil {
+		d.report(checkFail, name, err.Error(),
+			"check outbound WebSocket (wss, port 443) to ris-live.ripe.net, or fix ris_live_url")
+		return
+	}
+	client.Stop()
+	d.report(checkOK, name, "connected to "+cfg.RISLiveURL, "")
+}
+
+// checkBGPStream asks the BGPStream broker for recent update dumps
+func (d *doctor) checkBGPStream(ctx context.Context, cfg *config.Config) {
+	const name = "BGPStream"
+	dumps, err := monitor.NewBGPStreamClient(cfg.BGPStream).CheckBroker(ctx)
+	switch {
+	case err != nil:
+		d.report(checkFail, name, err.Error(),
+			"check outbound HTTPS to broker.bgpstream.caida.org, or fix bgpstream.broker_url")
+	case dumps == 0:
+		d.report(checkWarn, name, "broker lists no update dumps of the last hour",
+			"check bgpstream.collectors")
+	default:
+		d.report(checkOK, name, fmt.Sprintf("broker lists %d update dumps of the last hour", dumps), "")
+	}
+}
+
+// checkRPKI downloads the ROA export of the RPKI validation
+func (d *doctor) checkRPKI(ctx context.Context, cfg *config.Config) {
+	const name = "RPKI"
+	roas, err := monitor.NewRPKIValidator(cfg).CheckRPKI(ctx)
+	if err != nil {
+		d.report(checkWarn, name, err.Error(),
+			"check outbound HTTPS to rpki.cloudflare.com, or fix rpki.url (JSON export with a \"roas\" array)")
+		return
+	}
+	d.report(checkOK, name, fmt.Sprintf("export lists %d ROAs", roas), "")
+}
+
+// checkDNS queries public resolvers and the configured servers over UDP port 53
+func (d *doctor) checkDNS(ctx context.Context, cfg *config.Config) {
+	const name = "DNS"
+	egress := monitor.NewDNSMonitor(egressResolvers, 5*time.Second).CheckAll(ctx)
+	egressAlive := 0
+	for _, status := range egress {
+		if status.Alive {
+			egressAlive++
+		}
+	}
+	if egressAlive == 0 {
+		d.report(checkFail, name, "public resolvers (1.1.1.1, 8.8.8.8) do not answer",
+			"allow outbound UDP/TCP port 53 from this host; without it every DNS server looks down")
+		return
+	}
+
+	// Private addresses only answer probes inside the country; the monitor skips them elsewhere too
+	servers, skipped := monitor.ReachableDNSServers(cfg, cfg.DNSServers)
+	statuses := monitor.NewDNSMonitor(servers, 8*time.Second).CheckAll(ctx)
+	alive := 0
+	for _, status := range statuses {
+		if status.Alive {
+			alive++
+		}
+	}
+	detail := fmt.Sprintf("egress works; %d/%d configured servers answer", alive, len(statuses))
+	if len(skipped) > 0 {
+		detail += fmt.Sprintf(" (%d private servers skipped outside %s)", len(skipped), cfg.Country)
+	}
+	if len(statuses) > 0 && alive*2 < len(statuses) {
+		d.report(checkWarn, name, detail,
+			"fewer than half answer: servers inside the country often drop foreign queries, so run the monitor from inside it or near it for meaningful DNS data")
+		return
+	}
+	d.report(checkOK, name, detail, "")
+}
@@ -6,30 +6,67 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/i18n"
 	"github.com/netblocks/netblocks/internal/models"
 	"github.com/netblocks/netblocks/internal/monitor"
+	"github.com/netblocks/netblocks/internal/version"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		runBackfill(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		runDoctor(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sign" {
+		runSign(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "charts" {
+		runCharts(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "captures" {
+		runCaptures(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+
 	configPath := flag.String("config", "config.json", "Path to configuration file")
 	outputDir := flag.String("output", ".", "Directory to save chart images (default: current directory)")
 	saveCharts := flag.Bool("charts", false, "Save traffic charts as PNG files")
+	period := flag.String("period", "", "Traffic chart period when saving charts: 24h, 7d or 30d (default: chart_period from config)")
+	showVersion := flag.Bool("version", false, "Print the version and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Println("netblocks-cli " + version.String())
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-	
+	i18n.SetPersianDigits(cfg.PersianDigits)
+
 	// Check if Cloudflare credentials are available in config file
 	// CLI reads from config.json (not environment variables, unlike bot)
-	if cfg.CloudflareToken != "" {
-		log.Printf("✓ Cloudflare token loaded from config file (%d chars)", len(cfg.CloudflareToken))
+	if tokens := cfg.CloudflareTokenList(); len(tokens) > 0 {
+		log.Printf("✓ %d Cloudflare token(s) loaded from config file", len(tokens))
 	} else if cfg.CloudflareEmail != "" && cfg.CloudflareKey != "" {
 		log.Printf("✓ Cloudflare API key loaded from config file (email: %s)", cfg.CloudflareEmail)
 	} else {
@@ -37,6 +74,9 @@ func main() {
 		log.Println("   Add 'cloudflare_token' to your config.json to enable traffic charts")
 	}
 
+	// Process-wide monitor settings come from the primary config only
+	monitor.Configure(cfg)
+
 	// Create monitor
 	mon, err := monitor.NewMonitor(cfg)
 	if err != nil {
@@ -51,38 +91,50 @@ func main() {
 	// Default behavior: run once and exit
 	// Perform initial check synchronously to ensure DNS results are available
 	mon.PerformInitialCheck(ctx)
-	
+
 	// Start monitor briefly to allow BGP updates to arrive
 	go mon.Start(ctx)
 	time.Sleep(5 * time.Second) // Give BGP a moment to receive some updates
-	
+
 	// Get results
 	result := mon.GetResults()
-	
+
 	// Print status and exit (default behavior: run once)
-	printStatus(result)
-	
+	printStatus(result, cfg.Language)
+
 	// Save charts if requested
 	if *saveCharts {
+		// Longer periods are rendered from the persisted history
+		if *period != "" && result.TrafficData != nil {
+			chartBuffer, err := mon.TrafficChart(ctx, *period)
+			if err != nil {
+				log.Printf("⚠️  Failed to generate %s traffic chart: %v", *period, err)
+			} else {
+				result.TrafficData.ChartBuffer = chartBuffer
+			}
+		}
 		saveChartsToFiles(result, *outputDir)
 	}
 }
 
-func printStatus(result *models.MonitoringResult) {
+// printStatus prints a result with the headings, numbers and times of lang
+func printStatus(result *models.MonitoringResult, lang string) {
+	locale := i18n.For(lang)
+	lang = locale.Lang()
 	fmt.Println("\n" + strings.Repeat("═", 80))
-	fmt.Printf("📊 NetBlocks Monitoring Status - %s\n", result.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Printf("📊 %s - %s\n", i18n.T(lang, "NetBlocks Monitoring Status"), locale.Time(result.Timestamp, "2006-01-02 15:04:05"))
 	fmt.Println(strings.Repeat("═", 80))
 
 	// ASN Status
-	fmt.Println("\n🌐 ASN Connectivity")
+	fmt.Println("\n🌐 " + i18n.T(lang, "ASN Connectivity"))
 	fmt.Println(strings.Repeat("─", 80))
 	connectedCount := 0
 	totalCount := len(result.ASNStatuses)
 
 	// Sort ASNs for better readability (connected first)
 	type asnEntry struct {
-		asn      string
-		status   *models.ASNStatus
+		asn       string
+		status    *models.ASNStatus
 		connected bool
 	}
 	var entries []asnEntry
@@ -92,7 +144,7 @@ func printStatus(result *models.MonitoringResult) {
 			connectedCount++
 		}
 	}
-	
+
 	// Sort: connected first, then by ASN
 	for i := 0; i < len(entries)-1; i++ {
 		for j := i + 1; j < len(entries); j++ {
@@ -111,31 +163,105 @@ func printStatus(result *models.MonitoringResult) {
 		if entry.status.Connected {
 			statusIcon = "🟢"
 		}
-		lastSeen := "Never"
+		lastSeen := i18n.T(lang, "Never")
 		if !entry.status.LastSeen.IsZero() {
-			lastSeen = entry.status.LastSeen.Format("2006-01-02 15:04:05")
+			lastSeen = locale.Time(entry.status.LastSeen, "2006-01-02 15:04:05")
 		}
 		// Display ASN with readable name if available
 		asnDisplay := entry.asn
 		if entry.status.Name != "" {
 			asnDisplay = fmt.Sprintf("%s - %s", entry.asn, entry.status.Name)
 		}
-		fmt.Printf("%s %-50s Last seen: %s\n", statusIcon, asnDisplay, lastSeen)
+		source := ""
+		if visibility := entry.status.Visibility; visibility != nil {
+			source = fmt.Sprintf(" (RIPEstat: %s/%s peers)", locale.Int(visibility.PeersSeeing), locale.Int(visibility.TotalPeers))
+			if entry.status.Source == monitor.SourceRIPEstat {
+				source = " ⚠️" + source
+			}
+		}
+		if rpki := entry.status.RPKI; rpki != nil && rpki.Invalid > 0 {
+			source += fmt.Sprintf(" ⚠️ RPKI: %s %s", locale.Int(rpki.Invalid), i18n.T(lang, "invalid"))
+		}
+		fmt.Printf("%s %-50s %s: %s%s\n", statusIcon, asnDisplay, i18n.T(lang, "Last seen"), lastSeen, source)
 	}
 
-	fmt.Printf("\n📈 Summary: %d/%d Connected\n", connectedCount, totalCount)
+	fmt.Printf("\n📈 %s: %s/%s %s\n", i18n.T(lang, "Summary"), locale.Int(connectedCount), locale.Int(totalCount), i18n.T(lang, "Connected"))
+
+	// External reference ASNs (global CDNs), not counted above
+	if len(result.ReferenceASNs) > 0 {
+		fmt.Printf("\n🛰 %s (%s)\n", i18n.T(lang, "External Reference"), i18n.T(lang, "not counted in the summary"))
+		fmt.Println(strings.Repeat("─", 80))
+		references := make([]string, 0, len(result.ReferenceASNs))
+		for asn := range result.ReferenceASNs {
+			references = append(references, asn)
+		}
+		sort.Strings(references)
+		for _, asn := range references {
+			status := result.ReferenceASNs[asn]
+			statusIcon := "🔴"
+			if status.Connected {
+				statusIcon = "🟢"
+			}
+			fmt.Printf("%s %s - %s\n", statusIcon, asn, status.Name)
+		}
+	}
+
+	// Followed prefixes (bgp_prefixes); ⚪ not seen announced since start
+	if len(result.Prefixes) > 0 {
+		fmt.Println("\n🧭 " + i18n.T(lang, "Prefixes"))
+		fmt.Println(strings.Repeat("─", 80))
+		prefixes := make([]string, 0, len(result.Prefixes))
+		for prefix := range result.Prefixes {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			status := result.Prefixes[prefix]
+			switch {
+			case status.Announced:
+				fmt.Printf("🟢 %-20s %s, %s\n", prefix, status.Origin, fmt.Sprintf(i18n.T(lang, "%s/%s RIS peers"), locale.Int(status.Peers), locale.Int(status.MaxPeers)))
+			case status.LastAnnounced.IsZero():
+				fmt.Printf("⚪ %-20s %s\n", prefix, i18n.T(lang, "no announcement seen yet"))
+			default:
+				fmt.Printf("🔴 %-20s %s\n", prefix, fmt.Sprintf(i18n.T(lang, "withdrawn %s"), locale.Time(status.LastWithdrawn, "2006-01-02 15:04:05")))
+			}
+		}
+	}
+
+	if telegram := result.Telegram; telegram != nil {
+		fmt.Println("\n✈️  " + i18n.T(lang, "Telegram"))
+		fmt.Println(strings.Repeat("─", 80))
+		if api := telegram.API; api != nil {
+			if api.Reachable {
+				fmt.Printf("🟢 %-40s %s\n", i18n.T(lang, "Bot API from the monitoring host"), fmt.Sprintf(i18n.T(lang, "answered in %s"), locale.Digits(api.Latency.Round(time.Millisecond).String())))
+			} else {
+				fmt.Printf("🔴 %-40s %s\n", i18n.T(lang, "Bot API from the monitoring host"), api.LastError)
+			}
+		}
+		for _, endpoint := range telegram.Endpoints {
+			name := endpoint.Address
+			if endpoint.Vantage != nil && endpoint.Vantage.ProbeID != "" {
+				name += " @" + endpoint.Vantage.ProbeID
+			}
+			if endpoint.Reachable {
+				fmt.Printf("🟢 %-40s %s\n", name, locale.Digits(endpoint.Latency.Round(time.Millisecond).String()))
+			} else {
+				fmt.Printf("🔴 %-40s %s\n", name, endpoint.Error)
+			}
+		}
+	}
 
 	// DNS Status
-	fmt.Println("\n🔍 DNS Servers")
+	fmt.Println("\n🔍 " + i18n.T(lang, "DNS Servers Status"))
 	fmt.Println(strings.Repeat("─", 80))
 	aliveCount := 0
 	dnsTotal := len(result.DNSStatuses)
 
 	// Sort DNS servers (alive first)
 	type dnsEntry struct {
-		addr    string
-		status  *models.DNSStatus
-		alive   bool
+		addr   string
+		status *models.DNSStatus
+		alive  bool
 	}
 	var dnsEntries []dnsEntry
 	for addr, status := range result.DNSStatuses {
@@ -144,7 +270,7 @@ func printStatus(result *models.MonitoringResult) {
 			aliveCount++
 		}
 	}
-	
+
 	// Sort: alive first, then by name
 	for i := 0; i < len(dnsEntries)-1; i++ {
 		for j := i + 1; j < len(dnsEntries); j++ {
@@ -164,21 +290,21 @@ func printStatus(result *models.MonitoringResult) {
 			statusIcon = "🟢"
 		}
 		responseTime := entry.status.ResponseTime.Milliseconds()
-		fmt.Printf("%s %-45s %-18s %dms", statusIcon, entry.status.Name, entry.addr, responseTime)
+		fmt.Printf("%s %-45s %-18s %sms", statusIcon, entry.status.Name, entry.addr, locale.Int(int(responseTime)))
 		if entry.status.Error != "" {
 			fmt.Printf(" ⚠️  %s", entry.status.Error)
 		}
 		fmt.Println()
 	}
 
-	fmt.Printf("\n📈 Summary: %d/%d Alive\n", aliveCount, dnsTotal)
+	fmt.Printf("\n📈 %s: %s/%s %s\n", i18n.T(lang, "Summary"), locale.Int(aliveCount), locale.Int(dnsTotal), i18n.T(lang, "Alive"))
 	fmt.Println()
 }
 
 // saveChartsToFiles saves traffic charts as PNG files
 func saveChartsToFiles(result *models.MonitoringResult, outputDir string) {
 	timestamp := result.Timestamp.Format("20060102_150405")
-	
+
 	// Save Iran traffic chart
 	if result.TrafficData != nil && result.TrafficData.ChartBuffer != nil && result.TrafficData.ChartBuffer.Len() > 0 {
 		filename := fmt.Sprintf("%s/iran_traffic_%s.png", outputDir, timestamp)
@@ -190,7 +316,7 @@ func saveChartsToFiles(result *models.MonitoringResult, outputDir string) {
 	} else {
 		fmt.Printf("\n⚠️  Iran traffic chart not available\n")
 	}
-	
+
 	// Save ASN traffic chart
 	if result.ASTrafficData != nil && len(result.ASTrafficData) > 0 {
 		firstItem := result.ASTrafficData[0]
@@ -207,5 +333,28 @@ func saveChartsToFiles(result *models.MonitoringResult, outputDir string) {
 	} else {
 		fmt.Printf("⚠️  ASN traffic chart not available\n")
 	}
-}
 
+	// Save composite status image
+	if result.StatusImage != nil && result.StatusImage.Len() > 0 {
+		filename := fmt.Sprintf("%s/status_%s.png", outputDir, timestamp)
+		if err := os.WriteFile(filename, result.StatusImage.Bytes(), 0644); err != nil {
+			log.Printf("⚠️  Failed to save status image: %v", err)
+		} else {
+			fmt.Printf("✅ Status image saved: %s\n", filename)
+		}
+	} else {
+		fmt.Printf("⚠️  Status image not available\n")
+	}
+
+	// Save 7-day uptime heatmap
+	if result.UptimeChart != nil && result.UptimeChart.Len() > 0 {
+		filename := fmt.Sprintf("%s/uptime_heatmap_%s.png", outputDir, timestamp)
+		if err := os.WriteFile(filename, result.UptimeChart.Bytes(), 0644); err != nil {
+			log.Printf("⚠️  Failed to save uptime heatmap: %v", err)
+		} else {
+			fmt.Printf("✅ Uptime heatmap saved: %s\n", filename)
+		}
+	} else {
+		fmt.Printf("⚠️  Uptime heatmap not available\n")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// runReplay implements `cli replay [--step 5m] [--format text|jsonl] rib.20191116.0000.bz2 updates.20191116.*.bz2`:
+// it replays MRT dumps through the BGP state machine and prints how the
+// connectivity of the configured ASNs and prefixes evolved
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", "config.json", "Path to configuration file (ASNs, reference ASNs, bgp_prefixes, withdrawal_storm)")
+	step := fs.Duration("step", 5*time.Minute, "Dump time between snapshots")
+	stale := fs.Duration("stale", 0, "Silence before an ASN is considered offline (default: asn_stale_after, as live)")
+	format := fs.String("format", "text", "Output: text (changes per step) or jsonl (the full state per step)")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("Usage: netblocks-cli replay [--step 5m] [--format text|jsonl] <rib dump> <update dumps>...")
+	}
+	if *format != "text" && *format != "jsonl" {
+		log.Fatalf("Unknown format %q (use text or jsonl)", *format)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	replayer := monitor.NewReplayer(cfg, *step)
+	if *stale > 0 {
+		replayer.SetStaleAfter(*stale)
+	}
+
+	var emit func(*monitor.ReplayStep)
+	if *format == "jsonl" {
+		encoder := json.NewEncoder(os.Stdout)
+		emit = func(s *monitor.ReplayStep) {
+			if err := encoder.Encode(s); err != nil {
+				log.Fatalf("Failed to write: %v", err)
+			}
+		}
+	} else {
+		emit = (&replayPrinter{}).print
+	}
+	if err := replayer.Replay(fs.Args(), emit); err != nil {
+		log.Fatalf("Replay incomplete: %v", err)
+	}
+}
+
+// replayPrinter prints each step as a summary line and the changes since the previous one
+type replayPrinter struct {
+	previous *monitor.ReplayStep
+}
+
+func (p *replayPrinter) print(s *monitor.ReplayStep) {
+	connected := 0
+	for _, status := range s.ASNs {
+		if status.Connected {
+			connected++
+		}
+	}
+	line := fmt.Sprintf("%s  %d/%d ASNs connected", s.Time.Format("2006-01-02 15:04"), connected, len(s.ASNs))
+	if len(s.Prefixes) > 0 {
+		announced := 0
+		for _, status := range s.Prefixes {
+			if status.Announced {
+				announced++
+			}
+		}
+		line += fmt.Sprintf(", %d/%d prefixes announced", announced, len(s.Prefixes))
+	}
+	fmt.Printf("%s  (%d updates)\n", line, s.Updates)
+
+	var changes []string
+	for _, asn := range sortedKeys(s.ASNs) {
+		status := s.ASNs[asn]
+		var before *models.ASNStatus
+		if p.previous != nil {
+			before = p.previous.ASNs[asn]
+		}
+		switch {
+		case before != nil && before.Connected && !status.Connected:
+			changes = append(changes, fmt.Sprintf("🔴 %s %s disconnected (last seen %s)", asn, status.Name, status.LastSeen.UTC().Format("15:04")))
+		case (before == nil || !before.Connected) && status.Connected && p.previous != nil:
+			changes = append(changes, fmt.Sprintf("🟢 %s %s connected", asn, status.Name))
+		}
+		if status.WithdrawalStorm && (before == nil || !before.WithdrawalStorm) {
+			changes = append(changes, fmt.Sprintf("🌪 %s %s withdrawal storm (%d withdrawals)", asn, status.Name, status.Withdrawals))
+		}
+	}
+	for _, prefix := range sortedKeys(s.Prefixes) {
+		status := s.Prefixes[prefix]
+		var before *models.PrefixStatus
+		if p.previous != nil {
+			before = p.previous.Prefixes[prefix]
+		}
+		switch {
+		case before != nil && before.Announced && !status.Announced:
+			changes = append(changes, fmt.Sprintf("🔴 %s withdrawn", prefix))
+		case (before == nil || !before.Announced) && status.Announced && p.previous != nil:
+			changes = append(changes, fmt.Sprintf("🟢 %s announced by %s, %d peers", prefix, status.Origin, status.Peers))
+		}
+	}
+	for _, change := range changes {
+		fmt.Println("   " + change)
+	}
+	p.previous = s
+}
+
+// sortedKeys returns the keys of a status map in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/netblocks/netblocks/internal/evidence"
+	"github.com/netblocks/netblocks/internal/targetlist"
+)
+
+// runSign implements `cli sign --key list.key asns.json [dns.json ...]`: it writes
+// the detached signature each file needs to be used as asn_list_url or dns_list_url
+// with target_list_key set to the printed public key
+func runSign(args []string) {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := fs.String("key", "target_list.key", "Ed25519 key (hex seed, created if missing)")
+	_ = fs.Parse(args)
+	if fs.NArg() == 0 {
+		log.Fatal("Usage: netblocks-cli sign [--key target_list.key] <list.json>...")
+	}
+
+	var publicKey string
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Failed to read list: %v", err)
+		}
+		signature, key, err := evidence.SignDetached(*keyPath, data)
+		if err != nil {
+			log.Fatalf("Failed to sign %s: %v", path, err)
+		}
+		if err := os.WriteFile(path+targetlist.SignatureSuffix, []byte(signature+"\n"), 0644); err != nil {
+			log.Fatalf("Failed to write signature: %v", err)
+		}
+		publicKey = key
+		fmt.Printf("✅ Signed %s -> %s%s\n", path, path, targetlist.SignatureSuffix)
+	}
+	fmt.Printf("🔑 Public key (target_list_key): %s\n", publicKey)
+}
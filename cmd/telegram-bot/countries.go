@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/crash"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+	"github.com/netblocks/netblocks/internal/telegram"
+)
+
+// country is a further country monitored by this process (see config.Countries)
+type country struct {
+	cfg *config.Config
+	mon *monitor.Monitor
+	bot *telegram.Bot // Set by start
+}
+
+// newCountries creates the monitors of the further countries and runs their
+// initial checks in parallel
+func newCountries(ctx context.Context, cfg *config.Config) []*country {
+	seen := map[string]bool{cfg.Country: true}
+	var countries []*country
+	for _, entry := range cfg.Countries {
+		countryCfg, err := cfg.ForCountry(entry)
+		if err != nil {
+			log.Fatalf("Invalid country %q: %v", entry.Profile, err)
+		}
+		if seen[countryCfg.Country] {
+			log.Fatalf("Country %s is configured more than once", countryCfg.Country)
+		}
+		seen[countryCfg.Country] = true
+
+		mon, err := monitor.NewMonitor(countryCfg)
+		if err != nil {
+			log.Fatalf("Failed to create monitor for %s: %v", countryCfg.Country, err)
+		}
+		log.Printf("🌍 Monitoring %s too (profile %s): %d ASNs, %d DNS servers, history in %s",
+			countryCfg.CountryName, entry.Profile, len(countryCfg.IranASNs), len(countryCfg.DNSServers), countryCfg.HistoryPath)
+		countries = append(countries, &country{cfg: countryCfg, mon: mon})
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range countries {
+		wg.Add(1)
+		go func(c *country) {
+			defer wg.Done()
+			c.mon.PerformInitialCheck(ctx)
+		}(c)
+	}
+	wg.Wait()
+	return countries
+}
+
+// code is the country's API namespace, e.g. "mm" for /api/v1/mm/
+func (c *country) code() string {
+	return strings.ToLower(c.cfg.Country)
+}
+
+// start runs the country's monitor and posts its status and alerts to its
+// channels with a bot of its own; only the primary bot answers commands, as
+// Telegram delivers updates to one poller per token
+func (c *country) start(ctx context.Context) {
+	bot, err := telegram.NewBot(c.cfg.TelegramToken, c.cfg, func() (*models.MonitoringResult, error) {
+		return c.mon.LatestResults(), nil
+	})
+	if err != nil {
+		log.Fatalf("Failed to create Telegram bot for %s: %v", c.cfg.Country, err)
+	}
+	bot.SetReadiness(c.mon.Readiness())
+	bot.SetStatsProvider(c.mon.Stats)
+	c.mon.SetTelegramAPI(bot.TelegramAPI)
+	bot.SetChartProvider(func(period string) (*bytes.Buffer, string, error) {
+		chartBuffer, err := c.mon.TrafficChart(ctx, period)
+		if err != nil {
+			return nil, "", err
+		}
+		return chartBuffer, c.mon.TrafficSummary(ctx, period), nil
+	})
+
+	c.bot = bot
+	c.mon.SetEventHandler(bot.HandleEvents)
+	go crash.Supervise(ctx, "monitor "+c.cfg.Country, c.mon.Start)
+	go crash.Supervise(ctx, "periodic updates "+c.cfg.Country, bot.SendPeriodicUpdates)
+	if c.cfg.TelegramSpool != nil {
+		go crash.Supervise(ctx, "telegram spool "+c.cfg.Country, bot.RunSpool)
+	}
+	if c.cfg.TelegramArchiveChannel != "" {
+		go crash.Supervise(ctx, "telegram archive "+c.cfg.Country, bot.RunArchive)
+	}
+	crash.Go("startup message "+c.cfg.Country, func() { bot.SendStartupMessage(ctx) })
+}
+
+// drain lets the country's cycle and sends in progress finish by deadline
+func (c *country) drain(deadline time.Time) {
+	c.mon.Drain(time.Until(deadline))
+	if c.bot != nil {
+		c.bot.Drain(time.Until(deadline))
+	}
+}
@@ -1,38 +1,85 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // Embedded zone database so quiet hours work on minimal hosts
 
+	"github.com/netblocks/netblocks/internal/aggregator"
 	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/crash"
+	"github.com/netblocks/netblocks/internal/escalation"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/matrix"
+	"github.com/netblocks/netblocks/internal/mirror"
 	"github.com/netblocks/netblocks/internal/models"
 	"github.com/netblocks/netblocks/internal/monitor"
+	"github.com/netblocks/netblocks/internal/notify"
+	"github.com/netblocks/netblocks/internal/server"
+	"github.com/netblocks/netblocks/internal/sharedstate"
 	"github.com/netblocks/netblocks/internal/telegram"
+	"github.com/netblocks/netblocks/internal/version"
+)
+
+// Process modes: everything in one process, the monitor, bot and API server
+// as separate processes sharing state through Redis, or a read-only mirror
+// pulling the snapshots of a primary instance
+const (
+	modeAll     = "all"
+	modeMonitor = "monitor"
+	modeBot     = "bot"
+	modeAPI     = "api"
+	modeMirror  = "mirror"
 )
 
 func main() {
 	configPath := flag.String("config", "config.json", "Path to configuration file")
+	mode := flag.String("mode", modeAll, "Process mode: all, monitor, bot, api or mirror (split modes need redis_addr, mirror needs mirror_url)")
+	showVersion := flag.Bool("version", false, "Print the version and exit")
 	flag.Parse()
 
+	if *showVersion {
+		fmt.Println("netblocks-bot " + version.String())
+		return
+	}
+	log.Printf("🏷  NetBlocks %s (mode %s)", version.String(), *mode)
+
+	runMonitor := *mode == modeAll || *mode == modeMonitor
+	runBot := *mode == modeAll || *mode == modeBot || *mode == modeMirror
+	switch *mode {
+	case modeAll, modeMonitor, modeBot, modeAPI, modeMirror:
+	default:
+		log.Fatalf("Unknown mode %q (use all, monitor, bot, api or mirror)", *mode)
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig(*configPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	i18n.SetPersianDigits(cfg.PersianDigits)
 
 	// Check for Telegram token
-	if cfg.TelegramToken == "" {
+	if cfg.TelegramToken == "" && runBot {
 		token := os.Getenv("TELEGRAM_BOT_TOKEN")
-		if token == "" {
+		if token == "" && *mode == modeMirror {
+			// A mirror without its own bot only serves the dashboard
+			log.Println("⚠️  No Telegram bot token found - mirroring the dashboard only")
+			runBot = false
+		} else if token == "" {
 			log.Fatal("Telegram bot token not found. Set TELEGRAM_BOT_TOKEN environment variable or add it to config.json")
+		} else if token != "" {
+			cfg.TelegramToken = token
+			log.Println("✓ Telegram token loaded from environment variable")
 		}
-		cfg.TelegramToken = token
-		log.Println("✓ Telegram token loaded from environment variable")
 	}
 
 	// Check for Telegram channel from environment variable
@@ -43,75 +90,356 @@ func main() {
 			log.Printf("✓ Telegram channel loaded from environment variable: %s", channel)
 		}
 	}
-	
+
 	// Load Cloudflare credentials from environment variables (GitHub secrets)
 	// Environment variables take precedence for bot deployment
 	if token := os.Getenv("CLOUDFLARE_TOKEN"); token != "" {
 		cfg.CloudflareToken = token
 		log.Println("✓ Cloudflare token loaded from environment variable (GitHub secret)")
 	}
-	
+	if tokens := os.Getenv("CLOUDFLARE_TOKENS"); tokens != "" {
+		cfg.CloudflareTokens = strings.Split(tokens, ",")
+		log.Printf("✓ %d further Cloudflare tokens loaded from environment variable (GitHub secret)", len(cfg.CloudflareTokens))
+	}
+
 	if email := os.Getenv("CLOUDFLARE_EMAIL"); email != "" {
 		cfg.CloudflareEmail = email
 		log.Println("✓ Cloudflare email loaded from environment variable (GitHub secret)")
 	}
-	
+
 	if key := os.Getenv("CLOUDFLARE_KEY"); key != "" {
 		cfg.CloudflareKey = key
 		log.Println("✓ Cloudflare key loaded from environment variable (GitHub secret)")
 	}
-	
+
+	if addr := os.Getenv("SERVER_ADDR"); addr != "" {
+		cfg.ServerAddr = addr
+		log.Printf("✓ Dashboard server address loaded from environment variable: %s", addr)
+	}
+
+	if token := os.Getenv("AGGREGATOR_TOKEN"); token != "" {
+		cfg.AggregatorToken = token
+		log.Println("✓ Aggregator token loaded from environment variable")
+	}
+
+	if dsn := os.Getenv("HISTORY_DSN"); dsn != "" {
+		cfg.HistoryDSN = dsn
+		log.Println("✓ History database URL loaded from environment variable")
+	}
+
+	if password := os.Getenv("REDIS_PASSWORD"); password != "" {
+		cfg.RedisPassword = password
+		log.Println("✓ Redis password loaded from environment variable")
+	}
+
+	if token := os.Getenv("API_TOKEN"); token != "" {
+		cfg.APIToken = token
+		log.Println("✓ API token loaded from environment variable")
+	}
+
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		cfg.PagerDuty = &config.PagerDutyConfig{RoutingKey: key}
+		log.Println("✓ PagerDuty routing key loaded from environment variable")
+	}
+
+	if token := os.Getenv("SMS_AUTH_TOKEN"); token != "" && cfg.SMS != nil {
+		cfg.SMS.AuthToken = token
+		log.Println("✓ SMS gateway token loaded from environment variable")
+	}
+
+	if token := os.Getenv("MATRIX_ACCESS_TOKEN"); token != "" && cfg.Matrix != nil {
+		cfg.Matrix.AccessToken = token
+		log.Println("✓ Matrix access token loaded from environment variable")
+	}
+
+	if key := os.Getenv("OPSGENIE_API_KEY"); key != "" {
+		if cfg.Opsgenie == nil {
+			cfg.Opsgenie = &config.OpsgenieConfig{}
+		}
+		cfg.Opsgenie.APIKey = key
+		log.Println("✓ Opsgenie API key loaded from environment variable")
+	}
+
+	// Shared state lets the monitor, bot and API server run as separate processes
+	var state *sharedstate.Store
+	if cfg.RedisAddr != "" {
+		state, err = sharedstate.Open(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, cfg.RedisPrefix)
+		if err != nil {
+			log.Fatalf("Failed to connect to Redis: %v", err)
+		}
+		log.Printf("✓ Sharing state through Redis at %s", cfg.RedisAddr)
+	} else if *mode != modeAll && *mode != modeMirror {
+		log.Fatalf("Mode %q needs redis_addr to share state with the other processes", *mode)
+	}
+
+	// A mirror pulls results and public events from a primary instance instead of monitoring
+	var mirrorClient *mirror.Client
+	if *mode == modeMirror {
+		if cfg.MirrorURL == "" {
+			log.Fatal("Mode mirror needs mirror_url of the primary instance")
+		}
+		if !runBot && cfg.ServerAddr == "" {
+			log.Fatal("Mode mirror needs a Telegram bot token or server_addr to serve the mirrored results")
+		}
+		mirrorClient = mirror.New(cfg.MirrorURL)
+	}
+
+	// Further countries run their own monitor and bot next to the primary ones
+	if len(cfg.Countries) > 0 && *mode != modeAll {
+		log.Fatalf("countries need -mode all (mode %q is not supported)", *mode)
+	}
+
 	// Log if Cloudflare credentials are available (for ASN traffic chart)
-	if cfg.CloudflareToken != "" || (cfg.CloudflareEmail != "" && cfg.CloudflareKey != "") {
+	if len(cfg.CloudflareTokenList()) > 0 || (cfg.CloudflareEmail != "" && cfg.CloudflareKey != "") {
 		log.Println("✓ Cloudflare credentials available - ASN traffic chart will be generated")
 	} else {
 		log.Println("⚠️  No Cloudflare credentials found - ASN traffic chart will be skipped")
 	}
 
-	// Create monitor
-	mon, err := monitor.NewMonitor(cfg)
-	if err != nil {
-		log.Fatalf("Failed to create monitor: %v", err)
+	// Recovered panics are written to crash_dir and, once the bot runs, reported to the admins
+	switch cfg.CrashDir {
+	case "":
+	case "off":
+		crash.SetDir("")
+	default:
+		crash.SetDir(cfg.CrashDir)
 	}
-	defer mon.Stop()
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Perform initial check to ensure DNS results are available before bot starts
-	// This also fetches Cloudflare data FIRST before anything else
-	log.Println("🔄 Performing initial checks (Cloudflare, DNS, BGP)...")
-	log.Println("📊 Fetching Cloudflare Radar data FIRST...")
-	mon.PerformInitialCheck(ctx)
-	log.Println("✅ Initial checks completed - Cloudflare data fetched")
-
-	// Create Telegram bot
-	bot, err := telegram.NewBot(cfg.TelegramToken, cfg, func() (*models.MonitoringResult, error) {
-		result := mon.GetResults()
-		return result, nil
-	})
+	// Work in progress at shutdown gets drain_timeout to finish
+	drainTimeout := 30 * time.Second
+	if cfg.DrainTimeout != "" {
+		drainTimeout, err = time.ParseDuration(cfg.DrainTimeout)
+		if err != nil || drainTimeout < 0 {
+			log.Fatalf("Invalid drain_timeout %q", cfg.DrainTimeout)
+		}
+	}
+
+	// Process-wide monitor settings come from the primary config only
+	monitor.Configure(cfg)
+
+	// Create monitor
+	var mon *monitor.Monitor
+	if runMonitor {
+		mon, err = monitor.NewMonitor(cfg)
+		if err != nil {
+			log.Fatalf("Failed to create monitor: %v", err)
+		}
+		defer mon.Stop()
+
+		// Perform initial checks (Cloudflare, DNS, BGP in parallel) so results are available before the bot starts
+		mon.PerformInitialCheck(ctx)
+	}
+	countries := newCountries(ctx, cfg)
+	for _, c := range countries {
+		defer c.mon.Stop()
+	}
+
+	// In aggregator mode the bot posts the result merged from all probes
+	var agg *aggregator.Aggregator
+	if len(cfg.AggregatorProbes) > 0 && runMonitor {
+		if cfg.ServerAddr == "" {
+			log.Fatal("aggregator_probes requires server_addr to accept probe submissions")
+		}
+		agg = aggregator.New(cfg)
+		log.Printf("🛰  Aggregator mode: accepting results from %d probe(s)", len(cfg.AggregatorProbes))
+	}
+
+	// Results of this process's monitor, merged with probe submissions in aggregator mode
+	localResults := func() *models.MonitoringResult {
+		result := mon.LatestResults()
+		if agg != nil {
+			result = agg.Merge(result)
+		}
+		return result
+	}
+
+	// Webhook and email notifiers; Telegram is registered once the bot (or Redis) is ready
+	dispatcher, err := notify.NewDispatcher(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create Telegram bot: %v", err)
+		log.Fatalf("Invalid notifier config: %v", err)
 	}
 
-	// Start monitor in background
-	go mon.Start(ctx)
+	// Subsystems that stop unexpectedly are restarted (crash.Supervise); each
+	// restart is an operational event for the admins and routes of kind "subsystem"
+	crash.SetRestartHandler(func(r crash.Restart) {
+		dispatcher.HandleEvents([]models.Event{{
+			Timestamp: time.Now(),
+			Kind:      "subsystem",
+			Target:    r.Subsystem,
+			Severity:  models.SeverityWarning,
+			Message:   fmt.Sprintf("%s stopped unexpectedly (%s), restart %d in %v", r.Subsystem, r.Reason, r.Restarts, r.Backoff),
+			Actions:   []string{notify.ActionTelegramAdmins},
+		}})
+	})
 
-	// Start periodic updates in background
-	go bot.SendPeriodicUpdates(ctx)
+	// Rules with an escalation policy open incidents that escalate until acknowledged
+	var escalator *escalation.Manager
+	if runMonitor {
+		// A Matrix room mirrors everything sent to Telegram
+		if cfg.Matrix != nil {
+			room, err := matrix.New(cfg.Matrix)
+			if err != nil {
+				log.Fatalf("Invalid matrix config: %v", err)
+			}
+			dispatcher.Register(room)
+			dispatcher.Mirror(notify.ActionTelegram, matrix.ActionMatrix)
+			crash.Go("Matrix room", func() { room.Run(ctx, func() (*models.MonitoringResult, error) { return localResults(), nil }) })
+		}
+		// Signal groups get the critical Telegram alerts
+		if cfg.Signal != nil {
+			dispatcher.Mirror(notify.ActionTelegram, notify.ActionSignal)
+		}
+		// Critical Telegram alerts are also texted
+		if cfg.SMS != nil {
+			dispatcher.Mirror(notify.ActionTelegram, notify.ActionSMS)
+		}
+		if err := dispatcher.CheckActions(cfg); err != nil {
+			log.Fatalf("Invalid alert rules: %v", err)
+		}
+		if len(cfg.Rules) > 0 {
+			log.Printf("📏 Evaluating %d alert rule(s) each cycle", len(cfg.Rules))
+		}
+		escalator, err = escalation.New(cfg, dispatcher.HandleEvents)
+		if err != nil {
+			log.Fatalf("Invalid escalation policies: %v", err)
+		}
+	}
 
-	log.Println("✅ NetBlocks Telegram Bot started successfully!")
-	log.Println("📊 Monitoring Iranian ASNs and DNS servers...")
-	log.Println("🤖 Bot is ready to receive commands")
-	if cfg.TelegramChannel != "" {
-		log.Printf("📢 Channel updates enabled for: %s", cfg.TelegramChannel)
-		log.Println("   Channel will receive updates every 10 minutes")
-		
-		// Send startup message to channel
-		go bot.SendStartupMessage(ctx)
+	// Incidents live in the monitor process; the bot and api processes reach them through Redis
+	// Mirrors have no access to the primary's incidents
+	escalationEnabled := len(cfg.EscalationPolicies) > 0 && *mode != modeMirror
+	var listIncidents func() ([]escalation.Incident, error)
+	var ackIncident func(id, by string) error
+	if runMonitor {
+		listIncidents = func() ([]escalation.Incident, error) { return escalator.Incidents(), nil }
+		ackIncident = escalator.Ack
+	} else {
+		listIncidents = state.LoadIncidents
+		ackIncident = state.PushAck
+	}
+
+	if runMonitor {
+		// Start monitor in background
+		go crash.Supervise(ctx, "monitor", mon.Start)
+
+		// Probe mode: submit results to a central aggregation server
+		if cfg.AggregatorURL != "" {
+			crash.Go("aggregator client", func() {
+				aggregator.NewClient(cfg.AggregatorURL, cfg.AggregatorToken).Run(ctx, cfg.Interval, mon.LatestResults)
+			})
+		}
+
+		// Events go to the notifiers named by alert rule actions (Telegram by default)
+		mon.SetEventHandler(escalator.HandleEvents)
+		go crash.Supervise(ctx, "escalation", escalator.Run)
+
+		// A separate bot process reads results and events from Redis
+		if *mode == modeMonitor {
+			crash.Go("shared state publisher", func() { state.Run(ctx, cfg.Interval, localResults) })
+			dispatcher.Register(shareEvents(state, notify.ActionTelegram))
+			dispatcher.Register(shareEvents(state, notify.ActionTelegramAdmins))
+			if escalationEnabled {
+				if err := state.PublishIncidents(nil); err != nil {
+					log.Printf("⚠️  Failed to clear shared incidents: %v", err)
+				}
+				escalator.SetChangeHandler(func(incidents []escalation.Incident) {
+					if err := state.PublishIncidents(incidents); err != nil {
+						log.Printf("⚠️  Failed to share incidents: %v", err)
+					}
+				})
+				crash.Go("shared acknowledgements", func() { state.ConsumeAcks(ctx, escalator.Ack) })
+			}
+		}
+	}
+
+	// Start web dashboard if configured (the bot process leaves it to the api process)
+	if cfg.ServerAddr != "" && *mode != modeBot {
+		srv := server.NewServer(cfg.ServerAddr, mon)
+		if cfg.APICacheTTL != "" {
+			ttl, err := time.ParseDuration(cfg.APICacheTTL)
+			if err != nil || ttl < 0 {
+				log.Fatalf("Invalid api_cache_ttl %q", cfg.APICacheTTL)
+			}
+			srv.SetCacheTTL(ttl)
+		}
+		if cfg.ServerProtection != nil {
+			srv.SetProtection(*cfg.ServerProtection)
+		}
+		if agg != nil {
+			srv.SetAggregator(agg)
+		}
+		if mirrorClient != nil {
+			srv.SetResultsProvider(mirrorClient.Result)
+		} else if !runMonitor {
+			srv.SetResultsProvider(state.LoadResult)
+		}
+		if escalationEnabled {
+			srv.SetIncidents(listIncidents, ackIncident, cfg.APIToken)
+		}
+		// Annotations live in the monitor's history
+		if runMonitor {
+			srv.SetAnnotations(mon.Annotate, cfg.APIToken)
+			srv.SetCampaigns(cfg.APIToken)
+		}
+		provinces, err := server.NewProvinceMap(cfg)
+		if err != nil {
+			log.Fatalf("Failed to load the province map: %v", err)
+		}
+		srv.SetMap(provinces)
+		// Every country also has its own namespace, e.g. /api/v1/ir/status
+		srv.AddCountry(cfg.Country, nil, nil)
+		for _, c := range countries {
+			countryProvinces, err := server.NewProvinceMap(c.cfg)
+			if err != nil {
+				log.Fatalf("Failed to load the province map of %s: %v", c.cfg.Country, err)
+			}
+			srv.AddCountry(c.code(), c.mon, countryProvinces)
+		}
+		// The snapshot carries the public Telegram events for mirrors; a separate
+		// api process does not see the events and publishes results only
+		if runMonitor || mirrorClient != nil {
+			dispatcher.Register(notify.NewFunc(server.ActionSnapshot, srv.RecordEvents))
+			dispatcher.Mirror(notify.ActionTelegram, server.ActionSnapshot)
+		}
+		go crash.Supervise(ctx, "dashboard server", srv.Start)
+	}
+
+	// Mirrored events are posted to this instance's Telegram channels (and recorded
+	// for its own snapshot, so mirrors can be chained)
+	if mirrorClient != nil {
+		action := notify.ActionTelegram
+		if !runBot {
+			action = server.ActionSnapshot
+		}
+		crash.Go("mirror", func() {
+			mirrorClient.Run(ctx, cfg.Interval, func(events []models.Event) {
+				for i := range events {
+					events[i].Actions = []string{action}
+				}
+				dispatcher.Deliver(events)
+			})
+		})
+	}
+
+	// drain lets the cycles, Telegram sends and storage writes in progress finish
+	// (bounded by drain_timeout) before the deferred Stops close the history and RIS Live
+	drain := func(bot *telegram.Bot) {
+		log.Printf("⏳ Finishing work in progress (up to %v)...", drainTimeout)
+		deadline := time.Now().Add(drainTimeout)
+		if mon != nil {
+			mon.Drain(time.Until(deadline))
+		}
+		for _, c := range countries {
+			c.drain(deadline)
+		}
+		if bot != nil {
+			bot.Drain(time.Until(deadline))
+		}
 	}
-	log.Println("")
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -125,12 +453,101 @@ func main() {
 		cancel()
 	}()
 
+	if !runBot {
+		log.Printf("✅ NetBlocks %s process started successfully!", *mode)
+		<-ctx.Done()
+		drain(nil)
+		log.Println("Shutdown complete.")
+		return
+	}
+
+	// Create Telegram bot; without a local monitor it reads results from Redis or the primary
+	resultsProvider := func() (*models.MonitoringResult, error) {
+		return localResults(), nil
+	}
+	if mirrorClient != nil {
+		resultsProvider = mirrorClient.Result
+	} else if !runMonitor {
+		resultsProvider = state.LoadResult
+	}
+	bot, err := telegram.NewBot(cfg.TelegramToken, cfg, resultsProvider)
+	if err != nil {
+		log.Fatalf("Failed to create Telegram bot: %v", err)
+	}
+
+	if state != nil {
+		if err := bot.SetSharedState(state); err != nil {
+			log.Printf("⚠️  Failed to restore subscriptions from Redis: %v", err)
+		}
+	}
+	if escalationEnabled {
+		bot.SetIncidentHandlers(listIncidents, ackIncident)
+	}
+	crash.SetNotifier(bot.ReportCrash)
+	dispatcher.Register(notify.NewFunc(notify.ActionTelegram, bot.HandleEvents))
+	dispatcher.Register(notify.NewFunc(notify.ActionTelegramAdmins, bot.NotifyAdmins))
+	if runMonitor {
+		bot.SetReadiness(mon.Readiness())
+		bot.SetStatsProvider(mon.Stats)
+		mon.SetTelegramAPI(bot.TelegramAPI)
+		bot.SetExportProvider(mon.ExportHistory)
+		bot.SetAnnotationHandlers(mon.Annotate, mon.Annotations)
+		bot.SetCampaignHandlers(mon.StartCampaign, mon.StopCampaign, mon.Campaign, mon.CampaignProfiles())
+		bot.SetChartProvider(func(period string) (*bytes.Buffer, string, error) {
+			chartBuffer, err := mon.TrafficChart(ctx, period)
+			if err != nil {
+				return nil, "", err
+			}
+			return chartBuffer, mon.TrafficSummary(ctx, period), nil
+		})
+	} else if mirrorClient == nil {
+		crash.Go("shared events", func() { state.ConsumeEvents(ctx, dispatcher.Deliver) })
+	}
+
+	// Start periodic updates in background
+	go crash.Supervise(ctx, "periodic updates", bot.SendPeriodicUpdates)
+	if cfg.TelegramSpool != nil {
+		// Deliver the sends queued while Telegram was unreachable
+		go crash.Supervise(ctx, "telegram spool", bot.RunSpool)
+	}
+	if cfg.TelegramArchiveChannel != "" {
+		// Keep an off-site copy of every cycle's data in the archive channel
+		go crash.Supervise(ctx, "telegram archive", bot.RunArchive)
+	}
+	for _, c := range countries {
+		c.start(ctx)
+	}
+
+	log.Println("✅ NetBlocks Telegram Bot started successfully!")
+	log.Printf("📊 Monitoring ASNs and DNS servers of %s...", cfg.CountryName)
+	log.Println("🤖 Bot is ready to receive commands")
+	if cfg.TelegramChannel != "" {
+		log.Printf("📢 Channel updates enabled for: %s", cfg.TelegramChannel)
+	}
+	// Post the startup diagnostics to the channels or admins (startup_message)
+	crash.Go("startup message", func() { bot.SendStartupMessage(ctx) })
+	log.Println("")
+
 	// Start bot - this blocks and keeps the process alive
-	// Bot will stop when context is cancelled (by signal handler or error)
-	bot.Start(ctx)
-	
-	// Give goroutines time to clean up
+	// Bot will stop when context is cancelled (by signal handler or error); the
+	// update handler is restarted if it stops before
+	crash.Supervise(ctx, "telegram poller", bot.Start)
+
 	log.Println("Bot stopped, cleaning up...")
-	time.Sleep(1 * time.Second)
+	drain(bot)
 	log.Println("Shutdown complete.")
 }
+
+// shareEvents queues the events of a Telegram action for the separate bot process
+func shareEvents(state *sharedstate.Store, action string) notify.Notifier {
+	return notify.NewFunc(action, func(events []models.Event) {
+		shared := make([]models.Event, len(events))
+		for i, event := range events {
+			event.Actions = []string{action}
+			shared[i] = event
+		}
+		if err := state.PushEvents(shared); err != nil {
+			log.Printf("⚠️  Failed to share events: %v", err)
+		}
+	})
+}
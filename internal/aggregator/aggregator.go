@@ -0,0 +1,348 @@
+package aggregator
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// SubmitPath is the endpoint probes POST their results to
+const SubmitPath = "/api/v1/submit"
+
+// maxSubmissionBytes bounds the size of a submitted result
+const maxSubmissionBytes = 5 << 20
+
+// staleCycles is how many monitoring intervals a submission stays in the merge
+const staleCycles = 3
+
+// submission is the latest result received from a probe
+type submission struct {
+	result   *models.MonitoringResult
+	weight   float64
+	received time.Time
+}
+
+// Aggregator merges results submitted by several probes into one MonitoringResult
+// Each target is reconciled by a weighted vote: it counts as up when the weighted
+// share of probes seeing it up reaches the quorum
+type Aggregator struct {
+	probes      map[string]config.ProbeConfig // Keyed by token
+	quorum      float64
+	staleAfter  time.Duration
+	mu          sync.RWMutex
+	submissions map[string]*submission // Keyed by probe ID
+	country     string
+	provinces   []config.Province       // Provinces of the map, redrawn from the merged DNS statuses
+	shapes      *monitor.ProvinceShapes // Province polygons (nil: circles at the capitals)
+}
+
+// New creates an aggregator accepting the probes listed in the config
+func New(cfg *config.Config) *Aggregator {
+	a := &Aggregator{
+		probes:      make(map[string]config.ProbeConfig),
+		quorum:      cfg.AggregatorQuorum,
+		staleAfter:  staleCycles * cfg.Interval,
+		submissions: make(map[string]*submission),
+		country:     cfg.CountryName,
+		provinces:   cfg.Provinces,
+	}
+	if cfg.MapBoundaries != "" && len(cfg.Provinces) > 0 {
+		shapes, err := monitor.LoadProvinceShapes(cfg.MapBoundaries, cfg.Provinces)
+		if err != nil {
+			log.Printf("⚠️  Failed to load map_boundaries (drawing provinces as circles): %v", err)
+		}
+		a.shapes = shapes
+	}
+	for _, probe := range cfg.AggregatorProbes {
+		if probe.ID == "" || probe.Token == "" {
+			log.Printf("⚠️  Ignoring aggregator probe without id or token")
+			continue
+		}
+		if probe.Weight <= 0 {
+			probe.Weight = 1
+		}
+		a.probes[probe.Token] = probe
+	}
+	if a.quorum <= 0 || a.quorum > 1 {
+		a.quorum = 0.5
+	}
+	return a
+}
+
+// authenticate returns the probe whose token matches the request's Bearer token
+func (a *Aggregator) authenticate(r *http.Request) (config.ProbeConfig, bool) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		return config.ProbeConfig{}, false
+	}
+	for known, probe := range a.probes {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(token)) == 1 {
+			return probe, true
+		}
+	}
+	return config.ProbeConfig{}, false
+}
+
+// HandleSubmit accepts a MonitoringResult (JSON) from an authenticated probe
+func (a *Aggregator) HandleSubmit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	probe, ok := a.authenticate(r)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var result models.MonitoringResult
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmissionBytes)).Decode(&result); err != nil {
+		http.Error(w, fmt.Sprintf("invalid result: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	a.Submit(probe.ID, probe.Weight, &result)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Submit records the latest result of a probe
+// The configured probe ID replaces whatever ID the probe reported
+func (a *Aggregator) Submit(probeID string, weight float64, result *models.MonitoringResult) {
+	vantage := &models.Vantage{ProbeID: probeID}
+	if result.Vantage != nil {
+		vantage.Country = result.Vantage.Country
+		vantage.Network = result.Vantage.Network
+		vantage.Build = result.Vantage.Build
+	}
+	result.Vantage = vantage
+
+	a.mu.Lock()
+	a.submissions[probeID] = &submission{result: result, weight: weight, received: time.Now()}
+	a.mu.Unlock()
+}
+
+// Merge reconciles the local result with every fresh probe submission
+// Charts and history-based fields come from the local result; with no fresh
+// submissions the local result is returned unchanged
+func (a *Aggregator) Merge(local *models.MonitoringResult) *models.MonitoringResult {
+	inputs := a.fresh()
+	if len(inputs) == 0 {
+		return local
+	}
+	if local != nil {
+		inputs = append([]*submission{{result: local, weight: 1, received: time.Now()}}, inputs...)
+	}
+
+	base := inputs[0].result
+	merged := &models.MonitoringResult{
+		Timestamp:     time.Now(),
+		ASNStatuses:   make(map[string]*models.ASNStatus),
+		ReferenceASNs: base.ReferenceASNs,
+		Prefixes:      base.Prefixes,
+		DNSStatuses:   make(map[string]*models.DNSStatus),
+		TrafficData:   base.TrafficData,
+		ASTrafficData: base.ASTrafficData,
+		UptimeChart:   base.UptimeChart,
+		FailureChart:  base.FailureChart,
+		ASNSparklines: base.ASNSparklines,
+		UptimeSummary: base.UptimeSummary,
+		CustomChecks:  base.CustomChecks,
+		AtlasAnchors:  base.AtlasAnchors,
+		AtlasProbes:   base.AtlasProbes,
+		Campaign:      base.Campaign,
+		SLOs:          base.SLOs,
+	}
+	// Telegram endpoints are checked from every vantage: keep each probe's outcomes
+	for _, input := range inputs {
+		telegram := input.result.Telegram
+		if telegram == nil {
+			continue
+		}
+		if merged.Telegram == nil {
+			merged.Telegram = &models.Telegram{}
+			if base.Telegram != nil {
+				merged.Telegram.API = base.Telegram.API
+			}
+		}
+		for _, endpoint := range telegram.Endpoints {
+			copied := *endpoint
+			if copied.Vantage == nil {
+				copied.Vantage = input.result.Vantage
+			}
+			merged.Telegram.Endpoints = append(merged.Telegram.Endpoints, &copied)
+		}
+	}
+	// Traffic comes from Cloudflare and is the same for every probe; keep the freshest
+	for _, input := range inputs[1:] {
+		traffic := input.result.TrafficData
+		if traffic != nil && (merged.TrafficData == nil || traffic.LastUpdate.After(merged.TrafficData.LastUpdate)) {
+			merged.TrafficData = traffic
+			if len(input.result.ASTrafficData) > 0 {
+				merged.ASTrafficData = input.result.ASTrafficData
+			}
+		}
+	}
+	// So do the RIPE Atlas measurements, taken by Atlas probes worldwide
+	for _, input := range inputs[1:] {
+		probes := input.result.AtlasProbes
+		if probes != nil && (merged.AtlasProbes == nil || probes.CheckedAt.After(merged.AtlasProbes.CheckedAt)) {
+			merged.AtlasProbes = probes
+			merged.AtlasAnchors = input.result.AtlasAnchors
+		}
+	}
+
+	results := make([]*models.MonitoringResult, 0, len(inputs))
+	for _, input := range inputs {
+		results = append(results, input.result)
+		merged.Probes = append(merged.Probes, input.result.Vantage)
+	}
+	a.mergeASNs(merged, inputs)
+	a.mergeDNS(merged, inputs)
+	merged.Disagreements = monitor.CompareVantages(results)
+
+	merged.NationalScore = monitor.CalculateNationalScore(merged)
+	statusImage, err := monitor.GenerateStatusImage(merged, merged.NationalScore)
+	if err != nil {
+		log.Printf("⚠️  Failed to generate aggregated status image: %v", err)
+		statusImage = nil
+	}
+	merged.StatusImage = statusImage
+	if len(a.provinces) > 0 {
+		title := fmt.Sprintf("%s Connectivity by Province - %s UTC", a.country, merged.Timestamp.UTC().Format("2006-01-02 15:04"))
+		provinceMap, err := monitor.GenerateProvinceMap(title, monitor.ProvinceHealths(a.provinces, merged), a.shapes)
+		if err != nil {
+			log.Printf("⚠️  Failed to generate aggregated province map: %v", err)
+			provinceMap = nil
+		}
+		merged.ProvinceMap = provinceMap
+	}
+	return merged
+}
+
+// fresh returns the submissions received within the staleness window, ordered by probe ID
+func (a *Aggregator) fresh() []*submission {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	cutoff := time.Now().Add(-a.staleAfter)
+	ids := make([]string, 0, len(a.submissions))
+	for id, sub := range a.submissions {
+		if sub.received.After(cutoff) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	inputs := make([]*submission, 0, len(ids))
+	for _, id := range ids {
+		inputs = append(inputs, a.submissions[id])
+	}
+	return inputs
+}
+
+// mergeASNs votes on the connectivity of every ASN reported by any probe
+func (a *Aggregator) mergeASNs(merged *models.MonitoringResult, inputs []*submission) {
+	votes := make(map[string][2]float64) // ASN -> [weight up, weight total]
+	for _, input := range inputs {
+		for asn, status := range input.result.ASNStatuses {
+			v := votes[asn]
+			if status.Connected {
+				v[0] += input.weight
+			}
+			v[1] += input.weight
+			votes[asn] = v
+
+			existing, ok := merged.ASNStatuses[asn]
+			if !ok {
+				copied := *status
+				copied.Vantage = nil
+				merged.ASNStatuses[asn] = &copied
+				continue
+			}
+			if status.LastSeen.After(existing.LastSeen) {
+				existing.LastSeen = status.LastSeen
+			}
+			if status.LastUpdate.After(existing.LastUpdate) {
+				existing.LastUpdate = status.LastUpdate
+			}
+		}
+	}
+	for asn, v := range votes {
+		merged.ASNStatuses[asn].Connected = v[0]/v[1] >= a.quorum
+	}
+}
+
+// mergeDNS votes on the availability of every DNS server reported by any probe
+// The response times (cached and uncached) are weighted averages over the probes
+// that reached the server
+func (a *Aggregator) mergeDNS(merged *models.MonitoringResult, inputs []*submission) {
+	type dnsVote struct {
+		up, total, rttWeight     float64
+		rtt                      float64
+		uncached, uncachedWeight float64
+		lastError                string
+		lastCode                 models.DNSErrorCode
+	}
+	votes := make(map[string]*dnsVote)
+	for _, input := range inputs {
+		for key, status := range input.result.DNSStatuses {
+			v, ok := votes[key]
+			if !ok {
+				v = &dnsVote{}
+				votes[key] = v
+			}
+			v.total += input.weight
+			if status.Alive {
+				v.up += input.weight
+				v.rtt += float64(status.ResponseTime) * input.weight
+				v.rttWeight += input.weight
+				if status.UncachedTime > 0 {
+					v.uncached += float64(status.UncachedTime) * input.weight
+					v.uncachedWeight += input.weight
+				}
+			} else if status.Error != "" {
+				v.lastError = fmt.Sprintf("%s: %s", input.result.Vantage, status.Error)
+				v.lastCode = status.ErrorCode
+			}
+
+			existing, ok := merged.DNSStatuses[key]
+			if !ok {
+				copied := *status
+				copied.Vantage = nil
+				merged.DNSStatuses[key] = &copied
+				continue
+			}
+			if status.LastCheck.After(existing.LastCheck) {
+				existing.LastCheck = status.LastCheck
+			}
+		}
+	}
+	for key, v := range votes {
+		status := merged.DNSStatuses[key]
+		status.Alive = v.up/v.total >= a.quorum
+		status.Sample = status.Alive // The probes damped their own samples; the vote is the merged sample
+		status.Error, status.ErrorCode = "", ""
+		status.ResponseTime = 0
+		if v.rttWeight > 0 {
+			status.ResponseTime = time.Duration(v.rtt / v.rttWeight)
+		}
+		status.UncachedTime = 0
+		if v.uncachedWeight > 0 {
+			status.UncachedTime = time.Duration(v.uncached / v.uncachedWeight)
+		}
+		if !status.Alive {
+			status.Error, status.ErrorCode = v.lastError, v.lastCode
+		}
+	}
+}
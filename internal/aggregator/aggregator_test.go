@@ -0,0 +1,47 @@
+package aggregator
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// probeResult returns a result seeing asn up, as submitted by a probe
+func probeResult(asn string) *models.MonitoringResult {
+	return &models.MonitoringResult{
+		Timestamp:   time.Now(),
+		ASNStatuses: map[string]*models.ASNStatus{asn: {ASN: asn, Connected: true}},
+		DNSStatuses: map[string]*models.DNSStatus{},
+	}
+}
+
+func TestMergeKeepsLocalFields(t *testing.T) {
+	a := New(&config.Config{Interval: time.Minute})
+	a.Submit("probe-1", 1, probeResult("AS44244"))
+
+	local := probeResult("AS44244")
+	local.Prefixes = map[string]*models.PrefixStatus{"5.160.0.0/16": {Prefix: "5.160.0.0/16", Announced: false}}
+	local.FailureChart = bytes.NewBufferString("png")
+	local.Campaign = &models.Campaign{Name: "outage", Trigger: models.CampaignManual}
+	local.SLOs = []models.SLOStatus{{Name: "dns-7d", Target: "dns", Window: "7d", Availability: 99.5}}
+
+	merged := a.Merge(local)
+	if merged == local {
+		t.Fatal("Merge returned the local result despite a fresh submission")
+	}
+	if merged.Prefixes["5.160.0.0/16"] == nil {
+		t.Fatalf("Prefixes = %+v, want the local prefixes", merged.Prefixes)
+	}
+	if merged.FailureChart != local.FailureChart {
+		t.Fatal("FailureChart not carried over from the local result")
+	}
+	if merged.Campaign == nil || merged.Campaign.Name != "outage" {
+		t.Fatalf("Campaign = %+v, want the local campaign", merged.Campaign)
+	}
+	if len(merged.SLOs) != 1 || merged.SLOs[0].Name != "dns-7d" {
+		t.Fatalf("SLOs = %+v, want the local SLOs", merged.SLOs)
+	}
+}
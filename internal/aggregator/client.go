@@ -0,0 +1,77 @@
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Client submits this probe's results to an aggregation server
+type Client struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the aggregation server at baseURL
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		url:        strings.TrimSuffix(baseURL, "/") + SubmitPath,
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Submit sends one result to the aggregation server
+func (c *Client) Submit(ctx context.Context, result *models.MonitoringResult) error {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit result: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("aggregator returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Run submits the latest result every interval until the context is cancelled
+func (c *Client) Run(ctx context.Context, interval time.Duration, results func() *models.MonitoringResult) {
+	log.Printf("📡 Submitting results to aggregator %s every %s", c.url, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if result := results(); result != nil {
+			if err := c.Submit(ctx, result); err != nil && ctx.Err() == nil {
+				log.Printf("⚠️  %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package capture keeps the raw wire-format DNS exchanges of failed and
+// anomalous checks, so researchers can inspect TTLs, flags and injected answers
+// later instead of only the Alive/Error summary
+//
+// Captures are appended to one gzip-compressed JSON Lines file per UTC day
+// (captures-YYYY-MM-DD.jsonl.gz) and the directory is capped in size by
+// removing the oldest days
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// DefaultMaxMB is the size cap of the capture directory when none is configured
+const DefaultMaxMB = 100
+
+// Record is one captured DNS exchange
+type Record struct {
+	Time     time.Time `json:"time"`
+	Server   string    `json:"server"`             // Address of the DNS server
+	Name     string    `json:"name"`               // Display name of the server
+	Reason   string    `json:"reason"`             // Why it was captured, e.g. "timeout" or "injected answer 10.10.34.36"
+	Error    string    `json:"error,omitempty"`    // Error of the exchange, if any
+	Query    []byte    `json:"query"`              // Query in wire format (base64 in the file)
+	Response []byte    `json:"response,omitempty"` // Response in wire format (absent if none arrived)
+	RTT      int64     `json:"rtt_ms,omitempty"`   // Round trip of the response
+}
+
+// Writer appends records to the daily capture files
+type Writer struct {
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	all      bool
+	full     bool // The cap was reached with only the current day left; reported once
+}
+
+// NewWriter creates a writer for the capture settings
+func NewWriter(cfg config.DNSCapture) (*Writer, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("dns_capture.dir: %w", err)
+	}
+	maxMB := cfg.MaxMB
+	if maxMB <= 0 {
+		maxMB = DefaultMaxMB
+	}
+	return &Writer{dir: cfg.Dir, maxBytes: int64(maxMB) << 20, all: cfg.All}, nil
+}
+
+// All reports whether every exchange is captured, not only failed and anomalous ones
+func (w *Writer) All() bool {
+	return w != nil && w.all
+}
+
+// Write appends a record to the file of its day; a nil writer discards it
+func (w *Writer) Write(rec Record) error {
+	if w == nil {
+		return nil
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	path := filepath.Join(w.dir, "captures-"+rec.Time.UTC().Format("2006-01-02")+".jsonl.gz")
+	if !w.makeRoom(path) {
+		return nil
+	}
+
+	// Each record is its own gzip member; readers see one continuous stream
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// makeRoom removes the oldest capture files while the directory is over its cap,
+// and reports whether there is room for path (caller holds w.mu)
+func (w *Writer) makeRoom(path string) bool {
+	files, err := filepath.Glob(filepath.Join(w.dir, "captures-*.jsonl.gz"))
+	if err != nil {
+		return true
+	}
+	sort.Strings(files) // Oldest day first
+	sizes := make(map[string]int64, len(files))
+	var total int64
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			sizes[file] = info.Size()
+			total += info.Size()
+		}
+	}
+	for _, file := range files {
+		if total < w.maxBytes {
+			break
+		}
+		if file == path {
+			continue
+		}
+		if err := os.Remove(file); err == nil {
+			log.Printf("🗑  Removed DNS capture file %s (dns_capture.max_mb reached)", filepath.Base(file))
+			total -= sizes[file]
+		}
+	}
+	if total >= w.maxBytes {
+		if !w.full {
+			log.Printf("⚠️  DNS captures of today reached dns_capture.max_mb; further captures are dropped until tomorrow")
+			w.full = true
+		}
+		return false
+	}
+	w.full = false
+	return true
+}
+
+// Read calls fn with every record of a capture file
+func Read(path string, fn func(Record) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the country profile used when the config names none
+const DefaultProfile = "iran"
+
+//go:embed profiles/*.json
+var profileFiles embed.FS
+
+// Profile bundles everything that is specific to the monitored country:
+// targets, traffic thresholds, Radar location and language
+type Profile struct {
+	Name              string             `json:"name"`
+	Description       string             `json:"description,omitempty"`
+	Country           string             `json:"country"`                      // ISO 3166-1 alpha-2 code, used as Cloudflare Radar and IODA location
+	ISO3              string             `json:"iso3,omitempty"`               // ISO 3166-1 alpha-3 code, the Radar location retried when the alpha-2 code returns no data
+	CountryName       string             `json:"country_name,omitempty"`       // Display name, e.g. "Iran"
+	Timezone          string             `json:"timezone,omitempty"`           // IANA zone of the country
+	Language          string             `json:"language,omitempty"`           // Default language of channels ("en" or "fa")
+	ASNs              []string           `json:"asns,omitempty"`               // ASNs monitored via BGP
+	ASNNames          map[string]string  `json:"asn_names,omitempty"`          // Display names of the ASNs
+	DNSServers        []DNSServer        `json:"dns_servers,omitempty"`        // DNS servers probed each cycle
+	TrafficThresholds *TrafficThresholds `json:"traffic_thresholds,omitempty"` // Traffic status boundaries (default: 0.7, 0.3, 0.1)
+	Provinces         []Province         `json:"provinces,omitempty"`          // Provinces of the map, placed at their capitals
+}
+
+// Province is a first-level region of the country on the province map; DNS
+// servers belong to it when the place in their name is the province or one of
+// its cities, e.g. "DNS (Shiraz)" belongs to Fars
+type Province struct {
+	Name    string   `json:"name"`
+	Capital string   `json:"capital,omitempty"`
+	Lat     float64  `json:"lat"` // Coordinates of the capital
+	Lon     float64  `json:"lon"`
+	Cities  []string `json:"cities,omitempty"` // Places in DNS server names that lie in the province (the name and capital always match)
+}
+
+// Matches reports whether a place from a DNS server name (see GetDNSCity) lies in the province
+func (p Province) Matches(place string) bool {
+	if strings.EqualFold(place, p.Name) || strings.EqualFold(place, p.Capital) {
+		return true
+	}
+	for _, city := range p.Cities {
+		if strings.EqualFold(place, city) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrafficThresholds are the traffic levels, as a share of the baseline, below
+// which the traffic status becomes Degraded, Throttled and Shutdown
+type TrafficThresholds struct {
+	Degraded  float64 `json:"degraded"`
+	Throttled float64 `json:"throttled"`
+	Shutdown  float64 `json:"shutdown"`
+}
+
+// DefaultTrafficThresholds returns the thresholds used when a profile sets none
+func DefaultTrafficThresholds() TrafficThresholds {
+	return TrafficThresholds{Degraded: 0.7, Throttled: 0.3, Shutdown: 0.1}
+}
+
+// Validate checks that the thresholds are ratios in decreasing order
+func (t TrafficThresholds) Validate() error {
+	if !(t.Degraded < 1 && t.Throttled < t.Degraded && t.Shutdown < t.Throttled && t.Shutdown > 0) {
+		return fmt.Errorf("traffic thresholds must satisfy 1 > degraded > throttled > shutdown > 0 (got %v, %v, %v)",
+			t.Degraded, t.Throttled, t.Shutdown)
+	}
+	return nil
+}
+
+// ProfileNames lists the embedded profiles
+func ProfileNames() []string {
+	entries, _ := profileFiles.ReadDir("profiles")
+	var names []string
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfile returns an embedded profile by name ("iran", "myanmar", ...) or
+// reads a community profile from a JSON file (any name ending in .json)
+func LoadProfile(name string) (*Profile, error) {
+	var data []byte
+	var err error
+	embedded := !strings.HasSuffix(name, ".json")
+	if embedded {
+		data, err = profileFiles.ReadFile(path.Join("profiles", strings.ToLower(name)+".json"))
+		if err != nil {
+			return nil, fmt.Errorf("unknown profile %q (built in: %s; community profiles are paths ending in .json)",
+				name, strings.Join(ProfileNames(), ", "))
+		}
+	} else if data, err = os.ReadFile(name); err != nil {
+		return nil, err
+	}
+
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	if len(p.Country) != 2 {
+		return nil, fmt.Errorf("profile %s: country must be an ISO 3166-1 alpha-2 code, got %q", name, p.Country)
+	}
+	p.Country = strings.ToUpper(p.Country)
+	if p.TrafficThresholds != nil {
+		if err := p.TrafficThresholds.Validate(); err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	// The Iranian target lists are long and maintained in Go
+	if embedded && p.Name == DefaultProfile {
+		p.ASNs = GetDefaultIranianASNs()
+		p.DNSServers = GetDefaultIranianDNSServers()
+	}
+	return &p, nil
+}
+
+// apply fills the settings the config leaves empty from the profile
+func (p *Profile) apply(c *Config) {
+	if c.Country == "" {
+		c.Country = p.Country
+	}
+	if c.CountryISO3 == "" && strings.EqualFold(c.Country, p.Country) {
+		c.CountryISO3 = p.ISO3
+	}
+	if c.CountryName == "" {
+		c.CountryName = p.CountryName
+	}
+	if c.Timezone == "" {
+		c.Timezone = p.Timezone
+	}
+	if c.Language == "" {
+		c.Language = p.Language
+	}
+	if len(c.IranASNs) == 0 {
+		c.IranASNs = p.ASNs
+	}
+	if len(c.DNSServers) == 0 {
+		c.DNSServers = p.DNSServers
+	}
+	if len(c.Provinces) == 0 {
+		c.Provinces = p.Provinces
+	}
+	if c.TrafficThresholds == nil && p.TrafficThresholds != nil {
+		thresholds := *p.TrafficThresholds
+		c.TrafficThresholds = &thresholds
+	}
+	if c.profileASNNames == nil {
+		c.profileASNNames = p.ASNNames
+	}
+}
+
+// ForCountry derives the config of a further country: its profile replaces the
+// targets, location, thresholds, timezone and language, and it posts to its own
+// channels; alert rules, escalation and the other notifiers stay with the
+// primary country, and history is kept in a file of its own (the Telegram
+// spool in a subdirectory of its own)
+func (c *Config) ForCountry(entry CountryConfig) (*Config, error) {
+	profile, err := LoadProfile(entry.Profile)
+	if err != nil {
+		return nil, err
+	}
+	country := *c
+	country.Profile = entry.Profile
+	country.Countries = nil
+	country.Country, country.CountryISO3, country.CountryName, country.Timezone, country.Language = "", "", "", "", ""
+	country.IranASNs, country.DNSServers, country.TrafficThresholds, country.Provinces = nil, nil, nil, nil
+	country.profileASNNames = nil // Names of its own profile only
+	country.MapBoundaries = entry.MapBoundaries
+	profile.apply(&country)
+	if country.CountryName == "" {
+		country.CountryName = country.Country
+	}
+	if country.Timezone == "" {
+		country.Timezone = "UTC"
+	}
+
+	country.TelegramChannel = ""
+	country.TelegramTopics = nil
+	country.TelegramChannels = entry.TelegramChannels
+	country.ChatPrefsPath = ""       // Subscribers talk to the primary country's bot
+	country.ConfirmIncidents = false // Confirmation buttons need the bot that polls for updates
+	country.HistoryDSN = ""
+	country.HistoryPath = entry.HistoryPath
+	if country.HistoryPath == "" {
+		country.HistoryPath = fmt.Sprintf("history-%s.json", strings.ToLower(country.Country))
+	}
+	if country.SigningKeyPath != "" {
+		country.EvidencePath = fmt.Sprintf("evidence-%s.jsonl", strings.ToLower(country.Country))
+	}
+	country.Rules, country.Routes, country.EscalationPolicies = nil, nil, nil
+	country.SLOs = nil // Providers are the primary country's
+	country.Matrix, country.Signal, country.SMS = nil, nil, nil
+	country.AggregatorURL, country.AggregatorProbes = "", nil
+	country.BundleDir = ""
+	country.Campaigns = nil // Their extra targets belong to the primary country
+	if c.ASNDiscovery != nil {
+		discovery := *c.ASNDiscovery
+		discovery.CachePath = "" // One cache per country
+		country.ASNDiscovery = &discovery
+	}
+	if c.ASNNameLookup != nil {
+		lookup := *c.ASNNameLookup
+		lookup.CachePath = "" // One cache per country
+		country.ASNNameLookup = &lookup
+	}
+	if c.TelegramSpool != nil {
+		spool := *c.TelegramSpool
+		spool.Dir = filepath.Join(spool.Dir, strings.ToLower(country.Country))
+		country.TelegramSpool = &spool
+	}
+	return &country, nil
+}
@@ -0,0 +1,216 @@
+// Package correlation merges signals of independent data sources into one
+// high-confidence incident when they occur close together
+package correlation
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Signal sources
+const (
+	SourceTraffic = "traffic" // Cloudflare Radar traffic drops
+	SourceBGP     = "bgp"     // ASN withdrawals seen on RIS Live
+	SourceDNS     = "dns"     // DNS availability drops and throttling
+	SourceIODA    = "ioda"    // IODA outage alerts
+)
+
+// sourceWeight is the confidence one critical signal of a source gives on its own
+var sourceWeight = map[string]float64{
+	SourceTraffic: 0.6,
+	SourceBGP:     0.5,
+	SourceDNS:     0.4,
+	SourceIODA:    0.6,
+}
+
+// sourceLabel names the sources in messages
+var sourceLabel = map[string]string{
+	SourceTraffic: "traffic",
+	SourceBGP:     "BGP",
+	SourceDNS:     "DNS",
+	SourceIODA:    "IODA",
+}
+
+// warningWeight scales the weight of warning-level signals
+const warningWeight = 0.75
+
+// Signal is one observation pointing at an outage
+type Signal struct {
+	Source   string
+	Time     time.Time
+	Severity string
+	Detail   string
+}
+
+// Engine keeps the signals of the last window and opens a correlated incident
+// once enough distinct sources agree
+type Engine struct {
+	window     time.Duration
+	minSources int
+	signals    []Signal
+	open       bool
+	sources    map[string]bool // Sources of the open incident
+}
+
+// New creates an engine from the config
+func New(cfg *config.CorrelationConfig) (*Engine, error) {
+	e := &Engine{window: 15 * time.Minute, minSources: 2}
+	if cfg.Window != "" {
+		window, err := time.ParseDuration(cfg.Window)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid correlation window %q", cfg.Window)
+		}
+		e.window = window
+	}
+	if cfg.MinSources != 0 {
+		if cfg.MinSources < 2 || cfg.MinSources > len(sourceWeight) {
+			return nil, fmt.Errorf("correlation min_sources must be between 2 and %d", len(sourceWeight))
+		}
+		e.minSources = cfg.MinSources
+	}
+	return e, nil
+}
+
+// Window returns how close signals must be to be correlated
+func (e *Engine) Window() time.Duration {
+	return e.window
+}
+
+// signalOf returns the signal an event stands for, if any
+// Only warnings and critical events point at an outage; recoveries and rule events do not
+func signalOf(event models.Event) (Signal, bool) {
+	if event.Severity == models.SeverityInfo || event.Resolved {
+		return Signal{}, false
+	}
+	var source string
+	switch event.Kind {
+	case "traffic":
+		source = SourceTraffic
+	case "asn", "prefix":
+		source = SourceBGP
+	case "dns":
+		source = SourceDNS
+	default:
+		return Signal{}, false
+	}
+	return Signal{Source: source, Time: event.Timestamp, Severity: event.Severity, Detail: event.Message}, true
+}
+
+// Correlate adds the signals of a cycle's events and of external sources, and
+// returns the events to report: while a correlated incident is open, the events
+// feeding it are merged into it instead of being reported one by one
+func (e *Engine) Correlate(now time.Time, events []models.Event, external []Signal) []models.Event {
+	var kept []models.Event
+	var merged []models.Event
+	for _, event := range events {
+		signal, ok := signalOf(event)
+		if !ok {
+			kept = append(kept, event)
+			continue
+		}
+		e.signals = append(e.signals, signal)
+		merged = append(merged, event)
+	}
+	e.signals = append(e.signals, external...)
+
+	// Forget signals older than the window
+	recent := e.signals[:0]
+	for _, signal := range e.signals {
+		if now.Sub(signal.Time) <= e.window {
+			recent = append(recent, signal)
+		}
+	}
+	e.signals = recent
+
+	bySource := make(map[string][]Signal)
+	for _, signal := range e.signals {
+		bySource[signal.Source] = append(bySource[signal.Source], signal)
+	}
+
+	switch {
+	case len(bySource) >= e.minSources:
+		grown := false
+		for source := range bySource {
+			if !e.sources[source] {
+				grown = true
+			}
+		}
+		if !e.open || grown {
+			title := "🧩 Correlated outage"
+			if e.open {
+				title = "🧩 Correlated outage confirmed by more sources"
+			}
+			if !e.open {
+				e.sources = make(map[string]bool)
+			}
+			for source := range bySource {
+				e.sources[source] = true
+			}
+			e.open = true
+			score := confidence(bySource)
+			log.Printf("🧩 Correlated outage across %d sources (confidence %.0f%%)", len(bySource), score*100)
+			kept = append(kept, models.Event{Timestamp: now, Kind: "correlated", Target: "IR", Severity: models.SeverityCritical,
+				Message: fmt.Sprintf("%s (confidence %.0f%%): %s", title, score*100, describe(bySource))})
+		}
+		// The incident stands for the signals of this cycle
+		return kept
+	case e.open && len(bySource) == 0:
+		e.open = false
+		e.sources = nil
+		kept = append(kept, models.Event{Timestamp: now, Kind: "correlated", Target: "IR", Severity: models.SeverityInfo, Resolved: true,
+			Message: fmt.Sprintf("🧩 Correlated outage over: no outage signals for %.0f min", e.window.Minutes())})
+	case e.open:
+		// Some sources still report the outage: keep merging their signals
+		return kept
+	}
+	return append(kept, merged...)
+}
+
+// confidence combines the signals per source: each source is independent
+// evidence, so the chance that all of them are false alarms is multiplied
+func confidence(bySource map[string][]Signal) float64 {
+	doubt := 1.0
+	for source, signals := range bySource {
+		weight := sourceWeight[source] * warningWeight
+		for _, signal := range signals {
+			if signal.Severity == models.SeverityCritical {
+				weight = sourceWeight[source]
+			}
+		}
+		doubt *= 1 - weight
+	}
+	return math.Round((1-doubt)*100) / 100
+}
+
+// describe summarizes the signals per source, strongest source first
+func describe(bySource map[string][]Signal) string {
+	sources := make([]string, 0, len(bySource))
+	for source := range bySource {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		if sourceWeight[sources[i]] != sourceWeight[sources[j]] {
+			return sourceWeight[sources[i]] > sourceWeight[sources[j]]
+		}
+		return sources[i] < sources[j]
+	})
+
+	parts := make([]string, 0, len(sources))
+	for _, source := range sources {
+		signals := bySource[source]
+		latest := signals[len(signals)-1]
+		part := fmt.Sprintf("%s: %s", sourceLabel[source], latest.Detail)
+		if len(signals) > 1 {
+			part += fmt.Sprintf(" (+%d more)", len(signals)-1)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
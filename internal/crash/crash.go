@@ -0,0 +1,157 @@
+// Package crash recovers panics of goroutines, keeps their stack traces on disk
+// and reports them to the operators, so a crash in a background loop or a
+// command handler is noticed instead of only being logged
+package crash
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/version"
+)
+
+const (
+	// DefaultDir is where crash reports are written when no directory is configured
+	DefaultDir = "crashes"
+	// NotifyGap is the minimum time between two notifications about the same goroutine
+	NotifyGap = 10 * time.Minute
+	// keepReports is how many report files are kept; older ones are removed
+	keepReports = 50
+)
+
+// Report is a recovered panic
+type Report struct {
+	Goroutine string    // Name of the goroutine that panicked, e.g. "telegram message"
+	Panic     string    // Value passed to panic
+	Stack     string    // Stack trace of the panicking goroutine
+	Build     string    // Version of the running build
+	Time      time.Time // When the panic was recovered
+	Path      string    // File the report was written to (empty if writing failed)
+}
+
+var (
+	mu         sync.Mutex
+	dir        = DefaultDir
+	notifier   func(report Report, suppressed int)
+	lastNotify = make(map[string]time.Time) // Last notification per goroutine
+	suppressed = make(map[string]int)       // Panics per goroutine not notified since the last notification
+)
+
+// SetDir sets the directory crash reports are written to ("" disables writing them)
+func SetDir(d string) {
+	mu.Lock()
+	defer mu.Unlock()
+	dir = d
+}
+
+// SetNotifier sets the function told about recovered panics, at most once per
+// NotifyGap and goroutine; suppressed is the number of panics of the goroutine
+// that were not notified since the previous notification
+func SetNotifier(fn func(report Report, suppressed int)) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifier = fn
+}
+
+// Go runs fn in a goroutine that reports a panic instead of crashing the process
+func Go(name string, fn func()) {
+	go func() {
+		defer Recover(name)
+		fn()
+	}()
+}
+
+// Recover reports a panic of the calling goroutine; use it as `defer crash.Recover(name)`
+func Recover(name string) {
+	if v := recover(); v != nil {
+		handle(name, v, debug.Stack())
+	}
+}
+
+// handle logs, writes and notifies a recovered panic
+func handle(name string, v interface{}, stack []byte) {
+	report := Report{
+		Goroutine: name,
+		Panic:     fmt.Sprint(v),
+		Stack:     string(stack),
+		Build:     version.String(),
+		Time:      time.Now(),
+	}
+	log.Printf("💥 Recovered panic in %s: %s\n%s", name, report.Panic, report.Stack)
+
+	mu.Lock()
+	reportDir := dir
+	notify := notifier
+	var missed int
+	if notify != nil {
+		if last, ok := lastNotify[name]; ok && report.Time.Sub(last) < NotifyGap {
+			suppressed[name]++
+			notify = nil
+		} else {
+			lastNotify[name] = report.Time
+			missed = suppressed[name]
+			delete(suppressed, name)
+		}
+	}
+	mu.Unlock()
+
+	if reportDir != "" {
+		path, err := write(reportDir, report)
+		if err != nil {
+			log.Printf("⚠️  Failed to write crash report: %v", err)
+		} else {
+			report.Path = path
+			log.Printf("💾 Crash report written to %s", path)
+		}
+	}
+	if notify != nil {
+		// A panicking notifier must not take the recovering goroutine down
+		defer func() {
+			if v := recover(); v != nil {
+				log.Printf("⚠️  Crash notifier panicked: %v", v)
+			}
+		}()
+		notify(report, missed)
+	}
+}
+
+// write stores a report as a text file in d and removes the oldest reports
+// beyond keepReports
+func write(d string, report Report) (string, error) {
+	if err := os.MkdirAll(d, 0o755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("crash-%s-%s.log", report.Time.UTC().Format("20060102-150405.000"), sanitize(report.Goroutine))
+	path := filepath.Join(d, name)
+	content := fmt.Sprintf("goroutine: %s\ntime: %s\nbuild: %s\npanic: %s\n\n%s",
+		report.Goroutine, report.Time.UTC().Format(time.RFC3339), report.Build, report.Panic, report.Stack)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+
+	old, err := filepath.Glob(filepath.Join(d, "crash-*.log"))
+	if err == nil && len(old) > keepReports {
+		sort.Strings(old) // Names start with the time, so this is oldest first
+		for _, p := range old[:len(old)-keepReports] {
+			os.Remove(p)
+		}
+	}
+	return path, nil
+}
+
+// sanitize makes a goroutine name usable in a file name
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name)
+}
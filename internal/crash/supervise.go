@@ -0,0 +1,82 @@
+package crash
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// Backoff between restarts of a supervised subsystem
+const (
+	minBackoff  = time.Second
+	maxBackoff  = 5 * time.Minute
+	stableAfter = 10 * time.Minute // A run this long resets the backoff
+)
+
+// Restart describes a supervised subsystem that stopped unexpectedly
+type Restart struct {
+	Subsystem string        // Name passed to Supervise, e.g. "monitor"
+	Reason    string        // "returned" or the panic value
+	Restarts  int           // Restarts of the subsystem so far, including this one
+	Backoff   time.Duration // Delay before the restart
+}
+
+var restartHandler func(Restart)
+
+// SetRestartHandler sets the function told about every restart of a supervised
+// subsystem (call before Supervise)
+func SetRestartHandler(fn func(Restart)) {
+	mu.Lock()
+	defer mu.Unlock()
+	restartHandler = fn
+}
+
+// Supervise runs fn until ctx is done, restarting it with exponential backoff
+// when it returns or panics before that; panics are reported like with Recover
+// It blocks until ctx is done
+func Supervise(ctx context.Context, name string, fn func(ctx context.Context)) {
+	backoff := minBackoff
+	restarts := 0
+	for {
+		start := time.Now()
+		reason := runSupervised(ctx, name, fn)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) >= stableAfter {
+			backoff = minBackoff
+		}
+		restarts++
+		log.Printf("🔁 %s stopped unexpectedly (%s), restarting in %v (restart %d)", name, reason, backoff, restarts)
+
+		mu.Lock()
+		handler := restartHandler
+		mu.Unlock()
+		if handler != nil {
+			handler(Restart{Subsystem: name, Reason: reason, Restarts: restarts, Backoff: backoff})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runSupervised runs fn once and describes why it stopped
+func runSupervised(ctx context.Context, name string, fn func(ctx context.Context)) (reason string) {
+	defer func() {
+		if v := recover(); v != nil {
+			handle(name, v, debug.Stack())
+			reason = fmt.Sprintf("panic: %v", v)
+		}
+	}()
+	fn(ctx)
+	return "returned"
+}
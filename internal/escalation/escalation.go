@@ -0,0 +1,271 @@
+// Package escalation re-notifies further actions while incidents stay unacknowledged
+package escalation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/notify"
+)
+
+// checkInterval is how often open incidents are checked for due escalation steps
+const checkInterval = 30 * time.Second
+
+// ErrUnknownIncident is returned when acknowledging an incident that is not open
+var ErrUnknownIncident = fmt.Errorf("no open incident with this ID")
+
+// Incident is a firing rule with an escalation policy
+type Incident struct {
+	ID        string     `json:"id"`
+	Rule      string     `json:"rule"`
+	Policy    string     `json:"policy"`
+	Severity  string     `json:"severity"`
+	Message   string     `json:"message"`
+	Narrative string     `json:"narrative,omitempty"` // Situation when the incident opened (critical rules only)
+	OpenedAt  time.Time  `json:"opened_at"`
+	Notified  int        `json:"notified_steps"`     // Escalation steps already notified
+	AckedBy   string     `json:"acked_by,omitempty"` // Telegram user or API caller that acknowledged
+	AckedAt   *time.Time `json:"acked_at,omitempty"`
+}
+
+// step is a parsed escalation step
+type step struct {
+	after   time.Duration
+	actions []string
+}
+
+// Manager opens an incident when a rule with an escalation policy fires and
+// notifies each policy step in turn until the incident is acknowledged or resolved
+type Manager struct {
+	mu         sync.Mutex
+	policies   map[string][]step
+	rulePolicy map[string]string    // Rule name -> policy name
+	incidents  map[string]*Incident // Open incidents by rule name
+	dispatch   func([]models.Event)
+	onChange   func([]Incident) // Called with all open incidents after each change
+}
+
+// New parses the escalation policies of the config; dispatch delivers events to their actions
+func New(cfg *config.Config, dispatch func([]models.Event)) (*Manager, error) {
+	m := &Manager{
+		policies:   make(map[string][]step),
+		rulePolicy: make(map[string]string),
+		incidents:  make(map[string]*Incident),
+		dispatch:   dispatch,
+	}
+	for _, policy := range cfg.EscalationPolicies {
+		if policy.Name == "" {
+			return nil, fmt.Errorf("escalation policy without a name")
+		}
+		var steps []step
+		for i, s := range policy.Steps {
+			after, err := time.ParseDuration(s.After)
+			if err != nil || after <= 0 {
+				return nil, fmt.Errorf("escalation policy %q: step %d: invalid after %q", policy.Name, i+1, s.After)
+			}
+			if len(s.Actions) == 0 {
+				return nil, fmt.Errorf("escalation policy %q: step %d has no actions", policy.Name, i+1)
+			}
+			steps = append(steps, step{after: after, actions: s.Actions})
+		}
+		sort.SliceStable(steps, func(i, j int) bool { return steps[i].after < steps[j].after })
+		m.policies[policy.Name] = steps
+	}
+	for _, rule := range cfg.Rules {
+		if rule.Escalation == "" {
+			continue
+		}
+		if _, ok := m.policies[rule.Escalation]; !ok {
+			return nil, fmt.Errorf("rule %q: unknown escalation policy %q", rule.Name, rule.Escalation)
+		}
+		m.rulePolicy[rule.Name] = rule.Escalation
+	}
+	return m, nil
+}
+
+// SetChangeHandler sets the function called with the open incidents after each change
+// (e.g. to share them with a separate API process)
+func (m *Manager) SetChangeHandler(handler func([]Incident)) {
+	m.onChange = handler
+}
+
+// HandleEvents opens and closes incidents for rule events, then dispatches the
+// events; it is used as the monitor's event handler
+func (m *Manager) HandleEvents(events []models.Event) {
+	changed := false
+	m.mu.Lock()
+	for i := range events {
+		event := &events[i]
+		policy, ok := m.rulePolicy[event.Target]
+		if event.Kind != "rule" || !ok {
+			continue
+		}
+		if incident, open := m.incidents[event.Target]; open {
+			// The rule engine only reports a firing rule again once it resolved
+			// The resolution also goes to every escalation step already notified,
+			// so paging services close their incident too
+			event.Incident = incident.ID
+			event.Actions = m.notifiedActions(incident, event.Actions)
+			delete(m.incidents, event.Target)
+			log.Printf("✅ Incident %s (%s) resolved", incident.ID, incident.Rule)
+			changed = true
+			continue
+		}
+		incident := &Incident{
+			ID:        newID(),
+			Rule:      event.Target,
+			Policy:    policy,
+			Severity:  event.Severity,
+			Message:   event.Message,
+			Narrative: event.Narrative,
+			OpenedAt:  event.Timestamp,
+		}
+		m.incidents[event.Target] = incident
+		event.Incident = incident.ID
+		log.Printf("🚨 Incident %s opened for rule %s (escalation policy %s)", incident.ID, incident.Rule, policy)
+		changed = true
+	}
+	m.mu.Unlock()
+
+	m.dispatch(events)
+	if changed {
+		m.notifyChange()
+	}
+}
+
+// notifiedActions returns the rule's actions plus those of the notified steps of an incident
+func (m *Manager) notifiedActions(incident *Incident, actions []string) []string {
+	if len(actions) == 0 {
+		actions = []string{notify.ActionTelegram}
+	}
+	merged := append([]string(nil), actions...)
+	for _, s := range m.policies[incident.Policy][:incident.Notified] {
+		for _, action := range s.actions {
+			known := false
+			for _, a := range merged {
+				known = known || a == action
+			}
+			if !known {
+				merged = append(merged, action)
+			}
+		}
+	}
+	return merged
+}
+
+// Run notifies due escalation steps until the context is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.escalate(now)
+		}
+	}
+}
+
+// escalate dispatches the steps of unacknowledged incidents that are due at now
+func (m *Manager) escalate(now time.Time) {
+	var events []models.Event
+	m.mu.Lock()
+	for _, incident := range m.incidents {
+		steps := m.policies[incident.Policy]
+		for incident.AckedAt == nil && incident.Notified < len(steps) && now.Sub(incident.OpenedAt) >= steps[incident.Notified].after {
+			s := steps[incident.Notified]
+			incident.Notified++
+			events = append(events, models.Event{
+				Timestamp: now,
+				Kind:      "rule",
+				Target:    incident.Rule,
+				Severity:  incident.Severity,
+				Message:   fmt.Sprintf("⏫ Unacknowledged for %s: %s", formatAfter(s.after), incident.Message),
+				Actions:   s.actions,
+				Incident:  incident.ID,
+				Narrative: incident.Narrative,
+			})
+			log.Printf("⏫ Escalating incident %s (%s) to %v", incident.ID, incident.Rule, s.actions)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(events) > 0 {
+		m.dispatch(events)
+		m.notifyChange()
+	}
+}
+
+// Ack acknowledges an open incident, stopping its escalation
+func (m *Manager) Ack(id, by string) error {
+	m.mu.Lock()
+	var found *Incident
+	for _, incident := range m.incidents {
+		if incident.ID == id {
+			found = incident
+		}
+	}
+	if found == nil {
+		m.mu.Unlock()
+		return ErrUnknownIncident
+	}
+	if found.AckedAt == nil {
+		now := time.Now()
+		found.AckedAt = &now
+		found.AckedBy = by
+		log.Printf("👍 Incident %s (%s) acknowledged by %s", found.ID, found.Rule, by)
+	}
+	m.mu.Unlock()
+
+	m.notifyChange()
+	return nil
+}
+
+// Incidents returns the open incidents, oldest first
+func (m *Manager) Incidents() []Incident {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Incident, 0, len(m.incidents))
+	for _, incident := range m.incidents {
+		list = append(list, *incident)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].OpenedAt.Before(list[j].OpenedAt) })
+	return list
+}
+
+func (m *Manager) notifyChange() {
+	if m.onChange != nil {
+		m.onChange(m.Incidents())
+	}
+}
+
+// formatAfter formats a step delay without zero units ("15m", "1h")
+func formatAfter(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// newID returns a short random incident ID
+func newID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,212 @@
+package evidence
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is one signed measurement (a MonitoringResult or an event) in the evidence log
+// SHA256 covers the previous record's hash and the payload, chaining records so that
+// removing or editing any of them breaks every later hash
+type Record struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Kind      string          `json:"kind"` // "result" or "event"
+	PrevHash  string          `json:"prev_hash"`
+	SHA256    string          `json:"sha256"`
+	Signature string          `json:"signature"` // Ed25519 signature of the SHA256 digest, base64
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Log appends signed records to a JSON Lines file
+type Log struct {
+	path     string
+	key      ed25519.PrivateKey
+	mu       sync.Mutex
+	lastHash string
+}
+
+// Open opens the evidence log at path, signing with the Ed25519 key at keyPath
+// A missing key file is created with a new random key (mode 0600)
+func Open(path, keyPath string) (*Log, error) {
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{path: path, key: key}
+	records, err := l.read(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		l.lastHash = records[len(records)-1].SHA256
+	}
+	return l, nil
+}
+
+// loadOrCreateKey reads a hex-encoded Ed25519 seed, generating one if the file doesn't exist
+func loadOrCreateKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := hex.DecodeString(strings.TrimSpace(string(raw)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("invalid signing key in %s: expected %d hex-encoded bytes", path, ed25519.SeedSize)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write signing key: %w", err)
+	}
+	return key, nil
+}
+
+// PublicKey returns the base64-encoded public key used to verify records
+func (l *Log) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(l.key.Public().(ed25519.PublicKey))
+}
+
+// Append signs v (encoded as JSON) and appends it to the log
+func (l *Log) Append(kind string, timestamp time.Time, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s record: %w", kind, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	digest := chainDigest(l.lastHash, payload)
+	record := Record{
+		Timestamp: timestamp.UTC(),
+		Kind:      kind,
+		PrevHash:  l.lastHash,
+		SHA256:    hex.EncodeToString(digest),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(l.key, digest)),
+		Payload:   payload,
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open evidence log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write evidence log: %w", err)
+	}
+
+	l.lastHash = record.SHA256
+	return nil
+}
+
+// Sign returns the hex SHA-256 of data and its base64 Ed25519 signature
+func (l *Log) Sign(data []byte) (string, string) {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), base64.StdEncoding.EncodeToString(ed25519.Sign(l.key, digest[:]))
+}
+
+// Records returns the records logged since the given time, oldest first
+func (l *Log) Records(since time.Time) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.read(since)
+}
+
+// read parses the log file (caller holds the lock or has exclusive access)
+func (l *Log) read(since time.Time) ([]Record, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open evidence log: %w", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20) // Results can be large
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("corrupt evidence log line: %w", err)
+		}
+		if !record.Timestamp.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// Verify checks a record's hash chain link and signature against a base64 public key
+func Verify(publicKey string, record Record) error {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+	digest := chainDigest(record.PrevHash, record.Payload)
+	if hex.EncodeToString(digest) != record.SHA256 {
+		return errors.New("hash mismatch: payload or chain was altered")
+	}
+	sig, err := base64.StdEncoding.DecodeString(record.Signature)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(pub), digest, sig) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// SignDetached signs data with the Ed25519 key at keyPath (created if missing)
+// and returns the base64 signature of its SHA-256 digest and the base64 public key
+func SignDetached(keyPath string, data []byte) (string, string, error) {
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return "", "", err
+	}
+	digest := sha256.Sum256(data)
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, digest[:])),
+		base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)), nil
+}
+
+// VerifyDetached checks a signature made with SignDetached against a base64 public key
+func VerifyDetached(publicKey string, data []byte, signature string) error {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	digest := sha256.Sum256(data)
+	if err != nil || !ed25519.Verify(ed25519.PublicKey(pub), digest[:], sig) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// chainDigest hashes the previous record's hash followed by the payload
+func chainDigest(prevHash string, payload []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,40 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Annotate adds an operator annotation and writes it to the backend
+// Annotations are kept for the retention window like the buckets
+func (s *Store) Annotate(note models.Annotation) error {
+	note.Time = note.Time.UTC().Truncate(time.Second)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data.Notes = append(s.data.Notes, note)
+	sort.SliceStable(s.data.Notes, func(i, j int) bool { return s.data.Notes[i].Time.Before(s.data.Notes[j].Time) })
+
+	touched := newChanges()
+	touched.notes = []models.Annotation{note}
+	cutoff := time.Now().Add(-s.retention)
+	s.prune(cutoff)
+	return s.persist(touched, cutoff)
+}
+
+// Annotations returns the annotations since the given time, oldest first
+func (s *Store) Annotations(since time.Time) []models.Annotation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var notes []models.Annotation
+	for _, note := range s.data.Notes {
+		if !note.Time.Before(since) {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
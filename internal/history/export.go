@@ -0,0 +1,165 @@
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// ExportData is the JSON export document of a history window
+type ExportData struct {
+	GeneratedAt time.Time               `json:"generated_at"`
+	Since       time.Time               `json:"since"`
+	Traffic     []TrafficPoint          `json:"traffic"`
+	ASN         map[string][]Bucket     `json:"asn"`
+	DNS         map[string]DNSHistory   `json:"dns"`
+	ASNShare    map[string][]SharePoint `json:"asn_share"`
+	Annotations []models.Annotation     `json:"annotations,omitempty"`
+	Evidence    *Evidence               `json:"evidence,omitempty"`
+}
+
+// Evidence lists the signed measurement records covering an export window
+type Evidence struct {
+	PublicKey string       `json:"public_key"` // Base64 Ed25519 key that verifies the signatures
+	Records   []RecordHash `json:"records"`
+}
+
+// RecordHash identifies one signed record of the evidence log
+type RecordHash struct {
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`
+	SHA256    string    `json:"sha256"`
+	Signature string    `json:"signature"`
+}
+
+// DNSHistory is the exported history of a single DNS server
+type DNSHistory struct {
+	Name    string   `json:"name"`
+	Buckets []Bucket `json:"buckets"`
+}
+
+// Export writes all history since the given time as JSON or CSV
+// CSV rows are: kind,target,name,timestamp,value,up,total where value is the
+// traffic level (percent of peak), the availability ratio (0-1) or, for kind
+// asn_share, the ASN's percent of national traffic; annotations are rows
+// kind=annotation,target=<author>,name=<text>
+// With evidence, signed record hashes are included; in CSV as rows
+// kind=record,target=<record kind>,name=<signature>,value=<sha256>
+func (s *Store) Export(format string, since time.Time, evidence *Evidence) ([]byte, error) {
+	doc := ExportData{
+		GeneratedAt: time.Now().UTC(),
+		Since:       since.UTC(),
+		Traffic:     s.Traffic(since),
+		ASN:         s.ASNAvailability(since),
+		DNS:         make(map[string]DNSHistory),
+		ASNShare:    s.ASNShare(since),
+		Annotations: s.Annotations(since),
+		Evidence:    evidence,
+	}
+	for key, buckets := range s.DNSAvailability(since) {
+		doc.DNS[key] = DNSHistory{Name: s.DNSName(key), Buckets: buckets}
+	}
+
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case FormatCSV:
+		return exportCSV(doc)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (use json or csv)", format)
+	}
+}
+
+func exportCSV(doc ExportData) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"kind", "target", "name", "timestamp", "value", "up", "total"}); err != nil {
+		return nil, err
+	}
+
+	for _, p := range doc.Traffic {
+		if err := w.Write([]string{"traffic", "IR", "", p.Timestamp.Format(time.RFC3339),
+			strconv.FormatFloat(p.Level, 'f', 2, 64), "", ""}); err != nil {
+			return nil, err
+		}
+	}
+
+	writeBuckets := func(kind, target, name string, buckets []Bucket) error {
+		for _, b := range buckets {
+			if err := w.Write([]string{kind, target, name, b.Hour.UTC().Format(time.RFC3339),
+				strconv.FormatFloat(b.Ratio(), 'f', 4, 64), strconv.Itoa(b.Up), strconv.Itoa(b.Total)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, asn := range sortedKeys(doc.ASN) {
+		if err := writeBuckets("asn", asn, "", doc.ASN[asn]); err != nil {
+			return nil, err
+		}
+	}
+	dnsKeys := make([]string, 0, len(doc.DNS))
+	for key := range doc.DNS {
+		dnsKeys = append(dnsKeys, key)
+	}
+	sort.Strings(dnsKeys)
+	for _, key := range dnsKeys {
+		entry := doc.DNS[key]
+		if err := writeBuckets("dns", key, entry.Name, entry.Buckets); err != nil {
+			return nil, err
+		}
+	}
+
+	shareASNs := make([]string, 0, len(doc.ASNShare))
+	for asn := range doc.ASNShare {
+		shareASNs = append(shareASNs, asn)
+	}
+	sort.Strings(shareASNs)
+	for _, asn := range shareASNs {
+		for _, p := range doc.ASNShare[asn] {
+			if err := w.Write([]string{"asn_share", asn, "", p.Timestamp.Format(time.RFC3339),
+				strconv.FormatFloat(p.Share, 'f', 2, 64), "", ""}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, note := range doc.Annotations {
+		if err := w.Write([]string{"annotation", note.Author, note.Text, note.Time.UTC().Format(time.RFC3339), "", "", ""}); err != nil {
+			return nil, err
+		}
+	}
+
+	if doc.Evidence != nil {
+		for _, r := range doc.Evidence.Records {
+			if err := w.Write([]string{"record", r.Kind, r.Signature, r.Timestamp.UTC().Format(time.RFC3339), r.SHA256, "", ""}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func sortedKeys(m map[string][]Bucket) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
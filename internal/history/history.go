@@ -0,0 +1,448 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// DefaultRetention is how long hourly buckets are kept when no retention is configured
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Bucket aggregates all samples of a single target taken within one hour
+type Bucket struct {
+	Hour    time.Time `json:"hour"`
+	Up      int       `json:"up"`
+	Total   int       `json:"total"`
+	Latency float64   `json:"latency_ms,omitempty"` // Mean response time of the up DNS samples
+}
+
+// Ratio returns the fraction of samples in the bucket that were up (0-1)
+func (b Bucket) Ratio() float64 {
+	if b.Total == 0 {
+		return 0
+	}
+	return float64(b.Up) / float64(b.Total)
+}
+
+// TrafficPoint is a single hourly traffic level (percentage of window peak)
+type TrafficPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     float64   `json:"level"`
+}
+
+// storeData is the on-disk representation of the history file
+// Buckets are keyed by target, then by the Unix time of the hour they cover
+type storeData struct {
+	Version  int                          `json:"version"` // File format version (see fileMigrations)
+	ASN      map[string]map[int64]*Bucket `json:"asn"`
+	DNS      map[string]map[int64]*Bucket `json:"dns"`
+	Labels   map[string]string            `json:"labels"` // DNS key -> server name
+	Traffic  map[int64]float64            `json:"traffic"`
+	Share    map[string]map[int64]float64 `json:"asn_share"`              // ASN -> hour -> percent of national traffic
+	Notes    []models.Annotation          `json:"annotations,omitempty"`  // Operator annotations, oldest first
+	Failures map[string]map[int64]int     `json:"dns_failures,omitempty"` // DNS error code -> hour -> failed or anomalous checks
+}
+
+// Store keeps hourly availability buckets and traffic points on disk (a JSON
+// file or PostgreSQL) so charts can cover days of data instead of only the latest snapshot
+type Store struct {
+	path      string
+	retention time.Duration
+	mu        sync.RWMutex
+	data      *storeData
+	db        *sql.DB // PostgreSQL backend (nil for the JSON file)
+	dbDirty   bool    // Last PostgreSQL write failed; rewrite everything next time
+}
+
+// Open loads the history file at path, creating an empty store if it doesn't exist
+func Open(path string, retention time.Duration) (*Store, error) {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	s := &Store{
+		path:      path,
+		retention: retention,
+		data:      newStoreData(),
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	s.data.Version = 0 // Files written before versioning have no version field
+	if err := json.Unmarshal(raw, s.data); err != nil {
+		return nil, fmt.Errorf("failed to parse history file %s: %w", path, err)
+	}
+	// Older or hand-edited files may miss sections
+	if s.data.ASN == nil {
+		s.data.ASN = make(map[string]map[int64]*Bucket)
+	}
+	if s.data.DNS == nil {
+		s.data.DNS = make(map[string]map[int64]*Bucket)
+	}
+	if s.data.Labels == nil {
+		s.data.Labels = make(map[string]string)
+	}
+	if s.data.Traffic == nil {
+		s.data.Traffic = make(map[int64]float64)
+	}
+	if s.data.Share == nil {
+		s.data.Share = make(map[string]map[int64]float64)
+	}
+	if s.data.Failures == nil {
+		s.data.Failures = make(map[string]map[int64]int)
+	}
+
+	upgraded, err := migrateFile(s.data)
+	if err != nil {
+		return nil, err
+	}
+	if upgraded {
+		if err := s.save(); err != nil {
+			return nil, fmt.Errorf("failed to write upgraded history file: %w", err)
+		}
+	}
+
+	return s, nil
+}
+
+func newStoreData() *storeData {
+	return &storeData{
+		Version:  fileVersion,
+		ASN:      make(map[string]map[int64]*Bucket),
+		DNS:      make(map[string]map[int64]*Bucket),
+		Labels:   make(map[string]string),
+		Traffic:  make(map[int64]float64),
+		Share:    make(map[string]map[int64]float64),
+		Failures: make(map[string]map[int64]int),
+	}
+}
+
+// Close releases the database connection of a PostgreSQL store
+func (s *Store) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// Record adds a monitoring result to the history and writes it to the backend
+func (s *Store) Record(result *models.MonitoringResult) error {
+	if result == nil {
+		return nil
+	}
+
+	hour := result.Timestamp.UTC().Truncate(time.Hour)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	touched := newChanges()
+	for asn, status := range result.ASNStatuses {
+		addSample(s.data.ASN, asn, hour, status.Connected, 0)
+		touched.asn[asn] = append(touched.asn[asn], hour.Unix())
+	}
+	for key, status := range result.DNSStatuses {
+		// The raw sample: availability history shows what the checks saw, before flap damping
+		var latency time.Duration
+		if status.Sample {
+			latency = status.ResponseTime
+		}
+		addSample(s.data.DNS, key, hour, status.Sample, latency)
+		s.data.Labels[key] = status.Name
+		touched.dns[key] = append(touched.dns[key], hour.Unix())
+		if status.ErrorCode != "" {
+			code := string(status.ErrorCode)
+			if s.data.Failures[code] == nil {
+				s.data.Failures[code] = make(map[int64]int)
+			}
+			s.data.Failures[code][hour.Unix()]++
+			touched.failures[code] = append(touched.failures[code], hour.Unix())
+		}
+	}
+
+	// Radar returns hourly points; later fetches overwrite the same hour
+	if result.TrafficData != nil {
+		td := result.TrafficData
+		for i, ts := range td.Timestamps {
+			if i >= len(td.Trend24h) {
+				break
+			}
+			s.data.Traffic[ts.UTC().Truncate(time.Hour).Unix()] = td.Trend24h[i]
+			touched.traffic = append(touched.traffic, ts.UTC().Truncate(time.Hour).Unix())
+		}
+	}
+
+	// Shares are fetched less often than the cycle runs; the latest fetch of an hour wins
+	for _, item := range result.ASTrafficData {
+		if s.data.Share[item.ASN] == nil {
+			s.data.Share[item.ASN] = make(map[int64]float64)
+		}
+		s.data.Share[item.ASN][hour.Unix()] = item.Percentage
+		touched.share[item.ASN] = append(touched.share[item.ASN], hour.Unix())
+	}
+
+	cutoff := time.Now().Add(-s.retention)
+	s.prune(cutoff)
+
+	return s.persist(touched, cutoff)
+}
+
+// persist writes changes to the backend (caller holds the lock)
+func (s *Store) persist(touched *changes, cutoff time.Time) error {
+	if s.db != nil {
+		return s.savePostgres(touched, cutoff)
+	}
+	return s.save()
+}
+
+func addSample(targets map[string]map[int64]*Bucket, key string, hour time.Time, up bool, latency time.Duration) {
+	buckets, ok := targets[key]
+	if !ok {
+		buckets = make(map[int64]*Bucket)
+		targets[key] = buckets
+	}
+	b, ok := buckets[hour.Unix()]
+	if !ok {
+		b = &Bucket{Hour: hour}
+		buckets[hour.Unix()] = b
+	}
+	b.Total++
+	if up {
+		b.Up++
+		if latency > 0 {
+			b.Latency += (float64(latency)/float64(time.Millisecond) - b.Latency) / float64(b.Up)
+		}
+	}
+}
+
+// prune drops everything older than cutoff (caller holds the lock)
+func (s *Store) prune(cutoff time.Time) {
+	limit := cutoff.Unix()
+	for _, targets := range []map[string]map[int64]*Bucket{s.data.ASN, s.data.DNS} {
+		for key, buckets := range targets {
+			for hour := range buckets {
+				if hour < limit {
+					delete(buckets, hour)
+				}
+			}
+			if len(buckets) == 0 {
+				delete(targets, key)
+				delete(s.data.Labels, key)
+			}
+		}
+	}
+	for hour := range s.data.Traffic {
+		if hour < limit {
+			delete(s.data.Traffic, hour)
+		}
+	}
+	for asn, shares := range s.data.Share {
+		for hour := range shares {
+			if hour < limit {
+				delete(shares, hour)
+			}
+		}
+		if len(shares) == 0 {
+			delete(s.data.Share, asn)
+		}
+	}
+	for code, counts := range s.data.Failures {
+		for hour := range counts {
+			if hour < limit {
+				delete(counts, hour)
+			}
+		}
+		if len(counts) == 0 {
+			delete(s.data.Failures, code)
+		}
+	}
+	kept := s.data.Notes[:0]
+	for _, note := range s.data.Notes {
+		if !note.Time.Before(cutoff) {
+			kept = append(kept, note)
+		}
+	}
+	s.data.Notes = kept
+}
+
+// save writes the history atomically (temp file + rename) so a crash
+// mid-write never leaves a truncated file behind (caller holds the lock)
+func (s *Store) save() error {
+	raw, err := json.Marshal(s.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode history: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp history file: %w", err)
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// ASNAvailability returns hourly buckets per ASN since the given time, oldest first
+func (s *Store) ASNAvailability(since time.Time) map[string][]Bucket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return collect(s.data.ASN, since)
+}
+
+// DNSAvailability returns hourly buckets per DNS server key since the given time, oldest first
+func (s *Store) DNSAvailability(since time.Time) map[string][]Bucket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return collect(s.data.DNS, since)
+}
+
+// DNSName returns the server name recorded for a DNS key
+func (s *Store) DNSName(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data.Labels[key]
+}
+
+func collect(targets map[string]map[int64]*Bucket, since time.Time) map[string][]Bucket {
+	limit := since.Unix()
+	result := make(map[string][]Bucket, len(targets))
+	for key, buckets := range targets {
+		list := make([]Bucket, 0, len(buckets))
+		for hour, b := range buckets {
+			if hour >= limit {
+				list = append(list, *b)
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Hour.Before(list[j].Hour) })
+		result[key] = list
+	}
+	return result
+}
+
+// Traffic returns the stored hourly traffic levels since the given time, oldest first
+func (s *Store) Traffic(since time.Time) []TrafficPoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limit := since.Unix()
+	points := make([]TrafficPoint, 0, len(s.data.Traffic))
+	for hour, level := range s.data.Traffic {
+		if hour >= limit {
+			points = append(points, TrafficPoint{Timestamp: time.Unix(hour, 0).UTC(), Level: level})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+	return points
+}
+
+// SharePoint is an ASN's share of national traffic (percent) in one hour
+type SharePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Share     float64   `json:"share"`
+}
+
+// ASNShare returns the stored hourly traffic shares per ASN since the given time, oldest first
+func (s *Store) ASNShare(since time.Time) map[string][]SharePoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limit := since.Unix()
+	result := make(map[string][]SharePoint, len(s.data.Share))
+	for asn, shares := range s.data.Share {
+		var points []SharePoint
+		for hour, share := range shares {
+			if hour >= limit {
+				points = append(points, SharePoint{Timestamp: time.Unix(hour, 0).UTC(), Share: share})
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+		sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+		result[asn] = points
+	}
+	return result
+}
+
+// FailureBucket counts the failed and anomalous DNS checks of one hour or day by error code
+type FailureBucket struct {
+	Start  time.Time                   `json:"start"`
+	Counts map[models.DNSErrorCode]int `json:"counts"`
+	Total  int                         `json:"total"`
+}
+
+// DNSFailures returns the DNS failure counts since the given time in buckets of
+// step (an hour or a day, UTC), oldest first; buckets without failures are left out
+func (s *Store) DNSFailures(since time.Time, step time.Duration) []FailureBucket {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	limit := since.Unix()
+	byStart := make(map[int64]*FailureBucket)
+	for code, counts := range s.data.Failures {
+		for hour, count := range counts {
+			if hour < limit {
+				continue
+			}
+			start := time.Unix(hour, 0).UTC().Truncate(step)
+			b, ok := byStart[start.Unix()]
+			if !ok {
+				b = &FailureBucket{Start: start, Counts: make(map[models.DNSErrorCode]int)}
+				byStart[start.Unix()] = b
+			}
+			b.Counts[models.DNSErrorCode(code)] += count
+			b.Total += count
+		}
+	}
+	buckets := make([]FailureBucket, 0, len(byStart))
+	for _, b := range byStart {
+		buckets = append(buckets, *b)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Start.Before(buckets[j].Start) })
+	return buckets
+}
+
+// HourlyRatios lays buckets out on a fixed hourly grid starting at start
+// Hours without samples are reported as -1 so callers can tell "no data" from "down"
+func HourlyRatios(buckets []Bucket, start time.Time, hours int) []float64 {
+	byHour := make(map[int64]Bucket, len(buckets))
+	for _, b := range buckets {
+		byHour[b.Hour.UTC().Truncate(time.Hour).Unix()] = b
+	}
+
+	ratios := make([]float64, hours)
+	startHour := start.UTC().Truncate(time.Hour)
+	for h := 0; h < hours; h++ {
+		b, ok := byHour[startHour.Add(time.Duration(h)*time.Hour).Unix()]
+		if !ok || b.Total == 0 {
+			ratios[h] = -1
+			continue
+		}
+		ratios[h] = b.Ratio()
+	}
+	return ratios
+}
@@ -0,0 +1,65 @@
+package history
+
+import (
+	"time"
+)
+
+// ImportTraffic adds historical traffic levels (e.g. from a backfill) without
+// overwriting hours that already have data; it returns how many hours were added
+func (s *Store) ImportTraffic(points []TrafficPoint) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	touched := newChanges()
+	for _, p := range points {
+		hour := p.Timestamp.UTC().Truncate(time.Hour)
+		if hour.Before(cutoff) {
+			continue
+		}
+		if _, ok := s.data.Traffic[hour.Unix()]; ok {
+			continue
+		}
+		s.data.Traffic[hour.Unix()] = p.Level
+		touched.traffic = append(touched.traffic, hour.Unix())
+	}
+	if len(touched.traffic) == 0 {
+		return 0, nil
+	}
+	return len(touched.traffic), s.persist(touched, cutoff)
+}
+
+// ImportASNAvailability adds historical hourly buckets of one ASN without
+// overwriting hours that already have samples; it returns how many hours were added
+func (s *Store) ImportASNAvailability(asn string, buckets []Bucket) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-s.retention)
+	touched := newChanges()
+	for _, b := range buckets {
+		hour := b.Hour.UTC().Truncate(time.Hour)
+		if hour.Before(cutoff) || b.Total == 0 {
+			continue
+		}
+		existing, ok := s.data.ASN[asn]
+		if !ok {
+			existing = make(map[int64]*Bucket)
+			s.data.ASN[asn] = existing
+		}
+		if _, ok := existing[hour.Unix()]; ok {
+			continue
+		}
+		existing[hour.Unix()] = &Bucket{Hour: hour, Up: b.Up, Total: b.Total}
+		touched.asn[asn] = append(touched.asn[asn], hour.Unix())
+	}
+	if len(touched.asn[asn]) == 0 {
+		return 0, nil
+	}
+	return len(touched.asn[asn]), s.persist(touched, cutoff)
+}
+
+// Retention returns how far back the store keeps data
+func (s *Store) Retention() time.Duration {
+	return s.retention
+}
@@ -0,0 +1,138 @@
+package history
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Schema migrations run at startup for whichever backend is configured, so
+// upgrading netblocks upgrades the stored history automatically. A store
+// written by a newer version is refused instead of being silently rewritten.
+
+// PostgreSQL migrations are golang-migrate files named
+// <version>_<name>.up.sql and .down.sql; golang-migrate applies them in
+// version order under an advisory lock and records the version in
+// schema_migrations
+//
+//go:embed migrations/*.sql
+var postgresMigrations embed.FS
+
+// migratePostgres applies all pending migrations; it opens its own
+// connection, which golang-migrate closes when done
+func migratePostgres(dsn string) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	source, err := iofs.New(postgresMigrations, "migrations")
+	if err != nil {
+		db.Close()
+		return err
+	}
+	driver, err := pgxmigrate.WithInstance(db, &pgxmigrate.Config{})
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "pgx5", driver)
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("failed to prepare migrations: %w", err)
+	}
+	defer m.Close()
+
+	known, err := latestMigration(source)
+	if err != nil {
+		return err
+	}
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("history schema migration %d failed halfway; repair it and mark it with migrate force", version)
+	}
+	if version > known {
+		return fmt.Errorf("history schema version %d is newer than this build supports (%d); upgrade netblocks", version, known)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("history migration failed: %w", err)
+	}
+	if applied, _, err := m.Version(); err == nil && applied != version {
+		log.Printf("🗄  Applied history migrations up to version %d", applied)
+	}
+	return nil
+}
+
+// latestMigration returns the version of the newest embedded migration
+func latestMigration(source interface {
+	First() (uint, error)
+	Next(uint) (uint, error)
+}) (uint, error) {
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("no history migrations: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// fileMigrations upgrade the JSON history file; fileMigrations[i] upgrades
+// version i to i+1. Files written before versioning are version 0.
+var fileMigrations = []func(data *storeData){
+	// 0 -> 1: adds the version field; the layout is unchanged
+	func(data *storeData) {},
+	// 1 -> 2: adds per-ASN traffic shares
+	func(data *storeData) {
+		if data.Share == nil {
+			data.Share = make(map[string]map[int64]float64)
+		}
+	},
+	// 2 -> 3: adds operator annotations; files without any stay valid
+	func(data *storeData) {},
+	// 3 -> 4: adds DNS failure counts by error code
+	func(data *storeData) {
+		if data.Failures == nil {
+			data.Failures = make(map[string]map[int64]int)
+		}
+	},
+	// 4 -> 5: adds the mean DNS response time to the buckets; older buckets have none
+	func(data *storeData) {},
+}
+
+// fileVersion is the current JSON history file format version
+var fileVersion = len(fileMigrations)
+
+// migrateFile upgrades a loaded history file to the current format
+// It reports whether anything changed so the caller can rewrite the file
+func migrateFile(data *storeData) (bool, error) {
+	if data.Version > fileVersion {
+		return false, fmt.Errorf("history file version %d is newer than this build supports (%d); upgrade netblocks", data.Version, fileVersion)
+	}
+	if data.Version == fileVersion {
+		return false, nil
+	}
+	for v := data.Version; v < fileVersion; v++ {
+		fileMigrations[v](data)
+		log.Printf("🗄  Upgraded history file to version %d", v+1)
+	}
+	data.Version = fileVersion
+	return true, nil
+}
@@ -0,0 +1,5 @@
+-- Drops the initial history schema
+
+DROP TABLE IF EXISTS traffic;
+DROP TABLE IF EXISTS dns_labels;
+DROP TABLE IF EXISTS availability;
@@ -0,0 +1,22 @@
+-- Initial history schema: hourly availability buckets, DNS labels and traffic levels
+
+CREATE TABLE IF NOT EXISTS availability (
+    kind   TEXT        NOT NULL, -- 'asn' or 'dns'
+    target TEXT        NOT NULL, -- ASN or DNS server key
+    hour   TIMESTAMPTZ NOT NULL, -- Start of the hour the samples cover
+    up     INTEGER     NOT NULL,
+    total  INTEGER     NOT NULL,
+    PRIMARY KEY (kind, target, hour)
+);
+
+CREATE INDEX IF NOT EXISTS availability_hour_idx ON availability (hour);
+
+CREATE TABLE IF NOT EXISTS dns_labels (
+    target TEXT PRIMARY KEY, -- DNS server key
+    name   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS traffic (
+    hour  TIMESTAMPTZ PRIMARY KEY,
+    level DOUBLE PRECISION NOT NULL -- Percent of window peak
+);
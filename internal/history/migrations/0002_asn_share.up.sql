@@ -0,0 +1,10 @@
+-- Hourly share of national traffic per ASN
+
+CREATE TABLE IF NOT EXISTS asn_share (
+    asn   TEXT             NOT NULL,
+    hour  TIMESTAMPTZ      NOT NULL, -- Start of the hour the share was fetched in
+    share DOUBLE PRECISION NOT NULL, -- Percent of national traffic
+    PRIMARY KEY (asn, hour)
+);
+
+CREATE INDEX IF NOT EXISTS asn_share_hour_idx ON asn_share (hour);
@@ -0,0 +1,8 @@
+-- Operator annotations of points in time
+
+CREATE TABLE IF NOT EXISTS annotations (
+    at     TIMESTAMPTZ NOT NULL, -- Time the note refers to
+    author TEXT        NOT NULL, -- Telegram user or "api[:<name>]"
+    text   TEXT        NOT NULL,
+    PRIMARY KEY (at, author)
+);
@@ -0,0 +1,10 @@
+-- Hourly counts of failed and anomalous DNS checks by error code
+
+CREATE TABLE IF NOT EXISTS dns_failures (
+    code  TEXT        NOT NULL, -- DNS error code, e.g. TIMEOUT or INJECTED_ANSWER
+    hour  TIMESTAMPTZ NOT NULL, -- Start of the hour the checks ran in
+    count INTEGER     NOT NULL,
+    PRIMARY KEY (code, hour)
+);
+
+CREATE INDEX IF NOT EXISTS dns_failures_hour_idx ON dns_failures (hour);
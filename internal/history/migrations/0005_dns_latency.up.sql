@@ -0,0 +1,3 @@
+-- Mean response time of the answered DNS checks of each hour, in milliseconds (0 for ASNs)
+
+ALTER TABLE availability ADD COLUMN IF NOT EXISTS latency_ms DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS annotations_at_idx;
+-- Keep the latest note of each author and second, as the (at, author) key allows one
+DELETE FROM annotations a USING annotations b WHERE a.at = b.at AND a.author = b.author AND a.id < b.id;
+ALTER TABLE annotations DROP COLUMN IF EXISTS id;
+ALTER TABLE annotations ADD PRIMARY KEY (at, author);
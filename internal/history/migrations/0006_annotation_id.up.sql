@@ -0,0 +1,6 @@
+-- Annotations are keyed by a serial ID, so one author may add several in the same second
+
+ALTER TABLE annotations DROP CONSTRAINT IF EXISTS annotations_pkey;
+ALTER TABLE annotations ADD COLUMN IF NOT EXISTS id BIGSERIAL PRIMARY KEY;
+
+CREATE INDEX IF NOT EXISTS annotations_at_idx ON annotations (at);
@@ -0,0 +1,319 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // Registers the "pgx" database/sql driver
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// OpenPostgres opens a history store kept in PostgreSQL (dsn is a postgres:// URL)
+// The database is the source of truth and can be read concurrently by other
+// tools; the store keeps an in-memory copy of the retention window for reads
+func OpenPostgres(dsn string, retention time.Duration) (*Store, error) {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history_dsn: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := migratePostgres(dsn); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &Store{
+		retention: retention,
+		data:      newStoreData(),
+		db:        db,
+	}
+	if err := s.loadPostgres(time.Now().Add(-retention)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// queryRows runs a query and calls scan for each row
+func (s *Store) queryRows(scan func(rows *sql.Rows) error, query string, args ...any) error {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// loadPostgres reads the retention window into memory
+func (s *Store) loadPostgres(since time.Time) error {
+	err := s.queryRows(func(rows *sql.Rows) error {
+		var kind, target string
+		var hour int64
+		var b Bucket
+		if err := rows.Scan(&kind, &target, &hour, &b.Up, &b.Total, &b.Latency); err != nil {
+			return err
+		}
+		targets := s.data.ASN
+		if kind == "dns" {
+			targets = s.data.DNS
+		}
+		if targets[target] == nil {
+			targets[target] = make(map[int64]*Bucket)
+		}
+		b.Hour = time.Unix(hour, 0).UTC()
+		targets[target][hour] = &b
+		return nil
+	}, `SELECT kind, target, EXTRACT(EPOCH FROM hour)::BIGINT, up, total, latency_ms FROM availability WHERE hour >= $1`, since)
+	if err != nil {
+		return fmt.Errorf("failed to load availability history: %w", err)
+	}
+
+	err = s.queryRows(func(rows *sql.Rows) error {
+		var target, name string
+		if err := rows.Scan(&target, &name); err != nil {
+			return err
+		}
+		s.data.Labels[target] = name
+		return nil
+	}, `SELECT target, name FROM dns_labels`)
+	if err != nil {
+		return fmt.Errorf("failed to load DNS labels: %w", err)
+	}
+
+	err = s.queryRows(func(rows *sql.Rows) error {
+		var hour int64
+		var level float64
+		if err := rows.Scan(&hour, &level); err != nil {
+			return err
+		}
+		s.data.Traffic[hour] = level
+		return nil
+	}, `SELECT EXTRACT(EPOCH FROM hour)::BIGINT, level FROM traffic WHERE hour >= $1`, since)
+	if err != nil {
+		return fmt.Errorf("failed to load traffic history: %w", err)
+	}
+
+	err = s.queryRows(func(rows *sql.Rows) error {
+		var asn string
+		var hour int64
+		var share float64
+		if err := rows.Scan(&asn, &hour, &share); err != nil {
+			return err
+		}
+		if s.data.Share[asn] == nil {
+			s.data.Share[asn] = make(map[int64]float64)
+		}
+		s.data.Share[asn][hour] = share
+		return nil
+	}, `SELECT asn, EXTRACT(EPOCH FROM hour)::BIGINT, share FROM asn_share WHERE hour >= $1`, since)
+	if err != nil {
+		return fmt.Errorf("failed to load ASN share history: %w", err)
+	}
+
+	err = s.queryRows(func(rows *sql.Rows) error {
+		var code string
+		var hour int64
+		var count int
+		if err := rows.Scan(&code, &hour, &count); err != nil {
+			return err
+		}
+		if s.data.Failures[code] == nil {
+			s.data.Failures[code] = make(map[int64]int)
+		}
+		s.data.Failures[code][hour] = count
+		return nil
+	}, `SELECT code, EXTRACT(EPOCH FROM hour)::BIGINT, count FROM dns_failures WHERE hour >= $1`, since)
+	if err != nil {
+		return fmt.Errorf("failed to load DNS failure history: %w", err)
+	}
+
+	err = s.queryRows(func(rows *sql.Rows) error {
+		var at int64
+		var note models.Annotation
+		if err := rows.Scan(&at, &note.Author, &note.Text); err != nil {
+			return err
+		}
+		note.Time = time.Unix(at, 0).UTC()
+		s.data.Notes = append(s.data.Notes, note)
+		return nil
+	}, `SELECT EXTRACT(EPOCH FROM at)::BIGINT, author, text FROM annotations WHERE at >= $1 ORDER BY at`, since)
+	if err != nil {
+		return fmt.Errorf("failed to load annotations: %w", err)
+	}
+	return nil
+}
+
+// savePostgres writes the buckets and traffic points touched by one record in a
+// transaction and prunes rows older than cutoff (caller holds the lock)
+// After a failed write the next call rewrites the whole window
+func (s *Store) savePostgres(touched *changes, cutoff time.Time) error {
+	if s.dbDirty {
+		touched = allChanges(s.data)
+	}
+
+	tx, err := s.db.Begin()
+	if err == nil {
+		if err = writePostgres(tx, s.data, touched, cutoff); err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}
+	if err != nil {
+		s.dbDirty = true
+		return fmt.Errorf("failed to write history to postgres: %w", err)
+	}
+	s.dbDirty = false
+	return nil
+}
+
+func writePostgres(tx *sql.Tx, data *storeData, touched *changes, cutoff time.Time) error {
+	for _, target := range []struct {
+		kind    string
+		keys    map[string][]int64
+		buckets map[string]map[int64]*Bucket
+	}{
+		{"asn", touched.asn, data.ASN},
+		{"dns", touched.dns, data.DNS},
+	} {
+		for key, hours := range target.keys {
+			for _, hour := range hours {
+				b, ok := target.buckets[key][hour]
+				if !ok {
+					continue
+				}
+				if _, err := tx.Exec(`INSERT INTO availability (kind, target, hour, up, total, latency_ms) VALUES ($1, $2, $3, $4, $5, $6)
+					ON CONFLICT (kind, target, hour) DO UPDATE SET up = EXCLUDED.up, total = EXCLUDED.total, latency_ms = EXCLUDED.latency_ms`,
+					target.kind, key, b.Hour, b.Up, b.Total, b.Latency); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for key := range touched.dns {
+		if _, err := tx.Exec(`INSERT INTO dns_labels (target, name) VALUES ($1, $2)
+			ON CONFLICT (target) DO UPDATE SET name = EXCLUDED.name`, key, data.Labels[key]); err != nil {
+			return err
+		}
+	}
+	for _, hour := range touched.traffic {
+		level, ok := data.Traffic[hour]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO traffic (hour, level) VALUES ($1, $2)
+			ON CONFLICT (hour) DO UPDATE SET level = EXCLUDED.level`, time.Unix(hour, 0), level); err != nil {
+			return err
+		}
+	}
+	for asn, hours := range touched.share {
+		for _, hour := range hours {
+			share, ok := data.Share[asn][hour]
+			if !ok {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO asn_share (asn, hour, share) VALUES ($1, $2, $3)
+				ON CONFLICT (asn, hour) DO UPDATE SET share = EXCLUDED.share`, asn, time.Unix(hour, 0), share); err != nil {
+				return err
+			}
+		}
+	}
+
+	for code, hours := range touched.failures {
+		for _, hour := range hours {
+			count, ok := data.Failures[code][hour]
+			if !ok {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT INTO dns_failures (code, hour, count) VALUES ($1, $2, $3)
+				ON CONFLICT (code, hour) DO UPDATE SET count = EXCLUDED.count`, code, time.Unix(hour, 0), count); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Annotations have no natural key: a rewrite replaces those of the window
+	if touched.all {
+		if _, err := tx.Exec(`DELETE FROM annotations WHERE at >= $1`, cutoff); err != nil {
+			return err
+		}
+	}
+	for _, note := range touched.notes {
+		if _, err := tx.Exec(`INSERT INTO annotations (at, author, text) VALUES ($1, $2, $3)`, note.Time, note.Author, note.Text); err != nil {
+			return err
+		}
+	}
+
+	for _, prune := range []string{
+		`DELETE FROM availability WHERE hour < $1`,
+		`DELETE FROM traffic WHERE hour < $1`,
+		`DELETE FROM asn_share WHERE hour < $1`,
+		`DELETE FROM dns_failures WHERE hour < $1`,
+		`DELETE FROM annotations WHERE at < $1`,
+	} {
+		if _, err := tx.Exec(prune, cutoff); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(`DELETE FROM dns_labels WHERE target NOT IN (SELECT target FROM availability WHERE kind = 'dns')`)
+	return err
+}
+
+// changes lists the bucket hours (Unix) written by one record, per target, and new annotations
+type changes struct {
+	asn      map[string][]int64
+	dns      map[string][]int64
+	traffic  []int64
+	share    map[string][]int64
+	failures map[string][]int64 // By DNS error code
+	notes    []models.Annotation
+	all      bool // Everything held in memory, see allChanges
+}
+
+func newChanges() *changes {
+	return &changes{asn: make(map[string][]int64), dns: make(map[string][]int64), share: make(map[string][]int64), failures: make(map[string][]int64)}
+}
+
+// allChanges lists every bucket, traffic point and annotation held in memory
+func allChanges(data *storeData) *changes {
+	all := newChanges()
+	all.all = true
+	for key, buckets := range data.ASN {
+		for hour := range buckets {
+			all.asn[key] = append(all.asn[key], hour)
+		}
+	}
+	for key, buckets := range data.DNS {
+		for hour := range buckets {
+			all.dns[key] = append(all.dns[key], hour)
+		}
+	}
+	for hour := range data.Traffic {
+		all.traffic = append(all.traffic, hour)
+	}
+	for asn, shares := range data.Share {
+		for hour := range shares {
+			all.share[asn] = append(all.share[asn], hour)
+		}
+	}
+	for code, counts := range data.Failures {
+		for hour := range counts {
+			all.failures[code] = append(all.failures[code], hour)
+		}
+	}
+	all.notes = append(all.notes, data.Notes...)
+	return all
+}
@@ -0,0 +1,138 @@
+// Package hook runs user-provided executables that read JSON on stdin and write
+// JSON to stdout, so checks and notifiers can be added in any language
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/pkg/checker"
+)
+
+// Hook stages
+const (
+	StageCheck = "check" // Run each cycle with the result; prints check outcomes
+	StageEvent = "event" // Run with events routed to the hook's action
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+	maxOutputBytes = 1 << 20
+	maxStderrBytes = 512
+)
+
+// Hook is a configured executable
+type Hook struct {
+	name    string
+	stage   string
+	command []string
+	timeout time.Duration
+}
+
+// New validates a hook's config
+func New(cfg config.HookConfig) (*Hook, error) {
+	if cfg.Name == "" || len(cfg.Command) == 0 {
+		return nil, fmt.Errorf("hooks need a name and a command")
+	}
+	if cfg.Stage != StageCheck && cfg.Stage != StageEvent {
+		return nil, fmt.Errorf("hook %q: stage must be %q or %q", cfg.Name, StageCheck, StageEvent)
+	}
+	h := &Hook{name: cfg.Name, stage: cfg.Stage, command: cfg.Command, timeout: defaultTimeout}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("hook %q: invalid timeout %q", cfg.Name, cfg.Timeout)
+		}
+		h.timeout = timeout
+	}
+	return h, nil
+}
+
+// Load returns the hooks of a stage
+func Load(hooks []config.HookConfig, stage string) ([]*Hook, error) {
+	var loaded []*Hook
+	for _, cfg := range hooks {
+		h, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if h.stage == stage {
+			loaded = append(loaded, h)
+		}
+	}
+	return loaded, nil
+}
+
+// Name returns the hook's name
+func (h *Hook) Name() string {
+	return h.name
+}
+
+// Run writes input as JSON to the hook's stdin and decodes its stdout into
+// output (ignored if nil); a non-zero exit fails with the end of its stderr
+func (h *Hook) Run(ctx context.Context, input, output interface{}) error {
+	stdin, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("hook %s: failed to encode input: %w", h.name, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, h.command[0], h.command[1:]...)
+	cmd.Env = append(os.Environ(), "NETBLOCKS_HOOK="+h.name, "NETBLOCKS_HOOK_STAGE="+h.stage)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &stdout, max: maxOutputBytes}
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("hook %s: timed out after %s", h.name, h.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > maxStderrBytes {
+			msg = "…" + msg[len(msg)-maxStderrBytes:]
+		}
+		return fmt.Errorf("hook %s: %v: %s", h.name, err, msg)
+	}
+	if output == nil {
+		return nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), output); err != nil {
+		return fmt.Errorf("hook %s: invalid JSON output: %w", h.name, err)
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first max bytes written to it and drops the rest
+type limitedBuffer struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if room := l.max - l.buf.Len(); room > 0 {
+		if len(p) > room {
+			l.buf.Write(p[:room])
+		} else {
+			l.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// Check runs a check hook as a checker: the input is its stdin and it prints
+// the outcomes as a JSON array
+func (h *Hook) Check(ctx context.Context, input checker.Input) ([]checker.Outcome, error) {
+	var outcomes []checker.Outcome
+	if err := h.Run(ctx, input, &outcomes); err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
@@ -0,0 +1,100 @@
+package i18n
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// persianDigits is whether Persian output uses Persian digits (persian_digits)
+var persianDigits atomic.Bool
+
+// SetPersianDigits makes Persian output use Persian digits and separators
+// (۱۲٬۳۴۵٫۶) instead of Latin ones
+func SetPersianDigits(enabled bool) {
+	persianDigits.Store(enabled)
+}
+
+// Locale formats numbers, percentages and times for a language
+type Locale struct {
+	lang   string
+	native bool // Persian digits and separators
+}
+
+// For returns the locale of a configured language
+func For(lang string) Locale {
+	lang = Normalize(lang)
+	return Locale{lang: lang, native: lang == Persian && persianDigits.Load()}
+}
+
+// Lang returns the language of the locale, for T
+func (l Locale) Lang() string {
+	return l.lang
+}
+
+// Latin returns the locale with Latin digits and separators, for images
+// drawn with fonts that have no Persian digits
+func (l Locale) Latin() Locale {
+	l.native = false
+	return l
+}
+
+// Number formats a number with the given decimals and grouped thousands
+func (l Locale) Number(v float64, decimals int) string {
+	text := strconv.FormatFloat(v, 'f', decimals, 64)
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(text, ".")
+	var builder strings.Builder
+	builder.WriteString(sign)
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			builder.WriteString(l.separator(",", "٬"))
+		}
+		builder.WriteRune(digit)
+	}
+	if hasFraction {
+		builder.WriteString(l.separator(".", "٫"))
+		builder.WriteString(fraction)
+	}
+	return l.Digits(builder.String())
+}
+
+// Int formats an integer with grouped thousands
+func (l Locale) Int(n int) string {
+	return l.Number(float64(n), 0)
+}
+
+// Percent formats a percentage, e.g. 12.5% or ۱۲٫۵٪
+func (l Locale) Percent(v float64, decimals int) string {
+	return l.Number(v, decimals) + l.separator("%", "٪")
+}
+
+// Time formats a time with a time.Format layout
+func (l Locale) Time(t time.Time, layout string) string {
+	return l.Digits(t.Format(layout))
+}
+
+// Digits replaces the Latin digits of already formatted text, e.g. "3/5"
+func (l Locale) Digits(text string) string {
+	if !l.native {
+		return text
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return '۰' + (r - '0')
+		}
+		return r
+	}, text)
+}
+
+// separator returns the Latin or the Persian form of a separator
+func (l Locale) separator(latin, native string) string {
+	if l.native {
+		return native
+	}
+	return latin
+}
@@ -0,0 +1,131 @@
+// Package i18n translates the headings of posts and CLI output and formats
+// numbers, percentages and times for their language
+package i18n
+
+import "strings"
+
+// Supported languages
+const (
+	English = "en"
+	Persian = "fa"
+)
+
+// translations maps English headings to their translations
+// Only headings and labels are translated; ASN/DNS names stay as configured
+var translations = map[string]map[string]string{
+	Persian: {
+		"NetBlocks Monitoring Status":            "وضعیت پایش نت‌بلاکس",
+		"Last Update":                            "آخرین به‌روزرسانی",
+		"National Score":                         "امتیاز ملی",
+		"Normal":                                 "عادی",
+		"Degraded":                               "کاهش کیفیت",
+		"Severe Disruption":                      "اختلال شدید",
+		"Shutdown":                               "قطعی",
+		"ASN Connectivity":                       "اتصال شبکه‌ها (ASN)",
+		"Last seen":                              "آخرین مشاهده",
+		"Never":                                  "هرگز",
+		"valid":                                  "معتبر",
+		"invalid":                                "نامعتبر",
+		"not found":                              "بدون ROA",
+		"Flapping":                               "ناپایدار",
+		"changes":                                "تغییر",
+		"Digest":                                 "گزارش دوره‌ای",
+		"SLO Report":                             "گزارش تعهد سطح خدمت (SLO)",
+		"availability":                           "دسترس‌پذیری",
+		"latency":                                "تأخیر",
+		"objective":                              "هدف",
+		"not enough checks yet":                  "هنوز بررسی کافی انجام نشده",
+		"DNS servers":                            "سرور DNS",
+		"Summary":                                "خلاصه",
+		"Connected":                              "متصل",
+		"External Reference":                     "مرجع خارجی",
+		"not counted in the summary":             "در خلاصه شمرده نمی‌شود",
+		"DNS Servers Status":                     "وضعیت سرورهای DNS",
+		"Alive":                                  "فعال",
+		"Top %s Iranian ASNs by Traffic":         "%s شبکه برتر ایران بر اساس ترافیک",
+		"of total traffic":                       "از کل ترافیک",
+		"up from %s to %s in %sh":                "افزایش از %s به %s در %s ساعت",
+		"down from %s to %s in %sh":              "کاهش از %s به %s در %s ساعت",
+		"Critical Network Alert":                 "هشدار بحرانی شبکه",
+		"%s network change(s) since last update": "%s تغییر شبکه از آخرین به‌روزرسانی",
+		"No change in the last %s":               "بدون تغییر در %s گذشته",
+		"ASN / DNS Availability - Last 7 Days":   "دسترس‌پذیری ASN / DNS - هفت روز اخیر",
+		"Each row is an ASN or a city's DNS servers, each column one hour (UTC)": "هر ردیف یک ASN یا سرورهای DNS یک شهر و هر ستون یک ساعت (UTC) است",
+
+		// Traffic caption
+		"Traffic Level":             "سطح ترافیک",
+		"Change":                    "تغییر",
+		"Status":                    "وضعیت",
+		"Updated":                   "به‌روزرسانی",
+		"%s ago":                    "%s پیش",
+		"%s secs":                   "%s ثانیه",
+		"%s mins":                   "%s دقیقه",
+		"%s hours":                  "%s ساعت",
+		"%s days":                   "%s روز",
+		"Throttled":                 "محدودشده",
+		"Traffic data unavailable":  "داده ترافیک در دسترس نیست",
+		"MAJOR DISRUPTION DETECTED": "اختلال گسترده شناسایی شد",
+
+		// Confidence of critical alerts (confirm_incidents)
+		"Possible Network Disruption":                                                    "اختلال احتمالی شبکه",
+		"Confirmed Network Disruption":                                                   "اختلال تأییدشده شبکه",
+		"Unconfirmed: detected automatically, pending verification (ref %s)":             "تأییدنشده: به‌طور خودکار شناسایی شده و در انتظار بررسی است (شناسه %s)",
+		"The disruption reported at %s has been confirmed (ref %s)":                      "اختلال گزارش‌شده در ساعت %s تأیید شد (شناسه %s)",
+		"Update: the possible disruption reported at %s could not be confirmed (ref %s)": "به‌روزرسانی: اختلال احتمالی گزارش‌شده در ساعت %s تأیید نشد (شناسه %s)",
+
+		// Province map
+		"Connectivity by Province": "اتصال به تفکیک استان",
+		"Share of each province's DNS servers alive; gray provinces have none": "سهم سرورهای DNS فعال هر استان؛ استان‌های خاکستری سروری ندارند",
+
+		// Digest sparklines
+		"ASN Availability - Last 24h": "دسترس‌پذیری شبکه‌ها (ASN) - ۲۴ ساعت گذشته",
+
+		// Plain status for screen readers
+		"NetBlocks status at %s UTC.":                            "وضعیت نت‌بلاکس در %s به وقت UTC.",
+		"National score is %s of %s, %s.":                        "امتیاز ملی %s از %s است، %s.",
+		"Internet traffic is at %s of normal, %s.":               "ترافیک اینترنت %s حالت عادی است، %s.",
+		"Traffic is rising.":                                     "ترافیک رو به افزایش است.",
+		"Traffic is falling.":                                    "ترافیک رو به کاهش است.",
+		"%s of %s networks are connected.":                       "%s شبکه از %s شبکه متصل است.",
+		"Disconnected: %s.":                                      "قطع: %s.",
+		"Many routes withdrawn: %s.":                             "برداشت گسترده مسیرها: %s.",
+		"Repeatedly connecting and disconnecting: %s.":           "قطع و وصل پیاپی: %s.",
+		"Announcements failing RPKI validation: %s.":             "اعلان‌های نامعتبر در اعتبارسنجی RPKI: %s.",
+		"%s of %s DNS servers answer.":                           "%s سرور DNS از %s سرور پاسخ می‌دهد.",
+		"Not answering: %s.":                                     "بدون پاسخ: %s.",
+		"%s of %s RIPE Atlas anchors are reachable from abroad.": "%s لنگر RIPE Atlas از %s لنگر از خارج در دسترس است.",
+		"Measurement campaign %s is running.":                    "کارزار اندازه‌گیری %s در جریان است.",
+		"Telegram API reachable from the monitoring host: yes.":  "API تلگرام از میزبان پایش در دسترس است: بله.",
+		"Telegram API reachable from the monitoring host: no.":   "API تلگرام از میزبان پایش در دسترس است: خیر.",
+		"%s of %s Telegram endpoint checks succeeded.":           "%s بررسی از %s بررسی نقاط دسترسی تلگرام موفق بود.",
+		"and %s more": "و %s مورد دیگر",
+
+		// Followed prefixes (CLI)
+		"Prefixes":                 "پیشوندهای IP",
+		"%s/%s RIS peers":          "%s از %s همتای RIS",
+		"no announcement seen yet": "هنوز اعلانی دیده نشده",
+		"withdrawn %s":             "برداشته‌شده در %s",
+
+		// Telegram reachability (CLI)
+		"Telegram":                         "تلگرام",
+		"Bot API from the monitoring host": "API ربات از میزبان پایش",
+		"answered in %s":                   "پاسخ در %s",
+	},
+}
+
+// Normalize maps a configured language to a supported one, defaulting to English
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if _, ok := translations[lang]; ok {
+		return lang
+	}
+	return English
+}
+
+// T returns the translation of an English heading, or the heading itself
+func T(lang, text string) string {
+	if translated, ok := translations[lang][text]; ok {
+		return translated
+	}
+	return text
+}
@@ -0,0 +1,333 @@
+// Package matrix mirrors the Telegram output (status posts and alerts) into a Matrix room
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// ActionMatrix routes events to the Matrix room
+const ActionMatrix = "matrix"
+
+// Room content profiles, as for Telegram channels
+const (
+	profileFull   = "full"   // Status posts and alerts
+	profileAlerts = "alerts" // Alerts only
+)
+
+// defaultInterval matches the status post interval of Telegram channels
+const defaultInterval = 19 * time.Minute
+
+// maxListed bounds the down ASNs/DNS servers named in a status post
+const maxListed = 15
+
+// Client posts to one Matrix room through the client-server API
+type Client struct {
+	homeserver string
+	token      string
+	roomID     string
+	profile    string
+	interval   time.Duration
+	txn        atomic.Int64 // Transaction ID counter (combined with the start time)
+	started    int64
+	httpClient *http.Client
+}
+
+// New creates a client for the configured room
+func New(cfg *config.MatrixConfig) (*Client, error) {
+	if cfg.Homeserver == "" || cfg.AccessToken == "" || cfg.RoomID == "" {
+		return nil, fmt.Errorf("matrix needs homeserver, access_token and room_id")
+	}
+	profile := strings.ToLower(cfg.Profile)
+	switch profile {
+	case "":
+		profile = profileFull
+	case profileFull, profileAlerts:
+	default:
+		return nil, fmt.Errorf("unknown matrix profile %q", cfg.Profile)
+	}
+	interval := defaultInterval
+	if cfg.Interval != "" {
+		parsed, err := time.ParseDuration(cfg.Interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid matrix interval %q", cfg.Interval)
+		}
+		interval = parsed
+	}
+	return &Client{
+		homeserver: strings.TrimSuffix(cfg.Homeserver, "/"),
+		token:      cfg.AccessToken,
+		roomID:     cfg.RoomID,
+		profile:    profile,
+		interval:   interval,
+		started:    time.Now().UnixNano(),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Name returns the action name of the notifier
+func (c *Client) Name() string {
+	return ActionMatrix
+}
+
+// Notify posts events as one alert message
+func (c *Client) Notify(ctx context.Context, events []models.Event) error {
+	title := "🔔 *Network Changes*"
+	for _, event := range events {
+		if event.Severity == models.SeverityCritical {
+			title = "🚨 *Critical Network Alert*"
+		}
+	}
+	var builder strings.Builder
+	builder.WriteString(title + "\n\n")
+	for _, event := range events {
+		builder.WriteString(fmt.Sprintf("%s %s `%s`", monitor.SeverityEmoji(event.Severity), event.Message, event.Timestamp.Format("15:04")))
+		if event.Incident != "" {
+			builder.WriteString(fmt.Sprintf(" (incident `%s`)", event.Incident))
+		}
+		builder.WriteString("\n")
+	}
+	for _, narrative := range models.Narratives(events) {
+		builder.WriteString("\n📝 " + narrative + "\n")
+	}
+	return c.sendText(ctx, builder.String())
+}
+
+// Run posts a status every interval until the context is cancelled (full profile only)
+func (c *Client) Run(ctx context.Context, results func() (*models.MonitoringResult, error)) {
+	if c.profile != profileFull {
+		log.Printf("✅ Matrix room %s receives alerts only", c.roomID)
+		return
+	}
+	log.Printf("✅ Matrix status posts will be sent every %v to %s", c.interval, c.roomID)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := results(); err != nil {
+			log.Printf("⚠️  Matrix status skipped: %v", err)
+		} else if err := c.PostStatus(ctx, result); err != nil {
+			log.Printf("⚠️  Failed to post status to Matrix: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PostStatus posts the status image, a summary of what is down and the traffic chart
+func (c *Client) PostStatus(ctx context.Context, result *models.MonitoringResult) error {
+	header := fmt.Sprintf("📊 *NetBlocks Monitoring Status*\n⏰ Last Update: `%s`\n", result.Timestamp.Format("2006-01-02 15:04:05"))
+	status, emoji := monitor.ScoreStatus(result.NationalScore)
+	header += fmt.Sprintf("%s *National Score:* %.0f/100 (%s)\n", emoji, result.NationalScore, status)
+	header += monitor.DescribeStatus(result)
+
+	if result.StatusImage != nil && result.StatusImage.Len() > 0 {
+		if err := c.sendImage(ctx, "status.png", result.StatusImage.Bytes(), monitor.DescribeStatus(result)); err != nil {
+			return err
+		}
+	}
+	if err := c.sendText(ctx, header+"\n\n"+formatOutages(result)); err != nil {
+		return err
+	}
+	if data := result.TrafficData; data != nil && data.ChartBuffer != nil && data.ChartBuffer.Len() > 0 {
+		if err := c.sendImage(ctx, "iran_traffic_24h.png", data.ChartBuffer.Bytes(), "Last 24h: "+monitor.DescribeSeries(data.Trend24h)); err != nil {
+			return err
+		}
+		return c.sendText(ctx, monitor.FormatTrafficStatus(data, i18n.English))
+	}
+	return nil
+}
+
+// formatOutages lists the disconnected ASNs and unreachable DNS servers
+func formatOutages(result *models.MonitoringResult) string {
+	var down []string
+	for asn, status := range result.ASNStatuses {
+		if !status.Connected {
+			down = append(down, fmt.Sprintf("%s (%s)", status.Name, asn))
+		}
+	}
+	var dead []string
+	for _, status := range result.DNSStatuses {
+		if !status.Alive {
+			dead = append(dead, status.Name)
+		}
+	}
+	sort.Strings(down)
+	sort.Strings(dead)
+
+	var builder strings.Builder
+	writeList := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		builder.WriteString(fmt.Sprintf("*%s (%d):*\n", title, len(items)))
+		for i, item := range items {
+			if i >= maxListed {
+				builder.WriteString(fmt.Sprintf("…and %d more\n", len(items)-maxListed))
+				break
+			}
+			builder.WriteString("🔴 " + item + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	writeList("Disconnected ASNs", down)
+	writeList("Unreachable DNS servers", dead)
+	if builder.Len() == 0 {
+		return "🟢 All monitored ASNs and DNS servers are up."
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+// sendText posts a message written in the bot's Markdown subset (*bold*, `code`)
+func (c *Client) sendText(ctx context.Context, text string) error {
+	return c.sendEvent(ctx, map[string]interface{}{
+		"msgtype":        "m.text",
+		"body":           plainText(text),
+		"format":         "org.matrix.custom.html",
+		"formatted_body": markdownToHTML(text),
+	})
+}
+
+// sendImage uploads a PNG and posts it; the body doubles as alt text
+func (c *Client) sendImage(ctx context.Context, name string, data []byte, altText string) error {
+	var upload struct {
+		ContentURI string `json:"content_uri"`
+	}
+	endpoint := c.homeserver + "/_matrix/media/v3/upload?filename=" + url.QueryEscape(name)
+	if err := c.request(ctx, http.MethodPost, endpoint, "image/png", data, &upload); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	return c.sendEvent(ctx, map[string]interface{}{
+		"msgtype": "m.image",
+		"body":    altText,
+		"url":     upload.ContentURI,
+		"info":    map[string]interface{}{"mimetype": "image/png", "size": len(data)},
+	})
+}
+
+// sendEvent sends an m.room.message event to the room
+func (c *Client) sendEvent(ctx context.Context, content map[string]interface{}) error {
+	body, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	txnID := strconv.FormatInt(c.started, 36) + "-" + strconv.FormatInt(c.txn.Add(1), 10)
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s", c.homeserver, url.PathEscape(c.roomID), txnID)
+	return c.request(ctx, http.MethodPut, endpoint, "application/json", body, nil)
+}
+
+// request performs an authenticated API call, waiting once if rate limited
+func (c *Client) request(ctx context.Context, method, endpoint, contentType string, body []byte, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		req.Header.Set("Content-Type", contentType)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			var limited struct {
+				RetryAfterMs int64 `json:"retry_after_ms"`
+			}
+			_ = json.Unmarshal(data, &limited)
+			wait := time.Duration(limited.RetryAfterMs) * time.Millisecond
+			if wait <= 0 || wait > time.Minute {
+				wait = 5 * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			var apiErr struct {
+				Code  string `json:"errcode"`
+				Error string `json:"error"`
+			}
+			_ = json.Unmarshal(data, &apiErr)
+			return fmt.Errorf("matrix API status %d: %s %s", resp.StatusCode, apiErr.Code, apiErr.Error)
+		}
+		if out != nil {
+			return json.Unmarshal(data, out)
+		}
+		return nil
+	}
+}
+
+// markdownToHTML converts the bot's Markdown subset to Matrix HTML
+func markdownToHTML(text string) string {
+	var builder strings.Builder
+	bold, code := false, false
+	for _, r := range text {
+		switch {
+		case r == '`':
+			code = !code
+			if code {
+				builder.WriteString("<code>")
+			} else {
+				builder.WriteString("</code>")
+			}
+		case r == '*' && !code:
+			bold = !bold
+			if bold {
+				builder.WriteString("<b>")
+			} else {
+				builder.WriteString("</b>")
+			}
+		case r == '\n':
+			builder.WriteString("<br>")
+		case r == '<':
+			builder.WriteString("&lt;")
+		case r == '>':
+			builder.WriteString("&gt;")
+		case r == '&':
+			builder.WriteString("&amp;")
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	if code {
+		builder.WriteString("</code>")
+	}
+	if bold {
+		builder.WriteString("</b>")
+	}
+	return builder.String()
+}
+
+// plainText strips the Markdown markers for clients without HTML support
+func plainText(text string) string {
+	return strings.NewReplacer("*", "", "`", "").Replace(text)
+}
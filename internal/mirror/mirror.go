@@ -0,0 +1,128 @@
+// Package mirror pulls the published snapshots of a primary instance, so that
+// read-only public mirrors can serve and post them without data source credentials
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// snapshotPath is appended to primary base URLs (it matches server.SnapshotPath)
+const snapshotPath = "/api/v1/snapshot"
+
+// maxSnapshotBytes bounds a downloaded snapshot (results with all charts are a few MB)
+const maxSnapshotBytes = 32 << 20
+
+// Client keeps the latest snapshot of the primary
+type Client struct {
+	url        string
+	httpClient *http.Client
+	mu         sync.RWMutex
+	result     *models.MonitoringResult
+	lastEvent  time.Time // Timestamp of the newest event already delivered
+}
+
+// New creates a client for a primary base URL (e.g. "https://netblocks.example.org")
+// or the URL of a snapshot JSON file (e.g. a copy on static hosting or S3)
+func New(url string) *Client {
+	url = strings.TrimSuffix(url, "/")
+	if !strings.HasSuffix(url, ".json") && !strings.HasSuffix(url, snapshotPath) {
+		url += snapshotPath
+	}
+	return &Client{url: url, httpClient: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// Result returns the latest pulled result
+func (c *Client) Result() (*models.MonitoringResult, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.result == nil {
+		return nil, fmt.Errorf("no snapshot pulled from the primary yet")
+	}
+	return c.result, nil
+}
+
+// Run pulls a snapshot every interval until the context is cancelled, passing
+// events newer than the first snapshot to onEvents
+func (c *Client) Run(ctx context.Context, interval time.Duration, onEvents func([]models.Event)) {
+	log.Printf("🪞 Mirroring %s every %s", c.url, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	first := true
+	for {
+		events, err := c.Pull(ctx)
+		if err != nil {
+			log.Printf("⚠️  Failed to pull snapshot: %v", err)
+		} else if len(events) > 0 && !first {
+			// Events of the first snapshot happened before this mirror started
+			onEvents(events)
+		}
+		if err == nil {
+			first = false
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pull downloads the snapshot and returns its events not seen before
+func (c *Client) Pull(ctx context.Context) ([]models.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Mirror/1.0")
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var snapshot models.Snapshot
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxSnapshotBytes)).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snapshot.Result == nil {
+		return nil, fmt.Errorf("snapshot has no result")
+	}
+	for name, image := range snapshot.Result.Images() {
+		if data, ok := snapshot.Images[name]; ok {
+			*image = bytes.NewBuffer(data)
+		}
+	}
+	snapshot.Result.ShareASNChart()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.result = snapshot.Result
+	var fresh []models.Event
+	for _, event := range snapshot.Events {
+		if event.Timestamp.After(c.lastEvent) {
+			fresh = append(fresh, event)
+		}
+	}
+	for _, event := range fresh {
+		if event.Timestamp.After(c.lastEvent) {
+			c.lastEvent = event.Timestamp
+		}
+	}
+	return fresh, nil
+}
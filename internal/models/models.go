@@ -2,56 +2,390 @@ package models
 
 import (
 	"bytes"
+	"strings"
 	"time"
 )
 
+// Vantage identifies the probe a measurement was taken from
+// Results from several probes can disagree (e.g. a DNS server reachable from
+// one network but not another), so every measurement records its perspective
+type Vantage struct {
+	ProbeID string `json:"probe_id"`
+	Country string `json:"country,omitempty"` // ISO country code of the probe
+	Network string `json:"network,omitempty"` // ASN or network name the probe is connected through
+	Build   string `json:"build,omitempty"`   // Version of the netblocks build that measured (see internal/version)
+}
+
+// String formats the vantage as "probe (network, country)"
+func (v *Vantage) String() string {
+	if v == nil {
+		return "unknown"
+	}
+	var parts []string
+	if v.Network != "" {
+		parts = append(parts, v.Network)
+	}
+	if v.Country != "" {
+		parts = append(parts, v.Country)
+	}
+	if len(parts) == 0 {
+		return v.ProbeID
+	}
+	return v.ProbeID + " (" + strings.Join(parts, ", ") + ")"
+}
+
+// CustomCheck is one outcome reported by a check hook or checker plugin
+type CustomCheck struct {
+	Checker  string           `json:"checker"` // Name of the hook or plugin
+	Name     string           `json:"name"`
+	Up       bool             `json:"up"`
+	Detail   string           `json:"detail,omitempty"`
+	Step     string           `json:"step,omitempty"`     // First step of a multi-step check that failed, e.g. "tls" (transactions)
+	Mismatch *ContentMismatch `json:"mismatch,omitempty"` // Content that failed the integrity step (transactions)
+}
+
+// ContentMismatch is a fetched page whose content differs from the expected
+// one, e.g. with a filtering notice injected in the path
+type ContentMismatch struct {
+	URL        string    `json:"url"`
+	SHA256     string    `json:"sha256"`              // Of the fetched body
+	Expected   string    `json:"expected_sha256"`     // The configured hash, or the reference's
+	Reference  string    `json:"reference,omitempty"` // URL the body was compared with
+	Diff       string    `json:"diff,omitempty"`      // Lines missing (-) and added (+) against the reference
+	Body       string    `json:"body,omitempty"`      // Start of the fetched body, when there is no reference to diff
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// Key identifies the check across cycles
+func (c *CustomCheck) Key() string {
+	return c.Checker + "/" + c.Name
+}
+
+// AtlasAnchor is the reachability of a RIPE Atlas anchor in the country,
+// measured by the anchor mesh pings of probes abroad
+type AtlasAnchor struct {
+	ID         int           `json:"id"`
+	FQDN       string        `json:"fqdn"`
+	Address    string        `json:"address"`
+	City       string        `json:"city,omitempty"`
+	ASN        string        `json:"asn,omitempty"`
+	Probes     int           `json:"probes"`  // Probes that reported in the last window
+	Reached    int           `json:"reached"` // Probes of them that got a reply
+	LossPct    float64       `json:"loss_pct"`
+	RTT        time.Duration `json:"rtt,omitempty"` // Median round-trip time of the probes that got a reply
+	Reachable  bool          `json:"reachable"`     // At least half of the probes got a reply
+	LastCheck  time.Time     `json:"last_check"`
+	Traceroute string        `json:"traceroute,omitempty"` // Where the paths of the last scheduled traceroute ended
+}
+
+// AtlasProbes is the number of RIPE Atlas probes in the country connected to
+// the Atlas infrastructure; probes drop off together in a national shutdown
+type AtlasProbes struct {
+	Connected    int       `json:"connected"`
+	Disconnected int       `json:"disconnected"` // Probes that were connected before (abandoned ones are not counted)
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// SLOStatus is how the DNS servers or ASNs of a provider fared against an SLO
+// over its rolling window, from the hourly availability history
+type SLOStatus struct {
+	Name                  string   `json:"name"`
+	Provider              string   `json:"provider,omitempty"`
+	Target                string   `json:"target"`                           // "dns" or "asn"
+	Window                string   `json:"window"`                           // e.g. "7d"
+	Targets               int      `json:"targets"`                          // DNS servers or ASNs the SLO covers
+	Samples               int      `json:"samples"`                          // Checks in the window
+	Availability          float64  `json:"availability"`                     // Percent of the checks that succeeded
+	AvailabilityObjective float64  `json:"availability_objective,omitempty"` // Percent the SLO requires
+	LatencyMS             float64  `json:"latency_ms,omitempty"`             // Mean response time of the answered DNS checks
+	LatencyObjective      int      `json:"latency_objective_ms,omitempty"`   // Highest mean response time the SLO allows
+	Pending               bool     `json:"pending,omitempty"`                // Too few checks in the window to judge
+	Breached              []string `json:"breached,omitempty"`               // Objectives missed: "availability", "latency"
+}
+
+// Met reports whether the SLO was judged and every objective was met
+func (s SLOStatus) Met() bool {
+	return !s.Pending && len(s.Breached) == 0
+}
+
+// Telegram is the reachability of Telegram as a measurement: the bot's own API
+// calls from the monitoring host, and the endpoints checked from each vantage
+type Telegram struct {
+	API       *TelegramAPI        `json:"api,omitempty"`       // The bot's calls to the Bot API (instances running the bot only)
+	Endpoints []*TelegramEndpoint `json:"endpoints,omitempty"` // Telegram endpoints checked from this vantage (telegram_check)
+}
+
+// TelegramAPI is how the bot's calls to the Bot API went
+type TelegramAPI struct {
+	Reachable  bool          `json:"reachable"`         // The last call got an answer, API errors included
+	Latency    time.Duration `json:"latency,omitempty"` // Round trip of the last answered call
+	Calls      int64         `json:"calls"`             // Calls since start
+	Unanswered int64         `json:"unanswered"`        // Calls of them that got no answer (DNS, connect, TLS or timeout)
+	LastAnswer time.Time     `json:"last_answer,omitempty"`
+	LastError  string        `json:"last_error,omitempty"` // Why the last unanswered call failed
+	LastCall   time.Time     `json:"last_call"`
+}
+
+// TelegramEndpoint is whether a Telegram endpoint could be connected to
+type TelegramEndpoint struct {
+	Address   string        `json:"address"`           // host:port
+	Reachable bool          `json:"reachable"`         // TCP connect, and for hostnames a TLS handshake, succeeded
+	Latency   time.Duration `json:"latency,omitempty"` // Time to connect (and handshake)
+	Error     string        `json:"error,omitempty"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Vantage   *Vantage      `json:"vantage,omitempty"`
+}
+
+// Key identifies the endpoint across cycles, per vantage in aggregated results
+func (e *TelegramEndpoint) Key() string {
+	if e.Vantage != nil && e.Vantage.ProbeID != "" {
+		return e.Address + "@" + e.Vantage.ProbeID
+	}
+	return e.Address
+}
+
+// Campaign triggers
+const (
+	CampaignManual = "manual" // Started by an operator
+	CampaignAuto   = "auto"   // Started by an incident
+)
+
+// Campaign is a running measurement campaign: a time-bounded intensification
+// of the measurements (see config.Campaign)
+type Campaign struct {
+	Name      string    `json:"name"`
+	Trigger   string    `json:"trigger"`              // CampaignManual or CampaignAuto
+	StartedBy string    `json:"started_by,omitempty"` // Operator of a manual campaign
+	Reason    string    `json:"reason,omitempty"`     // Operator's note, or the event that started the campaign
+	Started   time.Time `json:"started"`
+	Ends      time.Time `json:"ends"`
+}
+
+// VantageDisagreement is a target whose status differs between vantages
+type VantageDisagreement struct {
+	Kind   string   `json:"kind"`   // "asn" or "dns"
+	Target string   `json:"target"` // ASN or DNS key
+	Name   string   `json:"name,omitempty"`
+	Up     []string `json:"up"`   // Probe IDs that see the target up
+	Down   []string `json:"down"` // Probe IDs that see the target down
+}
+
 // ASNStatus represents the connectivity status of an Autonomous System
 type ASNStatus struct {
-	ASN        string    `json:"asn"`
-	Country    string    `json:"country"`
-	Name       string    `json:"name"`
-	Connected  bool      `json:"connected"`
-	LastSeen   time.Time `json:"last_seen"`
-	LastUpdate time.Time `json:"last_update"`
+	ASN             string         `json:"asn"`
+	Country         string         `json:"country"`
+	Name            string         `json:"name"`
+	Connected       bool           `json:"connected"`
+	LastSeen        time.Time      `json:"last_seen"`
+	LastUpdate      time.Time      `json:"last_update"`
+	Uptime24h       []float64      `json:"uptime_24h,omitempty"`       // Hourly availability (0-1) for the last 24h, oldest first; -1 = no data
+	Withdrawals     int            `json:"withdrawals,omitempty"`      // BGP withdrawals of its prefixes within the withdrawal_storm window
+	WithdrawalStorm bool           `json:"withdrawal_storm,omitempty"` // Withdrawals reached the withdrawal_storm threshold
+	Flaps           int            `json:"flaps,omitempty"`            // Connectivity changes within the asn_flapping window
+	Flapping        bool           `json:"flapping,omitempty"`         // Flaps went above the asn_flapping threshold; changes are not alerted one by one
+	Visibility      *ASNVisibility `json:"visibility,omitempty"`       // RIPEstat routing status (ripestat only)
+	Source          string         `json:"source,omitempty"`           // "ripestat" when RIPEstat decided Connected because RIS Live was quiet
+	RPKI            *RPKISummary   `json:"rpki,omitempty"`             // RPKI validity of the prefixes it announces (rpki only)
+	Vantage         *Vantage       `json:"vantage,omitempty"`
+}
+
+// ASNVisibility is the routing status of an ASN on RIPEstat, a cross-check
+// of RIS Live
+type ASNVisibility struct {
+	PeersSeeing int       `json:"peers_seeing"` // RIS peers that see the ASN's prefixes (IPv4 or IPv6, whichever more)
+	TotalPeers  int       `json:"total_peers"`  // RIS peers of that address family
+	Prefixes    int       `json:"prefixes"`     // Announced IPv4 and IPv6 prefixes
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// RPKI origin validation states (RFC 6811)
+const (
+	RPKIValid    = "valid"     // A ROA covers the prefix for this origin and length
+	RPKIInvalid  = "invalid"   // ROAs cover the prefix, none for this origin and length: a possible hijack or misconfiguration
+	RPKINotFound = "not-found" // No ROA covers the prefix
+)
+
+// RPKISummary counts the RPKI validity of the prefixes an ASN was last seen announcing
+type RPKISummary struct {
+	Valid     int       `json:"valid"`
+	Invalid   int       `json:"invalid"`
+	NotFound  int       `json:"not_found"`
+	Invalids  []string  `json:"invalid_prefixes,omitempty"` // Invalid prefixes, at most 10
+	CheckedAt time.Time `json:"checked_at"`                 // When the ROAs were fetched
+}
+
+// PrefixStatus is the routing state of a prefix followed on RIS Live: whether
+// any RIS peer has a route to it or to a more specific prefix within it
+type PrefixStatus struct {
+	Prefix          string    `json:"prefix"`
+	Announced       bool      `json:"announced"`
+	Peers           int       `json:"peers"`                    // RIS peers with a route to the prefix now
+	MaxPeers        int       `json:"max_peers"`                // Most RIS peers seen with a route since start
+	Origin          string    `json:"origin,omitempty"`         // Origin ASN of the last announcement, e.g. AS58224
+	OriginRoute     string    `json:"origin_route,omitempty"`   // Prefix of that announcement: the followed prefix or one within it
+	RPKI            string    `json:"rpki,omitempty"`           // RPKI validity of OriginRoute for Origin: valid, invalid or not-found (rpki only)
+	LastAnnounced   time.Time `json:"last_announced,omitempty"` // Zero until an announcement was seen
+	LastWithdrawn   time.Time `json:"last_withdrawn,omitempty"`
+	Withdrawals     int       `json:"withdrawals,omitempty"`      // BGP withdrawals within it in the withdrawal_storm window
+	WithdrawalStorm bool      `json:"withdrawal_storm,omitempty"` // Withdrawals reached the withdrawal_storm threshold
+	LastUpdate      time.Time `json:"last_update"`
 }
 
 // DNSStatus represents the status of a DNS server
 type DNSStatus struct {
-	Server     string    `json:"server"`
-	Name       string    `json:"name"`
-	Alive      bool      `json:"alive"`
+	Server       string        `json:"server"`
+	Name         string        `json:"name"`
+	Alive        bool          `json:"alive"`            // Declared state, after flap damping
+	Sample       bool          `json:"sample"`           // Whether the last check got an answer (raw, before damping)
+	Streak       int           `json:"streak,omitempty"` // Consecutive checks with the same sample
 	ResponseTime time.Duration `json:"response_time"`
-	LastCheck  time.Time `json:"last_check"`
-	Error      string    `json:"error,omitempty"`
+	UncachedTime time.Duration `json:"uncached_response_time,omitempty"` // Response time for a random name the resolver cannot have cached (cache busting only)
+	LastCheck    time.Time     `json:"last_check"`
+	Error        string        `json:"error,omitempty"`      // Human-readable description of ErrorCode
+	ErrorCode    DNSErrorCode  `json:"error_code,omitempty"` // Cause of a failed or anomalous check
+	Type         string        `json:"type,omitempty"`       // Server type the behavior was checked for: "recursive", "authoritative" or "both"
+	Verdict      string        `json:"verdict,omitempty"`    // VerdictPass or VerdictFail: whether an alive server behaves as its type requires (empty if not checked)
+	Reason       string        `json:"reason,omitempty"`     // Why the verdict was reached, e.g. "recursion refused (REFUSED)"
+	Vantage      *Vantage      `json:"vantage,omitempty"`
 }
 
+// DNSErrorCode is the cause of a failed or anomalous DNS check, stable across
+// versions so the API, alert rules and statistics can aggregate by it
+type DNSErrorCode string
+
+// DNS error codes
+const (
+	DNSErrorTimeout            DNSErrorCode = "TIMEOUT"             // No answer within the timeout
+	DNSErrorNetworkUnreachable DNSErrorCode = "NETWORK_UNREACHABLE" // No route to the server's network or host
+	DNSErrorConnectionRefused  DNSErrorCode = "CONNECTION_REFUSED"  // The host refused the connection (ICMP port unreachable, TCP RST)
+	DNSErrorNetwork            DNSErrorCode = "NETWORK_ERROR"       // Other network failures
+	DNSErrorRefused            DNSErrorCode = "REFUSED"             // Answered with rcode REFUSED
+	DNSErrorNXDomain           DNSErrorCode = "NXDOMAIN"            // Answered with rcode NXDOMAIN
+	DNSErrorServFail           DNSErrorCode = "SERVFAIL"            // Answered with rcode SERVFAIL
+	DNSErrorRcode              DNSErrorCode = "RCODE"               // Answered with another error rcode (NOTAUTH, FORMERR, ...)
+	DNSErrorInjectedAnswer     DNSErrorCode = "INJECTED_ANSWER"     // Answered with a private address, as injected for blocked names
+	DNSErrorNoResponse         DNSErrorCode = "NO_RESPONSE"         // Neither an answer nor an error
+	DNSErrorUnknown            DNSErrorCode = "UNKNOWN"             // Errors matching no other code
+)
+
+// Verdicts of the type-aware DNS checks
+const (
+	VerdictPass = "pass"
+	VerdictFail = "fail"
+)
+
 // MonitoringConfig holds the configuration for monitoring
 type MonitoringConfig struct {
-	Interval      time.Duration `json:"interval"`
-	RISLiveURL    string        `json:"ris_live_url"`
-	DNSServers    []string      `json:"dns_servers"`
-	IranASNs      []string      `json:"iran_asns"`
+	Interval   time.Duration `json:"interval"`
+	RISLiveURL string        `json:"ris_live_url"`
+	DNSServers []string      `json:"dns_servers"`
+	IranASNs   []string      `json:"iran_asns"`
 }
 
 // MonitoringResult contains the results of a monitoring check
 type MonitoringResult struct {
-	Timestamp    time.Time              `json:"timestamp"`
-	ASNStatuses  map[string]*ASNStatus  `json:"asn_statuses"`
-	DNSStatuses  map[string]*DNSStatus  `json:"dns_statuses"`
-	TrafficData  *TrafficData           `json:"traffic_data,omitempty"`
-	ASTrafficData []*ASTrafficData      `json:"as_traffic_data,omitempty"`
+	Timestamp     time.Time                `json:"timestamp"`
+	ASNStatuses   map[string]*ASNStatus    `json:"asn_statuses"`
+	ReferenceASNs map[string]*ASNStatus    `json:"reference_asns,omitempty"` // External reference ASNs (global CDNs), not counted in the country's summary
+	Prefixes      map[string]*PrefixStatus `json:"prefixes,omitempty"`       // Routing state of the bgp_prefixes, by prefix
+	DNSStatuses   map[string]*DNSStatus    `json:"dns_statuses"`
+	TrafficData   *TrafficData             `json:"traffic_data,omitempty"`
+	ASTrafficData []*ASTrafficData         `json:"as_traffic_data,omitempty"`
+	UptimeChart   *bytes.Buffer            `json:"-"`                        // 7-day availability heatmap PNG, not serialized to JSON
+	NationalScore float64                  `json:"national_score"`           // Combined 0-100 connectivity score
+	StatusImage   *bytes.Buffer            `json:"-"`                        // Composite multi-panel status PNG, not serialized to JSON
+	ASNSparklines *bytes.Buffer            `json:"-"`                        // Per-ASN 24h availability sparkline strip PNG, not serialized to JSON
+	ProvinceMap   *bytes.Buffer            `json:"-"`                        // Map of the provinces colored by connectivity PNG, not serialized to JSON
+	FailureChart  *bytes.Buffer            `json:"-"`                        // 7-day DNS failure causes PNG, not serialized to JSON
+	UptimeSummary string                   `json:"uptime_summary,omitempty"` // Text alternative of the uptime heatmap
+	Vantage       *Vantage                 `json:"vantage,omitempty"`        // Probe that produced this result
+	Probes        []*Vantage               `json:"probes,omitempty"`         // Aggregated results: vantages merged into this result
+	Disagreements []VantageDisagreement    `json:"disagreements,omitempty"`  // Aggregated results: targets the probes disagree on
+	CustomChecks  []*CustomCheck           `json:"custom_checks,omitempty"`  // Outcomes of check hooks and checker plugins
+	AtlasAnchors  []*AtlasAnchor           `json:"atlas_anchors,omitempty"`  // RIPE Atlas anchors in the country as seen by probes abroad
+	AtlasProbes   *AtlasProbes             `json:"atlas_probes,omitempty"`   // RIPE Atlas probes in the country connected, as of the last count
+	Campaign      *Campaign                `json:"campaign,omitempty"`       // Measurement campaign running during the cycle
+	Telegram      *Telegram                `json:"telegram,omitempty"`       // Reachability of Telegram from the monitoring host and probes
+	SLOs          []SLOStatus              `json:"slos,omitempty"`           // How the providers fare against their SLOs over the windows
+}
+
+// Images maps image keys to the chart buffers of a result, which are not part of its JSON
+func (r *MonitoringResult) Images() map[string]**bytes.Buffer {
+	images := map[string]**bytes.Buffer{
+		"status":     &r.StatusImage,
+		"uptime":     &r.UptimeChart,
+		"sparklines": &r.ASNSparklines,
+		"provinces":  &r.ProvinceMap,
+		"failures":   &r.FailureChart,
+	}
+	if r.TrafficData != nil {
+		images["traffic"] = &r.TrafficData.ChartBuffer
+	}
+	if len(r.ASTrafficData) > 0 {
+		images["asn_traffic"] = &r.ASTrafficData[0].ChartBuffer
+	}
+	return images
+}
+
+// ShareASNChart gives every ASN traffic item the chart of the first one (they share one chart)
+func (r *MonitoringResult) ShareASNChart() {
+	for _, item := range r.ASTrafficData {
+		item.ChartBuffer = r.ASTrafficData[0].ChartBuffer
+	}
+}
+
+// Snapshot is a published result with its charts and recent public events,
+// pulled by read-only mirrors
+type Snapshot struct {
+	GeneratedAt time.Time         `json:"generated_at"`
+	Result      *MonitoringResult `json:"result"`
+	Images      map[string][]byte `json:"images,omitempty"` // PNG charts by Images key (base64 in JSON)
+	Events      []Event           `json:"events,omitempty"` // Recent events sent to the public Telegram channels, oldest first
+}
+
+// NewSnapshot builds the snapshot of a result with its non-empty charts
+func NewSnapshot(result *MonitoringResult, events []Event) Snapshot {
+	snapshot := Snapshot{GeneratedAt: time.Now().UTC(), Result: result, Images: make(map[string][]byte)}
+	for name, image := range result.Images() {
+		if *image != nil && (*image).Len() > 0 {
+			snapshot.Images[name] = (*image).Bytes()
+		}
+	}
+	snapshot.Events = append([]Event(nil), events...)
+	return snapshot
+}
+
+// Narratives returns the distinct narratives of events, in order
+func Narratives(events []Event) []string {
+	var texts []string
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.Narrative != "" && !seen[event.Narrative] {
+			seen[event.Narrative] = true
+			texts = append(texts, event.Narrative)
+		}
+	}
+	return texts
 }
 
 // ASTrafficData represents traffic statistics for a specific ASN
 type ASTrafficData struct {
-	ASN            string        `json:"asn"`
-	Name           string        `json:"name"`
-	TrafficVolume  float64       `json:"traffic_volume"`  // Bytes or requests
-	Percentage     float64       `json:"percentage"`      // Percentage of total Iranian traffic
-	Status         string        `json:"status"`          // Status indicator
-	StatusEmoji    string        `json:"status_emoji"`
-	ChartBuffer    *bytes.Buffer `json:"-"`               // PNG chart, not serialized to JSON
-	LastUpdate     time.Time     `json:"last_update"`
+	ASN           string        `json:"asn"`
+	Name          string        `json:"name"`
+	TrafficVolume float64       `json:"traffic_volume"` // Bytes or requests
+	Percentage    float64       `json:"percentage"`     // Percentage of total Iranian traffic
+	Status        string        `json:"status"`         // Status indicator
+	StatusEmoji   string        `json:"status_emoji"`
+	ChartBuffer   *bytes.Buffer `json:"-"` // PNG chart, not serialized to JSON
+	LastUpdate    time.Time     `json:"last_update"`
+	Pinned        bool          `json:"pinned,omitempty"` // Shown because asn_traffic.pinned lists it, not for its rank
+	ShareChange   *ShareChange  `json:"share_change,omitempty"`
+}
+
+// ShareChange is the traffic share an ASN had some hours before the current one
+type ShareChange struct {
+	From  float64 `json:"from"`  // Percentage of total Iranian traffic back then
+	Hours int     `json:"hours"` // How long ago
 }
 
 // TrafficData represents Iran's internet traffic statistics
@@ -63,6 +397,34 @@ type TrafficData struct {
 	Status        string        `json:"status"`
 	StatusEmoji   string        `json:"status_emoji"`
 	ChartBuffer   *bytes.Buffer `json:"-"` // PNG chart, not serialized to JSON
+	Vantage       *Vantage      `json:"vantage,omitempty"`
 	LastUpdate    time.Time     `json:"last_update"`
 }
 
+// Event severities, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Event represents a state change detected between two monitoring cycles
+type Event struct {
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`     // "asn", "asn_share", "prefix", "dns", "traffic", "national", "rule", "correlated", "check", "subsystem", "annotation", "atlas", "campaign", "telegram" or "slo"
+	Target    string    `json:"target"`   // ASN, DNS server key, or "IR"
+	Severity  string    `json:"severity"` // SeverityInfo, SeverityWarning or SeverityCritical
+	Message   string    `json:"message"`
+	Actions   []string  `json:"actions,omitempty"`   // Notifiers the event is routed to (rule events only; default: telegram)
+	Incident  string    `json:"incident,omitempty"`  // ID of the escalating incident the event belongs to (acknowledge to stop escalation)
+	Resolved  bool      `json:"resolved,omitempty"`  // The condition of a rule event cleared (closes paging incidents)
+	Narrative string    `json:"narrative,omitempty"` // Plain-language account of the situation (critical events only)
+}
+
+// Annotation is an operator's note on a point in time ("power outage in DC",
+// "confirmed by local sources"), shown on charts, in the event timeline and in exports
+type Annotation struct {
+	Time   time.Time `json:"time"`
+	Text   string    `json:"text"`
+	Author string    `json:"author"` // "telegram:@<name>", "telegram:<user ID>" or "api[:<name>]"
+}
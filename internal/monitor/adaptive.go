@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Adaptive interval defaults
+const (
+	defaultAdaptiveSpeedup    = 4
+	defaultAdaptiveRelaxAfter = 30 * time.Minute
+)
+
+// adaptiveIntervals divides the fetch intervals by a factor while the events
+// show an incident: the full factor on critical events, half of it on
+// warnings, halved again after every quiet relaxAfter
+type adaptiveIntervals struct {
+	maxSpeedup int
+	relaxAfter time.Duration
+
+	mu      sync.Mutex
+	speedup int       // Current factor (1: normal intervals)
+	changed time.Time // Last raise or relax of the factor
+}
+
+// newAdaptiveIntervals reads the adaptive_intervals config (nil if it is not set)
+func newAdaptiveIntervals(settings *config.AdaptiveIntervals) *adaptiveIntervals {
+	if settings == nil {
+		return nil
+	}
+	a := &adaptiveIntervals{maxSpeedup: settings.Speedup, relaxAfter: defaultAdaptiveRelaxAfter, speedup: 1}
+	if a.maxSpeedup == 0 {
+		a.maxSpeedup = defaultAdaptiveSpeedup
+	}
+	if d, err := time.ParseDuration(settings.RelaxAfter); err == nil && d > 0 {
+		a.relaxAfter = d
+	}
+	return a
+}
+
+// shorten returns base divided by the current factor, not below floor (base
+// itself if adaptive intervals are disabled)
+func (a *adaptiveIntervals) shorten(base, floor time.Duration) time.Duration {
+	if a == nil {
+		return base
+	}
+	a.mu.Lock()
+	speedup := a.speedup
+	a.mu.Unlock()
+	d := base / time.Duration(speedup)
+	if d < floor {
+		d = floor
+	}
+	if d > base {
+		d = base
+	}
+	return d
+}
+
+// observe raises the factor on warning and critical events and relaxes it
+// after quiet periods; it reports the factor and whether it changed
+func (a *adaptiveIntervals) observe(now time.Time, events []models.Event) (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	wanted := 1
+	for _, event := range events {
+		if event.Resolved || event.Kind == "campaign" || event.Kind == "annotation" || event.Kind == "subsystem" || event.Kind == "slo" {
+			continue
+		}
+		switch event.Severity {
+		case models.SeverityCritical:
+			wanted = a.maxSpeedup
+		case models.SeverityWarning:
+			if half := max(a.maxSpeedup/2, 2); half > wanted {
+				wanted = half
+			}
+		}
+	}
+
+	previous := a.speedup
+	switch {
+	case wanted > 1 && wanted >= a.speedup:
+		// An ongoing incident keeps the factor from relaxing
+		a.speedup, a.changed = wanted, now
+	case a.speedup > 1 && now.Sub(a.changed) >= a.relaxAfter:
+		a.speedup, a.changed = max(a.speedup/2, 1), now
+	}
+	return a.speedup, a.speedup != previous
+}
+
+// adaptIntervals feeds a cycle's events to the adaptive intervals and retunes
+// the fetch loops and the BGP staleness when the factor changes
+func (m *Monitor) adaptIntervals(events []models.Event) {
+	if m.adaptive == nil {
+		return
+	}
+	speedup, changed := m.adaptive.observe(m.clock.Now(), events)
+	if !changed {
+		return
+	}
+	if speedup > 1 {
+		log.Printf("⏩ Adaptive intervals: checking %dx as often (cycle every %s)", speedup,
+			formatDuration(m.adaptive.shorten(m.config.Interval, m.schedule.minCycle)))
+	} else {
+		log.Println("⏸  Adaptive intervals: back to the normal check intervals")
+	}
+	m.applyStaleAfter()
+	m.retune.notify()
+}
+
+// applyStaleAfter sets how long the ASNs may stay silent before they count as
+// offline, shortened during an incident; an ASN whose stale time is below the
+// floor keeps it
+func (m *Monitor) applyStaleAfter() {
+	m.bgpClient.SetStaleAfter(m.adaptive.shorten(m.schedule.asnStale, m.schedule.minBGPStale))
+	if len(m.schedule.asnStaleOverrides) == 0 {
+		return
+	}
+	overrides := make(map[string]time.Duration, len(m.schedule.asnStaleOverrides))
+	for asn, d := range m.schedule.asnStaleOverrides {
+		overrides[asn] = m.adaptive.shorten(d, m.schedule.minBGPStale)
+	}
+	m.bgpClient.SetStaleOverrides(overrides)
+}
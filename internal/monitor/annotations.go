@@ -0,0 +1,112 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// Annotation limits
+const (
+	maxAnnotationLength = 280 // Characters of an annotation's text
+	noteLabelLength     = 32  // Characters of an annotation shown on charts
+)
+
+// Annotate records an operator's note on the current time: it is kept in the
+// history (charts, exports), signed into the evidence log and reported as an
+// event of kind "annotation", so it also reaches the channels and the snapshot
+func (m *Monitor) Annotate(text, author string) (models.Annotation, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return models.Annotation{}, fmt.Errorf("annotation text is empty")
+	}
+	if utf8.RuneCountInString(text) > maxAnnotationLength {
+		return models.Annotation{}, fmt.Errorf("annotation is longer than %d characters", maxAnnotationLength)
+	}
+	if m.history == nil {
+		return models.Annotation{}, fmt.Errorf("history is disabled")
+	}
+
+	note := models.Annotation{Time: m.clock.Now().UTC().Truncate(time.Second), Text: text, Author: author}
+	if err := m.history.Annotate(note); err != nil {
+		return models.Annotation{}, err
+	}
+	log.Printf("📝 Annotation by %s: %s", author, text)
+
+	event := models.Event{
+		Timestamp: note.Time,
+		Kind:      "annotation",
+		Target:    m.config.Country,
+		Severity:  models.SeverityInfo,
+		Message:   fmt.Sprintf("📝 Note (%s): %s", author, text),
+	}
+	if m.evidence != nil {
+		if err := m.evidence.Append("annotation", note.Time, note); err != nil {
+			log.Printf("⚠️  Failed to sign annotation: %v", err)
+		}
+	}
+	if m.onEvents != nil {
+		m.onEvents([]models.Event{event})
+	}
+	return note, nil
+}
+
+// Annotations returns the annotations of a period ("24h", "7d" or "30d"), oldest first
+func (m *Monitor) Annotations(period string) ([]models.Annotation, error) {
+	if m.history == nil {
+		return nil, fmt.Errorf("history is disabled")
+	}
+	hours, err := ParseChartPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	return m.history.Annotations(time.Now().Add(-time.Duration(hours) * time.Hour)), nil
+}
+
+// noteMarkers draws each annotation as a dashed vertical line with a short
+// label; x places a note on the chart's X axis and notes outside [minX, maxX]
+// are left out
+func noteMarkers(notes []models.Annotation, x func(time.Time) float64, minX, maxX float64) []chart.Series {
+	var series []chart.Series
+	labels := chart.AnnotationSeries{
+		Style: chart.Style{
+			FontSize:    8,
+			FillColor:   drawing.Color{R: 255, G: 255, B: 255, A: 220},
+			StrokeColor: drawing.Color{R: 120, G: 120, B: 120, A: 255},
+		},
+	}
+	for _, note := range notes {
+		at := x(note.Time)
+		if at < minX || at > maxX {
+			continue
+		}
+		series = append(series, chart.ContinuousSeries{
+			XValues: []float64{at, at},
+			YValues: []float64{0, 100},
+			Style: chart.Style{
+				StrokeColor:     drawing.Color{R: 120, G: 120, B: 120, A: 255},
+				StrokeWidth:     1,
+				StrokeDashArray: []float64{4, 3},
+			},
+		})
+		labels.Annotations = append(labels.Annotations, chart.Value2{XValue: at, YValue: 95, Label: noteLabel(note.Text)})
+	}
+	if len(labels.Annotations) > 0 {
+		series = append(series, labels)
+	}
+	return series
+}
+
+// noteLabel shortens an annotation to fit on a chart
+func noteLabel(text string) string {
+	if utf8.RuneCountInString(text) <= noteLabelLength {
+		return text
+	}
+	return string([]rune(text)[:noteLabelLength-1]) + "…"
+}
@@ -0,0 +1,164 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Change-point detection on the share of alive DNS servers: a one-sided CUSUM
+// accumulates how far each cycle falls below a learned baseline, so a slide over
+// several cycles is reported even though no single cycle looks unusual
+const (
+	anomalyAlpha        = 0.1  // EWMA weight of a new cycle in the baseline mean and variance
+	anomalyWarmup       = 6    // Cycles learned before drops are reported
+	anomalyMinSigma     = 0.02 // Floor of the baseline standard deviation (a perfectly stable ratio has none)
+	anomalySlack        = 0.5  // Drift per cycle (in standard deviations) tolerated by the CUSUM
+	anomalyThreshold    = 5.0  // CUSUM value that signals a drop
+	anomalyMinDrop      = 0.05 // Drop below the baseline a reported change must have
+	anomalyMinServers   = 5    // Provinces with fewer DNS servers are only part of the overall ratio
+	anomalyRelearnAfter = 36   // Cycles after which a persisting drop becomes the new baseline
+)
+
+// baseline is an exponentially weighted mean and variance of a measurement
+type baseline struct {
+	samples  int
+	mean     float64
+	variance float64
+}
+
+// learn updates the baseline with a value
+func (b *baseline) learn(value float64) {
+	if b.samples == 0 {
+		b.mean = value
+	}
+	b.samples++
+	diff := value - b.mean
+	b.mean += anomalyAlpha * diff
+	b.variance = (1 - anomalyAlpha) * (b.variance + anomalyAlpha*diff*diff)
+}
+
+// ratioSeries tracks the baseline and CUSUM of one alive ratio
+type ratioSeries struct {
+	baseline
+	cusum    float64
+	alarmed  bool
+	alarmFor int // Cycles since the drop was reported
+}
+
+// observe adds a cycle's ratio and reports whether a drop was detected (true, false)
+// or the ratio recovered after one (false, true)
+func (s *ratioSeries) observe(ratio float64) (dropped, recovered bool) {
+	if s.samples < anomalyWarmup {
+		s.learn(ratio)
+		return false, false
+	}
+
+	if s.alarmed {
+		s.alarmFor++
+		switch {
+		case ratio >= s.mean-anomalyMinDrop/2:
+			s.alarmed, s.cusum = false, 0
+			s.learn(ratio)
+			return false, true
+		case s.alarmFor >= anomalyRelearnAfter:
+			// The lower level persisted: learn it afresh instead of alarming forever
+			*s = ratioSeries{}
+			s.learn(ratio)
+		}
+		return false, false
+	}
+
+	sigma := math.Max(math.Sqrt(s.variance), anomalyMinSigma)
+	s.cusum = math.Max(0, s.cusum+(s.mean-ratio)/sigma-anomalySlack)
+	if s.cusum >= anomalyThreshold && s.mean-ratio >= anomalyMinDrop {
+		s.alarmed, s.alarmFor = true, 0
+		return true, false
+	}
+	// The baseline only follows cycles that are not part of a suspected drop
+	if s.cusum == 0 {
+		s.learn(ratio)
+	}
+	return false, false
+}
+
+// DNSAnomalyDetector raises events on significant drops of the alive DNS server
+// ratio, overall and per province
+type DNSAnomalyDetector struct {
+	series map[string]*ratioSeries // "IR" or province name
+}
+
+// NewDNSAnomalyDetector creates a detector without history
+func NewDNSAnomalyDetector() *DNSAnomalyDetector {
+	return &DNSAnomalyDetector{series: make(map[string]*ratioSeries)}
+}
+
+// Observe feeds one cycle's DNS statuses and returns the resulting events
+func (d *DNSAnomalyDetector) Observe(result *models.MonitoringResult) []models.Event {
+	if result == nil || len(result.DNSStatuses) == 0 {
+		return nil
+	}
+	now := result.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	type count struct{ alive, total int }
+	counts := map[string]*count{"IR": {}}
+	for _, status := range result.DNSStatuses {
+		province := config.GetDNSCity(status.Name)
+		if counts[province] == nil {
+			counts[province] = &count{}
+		}
+		for _, c := range []*count{counts["IR"], counts[province]} {
+			c.total++
+			if status.Alive {
+				c.alive++
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var events []models.Event
+	for _, key := range keys {
+		c := counts[key]
+		if key != "IR" && (key == "Other" || c.total < anomalyMinServers) {
+			continue
+		}
+		s, ok := d.series[key]
+		if !ok {
+			s = &ratioSeries{}
+			d.series[key] = s
+		}
+		baseline := s.mean
+		ratio := float64(c.alive) / float64(c.total)
+		dropped, recovered := s.observe(ratio)
+
+		scope := "in " + key
+		if key == "IR" {
+			scope = "nationwide"
+		}
+		switch {
+		case dropped:
+			severity := models.SeverityWarning
+			if key == "IR" {
+				severity = models.SeverityCritical
+			}
+			events = append(events, models.Event{Timestamp: now, Kind: "dns", Target: "ratio:" + key, Severity: severity,
+				Message: fmt.Sprintf("DNS availability %s dropped to %.0f%% (%d/%d alive, usually %.0f%%)", scope, ratio*100, c.alive, c.total, baseline*100)})
+		case recovered:
+			events = append(events, models.Event{Timestamp: now, Kind: "dns", Target: "ratio:" + key, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("DNS availability %s recovered to %.0f%% (%d/%d alive)", scope, ratio*100, c.alive, c.total)})
+		}
+	}
+	return events
+}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Share changes compare an ASN's traffic share with the one recorded in the
+// history shareChangeWindow ago
+const (
+	shareChangeWindow = 6 * time.Hour
+	shareChangeMin    = 2.0 // Percentage points a share must move to be shown
+	shareDropMin      = 5.0 // Percentage points a share must lose to be alerted...
+	shareDropRatio    = 0.5 // ...while falling to at most this fraction of what it was
+)
+
+// SelectASNTraffic returns the ASNs of the traffic chart from Radar's list
+// (sorted by volume): the top limits.Chart with at least limits.MinShare
+// percent, followed by the pinned ASNs that did not make the cut
+func SelectASNTraffic(traffic []*models.ASTrafficData, limits config.ASNTrafficLimits) []*models.ASTrafficData {
+	pinned := make(map[string]bool, len(limits.Pinned))
+	for _, asn := range limits.Pinned {
+		pinned[asn] = true
+	}
+	var selected, extra []*models.ASTrafficData
+	for _, item := range traffic {
+		switch {
+		case len(selected) < limits.Chart && item.Percentage >= limits.MinShare:
+			selected = append(selected, item)
+		case pinned[item.ASN]:
+			copied := *item
+			copied.Pinned = true
+			extra = append(extra, &copied)
+		}
+	}
+	return append(selected, extra...)
+}
+
+// RankedASNCount returns how many ASNs are shown for their rank, not pinned
+func RankedASNCount(traffic []*models.ASTrafficData) int {
+	count := 0
+	for _, item := range traffic {
+		if !item.Pinned {
+			count++
+		}
+	}
+	return count
+}
+
+// attachShareChanges returns copies of the traffic items with the share each
+// ASN had shareChangeWindow ago (or as long ago as the history goes)
+func (m *Monitor) attachShareChanges(traffic []*models.ASTrafficData, now time.Time) []*models.ASTrafficData {
+	if m.history == nil {
+		return traffic
+	}
+	current := now.UTC().Truncate(time.Hour)
+	shares := m.history.ASNShare(current.Add(-shareChangeWindow))
+	result := make([]*models.ASTrafficData, 0, len(traffic))
+	for _, item := range traffic {
+		copied := *item
+		if points := shares[item.ASN]; len(points) > 0 {
+			if hours := int(current.Sub(points[0].Timestamp) / time.Hour); hours >= 1 {
+				copied.ShareChange = &models.ShareChange{From: points[0].Share, Hours: hours}
+			}
+		}
+		result = append(result, &copied)
+	}
+	return result
+}
+
+// ShareMoved reports whether an ASN's traffic share changed enough to be shown
+func ShareMoved(item *models.ASTrafficData) bool {
+	return item.ShareChange != nil && math.Abs(item.Percentage-item.ShareChange.From) >= shareChangeMin
+}
+
+// ShareDropped reports whether an ASN's traffic share collapsed
+func ShareDropped(item *models.ASTrafficData) bool {
+	change := item.ShareChange
+	return change != nil && change.From-item.Percentage >= shareDropMin && item.Percentage <= change.From*shareDropRatio
+}
+
+// FormatShare formats a traffic share without a percent sign: whole percents
+// from 10 up, one decimal below
+func FormatShare(share float64) string {
+	if share >= 10 {
+		return fmt.Sprintf("%.0f", share)
+	}
+	return fmt.Sprintf("%.1f", share)
+}
+
+// FormatShareIn formats a traffic share as a percentage in a locale, with the
+// decimals of FormatShare
+func FormatShareIn(share float64, locale i18n.Locale) string {
+	if share >= 10 {
+		return locale.Percent(share, 0)
+	}
+	return locale.Percent(share, 1)
+}
+
+// DescribeShareChange describes a share change, e.g. "down from 24% to 3.0% in 6h"
+func DescribeShareChange(item *models.ASTrafficData) string {
+	if item.ShareChange == nil {
+		return ""
+	}
+	direction := "up"
+	if item.Percentage < item.ShareChange.From {
+		direction = "down"
+	}
+	return fmt.Sprintf("%s from %s%% to %s%% in %dh", direction,
+		FormatShare(item.ShareChange.From), FormatShare(item.Percentage), item.ShareChange.Hours)
+}
@@ -0,0 +1,562 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// RIPE Atlas settings
+const (
+	defaultAtlasURL      = "https://atlas.ripe.net/api/v2"
+	defaultAtlasInterval = 10 * time.Minute
+	defaultAtlasProbes   = 10
+	atlasMinWindow       = 10 * time.Minute    // Mesh pings run every 4 minutes; shorter windows miss probes
+	atlasAnchorRefresh   = 24 * time.Hour      // How often the anchor list and their measurements are looked up again
+	atlasTraceWait       = 30 * time.Minute    // How long the results of a scheduled traceroute are waited for
+	atlasProbeHistory    = 30 * 24 * time.Hour // Probe counts kept for the traffic charts
+)
+
+// AtlasMonitor follows the RIPE Atlas anchors in the country through the
+// anchor mesh: every anchor is pinged by hundreds of probes worldwide, whose
+// results are public. With an API key it also schedules a traceroute toward an
+// anchor that stops answering, to show where the paths into the country end.
+// It also counts the probes in the country that are connected to Atlas
+type AtlasMonitor struct {
+	base       string
+	key        string
+	country    string
+	only       map[string]bool // Configured anchor FQDNs and IDs (empty: all)
+	window     time.Duration
+	traceroute bool
+	probes     int
+	client     *http.Client
+
+	mu        sync.Mutex
+	anchors   []atlasAnchor
+	refreshed time.Time
+	statuses  map[int]*models.AtlasAnchor
+	traces    map[int]atlasTrace   // Scheduled traceroutes by anchor ID
+	counts    []models.AtlasProbes // Probe counts of the last atlasProbeHistory, oldest first
+	lastErr   error
+}
+
+// atlasAnchor is an anchor with the IDs of its mesh ping measurements
+type atlasAnchor struct {
+	id           int
+	fqdn         string
+	address      string
+	city         string
+	asn          string
+	measurements []int
+}
+
+// atlasTrace is a one-off traceroute scheduled toward an anchor
+type atlasTrace struct {
+	measurement int
+	scheduled   time.Time
+}
+
+// NewAtlasMonitor creates the RIPE Atlas client of the config (nil if ripe_atlas is not set)
+func NewAtlasMonitor(cfg *config.Config) *AtlasMonitor {
+	settings := cfg.RIPEAtlas
+	if settings == nil {
+		return nil
+	}
+	a := &AtlasMonitor{
+		base:       strings.TrimSuffix(settings.URL, "/"),
+		key:        settings.APIKey,
+		country:    cfg.Country,
+		only:       make(map[string]bool),
+		window:     atlasInterval(settings),
+		traceroute: settings.Traceroute,
+		probes:     settings.Probes,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		statuses:   make(map[int]*models.AtlasAnchor),
+		traces:     make(map[int]atlasTrace),
+	}
+	if a.base == "" {
+		a.base = defaultAtlasURL
+	}
+	if a.window < atlasMinWindow {
+		a.window = atlasMinWindow
+	}
+	if a.probes <= 0 {
+		a.probes = defaultAtlasProbes
+	}
+	for _, anchor := range settings.Anchors {
+		a.only[strings.ToLower(anchor)] = true
+	}
+	return a
+}
+
+// atlasInterval is how often RIPE Atlas results are fetched
+func atlasInterval(settings *config.RIPEAtlas) time.Duration {
+	if d, err := time.ParseDuration(settings.Interval); err == nil && d > 0 {
+		return d
+	}
+	return defaultAtlasInterval
+}
+
+// Anchors returns the anchor statuses of the last fetch, ordered by FQDN, and
+// the error of the last fetch
+func (a *AtlasMonitor) Anchors() ([]*models.AtlasAnchor, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	anchors := make([]*models.AtlasAnchor, 0, len(a.statuses))
+	for _, status := range a.statuses {
+		copied := *status
+		anchors = append(anchors, &copied)
+	}
+	sort.Slice(anchors, func(i, j int) bool { return anchors[i].FQDN < anchors[j].FQDN })
+	return anchors, a.lastErr
+}
+
+// Probes returns the last probe count (nil before the first one) and its history since a time
+func (a *AtlasMonitor) Probes(since time.Time) (*models.AtlasProbes, []models.AtlasProbes) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.counts) == 0 {
+		return nil, nil
+	}
+	last := a.counts[len(a.counts)-1]
+	start := sort.Search(len(a.counts), func(i int) bool { return !a.counts[i].CheckedAt.Before(since) })
+	return &last, append([]models.AtlasProbes(nil), a.counts[start:]...)
+}
+
+// Fetch counts the connected probes, updates the reachability of every anchor
+// from the mesh pings of the last window and collects the results of
+// scheduled traceroutes
+func (a *AtlasMonitor) Fetch(ctx context.Context) error {
+	err := a.fetch(ctx)
+	a.mu.Lock()
+	a.lastErr = err
+	a.mu.Unlock()
+	if err != nil {
+		log.Printf("⚠️  RIPE Atlas fetch failed: %v", err)
+	}
+	return err
+}
+
+func (a *AtlasMonitor) fetch(ctx context.Context) error {
+	now := time.Now()
+	probes, probeErr := a.countProbes(ctx, now)
+	if probeErr != nil {
+		probeErr = fmt.Errorf("probe count: %w", probeErr)
+	} else {
+		a.mu.Lock()
+		a.counts = append(a.counts, *probes)
+		cutoff := sort.Search(len(a.counts), func(i int) bool { return now.Sub(a.counts[i].CheckedAt) <= atlasProbeHistory })
+		a.counts = append([]models.AtlasProbes(nil), a.counts[cutoff:]...)
+		a.mu.Unlock()
+	}
+	return errors.Join(probeErr, a.fetchAnchors(ctx, now))
+}
+
+// fetchAnchors measures the anchors, looking them up again once a day
+func (a *AtlasMonitor) fetchAnchors(ctx context.Context, now time.Time) error {
+	a.mu.Lock()
+	anchors, stale := a.anchors, now.Sub(a.refreshed) > atlasAnchorRefresh
+	a.mu.Unlock()
+	if stale {
+		found, err := a.lookupAnchors(ctx)
+		if err != nil {
+			return fmt.Errorf("anchor lookup: %w", err)
+		}
+		anchors = found
+		a.mu.Lock()
+		a.anchors, a.refreshed = anchors, now
+		a.mu.Unlock()
+		log.Printf("📍 RIPE Atlas: following %d anchor(s) in %s", len(anchors), a.country)
+	}
+
+	var failed int
+	for _, anchor := range anchors {
+		status, err := a.measure(ctx, anchor, now)
+		if err != nil {
+			failed++
+			log.Printf("⚠️  RIPE Atlas results of %s unavailable: %v", anchor.fqdn, err)
+			continue
+		}
+		if status.Probes == 0 {
+			continue // No probe reported in the window: keep the last status
+		}
+
+		a.mu.Lock()
+		before := a.statuses[anchor.id]
+		if before != nil {
+			status.Traceroute = before.Traceroute
+		}
+		a.statuses[anchor.id] = status
+		_, tracing := a.traces[anchor.id]
+		lost := before != nil && before.Reachable && !status.Reachable
+		a.mu.Unlock()
+
+		if lost && a.traceroute && !tracing {
+			a.scheduleTrace(ctx, anchor, now)
+		}
+	}
+	a.collectTraces(ctx, now)
+
+	if failed > 0 && failed == len(anchors) {
+		return fmt.Errorf("no results for any of %d anchor(s)", failed)
+	}
+	return nil
+}
+
+// countProbes counts the connected and disconnected probes in the country
+func (a *AtlasMonitor) countProbes(ctx context.Context, now time.Time) (*models.AtlasProbes, error) {
+	count := func(status int) (int, error) {
+		var page struct {
+			Count int `json:"count"`
+		}
+		err := a.get(ctx, fmt.Sprintf("/probes/?country_code=%s&status=%d&fields=id&page_size=1", a.country, status), &page)
+		return page.Count, err
+	}
+	connected, err := count(1)
+	if err != nil {
+		return nil, err
+	}
+	disconnected, err := count(2)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AtlasProbes{Connected: connected, Disconnected: disconnected, CheckedAt: now}, nil
+}
+
+// lookupAnchors lists the active anchors in the country and their mesh ping measurements
+func (a *AtlasMonitor) lookupAnchors(ctx context.Context) ([]atlasAnchor, error) {
+	var page struct {
+		Results []struct {
+			ID         int    `json:"id"`
+			FQDN       string `json:"fqdn"`
+			IPv4       string `json:"ip_v4"`
+			ASv4       int    `json:"as_v4"`
+			City       string `json:"city"`
+			IsDisabled bool   `json:"is_disabled"`
+		} `json:"results"`
+	}
+	if err := a.get(ctx, fmt.Sprintf("/anchors/?country=%s&page_size=500", a.country), &page); err != nil {
+		return nil, err
+	}
+
+	var anchors []atlasAnchor
+	for _, result := range page.Results {
+		if result.IsDisabled || result.IPv4 == "" {
+			continue
+		}
+		if len(a.only) > 0 && !a.only[strings.ToLower(result.FQDN)] && !a.only[strconv.Itoa(result.ID)] {
+			continue
+		}
+		anchor := atlasAnchor{id: result.ID, fqdn: result.FQDN, address: result.IPv4, city: result.City}
+		if result.ASv4 != 0 {
+			anchor.asn = fmt.Sprintf("AS%d", result.ASv4)
+		}
+
+		var measurements struct {
+			Results []struct {
+				Measurement string `json:"measurement"` // URL of the measurement
+				Type        string `json:"type"`
+				IsMesh      bool   `json:"is_mesh"`
+			} `json:"results"`
+		}
+		if err := a.get(ctx, fmt.Sprintf("/anchor-measurements/?target=%d&page_size=100", result.ID), &measurements); err != nil {
+			return nil, fmt.Errorf("measurements of %s: %w", result.FQDN, err)
+		}
+		for _, m := range measurements.Results {
+			if m.Type != "ping" || !m.IsMesh {
+				continue
+			}
+			if id, err := strconv.Atoi(path.Base(strings.TrimSuffix(m.Measurement, "/"))); err == nil {
+				anchor.measurements = append(anchor.measurements, id)
+			}
+		}
+		if len(anchor.measurements) == 0 {
+			log.Printf("⚠️  RIPE Atlas anchor %s has no mesh ping measurement, skipping it", result.FQDN)
+			continue
+		}
+		anchors = append(anchors, anchor)
+	}
+	return anchors, nil
+}
+
+// measure reads the IPv4 mesh ping results of the window before now and
+// keeps the latest result of every probe
+func (a *AtlasMonitor) measure(ctx context.Context, anchor atlasAnchor, now time.Time) (*models.AtlasAnchor, error) {
+	type pingResult struct {
+		AF        int     `json:"af"`
+		Probe     int     `json:"prb_id"`
+		Avg       float64 `json:"avg"` // -1 without reply
+		Sent      int     `json:"sent"`
+		Received  int     `json:"rcvd"`
+		Timestamp int64   `json:"timestamp"`
+	}
+	latest := make(map[int]pingResult)
+	for _, id := range anchor.measurements {
+		var results []pingResult
+		query := fmt.Sprintf("/measurements/%d/results/?start=%d&stop=%d", id, now.Add(-a.window).Unix(), now.Unix())
+		if err := a.get(ctx, query, &results); err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			if result.AF != 4 {
+				continue
+			}
+			if prev, ok := latest[result.Probe]; !ok || result.Timestamp > prev.Timestamp {
+				latest[result.Probe] = result
+			}
+		}
+	}
+
+	status := &models.AtlasAnchor{ID: anchor.id, FQDN: anchor.fqdn, Address: anchor.address, City: anchor.city,
+		ASN: anchor.asn, LastCheck: now}
+	var sent, received int
+	var rtts []float64
+	for _, result := range latest {
+		status.Probes++
+		sent += result.Sent
+		received += result.Received
+		if result.Received > 0 {
+			status.Reached++
+			if result.Avg >= 0 {
+				rtts = append(rtts, result.Avg)
+			}
+		}
+	}
+	if sent > 0 {
+		status.LossPct = float64(sent-received) / float64(sent) * 100
+	}
+	if len(rtts) > 0 {
+		sort.Float64s(rtts)
+		status.RTT = time.Duration(rtts[len(rtts)/2] * float64(time.Millisecond))
+	}
+	status.Reachable = status.Probes > 0 && status.Reached*2 >= status.Probes
+	return status, nil
+}
+
+// scheduleTrace starts a one-off ICMP traceroute from probes worldwide toward an anchor
+func (a *AtlasMonitor) scheduleTrace(ctx context.Context, anchor atlasAnchor, now time.Time) {
+	request := map[string]interface{}{
+		"definitions": []map[string]interface{}{{
+			"target":      anchor.address,
+			"af":          4,
+			"type":        "traceroute",
+			"protocol":    "ICMP",
+			"description": "NetBlocks: " + anchor.fqdn + " stopped answering",
+		}},
+		"probes":    []map[string]interface{}{{"type": "area", "value": "WW", "requested": a.probes}},
+		"is_oneoff": true,
+	}
+	var created struct {
+		Measurements []int `json:"measurements"`
+	}
+	if err := a.post(ctx, "/measurements/", request, &created); err != nil {
+		log.Printf("⚠️  Failed to schedule a RIPE Atlas traceroute to %s: %v", anchor.fqdn, err)
+		return
+	}
+	if len(created.Measurements) == 0 {
+		return
+	}
+	log.Printf("🛰  Scheduled RIPE Atlas traceroute %d to %s from %d probes", created.Measurements[0], anchor.fqdn, a.probes)
+	a.mu.Lock()
+	a.traces[anchor.id] = atlasTrace{measurement: created.Measurements[0], scheduled: now}
+	a.mu.Unlock()
+}
+
+// collectTraces summarizes the scheduled traceroutes whose probes all
+// reported, or that ran out of time, into their anchor's status
+func (a *AtlasMonitor) collectTraces(ctx context.Context, now time.Time) {
+	a.mu.Lock()
+	traces := make(map[int]atlasTrace, len(a.traces))
+	for id, trace := range a.traces {
+		traces[id] = trace
+	}
+	a.mu.Unlock()
+
+	for anchorID, trace := range traces {
+		var results []struct {
+			Probe   int    `json:"prb_id"`
+			DstAddr string `json:"dst_addr"`
+			Result  []struct {
+				Hop    int `json:"hop"`
+				Result []struct {
+					From string `json:"from"`
+				} `json:"result"`
+			} `json:"result"`
+		}
+		if err := a.get(ctx, fmt.Sprintf("/measurements/%d/results/", trace.measurement), &results); err != nil {
+			log.Printf("⚠️  RIPE Atlas traceroute %d unavailable: %v", trace.measurement, err)
+			continue
+		}
+		expired := now.Sub(trace.scheduled) > atlasTraceWait
+		if len(results) < a.probes && !expired {
+			continue
+		}
+
+		reached := 0
+		ends := make(map[string]int) // "<address> (hop N)" by probe count
+		for _, result := range results {
+			last, lastHop := "", 0
+			for _, hop := range result.Result {
+				for _, reply := range hop.Result {
+					if reply.From != "" {
+						last, lastHop = reply.From, hop.Hop
+					}
+				}
+			}
+			switch {
+			case last == result.DstAddr:
+				reached++
+			case last != "":
+				ends[fmt.Sprintf("%s (hop %d)", last, lastHop)]++
+			}
+		}
+		summary := "no traceroute results"
+		if len(results) > 0 {
+			summary = fmt.Sprintf("traceroute reached it from %d/%d probes", reached, len(results))
+			if end, count := mostCommon(ends); count > 0 {
+				summary += fmt.Sprintf("; %d stop at %s", count, end)
+			}
+		}
+
+		a.mu.Lock()
+		if status := a.statuses[anchorID]; status != nil {
+			status.Traceroute = summary
+		}
+		delete(a.traces, anchorID)
+		a.mu.Unlock()
+		log.Printf("🛰  RIPE Atlas traceroute %d: %s", trace.measurement, summary)
+	}
+}
+
+// mostCommon returns the key with the highest count (ties broken by key)
+func mostCommon(counts map[string]int) (string, int) {
+	best, bestCount := "", 0
+	for key, count := range counts {
+		if count > bestCount || (count == bestCount && key < best) {
+			best, bestCount = key, count
+		}
+	}
+	return best, bestCount
+}
+
+// get decodes an API response
+func (a *AtlasMonitor) get(ctx context.Context, query string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.base+query, nil)
+	if err != nil {
+		return err
+	}
+	return a.do(req, v)
+}
+
+// post sends an authenticated API request
+func (a *AtlasMonitor) post(ctx context.Context, query string, body, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.base+query, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return a.do(req, v)
+}
+
+func (a *AtlasMonitor) do(req *http.Request, v interface{}) error {
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	if a.key != "" {
+		req.Header.Set("Authorization", "Key "+a.key)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("RIPE Atlas API status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode RIPE Atlas response: %w", err)
+	}
+	return nil
+}
+
+// atlasReachablePercent returns the percentage of anchors reachable from abroad
+func atlasReachablePercent(anchors []*models.AtlasAnchor) float64 {
+	if len(anchors) == 0 {
+		return 0
+	}
+	reachable := 0
+	for _, anchor := range anchors {
+		if anchor.Reachable {
+			reachable++
+		}
+	}
+	return float64(reachable) / float64(len(anchors)) * 100.0
+}
+
+// addProbeSeries plots the connected RIPE Atlas probes between minX and maxX
+// on the secondary Y axis of a traffic chart
+func addProbeSeries(graph *chart.Chart, counts []models.AtlasProbes, x func(time.Time) float64, minX, maxX float64) {
+	var xValues, yValues []float64
+	peak := 1.0
+	for _, count := range counts {
+		at := x(count.CheckedAt)
+		if at < minX || at > maxX {
+			continue
+		}
+		xValues = append(xValues, at)
+		yValues = append(yValues, float64(count.Connected))
+		peak = math.Max(peak, float64(count.Connected))
+	}
+	if len(xValues) < 2 {
+		return
+	}
+	if xValues[0] > xValues[len(xValues)-1] {
+		// Hours-ago axes run backwards in time
+		for i, j := 0, len(xValues)-1; i < j; i, j = i+1, j-1 {
+			xValues[i], xValues[j] = xValues[j], xValues[i]
+			yValues[i], yValues[j] = yValues[j], yValues[i]
+		}
+	}
+
+	graph.YAxisSecondary = chart.YAxis{
+		Name:  "RIPE Atlas Probes Connected",
+		Range: &chart.ContinuousRange{Min: 0, Max: math.Ceil(peak * 1.2)},
+		ValueFormatter: func(v interface{}) string {
+			if vf, ok := v.(float64); ok {
+				return chartLocale.Number(vf, 0)
+			}
+			return ""
+		},
+	}
+	graph.Series = append(graph.Series, chart.ContinuousSeries{
+		Name:    "RIPE Atlas probes",
+		XValues: xValues,
+		YValues: yValues,
+		YAxis:   chart.YAxisSecondary,
+		Style: chart.Style{
+			StrokeColor: drawing.Color{R: 156, G: 39, B: 176, A: 255}, // Purple, apart from the status colors of traffic
+			StrokeWidth: 2,
+		},
+	})
+}
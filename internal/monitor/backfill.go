@@ -0,0 +1,193 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+)
+
+// Historical data sources for backfilling a fresh history store
+
+// radarChunk is the date range of one Radar request; longer ranges are split
+const radarChunk = 7 * 24 * time.Hour
+
+// iodaAPI is the base URL of the IODA v2 API
+const iodaAPI = "https://api.ioda.inetintel.cc.gatech.edu/v2"
+
+// FetchRadarHistory fetches Iran's hourly traffic of the configured dataset
+// (see SetSeries) from Cloudflare Radar between from and to. Levels are
+// normalized to the peak of each UTC day, matching the 24h window the live monitor stores
+func (tm *TrafficMonitor) FetchRadarHistory(ctx context.Context, from, to time.Time) ([]history.TrafficPoint, error) {
+	type sample struct {
+		at    time.Time
+		value float64
+	}
+	var samples []sample
+	for start := from.UTC(); start.Before(to); start = start.Add(radarChunk) {
+		end := start.Add(radarChunk)
+		if end.After(to) {
+			end = to.UTC()
+		}
+		url := tm.seriesURL(tm.location, fmt.Sprintf("dateStart=%s&dateEnd=%s&aggInterval=1h",
+			start.Format(time.RFC3339), end.Format(time.RFC3339)))
+
+		body, err := tm.getRadar(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("radar %s - %s: %w", start.Format("2006-01-02"), end.Format("2006-01-02"), err)
+		}
+		timestamps, values, found := extractSeries(body)
+		if !found || len(timestamps) != len(values) {
+			return nil, fmt.Errorf("radar %s - %s: no timestamped series in response", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		}
+		for i, ts := range timestamps {
+			at, err := time.Parse(time.RFC3339, ts)
+			if err != nil {
+				continue
+			}
+			samples = append(samples, sample{at: at.UTC(), value: values[i]})
+		}
+	}
+
+	// Normalize each value to the peak of its UTC day
+	peaks := make(map[string]float64)
+	for _, s := range samples {
+		day := s.at.Format("2006-01-02")
+		if s.value > peaks[day] {
+			peaks[day] = s.value
+		}
+	}
+	points := make([]history.TrafficPoint, 0, len(samples))
+	for _, s := range samples {
+		peak := peaks[s.at.Format("2006-01-02")]
+		if peak <= 0 {
+			continue
+		}
+		points = append(points, history.TrafficPoint{Timestamp: s.at, Level: s.value / peak * 100.0})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp) })
+	return points, nil
+}
+
+// getRadar performs an authenticated Radar request and returns the result field
+func (tm *TrafficMonitor) getRadar(ctx context.Context, url string) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+
+	resp, err := tm.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cloudflare API status %d: %s", resp.StatusCode, strings.TrimSpace(string(bodyBytes[:min(300, len(bodyBytes))])))
+	}
+
+	var apiResp CloudflareRadarResponse
+	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+		return nil, err
+	}
+	if !apiResp.Success {
+		return nil, fmt.Errorf("cloudflare API returned success=false")
+	}
+	return apiResp.Result, nil
+}
+
+// iodaSignal is one time series of the IODA signals API
+type iodaSignal struct {
+	EntityCode string     `json:"entityCode"`
+	Datasource string     `json:"datasource"`
+	From       int64      `json:"from"`
+	Step       int64      `json:"step"`
+	Values     []*float64 `json:"values"` // null where IODA has no measurement
+}
+
+// FetchIODAHistory fetches IODA's BGP signal (visible /24 prefixes) of an ASN
+// and converts it into hourly availability buckets: a sample counts as up when
+// at least half of the series' median prefix count is visible
+func FetchIODAHistory(ctx context.Context, client *http.Client, asn string, from, to time.Time) ([]history.Bucket, error) {
+	url := fmt.Sprintf("%s/signals/raw/asn/%s?from=%d&until=%d&datasource=bgp",
+		iodaAPI, strings.TrimPrefix(strings.ToUpper(asn), "AS"), from.Unix(), to.Unix())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IODA API status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Data [][]iodaSignal `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode IODA response: %w", err)
+	}
+
+	var signal *iodaSignal
+	for _, group := range payload.Data {
+		for i := range group {
+			if group[i].Datasource == "bgp" && group[i].Step > 0 {
+				signal = &group[i]
+			}
+		}
+	}
+	if signal == nil {
+		return nil, nil
+	}
+
+	var observed []float64
+	for _, v := range signal.Values {
+		if v != nil {
+			observed = append(observed, *v)
+		}
+	}
+	if len(observed) == 0 {
+		return nil, nil
+	}
+	sort.Float64s(observed)
+	threshold := observed[len(observed)/2] / 2
+
+	buckets := make(map[int64]*history.Bucket)
+	for i, v := range signal.Values {
+		if v == nil {
+			continue
+		}
+		hour := time.Unix(signal.From+int64(i)*signal.Step, 0).UTC().Truncate(time.Hour)
+		b, ok := buckets[hour.Unix()]
+		if !ok {
+			b = &history.Bucket{Hour: hour}
+			buckets[hour.Unix()] = b
+		}
+		b.Total++
+		if *v >= threshold && *v > 0 {
+			b.Up++
+		}
+	}
+
+	list := make([]history.Bucket, 0, len(buckets))
+	for _, b := range buckets {
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Hour.Before(list[j].Hour) })
+	return list, nil
+}
@@ -4,26 +4,59 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/netblocks/netblocks/internal/clock"
 	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/crash"
 	"github.com/netblocks/netblocks/internal/models"
 )
 
 // RISLiveClient handles BGP monitoring via RIS Live WebSocket API
 type RISLiveClient struct {
-	conn          *websocket.Conn
-	asnStatuses   map[string]*models.ASNStatus
-	mu            sync.RWMutex
+	conn           *websocket.Conn
+	asnStatuses    map[string]*models.ASNStatus
+	mu             sync.RWMutex
 	subscribedASNs map[string]bool
-	done          chan struct{}
-	url           string
-	reconnectMu   sync.Mutex
-	reconnecting  bool
+	prefixes       map[string]*prefixRoutes // Prefixes followed with prefix filters, by prefix (guarded by mu)
+	origins        map[string]string        // Monitored ASN announcing each prefix, as withdrawals name none (guarded by mu)
+	withdrawals    map[string][]time.Time   // Recent withdrawals by ASN or followed prefix (guarded by mu)
+	stormWindow    time.Duration            // Sliding window of the withdrawal storms
+	stormAt        int                      // Withdrawals within stormWindow that make a storm
+	done           chan struct{}
+	url            string
+	reconnectMu    sync.Mutex
+	reconnecting   bool
+	reconnects     int                      // Successful reconnects since start (guarded by reconnectMu)
+	country        string                   // ISO code reported in the ASN statuses
+	asnName        func(asn string) string  // Display name of an ASN in the statuses (guarded by mu)
+	clock          clock.Clock              // Time source of the staleness checks
+	staleAfter     time.Duration            // Silence before an ASN is considered offline (guarded by mu)
+	staleOverrides map[string]time.Duration // staleAfter of single ASNs (guarded by mu)
+	lastMessage    time.Time                // When the last UPDATE arrived, or the start (guarded by mu)
 }
 
+// prefixRoutes is the routing state of a followed prefix: the routes each RIS
+// peer has to it or to more specific prefixes within it
+type prefixRoutes struct {
+	network *net.IPNet
+	routes  map[string]map[string]bool // Announced prefixes by RIS peer (collector/address)
+	status  models.PrefixStatus
+}
+
+// Withdrawal storm defaults
+const (
+	defaultStormWindow    = 5 * time.Minute
+	defaultStormThreshold = 100
+)
+
+// asnStaleAfter is how long an ASN may stay silent before it is considered offline
+const asnStaleAfter = 30 * time.Minute
+
 // RISMessage represents a message from RIS Live
 type RISMessage struct {
 	Type string          `json:"type"`
@@ -32,40 +65,45 @@ type RISMessage struct {
 
 // RISUpdateMessage represents a BGP UPDATE message
 type RISUpdateMessage struct {
-	Timestamp   float64 `json:"timestamp"`
-	Peer        string  `json:"peer"`
-	PeerASN     string  `json:"peer_asn"`
-	ID          string  `json:"id"`
-	Host        string  `json:"host"`
-	Type        string  `json:"type"`
-	Path        []interface{} `json:"path,omitempty"`
-	Announcements []struct {
-		NextHop string   `json:"next_hop"`
-		Prefixes []string `json:"prefixes"`
-	} `json:"announcements,omitempty"`
-	Withdrawals []string `json:"withdrawals,omitempty"`
+	Timestamp     float64           `json:"timestamp"`
+	Peer          string            `json:"peer"`
+	PeerASN       string            `json:"peer_asn"`
+	ID            string            `json:"id"`
+	Host          string            `json:"host"`
+	Type          string            `json:"type"`
+	Path          []interface{}     `json:"path,omitempty"`
+	Announcements []RISAnnouncement `json:"announcements,omitempty"`
+	Withdrawals   []string          `json:"withdrawals,omitempty"`
+}
+
+// RISAnnouncement is the prefixes of an UPDATE announced with one next hop
+type RISAnnouncement struct {
+	NextHop  string   `json:"next_hop"`
+	Prefixes []string `json:"prefixes"`
 }
 
 // RISSubscribeMessage represents a subscription request
 type RISSubscribeMessage struct {
-	Type string                 `json:"type"`
-	Data RISSubscribeData       `json:"data"`
+	Type string           `json:"type"`
+	Data RISSubscribeData `json:"data"`
 }
 
 // RISSubscribeData contains subscription parameters
 type RISSubscribeData struct {
-	Type         string   `json:"type"`
-	PeerASN      string   `json:"peer_asn,omitempty"`
-	PrefixMore   string   `json:"prefix_more,omitempty"`
-	PrefixLess   string   `json:"prefix_less,omitempty"`
-	PrefixExact  string   `json:"prefix_exact,omitempty"`
-	Host         string   `json:"host,omitempty"`
+	Type          string        `json:"type"`
+	PeerASN       string        `json:"peer_asn,omitempty"`
+	Prefix        string        `json:"prefix,omitempty"`
+	MoreSpecific  bool          `json:"moreSpecific,omitempty"` // Also more specific prefixes of Prefix
+	PrefixMore    string        `json:"prefix_more,omitempty"`
+	PrefixLess    string        `json:"prefix_less,omitempty"`
+	PrefixExact   string        `json:"prefix_exact,omitempty"`
+	Host          string        `json:"host,omitempty"`
 	SocketOptions SocketOptions `json:"socketOptions"`
 }
 
 // SocketOptions for RIS Live subscription
 type SocketOptions struct {
-	IncludeRaw bool `json:"include_raw"`
+	IncludeRaw  bool `json:"include_raw"`
 	Acknowledge bool `json:"acknowledge"`
 }
 
@@ -79,80 +117,161 @@ func NewRISLiveClient(url string) (*RISLiveClient, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to RIS Live: %w", err)
 	}
+	return newRISLiveClient(conn, url), nil
+}
 
+// newRISLiveClient creates the client state around a connection (nil for the
+// backends that feed updates from elsewhere, see BGPStreamClient)
+func newRISLiveClient(conn *websocket.Conn, url string) *RISLiveClient {
 	client := &RISLiveClient{
-		conn:          conn,
-		asnStatuses:   make(map[string]*models.ASNStatus),
+		conn:           conn,
+		asnStatuses:    make(map[string]*models.ASNStatus),
 		subscribedASNs: make(map[string]bool),
-		done:          make(chan struct{}),
-		url:           url,
-		reconnecting:  false,
+		prefixes:       make(map[string]*prefixRoutes),
+		origins:        make(map[string]string),
+		withdrawals:    make(map[string][]time.Time),
+		stormWindow:    defaultStormWindow,
+		stormAt:        defaultStormThreshold,
+		done:           make(chan struct{}),
+		url:            url,
+		reconnecting:   false,
+		country:        "IR",
+		asnName:        config.GetASNName,
+		clock:          clock.Real,
+		staleAfter:     asnStaleAfter,
+		lastMessage:    time.Now(),
 	}
 
-	return client, nil
+	return client
+}
+
+// SetCountry sets the country reported for ASNs subscribed afterwards
+func (c *RISLiveClient) SetCountry(country string) {
+	c.country = country
+}
+
+// SetASNNames sets how the ASNs are named in the statuses, e.g. with the
+// names of the country's profile (see config.Config.GetASNName)
+func (c *RISLiveClient) SetASNNames(name func(asn string) string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.asnName = name
+}
+
+// SetClock replaces the system clock, e.g. with a fake one in tests
+func (c *RISLiveClient) SetClock(clk clock.Clock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = clk
+	c.lastMessage = clk.Now()
 }
 
 // reconnect attempts to reconnect to RIS Live WebSocket
 func (c *RISLiveClient) reconnect() error {
 	c.reconnectMu.Lock()
 	defer c.reconnectMu.Unlock()
-	
+
 	if c.reconnecting {
 		return fmt.Errorf("reconnection already in progress")
 	}
-	
+
 	c.reconnecting = true
 	defer func() { c.reconnecting = false }()
-	
+
 	log.Printf("Attempting to reconnect to RIS Live WebSocket...")
-	
+
 	// Close existing connection if any
 	if c.conn != nil {
 		c.conn.Close()
 	}
-	
+
 	// Wait a bit before reconnecting
 	time.Sleep(2 * time.Second)
-	
+
 	// Reconnect
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
 	}
-	
+
 	conn, _, err := dialer.Dial(c.url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to reconnect: %w", err)
 	}
-	
+
 	c.conn = conn
-	
+	c.reconnects++
+
 	// Resubscribe to all ASNs
 	c.mu.Lock()
 	asns := make([]string, 0, len(c.subscribedASNs))
 	for asn := range c.subscribedASNs {
 		asns = append(asns, asn)
 	}
+	prefixes := make([]string, 0, len(c.prefixes))
+	for prefix := range c.prefixes {
+		prefixes = append(prefixes, prefix)
+	}
 	c.mu.Unlock()
-	
+
 	for _, asn := range asns {
-		if err := c.SubscribeToASN(asn); err != nil {
+		if err := c.subscribeASN(asn); err != nil {
 			log.Printf("Warning: Failed to resubscribe to ASN %s after reconnect: %v", asn, err)
 		}
 	}
-	
+	// The routes seen before are kept: RIS Live does not replay them
+	for _, prefix := range prefixes {
+		if err := c.subscribePrefix(prefix); err != nil {
+			log.Printf("Warning: Failed to resubscribe to prefix %s after reconnect: %v", prefix, err)
+		}
+	}
+
 	log.Printf("Successfully reconnected to RIS Live WebSocket")
 	return nil
 }
 
+// ReconnectCount returns how many times the WebSocket has been reconnected since start
+func (c *RISLiveClient) ReconnectCount() int {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	return c.reconnects
+}
+
+// SeenCount returns how many subscribed ASNs have had a BGP update since start, and how many are subscribed
+func (c *RISLiveClient) SeenCount() (seen, total int) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, status := range c.asnStatuses {
+		if !status.LastSeen.IsZero() {
+			seen++
+		}
+	}
+	return seen, len(c.asnStatuses)
+}
+
 // SubscribeToASN subscribes to BGP updates for a specific ASN
 func (c *RISLiveClient) SubscribeToASN(asn string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.subscribedASNs[asn] {
+	c.mu.RLock()
+	subscribed := c.subscribedASNs[asn]
+	c.mu.RUnlock()
+	if subscribed {
 		return nil // Already subscribed
 	}
 
+	if err := c.subscribeASN(asn); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.trackASN(asn)
+	c.mu.Unlock()
+	return nil
+}
+
+// subscribeASN sends the peer filter of a subscribed ASN
+func (c *RISLiveClient) subscribeASN(asn string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	// Remove "AS" prefix if present
 	asnNumber := asn
 	if len(asn) > 2 && asn[:2] == "AS" {
@@ -165,45 +284,169 @@ func (c *RISLiveClient) SubscribeToASN(asn string) error {
 			Type:    "UPDATE",
 			PeerASN: asnNumber,
 			SocketOptions: SocketOptions{
-				IncludeRaw: false,
+				IncludeRaw:  false,
 				Acknowledge: false,
 			},
 		},
 	}
-
 	if err := c.conn.WriteJSON(subscribeMsg); err != nil {
 		return fmt.Errorf("failed to subscribe to ASN %s: %w", asn, err)
 	}
+	return nil
+}
 
+// trackASN adds an ASN to the monitored ones with an initial status (called with mu held)
+func (c *RISLiveClient) trackASN(asn string) {
 	c.subscribedASNs[asn] = true
-	
+
 	// Initialize ASN status if not exists
 	if _, exists := c.asnStatuses[asn]; !exists {
 		c.asnStatuses[asn] = &models.ASNStatus{
 			ASN:        asn,
-			Country:    "IR",
-			Name:       config.GetASNName(asn),
+			Country:    c.country,
+			Name:       c.asnName(asn),
 			Connected:  false,
 			LastSeen:   time.Time{},
-			LastUpdate: time.Now(),
+			LastUpdate: c.clock.Now(),
 		}
 	}
+}
+
+// SubscribeToPrefix follows the announcements and withdrawals of a prefix and
+// of the more specific prefixes within it
+func (c *RISLiveClient) SubscribeToPrefix(prefix string) error {
+	key, added, err := c.trackPrefix(prefix)
+	if err != nil || !added {
+		return err
+	}
 
-	// Log subscription silently (only log errors)
-	// Removed verbose subscription logging
+	if err := c.subscribePrefix(key); err != nil {
+		c.mu.Lock()
+		delete(c.prefixes, key)
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// trackPrefix adds a prefix to the followed ones; added is false if it already was
+func (c *RISLiveClient) trackPrefix(prefix string) (key string, added bool, err error) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return "", false, fmt.Errorf("invalid prefix %q: %w", prefix, err)
+	}
+	key = network.String()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.prefixes[key]; exists {
+		return key, false, nil // Already subscribed
+	}
+	c.prefixes[key] = &prefixRoutes{
+		network: network,
+		routes:  make(map[string]map[string]bool),
+		status:  models.PrefixStatus{Prefix: key, LastUpdate: c.clock.Now()},
+	}
+	return key, true, nil
+}
+
+// subscribePrefix sends the prefix filter of a followed prefix
+func (c *RISLiveClient) subscribePrefix(prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	subscribeMsg := RISSubscribeMessage{
+		Type: "ris_subscribe",
+		Data: RISSubscribeData{
+			Type:         "UPDATE",
+			Prefix:       prefix,
+			MoreSpecific: true,
+		},
+	}
+	if err := c.conn.WriteJSON(subscribeMsg); err != nil {
+		return fmt.Errorf("failed to subscribe to prefix %s: %w", prefix, err)
+	}
 	return nil
 }
 
+// PrefixStatuses returns the routing state of the followed prefixes
+func (c *RISLiveClient) PrefixStatuses() map[string]*models.PrefixStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.prefixes) == 0 {
+		return nil
+	}
+	result := make(map[string]*models.PrefixStatus, len(c.prefixes))
+	now := c.clock.Now()
+	for prefix, state := range c.prefixes {
+		status := state.status
+		status.Withdrawals, status.WithdrawalStorm = c.withdrawalsOf(prefix, now)
+		result[prefix] = &status
+	}
+	return result
+}
+
+// UnsubscribeFromASN stops BGP updates for an ASN and drops its status
+func (c *RISLiveClient) UnsubscribeFromASN(asn string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.untrackASN(asn) {
+		return nil
+	}
+
+	asnNumber := asn
+	if len(asn) > 2 && asn[:2] == "AS" {
+		asnNumber = asn[2:]
+	}
+	unsubscribeMsg := RISSubscribeMessage{
+		Type: "ris_unsubscribe",
+		Data: RISSubscribeData{
+			Type:    "UPDATE",
+			PeerASN: asnNumber,
+		},
+	}
+	if err := c.conn.WriteJSON(unsubscribeMsg); err != nil {
+		return fmt.Errorf("failed to unsubscribe from ASN %s: %w", asn, err)
+	}
+	return nil
+}
+
+// untrackASN drops an ASN and its state; false if it was not monitored (called with mu held)
+func (c *RISLiveClient) untrackASN(asn string) bool {
+	if !c.subscribedASNs[asn] {
+		return false
+	}
+	delete(c.subscribedASNs, asn)
+	delete(c.asnStatuses, asn)
+	delete(c.withdrawals, asn)
+	for prefix, origin := range c.origins {
+		if origin == asn {
+			delete(c.origins, prefix)
+		}
+	}
+	return true
+}
+
 // Start starts listening for BGP messages
 func (c *RISLiveClient) Start() {
-	go c.readMessages()
+	crash.Go("RIS Live reader", c.readMessages)
 }
 
-// Stop stops the client
+// Stop stops the client, closing the WebSocket with a close frame so RIS Live
+// ends the session cleanly
 func (c *RISLiveClient) Stop() {
 	close(c.done)
-	if c.conn != nil {
-		c.conn.Close()
+	c.mu.RLock()
+	conn := c.conn
+	c.mu.RUnlock()
+	if conn != nil {
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "shutting down")
+		if err := conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(5*time.Second)); err != nil {
+			log.Printf("Failed to send RIS Live close frame: %v", err)
+		}
+		conn.Close()
 	}
 }
 
@@ -217,7 +460,7 @@ func (c *RISLiveClient) GetASNStatuses() map[string]*models.ASNStatus {
 		result[asn] = &models.ASNStatus{
 			ASN:        status.ASN,
 			Country:    status.Country,
-			Name:       status.Name,
+			Name:       c.asnName(asn),
 			Connected:  status.Connected,
 			LastSeen:   status.LastSeen,
 			LastUpdate: status.LastUpdate,
@@ -231,7 +474,7 @@ func (c *RISLiveClient) readMessages() {
 	lastHealthLog := time.Now()
 	lastPing := time.Now()
 	pingInterval := 30 * time.Second
-	
+
 	for {
 		select {
 		case <-c.done:
@@ -242,7 +485,7 @@ func (c *RISLiveClient) readMessages() {
 				c.mu.RLock()
 				conn := c.conn
 				c.mu.RUnlock()
-				
+
 				if conn != nil {
 					if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second)); err != nil {
 						log.Printf("Failed to send ping: %v", err)
@@ -251,23 +494,29 @@ func (c *RISLiveClient) readMessages() {
 					}
 				}
 			}
-			
+
 			// Set read deadline
 			c.mu.RLock()
 			conn := c.conn
 			c.mu.RUnlock()
-			
+
 			if conn == nil {
 				time.Sleep(1 * time.Second)
 				continue
 			}
-			
+
 			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-			
+
 			var msg RISMessage
 			if err := conn.ReadJSON(&msg); err != nil {
+				// Stop closed the connection; don't reconnect
+				select {
+				case <-c.done:
+					return
+				default:
+				}
 				log.Printf("Error reading RIS Live message: %v", err)
-				
+
 				// Check if connection is closed or network error
 				if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 					log.Printf("RIS Live WebSocket connection closed, attempting to reconnect...")
@@ -296,7 +545,7 @@ func (c *RISLiveClient) readMessages() {
 			}
 
 			messageCount++
-			
+
 			// Log connection health less frequently (every 10000 messages or every 30 minutes)
 			// Reduced verbosity for cleaner output
 			if messageCount%10000 == 0 || time.Since(lastHealthLog) > 30*time.Minute {
@@ -329,10 +578,18 @@ func (c *RISLiveClient) handleRISMessage(data json.RawMessage) {
 	if update.Type != "UPDATE" {
 		return
 	}
+	c.handleUpdate(&update)
+}
 
+// handleUpdate applies a BGP UPDATE to the ASN, prefix and withdrawal state
+func (c *RISLiveClient) handleUpdate(update *RISUpdateMessage) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.lastMessage = c.clock.Now()
+	c.observePrefixes(update)
+	c.observeWithdrawals(update)
+
 	// Check if this update is from or about any of our monitored ASNs
 	for asn := range c.subscribedASNs {
 		asnNumber := asn
@@ -345,7 +602,7 @@ func (c *RISLiveClient) handleRISMessage(data json.RawMessage) {
 			if status, exists := c.asnStatuses[asn]; exists {
 				status.Connected = true
 				status.LastSeen = time.Unix(int64(update.Timestamp), 0)
-				status.LastUpdate = time.Now()
+				status.LastUpdate = c.clock.Now()
 			}
 		}
 
@@ -366,7 +623,7 @@ func (c *RISLiveClient) handleRISMessage(data json.RawMessage) {
 							if status, exists := c.asnStatuses[asn]; exists {
 								status.Connected = true
 								status.LastSeen = time.Unix(int64(update.Timestamp), 0)
-								status.LastUpdate = time.Now()
+								status.LastUpdate = c.clock.Now()
 							}
 						}
 					}
@@ -378,58 +635,279 @@ func (c *RISLiveClient) handleRISMessage(data json.RawMessage) {
 				if status, exists := c.asnStatuses[asn]; exists {
 					status.Connected = true
 					status.LastSeen = time.Unix(int64(update.Timestamp), 0)
-					status.LastUpdate = time.Now()
+					status.LastUpdate = c.clock.Now()
 				}
 			}
 		}
 	}
 }
 
+// observePrefixes applies the announcements and withdrawals of an update to
+// the followed prefixes they fall in (called with mu held)
+func (c *RISLiveClient) observePrefixes(update *RISUpdateMessage) {
+	if len(c.prefixes) == 0 {
+		return
+	}
+	peer := update.Host + "/" + update.Peer
+	at := time.Unix(int64(update.Timestamp), 0)
+	origin := originASN(update.Path)
+	for _, announcement := range update.Announcements {
+		for _, prefix := range announcement.Prefixes {
+			c.routeChanged(peer, prefix, true, origin, at)
+		}
+	}
+	for _, prefix := range update.Withdrawals {
+		c.routeChanged(peer, prefix, false, "", at)
+	}
+}
+
+// routeChanged records a peer's route to a prefix as announced or withdrawn
+// in every followed prefix covering it
+func (c *RISLiveClient) routeChanged(peer, prefix string, announced bool, origin string, at time.Time) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return
+	}
+	key := network.String()
+	for _, state := range c.prefixes {
+		if !state.covers(network) {
+			continue
+		}
+		routes := state.routes[peer]
+		if announced {
+			if routes == nil {
+				routes = make(map[string]bool)
+				state.routes[peer] = routes
+			}
+			routes[key] = true
+			state.status.LastAnnounced = at
+			if origin != "" {
+				state.status.Origin = origin
+				state.status.OriginRoute = key
+			}
+		} else {
+			if !routes[key] {
+				continue // A route this client never saw
+			}
+			delete(routes, key)
+			if len(routes) == 0 {
+				delete(state.routes, peer)
+			}
+			state.status.LastWithdrawn = at
+		}
+		state.status.Peers = len(state.routes)
+		state.status.MaxPeers = max(state.status.MaxPeers, state.status.Peers)
+		state.status.Announced = state.status.Peers > 0
+		state.status.LastUpdate = c.clock.Now()
+	}
+}
+
+// covers reports whether a prefix is the followed prefix or one within it
+func (s *prefixRoutes) covers(network *net.IPNet) bool {
+	ones, bits := network.Mask.Size()
+	followedOnes, followedBits := s.network.Mask.Size()
+	return bits == followedBits && ones >= followedOnes && s.network.Contains(network.IP)
+}
+
+// SetWithdrawalStorm sets the window and threshold of the withdrawal storms
+// (nil: the defaults)
+func (c *RISLiveClient) SetWithdrawalStorm(settings *config.WithdrawalStorm) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stormWindow, c.stormAt = defaultStormWindow, defaultStormThreshold
+	if settings == nil {
+		return
+	}
+	if d, err := time.ParseDuration(settings.Window); err == nil && d > 0 {
+		c.stormWindow = d
+	}
+	if settings.Threshold > 0 {
+		c.stormAt = settings.Threshold
+	}
+}
+
+// observeWithdrawals counts the withdrawals of an update against the
+// monitored ASNs and the followed prefixes (called with mu held). Withdrawals
+// carry no AS path: they count for the ASN that announced the prefix, or the
+// peer ASN if it is monitored
+func (c *RISLiveClient) observeWithdrawals(update *RISUpdateMessage) {
+	origin, originMonitored := c.monitoredASN(strings.TrimPrefix(originASN(update.Path), "AS"))
+	for _, announcement := range update.Announcements {
+		for _, prefix := range announcement.Prefixes {
+			if originMonitored {
+				c.origins[prefix] = origin
+			} else {
+				delete(c.origins, prefix) // Announced by another ASN now
+			}
+		}
+	}
+	if len(update.Withdrawals) == 0 {
+		return
+	}
+
+	now := c.clock.Now()
+	peer, peerMonitored := c.monitoredASN(update.PeerASN)
+	for _, prefix := range update.Withdrawals {
+		if asn, ok := c.origins[prefix]; ok {
+			c.countWithdrawal(asn, now)
+		} else if peerMonitored {
+			c.countWithdrawal(peer, now)
+		}
+		if len(c.prefixes) == 0 {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(prefix); err == nil {
+			for key, state := range c.prefixes {
+				if state.covers(network) {
+					c.countWithdrawal(key, now)
+				}
+			}
+		}
+	}
+}
+
+// monitoredASN returns the subscribed ASN of an AS number, e.g. "AS12880" for "12880"
+func (c *RISLiveClient) monitoredASN(number string) (string, bool) {
+	if number == "" {
+		return "", false
+	}
+	for asn := range c.subscribedASNs {
+		if asn == number || strings.TrimPrefix(asn, "AS") == number {
+			return asn, true
+		}
+	}
+	return "", false
+}
+
+// countWithdrawal adds a withdrawal of an ASN or followed prefix to its
+// window, dropping the ones that left it (called with mu held)
+func (c *RISLiveClient) countWithdrawal(key string, at time.Time) {
+	recent := c.withdrawals[key]
+	cutoff := at.Add(-c.stormWindow)
+	drop := 0
+	for drop < len(recent) && recent[drop].Before(cutoff) {
+		drop++
+	}
+	// Far beyond the threshold the exact count no longer matters
+	if len(recent)-drop >= c.stormAt*10 {
+		drop++
+	}
+	c.withdrawals[key] = append(recent[drop:], at)
+}
+
+// withdrawalsOf returns how many withdrawals of an ASN or followed prefix fall
+// in the window ending at now, and whether they make a storm (called with mu held)
+func (c *RISLiveClient) withdrawalsOf(key string, now time.Time) (int, bool) {
+	cutoff := now.Add(-c.stormWindow)
+	count := 0
+	for _, at := range c.withdrawals[key] {
+		if !at.Before(cutoff) {
+			count++
+		}
+	}
+	return count, count >= c.stormAt
+}
+
+// originASN returns the origin of an AS path, e.g. "AS58224" ("" for an AS_SET)
+func originASN(path []interface{}) string {
+	if len(path) == 0 {
+		return ""
+	}
+	switch v := path[len(path)-1].(type) {
+	case float64:
+		return fmt.Sprintf("AS%.0f", v)
+	case string:
+		return "AS" + v
+	}
+	return ""
+}
+
+// Announcements returns the prefixes whose last announcement came from a
+// monitored ASN, with that origin ASN
+func (c *RISLiveClient) Announcements() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	announcements := make(map[string]string, len(c.origins))
+	for prefix, origin := range c.origins {
+		announcements[prefix] = origin
+	}
+	return announcements
+}
+
+// SetStaleAfter changes how long an ASN may stay silent before it is
+// considered offline, e.g. shorter during an incident
+func (c *RISLiveClient) SetStaleAfter(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.staleAfter = d
+}
+
+// SetStaleOverrides sets how long single ASNs may stay silent before they are
+// considered offline, replacing the stale time for them
+func (c *RISLiveClient) SetStaleOverrides(overrides map[string]time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.staleOverrides = overrides
+}
+
+// QuietFor returns how long no UPDATE arrived from RIS Live, since start if none did
+func (c *RISLiveClient) QuietFor() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clock.Now().Sub(c.lastMessage)
+}
+
 // CheckConnectivity performs a connectivity check for all monitored ASNs
 // Returns all subscribed ASNs, ensuring they're all included even if no updates received yet
 func (c *RISLiveClient) CheckConnectivity() map[string]*models.ASNStatus {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	now := time.Now()
+	now := c.clock.Now()
 	result := make(map[string]*models.ASNStatus)
 
 	// Ensure all subscribed ASNs are included in the result
 	// This handles the case where statuses might not be initialized yet
 	for asn := range c.subscribedASNs {
 		if status, exists := c.asnStatuses[asn]; exists {
-			// Consider disconnected if no update in last 30 minutes (increased from 10)
-			// This is more appropriate for stable ASNs that may not send frequent updates
+			// Consider disconnected if no update within the stale time (30 minutes
+			// unless configured), which may differ per ASN
+			staleAfter := c.staleAfter
+			if d, ok := c.staleOverrides[asn]; ok {
+				staleAfter = d
+			}
 			timeSinceLastSeen := now.Sub(status.LastSeen)
-			connected := status.Connected && timeSinceLastSeen < 30*time.Minute
-			
+			connected := status.Connected && timeSinceLastSeen < staleAfter
+
 			// Log when ASNs are marked offline for debugging
 			if !connected && status.Connected {
-				log.Printf("ASN %s (%s) marked offline - last seen %v ago", 
+				log.Printf("ASN %s (%s) marked offline - last seen %v ago",
 					asn, status.Name, timeSinceLastSeen)
 			}
-			
+
+			withdrawals, storm := c.withdrawalsOf(asn, now)
 			result[asn] = &models.ASNStatus{
-				ASN:        status.ASN,
-				Country:    status.Country,
-				Name:       status.Name,
-				Connected:  connected,
-				LastSeen:   status.LastSeen,
-				LastUpdate: status.LastUpdate,
+				ASN:             status.ASN,
+				Country:         status.Country,
+				Name:            c.asnName(asn),
+				Connected:       connected,
+				LastSeen:        status.LastSeen,
+				LastUpdate:      status.LastUpdate,
+				Withdrawals:     withdrawals,
+				WithdrawalStorm: storm,
 			}
 		} else {
 			// Initialize status if it doesn't exist (shouldn't happen, but safety check)
 			result[asn] = &models.ASNStatus{
 				ASN:        asn,
-				Country:    "IR",
-				Name:       config.GetASNName(asn),
+				Country:    c.country,
+				Name:       c.asnName(asn),
 				Connected:  false,
 				LastSeen:   time.Time{},
-				LastUpdate: time.Now(),
+				LastUpdate: c.clock.Now(),
 			}
 		}
 	}
 
 	return result
 }
-
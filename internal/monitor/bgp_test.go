@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/clock"
+)
+
+// seenASN returns a RIS Live client following asn, last heard from at now
+func seenASN(t *testing.T, clk *clock.Fake, asn string) *RISLiveClient {
+	t.Helper()
+	c := newRISLiveClient(nil, "")
+	c.SetClock(clk)
+	c.mu.Lock()
+	c.trackASN(asn)
+	c.mu.Unlock()
+	c.handleUpdate(&RISUpdateMessage{Type: "UPDATE", Timestamp: float64(clk.Now().Unix()), PeerASN: asn[2:]})
+	return c
+}
+
+func TestASNGoesOfflineAfterStaleWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := seenASN(t, clk, "AS44244")
+	c.SetStaleAfter(30 * time.Minute)
+
+	if !c.CheckConnectivity()["AS44244"].Connected {
+		t.Fatal("ASN offline right after an update")
+	}
+	clk.Advance(29 * time.Minute)
+	if !c.CheckConnectivity()["AS44244"].Connected {
+		t.Fatal("ASN offline within the stale window")
+	}
+	clk.Advance(time.Minute)
+	if c.CheckConnectivity()["AS44244"].Connected {
+		t.Fatal("ASN still connected after the stale window")
+	}
+
+	// An update brings it back
+	c.handleUpdate(&RISUpdateMessage{Type: "UPDATE", Timestamp: float64(clk.Now().Unix()), Path: []interface{}{float64(12880), float64(44244)}})
+	if !c.CheckConnectivity()["AS44244"].Connected {
+		t.Fatal("ASN still offline after an update through it")
+	}
+}
+
+func TestASNStaleOverride(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	c := seenASN(t, clk, "AS44244")
+	c.SetStaleAfter(30 * time.Minute)
+	c.SetStaleOverrides(map[string]time.Duration{"AS44244": 10 * time.Minute})
+
+	clk.Advance(10 * time.Minute)
+	if c.CheckConnectivity()["AS44244"].Connected {
+		t.Fatal("ASN still connected after its own stale window")
+	}
+}
@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/clock"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// BGPSource is a BGP backend: it follows the monitored ASNs and prefixes and
+// reports their routing state, whichever feed the updates come from
+type BGPSource interface {
+	SubscribeToASN(asn string) error
+	UnsubscribeFromASN(asn string) error
+	SubscribeToPrefix(prefix string) error
+	Start()
+	Stop()
+
+	// CheckConnectivity returns the monitored ASNs, stale ones disconnected
+	CheckConnectivity() map[string]*models.ASNStatus
+	GetASNStatuses() map[string]*models.ASNStatus
+	PrefixStatuses() map[string]*models.PrefixStatus
+	// Announcements returns the prefixes last announced by a monitored ASN, with that origin ASN
+	Announcements() map[string]string
+	// SeenCount returns how many monitored ASNs had an update since start, and how many are monitored
+	SeenCount() (seen, total int)
+	// QuietFor returns how long no update arrived
+	QuietFor() time.Duration
+	// ReconnectCount returns how often the feed had to be reconnected
+	ReconnectCount() int
+
+	SetStaleAfter(d time.Duration)
+	// SetStaleOverrides sets the stale time of single ASNs
+	SetStaleOverrides(overrides map[string]time.Duration)
+	SetClock(clk clock.Clock)
+}
+
+// BGP backends (bgp_backend)
+const (
+	BGPBackendRISLive   = "ris_live"
+	BGPBackendBGPStream = "bgpstream"
+)
+
+// NewBGPSource creates the BGP backend of the config
+func NewBGPSource(cfg *config.Config) (BGPSource, error) {
+	switch cfg.BGPBackend {
+	case "", BGPBackendRISLive:
+		client, err := NewRISLiveClient(cfg.RISLiveURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create RIS Live client: %w", err)
+		}
+		client.SetCountry(cfg.Country)
+		client.SetASNNames(cfg.GetASNName)
+		client.SetWithdrawalStorm(cfg.WithdrawalStorm)
+		return client, nil
+	case BGPBackendBGPStream:
+		client := NewBGPStreamClient(cfg.BGPStream)
+		client.SetCountry(cfg.Country)
+		client.SetASNNames(cfg.GetASNName)
+		client.SetWithdrawalStorm(cfg.WithdrawalStorm)
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown bgp_backend %q", cfg.BGPBackend)
+	}
+}
@@ -0,0 +1,345 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/crash"
+)
+
+// BGPStream settings
+const (
+	defaultBGPStreamBroker   = "https://broker.bgpstream.caida.org/v2/data"
+	defaultBGPStreamInterval = 5 * time.Minute
+	bgpStreamLookback        = time.Hour        // How far back the broker is asked for dumps
+	bgpStreamForget          = 2 * time.Hour    // How long read dumps are remembered
+	bgpStreamLag             = 45 * time.Minute // Usual delay from an update to its dump being published
+)
+
+// defaultBGPStreamCollectors are a RouteViews and a RIS collector, so the feed
+// does not depend on either project alone
+var defaultBGPStreamCollectors = []string{"route-views2", "rrc00"}
+
+// BGPStreamClient follows the monitored ASNs and prefixes in the MRT update
+// dumps of RouteViews and RIS collectors, found through the BGPStream broker.
+// It shares the routing state of RISLiveClient and feeds it the dumped
+// updates, so statuses, prefixes and withdrawal storms work the same, only
+// delayed by the dump publication
+type BGPStreamClient struct {
+	*RISLiveClient
+	broker     string
+	collectors []string
+	interval   time.Duration
+	client     *http.Client
+
+	readMu    sync.Mutex
+	read      map[string]time.Time // End of the dumps already read, by URL (guarded by readMu)
+	newestEnd time.Time            // End of the newest dump read (guarded by readMu)
+}
+
+// bgpStreamDump is a dump file listed by the broker
+type bgpStreamDump struct {
+	Collector   string `json:"collector"`
+	Type        string `json:"type"`
+	InitialTime int64  `json:"initialTime"`
+	Duration    int64  `json:"duration"`
+	URL         string `json:"url"`
+}
+
+// NewBGPStreamClient creates the BGPStream backend (settings may be nil for the defaults)
+func NewBGPStreamClient(settings *config.BGPStream) *BGPStreamClient {
+	c := &BGPStreamClient{
+		RISLiveClient: newRISLiveClient(nil, ""),
+		broker:        defaultBGPStreamBroker,
+		collectors:    defaultBGPStreamCollectors,
+		interval:      defaultBGPStreamInterval,
+		client:        &http.Client{Timeout: 5 * time.Minute},
+		read:          make(map[string]time.Time),
+	}
+	c.staleAfter = asnStaleAfter + bgpStreamLag
+	if settings != nil {
+		if settings.BrokerURL != "" {
+			c.broker = settings.BrokerURL
+		}
+		if len(settings.Collectors) > 0 {
+			c.collectors = settings.Collectors
+		}
+		if d, err := time.ParseDuration(settings.Interval); err == nil && d > 0 {
+			c.interval = d
+		}
+	}
+	return c
+}
+
+// SubscribeToASN adds an ASN to the ones followed in the dumps
+func (c *BGPStreamClient) SubscribeToASN(asn string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trackASN(asn)
+	return nil
+}
+
+// UnsubscribeFromASN drops an ASN and its status
+func (c *BGPStreamClient) UnsubscribeFromASN(asn string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.untrackASN(asn)
+	return nil
+}
+
+// SubscribeToPrefix follows a prefix and the more specific prefixes within it
+func (c *BGPStreamClient) SubscribeToPrefix(prefix string) error {
+	_, _, err := c.trackPrefix(prefix)
+	return err
+}
+
+// SetStaleAfter changes how long an ASN may stay silent before it is
+// considered offline, plus the dump publication delay
+func (c *BGPStreamClient) SetStaleAfter(d time.Duration) {
+	c.RISLiveClient.SetStaleAfter(d + bgpStreamLag)
+}
+
+// SetStaleOverrides sets the stale times of single ASNs, plus the dump
+// publication delay
+func (c *BGPStreamClient) SetStaleOverrides(overrides map[string]time.Duration) {
+	lagged := make(map[string]time.Duration, len(overrides))
+	for asn, d := range overrides {
+		lagged[asn] = d + bgpStreamLag
+	}
+	c.RISLiveClient.SetStaleOverrides(lagged)
+}
+
+// QuietFor returns how far the newest dump read lags behind beyond the usual
+// publication delay, since start if none was read
+func (c *BGPStreamClient) QuietFor() time.Duration {
+	c.readMu.Lock()
+	newestEnd := c.newestEnd
+	c.readMu.Unlock()
+	if newestEnd.IsZero() {
+		return c.RISLiveClient.QuietFor()
+	}
+	c.mu.RLock()
+	now := c.clock.Now()
+	c.mu.RUnlock()
+	if quiet := now.Sub(newestEnd) - bgpStreamLag; quiet > 0 {
+		return quiet
+	}
+	return 0
+}
+
+// Start starts polling the broker for new dumps
+func (c *BGPStreamClient) Start() {
+	crash.Go("BGPStream reader", c.run)
+}
+
+func (c *BGPStreamClient) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-c.done
+		cancel()
+	}()
+
+	log.Printf("📡 Reading BGP update dumps of %s through the BGPStream broker", strings.Join(c.collectors, ", "))
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		if err := c.poll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  BGPStream poll failed: %v", err)
+		}
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads the dumps published since the last poll, oldest first
+func (c *BGPStreamClient) poll(ctx context.Context) error {
+	now := time.Now()
+	dumps, err := c.listDumps(ctx, now.Add(-bgpStreamLookback), now)
+	if err != nil {
+		return err
+	}
+	sort.Slice(dumps, func(i, j int) bool { return dumps[i].InitialTime < dumps[j].InitialTime })
+
+	c.readMu.Lock()
+	for dumpURL, end := range c.read {
+		if now.Sub(end) > bgpStreamForget {
+			delete(c.read, dumpURL)
+		}
+	}
+	c.readMu.Unlock()
+
+	for _, dump := range dumps {
+		if dump.Type != "" && dump.Type != "updates" {
+			continue
+		}
+		end := time.Unix(dump.InitialTime+dump.Duration, 0)
+		c.readMu.Lock()
+		_, done := c.read[dump.URL]
+		c.readMu.Unlock()
+		if done {
+			continue
+		}
+
+		count, err := c.readDump(ctx, dump)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("⚠️  BGPStream dump %s failed: %v", dump.URL, err)
+			continue // Retried on the next poll
+		}
+		log.Printf("📡 BGPStream %s dump until %s: %d updates", dump.Collector, end.UTC().Format("15:04"), count)
+
+		c.readMu.Lock()
+		c.read[dump.URL] = end
+		if end.After(c.newestEnd) {
+			c.newestEnd = end
+		}
+		c.readMu.Unlock()
+	}
+	return nil
+}
+
+// listDumps asks the broker for the update dumps of the collectors overlapping an interval
+func (c *BGPStreamClient) listDumps(ctx context.Context, from, until time.Time) ([]bgpStreamDump, error) {
+	query := url.Values{
+		"intervals[]": {fmt.Sprintf("%d,%d", from.Unix(), until.Unix())},
+		"types[]":     {"updates"},
+	}
+	for _, collector := range c.collectors {
+		query.Add("collectors[]", collector)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.broker+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("BGPStream broker status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// Older broker versions list the dumps as dumpFiles
+	var payload struct {
+		Error interface{} `json:"error"`
+		Data  struct {
+			Resources []bgpStreamDump `json:"resources"`
+			DumpFiles []bgpStreamDump `json:"dumpFiles"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode BGPStream broker response: %w", err)
+	}
+	if payload.Error != nil {
+		return nil, fmt.Errorf("BGPStream broker error: %v", payload.Error)
+	}
+	return append(payload.Data.Resources, payload.Data.DumpFiles...), nil
+}
+
+// readDump downloads a dump and applies its relevant updates
+func (c *BGPStreamClient) readDump(ctx context.Context, dump bgpStreamDump) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", dump.URL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	body, err := decompressMRT(resp.Body, dump.URL)
+	if err != nil {
+		return 0, err
+	}
+
+	asns := c.asnNumbers()
+	count := 0
+	err = readMRT(body, dump.Collector, func(update *RISUpdateMessage) {
+		count++
+		if c.relevant(update, asns) {
+			c.handleUpdate(update)
+		}
+	})
+	return count, err
+}
+
+// asnNumbers returns the monitored AS numbers without the "AS" prefix
+func (c *BGPStreamClient) asnNumbers() map[string]bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	numbers := make(map[string]bool, len(c.subscribedASNs))
+	for asn := range c.subscribedASNs {
+		numbers[strings.TrimPrefix(asn, "AS")] = true
+	}
+	return numbers
+}
+
+// relevant reports whether an update can change the monitored state: a dump
+// holds the updates of every peer, far more than the RIS Live subscriptions
+func (c *BGPStreamClient) relevant(update *RISUpdateMessage, asns map[string]bool) bool {
+	if len(update.Withdrawals) > 0 || asns[update.PeerASN] {
+		return true
+	}
+	for _, item := range update.Path {
+		switch v := item.(type) {
+		case float64:
+			if asns[strconv.FormatFloat(v, 'f', 0, 64)] {
+				return true
+			}
+		case []interface{}:
+			for _, setItem := range v {
+				if number, ok := setItem.(float64); ok && asns[strconv.FormatFloat(number, 'f', 0, 64)] {
+					return true
+				}
+			}
+		}
+	}
+
+	// Announcements of followed prefixes, or of prefixes a monitored ASN announced before
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.prefixes) > 0 {
+		return true
+	}
+	for _, announcement := range update.Announcements {
+		for _, prefix := range announcement.Prefixes {
+			if _, ok := c.origins[prefix]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckBroker asks the broker for the update dumps of the last lookback
+// period and returns how many it lists
+func (c *BGPStreamClient) CheckBroker(ctx context.Context) (int, error) {
+	now := time.Now()
+	dumps, err := c.listDumps(ctx, now.Add(-bgpStreamLookback), now)
+	return len(dumps), err
+}
@@ -0,0 +1,222 @@
+package monitor
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+const (
+	bundleRecordWindow  = 6 * time.Hour  // Signed measurements included before the incident
+	bundleHistoryWindow = 24 * time.Hour // Availability history included before the incident
+	bundleCooldown      = time.Hour      // A target already bundled is not bundled again within this time
+)
+
+// bundleFile is one file of an evidence bundle
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// bundleIncident writes an evidence bundle for the critical events of a cycle,
+// unless all of their targets were bundled within the cooldown
+func (m *Monitor) bundleIncident(prev, current *models.MonitoringResult, events []models.Event) {
+	if m.config.BundleDir == "" || current == nil {
+		return
+	}
+	now := time.Now()
+	fresh := false
+	for _, event := range events {
+		if event.Severity != models.SeverityCritical || event.Resolved {
+			continue
+		}
+		key := event.Kind + ":" + event.Target
+		if now.Sub(m.bundled[key]) >= bundleCooldown {
+			fresh = true
+		}
+		m.bundled[key] = now
+	}
+	if !fresh {
+		return
+	}
+
+	go func() {
+		path, err := m.writeBundle(now, prev, current, events)
+		if err != nil {
+			log.Printf("⚠️  Failed to write evidence bundle: %v", err)
+			return
+		}
+		log.Printf("📦 Evidence bundle written to %s", path)
+	}()
+}
+
+// writeBundle assembles the measurements, charts, snapshots and narrative of an
+// incident into a zip file and returns its path
+func (m *Monitor) writeBundle(now time.Time, prev, current *models.MonitoringResult, events []models.Event) (string, error) {
+	var files []bundleFile
+	addJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", name, err)
+		}
+		files = append(files, bundleFile{name, data})
+		return nil
+	}
+	addImage := func(name string, buf *bytes.Buffer) {
+		if buf != nil && buf.Len() > 0 {
+			files = append(files, bundleFile{name, buf.Bytes()})
+		}
+	}
+
+	files = append(files, bundleFile{"narrative.txt", []byte(bundleNarrative(now, events))})
+	if err := addJSON("events.json", events); err != nil {
+		return "", err
+	}
+	if err := addJSON("snapshot.json", current); err != nil {
+		return "", err
+	}
+	if prev != nil {
+		if err := addJSON("previous_snapshot.json", prev); err != nil {
+			return "", err
+		}
+	}
+
+	addImage("charts/status.png", current.StatusImage)
+	if current.TrafficData != nil {
+		addImage("charts/traffic.png", current.TrafficData.ChartBuffer)
+	}
+	if len(current.ASTrafficData) > 0 {
+		addImage("charts/asn_traffic.png", current.ASTrafficData[0].ChartBuffer)
+	}
+	addImage("charts/uptime_7d.png", current.UptimeChart)
+	addImage("charts/asn_sparklines.png", current.ASNSparklines)
+	addImage("charts/provinces.png", current.ProvinceMap)
+
+	var ev *history.Evidence
+	if m.evidence != nil {
+		records, err := m.evidence.Records(now.Add(-bundleRecordWindow))
+		if err != nil {
+			return "", err
+		}
+		var raw bytes.Buffer
+		ev = &history.Evidence{PublicKey: m.evidence.PublicKey()}
+		for _, r := range records {
+			line, err := json.Marshal(r)
+			if err != nil {
+				return "", err
+			}
+			raw.Write(append(line, '\n'))
+			ev.Records = append(ev.Records, history.RecordHash{Timestamp: r.Timestamp, Kind: r.Kind, SHA256: r.SHA256, Signature: r.Signature})
+		}
+		files = append(files, bundleFile{"measurements.jsonl", raw.Bytes()})
+	}
+	if m.history != nil {
+		for _, format := range []string{history.FormatJSON, history.FormatCSV} {
+			data, err := m.history.Export(format, now.Add(-bundleHistoryWindow), ev)
+			if err != nil {
+				return "", err
+			}
+			files = append(files, bundleFile{"history_24h." + format, data})
+		}
+	}
+
+	// The manifest fixes every file's hash; with signing enabled it is signed too
+	var manifest strings.Builder
+	for _, f := range files {
+		sum := sha256.Sum256(f.data)
+		fmt.Fprintf(&manifest, "%s  %s\n", hex.EncodeToString(sum[:]), f.name)
+	}
+	files = append(files, bundleFile{"MANIFEST.sha256", []byte(manifest.String())})
+	if m.evidence != nil {
+		digest, signature := m.evidence.Sign([]byte(manifest.String()))
+		files = append(files, bundleFile{"MANIFEST.sig", []byte(fmt.Sprintf(
+			"SHA-256 of MANIFEST.sha256: %s\nEd25519 signature (base64): %s\nPublic key (base64): %s\n", digest, signature, m.evidence.PublicKey()))})
+	}
+	files = append(files, bundleFile{"README.txt", []byte(bundleReadme(m.evidence != nil))})
+
+	if err := os.MkdirAll(m.config.BundleDir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(m.config.BundleDir, "netblocks-incident-"+now.UTC().Format("20060102-150405")+".zip")
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	archive := zip.NewWriter(out)
+	for _, f := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: now})
+		if err == nil {
+			_, err = w.Write(f.data)
+		}
+		if err != nil {
+			out.Close()
+			os.Remove(tmp)
+			return "", fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, os.Rename(tmp, path)
+}
+
+// bundleNarrative lists the incident's narrative and events as plain text
+func bundleNarrative(now time.Time, events []models.Event) string {
+	sorted := make([]models.Event, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Severity == models.SeverityCritical && sorted[j].Severity != models.SeverityCritical
+	})
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "NetBlocks incident evidence, %s\n\n", now.UTC().Format("2006-01-02 15:04:05 UTC"))
+	for _, narrative := range models.Narratives(sorted) {
+		builder.WriteString(narrative + "\n\n")
+	}
+	builder.WriteString("Events:\n")
+	for _, event := range sorted {
+		fmt.Fprintf(&builder, "- [%s] %s %s/%s: %s\n", strings.ToUpper(event.Severity),
+			event.Timestamp.UTC().Format("15:04 UTC"), event.Kind, event.Target, event.Message)
+	}
+	return builder.String()
+}
+
+// bundleReadme explains the bundle to readers without access to the monitor
+func bundleReadme(signed bool) string {
+	text := `This archive documents an Internet disruption in Iran detected by NetBlocks.
+
+narrative.txt            Plain-language summary and the detected events
+events.json              The events of the detection cycle
+snapshot.json            All measurements (BGP, DNS, traffic) of that cycle
+previous_snapshot.json   The measurements of the cycle before, for comparison
+charts/                  Status image, traffic, ASN traffic, uptime and sparkline charts
+history_24h.json/.csv    Hourly availability and traffic of the preceding 24 hours
+MANIFEST.sha256          SHA-256 of every file (check with: sha256sum -c MANIFEST.sha256)
+`
+	if signed {
+		text += `measurements.jsonl       Signed measurement log of the preceding 6 hours; each record's
+                         sha256 chains the previous record's hash and its payload
+MANIFEST.sig             Ed25519 signature of MANIFEST.sha256 by the monitor's public key
+`
+	}
+	return text
+}
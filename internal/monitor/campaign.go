@@ -0,0 +1,342 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Campaign limits
+const (
+	defaultCampaignDuration = 6 * time.Hour
+	defaultCampaignInterval = time.Minute
+	maxCampaignDuration     = 7 * 24 * time.Hour
+)
+
+// campaignProfile is a parsed config.Campaign
+type campaignProfile struct {
+	name       string
+	duration   time.Duration
+	interval   time.Duration // Monitoring cycle and DNS checks
+	traffic    time.Duration // Cloudflare Radar traffic fetches (0: unchanged)
+	asnTraffic time.Duration // Cloudflare Radar traffic per ASN fetches (0: unchanged)
+	asns       []string
+	dnsServers []config.DNSServer
+	captureAll bool // Capture every DNS exchange
+	atlas      bool // Fetch RIPE Atlas results at the campaign interval
+	autoStart  string
+}
+
+// startedBy reports whether an event of a severity starts the profile's campaign
+func (p *campaignProfile) startedBy(severity string) bool {
+	switch p.autoStart {
+	case models.SeverityCritical:
+		return severity == models.SeverityCritical
+	case models.SeverityWarning:
+		return severity == models.SeverityWarning || severity == models.SeverityCritical
+	}
+	return false
+}
+
+// campaigns are the campaign profiles, the running campaign and the targets
+// measured outside campaigns
+type campaigns struct {
+	profiles []*campaignProfile
+
+	mu       sync.Mutex
+	active   *models.Campaign
+	profile  *campaignProfile   // Profile of the running campaign
+	baseASNs map[string]bool    // ASNs of the config or the remote list
+	baseDNS  []config.DNSServer // DNS servers of the config or the remote list
+}
+
+// newCampaigns parses the campaign profiles of the config
+func newCampaigns(cfg *config.Config, asns []string, dnsServers []config.DNSServer) *campaigns {
+	c := &campaigns{baseASNs: make(map[string]bool, len(asns)), baseDNS: dnsServers}
+	for _, asn := range asns {
+		c.baseASNs[asn] = true
+	}
+	parse := func(value string, fallback time.Duration) time.Duration {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		return fallback
+	}
+	for _, campaign := range cfg.Campaigns {
+		profile := &campaignProfile{
+			name:       campaign.Name,
+			duration:   parse(campaign.Duration, defaultCampaignDuration),
+			interval:   parse(campaign.Interval, defaultCampaignInterval),
+			traffic:    parse(campaign.TrafficInterval, 0),
+			asnTraffic: parse(campaign.ASNTrafficInterval, 0),
+			asns:       campaign.ASNs,
+			dnsServers: reachableDNSServers(cfg, campaign.DNSServers),
+			autoStart:  campaign.AutoStart,
+		}
+		for _, dataset := range campaign.Datasets {
+			profile.captureAll = profile.captureAll || dataset == config.DatasetDNSCapture
+			profile.atlas = profile.atlas || dataset == config.DatasetRIPEAtlas
+		}
+		c.profiles = append(c.profiles, profile)
+	}
+	return c
+}
+
+// lookup returns the profile of a name (nil if there is none)
+func (c *campaigns) lookup(name string) *campaignProfile {
+	for _, profile := range c.profiles {
+		if strings.EqualFold(profile.name, name) {
+			return profile
+		}
+	}
+	return nil
+}
+
+// running returns a copy of the running campaign and its profile (nil if none)
+func (c *campaigns) running() (*models.Campaign, *campaignProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active == nil {
+		return nil, nil
+	}
+	active := *c.active
+	return &active, c.profile
+}
+
+// extraASN reports whether an ASN is only monitored for the running campaign
+func (c *campaigns) extraASN(asn string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.profile == nil || c.baseASNs[asn] {
+		return false
+	}
+	for _, extra := range c.profile.asns {
+		if extra == asn {
+			return true
+		}
+	}
+	return false
+}
+
+// CampaignProfiles returns the names of the configured campaign profiles
+func (m *Monitor) CampaignProfiles() []string {
+	names := make([]string, 0, len(m.campaigns.profiles))
+	for _, profile := range m.campaigns.profiles {
+		names = append(names, profile.name)
+	}
+	return names
+}
+
+// Campaign returns the running measurement campaign (nil if none)
+func (m *Monitor) Campaign() *models.Campaign {
+	active, _ := m.campaigns.running()
+	return active
+}
+
+// StartCampaign starts a campaign of a profile by hand, replacing the running
+// one; a zero duration uses the profile's
+func (m *Monitor) StartCampaign(name string, duration time.Duration, by, reason string) (*models.Campaign, error) {
+	profile := m.campaigns.lookup(name)
+	if profile == nil {
+		if len(m.campaigns.profiles) == 0 {
+			return nil, fmt.Errorf("no campaigns are configured")
+		}
+		return nil, fmt.Errorf("unknown campaign %q (configured: %s)", name, strings.Join(m.CampaignProfiles(), ", "))
+	}
+	if duration <= 0 {
+		duration = profile.duration
+	}
+	if duration < time.Minute || duration > maxCampaignDuration {
+		return nil, fmt.Errorf("campaign duration must be between 1m and %v", maxCampaignDuration)
+	}
+
+	var events []models.Event
+	if active, _ := m.campaigns.running(); active != nil {
+		events = append(events, m.endCampaign(fmt.Sprintf("replaced by %s", profile.name))...)
+	}
+	now := m.clock.Now().UTC().Truncate(time.Second)
+	campaign := &models.Campaign{Name: profile.name, Trigger: models.CampaignManual, StartedBy: by,
+		Reason: strings.TrimSpace(reason), Started: now, Ends: now.Add(duration)}
+	events = append(events, m.startCampaign(profile, campaign))
+	if m.onEvents != nil {
+		m.onEvents(events)
+	}
+	started := *campaign
+	return &started, nil
+}
+
+// StopCampaign ends the running campaign before its time
+func (m *Monitor) StopCampaign(by string) (*models.Campaign, error) {
+	active, _ := m.campaigns.running()
+	if active == nil {
+		return nil, fmt.Errorf("no campaign is running")
+	}
+	events := m.endCampaign("stopped by " + by)
+	if m.onEvents != nil && len(events) > 0 {
+		m.onEvents(events)
+	}
+	return active, nil
+}
+
+// autoCampaign starts the campaign of the first profile whose auto_start the
+// events reach; a running campaign of that profile is extended instead, one
+// of another profile is left alone
+func (m *Monitor) autoCampaign(events []models.Event) []models.Event {
+	for _, event := range events {
+		if event.Resolved || event.Kind == "campaign" || event.Kind == "annotation" || event.Kind == "slo" {
+			continue
+		}
+		for _, profile := range m.campaigns.profiles {
+			if !profile.startedBy(event.Severity) {
+				continue
+			}
+			now := m.clock.Now().UTC().Truncate(time.Second)
+			m.campaigns.mu.Lock()
+			active := m.campaigns.active
+			if active != nil {
+				if active.Name == profile.name && now.Add(profile.duration).After(active.Ends) {
+					active.Ends = now.Add(profile.duration)
+					log.Printf("📈 Campaign %s extended until %s by: %s", active.Name, active.Ends.Format(time.RFC3339), event.Message)
+				}
+				m.campaigns.mu.Unlock()
+				return nil
+			}
+			m.campaigns.mu.Unlock()
+
+			campaign := &models.Campaign{Name: profile.name, Trigger: models.CampaignAuto, Reason: event.Message,
+				Started: now, Ends: now.Add(profile.duration)}
+			return []models.Event{m.startCampaign(profile, campaign)}
+		}
+	}
+	return nil
+}
+
+// expireCampaign ends the running campaign once its time is up
+func (m *Monitor) expireCampaign() []models.Event {
+	active, _ := m.campaigns.running()
+	if active == nil || m.clock.Now().Before(active.Ends) {
+		return nil
+	}
+	return m.endCampaign("time is up")
+}
+
+// startCampaign applies a profile and reports the campaign as an event
+func (m *Monitor) startCampaign(profile *campaignProfile, campaign *models.Campaign) models.Event {
+	m.applyCampaign(campaign, profile)
+
+	var extras []string
+	if len(profile.asns) > 0 {
+		extras = append(extras, fmt.Sprintf("+%d ASNs", len(profile.asns)))
+	}
+	if len(profile.dnsServers) > 0 {
+		extras = append(extras, fmt.Sprintf("+%d DNS servers", len(profile.dnsServers)))
+	}
+	if profile.captureAll {
+		extras = append(extras, "all DNS exchanges captured")
+	}
+	if profile.atlas {
+		extras = append(extras, "RIPE Atlas every "+formatDuration(profile.interval))
+	}
+	message := fmt.Sprintf("📈 Measurement campaign %s started until %s UTC: checks every %s", campaign.Name, campaign.Ends.Format("Jan 2 15:04"), formatDuration(profile.interval))
+	if len(extras) > 0 {
+		message += ", " + strings.Join(extras, ", ")
+	}
+	if campaign.Reason != "" {
+		message += " - " + campaign.Reason
+	}
+	log.Print(message)
+	m.noteCampaign(fmt.Sprintf("Campaign %s started", campaign.Name), campaign)
+	return models.Event{Timestamp: campaign.Started, Kind: "campaign", Target: campaign.Name, Severity: models.SeverityInfo, Message: message}
+}
+
+// endCampaign restores the normal measurements and reports the end as an event
+// (none if no campaign is running)
+func (m *Monitor) endCampaign(why string) []models.Event {
+	active, _ := m.campaigns.running()
+	if active == nil {
+		return nil
+	}
+	m.applyCampaign(nil, nil)
+	now := m.clock.Now().UTC().Truncate(time.Second)
+	message := fmt.Sprintf("📉 Measurement campaign %s ended after %s (%s)", active.Name, formatDuration(now.Sub(active.Started)), why)
+	log.Print(message)
+	m.noteCampaign(fmt.Sprintf("Campaign %s ended", active.Name), active)
+	return []models.Event{{Timestamp: now, Kind: "campaign", Target: active.Name, Severity: models.SeverityInfo, Message: message}}
+}
+
+// noteCampaign marks the start or end of a campaign on the charts
+func (m *Monitor) noteCampaign(text string, campaign *models.Campaign) {
+	if m.history == nil {
+		return
+	}
+	author := campaign.StartedBy
+	if author == "" {
+		author = campaign.Trigger
+	}
+	if err := m.history.Annotate(models.Annotation{Time: m.clock.Now(), Text: text, Author: author}); err != nil {
+		log.Printf("⚠️  Failed to annotate campaign: %v", err)
+	}
+}
+
+// applyCampaign switches to a campaign's targets and datasets (or back to the
+// normal ones with a nil profile) and wakes the fetch loops to pick up its intervals
+func (m *Monitor) applyCampaign(campaign *models.Campaign, profile *campaignProfile) {
+	c := m.campaigns
+	c.mu.Lock()
+	previous := c.profile
+	c.active, c.profile = campaign, profile
+	servers := append([]config.DNSServer(nil), c.baseDNS...)
+	base := make(map[string]bool, len(c.baseASNs))
+	for asn := range c.baseASNs {
+		base[asn] = true
+	}
+	c.mu.Unlock()
+
+	var wanted []string
+	if profile != nil {
+		servers = append(servers, profile.dnsServers...)
+		wanted = profile.asns
+	}
+	m.dnsMonitor.SetServers(servers)
+	m.dnsMonitor.SetCaptureAll(profile != nil && profile.captureAll)
+	m.retune.notify()
+
+	keep := make(map[string]bool, len(wanted))
+	for _, asn := range wanted {
+		keep[asn] = true
+		if base[asn] || m.reference[asn] {
+			continue
+		}
+		if err := m.bgpClient.SubscribeToASN(asn); err != nil {
+			log.Printf("Warning: Failed to subscribe to ASN %s: %v", asn, err)
+		}
+	}
+	if previous != nil {
+		for _, asn := range previous.asns {
+			if keep[asn] || base[asn] || m.reference[asn] {
+				continue
+			}
+			if err := m.bgpClient.UnsubscribeFromASN(asn); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// setDNSServers replaces the DNS servers measured outside campaigns; the
+// running campaign's extra servers stay
+func (m *Monitor) setDNSServers(servers []config.DNSServer) {
+	c := m.campaigns
+	c.mu.Lock()
+	c.baseDNS = servers
+	if c.profile != nil {
+		servers = append(append([]config.DNSServer(nil), servers...), c.profile.dnsServers...)
+	}
+	c.mu.Unlock()
+	m.dnsMonitor.SetServers(servers)
+}
@@ -3,23 +3,43 @@ package monitor
 import (
 	"bytes"
 	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"strings"
 	"time"
 
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/i18n"
 	"github.com/netblocks/netblocks/internal/models"
 	"github.com/wcharczuk/go-chart/v2"
 	"github.com/wcharczuk/go-chart/v2/drawing"
 )
 
-// GenerateTrafficChart generates a PNG chart image from traffic data
-func GenerateTrafficChart(data *TrafficData) (*bytes.Buffer, error) {
+// hoursPerPoint returns the spacing of a traffic series in hours (1 without timestamps)
+func hoursPerPoint(timestamps []time.Time) float64 {
+	n := len(timestamps)
+	if n < 2 {
+		return 1
+	}
+	if step := timestamps[n-1].Sub(timestamps[n-2]).Hours(); step > 0 {
+		return step
+	}
+	return 1
+}
+
+// GenerateTrafficChart generates a PNG chart image from traffic data, marking the annotations of the period
+// and plotting the connected RIPE Atlas probes, if any, on a second axis
+func GenerateTrafficChart(data *TrafficData, notes []models.Annotation, probes []models.AtlasProbes) (*bytes.Buffer, error) {
 	if data == nil || len(data.Trend24h) == 0 {
 		return nil, fmt.Errorf("no traffic data available")
 	}
 
 	// Prepare X values (hours ago)
+	step := hoursPerPoint(data.Timestamps)
 	xValues := make([]float64, len(data.Trend24h))
 	for i := range xValues {
-		xValues[i] = float64(len(data.Trend24h) - i - 1) // Hours ago
+		xValues[i] = float64(len(data.Trend24h)-i-1) * step // Hours ago
 	}
 
 	// Reverse for chronological order (oldest to newest)
@@ -34,19 +54,7 @@ func GenerateTrafficChart(data *TrafficData) (*bytes.Buffer, error) {
 	}
 
 	// Determine line color based on status
-	var lineColor drawing.Color
-	switch data.Status {
-	case "Normal":
-		lineColor = drawing.Color{R: 76, G: 175, B: 80, A: 255} // Green
-	case "Degraded":
-		lineColor = drawing.Color{R: 255, G: 193, B: 7, A: 255} // Yellow
-	case "Throttled":
-		lineColor = drawing.Color{R: 255, G: 152, B: 0, A: 255} // Orange
-	case "Shutdown":
-		lineColor = drawing.Color{R: 244, G: 67, B: 54, A: 255} // Red
-	default:
-		lineColor = chart.ColorBlue
-	}
+	lineColor := statusColor(data.Status)
 
 	// Create the chart
 	graph := chart.Chart{
@@ -67,7 +75,7 @@ func GenerateTrafficChart(data *TrafficData) (*bytes.Buffer, error) {
 			Style:     chart.Style{},
 			ValueFormatter: func(v interface{}) string {
 				if vf, ok := v.(float64); ok {
-					return fmt.Sprintf("%.0fh", vf)
+					return chartLocale.Number(vf, 0) + "h"
 				}
 				return ""
 			},
@@ -94,6 +102,18 @@ func GenerateTrafficChart(data *TrafficData) (*bytes.Buffer, error) {
 		},
 	}
 
+	// Annotations, placed by hours before the latest point
+	latest := time.Now()
+	if len(data.Timestamps) > 0 {
+		latest = data.Timestamps[len(data.Timestamps)-1]
+	}
+	graph.Series = append(graph.Series, noteMarkers(notes, func(t time.Time) float64 {
+		return latest.Sub(t).Hours()
+	}, 0, xValues[len(xValues)-1])...)
+	addProbeSeries(&graph, probes, func(t time.Time) float64 {
+		return latest.Sub(t).Hours()
+	}, 0, xValues[len(xValues)-1])
+
 	// Add title
 	graph.Title = "Iran Internet Traffic (Last 24h)"
 	graph.TitleStyle = chart.Style{
@@ -101,7 +121,7 @@ func GenerateTrafficChart(data *TrafficData) (*bytes.Buffer, error) {
 	}
 
 	// Render to buffer
-	buffer := bytes.NewBuffer([]byte{})
+	buffer := charts.scratch()
 	err := graph.Render(chart.PNG, buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to render chart: %w", err)
@@ -110,29 +130,35 @@ func GenerateTrafficChart(data *TrafficData) (*bytes.Buffer, error) {
 	return buffer, nil
 }
 
-// FormatTrafficStatus formats traffic data for text display
-func FormatTrafficStatus(data *models.TrafficData) string {
+// FormatTrafficStatus formats traffic data for text display in a language
+func FormatTrafficStatus(data *models.TrafficData, lang string) string {
+	locale := i18n.For(lang)
+	lang = locale.Lang()
 	if data == nil {
-		return "❌ Traffic data unavailable"
+		return "❌ " + i18n.T(lang, "Traffic data unavailable")
 	}
 
 	timeSince := time.Since(data.LastUpdate)
-	timeStr := formatDuration(timeSince)
+	timeStr := formatDurationIn(timeSince, locale)
+	change := locale.Percent(data.ChangePercent, 1)
+	if data.ChangePercent >= 0 {
+		change = "+" + change
+	}
 
 	statusText := fmt.Sprintf(
-		"%s *Traffic Level:* %.1f%%\n"+
-			"📈 *Change:* %+.1f%%\n"+
-			"📊 *Status:* %s\n"+
-			"⏱ *Updated:* %s ago",
+		"%s *%s:* %s\n"+
+			"📈 *%s:* %s\n"+
+			"📊 *%s:* %s\n"+
+			"⏱ *%s:* "+i18n.T(lang, "%s ago"),
 		data.StatusEmoji,
-		data.CurrentLevel,
-		data.ChangePercent,
-		data.Status,
-		timeStr,
+		i18n.T(lang, "Traffic Level"), locale.Percent(data.CurrentLevel, 1),
+		i18n.T(lang, "Change"), change,
+		i18n.T(lang, "Status"), i18n.T(lang, data.Status),
+		i18n.T(lang, "Updated"), timeStr,
 	)
 
 	if data.Status == "Shutdown" || data.Status == "Throttled" {
-		statusText += "\n\n⚠️ *MAJOR DISRUPTION DETECTED*"
+		statusText += "\n\n⚠️ *" + i18n.T(lang, "MAJOR DISRUPTION DETECTED") + "*"
 	}
 
 	return statusText
@@ -151,18 +177,12 @@ func formatDuration(d time.Duration) string {
 }
 
 // GenerateASNTrafficChart generates a bar chart visualization for ASN traffic data
-// Shows top 10 Iranian ASNs with their names and current bandwidth (independent bars)
+// Shows the selected ASNs (see SelectASNTraffic) with their names and current bandwidth (independent bars)
 func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("no ASN traffic data available")
 	}
 
-	// Limit to top 10 ASNs (already sorted by traffic volume)
-	maxItems := 10
-	if len(data) > maxItems {
-		data = data[:maxItems]
-	}
-
 	// Prepare data for bar chart - use TrafficVolume (which is percentage from API)
 	// Note: TrafficVolume from Cloudflare API is actually a percentage (0-100)
 	// For netflows endpoint: percentage of total bytes
@@ -175,7 +195,7 @@ func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error
 		if percentage > maxPercentage {
 			maxPercentage = percentage
 		}
-		
+
 		// Create label: "AS12345 - Name" to show both ASN and name
 		label := fmt.Sprintf("%s - %s", item.ASN, item.Name)
 		if len(label) > 40 {
@@ -188,11 +208,15 @@ func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error
 				label = item.ASN
 			}
 		}
-		
+
 		// Use light blue color for all bars (white-ish but a bit blue)
 		// Light blue: RGB(173, 216, 230) or similar - slightly lighter
 		barColor := drawing.Color{R: 176, G: 224, B: 230, A: 255} // Light blue (PowderBlue)
-		
+		if item.Pinned {
+			// Pinned ASNs below the cutoff stand out in amber
+			barColor = drawing.Color{R: 255, G: 204, B: 128, A: 255}
+		}
+
 		barValues[i] = chart.Value{
 			Label: label,
 			Value: percentage, // This is a percentage value from the API
@@ -205,11 +229,15 @@ func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error
 	}
 
 	// Create bar chart
-	// Adjust width to accommodate more bars (10 ASNs)
+	// Adjust width to accommodate more bars (10 ASNs fit the base width)
+	width := 1400
+	if len(data) > 10 {
+		width += (len(data) - 10) * 120
+	}
 	graph := chart.BarChart{
-		Width:  1400, // Wider to accommodate 10 ASN names
-		Height: 600,  // Taller for better readability
-		Title:  fmt.Sprintf("Top %d Iranian ASNs by Traffic Share", len(data)),
+		Width:  width, // Wider to accommodate the ASN names
+		Height: 600,   // Taller for better readability
+		Title:  fmt.Sprintf("Top %d Iranian ASNs by Traffic Share", RankedASNCount(data)),
 		TitleStyle: chart.Style{
 			FontSize: 18,
 		},
@@ -237,7 +265,7 @@ func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error
 				if vf, ok := v.(float64); ok {
 					// Values are already percentages from Cloudflare API
 					// Format as percentage with 1 decimal place
-					return fmt.Sprintf("%.1f%%", vf)
+					return chartLocale.Percent(vf, 1)
 				}
 				return ""
 			},
@@ -246,7 +274,7 @@ func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error
 	}
 
 	// Render to buffer
-	buffer := bytes.NewBuffer([]byte{})
+	buffer := charts.scratch()
 	err := graph.Render(chart.PNG, buffer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to render ASN traffic bar chart: %w", err)
@@ -255,3 +283,596 @@ func GenerateASNTrafficChart(data []*models.ASTrafficData) (*bytes.Buffer, error
 	return buffer, nil
 }
 
+// HeatmapRow is one target line of the uptime heatmap
+type HeatmapRow struct {
+	Label   string
+	Buckets []history.Bucket // Hourly buckets, any order
+}
+
+// heatmapColor maps an availability ratio to a GitHub-style color scale
+// Hours without any samples are drawn light gray
+func heatmapColor(b history.Bucket, ok bool) drawing.Color {
+	if !ok || b.Total == 0 {
+		return drawing.Color{R: 235, G: 237, B: 240, A: 255} // No data
+	}
+	ratio := b.Ratio()
+	switch {
+	case ratio >= 0.99:
+		return drawing.Color{R: 33, G: 110, B: 57, A: 255} // Dark green
+	case ratio >= 0.9:
+		return drawing.Color{R: 48, G: 161, B: 78, A: 255} // Green
+	case ratio >= 0.7:
+		return drawing.Color{R: 155, G: 233, B: 168, A: 255} // Light green
+	case ratio >= 0.3:
+		return drawing.Color{R: 255, G: 152, B: 0, A: 255} // Orange
+	default:
+		return drawing.Color{R: 244, G: 67, B: 54, A: 255} // Red
+	}
+}
+
+// GenerateUptimeHeatmap renders a heatmap of targets (rows) by hours (columns)
+// covering the given number of hours ending at end
+func GenerateUptimeHeatmap(title string, rows []HeatmapRow, end time.Time, hours int) (*bytes.Buffer, error) {
+	if len(rows) == 0 || hours <= 0 {
+		return nil, fmt.Errorf("no uptime history available")
+	}
+
+	const (
+		cellWidth   = 6
+		cellHeight  = 12
+		cellGap     = 1
+		labelWidth  = 260
+		topPadding  = 60
+		sidePadding = 20
+		footer      = 50
+	)
+
+	width := sidePadding*2 + labelWidth + hours*cellWidth
+	height := topPadding + len(rows)*(cellHeight+cellGap) + footer
+
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create heatmap renderer: %w", err)
+	}
+
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart font: %w", err)
+	}
+	r.SetFont(font)
+
+	// White background
+	drawRect(r, 0, 0, width, height, drawing.Color{R: 255, G: 255, B: 255, A: 255})
+
+	// Title
+	r.SetFontColor(drawing.Color{R: 0, G: 0, B: 0, A: 255})
+	r.SetFontSize(16)
+	r.Text(title, sidePadding, 30)
+
+	endHour := end.UTC().Truncate(time.Hour)
+	startHour := endHour.Add(-time.Duration(hours-1) * time.Hour)
+
+	r.SetFontSize(9)
+	for i, row := range rows {
+		byHour := make(map[int64]history.Bucket, len(row.Buckets))
+		for _, b := range row.Buckets {
+			byHour[b.Hour.UTC().Truncate(time.Hour).Unix()] = b
+		}
+
+		y := topPadding + i*(cellHeight+cellGap)
+
+		label := row.Label
+		if len(label) > 45 {
+			label = label[:42] + "..."
+		}
+		r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+		r.Text(label, sidePadding, y+cellHeight-2)
+
+		for h := 0; h < hours; h++ {
+			hour := startHour.Add(time.Duration(h) * time.Hour)
+			b, ok := byHour[hour.Unix()]
+			x := sidePadding + labelWidth + h*cellWidth
+			drawRect(r, x, y, cellWidth-cellGap, cellHeight, heatmapColor(b, ok))
+		}
+	}
+
+	// Day markers along the bottom axis
+	axisY := topPadding + len(rows)*(cellHeight+cellGap) + 15
+	r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+	for h := 0; h < hours; h++ {
+		hour := startHour.Add(time.Duration(h) * time.Hour)
+		if hour.Hour() == 0 {
+			x := sidePadding + labelWidth + h*cellWidth
+			r.Text(hour.Format("Jan 2"), x, axisY)
+		}
+	}
+	r.Text("Green = available, orange/red = outage, gray = no data (UTC)", sidePadding, axisY+20)
+
+	buffer := charts.scratch()
+	if err := r.Save(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render uptime heatmap: %w", err)
+	}
+	return buffer, nil
+}
+
+// drawRect fills a rectangle on the renderer
+func drawRect(r chart.Renderer, x, y, w, h int, color drawing.Color) {
+	r.SetFillColor(color)
+	r.SetStrokeColor(color)
+	r.SetStrokeWidth(0)
+	r.MoveTo(x, y)
+	r.LineTo(x+w, y)
+	r.LineTo(x+w, y+h)
+	r.LineTo(x, y+h)
+	r.Close()
+	r.Fill()
+}
+
+// GenerateStatusImage renders a single 2x2 composite PNG summarizing the whole status:
+// traffic line, ASN availability bars, DNS alive gauge and the national score
+// Panels whose data is unavailable are drawn with a "no data" placeholder
+func GenerateStatusImage(result *models.MonitoringResult, score float64) (*bytes.Buffer, error) {
+	if result == nil {
+		return nil, fmt.Errorf("no monitoring result available")
+	}
+
+	const (
+		panelWidth  = 600
+		panelHeight = 350
+	)
+
+	canvas := statusCanvas(panelWidth*2, panelHeight*2)
+	defer statusCanvases.Put(canvas)
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+
+	panels := []func() (*bytes.Buffer, error){
+		func() (*bytes.Buffer, error) { return renderTrafficPanel(result.TrafficData, panelWidth, panelHeight) },
+		func() (*bytes.Buffer, error) { return renderASNPanel(result, panelWidth, panelHeight) },
+		func() (*bytes.Buffer, error) { return renderDNSPanel(result, panelWidth, panelHeight) },
+		func() (*bytes.Buffer, error) { return renderScorePanel(result, score, panelWidth, panelHeight) },
+	}
+
+	for i, render := range panels {
+		buf, err := render()
+		if err != nil {
+			buf, err = renderTextPanel("No data", err.Error(), panelWidth, panelHeight)
+			if err != nil {
+				return nil, err
+			}
+		}
+		panel, err := png.Decode(buf)
+		charts.release(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode status panel %d: %w", i, err)
+		}
+		offset := image.Pt((i%2)*panelWidth, (i/2)*panelHeight)
+		draw.Draw(canvas, panel.Bounds().Add(offset), panel, panel.Bounds().Min, draw.Over)
+	}
+
+	buffer := charts.scratch()
+	if err := png.Encode(buffer, canvas); err != nil {
+		return nil, fmt.Errorf("failed to encode status image: %w", err)
+	}
+	return buffer, nil
+}
+
+// renderTrafficPanel renders the 24h traffic line for the composite image
+func renderTrafficPanel(data *models.TrafficData, width, height int) (*bytes.Buffer, error) {
+	if data == nil || len(data.Trend24h) == 0 {
+		return nil, fmt.Errorf("traffic data unavailable")
+	}
+
+	// Trend24h is oldest first; x = hours ago (negative) so the line reads left to right
+	step := hoursPerPoint(data.Timestamps)
+	xValues := make([]float64, len(data.Trend24h))
+	for i := range xValues {
+		xValues[i] = -float64(len(data.Trend24h)-i-1) * step
+	}
+
+	graph := chart.Chart{
+		Width:  width,
+		Height: height,
+		Title:  fmt.Sprintf("Traffic (24h) - %s %s", chartLocale.Percent(data.CurrentLevel, 0), data.Status),
+		TitleStyle: chart.Style{
+			FontSize: 14,
+		},
+		Background: chart.Style{
+			Padding: chart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis: chart.XAxis{
+			ValueFormatter: func(v interface{}) string {
+				if vf, ok := v.(float64); ok {
+					return chartLocale.Number(-vf, 0) + "h"
+				}
+				return ""
+			},
+		},
+		YAxis: chart.YAxis{
+			Range: &chart.ContinuousRange{Min: 0, Max: 100},
+		},
+		Series: []chart.Series{
+			chart.ContinuousSeries{
+				XValues: xValues,
+				YValues: data.Trend24h,
+				Style: chart.Style{
+					StrokeColor: statusColor(data.Status),
+					StrokeWidth: 3,
+				},
+			},
+		},
+	}
+
+	buffer := charts.scratch()
+	if err := graph.Render(chart.PNG, buffer); err != nil {
+		return nil, fmt.Errorf("failed to render traffic panel: %w", err)
+	}
+	return buffer, nil
+}
+
+// renderASNPanel renders connected vs disconnected ASN counts
+func renderASNPanel(result *models.MonitoringResult, width, height int) (*bytes.Buffer, error) {
+	total := len(result.ASNStatuses)
+	if total == 0 {
+		return nil, fmt.Errorf("no ASN data")
+	}
+	connected := 0
+	for _, status := range result.ASNStatuses {
+		if status.Connected {
+			connected++
+		}
+	}
+
+	green := drawing.Color{R: 76, G: 175, B: 80, A: 255}
+	red := drawing.Color{R: 244, G: 67, B: 54, A: 255}
+
+	graph := chart.BarChart{
+		Width:  width,
+		Height: height,
+		Title:  fmt.Sprintf("ASN Availability - %s/%s connected", chartLocale.Int(connected), chartLocale.Int(total)),
+		TitleStyle: chart.Style{
+			FontSize: 14,
+		},
+		Background: chart.Style{
+			Padding: chart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20},
+		},
+		BarWidth: 120,
+		YAxis: chart.YAxis{
+			// Counts are in the title; the axis only adds clutter at this size
+			Style: chart.Style{Hidden: true},
+			Range: &chart.ContinuousRange{Min: 0, Max: float64(total)},
+		},
+		Bars: []chart.Value{
+			{Label: "Connected", Value: float64(connected), Style: chart.Style{FillColor: green, StrokeColor: green}},
+			{Label: "Disconnected", Value: float64(total - connected), Style: chart.Style{FillColor: red, StrokeColor: red}},
+		},
+	}
+
+	buffer := charts.scratch()
+	if err := graph.Render(chart.PNG, buffer); err != nil {
+		return nil, fmt.Errorf("failed to render ASN panel: %w", err)
+	}
+	return buffer, nil
+}
+
+// renderDNSPanel renders a donut gauge of alive vs dead DNS servers
+func renderDNSPanel(result *models.MonitoringResult, width, height int) (*bytes.Buffer, error) {
+	total := len(result.DNSStatuses)
+	if total == 0 {
+		return nil, fmt.Errorf("no DNS data")
+	}
+	alive := 0
+	for _, status := range result.DNSStatuses {
+		if status.Alive {
+			alive++
+		}
+	}
+
+	green := drawing.Color{R: 76, G: 175, B: 80, A: 255}
+	red := drawing.Color{R: 244, G: 67, B: 54, A: 255}
+
+	// Donut charts can't render zero-value slices, so only add non-empty ones
+	var values []chart.Value
+	if alive > 0 {
+		values = append(values, chart.Value{Label: "Alive " + chartLocale.Int(alive), Value: float64(alive), Style: chart.Style{FillColor: green}})
+	}
+	if total-alive > 0 {
+		values = append(values, chart.Value{Label: "Down " + chartLocale.Int(total-alive), Value: float64(total - alive), Style: chart.Style{FillColor: red}})
+	}
+
+	graph := chart.DonutChart{
+		Width:  width,
+		Height: height,
+		Title:  fmt.Sprintf("DNS Servers - %s alive", chartLocale.Percent(float64(alive)/float64(total)*100.0, 0)),
+		TitleStyle: chart.Style{
+			FontSize: 14,
+		},
+		Background: chart.Style{
+			Padding: chart.Box{Top: 50, Left: 20, Right: 20, Bottom: 20},
+		},
+		Values: values,
+	}
+
+	buffer := charts.scratch()
+	if err := graph.Render(chart.PNG, buffer); err != nil {
+		return nil, fmt.Errorf("failed to render DNS panel: %w", err)
+	}
+	return buffer, nil
+}
+
+// renderScorePanel renders the national score as a large colored number
+func renderScorePanel(result *models.MonitoringResult, score float64, width, height int) (*bytes.Buffer, error) {
+	status, _ := ScoreStatus(score)
+	return renderTextPanel(
+		chartLocale.Number(score, 0)+" / 100",
+		fmt.Sprintf("National Score - %s (%s UTC)", status, chartLocale.Time(result.Timestamp.UTC(), "2006-01-02 15:04")),
+		width, height,
+	)
+}
+
+// renderTextPanel renders a panel with a large headline and a smaller subtitle
+func renderTextPanel(headline, subtitle string, width, height int) (*bytes.Buffer, error) {
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create panel renderer: %w", err)
+	}
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart font: %w", err)
+	}
+	r.SetFont(font)
+
+	drawRect(r, 0, 0, width, height, drawing.Color{R: 255, G: 255, B: 255, A: 255})
+
+	r.SetFontColor(drawing.Color{R: 33, G: 33, B: 33, A: 255})
+	r.SetFontSize(48)
+	box := r.MeasureText(headline)
+	r.Text(headline, (width-box.Width())/2, height/2)
+
+	r.SetFontColor(drawing.Color{R: 90, G: 90, B: 90, A: 255})
+	r.SetFontSize(13)
+	box = r.MeasureText(subtitle)
+	r.Text(subtitle, (width-box.Width())/2, height/2+40)
+
+	buffer := charts.scratch()
+	if err := r.Save(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render text panel: %w", err)
+	}
+	return buffer, nil
+}
+
+// statusColor returns the chart color used for a traffic status
+func statusColor(status string) drawing.Color {
+	switch status {
+	case "Normal":
+		return drawing.Color{R: 76, G: 175, B: 80, A: 255} // Green
+	case "Degraded":
+		return drawing.Color{R: 255, G: 193, B: 7, A: 255} // Yellow
+	case "Throttled":
+		return drawing.Color{R: 255, G: 152, B: 0, A: 255} // Orange
+	case "Shutdown":
+		return drawing.Color{R: 244, G: 67, B: 54, A: 255} // Red
+	default:
+		return chart.ColorBlue
+	}
+}
+
+// SparklineRow is one labeled sparkline of hourly values (0-1, -1 = no data)
+type SparklineRow struct {
+	Label  string
+	Values []float64
+}
+
+// sparkBlocks are the Unicode block characters used for text sparklines (low to high)
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values in 0-1 as a Unicode sparkline; -1 (no data) is shown as "·"
+func Sparkline(values []float64) string {
+	var builder strings.Builder
+	for _, v := range values {
+		if v < 0 {
+			builder.WriteRune('·')
+			continue
+		}
+		idx := int(v * float64(len(sparkBlocks)-1))
+		if idx >= len(sparkBlocks) {
+			idx = len(sparkBlocks) - 1
+		}
+		builder.WriteRune(sparkBlocks[idx])
+	}
+	return builder.String()
+}
+
+// GenerateSparklineStrip renders a compact image with one small availability line per row
+func GenerateSparklineStrip(title string, rows []SparklineRow) (*bytes.Buffer, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no sparkline data available")
+	}
+
+	const (
+		labelWidth  = 280
+		sparkWidth  = 360
+		rowHeight   = 26
+		topPadding  = 50
+		sidePadding = 20
+		valueWidth  = 70
+	)
+
+	width := sidePadding*2 + labelWidth + sparkWidth + valueWidth
+	height := topPadding + len(rows)*rowHeight + sidePadding
+
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sparkline renderer: %w", err)
+	}
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart font: %w", err)
+	}
+	r.SetFont(font)
+
+	drawRect(r, 0, 0, width, height, drawing.Color{R: 255, G: 255, B: 255, A: 255})
+
+	r.SetFontColor(drawing.Color{R: 0, G: 0, B: 0, A: 255})
+	r.SetFontSize(15)
+	r.Text(title, sidePadding, 30)
+
+	for i, row := range rows {
+		top := topPadding + i*rowHeight
+		lineTop, lineBottom := top+3, top+rowHeight-5
+
+		label := row.Label
+		if len(label) > 42 {
+			label = label[:39] + "..."
+		}
+		r.SetFontSize(10)
+		r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+		r.Text(label, sidePadding, top+rowHeight/2+4)
+
+		// Light baseline so rows without data are still visible
+		x0 := sidePadding + labelWidth
+		drawRect(r, x0, lineBottom, sparkWidth, 1, drawing.Color{R: 220, G: 220, B: 220, A: 255})
+
+		// Draw each run of consecutive samples as its own line segment
+		sum, count := 0.0, 0
+		step := float64(sparkWidth) / float64(max(len(row.Values)-1, 1))
+		inRun := false
+		r.SetStrokeWidth(2)
+		for j, v := range row.Values {
+			if v < 0 {
+				if inRun {
+					r.Stroke()
+					inRun = false
+				}
+				continue
+			}
+			sum += v
+			count++
+			x := x0 + int(float64(j)*step)
+			y := lineBottom - int(v*float64(lineBottom-lineTop))
+			if !inRun {
+				r.SetStrokeColor(statusColorForRatio(v))
+				r.MoveTo(x, y)
+				inRun = true
+			} else {
+				r.LineTo(x, y)
+			}
+		}
+		if inRun {
+			r.Stroke()
+		}
+
+		value := "n/a"
+		if count > 0 {
+			value = chartLocale.Percent(sum/float64(count)*100.0, 0)
+		}
+		r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+		r.Text(value, x0+sparkWidth+15, top+rowHeight/2+4)
+	}
+
+	buffer := charts.scratch()
+	if err := r.Save(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render sparkline strip: %w", err)
+	}
+	return buffer, nil
+}
+
+// statusColorForRatio maps an availability ratio to the traffic status colors
+func statusColorForRatio(ratio float64) drawing.Color {
+	switch {
+	case ratio > 0.7:
+		return statusColor("Normal")
+	case ratio > 0.3:
+		return statusColor("Degraded")
+	case ratio > 0.1:
+		return statusColor("Throttled")
+	default:
+		return statusColor("Shutdown")
+	}
+}
+
+// ParseChartPeriod converts a chart period ("24h", "7d", "30d") into hours
+// An empty period means the default 24h chart
+func ParseChartPeriod(period string) (int, error) {
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "", "24h", "1d":
+		return 24, nil
+	case "7d":
+		return 7 * 24, nil
+	case "30d":
+		return 30 * 24, nil
+	}
+	return 0, fmt.Errorf("unsupported period %q (use 24h, 7d or 30d)", period)
+}
+
+// GenerateTrafficHistoryChart renders a traffic line chart from persisted hourly history, marking the annotations
+// and plotting the connected RIPE Atlas probes, if any, on a second axis
+func GenerateTrafficHistoryChart(points []history.TrafficPoint, period string, notes []models.Annotation, probes []models.AtlasProbes) (*bytes.Buffer, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("not enough traffic history for %s chart", period)
+	}
+
+	xValues := make([]time.Time, len(points))
+	yValues := make([]float64, len(points))
+	for i, p := range points {
+		xValues[i] = p.Timestamp
+		yValues[i] = p.Level
+	}
+
+	// Label by hour for short periods and by day for longer ones
+	dateFormat := "Jan 2 15:04"
+	if len(points) > 48 {
+		dateFormat = "Jan 2"
+	}
+
+	graph := chart.Chart{
+		Width:  1000,
+		Height: 400,
+		Background: chart.Style{
+			Padding: chart.Box{
+				Top:    50,
+				Left:   20,
+				Right:  20,
+				Bottom: 20,
+			},
+			FillColor: drawing.Color{R: 255, G: 255, B: 255, A: 255}, // White background
+		},
+		XAxis: chart.XAxis{
+			Name:           "Time (UTC)",
+			ValueFormatter: chart.TimeValueFormatterWithFormat(dateFormat),
+		},
+		YAxis: chart.YAxis{
+			Name: "Traffic Level (%)",
+			Range: &chart.ContinuousRange{
+				Min: 0,
+				Max: 100,
+			},
+		},
+		Series: []chart.Series{
+			chart.TimeSeries{
+				Name:    "Traffic",
+				XValues: xValues,
+				YValues: yValues,
+				Style: chart.Style{
+					StrokeColor: statusColorForRatio(yValues[len(yValues)-1] / 100.0),
+					StrokeWidth: 2,
+				},
+			},
+		},
+	}
+
+	graph.Series = append(graph.Series, noteMarkers(notes, chart.TimeToFloat64,
+		chart.TimeToFloat64(xValues[0]), chart.TimeToFloat64(xValues[len(xValues)-1]))...)
+	addProbeSeries(&graph, probes, chart.TimeToFloat64,
+		chart.TimeToFloat64(xValues[0]), chart.TimeToFloat64(xValues[len(xValues)-1]))
+
+	graph.Title = fmt.Sprintf("Iran Internet Traffic (Last %s)", period)
+	graph.TitleStyle = chart.Style{
+		FontSize: 16,
+	}
+
+	buffer := charts.scratch()
+	if err := graph.Render(chart.PNG, buffer); err != nil {
+		return nil, fmt.Errorf("failed to render traffic history chart: %w", err)
+	}
+	return buffer, nil
+}
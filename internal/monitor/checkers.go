@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/hook"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/pkg/checker"
+)
+
+// loadCheckers returns the check hooks, checker plugins and transactions of the config
+func loadCheckers(cfg *config.Config) ([]checker.Checker, error) {
+	hooks, err := hook.Load(cfg.Hooks, hook.StageCheck)
+	if err != nil {
+		return nil, err
+	}
+	var checkers []checker.Checker
+	for _, h := range hooks {
+		checkers = append(checkers, h)
+	}
+	for _, pluginCfg := range cfg.CheckerPlugins {
+		c, err := loadPlugin(pluginCfg)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("🧩 Loaded checker plugin %s from %s", c.Name(), pluginCfg.Path)
+		checkers = append(checkers, c)
+	}
+	if len(cfg.Transactions) > 0 {
+		checkers = append(checkers, newTransactionChecker(cfg.Transactions))
+	}
+	seen := make(map[string]bool)
+	for _, c := range checkers {
+		if seen[c.Name()] {
+			return nil, fmt.Errorf("checker %q is configured more than once", c.Name())
+		}
+		seen[c.Name()] = true
+	}
+	return checkers, nil
+}
+
+// runCheckers runs the checkers in parallel with the cycle's result and
+// returns their outcomes and the names of the checkers that failed
+func (m *Monitor) runCheckers(ctx context.Context, result *models.MonitoringResult) ([]*models.CustomCheck, []string) {
+	if len(m.checkers) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("⚠️  Failed to encode result for checkers: %v", err)
+		return nil, nil
+	}
+	input := checker.Input{Country: m.config.Country, Result: data}
+	outcomes := make([][]checker.Outcome, len(m.checkers))
+	errs := make([]error, len(m.checkers))
+	var wg sync.WaitGroup
+	for i, c := range m.checkers {
+		wg.Add(1)
+		go func(i int, c checker.Checker) {
+			defer wg.Done()
+			outcomes[i], errs[i] = c.Check(ctx, input)
+		}(i, c)
+	}
+	wg.Wait()
+
+	var checks []*models.CustomCheck
+	var failures []string
+	for i, c := range m.checkers {
+		if errs[i] != nil {
+			log.Printf("⚠️  %v", errs[i])
+			failures = append(failures, failureChecker+c.Name())
+			continue
+		}
+		transactions, _ := c.(*TransactionChecker)
+		for _, outcome := range outcomes[i] {
+			check := &models.CustomCheck{Checker: c.Name(), Name: outcome.Name, Up: outcome.Up, Detail: outcome.Detail, Step: outcome.Step}
+			if transactions != nil {
+				check.Mismatch = transactions.Mismatch(outcome.Name)
+			}
+			checks = append(checks, check)
+		}
+		if transactions != nil {
+			m.recordMismatches(transactions, checks)
+		}
+	}
+	return checks, failures
+}
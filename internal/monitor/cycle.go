@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Parts of a cycle that can fail without stopping it
+const (
+	failureTraffic         = "traffic"           // Cloudflare Radar traffic data
+	failureTrafficChart    = "traffic_chart"     // Iran traffic chart
+	failureASNTraffic      = "asn_traffic"       // Cloudflare Radar ASN traffic data
+	failureASNTrafficChart = "asn_traffic_chart" // ASN traffic chart
+	failureAtlas           = "ripe_atlas"        // RIPE Atlas anchor measurements
+	failureRIPEstat        = "ripestat"          // RIPEstat routing status
+	failureRPKI            = "rpki"              // RPKI ROA export
+	failureUptimeChart     = "uptime_heatmap"    // 7-day uptime heatmap
+	failureSparklines      = "asn_sparklines"    // 24h ASN sparkline strip
+	failureStatusImage     = "status_image"      // Composite status image
+	failureProvinceMap     = "province_map"      // Map of the provinces
+	failureCausesChart     = "failure_causes"    // 7-day DNS failure causes chart
+	failureHistory         = "history"           // Availability history store
+	failureEvidence        = "evidence"          // Signed measurement log
+	failureChecker         = "check:"            // Check hook or checker plugin, followed by its name
+)
+
+// CycleSummary describes one completed monitoring cycle
+type CycleSummary struct {
+	Start     time.Time                   `json:"start"`
+	Duration  time.Duration               `json:"duration"`
+	ASNs      int                         `json:"asns"`                 // ASNs checked
+	ASNsDown  int                         `json:"asns_down"`            // ASNs without BGP updates
+	DNS       int                         `json:"dns"`                  // DNS servers checked
+	DNSDown   int                         `json:"dns_down"`             // DNS servers not answering
+	DNSErrors map[models.DNSErrorCode]int `json:"dns_errors,omitempty"` // Failed and anomalous DNS checks by error code
+	Traffic   bool                        `json:"traffic"`              // Cloudflare traffic data was available
+	Failures  []string                    `json:"failures"`             // Data sources, charts and stores that failed (see failure* constants)
+	Events    int                         `json:"events"`               // Changes detected
+	Critical  int                         `json:"critical"`             // Critical changes among them
+}
+
+// CycleStats aggregates the completed cycles since start
+type CycleStats struct {
+	Count         int           // Completed cycles
+	Failed        int           // Cycles with at least one failure
+	Events        int           // Changes detected over all cycles
+	TotalDuration time.Duration // Sum of all cycle durations
+	MaxDuration   time.Duration // Longest cycle
+	Last          *CycleSummary // Most recent cycle (nil before the first one)
+}
+
+// AvgDuration returns the mean cycle duration
+func (s CycleStats) AvgDuration() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.TotalDuration / time.Duration(s.Count)
+}
+
+// cycleStats is the monitor's guarded CycleStats
+type cycleStats struct {
+	mu    sync.Mutex
+	stats CycleStats
+}
+
+func (c *cycleStats) add(summary CycleSummary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Count++
+	if len(summary.Failures) > 0 {
+		c.stats.Failed++
+	}
+	c.stats.Events += summary.Events
+	c.stats.TotalDuration += summary.Duration
+	if summary.Duration > c.stats.MaxDuration {
+		c.stats.MaxDuration = summary.Duration
+	}
+	c.stats.Last = &summary
+}
+
+func (c *cycleStats) snapshot() CycleStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// runCycle updates and records the results, detects events and logs one
+// structured summary record of the cycle
+func (m *Monitor) runCycle(ctx context.Context) {
+	start := m.clock.Now()
+	failures := m.updateResults(ctx)
+	failures = append(failures, m.recordHistory()...)
+	events := m.detectEvents(ctx)
+
+	summary := summarizeCycle(m.LatestResults(), events, failures)
+	summary.Start = start
+	summary.Duration = m.clock.Since(start)
+	m.cycles.add(summary)
+
+	level := slog.LevelInfo
+	if len(summary.Failures) > 0 {
+		level = slog.LevelWarn
+	}
+	slog.Log(ctx, level, "monitor cycle",
+		"duration", summary.Duration.Round(time.Millisecond),
+		"asns", summary.ASNs, "asns_down", summary.ASNsDown,
+		"dns", summary.DNS, "dns_down", summary.DNSDown, "dns_errors", summary.DNSErrors,
+		"traffic", summary.Traffic,
+		"failures", summary.Failures,
+		"events", summary.Events, "critical", summary.Critical)
+}
+
+// summarizeCycle counts the checks and changes of a cycle
+func summarizeCycle(result *models.MonitoringResult, events []models.Event, failures []string) CycleSummary {
+	summary := CycleSummary{Failures: failures, Events: len(events)}
+	if summary.Failures == nil {
+		summary.Failures = []string{}
+	}
+	if result != nil {
+		summary.ASNs = len(result.ASNStatuses)
+		for _, status := range result.ASNStatuses {
+			if !status.Connected {
+				summary.ASNsDown++
+			}
+		}
+		summary.DNS = len(result.DNSStatuses)
+		for _, status := range result.DNSStatuses {
+			if !status.Alive {
+				summary.DNSDown++
+			}
+			if status.ErrorCode != "" {
+				if summary.DNSErrors == nil {
+					summary.DNSErrors = make(map[models.DNSErrorCode]int)
+				}
+				summary.DNSErrors[status.ErrorCode]++
+			}
+		}
+		summary.Traffic = result.TrafficData != nil
+	}
+	for _, event := range events {
+		if event.Severity == models.SeverityCritical {
+			summary.Critical++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// SetFlapDamping sets how many consecutive checks change a server's state
+// (the default of NewDNSMonitor is 1: every check decides)
+func (dm *DNSMonitor) SetFlapDamping(damping config.FlapDamping) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.damping = damping
+}
+
+// damp sets the declared state of a fresh check from the previous status of the
+// server: the raw sample is kept, but a sample contradicting the declared state
+// only changes it once enough consecutive checks agree (caller holds dm.mu)
+func (dm *DNSMonitor) damp(previous, current *models.DNSStatus) {
+	current.Sample = current.Alive
+	current.Streak = 1
+	if previous == nil || previous.LastCheck.IsZero() {
+		return
+	}
+	if previous.Sample == current.Sample {
+		current.Streak = previous.Streak + 1
+	}
+	if current.Sample == previous.Alive {
+		return
+	}
+	need := dm.damping.UpAfter
+	if !current.Sample {
+		need = dm.damping.DownAfter
+	}
+	if current.Streak >= need {
+		return
+	}
+	current.Alive = previous.Alive
+	if current.Alive {
+		// Still declared up: keep the measurements of the last answer, so the
+		// timeout of the failed check is not taken for the server's latency
+		current.ResponseTime = previous.ResponseTime
+		current.UncachedTime = previous.UncachedTime
+		current.Verdict, current.Reason = previous.Verdict, previous.Reason
+	}
+}
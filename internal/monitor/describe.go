@@ -0,0 +1,212 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Text alternatives for charts, for low-bandwidth users and screen readers.
+// Each one summarizes the key numbers of a chart in a single short line.
+
+// trendThreshold is the change (percentage points) between the older and newer
+// half of a series that counts as rising or falling
+const trendThreshold = 5.0
+
+// DescribeSeries summarizes a traffic series (percent of peak, oldest first):
+// current, min, max and average level plus a trend arrow
+func DescribeSeries(values []float64) string {
+	var valid []float64
+	for _, v := range values {
+		if v >= 0 {
+			valid = append(valid, v)
+		}
+	}
+	if len(valid) == 0 {
+		return "No traffic data"
+	}
+
+	minV, maxV, sum := valid[0], valid[0], 0.0
+	for _, v := range valid {
+		if v < minV {
+			minV = v
+		}
+		if v > maxV {
+			maxV = v
+		}
+		sum += v
+	}
+	avg := sum / float64(len(valid))
+
+	return fmt.Sprintf("Now %.0f%% · min %.0f%% · max %.0f%% · avg %.0f%% · trend %s",
+		valid[len(valid)-1], minV, maxV, avg, trendArrow(valid))
+}
+
+// trendArrow compares the newer half of a series with the older half
+func trendArrow(values []float64) string {
+	if len(values) < 2 {
+		return "→"
+	}
+	half := len(values) / 2
+	older, newer := 0.0, 0.0
+	for _, v := range values[:half] {
+		older += v
+	}
+	for _, v := range values[half:] {
+		newer += v
+	}
+	diff := newer/float64(len(values)-half) - older/float64(half)
+	switch {
+	case diff >= trendThreshold:
+		return "↗"
+	case diff <= -trendThreshold:
+		return "↘"
+	default:
+		return "→"
+	}
+}
+
+// DescribeStatus summarizes the composite status image
+func DescribeStatus(result *models.MonitoringResult) string {
+	connected := 0
+	for _, status := range result.ASNStatuses {
+		if status.Connected {
+			connected++
+		}
+	}
+	alive := 0
+	for _, status := range result.DNSStatuses {
+		if status.Alive {
+			alive++
+		}
+	}
+
+	text := fmt.Sprintf("ASNs %d/%d connected · DNS %d/%d alive", connected, len(result.ASNStatuses), alive, len(result.DNSStatuses))
+	if result.TrafficData != nil {
+		text += fmt.Sprintf(" · traffic %.0f%% (%s)", result.TrafficData.CurrentLevel, result.TrafficData.Status)
+	}
+	if len(result.AtlasAnchors) > 0 {
+		reachable := 0
+		for _, anchor := range result.AtlasAnchors {
+			if anchor.Reachable {
+				reachable++
+			}
+		}
+		text += fmt.Sprintf(" · RIPE Atlas anchors %d/%d reachable", reachable, len(result.AtlasAnchors))
+	}
+	if result.AtlasProbes != nil {
+		text += fmt.Sprintf(" · %d RIPE Atlas probes connected", result.AtlasProbes.Connected)
+	}
+	if result.Campaign != nil {
+		text += fmt.Sprintf(" · campaign %s running", result.Campaign.Name)
+	}
+	return text
+}
+
+// DescribeProvinces summarizes the province map: the provinces that are not
+// normal, worst first, and how many are
+func DescribeProvinces(healths []ProvinceHealth) string {
+	var affected []ProvinceHealth
+	normal, unknown := 0, 0
+	for _, health := range healths {
+		switch health.Status {
+		case ProvinceNormal:
+			normal++
+		case ProvinceUnknown:
+			unknown++
+		default:
+			affected = append(affected, health)
+		}
+	}
+	if normal+len(affected) == 0 {
+		return "No DNS servers in any province"
+	}
+	sort.SliceStable(affected, func(i, j int) bool { return affected[i].Score < affected[j].Score })
+
+	var parts []string
+	for _, health := range affected {
+		parts = append(parts, fmt.Sprintf("%s %s (%d/%d DNS alive)", health.Province.Name, health.Status, health.DNSAlive, health.DNSTotal))
+	}
+	text := fmt.Sprintf("%d province(s) normal", normal)
+	if len(parts) > 0 {
+		text = strings.Join(parts, ", ") + "; " + text
+	}
+	if unknown > 0 {
+		text += fmt.Sprintf(", %d without DNS servers", unknown)
+	}
+	return text
+}
+
+// DescribeASNTraffic summarizes the ASN traffic share chart
+func DescribeASNTraffic(data []*models.ASTrafficData) string {
+	if len(data) == 0 {
+		return "No ASN traffic data"
+	}
+	total := 0.0
+	for _, item := range data {
+		total += item.Percentage
+	}
+	return fmt.Sprintf("Top %d ASNs carry %.1f%% of traffic; largest %s (%.1f%%), smallest %s (%.1f%%)",
+		len(data), total, data[0].Name, data[0].Percentage, data[len(data)-1].Name, data[len(data)-1].Percentage)
+}
+
+// maxDescribedRows is how many of the least available heatmap rows are named
+const maxDescribedRows = 3
+
+// DescribeUptime summarizes the uptime heatmap: overall availability and the least available rows
+func DescribeUptime(rows []HeatmapRow, end time.Time, hours int) string {
+	type rowUptime struct {
+		label string
+		ratio float64
+	}
+	var uptimes []rowUptime
+	up, total := 0, 0
+	since := end.Add(-time.Duration(hours) * time.Hour)
+	for _, row := range rows {
+		rowUp, rowTotal := 0, 0
+		for _, b := range row.Buckets {
+			if b.Hour.Before(since) {
+				continue
+			}
+			rowUp += b.Up
+			rowTotal += b.Total
+		}
+		if rowTotal == 0 {
+			continue
+		}
+		up += rowUp
+		total += rowTotal
+		uptimes = append(uptimes, rowUptime{label: row.Label, ratio: float64(rowUp) / float64(rowTotal)})
+	}
+	if total == 0 {
+		return "No availability history yet"
+	}
+
+	sort.SliceStable(uptimes, func(i, j int) bool { return uptimes[i].ratio < uptimes[j].ratio })
+	var worst []string
+	for i := 0; i < len(uptimes) && i < maxDescribedRows; i++ {
+		if uptimes[i].ratio >= 1 {
+			break
+		}
+		worst = append(worst, fmt.Sprintf("%s %.0f%%", uptimes[i].label, uptimes[i].ratio*100.0))
+	}
+
+	text := fmt.Sprintf("Overall availability %.1f%% across %d targets", float64(up)/float64(total)*100.0, len(uptimes))
+	if len(worst) > 0 {
+		text += "; lowest: " + strings.Join(worst, ", ")
+	}
+	return text
+}
+
+// trafficLevels extracts the levels of stored traffic points
+func trafficLevels(points []history.TrafficPoint) []float64 {
+	levels := make([]float64, len(points))
+	for i, p := range points {
+		levels[i] = p.Level
+	}
+	return levels
+}
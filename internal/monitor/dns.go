@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/netblocks/netblocks/internal/capture"
 	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/crash"
 	"github.com/netblocks/netblocks/internal/models"
 )
 
@@ -21,6 +23,11 @@ type DNSMonitor struct {
 	statuses   map[string]*models.DNSStatus
 	mu         sync.RWMutex
 	timeout    time.Duration
+	lastCycle  time.Duration      // How long the last CheckAll took
+	cacheBust  bool               // Measure uncached resolution of recursive servers too (see SetCacheBust)
+	damping    config.FlapDamping // Consecutive checks needed to change a server's state (see SetFlapDamping)
+	capture    *capture.Writer    // Keeps the raw exchanges of failed and anomalous checks (nil: none)
+	captureAll bool               // Keep every exchange while a campaign asks for it (see SetCaptureAll)
 }
 
 // NewDNSMonitor creates a new DNS monitor
@@ -46,9 +53,32 @@ func NewDNSMonitor(servers []config.DNSServer, timeout time.Duration) *DNSMonito
 		servers:  servers,
 		statuses: statuses,
 		timeout:  timeout,
+		damping:  config.FlapDamping{DownAfter: 1, UpAfter: 1},
 	}
 }
 
+// SetServers replaces the monitored servers; statuses of servers still in the
+// list are kept, new servers are unchecked until the next CheckAll
+func (dm *DNSMonitor) SetServers(servers []config.DNSServer) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+
+	statuses := make(map[string]*models.DNSStatus)
+	for _, server := range servers {
+		key := server.Address + ":" + server.Name
+		if status, ok := dm.statuses[key]; ok {
+			statuses[key] = status
+			continue
+		}
+		statuses[key] = &models.DNSStatus{
+			Server: server.Address,
+			Name:   server.Name,
+		}
+	}
+	dm.servers = servers
+	dm.statuses = statuses
+}
+
 // isNetworkError checks if an error is a network-level error (timeout, connection refused, etc.)
 // These errors indicate the server is truly offline/unreachable
 func isNetworkError(err error) bool {
@@ -57,7 +87,7 @@ func isNetworkError(err error) bool {
 	}
 
 	errStr := strings.ToLower(err.Error())
-	
+
 	// Check for common network errors
 	networkErrorPatterns := []string{
 		"timeout",
@@ -90,59 +120,74 @@ func isNetworkError(err error) bool {
 
 // CheckAll checks all DNS servers
 func (dm *DNSMonitor) CheckAll(ctx context.Context) map[string]*models.DNSStatus {
+	start := time.Now()
 	var wg sync.WaitGroup
 	results := make(map[string]*models.DNSStatus)
 	mu := sync.Mutex{}
-	
+
 	// Track IP addresses that are confirmed alive to prevent overwriting with failed checks
 	aliveIPs := make(map[string]bool)
 
-	for _, server := range dm.servers {
+	dm.mu.RLock()
+	servers := dm.servers
+	dm.mu.RUnlock()
+	budget := newRetryBudget(len(servers), start, dm.timeout)
+
+	for _, server := range servers {
 		wg.Add(1)
 		go func(srv config.DNSServer) {
 			defer wg.Done()
-			status := dm.checkServer(ctx, srv)
-			
+			defer crash.Recover("DNS server check")
+			status := dm.checkServer(ctx, srv, budget)
+
 			mu.Lock()
 			// Use composite key (address:name) to handle duplicate IPs with different names
 			key := srv.Address + ":" + srv.Name
-			
+
 			// If this IP was already confirmed alive by another concurrent check,
 			// mark this entry as alive too (same IP, different name)
 			if !status.Alive && aliveIPs[srv.Address] {
-				status.Alive = true
-				status.Error = "" // Clear error since IP is confirmed alive
-				log.Printf("DNS server %s (%s) marked alive (IP %s confirmed alive by another check)", 
+				status.Alive, status.Sample = true, true
+				status.Error, status.ErrorCode = "", "" // Clear error since IP is confirmed alive
+				log.Printf("DNS server %s (%s) marked alive (IP %s confirmed alive by another check)",
 					srv.Address, srv.Name, srv.Address)
 			}
-			
-			// Track alive IPs
-			if status.Alive {
+
+			// Track alive IPs (answers of this cycle, not states held by flap damping)
+			if status.Sample {
 				aliveIPs[srv.Address] = true
 			}
-			
+
 			results[key] = status
 			mu.Unlock()
 		}(server)
 	}
 
 	wg.Wait()
-	
+	if used, denied := budget.usage(); denied > 0 {
+		log.Printf("⚠️  DNS retry budget used up: %d retries done, %d skipped (%d servers)", used, denied, len(servers))
+	}
+
 	// Ensure all statuses are updated in dm.statuses map
 	// Use composite keys to preserve all entries
+	// Servers removed by SetServers meanwhile stay removed
 	dm.mu.Lock()
 	for key, status := range results {
-		dm.statuses[key] = status
+		if _, ok := dm.statuses[key]; ok {
+			dm.statuses[key] = status
+		}
 	}
+	dm.lastCycle = time.Since(start)
 	dm.mu.Unlock()
-	
+
 	return results
 }
 
 // checkServer checks a single DNS server with retry logic for transient network errors
-func (dm *DNSMonitor) checkServer(ctx context.Context, server config.DNSServer) *models.DNSStatus {
+// Retries are taken from the budget shared by the cycle's checks
+func (dm *DNSMonitor) checkServer(ctx context.Context, server config.DNSServer, budget *retryBudget) *models.DNSStatus {
 	start := time.Now()
-	
+
 	// Create DNS client
 	client := &dns.Client{
 		Timeout: dm.timeout,
@@ -150,7 +195,7 @@ func (dm *DNSMonitor) checkServer(ctx context.Context, server config.DNSServer)
 
 	// Create a DNS message for leader.ir
 	msg := new(dns.Msg)
-	msg.SetQuestion("leader.ir.", dns.TypeA)
+	msg.SetQuestion(dnsProbeName, dns.TypeA)
 	// Set RecursionDesired based on server type (if specified)
 	// For authoritative servers, recursion may be refused, but that's OK
 	// Any DNS response (even REFUSED/NOTAUTH) means the server is online
@@ -174,9 +219,13 @@ func (dm *DNSMonitor) checkServer(ctx context.Context, server config.DNSServer)
 	baseDelay := 100 * time.Millisecond
 	var r *dns.Msg
 	var err error
-	
+
 	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
+			// Under mass failure the budget runs out and the first answer stands
+			if !budget.take(time.Now()) {
+				break
+			}
 			// Exponential backoff: 100ms, 200ms
 			delay := baseDelay * time.Duration(1<<uint(attempt-1))
 			select {
@@ -187,42 +236,44 @@ func (dm *DNSMonitor) checkServer(ctx context.Context, server config.DNSServer)
 				// Continue with retry
 			}
 		}
-		
+
 		// Query the DNS server
 		r, _, err = client.Exchange(msg, address)
-		
+
 		// If we got a response (even with error code), server is alive - no retry needed
 		if r != nil {
 			break
 		}
-		
+
 		// If it's not a network error, don't retry (e.g., DNS protocol errors)
 		if err != nil && !isNetworkError(err) {
 			break
 		}
-		
+
 		// If context is cancelled, don't retry
 		if err != nil && err == ctx.Err() {
 			break
 		}
-		
+
 		// For network errors, retry (transient issues like packet loss)
 		if err != nil && attempt < maxRetries {
-			log.Printf("DNS server %s (%s) retry attempt %d/%d: %v", 
+			log.Printf("DNS server %s (%s) retry attempt %d/%d: %v",
 				server.Address, server.Name, attempt+1, maxRetries, err)
 		}
 	}
-	
+
 	responseTime := time.Since(start)
-	
+
 	status := &models.DNSStatus{
-		Server:      server.Address,
-		Name:        server.Name,
-		LastCheck:   time.Now(),
+		Server:       server.Address,
+		Name:         server.Name,
+		LastCheck:    time.Now(),
 		ResponseTime: responseTime,
+		Type:         serverType(server),
 	}
 
 	if err != nil {
+		status.ErrorCode = dnsErrorCode(err)
 		// Check if it's a network error (server truly offline) vs other error
 		if isNetworkError(err) {
 			status.Alive = false
@@ -238,34 +289,53 @@ func (dm *DNSMonitor) checkServer(ctx context.Context, server config.DNSServer)
 		// ANY DNS response means the server is alive and responding
 		// Response codes like NOTAUTH, REFUSED, NXDOMAIN still mean server is online
 		status.Alive = true
-		
+
 		if r.Rcode != dns.RcodeSuccess {
 			// Server responded but with a non-success code - still alive!
 			rcodeName := dns.RcodeToString[r.Rcode]
 			status.Error = fmt.Sprintf("DNS response: %s (rcode %d)", rcodeName, r.Rcode)
-			log.Printf("DNS server %s (%s) responded with %s - server is online", 
+			status.ErrorCode = rcodeErrorCode(r.Rcode)
+			log.Printf("DNS server %s (%s) responded with %s - server is online",
 				server.Address, server.Name, rcodeName)
 		}
 		// If RcodeSuccess, no error message needed - server is working perfectly
+		if address := injectedAddress(r); address != "" {
+			status.Error = "Injected answer: " + address
+			status.ErrorCode = models.DNSErrorInjectedAnswer
+		}
+
+		// Alive is not enough: the server must also behave as its type requires
+		status.Verdict, status.Reason = assessDNS(client, address, server, r)
+		if status.Verdict == models.VerdictFail {
+			log.Printf("DNS server %s (%s) is alive but fails the %s check: %s",
+				server.Address, server.Name, status.Type, status.Reason)
+		}
+
+		// The answer above may come from the cache; a random name has to be resolved
+		if dm.cacheBusting(server) {
+			if rtt, err := measureUncached(client, address); err != nil {
+				log.Printf("DNS server %s (%s) uncached query failed: %v", server.Address, server.Name, err)
+			} else {
+				status.UncachedTime = rtt
+			}
+		}
 	} else {
 		// This shouldn't happen (err == nil but r == nil), but handle it
 		status.Alive = false
 		status.Error = "DNS query returned nil response"
+		status.ErrorCode = models.DNSErrorNoResponse
 		log.Printf("DNS server %s (%s) returned nil response", server.Address, server.Name)
 	}
 
+	dm.captureExchange(server, status, msg, r, err)
+
 	// Use composite key to handle duplicate IPs with different names
 	key := server.Address + ":" + server.Name
-	
+
 	dm.mu.Lock()
-	// If IP is already confirmed alive, preserve that status
-	if existing, exists := dm.statuses[key]; exists && existing.Alive && !status.Alive {
-		// Don't overwrite alive status with dead status for the same IP
-		// This handles race conditions in concurrent checks
-		status = existing
-	} else {
-		dm.statuses[key] = status
-	}
+	// The previous status decides together with this check (flap damping)
+	dm.damp(dm.statuses[key], status)
+	dm.statuses[key] = status
 	dm.mu.Unlock()
 	return status
 }
@@ -278,32 +348,27 @@ func (dm *DNSMonitor) GetStatuses() map[string]*models.DNSStatus {
 	result := make(map[string]*models.DNSStatus)
 	for addr, status := range dm.statuses {
 		result[addr] = &models.DNSStatus{
-			Server:      status.Server,
-			Name:        status.Name,
-			Alive:       status.Alive,
+			Server:       status.Server,
+			Name:         status.Name,
+			Alive:        status.Alive,
+			Sample:       status.Sample,
+			Streak:       status.Streak,
 			ResponseTime: status.ResponseTime,
-			LastCheck:   status.LastCheck,
-			Error:       status.Error,
+			UncachedTime: status.UncachedTime,
+			LastCheck:    status.LastCheck,
+			Error:        status.Error,
+			ErrorCode:    status.ErrorCode,
+			Type:         status.Type,
+			Verdict:      status.Verdict,
+			Reason:       status.Reason,
 		}
 	}
 	return result
 }
 
-// StartPeriodicCheck starts periodic DNS checks
-// Note: Initial check is performed synchronously in Monitor.Start() to ensure
-// results are available before first status display
-func (dm *DNSMonitor) StartPeriodicCheck(ctx context.Context, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			log.Println("Performing periodic DNS check...")
-			dm.CheckAll(ctx)
-		}
-	}
+// LastCycleDuration returns how long the most recent check of all servers took
+func (dm *DNSMonitor) LastCycleDuration() time.Duration {
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.lastCycle
 }
-
@@ -0,0 +1,47 @@
+package monitor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// SetCacheBust sets whether recursive servers also get a query for a random name
+// (dns_cache_bust); servers with cache_bust set keep their own choice
+func (dm *DNSMonitor) SetCacheBust(enabled bool) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.cacheBust = enabled
+}
+
+// cacheBusting reports whether a server gets the uncached query; authoritative
+// servers do not resolve names of other zones
+func (dm *DNSMonitor) cacheBusting(server config.DNSServer) bool {
+	if serverType(server) == dnsTypeAuthoritative {
+		return false
+	}
+	if server.CacheBust != nil {
+		return *server.CacheBust
+	}
+	dm.mu.RLock()
+	defer dm.mu.RUnlock()
+	return dm.cacheBust
+}
+
+// measureUncached resolves a random name below the probe name, which the resolver
+// cannot have cached, so the round trip includes the resolution itself
+// Any answer counts: the random name normally does not exist (NXDOMAIN)
+func measureUncached(client *dns.Client, address string) (time.Duration, error) {
+	label := make([]byte, 6)
+	if _, err := rand.Read(label); err != nil {
+		return 0, err
+	}
+	msg := new(dns.Msg)
+	msg.SetQuestion(hex.EncodeToString(label)+"."+dnsProbeName, dns.TypeA)
+	msg.RecursionDesired = true
+	_, rtt, err := client.Exchange(msg, address)
+	return rtt, err
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"log"
+
+	"github.com/miekg/dns"
+	"github.com/netblocks/netblocks/internal/capture"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// SetCapture sets where the raw exchanges of failed and anomalous checks are
+// kept (nil keeps none)
+func (dm *DNSMonitor) SetCapture(w *capture.Writer) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.capture = w
+}
+
+// SetCaptureAll makes the capture keep every exchange, not only failed and
+// anomalous ones, e.g. during a measurement campaign
+func (dm *DNSMonitor) SetCaptureAll(all bool) {
+	dm.mu.Lock()
+	defer dm.mu.Unlock()
+	dm.captureAll = all
+}
+
+// captureReason says why an exchange is worth keeping, or "" if it is not
+func captureReason(status *models.DNSStatus, r *dns.Msg) string {
+	if r == nil {
+		return "no answer"
+	}
+	// Resolvers inside the country answer blocked names with private addresses
+	if address := injectedAddress(r); address != "" {
+		return "injected answer " + address
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return "rcode " + dns.RcodeToString[r.Rcode]
+	}
+	if status.Verdict == models.VerdictFail {
+		return "type check failed: " + status.Reason
+	}
+	return ""
+}
+
+// captureExchange writes the query and response of a check if it failed or
+// looks anomalous (or every check with dns_capture.all or during a campaign)
+func (dm *DNSMonitor) captureExchange(server config.DNSServer, status *models.DNSStatus, query, r *dns.Msg, err error) {
+	dm.mu.RLock()
+	w, all := dm.capture, dm.captureAll
+	dm.mu.RUnlock()
+	if w == nil {
+		return
+	}
+	reason := captureReason(status, r)
+	if reason == "" {
+		if !w.All() && !all {
+			return
+		}
+		reason = "all"
+	}
+
+	rec := capture.Record{
+		Time:   status.LastCheck,
+		Server: server.Address,
+		Name:   server.Name,
+		Reason: reason,
+		RTT:    status.ResponseTime.Milliseconds(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	rec.Query, _ = query.Pack()
+	if r != nil {
+		rec.Response, _ = r.Pack()
+	}
+	if err := w.Write(rec); err != nil {
+		log.Printf("⚠️  Failed to write DNS capture: %v", err)
+	}
+}
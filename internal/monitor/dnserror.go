@@ -0,0 +1,66 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/miekg/dns"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// dnsErrorCode classifies the error of a DNS exchange that got no answer
+func dnsErrorCode(err error) models.DNSErrorCode {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return models.DNSErrorTimeout
+	case errors.Is(err, syscall.ENETUNREACH), errors.Is(err, syscall.EHOSTUNREACH):
+		return models.DNSErrorNetworkUnreachable
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET):
+		return models.DNSErrorConnectionRefused
+	}
+
+	// Errors wrapped without their errno only keep the message
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "timeout"), strings.Contains(message, "timed out"):
+		return models.DNSErrorTimeout
+	case strings.Contains(message, "network is unreachable"), strings.Contains(message, "host unreachable"),
+		strings.Contains(message, "no route to host"):
+		return models.DNSErrorNetworkUnreachable
+	case strings.Contains(message, "connection refused"), strings.Contains(message, "connection reset"):
+		return models.DNSErrorConnectionRefused
+	case isNetworkError(err):
+		return models.DNSErrorNetwork
+	}
+	return models.DNSErrorUnknown
+}
+
+// rcodeErrorCode classifies an error rcode of an answer
+func rcodeErrorCode(rcode int) models.DNSErrorCode {
+	switch rcode {
+	case dns.RcodeRefused:
+		return models.DNSErrorRefused
+	case dns.RcodeNameError:
+		return models.DNSErrorNXDomain
+	case dns.RcodeServerFailure:
+		return models.DNSErrorServFail
+	}
+	return models.DNSErrorRcode
+}
+
+// injectedAddress returns the private address of an answer, as resolvers
+// inside the country answer blocked names with ("" if there is none)
+func injectedAddress(r *dns.Msg) string {
+	for _, rr := range r.Answer {
+		if a, ok := rr.(*dns.A); ok && isPrivateAddress(a.A.String()) {
+			return a.A.String()
+		}
+	}
+	return ""
+}
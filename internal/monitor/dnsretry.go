@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// Retry budget of a DNS check cycle
+const (
+	retryBudgetRatio = 0.2 // Retries per cycle as a share of the checked servers
+	minRetryBudget   = 5   // Retries every cycle may use, however few servers it checks
+	retryWindowScale = 2   // Retries start within this many query timeouts of the cycle start
+)
+
+// retryBudget is shared by the server checks of one CheckAll. Retries help
+// against packet loss at a few servers, but when hundreds time out during a
+// shutdown every retry adds a full query timeout to the cycle. The budget caps
+// the number of retries per cycle and refuses retries once the cycle has run
+// for retryWindowScale timeouts, so mass failure costs about two timeouts
+// instead of three and the servers beyond the budget are checked once
+type retryBudget struct {
+	mu        sync.Mutex
+	remaining int
+	size      int
+	deadline  time.Time // No retry starts after this
+	denied    int       // Retries refused by the budget
+}
+
+func newRetryBudget(servers int, start time.Time, timeout time.Duration) *retryBudget {
+	size := int(float64(servers) * retryBudgetRatio)
+	if size < minRetryBudget {
+		size = minRetryBudget
+	}
+	return &retryBudget{remaining: size, size: size, deadline: start.Add(retryWindowScale * timeout)}
+}
+
+// take reserves a retry, or reports that the budget allows none at now
+func (b *retryBudget) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining == 0 || now.After(b.deadline) {
+		b.denied++
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// usage returns the retries used and refused so far
+func (b *retryBudget) usage() (used, denied int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.size - b.remaining, b.denied
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Server types of config.DNSServer
+const (
+	dnsTypeRecursive     = "recursive"
+	dnsTypeAuthoritative = "authoritative"
+	dnsTypeBoth          = "both"
+)
+
+// dnsProbeName is the name resolved by the liveness query and the recursion check
+const dnsProbeName = "leader.ir."
+
+// serverType returns the configured type of a server ("both" if unset or unknown)
+func serverType(server config.DNSServer) string {
+	switch t := strings.ToLower(server.Type); t {
+	case dnsTypeRecursive, dnsTypeAuthoritative:
+		return t
+	default:
+		return dnsTypeBoth
+	}
+}
+
+// assessDNS checks whether an alive server behaves as its type requires:
+// recursives must resolve the probe name (the liveness answer), authoritative
+// servers must answer authoritatively with SOA and NS records for their zone.
+// "both" is checked for recursion and, if a zone is configured, authority.
+// It returns the verdict and its reason; authoritative servers without a zone
+// are not checked (empty verdict)
+func assessDNS(client *dns.Client, address string, server config.DNSServer, answer *dns.Msg) (verdict, reason string) {
+	var reasons []string
+	verdict = models.VerdictPass
+	record := func(ok bool, why string) {
+		if !ok {
+			verdict = models.VerdictFail
+		}
+		reasons = append(reasons, why)
+	}
+
+	t := serverType(server)
+	if t != dnsTypeAuthoritative {
+		record(assessRecursion(answer))
+	}
+	if t != dnsTypeRecursive && server.Zone != "" {
+		record(assessAuthority(client, address, dns.Fqdn(server.Zone)))
+	}
+	if len(reasons) == 0 {
+		return "", ""
+	}
+	return verdict, strings.Join(reasons, "; ")
+}
+
+// assessRecursion judges the answer to the recursive liveness query
+func assessRecursion(answer *dns.Msg) (bool, string) {
+	switch answer.Rcode {
+	case dns.RcodeSuccess:
+		if !answer.RecursionAvailable {
+			return false, "recursion not available (RA flag unset)"
+		}
+		for _, rr := range answer.Answer {
+			if _, ok := rr.(*dns.A); ok {
+				return true, "resolves " + strings.TrimSuffix(dnsProbeName, ".")
+			}
+		}
+		return false, "no address for " + strings.TrimSuffix(dnsProbeName, ".")
+	case dns.RcodeNameError:
+		// A resolver that finds the name missing still recursed
+		if answer.RecursionAvailable {
+			return true, "recursion works (NXDOMAIN)"
+		}
+		return false, "recursion not available (RA flag unset)"
+	case dns.RcodeRefused:
+		return false, "recursion refused (REFUSED)"
+	default:
+		return false, fmt.Sprintf("cannot resolve %s (%s)", strings.TrimSuffix(dnsProbeName, "."), dns.RcodeToString[answer.Rcode])
+	}
+}
+
+// assessAuthority asks a server for the SOA and NS records of its zone without
+// recursion; both must be authoritative answers
+func assessAuthority(client *dns.Client, address, zone string) (bool, string) {
+	name := strings.TrimSuffix(zone, ".")
+	var serial uint32
+	var hasSOA bool
+	var nameservers int
+	for _, qtype := range []uint16{dns.TypeSOA, dns.TypeNS} {
+		msg := new(dns.Msg)
+		msg.SetQuestion(zone, qtype)
+		msg.RecursionDesired = false
+		r, _, err := client.Exchange(msg, address)
+		if err != nil {
+			return false, fmt.Sprintf("%s %s query failed: %v", name, dns.TypeToString[qtype], err)
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			return false, fmt.Sprintf("%s %s answered %s", name, dns.TypeToString[qtype], dns.RcodeToString[r.Rcode])
+		}
+		if !r.Authoritative {
+			return false, fmt.Sprintf("not authoritative for %s (AA flag unset)", name)
+		}
+		for _, rr := range r.Answer {
+			switch rr := rr.(type) {
+			case *dns.SOA:
+				serial, hasSOA = rr.Serial, true
+			case *dns.NS:
+				nameservers++
+			}
+		}
+		if qtype == dns.TypeSOA && !hasSOA {
+			return false, "no SOA record for " + name
+		}
+	}
+	if nameservers == 0 {
+		return false, "no NS records for " + name
+	}
+	return true, fmt.Sprintf("authoritative for %s (SOA serial %d, %d NS)", name, serial, nameservers)
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/crash"
+)
+
+const (
+	drainPoll  = 50 * time.Millisecond // How often Drain checks whether the work in progress finished
+	abortGrace = time.Second           // How long cancelled work gets to return after the drain timeout
+)
+
+// workTracker counts the fetches and cycles in progress. They run on their own
+// context instead of the one of Start, so cancelling Start lets them finish
+// (see Drain) rather than leaving a partial cycle behind
+type workTracker struct {
+	mu       sync.Mutex
+	active   int
+	draining bool // Set by Drain; no new work starts afterwards
+	ctx      context.Context
+	abort    context.CancelFunc
+}
+
+func newWorkTracker() *workTracker {
+	ctx, abort := context.WithCancel(context.Background())
+	return &workTracker{ctx: ctx, abort: abort}
+}
+
+// begin registers work about to start; false once draining has begun
+func (w *workTracker) begin() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.draining {
+		return false
+	}
+	w.active++
+	return true
+}
+
+func (w *workTracker) done() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.active--
+}
+
+func (w *workTracker) idle() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.active == 0
+}
+
+// run calls fn with the work context unless draining has begun; a panic of fn
+// is reported under name (see crash.Recover) and ends only this run
+func (w *workTracker) run(name string, fn func(ctx context.Context)) {
+	if !w.begin() {
+		return
+	}
+	defer w.done()
+	defer crash.Recover(name)
+	fn(w.ctx)
+}
+
+// Drain waits up to timeout for the fetches and the cycle in progress (DNS
+// checks, history writes, event delivery) to finish, and reports whether they
+// did; work still running at the timeout is cancelled. Call it after cancelling
+// the context of Start and before Stop
+func (m *Monitor) Drain(timeout time.Duration) bool {
+	m.work.mu.Lock()
+	m.work.draining = true
+	m.work.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	for !m.work.idle() {
+		if time.Now().After(deadline) {
+			log.Printf("⚠️  Monitor work still running after %v, cancelling it", timeout)
+			m.work.abort()
+			for wait := time.Now().Add(abortGrace); !m.work.idle() && time.Now().Before(wait); {
+				time.Sleep(drainPoll)
+			}
+			return false
+		}
+		time.Sleep(drainPoll)
+	}
+	return true
+}
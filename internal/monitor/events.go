@@ -0,0 +1,299 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// massOutageRatio is the share of ASNs that must drop in a single cycle to be
+// reported as a critical, country-wide event rather than individual changes
+const massOutageRatio = 0.3
+
+// Connected RIPE Atlas probes change a few at a time in normal operation; a
+// quarter of them (and at least atlasProbeDropMin) dropping off within one
+// check is a warning, half of them critical
+const (
+	atlasProbeDropRatio     = 0.25
+	atlasProbeCriticalRatio = 0.5
+	atlasProbeDropMin       = 3
+)
+
+// DetectChanges compares two monitoring results and returns the state changes between them
+// Individual DNS flaps are informational, ASN losses and collapsing ASN traffic
+// shares are warnings, and traffic shutdowns or mass ASN outages are critical
+func DetectChanges(prev, cur *models.MonitoringResult) []models.Event {
+	if prev == nil || cur == nil {
+		return nil
+	}
+
+	now := cur.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var events []models.Event
+
+	// ASN connectivity changes; those of flapping ASNs are reported once when
+	// the flapping starts and when it is over
+	disconnected := 0
+	for asn, status := range cur.ASNStatuses {
+		before, ok := prev.ASNStatuses[asn]
+		if !ok {
+			continue
+		}
+		name := asn
+		if status.Name != "" && status.Name != "Unknown" {
+			name = fmt.Sprintf("%s (%s)", asn, status.Name)
+		}
+		switch {
+		case status.Flapping && !before.Flapping:
+			events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: asn, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("%s flapping: %d connectivity changes, further ones are not alerted", name, status.Flaps)})
+			continue
+		case !status.Flapping && before.Flapping:
+			state, severity := "disconnected", models.SeverityWarning
+			if status.Connected {
+				state, severity = "connected", models.SeverityInfo
+			}
+			events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: asn, Severity: severity,
+				Message: fmt.Sprintf("%s stopped flapping, %s", name, state)})
+			continue
+		case status.Flapping || before.Connected == status.Connected:
+			continue
+		}
+		if status.Connected {
+			events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: asn, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("%s reconnected", name)})
+		} else {
+			disconnected++
+			events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: asn, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("%s disconnected", name)})
+		}
+	}
+	if len(cur.ASNStatuses) > 0 && float64(disconnected)/float64(len(cur.ASNStatuses)) >= massOutageRatio {
+		events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: "IR", Severity: models.SeverityCritical,
+			Message: fmt.Sprintf("%d of %d ASNs disconnected within one check", disconnected, len(cur.ASNStatuses))})
+	}
+
+	// Withdrawal storms: many withdrawals of an ASN's prefixes within the
+	// withdrawal_storm window, the routing signature of a shutdown
+	storms := 0
+	for asn, status := range cur.ASNStatuses {
+		before, ok := prev.ASNStatuses[asn]
+		if !ok || before.WithdrawalStorm == status.WithdrawalStorm {
+			continue
+		}
+		name := asn
+		if status.Name != "" && status.Name != "Unknown" {
+			name = fmt.Sprintf("%s (%s)", asn, status.Name)
+		}
+		if status.WithdrawalStorm {
+			storms++
+			events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: asn, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("%s withdrawal storm: %d BGP withdrawals of its prefixes", name, status.Withdrawals)})
+		} else {
+			events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: asn, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("%s withdrawal storm over", name)})
+		}
+	}
+	if storms >= 2 && float64(storms)/float64(len(cur.ASNStatuses)) >= massOutageRatio {
+		events = append(events, models.Event{Timestamp: now, Kind: "asn", Target: "IR", Severity: models.SeverityCritical,
+			Message: fmt.Sprintf("Withdrawal storms in %d of %d ASNs within one check", storms, len(cur.ASNStatuses))})
+	}
+
+	// Prefix withdrawals, also of part of an ASN's address space; prefixes
+	// never seen announced since start have no state to compare
+	withdrawn, followed := 0, 0
+	for prefix, status := range cur.Prefixes {
+		before, ok := prev.Prefixes[prefix]
+		if !ok || before.LastAnnounced.IsZero() {
+			continue
+		}
+		followed++
+		if before.Announced == status.Announced {
+			continue
+		}
+		name := prefix
+		if status.Origin != "" {
+			name = fmt.Sprintf("%s (%s)", prefix, status.Origin)
+		}
+		if status.WithdrawalStorm && !before.WithdrawalStorm {
+			events = append(events, models.Event{Timestamp: now, Kind: "prefix", Target: prefix, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("%s withdrawal storm: %d BGP withdrawals within it", prefix, status.Withdrawals)})
+		}
+		if before.Announced == status.Announced {
+			continue
+		}
+		if status.Announced {
+			events = append(events, models.Event{Timestamp: now, Kind: "prefix", Target: prefix, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("%s announced again (%d RIS peers)", name, status.Peers)})
+		} else {
+			withdrawn++
+			events = append(events, models.Event{Timestamp: now, Kind: "prefix", Target: prefix, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("%s withdrawn: no RIS peer has a route to it", name)})
+		}
+	}
+	if withdrawn >= 2 && float64(withdrawn)/float64(followed) >= massOutageRatio {
+		events = append(events, models.Event{Timestamp: now, Kind: "prefix", Target: "IR", Severity: models.SeverityCritical,
+			Message: fmt.Sprintf("%d of %d prefixes withdrawn within one check", withdrawn, followed)})
+	}
+
+	// ASN traffic share collapses, reported when the share first counts as dropped
+	wasDropped := make(map[string]bool, len(prev.ASTrafficData))
+	for _, item := range prev.ASTrafficData {
+		wasDropped[item.ASN] = ShareDropped(item)
+	}
+	for _, item := range cur.ASTrafficData {
+		if !ShareDropped(item) || wasDropped[item.ASN] {
+			continue
+		}
+		events = append(events, models.Event{Timestamp: now, Kind: "asn_share", Target: item.ASN, Severity: models.SeverityWarning,
+			Message: fmt.Sprintf("%s (%s) traffic share %s", item.Name, item.ASN, DescribeShareChange(item))})
+	}
+
+	// DNS availability changes
+	for key, status := range cur.DNSStatuses {
+		before, ok := prev.DNSStatuses[key]
+		if !ok || before.Alive == status.Alive {
+			continue
+		}
+		state := "went offline"
+		if status.Alive {
+			state = "is back online"
+		}
+		events = append(events, models.Event{Timestamp: now, Kind: "dns", Target: key, Severity: models.SeverityInfo,
+			Message: fmt.Sprintf("DNS %s (%s) %s", status.Name, status.Server, state)})
+	}
+
+	// Traffic status changes
+	if prev.TrafficData != nil && cur.TrafficData != nil && prev.TrafficData.Status != cur.TrafficData.Status {
+		severity := models.SeverityInfo
+		switch cur.TrafficData.Status {
+		case "Shutdown", "Throttled":
+			severity = models.SeverityCritical
+		case "Degraded":
+			severity = models.SeverityWarning
+		}
+		events = append(events, models.Event{Timestamp: now, Kind: "traffic", Target: "IR", Severity: severity,
+			Message: fmt.Sprintf("Traffic status changed from %s to %s (level %.1f%%)",
+				prev.TrafficData.Status, cur.TrafficData.Status, cur.TrafficData.CurrentLevel)})
+	}
+
+	// Check hook outcome changes
+	before := make(map[string]*models.CustomCheck, len(prev.CustomChecks))
+	for _, check := range prev.CustomChecks {
+		before[check.Key()] = check
+	}
+	for _, check := range cur.CustomChecks {
+		if was, ok := before[check.Key()]; !ok || was.Up == check.Up {
+			continue
+		}
+		if check.Up {
+			events = append(events, models.Event{Timestamp: now, Kind: "check", Target: check.Key(), Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("Check %s recovered", check.Key())})
+		} else {
+			message := fmt.Sprintf("Check %s failed", check.Key())
+			if check.Detail != "" {
+				message += ": " + check.Detail
+			}
+			events = append(events, models.Event{Timestamp: now, Kind: "check", Target: check.Key(), Severity: models.SeverityWarning,
+				Message: message})
+		}
+	}
+
+	// RIPE Atlas anchors becoming unreachable from abroad; losing all of them
+	// within one check is critical
+	wasReachable := make(map[int]bool, len(prev.AtlasAnchors))
+	for _, anchor := range prev.AtlasAnchors {
+		wasReachable[anchor.ID] = anchor.Reachable
+	}
+	lost := 0
+	for _, anchor := range cur.AtlasAnchors {
+		reachable, ok := wasReachable[anchor.ID]
+		if !ok || reachable == anchor.Reachable {
+			continue
+		}
+		if anchor.Reachable {
+			events = append(events, models.Event{Timestamp: now, Kind: "atlas", Target: anchor.FQDN, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("RIPE Atlas anchor %s (%s) is reachable again from %d/%d probes", anchor.FQDN, anchor.ASN, anchor.Reached, anchor.Probes)})
+		} else {
+			lost++
+			events = append(events, models.Event{Timestamp: now, Kind: "atlas", Target: anchor.FQDN, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("RIPE Atlas anchor %s (%s) unreachable: %d/%d probes abroad got a reply", anchor.FQDN, anchor.ASN, anchor.Reached, anchor.Probes)})
+		}
+	}
+	if len(cur.AtlasAnchors) >= 2 && lost == len(cur.AtlasAnchors) {
+		events = append(events, models.Event{Timestamp: now, Kind: "atlas", Target: "IR", Severity: models.SeverityCritical,
+			Message: fmt.Sprintf("All %d RIPE Atlas anchors became unreachable from abroad within one check", lost)})
+	}
+
+	// Connected RIPE Atlas probes dropping off together, or coming back
+	if prev.AtlasProbes != nil && cur.AtlasProbes != nil {
+		before, after := prev.AtlasProbes.Connected, cur.AtlasProbes.Connected
+		switch {
+		case before-after >= atlasProbeDropMin && float64(before-after) >= float64(before)*atlasProbeDropRatio:
+			severity := models.SeverityWarning
+			if float64(before-after) >= float64(before)*atlasProbeCriticalRatio {
+				severity = models.SeverityCritical
+			}
+			events = append(events, models.Event{Timestamp: now, Kind: "atlas", Target: "probes", Severity: severity,
+				Message: fmt.Sprintf("RIPE Atlas probes connected dropped from %d to %d", before, after)})
+		case after-before >= atlasProbeDropMin && float64(after-before) >= float64(after)*atlasProbeDropRatio:
+			events = append(events, models.Event{Timestamp: now, Kind: "atlas", Target: "probes", Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("RIPE Atlas probes reconnecting: %d connected, up from %d", after, before)})
+		}
+	}
+
+	// Telegram endpoints becoming unreachable from a vantage, e.g. filtered
+	// for an in-country probe
+	if prev.Telegram != nil && cur.Telegram != nil {
+		wasReached := make(map[string]bool, len(prev.Telegram.Endpoints))
+		for _, endpoint := range prev.Telegram.Endpoints {
+			wasReached[endpoint.Key()] = endpoint.Reachable
+		}
+		for _, endpoint := range cur.Telegram.Endpoints {
+			reached, ok := wasReached[endpoint.Key()]
+			if !ok || reached == endpoint.Reachable {
+				continue
+			}
+			from := ""
+			if endpoint.Vantage != nil && endpoint.Vantage.ProbeID != "" {
+				from = " from " + endpoint.Vantage.String()
+			}
+			if endpoint.Reachable {
+				events = append(events, models.Event{Timestamp: now, Kind: "telegram", Target: endpoint.Address, Severity: models.SeverityInfo,
+					Message: fmt.Sprintf("Telegram endpoint %s is reachable again%s (%s)", endpoint.Address, from, endpoint.Latency.Round(time.Millisecond))})
+			} else {
+				events = append(events, models.Event{Timestamp: now, Kind: "telegram", Target: endpoint.Address, Severity: models.SeverityWarning,
+					Message: fmt.Sprintf("Telegram endpoint %s unreachable%s: %s", endpoint.Address, from, endpoint.Error)})
+			}
+		}
+	}
+
+	// National score crossing into a worse band
+	prevStatus, _ := ScoreStatus(prev.NationalScore)
+	curStatus, _ := ScoreStatus(cur.NationalScore)
+	if prevStatus != curStatus && cur.NationalScore < prev.NationalScore {
+		severity := models.SeverityWarning
+		if curStatus == "Shutdown" {
+			severity = models.SeverityCritical
+		}
+		events = append(events, models.Event{Timestamp: now, Kind: "national", Target: "IR", Severity: severity,
+			Message: fmt.Sprintf("National score fell from %.0f to %.0f (%s)", prev.NationalScore, cur.NationalScore, curStatus)})
+	}
+
+	return events
+}
+
+// SeverityEmoji returns the emoji used to display an event severity
+func SeverityEmoji(severity string) string {
+	switch severity {
+	case models.SeverityCritical:
+		return "🚨"
+	case models.SeverityWarning:
+		return "⚠️"
+	default:
+		return "ℹ️"
+	}
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// failureChartDays is how many days the failure-cause chart covers
+const failureChartDays = 7
+
+// failureCodes orders the DNS error codes in the failure-cause chart and its
+// legend: the censorship signals (injection, refusals) before plain outages
+var failureCodes = []struct {
+	code  models.DNSErrorCode
+	color drawing.Color
+}{
+	{models.DNSErrorInjectedAnswer, drawing.Color{R: 142, G: 68, B: 173, A: 255}},
+	{models.DNSErrorRefused, drawing.Color{R: 192, G: 57, B: 43, A: 255}},
+	{models.DNSErrorConnectionRefused, drawing.Color{R: 231, G: 76, B: 60, A: 255}},
+	{models.DNSErrorNXDomain, drawing.Color{R: 211, G: 84, B: 0, A: 255}},
+	{models.DNSErrorServFail, drawing.Color{R: 243, G: 156, B: 18, A: 255}},
+	{models.DNSErrorRcode, drawing.Color{R: 241, G: 196, B: 15, A: 255}},
+	{models.DNSErrorTimeout, drawing.Color{R: 52, G: 73, B: 94, A: 255}},
+	{models.DNSErrorNetworkUnreachable, drawing.Color{R: 41, G: 128, B: 185, A: 255}},
+	{models.DNSErrorNetwork, drawing.Color{R: 93, G: 173, B: 226, A: 255}},
+	{models.DNSErrorNoResponse, drawing.Color{R: 127, G: 140, B: 141, A: 255}},
+	{models.DNSErrorUnknown, drawing.Color{R: 189, G: 195, B: 199, A: 255}},
+}
+
+// FailureCauses counts the failed and anomalous DNS checks of a result by error code
+func FailureCauses(result *models.MonitoringResult) map[models.DNSErrorCode]int {
+	counts := make(map[models.DNSErrorCode]int)
+	if result == nil {
+		return counts
+	}
+	for _, status := range result.DNSStatuses {
+		if status.ErrorCode != "" {
+			counts[status.ErrorCode]++
+		}
+	}
+	return counts
+}
+
+// GenerateFailureChart renders the share of each DNS error code in the failed
+// checks of each day ending at end, as 100% stacked bars labeled with the day's total
+// The mix tells the techniques apart: timeouts for dropped packets, refusals
+// for blocked resolvers, injected answers for DNS tampering
+func GenerateFailureChart(title string, buckets []history.FailureBucket, end time.Time, days int) (*bytes.Buffer, error) {
+	if len(buckets) == 0 || days <= 0 {
+		return nil, fmt.Errorf("no DNS failures recorded")
+	}
+
+	const (
+		barWidth    = 70
+		barGap      = 30
+		plotHeight  = 300
+		topPadding  = 70
+		sidePadding = 40
+		legendRow   = 18
+	)
+
+	legendRows := (len(failureCodes) + 2) / 3
+	width := sidePadding*2 + days*(barWidth+barGap)
+	height := topPadding + plotHeight + 40 + legendRows*legendRow + 30
+
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create failure chart renderer: %w", err)
+	}
+
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart font: %w", err)
+	}
+	r.SetFont(font)
+
+	drawRect(r, 0, 0, width, height, drawing.Color{R: 255, G: 255, B: 255, A: 255})
+
+	r.SetFontColor(drawing.Color{R: 0, G: 0, B: 0, A: 255})
+	r.SetFontSize(16)
+	r.Text(title, sidePadding, 30)
+
+	byDay := make(map[int64]history.FailureBucket, len(buckets))
+	for _, b := range buckets {
+		byDay[b.Start.UTC().Truncate(24*time.Hour).Unix()] = b
+	}
+	endDay := end.UTC().Truncate(24 * time.Hour)
+	startDay := endDay.AddDate(0, 0, -(days - 1))
+
+	r.SetFontSize(9)
+	for d := 0; d < days; d++ {
+		day := startDay.AddDate(0, 0, d)
+		x := sidePadding + d*(barWidth+barGap)
+		r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+		r.Text(day.Format("Jan 2"), x+barWidth/2-14, topPadding+plotHeight+15)
+
+		b, ok := byDay[day.Unix()]
+		if !ok || b.Total == 0 {
+			drawRect(r, x, topPadding+plotHeight-2, barWidth, 2, drawing.Color{R: 220, G: 220, B: 220, A: 255})
+			continue
+		}
+		r.Text(fmt.Sprintf("%d", b.Total), x+barWidth/2-8, topPadding-6)
+
+		// Stack from the bottom; the last segment takes the rounding remainder
+		y := topPadding + plotHeight
+		seen := 0
+		for _, fc := range failureCodes {
+			count := b.Counts[fc.code]
+			if count == 0 {
+				continue
+			}
+			seen += count
+			top := topPadding + plotHeight - plotHeight*seen/b.Total
+			drawRect(r, x, top, barWidth, y-top, fc.color)
+			y = top
+		}
+	}
+
+	// Legend, three codes per row
+	legendY := topPadding + plotHeight + 40
+	for i, fc := range failureCodes {
+		x := sidePadding + (i%3)*((width-sidePadding*2)/3)
+		y := legendY + (i/3)*legendRow
+		drawRect(r, x, y-9, 10, 10, fc.color)
+		r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+		r.Text(string(fc.code), x+15, y)
+	}
+	r.Text("Share of failed DNS checks by cause per day, total on top (UTC)", sidePadding, legendY+legendRows*legendRow+10)
+
+	buffer := charts.scratch()
+	if err := r.Save(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render failure chart: %w", err)
+	}
+	return buffer, nil
+}
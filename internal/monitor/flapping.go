@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// FlapDetector counts the connectivity changes of each ASN over a sliding
+// window and marks the ASNs that keep connecting and disconnecting as
+// flapping, so their changes are alerted once rather than one by one
+type FlapDetector struct {
+	threshold int
+	window    time.Duration
+
+	mu   sync.Mutex // Results are also assembled on demand (GetResults)
+	asns map[string]*flapState
+}
+
+// flapState is the connectivity history of one ASN
+type flapState struct {
+	connected bool
+	changes   []time.Time // Within the window, oldest first
+	flapping  bool
+}
+
+// NewFlapDetector creates the flap detection of the ASNs
+func NewFlapDetector(settings config.ASNFlapping) *FlapDetector {
+	window, _ := time.ParseDuration(settings.Window) // Checked by config.LoadConfig
+	return &FlapDetector{threshold: settings.Threshold, window: window, asns: make(map[string]*flapState)}
+}
+
+// Apply records the changes of this cycle's ASN statuses and sets their flap
+// counts; an ASN flaps above the threshold and is stable again at half of it
+func (d *FlapDetector) Apply(statuses map[string]*models.ASNStatus, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for asn, status := range statuses {
+		state, ok := d.asns[asn]
+		if !ok {
+			d.asns[asn] = &flapState{connected: status.Connected}
+			continue
+		}
+		if status.Connected != state.connected {
+			state.connected = status.Connected
+			state.changes = append(state.changes, now)
+		}
+		expired := 0
+		for expired < len(state.changes) && now.Sub(state.changes[expired]) > d.window {
+			expired++
+		}
+		state.changes = state.changes[expired:]
+
+		switch {
+		case !state.flapping && len(state.changes) > d.threshold:
+			state.flapping = true
+		case state.flapping && len(state.changes) <= d.threshold/2:
+			state.flapping = false
+		}
+		status.Flaps, status.Flapping = len(state.changes), state.flapping
+	}
+}
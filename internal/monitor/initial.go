@@ -0,0 +1,217 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Per-step timeouts of the initial check; a step that times out keeps running
+// in the background and its data shows up in a later cycle
+const (
+	initialTrafficTimeout  = 30 * time.Second
+	initialDNSTimeout      = 20 * time.Second
+	initialBGPTimeout      = 10 * time.Second
+	initialAtlasTimeout    = 30 * time.Second
+	initialRIPEstatTimeout = time.Minute
+	initialRPKITimeout     = time.Minute
+	initialNamesTimeout    = 30 * time.Second
+	initialTelegramTimeout = 15 * time.Second
+	bgpPollInterval        = 200 * time.Millisecond
+)
+
+// ReadinessStep is the outcome of one subsystem's initial check
+type ReadinessStep struct {
+	Name     string
+	Ready    bool
+	Detail   string // Summary of the data, or why the step is not ready
+	Duration time.Duration
+}
+
+// initialStep is a subsystem check run by PerformInitialCheck
+type initialStep struct {
+	name    string
+	timeout time.Duration
+	check   func(ctx context.Context) (string, error)
+}
+
+// PerformInitialCheck runs the Cloudflare, DNS, BGP and (if configured) RIPE
+// Atlas, RIPEstat, RPKI, ASN name and Telegram initial checks in parallel, each bounded by its
+// own timeout, logs their progress and then builds the first result so it is
+// available before the first status display
+func (m *Monitor) PerformInitialCheck(ctx context.Context) {
+	steps := []initialStep{
+		{"Cloudflare traffic", initialTrafficTimeout, m.initialTraffic},
+		{"DNS", initialDNSTimeout, m.initialDNS},
+		{"BGP", initialBGPTimeout, m.initialBGP},
+	}
+	if m.atlas != nil {
+		steps = append(steps, initialStep{"RIPE Atlas", initialAtlasTimeout, m.initialAtlas})
+	}
+	if m.ripestat != nil {
+		steps = append(steps, initialStep{"RIPEstat", initialRIPEstatTimeout, m.initialRIPEstat})
+	}
+	if m.rpki != nil {
+		steps = append(steps, initialStep{"RPKI", initialRPKITimeout, m.initialRPKI})
+	}
+	if m.names != nil {
+		steps = append(steps, initialStep{"ASN names", initialNamesTimeout, m.resolveASNNames})
+	}
+	if m.telegram != nil {
+		steps = append(steps, initialStep{"Telegram", initialTelegramTimeout, m.initialTelegram})
+	}
+	log.Printf("🔄 Running %d initial checks in parallel...", len(steps))
+
+	start := time.Now()
+	readiness := make([]ReadinessStep, len(steps))
+	var finished atomic.Int32
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func(i int, step initialStep) {
+			defer wg.Done()
+			stepStart := time.Now()
+			detail, err := runWithTimeout(ctx, step.timeout, step.check)
+			r := ReadinessStep{Name: step.name, Ready: err == nil, Detail: detail, Duration: time.Since(stepStart)}
+			n := finished.Add(1)
+			if err != nil {
+				r.Detail = err.Error()
+				log.Printf("⚠️  [%d/%d] %s not ready after %s: %v", n, len(steps), step.name, r.Duration.Round(time.Millisecond), err)
+			} else {
+				log.Printf("✅ [%d/%d] %s ready in %s (%s)", n, len(steps), step.name, r.Duration.Round(time.Millisecond), detail)
+			}
+			readiness[i] = r
+		}(i, step)
+	}
+	wg.Wait()
+	log.Printf("🏁 Initial checks finished in %s", time.Since(start).Round(time.Millisecond))
+
+	m.readinessMu.Lock()
+	m.readiness = readiness
+	m.readinessMu.Unlock()
+
+	m.updateResults(ctx)
+	m.recordHistory()
+	m.lastCycle = m.LatestResults()
+}
+
+// Readiness returns the outcome of the initial checks (nil before they finished)
+func (m *Monitor) Readiness() []ReadinessStep {
+	m.readinessMu.Lock()
+	defer m.readinessMu.Unlock()
+	return append([]ReadinessStep(nil), m.readiness...)
+}
+
+// runWithTimeout returns the check's outcome, or a timeout error once the
+// step's time is up even if the check ignores its context
+func runWithTimeout(ctx context.Context, timeout time.Duration, check func(ctx context.Context) (string, error)) (string, error) {
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		detail string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		detail, err := check(stepCtx)
+		done <- outcome{detail, err}
+	}()
+	select {
+	case o := <-done:
+		return o.detail, o.err
+	case <-stepCtx.Done():
+		return "", fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+func (m *Monitor) initialTraffic(ctx context.Context) (string, error) {
+	data, err := m.trafficMonitor.FetchFromCloudflare(ctx)
+	if err != nil {
+		return "", err
+	}
+	if data == nil {
+		return "", fmt.Errorf("no traffic data")
+	}
+	return fmt.Sprintf("level %.1f%%, %s", data.CurrentLevel, data.Status), nil
+}
+
+func (m *Monitor) initialDNS(ctx context.Context) (string, error) {
+	statuses := m.dnsMonitor.CheckAll(ctx)
+	alive := 0
+	for _, status := range statuses {
+		if status.Alive {
+			alive++
+		}
+	}
+	if len(statuses) > 0 && alive == 0 {
+		return "", fmt.Errorf("none of %d servers answered", len(statuses))
+	}
+	return fmt.Sprintf("%d/%d servers alive", alive, len(statuses)), nil
+}
+
+// initialBGP waits for the first BGP update so the feed is known to be live
+func (m *Monitor) initialBGP(ctx context.Context) (string, error) {
+	ticker := time.NewTicker(bgpPollInterval)
+	defer ticker.Stop()
+	for {
+		if seen, total := m.bgpClient.SeenCount(); seen > 0 || total == 0 {
+			return fmt.Sprintf("updates for %d/%d ASNs", seen, total), nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("no BGP update yet")
+		case <-ticker.C:
+		}
+	}
+}
+
+// initialAtlas fetches the first RIPE Atlas results of the anchors and probes in the country
+func (m *Monitor) initialAtlas(ctx context.Context) (string, error) {
+	if err := m.atlas.Fetch(ctx); err != nil {
+		return "", err
+	}
+	anchors, _ := m.atlas.Anchors()
+	reachable := 0
+	for _, anchor := range anchors {
+		if anchor.Reachable {
+			reachable++
+		}
+	}
+	summary := fmt.Sprintf("%d/%d anchors reachable", reachable, len(anchors))
+	if probes, _ := m.atlas.Probes(time.Now()); probes != nil {
+		summary += fmt.Sprintf(", %d probes connected", probes.Connected)
+	}
+	return summary, nil
+}
+
+// initialRIPEstat fetches the first routing status of the ASNs from RIPEstat
+func (m *Monitor) initialRIPEstat(ctx context.Context) (string, error) {
+	if err := m.fetchRIPEstat(ctx); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("routing status of %d ASNs", len(m.bgpClient.GetASNStatuses())), nil
+}
+
+// initialRPKI fetches the ROAs a first time
+func (m *Monitor) initialRPKI(ctx context.Context) (string, error) {
+	if err := m.rpki.Fetch(ctx); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d ROAs", m.rpki.Count()), nil
+}
+
+// initialTelegram connects to the Telegram endpoints a first time
+func (m *Monitor) initialTelegram(ctx context.Context) (string, error) {
+	endpoints := m.telegram.Check(ctx)
+	reachable := 0
+	for _, endpoint := range endpoints {
+		if endpoint.Reachable {
+			reachable++
+		}
+	}
+	return fmt.Sprintf("%d/%d endpoints reachable", reachable, len(endpoints)), nil
+}
@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// IODAAlert is an outage alert of the IODA API for a country
+type IODAAlert struct {
+	Time       time.Time
+	Datasource string // "bgp", "ping-slash24", "merit-nt", ...
+	Level      string // "warning" or "critical"
+	Value      float64
+	Baseline   float64 // Value IODA expected from history
+}
+
+// FetchIODAAlerts fetches IODA's non-normal outage alerts for a country, oldest first
+func FetchIODAAlerts(ctx context.Context, client *http.Client, country string, from, to time.Time) ([]IODAAlert, error) {
+	url := fmt.Sprintf("%s/outages/alerts?entityType=country&entityCode=%s&from=%d&until=%d",
+		iodaAPI, country, from.Unix(), to.Unix())
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IODA API status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Data []struct {
+			Datasource   string  `json:"datasource"`
+			Time         int64   `json:"time"`
+			Level        string  `json:"level"`
+			Value        float64 `json:"value"`
+			HistoryValue float64 `json:"historyValue"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode IODA response: %w", err)
+	}
+
+	var alerts []IODAAlert
+	for _, a := range payload.Data {
+		if a.Level == "normal" {
+			continue
+		}
+		alerts = append(alerts, IODAAlert{
+			Time:       time.Unix(a.Time, 0),
+			Datasource: a.Datasource,
+			Level:      a.Level,
+			Value:      a.Value,
+			Baseline:   a.HistoryValue,
+		})
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Time.Before(alerts[j].Time) })
+	return alerts, nil
+}
@@ -0,0 +1,32 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/i18n"
+)
+
+// chartLocale formats the numbers drawn on the charts; the chart font has no
+// Persian digits, so they stay Latin in every language
+var chartLocale = i18n.For(i18n.English)
+
+// SetChartLocale sets the language of the numbers on the charts (call before
+// the monitors start)
+func SetChartLocale(lang string) {
+	chartLocale = i18n.For(lang).Latin()
+}
+
+// formatDurationIn is formatDuration in the language of a locale
+func formatDurationIn(d time.Duration, locale i18n.Locale) string {
+	lang := locale.Lang()
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf(i18n.T(lang, "%s secs"), locale.Int(int(d.Seconds())))
+	case d < time.Hour:
+		return fmt.Sprintf(i18n.T(lang, "%s mins"), locale.Int(int(d.Minutes())))
+	case d < 24*time.Hour:
+		return fmt.Sprintf(i18n.T(lang, "%s hours"), locale.Int(int(d.Hours())))
+	}
+	return fmt.Sprintf(i18n.T(lang, "%s days"), locale.Int(int(d.Hours()/24)))
+}
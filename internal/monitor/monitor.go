@@ -1,133 +1,538 @@
 package monitor
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/netblocks/netblocks/internal/capture"
+	"github.com/netblocks/netblocks/internal/clock"
 	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/correlation"
+	"github.com/netblocks/netblocks/internal/evidence"
+	"github.com/netblocks/netblocks/internal/history"
 	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/rules"
+	"github.com/netblocks/netblocks/internal/targetlist"
+	"github.com/netblocks/netblocks/pkg/checker"
 )
 
 // Monitor coordinates BGP and DNS monitoring
 type Monitor struct {
-	bgpClient      *RISLiveClient
+	bgpClient      BGPSource
 	dnsMonitor     *DNSMonitor
 	trafficMonitor *TrafficMonitor
 	config         *config.Config
-	results        *models.MonitoringResult
+	results        *models.MonitoringResult // Last assembled result (see LatestResults)
+	resultsMu      sync.RWMutex             // Guards results, read by the server and bot while the cycle writes it
+	updateMu       sync.Mutex               // Serializes updateResults, run by the cycle and GetResults
+	history        *history.Store
+	evidence       *evidence.Log            // Signed measurement log (nil if signing is disabled)
+	vantage        *models.Vantage          // Perspective attached to every measurement of this probe
+	lastCycle      *models.MonitoringResult // Result of the previous periodic cycle (for change detection)
+	onEvents       func([]models.Event)     // Called with the changes detected each cycle
+	rules          *rules.Engine            // Operator-defined alert rules evaluated each cycle
+	dnsAnomalies   *DNSAnomalyDetector      // Change-point detection on the alive DNS ratio
+	throttling     *ThrottlingDetector      // DNS latency baselines per province and provider
+	flaps          *FlapDetector            // Connectivity changes per ASN, for flapping
+	slos           *SLOTracker              // Provider SLOs evaluated on the history (nil if no slo is set)
+	correlator     *correlation.Engine      // Merges signals of several sources (nil if correlation is disabled)
+	iodaSince      time.Time                // Newest IODA alert already passed to the correlator
+	narrator       *Narrator                // Writes the narrative of critical events
+	bundled        map[string]time.Time     // Last evidence bundle per event kind and target
+	cycles         cycleStats               // Summaries of completed periodic cycles
+	readinessMu    sync.Mutex
+	readiness      []ReadinessStep            // Outcome of the initial checks
+	targets        *targetLists               // Remote ASN and DNS server lists (nil if none is configured)
+	checkers       []checker.Checker          // Check hooks and plugins run each cycle
+	clock          clock.Clock                // Time source of cycles, staleness checks and baselines
+	reference      map[string]bool            // External reference ASNs, reported apart from the country's
+	schedule       schedule                   // How often each data source is fetched
+	work           *workTracker               // Fetches and cycles in progress, finished on shutdown (see Drain)
+	fetchers       sync.Once                  // Starts the fetch loops once, even if a supervisor restarts Start
+	provinceShapes *ProvinceShapes            // Province polygons of the map (nil: circles at the capitals)
+	atlas          *AtlasMonitor              // RIPE Atlas anchor reachability (nil if ripe_atlas is not set)
+	ripestat       *RIPEstatMonitor           // RIPEstat routing status, the fallback of RIS Live (nil if ripestat is not set)
+	rpki           *RPKIValidator             // RPKI origin validation of the announcements (nil if rpki is not set)
+	names          *targetlist.NameResolver   // ASN name lookup on RIPEstat or PeeringDB (nil for the static source)
+	telegram       *TelegramChecker           // Telegram endpoint reachability (nil if telegram_check is not set)
+	telegramAPI    func() *models.TelegramAPI // The bot's Bot API calls (nil without a bot, see SetTelegramAPI)
+	campaigns      *campaigns                 // Measurement campaign profiles and the running campaign
+	adaptive       *adaptiveIntervals         // Shorter intervals during incidents (nil if adaptive_intervals is not set)
+	retune         *retune                    // Wakes the fetch loops when their intervals change
+}
+
+// Configure applies the settings shared by every monitor of the process:
+// the Radar call budget, the chart render bounds and the chart locale. Call
+// it once with the primary config before any monitor exists; the monitors of
+// further countries share them
+func Configure(cfg *config.Config) {
+	SetRadarBudget(cfg.CloudflareBudget)
+	SetChartRendering(cfg.ChartRendering)
+	SetChartLocale(cfg.Language)
 }
 
 // NewMonitor creates a new monitor instance
 func NewMonitor(cfg *config.Config) (*Monitor, error) {
-	// Initialize RIS Live client
-	bgpClient, err := NewRISLiveClient(cfg.RISLiveURL)
+	// Invalid alert rules are a configuration error, reported before connecting anywhere
+	ruleEngine, err := rules.NewEngine(cfg.Rules, cfg.Provinces)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alert rules: %w", err)
+	}
+	var correlator *correlation.Engine
+	if cfg.Correlation != nil {
+		if correlator, err = correlation.New(cfg.Correlation); err != nil {
+			return nil, fmt.Errorf("invalid correlation config: %w", err)
+		}
+	}
+	narrator, err := NewNarrator(cfg.NarrativeTemplate, cfg.Timezone)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create RIS Live client: %w", err)
+		return nil, fmt.Errorf("invalid narrative template: %w", err)
 	}
 
-	// Subscribe to all Iranian ASNs
-	for _, asn := range cfg.IranASNs {
+	checkers, err := loadCheckers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	fetchSchedule, err := newSchedule(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remote target lists replace the configured ASNs and DNS servers once fetched
+	targets, err := newTargetLists(cfg)
+	if err != nil {
+		return nil, err
+	}
+	asns, dnsServers := cfg.IranASNs, cfg.DNSServers
+	if targets != nil {
+		fetchCtx, cancel := context.WithTimeout(context.Background(), targetListTimeout)
+		if targets.discovery != nil {
+			if fetched := targets.startupASNs(fetchCtx); fetched != nil {
+				asns = fetched
+			}
+		} else if fetched := targets.fetchASNs(fetchCtx); fetched != nil {
+			asns = fetched
+			log.Printf("🎯 Using %d ASNs from %s", len(asns), cfg.ASNListURL)
+		}
+		if fetched := targets.fetchDNS(fetchCtx); fetched != nil {
+			dnsServers = fetched
+			log.Printf("🎯 Using %d DNS servers from %s", len(dnsServers), cfg.DNSListURL)
+		}
+		cancel()
+	}
+
+	// Initialize the BGP backend (RIS Live unless bgp_backend says otherwise)
+	bgpClient, err := NewBGPSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Subscribe to all ASNs of the monitored country and the external reference
+	reference := make(map[string]bool, len(cfg.ReferenceASNs))
+	for _, asn := range cfg.ReferenceASNs {
+		reference[asn] = true
+	}
+	for _, asn := range append(append([]string(nil), asns...), cfg.ReferenceASNs...) {
 		if err := bgpClient.SubscribeToASN(asn); err != nil {
 			log.Printf("Warning: Failed to subscribe to ASN %s: %v", asn, err)
 		}
 	}
+	// Prefixes catch outages of part of an ASN's address space
+	for _, prefix := range cfg.BGPPrefixes {
+		if err := bgpClient.SubscribeToPrefix(prefix); err != nil {
+			log.Printf("Warning: Failed to subscribe to prefix %s: %v", prefix, err)
+		}
+	}
 
 	bgpClient.Start()
 
 	// Initialize DNS monitor with 8 second timeout for better reliability
-	dnsMonitor := NewDNSMonitor(cfg.DNSServers, 8*time.Second)
+	// Private addresses are only probed from inside the country
+	dnsMonitor := NewDNSMonitor(reachableDNSServers(cfg, dnsServers), 8*time.Second)
+	dnsMonitor.SetCacheBust(cfg.DNSCacheBust)
+	dnsMonitor.SetFlapDamping(cfg.FlapDampingSettings())
+	if cfg.DNSCapture != nil {
+		writer, err := capture.NewWriter(*cfg.DNSCapture)
+		if err != nil {
+			return nil, err
+		}
+		dnsMonitor.SetCapture(writer)
+		log.Printf("🧪 Capturing raw DNS responses of failed and anomalous checks in %s", cfg.DNSCapture.Dir)
+	}
 
 	// Initialize Traffic monitor with Cloudflare credentials
 	// Supports both API Token (preferred) and API Key (legacy)
 	trafficMonitor := NewTrafficMonitor(cfg.CloudflareToken, cfg.CloudflareEmail, cfg.CloudflareKey)
+	trafficMonitor.SetTokens(cfg.CloudflareTokenList())
+	trafficMonitor.SetCountry(cfg.Country, cfg.CountryISO3, cfg.Thresholds())
+	trafficMonitor.SetASNNames(cfg.GetASNName)
+	// Scheduled fetches keep the caches fresh; reads only fetch if those fail
+	trafficMonitor.SetCacheDurations(2*fetchSchedule.traffic, 2*fetchSchedule.asnTraffic)
+	trafficMonitor.SetASNLimit(cfg.ASNTrafficLimits().Fetch)
+	trafficMonitor.SetSeries(cfg.TrafficSeriesSettings())
 
-	return &Monitor{
+	// Open availability history (used for the uptime heatmap)
+	// A broken history file should not prevent monitoring from starting
+	store, err := OpenHistory(cfg)
+	if err != nil {
+		log.Printf("⚠️  Failed to open history (history disabled): %v", err)
+		store = nil
+	}
+	// Names looked up before are used until the lookup runs again
+	names := targetlist.NewNameResolver(cfg.ASNNameLookup, cfg.Country)
+	if names != nil {
+		if count, err := names.Load(); err != nil {
+			log.Printf("⚠️  Failed to load ASN name cache: %v", err)
+		} else if count > 0 {
+			log.Printf("✅ Loaded %d cached ASN names", count)
+		}
+	}
+
+	if store == nil && len(cfg.SLOs) > 0 {
+		log.Printf("⚠️  slos are evaluated on the history, which is disabled - SLO tracking disabled")
+	}
+
+	// Open the signed evidence log if a signing key is configured
+	var evidenceLog *evidence.Log
+	if cfg.SigningKeyPath != "" {
+		evidenceLog, err = evidence.Open(cfg.EvidencePath, cfg.SigningKeyPath)
+		if err != nil {
+			log.Printf("⚠️  Failed to open evidence log %s (signing disabled): %v", cfg.EvidencePath, err)
+			evidenceLog = nil
+		} else {
+			log.Printf("🔏 Signing measurements to %s (public key: %s)", cfg.EvidencePath, evidenceLog.PublicKey())
+		}
+	}
+
+	// Province polygons of the map; without them provinces are drawn as circles
+	var provinceShapes *ProvinceShapes
+	if cfg.MapBoundaries != "" && len(cfg.Provinces) > 0 {
+		provinceShapes, err = LoadProvinceShapes(cfg.MapBoundaries, cfg.Provinces)
+		if err != nil {
+			log.Printf("⚠️  Failed to load map_boundaries (drawing provinces as circles): %v", err)
+			provinceShapes = nil
+		}
+	}
+
+	m := &Monitor{
 		bgpClient:      bgpClient,
+		work:           newWorkTracker(),
 		dnsMonitor:     dnsMonitor,
 		trafficMonitor: trafficMonitor,
 		config:         cfg,
-		results: &models.MonitoringResult{
-			Timestamp:   time.Now(),
-			ASNStatuses: make(map[string]*models.ASNStatus),
-			DNSStatuses: make(map[string]*models.DNSStatus),
-		},
-	}, nil
-}
-
-// PerformInitialCheck performs an initial synchronous check of all monitors
-// This ensures results are available before the first status display
-// IMPORTANT: Fetches Cloudflare data FIRST, then DNS, then BGP
-func (m *Monitor) PerformInitialCheck(ctx context.Context) {
-	// Fetch Cloudflare traffic data FIRST (most important - used for diagram)
-	log.Println("📡 Fetching Cloudflare Radar data for Iran...")
-	trafficData, err := m.trafficMonitor.FetchFromCloudflare(ctx)
-	if err != nil {
-		log.Printf("⚠️  Cloudflare fetch error (will use defaults): %v", err)
-	} else if trafficData != nil {
-		log.Printf("✅ Cloudflare data fetched successfully - Current Level: %.1f%%, Status: %s %s", 
-			trafficData.CurrentLevel, trafficData.StatusEmoji, trafficData.Status)
-	} else {
-		log.Println("⚠️  Cloudflare data is nil (will use defaults)")
-	}
-	
-	// Perform initial DNS check synchronously
-	log.Println("🔍 Checking DNS servers...")
-	_ = m.dnsMonitor.CheckAll(ctx)
-	
-	// Ensure BGP client has started and is ready
-	// (BGP statuses are event-driven and will update as messages arrive)
-	// Give a brief moment for WebSocket connection to stabilize
-	time.Sleep(1 * time.Second)
-	
-	// Update results with initial data (Cloudflare data should be ready now)
-	m.updateResults(ctx)
+		history:        store,
+		evidence:       evidenceLog,
+		vantage:        probeVantage(cfg),
+		rules:          ruleEngine,
+		dnsAnomalies:   NewDNSAnomalyDetector(),
+		throttling:     NewThrottlingDetector(),
+		flaps:          NewFlapDetector(cfg.ASNFlappingSettings()),
+		slos:           NewSLOTracker(cfg),
+		correlator:     correlator,
+		narrator:       narrator,
+		bundled:        make(map[string]time.Time),
+		targets:        targets,
+		checkers:       checkers,
+		clock:          clock.Real,
+		reference:      reference,
+		schedule:       fetchSchedule,
+		provinceShapes: provinceShapes,
+		atlas:          NewAtlasMonitor(cfg),
+		ripestat:       NewRIPEstatMonitor(cfg),
+		rpki:           NewRPKIValidator(cfg),
+		names:          names,
+		telegram:       NewTelegramChecker(cfg),
+		campaigns:      newCampaigns(cfg, asns, reachableDNSServers(cfg, dnsServers)),
+		adaptive:       newAdaptiveIntervals(cfg.AdaptiveIntervals),
+		retune:         newRetune(),
+	}
+	m.results = &models.MonitoringResult{
+		Timestamp:   m.clock.Now(),
+		ASNStatuses: make(map[string]*models.ASNStatus),
+		DNSStatuses: make(map[string]*models.DNSStatus),
+	}
+	m.applyStaleAfter()
+	return m, nil
+}
+
+// OpenHistory opens the configured history store: PostgreSQL if history_dsn is set,
+// otherwise the JSON file (nil without error if history is disabled)
+func OpenHistory(cfg *config.Config) (*history.Store, error) {
+	retention := time.Duration(cfg.HistoryRetentionDays) * 24 * time.Hour
+	if cfg.HistoryDSN != "" {
+		store, err := history.OpenPostgres(cfg.HistoryDSN, retention)
+		if err != nil {
+			return nil, fmt.Errorf("postgres: %w", err)
+		}
+		log.Println("✅ History stored in PostgreSQL")
+		return store, nil
+	}
+	if cfg.HistoryPath == "" {
+		return nil, nil
+	}
+	store, err := history.Open(cfg.HistoryPath, retention)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cfg.HistoryPath, err)
+	}
+	return store, nil
 }
 
 // Start starts monitoring
 func (m *Monitor) Start(ctx context.Context) {
-	// Start DNS periodic checks
-	go m.dnsMonitor.StartPeriodicCheck(ctx, m.config.Interval)
+	m.fetchers.Do(func() {
+		// Fetch each data source at its own cadence; the initial fetches were
+		// done in PerformInitialCheck
+		// Incidents and campaigns fetch more often while they last
+		go m.runEvery(ctx, "DNS check", m.interval(m.schedule.dns, m.schedule.minCycle, func(p *campaignProfile) time.Duration { return p.interval }), func(ctx context.Context) {
+			log.Println("Performing periodic DNS check...")
+			m.dnsMonitor.CheckAll(ctx)
+		})
+		go m.runEvery(ctx, "traffic fetch", m.interval(m.schedule.traffic, m.schedule.minTraffic, func(p *campaignProfile) time.Duration { return p.traffic }), func(ctx context.Context) {
+			log.Println("📡 Periodic Cloudflare Radar data fetch...")
+			_, _ = m.trafficMonitor.FetchFromCloudflare(ctx)
+		})
+		go m.runEvery(ctx, "ASN traffic fetch", m.interval(m.schedule.asnTraffic, m.schedule.minASNTraffic, func(p *campaignProfile) time.Duration { return p.asnTraffic }), func(ctx context.Context) {
+			log.Println("📡 Periodic Cloudflare Radar ASN traffic fetch...")
+			_, _ = m.trafficMonitor.FetchASNTrafficFromCloudflare(ctx)
+		})
+
+		if m.atlas != nil {
+			// RIPE Atlas results only come faster during campaigns
+			atlasBase := atlasInterval(m.config.RIPEAtlas)
+			atlasEvery := m.interval(atlasBase, atlasBase, func(p *campaignProfile) time.Duration {
+				if p.atlas {
+					return p.interval
+				}
+				return 0
+			})
+			go m.runEvery(ctx, "RIPE Atlas fetch", atlasEvery, func(ctx context.Context) {
+				log.Println("🛰  Periodic RIPE Atlas fetch...")
+				_ = m.atlas.Fetch(ctx)
+			})
+		}
+		if m.ripestat != nil {
+			go m.runEvery(ctx, "RIPEstat fetch", func() time.Duration { return m.ripestat.interval }, func(ctx context.Context) {
+				log.Println("📡 Periodic RIPEstat routing status fetch...")
+				_ = m.fetchRIPEstat(ctx)
+			})
+		}
+		if m.rpki != nil {
+			go m.runEvery(ctx, "RPKI fetch", func() time.Duration { return m.rpki.interval }, func(ctx context.Context) {
+				log.Println("🔏 Periodic RPKI ROA fetch...")
+				_ = m.rpki.Fetch(ctx)
+			})
+		}
+		if m.names != nil {
+			go m.runEvery(ctx, "ASN name lookup", func() time.Duration { return asnNamesInterval }, func(ctx context.Context) {
+				_, _ = m.resolveASNNames(ctx)
+			})
+		}
+		if m.telegram != nil {
+			go m.runEvery(ctx, "Telegram check", func() time.Duration { return m.config.Interval }, func(ctx context.Context) {
+				m.telegram.Check(ctx)
+			})
+		}
 
-	// Start traffic monitoring in background
-	go m.trafficMonitor.Start(ctx)
+		// Follow changes of the remote target lists
+		if m.targets != nil {
+			go m.refreshTargets(ctx)
+		}
+	})
 
 	// Start periodic BGP connectivity checks
-	ticker := time.NewTicker(m.config.Interval)
-	defer ticker.Stop()
+	m.runEvery(ctx, "monitor cycle", m.interval(m.config.Interval, m.schedule.minCycle, func(p *campaignProfile) time.Duration { return p.interval }), m.runCycle)
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			m.updateResults(ctx)
+// SetTelegramAPI sets the function reporting how the bot's Bot API calls went,
+// published with the results as a measurement of Telegram from this host
+func (m *Monitor) SetTelegramAPI(provider func() *models.TelegramAPI) {
+	m.telegramAPI = provider
+}
+
+// SetClock replaces the system clock of the cycles and the BGP staleness
+// checks, e.g. with a fake one in tests (call before Start)
+func (m *Monitor) SetClock(clk clock.Clock) {
+	m.clock = clk
+	m.results.Timestamp = clk.Now() // Empty result until the first cycle
+	m.bgpClient.SetClock(clk)
+}
+
+// SetEventHandler sets the function called with the changes detected each cycle
+func (m *Monitor) SetEventHandler(handler func([]models.Event)) {
+	m.onEvents = handler
+}
+
+// detectEvents compares this cycle with the previous one, looks for drops of the
+// alive DNS ratio and for throttling, correlates the signals and evaluates the
+// alert rules, then reports and returns the resulting events
+func (m *Monitor) detectEvents(ctx context.Context) []models.Event {
+	current, previous := m.LatestResults(), m.lastCycle
+	events := m.expireCampaign()
+	events = append(events, DetectChanges(previous, current)...)
+	events = append(events, m.dnsAnomalies.Observe(current)...)
+	events = append(events, m.throttling.Observe(current)...)
+	events = append(events, m.slos.Observe(current.SLOs, m.clock.Now())...)
+	if m.correlator != nil {
+		events = m.correlator.Correlate(m.clock.Now(), events, m.iodaSignals(ctx))
+	}
+	events = append(events, m.rules.Evaluate(current)...)
+	events = append(events, m.autoCampaign(events)...)
+	m.adaptIntervals(events)
+	m.lastCycle = current
+
+	if len(events) == 0 {
+		return nil
+	}
+	// Critical events carry a plain-language account of the whole situation
+	if narrative, err := m.narrator.Narrate(previous, current, events); err != nil {
+		log.Printf("⚠️  Failed to write incident narrative: %v", err)
+	} else if narrative != "" {
+		for i := range events {
+			if events[i].Severity == models.SeverityCritical && !events[i].Resolved {
+				events[i].Narrative = narrative
+			}
+		}
+	}
+	m.bundleIncident(previous, current, events)
+	log.Printf("🔔 Detected %d change(s) since last cycle", len(events))
+	if m.evidence != nil {
+		for _, event := range events {
+			if err := m.evidence.Append("event", event.Timestamp, event); err != nil {
+				log.Printf("⚠️  Failed to sign event: %v", err)
+			}
+		}
+	}
+	if m.onEvents != nil {
+		m.onEvents(events)
+	}
+	return events
+}
+
+// iodaSignals returns the IODA alerts for Iran raised since the last cycle as
+// correlation signals (none unless enabled in the correlation config)
+func (m *Monitor) iodaSignals(ctx context.Context) []correlation.Signal {
+	if !m.config.Correlation.IODA {
+		return nil
+	}
+	now := m.clock.Now()
+	from := now.Add(-m.correlator.Window())
+	if m.iodaSince.After(from) {
+		from = m.iodaSince
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	alerts, err := FetchIODAAlerts(fetchCtx, &http.Client{Timeout: 30 * time.Second}, m.config.Country, from, now)
+	if err != nil {
+		log.Printf("⚠️  IODA alerts unavailable: %v", err)
+		return nil
+	}
+
+	var signals []correlation.Signal
+	for _, alert := range alerts {
+		if !alert.Time.After(m.iodaSince) {
+			continue
+		}
+		m.iodaSince = alert.Time
+		severity := models.SeverityWarning
+		if alert.Level == "critical" {
+			severity = models.SeverityCritical
+		}
+		detail := fmt.Sprintf("%s alert (%s)", alert.Datasource, alert.Level)
+		if alert.Baseline > 0 {
+			detail = fmt.Sprintf("%s at %.0f%% of normal (%s)", alert.Datasource, alert.Value/alert.Baseline*100, alert.Level)
 		}
+		signals = append(signals, correlation.Signal{Source: correlation.SourceIODA, Time: alert.Time, Severity: severity, Detail: detail})
 	}
+	return signals
 }
 
-// GetResults returns current monitoring results
+// Stats holds operational counters of the data sources
+type Stats struct {
+	RISReconnects       int           // RIS Live WebSocket reconnects since start
+	LastCloudflareFetch time.Time     // Last successful Cloudflare Radar fetch (zero if never)
+	DNSCycleDuration    time.Duration // Duration of the last full DNS check
+	Cycles              CycleStats    // Completed monitoring cycles
+	Radar               RadarQuota    // Cloudflare Radar API usage and budget
+	RadarTokens         int           // Cloudflare credentials configured
+	RadarTokensUsable   int           // Cloudflare credentials not paused after a 429, 401 or 403
+	Renders             RenderStats   // Chart renders of this process
+}
+
+// Stats returns operational counters for health reporting
+func (m *Monitor) Stats() Stats {
+	usable, tokens := m.trafficMonitor.Credentials()
+	return Stats{
+		RISReconnects:       m.bgpClient.ReconnectCount(),
+		LastCloudflareFetch: m.trafficMonitor.LastSuccess(),
+		DNSCycleDuration:    m.dnsMonitor.LastCycleDuration(),
+		Cycles:              m.cycles.snapshot(),
+		Radar:               RadarQuotaStats(),
+		RadarTokens:         tokens,
+		RadarTokensUsable:   usable,
+		Renders:             charts.stats(),
+	}
+}
+
+// History returns the availability history store (nil if history is disabled)
+func (m *Monitor) History() *history.Store {
+	return m.history
+}
+
+// GetResults assembles a fresh result, fetching traffic and rendering the
+// charts again, and returns it; readers of the current status use
+// LatestResults instead
 func (m *Monitor) GetResults() *models.MonitoringResult {
 	m.updateResults(context.Background())
+	return m.LatestResults()
+}
+
+// LatestResults returns the result of the last cycle without side effects
+func (m *Monitor) LatestResults() *models.MonitoringResult {
+	m.resultsMu.RLock()
+	defer m.resultsMu.RUnlock()
 	return m.results
 }
 
-func (m *Monitor) updateResults(ctx context.Context) {
-	asnStatuses := m.bgpClient.CheckConnectivity()
+// updateResults assembles a fresh result and returns the data sources and charts
+// that failed (see cycle.go)
+func (m *Monitor) updateResults(ctx context.Context) []string {
+	m.updateMu.Lock()
+	defer m.updateMu.Unlock()
+	var failures []string
+	bgpStatuses := m.bgpClient.CheckConnectivity()
+	if m.ripestat != nil {
+		// RIPEstat decides while RIS Live is quiet, instead of every ASN going stale
+		m.ripestat.apply(bgpStatuses, m.bgpClient.QuietFor(), m.clock.Now())
+		if m.ripestat.Err() != nil {
+			failures = append(failures, failureRIPEstat)
+		}
+	}
+	asnStatuses, referenceStatuses := m.splitReference(bgpStatuses)
 	dnsStatuses := m.dnsMonitor.GetStatuses()
-	
+
 	// Get traffic data (will use cache if fresh; nil on error)
-	trafficData, _ := m.trafficMonitor.GetTrafficData(ctx)
-	
-	// Generate chart
+	trafficData, err := m.trafficMonitor.GetTrafficData(ctx)
+	if err != nil {
+		failures = append(failures, failureTraffic)
+	}
+
+	// Generate chart (configured period; longer periods come from history)
 	var trafficModelData *models.TrafficData
 	if trafficData != nil {
-		chartBuffer, err := GenerateTrafficChart(trafficData)
+		chartBuffer, err := m.renderTrafficChart(ctx, trafficData, m.config.ChartPeriod)
 		if err != nil {
 			chartBuffer = nil
+			failures = append(failures, failureTrafficChart)
 		}
-		
+
 		trafficModelData = &models.TrafficData{
 			CurrentLevel:  trafficData.CurrentLevel,
 			Trend24h:      trafficData.Trend24h,
@@ -140,23 +545,29 @@ func (m *Monitor) updateResults(ctx context.Context) {
 		}
 	}
 
-	// Fetch ASN-level traffic data (all ASNs from Cloudflare, not filtered by config)
+	// Fetch ASN-level traffic data (all ASNs Radar reports except the external reference)
 	var asnTrafficList []*models.ASTrafficData
-	asnTrafficRaw, err := m.trafficMonitor.FetchASNTrafficFromCloudflare(ctx)
+	asnTrafficRaw, err := m.trafficMonitor.GetASNTrafficData(ctx)
+	asnTrafficRaw = SelectASNTraffic(m.withoutReference(asnTrafficRaw), m.config.ASNTrafficLimits())
+	asnTrafficRaw = m.attachShareChanges(asnTrafficRaw, m.clock.Now())
 	if err != nil {
 		log.Printf("⚠️  Failed to fetch ASN traffic data: %v", err)
+		failures = append(failures, failureASNTraffic)
 		// Don't set asnTrafficList - will be nil/empty, chart will be skipped
 	} else if len(asnTrafficRaw) > 0 {
 		log.Printf("✅ Fetched ASN traffic data for %d ASNs, generating chart...", len(asnTrafficRaw))
 		// Generate ASN traffic chart
-		asnChartBuffer, err := GenerateASNTrafficChart(asnTrafficRaw)
+		asnChartBuffer, err := m.renderChart(ctx, "asn_traffic", asnChartBytes, func() (*bytes.Buffer, error) {
+			return GenerateASNTrafficChart(asnTrafficRaw)
+		})
 		if err != nil {
 			log.Printf("⚠️  Failed to generate ASN traffic chart: %v", err)
 			asnChartBuffer = nil
+			failures = append(failures, failureASNTrafficChart)
 		} else {
 			log.Printf("✅ ASN traffic chart generated successfully (buffer size: %d bytes)", asnChartBuffer.Len())
 		}
-		
+
 		// Add chart buffer to each ASN traffic data item (all items share the same chart)
 		for _, item := range asnTrafficRaw {
 			item.ChartBuffer = asnChartBuffer
@@ -166,13 +577,401 @@ func (m *Monitor) updateResults(ctx context.Context) {
 		log.Printf("⚠️  ASN traffic data is empty (no matching ASNs or no data available)")
 	}
 
-	m.results = &models.MonitoringResult{
-		Timestamp:    time.Now(),
-		ASNStatuses:  asnStatuses,
-		DNSStatuses:  dnsStatuses,
-		TrafficData:  trafficModelData,
+	// Generate 7-day uptime heatmap from recorded history
+	var uptimeChart *bytes.Buffer
+	var uptimeSummary string
+	if m.history != nil {
+		now := m.clock.Now()
+		rows := m.UptimeRows(now.Add(-uptimeHeatmapHours * time.Hour))
+		uptimeChart, err = m.renderChart(ctx, "uptime", heatmapBytes, func() (*bytes.Buffer, error) {
+			return GenerateUptimeHeatmap("ASN / DNS Availability (Last 7 Days)", rows, now, uptimeHeatmapHours)
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to generate uptime heatmap: %v", err)
+			uptimeChart = nil
+			failures = append(failures, failureUptimeChart)
+		}
+		uptimeSummary = DescribeUptime(rows, now, uptimeHeatmapHours)
+	}
+
+	// Daily breakdown of the DNS failure causes, once any were recorded
+	var failureChart *bytes.Buffer
+	if m.history != nil {
+		now := m.clock.Now()
+		since := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -(failureChartDays - 1))
+		if buckets := m.history.DNSFailures(since, 24*time.Hour); len(buckets) > 0 {
+			failureChart, err = m.renderChart(ctx, "failures", failureChartBytes, func() (*bytes.Buffer, error) {
+				return GenerateFailureChart("DNS Failure Causes (Last 7 Days)", buckets, now, failureChartDays)
+			})
+			if err != nil {
+				log.Printf("⚠️  Failed to generate failure causes chart: %v", err)
+				failureChart = nil
+				failures = append(failures, failureCausesChart)
+			}
+		}
+	}
+
+	// Attach 24h hourly availability to each ASN and render the sparkline strip
+	var asnSparklines *bytes.Buffer
+	if m.history != nil {
+		start := m.clock.Now().Add(-(sparklineHours - 1) * time.Hour)
+		asnHistory := m.history.ASNAvailability(start.Truncate(time.Hour))
+		for asn, status := range asnStatuses {
+			status.Uptime24h = history.HourlyRatios(asnHistory[asn], start, sparklineHours)
+		}
+		rows := majorASNRows(asnStatuses, asnTrafficList, maxSparklineRows)
+		if len(rows) > 0 {
+			asnSparklines, err = m.renderChart(ctx, "sparklines", sparklineBytes, func() (*bytes.Buffer, error) {
+				return GenerateSparklineStrip("ASN Availability (Last 24h)", rows)
+			})
+			if err != nil {
+				log.Printf("⚠️  Failed to generate ASN sparklines: %v", err)
+				asnSparklines = nil
+				failures = append(failures, failureSparklines)
+			}
+		}
+	}
+
+	// Flap counts of the ASNs, so flapping ones are alerted once
+	m.flaps.Apply(asnStatuses, m.clock.Now())
+
+	results := &models.MonitoringResult{
+		Timestamp:     m.clock.Now(),
+		ASNStatuses:   asnStatuses,
+		ReferenceASNs: referenceStatuses,
+		Prefixes:      m.bgpClient.PrefixStatuses(),
+		DNSStatuses:   dnsStatuses,
+		TrafficData:   trafficModelData,
 		ASTrafficData: asnTrafficList,
+		UptimeChart:   uptimeChart,
+		ASNSparklines: asnSparklines,
+		FailureChart:  failureChart,
+		UptimeSummary: uptimeSummary,
+	}
+
+	// Reachability of the RIPE Atlas anchors and connected probes, as of the last fetch
+	if m.atlas != nil {
+		anchors, err := m.atlas.Anchors()
+		if err != nil {
+			failures = append(failures, failureAtlas)
+		}
+		results.AtlasAnchors = anchors
+		results.AtlasProbes, _ = m.atlas.Probes(m.clock.Now())
+	}
+	// Origin validation of the announcements of the ASNs and followed prefixes
+	if m.rpki != nil {
+		m.rpki.apply(bgpStatuses, m.bgpClient.Announcements(), results.Prefixes, m.clock.Now())
+		if m.rpki.Err() != nil {
+			failures = append(failures, failureRPKI)
+		}
+	}
+	results.Campaign = m.Campaign()
+	results.SLOs = m.slos.Evaluate(m.history, results, m.clock.Now())
+
+	// Telegram as seen by the bot's own calls and from this vantage
+	if m.telegramAPI != nil || m.telegram != nil {
+		results.Telegram = &models.Telegram{}
+		if m.telegramAPI != nil {
+			results.Telegram.API = m.telegramAPI()
+		}
+		if m.telegram != nil {
+			results.Telegram.Endpoints = m.telegram.Endpoints()
+		}
+	}
+
+	// Label every measurement with this probe's perspective
+	results.Vantage = m.vantage
+	for _, status := range asnStatuses {
+		status.Vantage = m.vantage
+	}
+	for _, status := range dnsStatuses {
+		status.Vantage = m.vantage
 	}
+	if results.Telegram != nil {
+		for _, endpoint := range results.Telegram.Endpoints {
+			endpoint.Vantage = m.vantage
+		}
+	}
+	if trafficModelData != nil {
+		trafficModelData.Vantage = m.vantage
+	}
+
+	// Custom probes see the measurements of this cycle
+	var checkerFailures []string
+	results.CustomChecks, checkerFailures = m.runCheckers(ctx, results)
+	failures = append(failures, checkerFailures...)
+
+	// Composite status image for the header post (needs the assembled result)
+	results.NationalScore = CalculateNationalScore(results)
+	statusImage, err := m.renderChart(ctx, "status", statusImageBytes, func() (*bytes.Buffer, error) {
+		return GenerateStatusImage(results, results.NationalScore)
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to generate status image: %v", err)
+		statusImage = nil
+		failures = append(failures, failureStatusImage)
+	}
+	results.StatusImage = statusImage
+
+	// Map of the provinces colored by the share of their DNS servers alive
+	if len(m.config.Provinces) > 0 {
+		title := fmt.Sprintf("%s Connectivity by Province - %s UTC", m.config.CountryName, results.Timestamp.UTC().Format("2006-01-02 15:04"))
+		provinceMap, err := m.renderChart(ctx, "provinces", provinceMapBytes, func() (*bytes.Buffer, error) {
+			return GenerateProvinceMap(title, ProvinceHealths(m.config.Provinces, results), m.provinceShapes)
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to generate province map: %v", err)
+			provinceMap = nil
+			failures = append(failures, failureProvinceMap)
+		}
+		results.ProvinceMap = provinceMap
+	}
+
+	m.resultsMu.Lock()
+	m.results = results
+	m.resultsMu.Unlock()
+	return failures
+}
+
+// TrafficChart returns the Iran traffic chart for a period ("24h", "7d" or "30d")
+func (m *Monitor) TrafficChart(ctx context.Context, period string) (*bytes.Buffer, error) {
+	if _, err := ParseChartPeriod(period); err != nil {
+		return nil, err
+	}
+	trafficData, err := m.trafficMonitor.GetTrafficData(ctx)
+	if err != nil {
+		trafficData = nil
+	}
+	return m.renderTrafficChart(ctx, trafficData, period)
+}
+
+// maxExportBytes bounds the size of history exports sent as Telegram documents
+const maxExportBytes = 20 << 20
+
+// ExportHistory exports the persisted history of a period ("24h", "7d" or "30d") as JSON or CSV
+// With signing enabled the export lists the signed record hashes of the period, and the
+// returned note carries the SHA-256 of the file and its signature for the caption
+func (m *Monitor) ExportHistory(format, period string) ([]byte, string, error) {
+	if m.history == nil {
+		return nil, "", fmt.Errorf("history is disabled")
+	}
+	hours, err := ParseChartPeriod(period)
+	if err != nil {
+		return nil, "", err
+	}
+	since := m.clock.Now().Add(-time.Duration(hours) * time.Hour)
+
+	var ev *history.Evidence
+	if m.evidence != nil {
+		records, err := m.evidence.Records(since)
+		if err != nil {
+			return nil, "", err
+		}
+		ev = &history.Evidence{PublicKey: m.evidence.PublicKey()}
+		for _, r := range records {
+			ev.Records = append(ev.Records, history.RecordHash{Timestamp: r.Timestamp, Kind: r.Kind, SHA256: r.SHA256, Signature: r.Signature})
+		}
+	}
+
+	data, err := m.history.Export(format, since, ev)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxExportBytes {
+		return nil, "", fmt.Errorf("export is too large (%d MB), choose a shorter period", len(data)>>20)
+	}
+
+	note := ""
+	if m.evidence != nil {
+		digest, signature := m.evidence.Sign(data)
+		note = fmt.Sprintf("SHA-256: `%s`\nSignature: `%s`\nPublic key: `%s`", digest, signature, m.evidence.PublicKey())
+	}
+	return data, note, nil
+}
+
+// TrafficSummary returns the text alternative of the traffic chart for a period
+func (m *Monitor) TrafficSummary(ctx context.Context, period string) string {
+	hours, err := ParseChartPeriod(period)
+	if err != nil {
+		hours = 24
+	}
+	if hours > 24 && m.history != nil {
+		if points := m.history.Traffic(m.clock.Now().Add(-time.Duration(hours) * time.Hour)); len(points) >= 2 {
+			return DescribeSeries(trafficLevels(points))
+		}
+	}
+	trafficData, err := m.trafficMonitor.GetTrafficData(ctx)
+	if err != nil || trafficData == nil {
+		return "No traffic data"
+	}
+	return DescribeSeries(trafficData.Trend24h)
+}
+
+// renderTrafficChart renders the 24h chart from the latest Radar fetch, or a
+// 7d/30d chart from persisted history (falling back to 24h if history is too
+// short), in the shared chart renderer
+func (m *Monitor) renderTrafficChart(ctx context.Context, trafficData *TrafficData, period string) (*bytes.Buffer, error) {
+	return m.renderChart(ctx, "traffic/"+period, trafficChartBytes, func() (*bytes.Buffer, error) {
+		return m.generateTrafficChart(trafficData, period)
+	})
+}
+
+// generateTrafficChart renders the traffic chart of a period (see renderTrafficChart)
+func (m *Monitor) generateTrafficChart(trafficData *TrafficData, period string) (*bytes.Buffer, error) {
+	hours, err := ParseChartPeriod(period)
+	if err != nil {
+		log.Printf("⚠️  Invalid chart period %q, using 24h: %v", period, err)
+		hours = 24
+	}
+
+	if hours > 24 && m.history != nil {
+		label := strings.ToLower(strings.TrimSpace(period))
+		since := m.clock.Now().Add(-time.Duration(hours) * time.Hour)
+		chartBuffer, err := GenerateTrafficHistoryChart(m.history.Traffic(since), label, m.history.Annotations(since), m.AtlasProbes(since))
+		if err == nil {
+			return chartBuffer, nil
+		}
+		log.Printf("⚠️  %s traffic chart unavailable, falling back to 24h: %v", label, err)
+	}
+
+	dayAgo := m.clock.Now().Add(-24 * time.Hour)
+	var notes []models.Annotation
+	if m.history != nil {
+		notes = m.history.Annotations(dayAgo)
+	}
+	return GenerateTrafficChart(trafficData, notes, m.AtlasProbes(dayAgo))
+}
+
+// AtlasProbes returns the RIPE Atlas probe counts since a time (none without ripe_atlas)
+func (m *Monitor) AtlasProbes(since time.Time) []models.AtlasProbes {
+	if m.atlas == nil {
+		return nil
+	}
+	_, counts := m.atlas.Probes(since)
+	return counts
+}
+
+// uptimeHeatmapHours is the period covered by the uptime heatmap (7 days)
+const uptimeHeatmapHours = 7 * 24
+
+// sparklineHours is the period covered by per-ASN sparklines (24h)
+const sparklineHours = 24
+
+// maxSparklineRows caps the number of ASNs in the sparkline strip image
+const maxSparklineRows = 20
+
+// majorASNRows picks the ASNs shown in the sparkline strip: the largest networks
+// by traffic share first, then the remaining named ASNs alphabetically
+func majorASNRows(statuses map[string]*models.ASNStatus, traffic []*models.ASTrafficData, limit int) []SparklineRow {
+	var rows []SparklineRow
+	seen := make(map[string]bool)
+
+	add := func(asn string) {
+		status, ok := statuses[asn]
+		if !ok || seen[asn] || len(rows) >= limit {
+			return
+		}
+		seen[asn] = true
+		label := asn
+		if status.Name != "" && status.Name != "Unknown" {
+			label = fmt.Sprintf("%s - %s", asn, status.Name)
+		}
+		rows = append(rows, SparklineRow{Label: label, Values: status.Uptime24h})
+	}
+
+	for _, item := range traffic {
+		add(item.ASN)
+	}
+
+	asns := make([]string, 0, len(statuses))
+	for asn, status := range statuses {
+		if status.Name != "" && status.Name != "Unknown" {
+			asns = append(asns, asn)
+		}
+	}
+	sort.Strings(asns)
+	for _, asn := range asns {
+		add(asn)
+	}
+
+	return rows
+}
+
+// recordHistory stores the latest results in the availability history
+// Only called from the periodic cycle so on-demand GetResults calls don't skew the buckets
+// It returns the stores that failed (see cycle.go)
+func (m *Monitor) recordHistory() []string {
+	results := m.LatestResults()
+	if m.history == nil || results == nil {
+		return nil
+	}
+	var failures []string
+	if err := m.history.Record(results); err != nil {
+		log.Printf("⚠️  Failed to record history: %v", err)
+		failures = append(failures, failureHistory)
+	}
+	if m.evidence != nil {
+		if err := m.evidence.Append("result", results.Timestamp, results); err != nil {
+			log.Printf("⚠️  Failed to sign result: %v", err)
+			failures = append(failures, failureEvidence)
+		}
+	}
+	return failures
+}
+
+// UptimeRows converts stored history into heatmap rows
+// ASNs get one row each; DNS servers are aggregated per city to keep the image readable
+func (m *Monitor) UptimeRows(since time.Time) []HeatmapRow {
+	var rows []HeatmapRow
+	if m.history == nil {
+		return rows
+	}
+
+	asnHistory := m.history.ASNAvailability(since)
+	asns := make([]string, 0, len(asnHistory))
+	for asn := range asnHistory {
+		asns = append(asns, asn)
+	}
+	sort.Strings(asns)
+	for _, asn := range asns {
+		label := asn
+		if name := m.config.GetASNName(asn); name != "Unknown" {
+			label = fmt.Sprintf("%s - %s", asn, name)
+		}
+		rows = append(rows, HeatmapRow{Label: label, Buckets: asnHistory[asn]})
+	}
+
+	// Merge per-server buckets into per-city buckets
+	cityBuckets := make(map[string]map[int64]*history.Bucket)
+	for key, buckets := range m.history.DNSAvailability(since) {
+		city := config.GetDNSCity(m.history.DNSName(key))
+		if cityBuckets[city] == nil {
+			cityBuckets[city] = make(map[int64]*history.Bucket)
+		}
+		for _, b := range buckets {
+			merged, ok := cityBuckets[city][b.Hour.Unix()]
+			if !ok {
+				merged = &history.Bucket{Hour: b.Hour}
+				cityBuckets[city][b.Hour.Unix()] = merged
+			}
+			merged.Up += b.Up
+			merged.Total += b.Total
+		}
+	}
+	cities := make([]string, 0, len(cityBuckets))
+	for city := range cityBuckets {
+		cities = append(cities, city)
+	}
+	sort.Strings(cities)
+	for _, city := range cities {
+		buckets := make([]history.Bucket, 0, len(cityBuckets[city]))
+		for _, b := range cityBuckets[city] {
+			buckets = append(buckets, *b)
+		}
+		rows = append(rows, HeatmapRow{Label: "DNS - " + city, Buckets: buckets})
+	}
+
+	return rows
 }
 
 // Stop stops the monitor
@@ -180,5 +979,14 @@ func (m *Monitor) Stop() {
 	if m.bgpClient != nil {
 		m.bgpClient.Stop()
 	}
+	for _, c := range m.checkers {
+		if closer, ok := c.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	if m.history != nil {
+		if err := m.history.Close(); err != nil {
+			log.Printf("⚠️  Failed to close history store: %v", err)
+		}
+	}
 }
-
@@ -0,0 +1,424 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+// MRT record types and BGP constants read from RIB and update dumps (RFC 6396, RFC 4271, RFC 4760)
+const (
+	mrtTableDumpV2        = 13
+	tableDumpPeerIndex    = 1
+	tableDumpRIBIPv4      = 2
+	tableDumpRIBIPv6      = 4
+	mrtBGP4MP             = 16
+	mrtBGP4MPET           = 17 // BGP4MP with microsecond timestamps
+	bgp4mpMessage         = 1
+	bgp4mpMessageAS4      = 4
+	bgp4mpMessageLocal    = 6
+	bgp4mpMessageAS4Local = 7
+	mrtMaxRecord          = 1 << 20
+	bgpHeaderLen          = 19
+	bgpTypeUpdate         = 2
+	bgpAttrExtendedLength = 0x10
+	bgpAttrASPath         = 2
+	bgpAttrNextHop        = 3
+	bgpAttrMPReach        = 14
+	bgpAttrMPUnreach      = 15
+	bgpAttrAS4Path        = 17
+	bgpASSet              = 1
+	bgpSAFIUnicast        = 1
+)
+
+// readMRT reads the BGP UPDATE messages of an MRT update dump, or the routes
+// of a RIB dump as announcements by each peer, and hands each to handle in
+// the form RIS Live sends them, so every feed shares the routing state
+func readMRT(r io.Reader, collector string, handle func(*RISUpdateMessage)) error {
+	reader := bufio.NewReaderSize(r, 64<<10)
+	header := make([]byte, 12)
+	var body []byte
+	var peers []mrtPeer // Peer index table of a RIB dump
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("MRT header: %w", err)
+		}
+		timestamp := binary.BigEndian.Uint32(header[0:4])
+		recordType := binary.BigEndian.Uint16(header[4:6])
+		subtype := binary.BigEndian.Uint16(header[6:8])
+		length := binary.BigEndian.Uint32(header[8:12])
+		if length > mrtMaxRecord {
+			return fmt.Errorf("MRT record of %d bytes", length)
+		}
+		if cap(body) < int(length) {
+			body = make([]byte, length)
+		}
+		body = body[:length]
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return fmt.Errorf("MRT record: %w", err)
+		}
+
+		data := body
+		switch recordType {
+		case mrtTableDumpV2:
+			switch subtype {
+			case tableDumpPeerIndex:
+				peers = parsePeerIndex(data)
+			case tableDumpRIBIPv4, tableDumpRIBIPv6:
+				size := net.IPv4len
+				if subtype == tableDumpRIBIPv6 {
+					size = net.IPv6len
+				}
+				for _, update := range parseRIB(data, size, peers) {
+					update.Timestamp = float64(timestamp)
+					update.Host = collector
+					update.Type = "UPDATE"
+					handle(update)
+				}
+			}
+			continue
+		case mrtBGP4MP:
+		case mrtBGP4MPET:
+			if len(data) < 4 {
+				continue
+			}
+			data = data[4:] // Microseconds
+		default:
+			continue // Table dumps, state changes
+		}
+		update, ok := parseBGP4MP(data, subtype)
+		if !ok {
+			continue
+		}
+		update.Timestamp = float64(timestamp)
+		update.Host = collector
+		update.Type = "UPDATE"
+		handle(update)
+	}
+}
+
+// parseBGP4MP parses a BGP4MP message record holding an UPDATE; other
+// messages and malformed records are skipped
+func parseBGP4MP(data []byte, subtype uint16) (*RISUpdateMessage, bool) {
+	asSize := 2
+	switch subtype {
+	case bgp4mpMessage, bgp4mpMessageLocal:
+	case bgp4mpMessageAS4, bgp4mpMessageAS4Local:
+		asSize = 4
+	default:
+		return nil, false // Add-path and state change records
+	}
+	if len(data) < 2*asSize+4 {
+		return nil, false
+	}
+	peerAS := uint32(binary.BigEndian.Uint16(data))
+	if asSize == 4 {
+		peerAS = binary.BigEndian.Uint32(data)
+	}
+	data = data[2*asSize:]
+	addrLen := addressSize(binary.BigEndian.Uint16(data[2:4])) // After the interface index
+	data = data[4:]
+	if addrLen == 0 || len(data) < 2*addrLen+bgpHeaderLen {
+		return nil, false
+	}
+	peer := net.IP(data[:addrLen]).String()
+	message := data[2*addrLen:]
+	if message[18] != bgpTypeUpdate {
+		return nil, false
+	}
+	length := int(binary.BigEndian.Uint16(message[16:18]))
+	if length < bgpHeaderLen+4 || length > len(message) {
+		return nil, false
+	}
+
+	update := &RISUpdateMessage{Peer: peer, PeerASN: strconv.FormatUint(uint64(peerAS), 10)}
+	if !parseBGPUpdate(message[bgpHeaderLen:length], asSize, update) {
+		return nil, false
+	}
+	if len(update.Announcements) == 0 && len(update.Withdrawals) == 0 {
+		return nil, false // End-of-RIB marker
+	}
+	return update, true
+}
+
+// parseBGPUpdate reads the withdrawn routes, AS path and announced prefixes of
+// an UPDATE body, IPv6 ones from the multiprotocol attributes
+func parseBGPUpdate(data []byte, asSize int, update *RISUpdateMessage) bool {
+	if len(data) < 2 {
+		return false
+	}
+	withdrawnLen := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < withdrawnLen+2 {
+		return false
+	}
+	withdrawn, ok := parsePrefixes(data[:withdrawnLen], net.IPv4len)
+	if !ok {
+		return false
+	}
+	update.Withdrawals = withdrawn
+	data = data[withdrawnLen:]
+	attrsLen := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < attrsLen {
+		return false
+	}
+	attrs, nlri := data[:attrsLen], data[attrsLen:]
+
+	parsed, ok := parseAttributes(attrs, asSize, false)
+	if !ok {
+		return false
+	}
+	update.Withdrawals = append(update.Withdrawals, parsed.withdrawn...)
+	prefixes, ok := parsePrefixes(nlri, net.IPv4len)
+	if !ok {
+		return false
+	}
+	announced := append(parsed.announced, prefixes...)
+	if len(announced) > 0 {
+		update.Path = parsed.path
+		update.Announcements = []RISAnnouncement{{NextHop: parsed.nextHop, Prefixes: announced}}
+	}
+	return true
+}
+
+// bgpAttributes are the path attributes of a route read from a dump
+type bgpAttributes struct {
+	path      []interface{}
+	nextHop   string
+	announced []string // IPv6 prefixes of MP_REACH_NLRI
+	withdrawn []string // IPv6 prefixes of MP_UNREACH_NLRI
+}
+
+// parseAttributes reads the AS path, next hop and multiprotocol prefixes of
+// path attributes. RIB entries abbreviate MP_REACH_NLRI to the next hop
+func parseAttributes(attrs []byte, asSize int, rib bool) (bgpAttributes, bool) {
+	var parsed bgpAttributes
+	var path4 []interface{}
+	for len(attrs) >= 3 {
+		flags, code := attrs[0], attrs[1]
+		headerLen, valueLen := 3, int(attrs[2])
+		if flags&bgpAttrExtendedLength != 0 {
+			if len(attrs) < 4 {
+				return parsed, false
+			}
+			headerLen, valueLen = 4, int(binary.BigEndian.Uint16(attrs[2:4]))
+		}
+		if len(attrs) < headerLen+valueLen {
+			return parsed, false
+		}
+		value := attrs[headerLen : headerLen+valueLen]
+		attrs = attrs[headerLen+valueLen:]
+
+		switch code {
+		case bgpAttrASPath:
+			parsed.path = parseASPath(value, asSize)
+		case bgpAttrAS4Path:
+			path4 = parseASPath(value, 4)
+		case bgpAttrNextHop:
+			if len(value) == net.IPv4len {
+				parsed.nextHop = net.IP(value).String()
+			}
+		case bgpAttrMPReach:
+			if rib {
+				// Next hop length and next hop only
+				if len(value) > 0 && (int(value[0]) == net.IPv6len || int(value[0]) == 2*net.IPv6len) && len(value) > net.IPv6len {
+					parsed.nextHop = net.IP(value[1 : 1+net.IPv6len]).String()
+				}
+				continue
+			}
+			// AFI, SAFI, next hop length and next hop, reserved byte, NLRI
+			if len(value) < 5 || value[2] != bgpSAFIUnicast {
+				continue
+			}
+			size, hopLen := addressSize(binary.BigEndian.Uint16(value)), int(value[3])
+			if size == 0 || len(value) < 5+hopLen {
+				continue
+			}
+			if hopLen >= size {
+				parsed.nextHop = net.IP(value[4 : 4+size]).String()
+			}
+			if prefixes, ok := parsePrefixes(value[5+hopLen:], size); ok {
+				parsed.announced = append(parsed.announced, prefixes...)
+			}
+		case bgpAttrMPUnreach:
+			// AFI, SAFI, withdrawn NLRI
+			if len(value) < 3 || value[2] != bgpSAFIUnicast {
+				continue
+			}
+			size := addressSize(binary.BigEndian.Uint16(value))
+			if size == 0 {
+				continue
+			}
+			if prefixes, ok := parsePrefixes(value[3:], size); ok {
+				parsed.withdrawn = append(parsed.withdrawn, prefixes...)
+			}
+		}
+	}
+	// Sessions without 4-byte ASN support carry the real path in AS4_PATH
+	if asSize == 2 && path4 != nil {
+		parsed.path = path4
+	}
+	return parsed, true
+}
+
+// mrtPeer is a BGP peer of a collector listed in a RIB dump's peer index table
+type mrtPeer struct {
+	address string
+	asn     string
+}
+
+// parsePeerIndex reads the peer index table of a TABLE_DUMP_V2 RIB dump
+func parsePeerIndex(data []byte) []mrtPeer {
+	// Collector BGP ID, view name length and name, peer count
+	if len(data) < 6 {
+		return nil
+	}
+	viewLen := int(binary.BigEndian.Uint16(data[4:6]))
+	data = data[6:]
+	if len(data) < viewLen+2 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(data[viewLen:]))
+	data = data[viewLen+2:]
+
+	peers := make([]mrtPeer, 0, count)
+	for i := 0; i < count; i++ {
+		// Peer type (bit 0: IPv6 address, bit 1: 4-byte ASN), BGP ID, address, ASN
+		if len(data) < 1 {
+			break
+		}
+		addrLen, asSize := net.IPv4len, 2
+		if data[0]&1 != 0 {
+			addrLen = net.IPv6len
+		}
+		if data[0]&2 != 0 {
+			asSize = 4
+		}
+		if len(data) < 5+addrLen+asSize {
+			break
+		}
+		peer := mrtPeer{address: net.IP(data[5 : 5+addrLen]).String()}
+		as := data[5+addrLen:]
+		if asSize == 4 {
+			peer.asn = strconv.FormatUint(uint64(binary.BigEndian.Uint32(as)), 10)
+		} else {
+			peer.asn = strconv.FormatUint(uint64(binary.BigEndian.Uint16(as)), 10)
+		}
+		peers = append(peers, peer)
+		data = data[5+addrLen+asSize:]
+	}
+	return peers
+}
+
+// parseRIB reads a RIB entry record of a TABLE_DUMP_V2 dump: the routes of one
+// prefix, one announcement per peer that has it
+func parseRIB(data []byte, size int, peers []mrtPeer) []*RISUpdateMessage {
+	// Sequence number, prefix, entry count
+	if len(data) < 5 {
+		return nil
+	}
+	bits := int(data[4])
+	n := (bits + 7) / 8
+	if len(data) < 5+n+2 {
+		return nil
+	}
+	prefixes, ok := parsePrefixes(data[4:5+n], size)
+	if !ok || len(prefixes) != 1 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint16(data[5+n:]))
+	data = data[5+n+2:]
+
+	updates := make([]*RISUpdateMessage, 0, count)
+	for i := 0; i < count; i++ {
+		// Peer index, originated time, attribute length, attributes
+		if len(data) < 8 {
+			break
+		}
+		index := int(binary.BigEndian.Uint16(data))
+		attrsLen := int(binary.BigEndian.Uint16(data[6:8]))
+		if len(data) < 8+attrsLen {
+			break
+		}
+		attrs := data[8 : 8+attrsLen]
+		data = data[8+attrsLen:]
+		if index >= len(peers) {
+			continue
+		}
+		parsed, ok := parseAttributes(attrs, 4, true)
+		if !ok {
+			continue
+		}
+		updates = append(updates, &RISUpdateMessage{
+			Peer:          peers[index].address,
+			PeerASN:       peers[index].asn,
+			Path:          parsed.path,
+			Announcements: []RISAnnouncement{{NextHop: parsed.nextHop, Prefixes: prefixes}},
+		})
+	}
+	return updates
+}
+
+// parseASPath returns an AS path as RIS Live does: ASNs as numbers, AS_SETs
+// as nested lists
+func parseASPath(value []byte, asSize int) []interface{} {
+	path := []interface{}{}
+	for len(value) >= 2 {
+		segmentType, count := value[0], int(value[1])
+		value = value[2:]
+		if len(value) < count*asSize {
+			break
+		}
+		asns := make([]interface{}, count)
+		for i := range asns {
+			if asSize == 4 {
+				asns[i] = float64(binary.BigEndian.Uint32(value[i*4:]))
+			} else {
+				asns[i] = float64(binary.BigEndian.Uint16(value[i*2:]))
+			}
+		}
+		value = value[count*asSize:]
+		if segmentType == bgpASSet {
+			path = append(path, asns)
+		} else {
+			path = append(path, asns...)
+		}
+	}
+	return path
+}
+
+// parsePrefixes decodes NLRI prefixes (length in bits, then the significant bytes)
+func parsePrefixes(data []byte, size int) ([]string, bool) {
+	var prefixes []string
+	for len(data) > 0 {
+		bits := int(data[0])
+		n := (bits + 7) / 8
+		if bits > size*8 || len(data) < 1+n {
+			return nil, false
+		}
+		ip := make(net.IP, size)
+		copy(ip, data[1:1+n])
+		prefixes = append(prefixes, (&net.IPNet{IP: ip, Mask: net.CIDRMask(bits, size*8)}).String())
+		data = data[1+n:]
+	}
+	return prefixes, true
+}
+
+// addressSize returns the address length of an address family (0 if unknown)
+func addressSize(afi uint16) int {
+	switch afi {
+	case 1:
+		return net.IPv4len
+	case 2:
+		return net.IPv6len
+	}
+	return 0
+}
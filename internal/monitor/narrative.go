@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// trafficFallWindow is how far back a traffic fall is measured from its peak
+const trafficFallWindow = 2 * time.Hour
+
+// zoneNames replaces numeric zone abbreviations (Go prints "+0330" for Tehran)
+var zoneNames = map[string]string{"Asia/Tehran": "IRST"}
+
+// NarrativeASN is a disconnected ASN in the narrative data
+type NarrativeASN struct {
+	ASN  string
+	Name string
+}
+
+// NarrativeData is what narrative templates are executed with
+type NarrativeData struct {
+	Time          string         // Local time of the cycle, e.g. "14:32 IRST"
+	Events        []models.Event // Critical events of the cycle
+	NewlyDown     []NarrativeASN // ASNs that disconnected since the previous cycle
+	DownASNs      int            // ASNs disconnected in total
+	TotalASNs     int
+	TrafficLevel  float64 // Current traffic, % of the 24h peak (0 without traffic data)
+	TrafficStatus string
+	TrafficFall   float64 // Fall (%) from the recent peak to now; 0 if traffic did not fall
+	FallMinutes   int     // Minutes the fall took
+	DNSAlive      int
+	DNSTotal      int
+	Score         float64
+	ScoreStatus   string
+}
+
+// defaultNarrative renders e.g. "At 14:32 IRST, AS197207 (MCCI) lost BGP visibility;
+// national traffic fell 61% within 20 minutes; ..."
+const defaultNarrative = `At {{.Time}}, ` +
+	`{{- if .NewlyDown}} {{asns .NewlyDown}} lost BGP visibility ({{.DownASNs}} of {{.TotalASNs}} monitored ASNs now unreachable);` +
+	`{{- else if .DownASNs}} {{.DownASNs}} of {{.TotalASNs}} monitored ASNs were unreachable;{{end}}` +
+	`{{- if .TrafficFall}} national traffic fell {{printf "%.0f" .TrafficFall}}% within {{.FallMinutes}} minutes to {{printf "%.0f" .TrafficLevel}}% of its daily peak;` +
+	`{{- else if .TrafficStatus}} national traffic was at {{printf "%.0f" .TrafficLevel}}% of its daily peak ({{.TrafficStatus}});{{end}}` +
+	`{{- if .DNSTotal}} {{.DNSAlive}} of {{.DNSTotal}} DNS servers answered;{{end}}` +
+	` the national connectivity score stood at {{printf "%.0f" .Score}}/100 ({{.ScoreStatus}}).`
+
+// narrativeFuncs are available in narrative templates
+var narrativeFuncs = template.FuncMap{
+	// asns names up to three ASNs, e.g. "AS1 (A), AS2 (B) and 4 other ASNs"
+	"asns": func(asns []NarrativeASN) string {
+		names := make([]string, 0, 3)
+		for i, asn := range asns {
+			if i == 3 {
+				break
+			}
+			name := asn.ASN
+			if asn.Name != "" && asn.Name != "Unknown" {
+				name += " (" + asn.Name + ")"
+			}
+			names = append(names, name)
+		}
+		if rest := len(asns) - len(names); rest > 0 {
+			return fmt.Sprintf("%s and %d other ASNs", strings.Join(names, ", "), rest)
+		}
+		if len(names) > 1 {
+			return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+		}
+		return strings.Join(names, "")
+	},
+}
+
+// Narrator writes the narrative text attached to critical events
+type Narrator struct {
+	tmpl     *template.Template
+	location *time.Location
+	zone     string // Replaces the time zone abbreviation ("" keeps it)
+}
+
+// NewNarrator parses the narrative template (the default if text is empty);
+// times are given in the named IANA zone
+func NewNarrator(text, timezone string) (*Narrator, error) {
+	if text == "" {
+		text = defaultNarrative
+	}
+	tmpl, err := template.New("narrative").Funcs(narrativeFuncs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	return &Narrator{tmpl: tmpl, location: location, zone: zoneNames[timezone]}, nil
+}
+
+// Narrate describes the situation of a cycle with critical events ("" if there are none);
+// prev is the result of the previous cycle
+func (n *Narrator) Narrate(prev, result *models.MonitoringResult, events []models.Event) (string, error) {
+	data := NarrativeData{}
+	for _, event := range events {
+		if event.Severity != models.SeverityCritical || event.Resolved {
+			continue
+		}
+		data.Events = append(data.Events, event)
+	}
+	if len(data.Events) == 0 || result == nil {
+		return "", nil
+	}
+
+	now := result.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	local := now.In(n.location)
+	data.Time = local.Format("15:04 MST")
+	if n.zone != "" {
+		data.Time = local.Format("15:04") + " " + n.zone
+	}
+
+	for asn, status := range result.ASNStatuses {
+		data.TotalASNs++
+		if status.Connected {
+			continue
+		}
+		data.DownASNs++
+		if prev != nil {
+			if before, ok := prev.ASNStatuses[asn]; ok && before.Connected {
+				data.NewlyDown = append(data.NewlyDown, NarrativeASN{ASN: asn, Name: status.Name})
+			}
+		}
+	}
+	sort.Slice(data.NewlyDown, func(i, j int) bool { return data.NewlyDown[i].ASN < data.NewlyDown[j].ASN })
+	for _, status := range result.DNSStatuses {
+		data.DNSTotal++
+		if status.Alive {
+			data.DNSAlive++
+		}
+	}
+	if traffic := result.TrafficData; traffic != nil && len(traffic.Trend24h) > 0 {
+		data.TrafficLevel = traffic.CurrentLevel
+		data.TrafficStatus = traffic.Status
+		data.TrafficFall, data.FallMinutes = trafficFall(traffic)
+	}
+	data.Score = result.NationalScore
+	data.ScoreStatus, _ = ScoreStatus(result.NationalScore)
+
+	var buf bytes.Buffer
+	if err := n.tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
+
+// trafficFall returns the fall (%) from the highest point of the last
+// trafficFallWindow to the latest point, and the minutes in between
+func trafficFall(traffic *models.TrafficData) (float64, int) {
+	last := len(traffic.Trend24h) - 1
+	if len(traffic.Timestamps) != len(traffic.Trend24h) || last < 1 {
+		return 0, 0
+	}
+	peak := last
+	for i := last - 1; i >= 0 && traffic.Timestamps[last].Sub(traffic.Timestamps[i]) <= trafficFallWindow; i-- {
+		if traffic.Trend24h[i] > traffic.Trend24h[peak] {
+			peak = i
+		}
+	}
+	if peak == last || traffic.Trend24h[peak] <= 0 {
+		return 0, 0
+	}
+	fall := (traffic.Trend24h[peak] - traffic.Trend24h[last]) / traffic.Trend24h[peak] * 100
+	if fall < 10 {
+		return 0, 0
+	}
+	return fall, int(traffic.Timestamps[last].Sub(traffic.Timestamps[peak]).Minutes())
+}
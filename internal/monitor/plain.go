@@ -0,0 +1,151 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// plainListLimit is the number of names listed per sentence; longer lists
+// are cut short so a screen reader does not read out every ASN
+const plainListLimit = 5
+
+// PlainStatus renders the status for screen readers: short sentences, one
+// per line, without emoji, box drawing or Markdown
+func PlainStatus(result *models.MonitoringResult, lang string) string {
+	locale := i18n.For(lang)
+	lang = locale.Lang()
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(i18n.T(lang, format), args...))
+	}
+
+	add("NetBlocks status at %s UTC.", locale.Time(result.Timestamp.UTC(), "2006-01-02 15:04"))
+	status, _ := ScoreStatus(result.NationalScore)
+	add("National score is %s of %s, %s.", locale.Number(result.NationalScore, 0), locale.Int(100), i18n.T(lang, status))
+
+	if traffic := result.TrafficData; traffic != nil {
+		add("Internet traffic is at %s of normal, %s.", locale.Percent(traffic.CurrentLevel, 0), i18n.T(lang, traffic.Status))
+		switch trendArrow(validLevels(traffic.Trend24h)) {
+		case "↗":
+			add("Traffic is rising.")
+		case "↘":
+			add("Traffic is falling.")
+		}
+	}
+
+	var down []string
+	for _, status := range result.ASNStatuses {
+		if !status.Connected {
+			down = append(down, plainASNName(status))
+		}
+	}
+	add("%s of %s networks are connected.", locale.Int(len(result.ASNStatuses)-len(down)), locale.Int(len(result.ASNStatuses)))
+	if len(down) > 0 {
+		add("Disconnected: %s.", plainList(down, locale))
+	}
+	var storms []string
+	for _, status := range result.ASNStatuses {
+		if status.WithdrawalStorm {
+			storms = append(storms, plainASNName(status))
+		}
+	}
+	if len(storms) > 0 {
+		add("Many routes withdrawn: %s.", plainList(storms, locale))
+	}
+	var flapping []string
+	for _, status := range result.ASNStatuses {
+		if status.Flapping {
+			flapping = append(flapping, plainASNName(status))
+		}
+	}
+	if len(flapping) > 0 {
+		add("Repeatedly connecting and disconnecting: %s.", plainList(flapping, locale))
+	}
+	var invalid []string
+	for _, status := range result.ASNStatuses {
+		if status.RPKI != nil && status.RPKI.Invalid > 0 {
+			invalid = append(invalid, plainASNName(status))
+		}
+	}
+	if len(invalid) > 0 {
+		add("Announcements failing RPKI validation: %s.", plainList(invalid, locale))
+	}
+
+	var silent []string
+	for _, status := range result.DNSStatuses {
+		if !status.Alive {
+			silent = append(silent, status.Name)
+		}
+	}
+	if len(result.DNSStatuses) > 0 {
+		add("%s of %s DNS servers answer.", locale.Int(len(result.DNSStatuses)-len(silent)), locale.Int(len(result.DNSStatuses)))
+		if len(silent) > 0 {
+			add("Not answering: %s.", plainList(silent, locale))
+		}
+	}
+
+	if len(result.AtlasAnchors) > 0 {
+		reachable := 0
+		for _, anchor := range result.AtlasAnchors {
+			if anchor.Reachable {
+				reachable++
+			}
+		}
+		add("%s of %s RIPE Atlas anchors are reachable from abroad.", locale.Int(reachable), locale.Int(len(result.AtlasAnchors)))
+	}
+	if telegram := result.Telegram; telegram != nil {
+		if api := telegram.API; api != nil {
+			if api.Reachable {
+				add("Telegram API reachable from the monitoring host: yes.")
+			} else {
+				add("Telegram API reachable from the monitoring host: no.")
+			}
+		}
+		if len(telegram.Endpoints) > 0 {
+			reachable := 0
+			for _, endpoint := range telegram.Endpoints {
+				if endpoint.Reachable {
+					reachable++
+				}
+			}
+			add("%s of %s Telegram endpoint checks succeeded.", locale.Int(reachable), locale.Int(len(telegram.Endpoints)))
+		}
+	}
+	if result.Campaign != nil {
+		add("Measurement campaign %s is running.", result.Campaign.Name)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plainASNName names an ASN as "AS12880 DCI"
+func plainASNName(status *models.ASNStatus) string {
+	if status.Name == "" || status.Name == "Unknown" {
+		return status.ASN
+	}
+	return status.ASN + " " + status.Name
+}
+
+// plainList joins sorted names, cut to plainListLimit with a count of the rest
+func plainList(names []string, locale i18n.Locale) string {
+	sort.Strings(names)
+	if len(names) <= plainListLimit {
+		return strings.Join(names, ", ")
+	}
+	return strings.Join(names[:plainListLimit], ", ") + ", " +
+		fmt.Sprintf(i18n.T(locale.Lang(), "and %s more"), locale.Int(len(names)-plainListLimit))
+}
+
+// validLevels drops the missing points (-1) of a traffic series
+func validLevels(values []float64) []float64 {
+	var valid []float64
+	for _, v := range values {
+		if v >= 0 {
+			valid = append(valid, v)
+		}
+	}
+	return valid
+}
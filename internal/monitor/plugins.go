@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/pkg/checker"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultPluginTimeout = 30 * time.Second
+
+// pluginChecker runs a checker plugin in a child process over gRPC; a plugin
+// that crashed or overran its timeout is killed and started again for the
+// next check
+type pluginChecker struct {
+	cfg     config.PluginConfig
+	name    string
+	timeout time.Duration
+	mu      sync.Mutex     // Serializes the checks and restarts
+	client  *plugin.Client // Plugin process (nil while stopped)
+	remote  *checker.Client
+}
+
+// loadPlugin starts a checker plugin and creates its checker
+func loadPlugin(cfg config.PluginConfig) (checker.Checker, error) {
+	timeout := defaultPluginTimeout
+	if cfg.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(cfg.Timeout); err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("checker plugin %s: invalid timeout %q", cfg.Path, cfg.Timeout)
+		}
+	}
+	p := &pluginChecker{cfg: cfg, timeout: timeout}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// start launches the plugin process and configures its checker (caller holds mu)
+func (p *pluginChecker) start() error {
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  checker.Handshake,
+		Plugins:          plugin.PluginSet{checker.PluginName: &checker.GRPCPlugin{}},
+		Cmd:              exec.Command(p.cfg.Path),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		StartTimeout:     p.timeout,
+		Logger:           hclog.New(&hclog.LoggerOptions{Name: "checker-plugin", Level: hclog.Warn, Output: log.Writer()}),
+	})
+	conn, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return fmt.Errorf("checker plugin %s: %w", p.cfg.Path, err)
+	}
+	raw, err := conn.Dispense(checker.PluginName)
+	if err != nil {
+		client.Kill()
+		return fmt.Errorf("checker plugin %s: %w", p.cfg.Path, err)
+	}
+	remote := raw.(*checker.Client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	name, err := remote.Configure(ctx, p.cfg.Config)
+	switch {
+	case err != nil:
+		err = fmt.Errorf("checker plugin %s: %w", p.cfg.Path, err)
+	case name == "":
+		err = fmt.Errorf("checker plugin %s: checker has no name", p.cfg.Path)
+	case p.name != "" && name != p.name:
+		err = fmt.Errorf("checker plugin %s: checker %s came back as %s", p.cfg.Path, p.name, name)
+	}
+	if err != nil {
+		client.Kill()
+		return err
+	}
+	p.name, p.client, p.remote = name, client, remote
+	return nil
+}
+
+// stop kills the plugin process (caller holds mu)
+func (p *pluginChecker) stop() {
+	if p.client != nil {
+		p.client.Kill()
+		p.client, p.remote = nil, nil
+	}
+}
+
+// Name implements checker.Checker
+func (p *pluginChecker) Name() string {
+	return p.name
+}
+
+// Check runs the plugin's check, restarting the plugin if it is not running;
+// a check that overruns the timeout kills the plugin, so its work stops too
+func (p *pluginChecker) Check(ctx context.Context, input checker.Input) ([]checker.Outcome, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil || p.client.Exited() {
+		p.stop()
+		if err := p.start(); err != nil {
+			return nil, fmt.Errorf("checker %s: failed to restart: %w", p.name, err)
+		}
+		log.Printf("🧩 Restarted checker plugin %s", p.name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	outcomes, err := p.remote.Check(ctx, input)
+	switch {
+	case err == nil:
+		return outcomes, nil
+	case ctx.Err() != nil:
+		p.stop()
+		return nil, fmt.Errorf("checker %s: timed out after %s", p.name, p.timeout)
+	case p.client.Exited() || status.Code(err) == codes.Unavailable:
+		p.stop()
+		return nil, fmt.Errorf("checker %s: plugin exited: %w", p.name, err)
+	}
+	return nil, fmt.Errorf("checker %s: %w", p.name, err)
+}
+
+// Close stops the plugin process
+func (p *pluginChecker) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop()
+	return nil
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/pkg/checker"
+)
+
+// testPluginEnv makes the test binary serve testChecker as a checker plugin
+const testPluginEnv = "NETBLOCKS_TEST_CHECKER_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) != "" {
+		checker.Serve(func(config map[string]string) (checker.Checker, error) {
+			if config["fail"] != "" {
+				return nil, errors.New(config["fail"])
+			}
+			return testChecker{}, nil
+		})
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// testChecker answers by the country of the input: "HANG" never returns,
+// "PANIC" panics and "EXIT" ends the plugin process
+type testChecker struct{}
+
+func (testChecker) Name() string { return "test" }
+
+func (testChecker) Check(ctx context.Context, input checker.Input) ([]checker.Outcome, error) {
+	switch input.Country {
+	case "HANG":
+		select {}
+	case "PANIC":
+		panic("boom")
+	case "EXIT":
+		os.Exit(1)
+	}
+	return []checker.Outcome{{Name: "probe", Up: true, Detail: input.Country}}, nil
+}
+
+// startTestPlugin starts the test binary as a checker plugin
+func startTestPlugin(t *testing.T, cfg config.PluginConfig) (*pluginChecker, error) {
+	t.Helper()
+	t.Setenv(testPluginEnv, "1")
+	cfg.Path = os.Args[0]
+	c, err := loadPlugin(cfg)
+	if err != nil {
+		return nil, err
+	}
+	p := c.(*pluginChecker)
+	t.Cleanup(func() { p.Close() })
+	return p, nil
+}
+
+func TestPluginCheck(t *testing.T) {
+	p, err := startTestPlugin(t, config.PluginConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name() != "test" {
+		t.Fatalf("name = %q, want test", p.Name())
+	}
+	outcomes, err := p.Check(context.Background(), checker.Input{Country: "IR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != 1 || !outcomes[0].Up || outcomes[0].Detail != "IR" {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+}
+
+func TestPluginConfigError(t *testing.T) {
+	if _, err := startTestPlugin(t, config.PluginConfig{Config: map[string]string{"fail": "no endpoint"}}); err == nil {
+		t.Fatal("plugin with a failing factory loaded")
+	}
+}
+
+func TestPluginTimeoutKillsAndRestarts(t *testing.T) {
+	p, err := startTestPlugin(t, config.PluginConfig{Timeout: "500ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := p.Check(context.Background(), checker.Input{Country: "HANG"}); err == nil {
+		t.Fatal("hanging check succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("hanging check returned after %s", elapsed)
+	}
+	if p.client != nil {
+		t.Fatal("plugin still running after the timeout")
+	}
+	if _, err := p.Check(context.Background(), checker.Input{Country: "IR"}); err != nil {
+		t.Fatalf("check after the timeout: %v", err)
+	}
+}
+
+func TestPluginPanicAndExit(t *testing.T) {
+	p, err := startTestPlugin(t, config.PluginConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Check(context.Background(), checker.Input{Country: "PANIC"}); err == nil {
+		t.Fatal("panicking check succeeded")
+	}
+	if _, err := p.Check(context.Background(), checker.Input{Country: "EXIT"}); err == nil {
+		t.Fatal("check of an exiting plugin succeeded")
+	}
+	if _, err := p.Check(context.Background(), checker.Input{Country: "IR"}); err != nil {
+		t.Fatalf("check after the plugin exited: %v", err)
+	}
+}
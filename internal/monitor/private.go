@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"log"
+	"net/netip"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), like the
+// private ranges only routed inside the operators' networks
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPrivateAddress reports whether a DNS server address is only reachable
+// inside the country's networks (RFC 1918, RFC 6598 or IPv6 unique local)
+func isPrivateAddress(address string) bool {
+	addr, err := netip.ParseAddr(strings.Trim(address, "[]"))
+	if err != nil {
+		return false
+	}
+	return addr.IsPrivate() || sharedAddressSpace.Contains(addr)
+}
+
+// InCountry reports whether the probe measures from inside the monitored
+// country, i.e. probe_country is the country's code
+func InCountry(cfg *config.Config) bool {
+	return cfg.ProbeCountry != "" && strings.EqualFold(cfg.ProbeCountry, cfg.Country)
+}
+
+// ReachableDNSServers drops the servers with private addresses unless the probe
+// is in the country: from outside they can never answer, so probing them only
+// adds retries and perpetual failures to every cycle
+func ReachableDNSServers(cfg *config.Config, servers []config.DNSServer) (reachable []config.DNSServer, skipped []config.DNSServer) {
+	if InCountry(cfg) {
+		return servers, nil
+	}
+	reachable = make([]config.DNSServer, 0, len(servers))
+	for _, server := range servers {
+		if isPrivateAddress(server.Address) {
+			skipped = append(skipped, server)
+			continue
+		}
+		reachable = append(reachable, server)
+	}
+	return reachable, skipped
+}
+
+// reachableDNSServers is ReachableDNSServers with a log line about the skipped servers
+func reachableDNSServers(cfg *config.Config, servers []config.DNSServer) []config.DNSServer {
+	reachable, skipped := ReachableDNSServers(cfg, servers)
+	if len(skipped) > 0 {
+		names := make([]string, len(skipped))
+		for i, server := range skipped {
+			names[i] = server.Address + " (" + server.Name + ")"
+		}
+		log.Printf("🔒 Skipping %d private DNS server(s), only reachable from probes in %s (set probe_country to %q there): %s",
+			len(skipped), cfg.Country, cfg.Country, strings.Join(names, ", "))
+	}
+	return reachable
+}
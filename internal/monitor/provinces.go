@@ -0,0 +1,310 @@
+package monitor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+// Province status by the share of its DNS servers alive
+const (
+	ProvinceNormal    = "normal"    // At least 90% alive
+	ProvinceDegraded  = "degraded"  // At least 50% alive
+	ProvinceDisrupted = "disrupted" // Less than 50% alive
+	ProvinceUnknown   = "unknown"   // No DNS server in the province
+)
+
+// ProvinceHealth is the connectivity of a province in a result
+type ProvinceHealth struct {
+	Province    config.Province
+	Status      string        // ProvinceNormal, ProvinceDegraded, ProvinceDisrupted or ProvinceUnknown
+	DNSTotal    int           // DNS servers in the province
+	DNSAlive    int           // DNS servers of them alive
+	Score       float64       // Share of the DNS servers alive, 0-100 (0 without servers)
+	AvgResponse time.Duration // Mean response time of the alive servers
+}
+
+// ProvinceHealths aggregates the DNS statuses of a result per province
+func ProvinceHealths(provinces []config.Province, result *models.MonitoringResult) []ProvinceHealth {
+	healths := make([]ProvinceHealth, 0, len(provinces))
+	for _, province := range provinces {
+		health := ProvinceHealth{Province: province, Status: ProvinceUnknown}
+		var responseTotal time.Duration
+		for _, status := range result.DNSStatuses {
+			if !province.Matches(config.GetDNSCity(status.Name)) {
+				continue
+			}
+			health.DNSTotal++
+			if status.Alive {
+				health.DNSAlive++
+				responseTotal += status.ResponseTime
+			}
+		}
+		if health.DNSTotal > 0 {
+			health.Score = math.Round(float64(health.DNSAlive)/float64(health.DNSTotal)*1000) / 10
+			switch {
+			case health.Score >= 90:
+				health.Status = ProvinceNormal
+			case health.Score >= 50:
+				health.Status = ProvinceDegraded
+			default:
+				health.Status = ProvinceDisrupted
+			}
+		}
+		if health.DNSAlive > 0 {
+			health.AvgResponse = responseTotal / time.Duration(health.DNSAlive)
+		}
+		healths = append(healths, health)
+	}
+	return healths
+}
+
+// ProvinceColor is the map color of a province status
+func ProvinceColor(status string) drawing.Color {
+	switch status {
+	case ProvinceNormal:
+		return drawing.Color{R: 48, G: 161, B: 78, A: 255} // Green
+	case ProvinceDegraded:
+		return drawing.Color{R: 255, G: 152, B: 0, A: 255} // Orange
+	case ProvinceDisrupted:
+		return drawing.Color{R: 244, G: 67, B: 54, A: 255} // Red
+	default:
+		return drawing.Color{R: 189, G: 189, B: 189, A: 255} // Gray
+	}
+}
+
+// ProvinceShapes are the province polygons of map_boundaries
+type ProvinceShapes struct {
+	geometries map[string]json.RawMessage // GeoJSON geometry by lower-case province name
+	polygons   map[string][][][2]float64  // Outer rings ([lon, lat] points) by lower-case province name
+}
+
+// LoadProvinceShapes reads a GeoJSON FeatureCollection of province polygons
+// and matches its features to the provinces by their "name" property
+// ("Fars" or "Fars Province")
+func LoadProvinceShapes(path string, provinces []config.Province) (*ProvinceShapes, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var collection struct {
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+			Geometry   json.RawMessage        `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	shapes := &ProvinceShapes{geometries: make(map[string]json.RawMessage), polygons: make(map[string][][][2]float64)}
+	for _, feature := range collection.Features {
+		name, _ := feature.Properties["name"].(string)
+		name = strings.TrimSuffix(strings.TrimSpace(name), " Province")
+		for _, province := range provinces {
+			if !province.Matches(name) {
+				continue
+			}
+			rings, err := outerRings(feature.Geometry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: province %s: %w", path, province.Name, err)
+			}
+			key := strings.ToLower(province.Name)
+			shapes.geometries[key] = feature.Geometry
+			shapes.polygons[key] = rings
+		}
+	}
+	return shapes, nil
+}
+
+// Geometry returns the GeoJSON geometry of a province
+func (s *ProvinceShapes) Geometry(province string) (json.RawMessage, bool) {
+	if s == nil {
+		return nil, false
+	}
+	geometry, ok := s.geometries[strings.ToLower(province)]
+	return geometry, ok
+}
+
+// outerRings returns the outer ring of every polygon of a Polygon or
+// MultiPolygon geometry; holes are not drawn
+func outerRings(raw json.RawMessage) ([][][2]float64, error) {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(raw, &geometry); err != nil {
+		return nil, err
+	}
+	switch geometry.Type {
+	case "Polygon":
+		var polygon [][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, err
+		}
+		if len(polygon) == 0 {
+			return nil, nil
+		}
+		return [][][2]float64{polygon[0]}, nil
+	case "MultiPolygon":
+		var polygons [][][][2]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygons); err != nil {
+			return nil, err
+		}
+		var rings [][][2]float64
+		for _, polygon := range polygons {
+			if len(polygon) > 0 {
+				rings = append(rings, polygon[0])
+			}
+		}
+		return rings, nil
+	default:
+		return nil, fmt.Errorf("unsupported geometry type %q (want Polygon or MultiPolygon)", geometry.Type)
+	}
+}
+
+// GenerateProvinceMap renders the provinces colored by status: as polygons if
+// shapes has them, otherwise as circles at the capitals sized by their DNS servers
+func GenerateProvinceMap(title string, healths []ProvinceHealth, shapes *ProvinceShapes) (*bytes.Buffer, error) {
+	if len(healths) == 0 {
+		return nil, fmt.Errorf("no provinces configured")
+	}
+
+	const (
+		width       = 900
+		height      = 760
+		topPadding  = 60
+		sidePadding = 30
+		footer      = 60
+		labelMargin = 150 // Room right of the easternmost capital for its label
+	)
+
+	// Equirectangular projection of the bounding box, longitudes shortened by
+	// the cosine of the mean latitude so the country keeps its shape
+	minLon, minLat, maxLon, maxLat := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	extend := func(p [2]float64) {
+		minLon, maxLon = math.Min(minLon, p[0]), math.Max(maxLon, p[0])
+		minLat, maxLat = math.Min(minLat, p[1]), math.Max(maxLat, p[1])
+	}
+	for _, health := range healths {
+		extend([2]float64{health.Province.Lon, health.Province.Lat})
+		if shapes != nil {
+			for _, ring := range shapes.polygons[strings.ToLower(health.Province.Name)] {
+				for _, p := range ring {
+					extend(p)
+				}
+			}
+		}
+	}
+	minLon, maxLon, minLat, maxLat = minLon-0.5, maxLon+0.5, minLat-0.5, maxLat+0.5
+	aspect := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	areaWidth, areaHeight := float64(width-2*sidePadding-labelMargin), float64(height-topPadding-footer)
+	scale := math.Min(areaWidth/((maxLon-minLon)*aspect), areaHeight/(maxLat-minLat))
+	offsetX := sidePadding + (areaWidth-(maxLon-minLon)*aspect*scale)/2
+	offsetY := topPadding + (areaHeight-(maxLat-minLat)*scale)/2
+	project := func(lon, lat float64) (int, int) {
+		return int(offsetX + (lon-minLon)*aspect*scale), int(offsetY + (maxLat-lat)*scale)
+	}
+
+	r, err := chart.PNG(width, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create map renderer: %w", err)
+	}
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart font: %w", err)
+	}
+	r.SetFont(font)
+
+	drawRect(r, 0, 0, width, height, drawing.Color{R: 255, G: 255, B: 255, A: 255})
+	r.SetFontColor(drawing.Color{R: 0, G: 0, B: 0, A: 255})
+	r.SetFontSize(16)
+	r.Text(title, sidePadding, 35)
+
+	// Polygons first, so the labels of small provinces stay on top
+	for _, health := range healths {
+		if shapes == nil {
+			break
+		}
+		for _, ring := range shapes.polygons[strings.ToLower(health.Province.Name)] {
+			if len(ring) < 3 {
+				continue
+			}
+			r.SetFillColor(ProvinceColor(health.Status))
+			r.SetStrokeColor(drawing.Color{R: 255, G: 255, B: 255, A: 255})
+			r.SetStrokeWidth(1)
+			r.MoveTo(project(ring[0][0], ring[0][1]))
+			for _, p := range ring[1:] {
+				r.LineTo(project(p[0], p[1]))
+			}
+			r.Close()
+			r.FillStroke()
+		}
+	}
+
+	// Provinces without a polygon are circles sized by their DNS servers;
+	// the busiest are drawn first so small circles are not hidden
+	order := make([]int, len(healths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return healths[order[a]].DNSTotal > healths[order[b]].DNSTotal })
+	r.SetFontSize(9)
+	for _, i := range order {
+		health := healths[i]
+		x, y := project(health.Province.Lon, health.Province.Lat)
+		_, shaped := shapes.Geometry(health.Province.Name)
+		if !shaped {
+			radius := 6 + math.Min(18, 3*math.Sqrt(float64(health.DNSTotal)))
+			r.SetFillColor(ProvinceColor(health.Status))
+			r.SetStrokeColor(drawing.Color{R: 90, G: 90, B: 90, A: 255})
+			r.SetStrokeWidth(1)
+			r.MoveTo(x+int(radius), y)
+			for step := 1; step < 32; step++ {
+				angle := float64(step) * 2 * math.Pi / 32
+				r.LineTo(x+int(radius*math.Cos(angle)), y+int(radius*math.Sin(angle)))
+			}
+			r.Close()
+			r.FillStroke()
+			x += int(radius) + 3
+		}
+		label := health.Province.Name
+		if health.DNSTotal > 0 {
+			label += " " + chartLocale.Percent(health.Score, 0)
+		}
+		r.SetFontColor(drawing.Color{R: 40, G: 40, B: 40, A: 255})
+		r.Text(label, x, y+3)
+	}
+
+	// Legend
+	legendY := height - footer + 25
+	x := sidePadding
+	r.SetFontSize(10)
+	for _, entry := range []struct{ status, label string }{
+		{ProvinceNormal, "90%+ DNS alive"},
+		{ProvinceDegraded, "50-90%"},
+		{ProvinceDisrupted, "under 50%"},
+		{ProvinceUnknown, "no DNS servers"},
+	} {
+		drawRect(r, x, legendY-10, 14, 14, ProvinceColor(entry.status))
+		r.SetFontColor(drawing.Color{R: 60, G: 60, B: 60, A: 255})
+		r.Text(entry.label, x+20, legendY+1)
+		x += 40 + len(entry.label)*7
+	}
+
+	buffer := charts.scratch()
+	if err := r.Save(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render province map: %w", err)
+	}
+	return buffer, nil
+}
@@ -0,0 +1,98 @@
+package monitor
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Transport settings of the Radar client
+const (
+	radarTimeout      = 30 * time.Second
+	radarIdlePerHost  = 10               // Idle connections kept per host; the default of 2 forces new handshakes when fetches overlap
+	radarIdleTimeout  = 90 * time.Second // Longer than the gap between the retried endpoints of a fetch
+	radarDNSCacheTTL  = 5 * time.Minute
+	radarDialTimeout  = 10 * time.Second
+	radarTLSHandshake = 10 * time.Second
+)
+
+// radarClient is shared by every traffic monitor (country and ASN traffic, all
+// countries, backfill), so their fetches reuse one pool of keep-alive (HTTP/2
+// where offered) connections to api.cloudflare.com instead of handshaking per
+// fetch, and count against one API budget (see radarquota.go)
+var radarClient = &http.Client{
+	Timeout: radarTimeout,
+	Transport: &quotaTransport{tracker: radarQuota, next: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&cachingDialer{dialer: &net.Dialer{Timeout: radarDialTimeout, KeepAlive: 30 * time.Second}}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          2 * radarIdlePerHost,
+		MaxIdleConnsPerHost:   radarIdlePerHost,
+		IdleConnTimeout:       radarIdleTimeout,
+		TLSHandshakeTimeout:   radarTLSHandshake,
+		ExpectContinueTimeout: time.Second,
+	}},
+}
+
+// cachingDialer resolves host names once per radarDNSCacheTTL, so new
+// connections to the same API host do not each wait for a DNS lookup
+type cachingDialer struct {
+	dialer *net.Dialer
+	mu     sync.Mutex
+	hosts  map[string]cachedAddrs
+}
+
+type cachedAddrs struct {
+	addrs   []string
+	expires time.Time
+}
+
+// DialContext dials the cached addresses of the host in turn; IP literals and
+// hosts that fail to resolve are dialed as usual
+func (d *cachingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+	var lastErr error
+	for _, addr := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	// The cached addresses may be stale; resolve again next time
+	d.mu.Lock()
+	delete(d.hosts, host)
+	d.mu.Unlock()
+	return nil, lastErr
+}
+
+// lookup returns the addresses of host, from the cache while they are fresh
+func (d *cachingDialer) lookup(ctx context.Context, host string) ([]string, error) {
+	d.mu.Lock()
+	cached, ok := d.hosts[host]
+	d.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.addrs, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.hosts == nil {
+		d.hosts = make(map[string]cachedAddrs)
+	}
+	d.hosts[host] = cachedAddrs{addrs: addrs, expires: time.Now().Add(radarDNSCacheTTL)}
+	return addrs, nil
+}
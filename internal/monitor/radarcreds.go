@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rejectedPause is how long a token answered with 401 or 403 is skipped
+// before it is tried again (it may have been revoked)
+const rejectedPause = 10 * time.Minute
+
+// radarCredential is an API token, or the legacy email and API key
+type radarCredential struct {
+	token, email, key string
+	pausedUntil       time.Time // Skipped until then after a 429, 401 or 403
+}
+
+// authorize sets the authentication headers of a request
+func (c *radarCredential) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		return
+	}
+	req.Header.Set("X-Auth-Email", c.email)
+	req.Header.Set("X-Auth-Key", c.key)
+}
+
+// radarCredentials uses the Radar tokens in turn, skipping the ones that were
+// rate limited or rejected until they recover
+type radarCredentials struct {
+	mu    sync.Mutex
+	creds []*radarCredential
+	next  int // Index of the credential used next
+}
+
+// newRadarCredentials returns the tokens, or the legacy key if there are none
+func newRadarCredentials(tokens []string, email, key string) *radarCredentials {
+	c := &radarCredentials{}
+	for _, token := range tokens {
+		if token != "" {
+			c.creds = append(c.creds, &radarCredential{token: token})
+		}
+	}
+	if len(c.creds) == 0 && email != "" && key != "" {
+		c.creds = append(c.creds, &radarCredential{email: email, key: key})
+	}
+	return c
+}
+
+// pick returns the next credential not in tried: the next usable one in turn,
+// or the one that recovers first if all are paused (-1 if all were tried)
+func (c *radarCredentials) pick(now time.Time, tried map[int]bool) (int, *radarCredential) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	best := -1
+	for n := range c.creds {
+		i := (c.next + n) % len(c.creds)
+		if tried[i] {
+			continue
+		}
+		if !now.Before(c.creds[i].pausedUntil) {
+			c.next = (i + 1) % len(c.creds)
+			return i, c.creds[i]
+		}
+		if best < 0 || c.creds[i].pausedUntil.Before(c.creds[best].pausedUntil) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return -1, nil
+	}
+	return best, c.creds[best]
+}
+
+// pause skips a credential after a 429 (for its Retry-After), 401 or 403
+func (c *radarCredentials) pause(i int, resp *http.Response) {
+	wait := rejectedPause
+	if resp.StatusCode == http.StatusTooManyRequests {
+		wait = retryAfter(resp.Header)
+	}
+	c.mu.Lock()
+	c.creds[i].pausedUntil = time.Now().Add(wait)
+	total := len(c.creds)
+	c.mu.Unlock()
+	log.Printf("🔑 Cloudflare credential %d/%d answered HTTP %d: skipped for %s", i+1, total, resp.StatusCode, formatDuration(wait))
+}
+
+// usable returns how many credentials are not paused, and how many there are
+func (c *radarCredentials) usable() (int, int) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	usable := 0
+	for _, cred := range c.creds {
+		if !now.Before(cred.pausedUntil) {
+			usable++
+		}
+	}
+	return usable, len(c.creds)
+}
+
+// credentialFailure reports whether a response blames the credential
+func credentialFailure(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// do sends an authenticated Radar request, failing over to the next
+// credential when one is rate limited or rejected
+func (tm *TrafficMonitor) do(req *http.Request) (*http.Response, error) {
+	tried := make(map[int]bool)
+	for {
+		i, cred := tm.credentials.pick(time.Now(), tried)
+		if cred == nil {
+			return nil, fmt.Errorf("no Cloudflare credentials available")
+		}
+		tried[i] = true
+		attempt := req.Clone(req.Context())
+		cred.authorize(attempt)
+		resp, err := tm.client.Do(attempt)
+		if err != nil || !credentialFailure(resp.StatusCode) {
+			return resp, err
+		}
+		tm.credentials.pause(i, resp)
+		if len(tried) == len(tm.credentials.creds) {
+			return resp, nil
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// SetTokens replaces the API token with a list of tokens used in turn
+func (tm *TrafficMonitor) SetTokens(tokens []string) {
+	tm.credentials = newRadarCredentials(tokens, tm.cloudflareEmail, tm.cloudflareKey)
+	if len(tokens) > 0 {
+		tm.cloudflareToken = tokens[0]
+	}
+	if len(tokens) > 1 {
+		log.Printf("🔑 Using %d Cloudflare API tokens in turn", len(tokens))
+	}
+}
+
+// Credentials returns how many Radar credentials are usable, and how many there are
+func (tm *TrafficMonitor) Credentials() (int, int) {
+	return tm.credentials.usable()
+}
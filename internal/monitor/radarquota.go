@@ -0,0 +1,287 @@
+package monitor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Radar budget defaults
+const (
+	defaultDegradeBelow = 20              // Percent of the budget left
+	degradedCacheFactor = 4               // Cached traffic is served this much longer while the budget is low
+	defaultRetryAfter   = 5 * time.Minute // Pause after a 429 without Retry-After
+)
+
+// Budget levels of the Radar API
+const (
+	quotaOK        = iota
+	quotaLow       // Space out fetches and skip the ASN endpoint variations
+	quotaExhausted // Serve cached traffic only
+)
+
+// RadarQuota is the Cloudflare Radar API usage and the remaining budget
+type RadarQuota struct {
+	Calls       int       // API calls since start
+	CallsToday  int       // API calls this UTC day
+	RateLimited int       // Calls answered with 429 since start
+	DailyBudget int       // Configured calls per day (0: none)
+	Limit       int       // Rate limit of the credentials' last responses, summed (0: not reported)
+	Remaining   int       // Calls left in their rate limit windows (valid if Limit > 0)
+	Reset       time.Time // First end of a rate limit window, or of a pause after a 429
+	Degraded    bool      // Fetches are spaced out or paused to save the budget
+}
+
+// quotaTracker counts the calls of the shared Radar client and reads the rate
+// limit headers of the responses, per credential
+type quotaTracker struct {
+	mu           sync.Mutex
+	dailyBudget  int
+	degradeBelow int
+	calls        int
+	callsToday   int
+	day          string // UTC day of callsToday
+	rateLimited  int
+	windows      map[uint64]*rateWindow // By hash of the credential headers
+	level        int                    // Last reported level, to log changes
+}
+
+// rateWindow is the rate limit a credential's last response reported
+type rateWindow struct {
+	limit        int
+	remaining    int
+	reset        time.Time
+	blockedUntil time.Time // Retry-After of the last 429
+}
+
+// radarQuota tracks the calls of radarClient
+var radarQuota = &quotaTracker{degradeBelow: defaultDegradeBelow}
+
+// SetRadarBudget sets the daily call budget and the degrade threshold of the
+// Cloudflare Radar API (nil: only the rate limit headers count)
+func SetRadarBudget(budget *config.CloudflareBudget) {
+	radarQuota.mu.Lock()
+	defer radarQuota.mu.Unlock()
+	radarQuota.dailyBudget, radarQuota.degradeBelow = 0, defaultDegradeBelow
+	if budget == nil {
+		return
+	}
+	radarQuota.dailyBudget = budget.DailyCalls
+	if budget.DegradeBelow > 0 {
+		radarQuota.degradeBelow = budget.DegradeBelow
+	}
+}
+
+// RadarQuotaStats returns the Radar API usage of this process
+func RadarQuotaStats() RadarQuota {
+	q := radarQuota
+	level := q.check()
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	quota := RadarQuota{Calls: q.calls, CallsToday: q.callsToday, RateLimited: q.rateLimited, DailyBudget: q.dailyBudget,
+		Degraded: level != quotaOK}
+	for _, window := range q.windows {
+		for _, end := range []time.Time{window.reset, window.blockedUntil} {
+			if end.After(now) && (quota.Reset.IsZero() || end.Before(quota.Reset)) {
+				quota.Reset = end
+			}
+		}
+		if window.limit > 0 && now.Before(window.reset) {
+			quota.Limit += window.limit
+			quota.Remaining += window.remaining
+		}
+	}
+	return quota
+}
+
+// quotaTransport counts the requests it passes to next
+type quotaTransport struct {
+	next    http.RoundTripper
+	tracker *quotaTracker
+}
+
+func (t *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.tracker.count()
+	resp, err := t.next.RoundTrip(req)
+	if err == nil {
+		t.tracker.observe(credentialKey(req), resp)
+	}
+	return resp, err
+}
+
+// count counts a call, failed or not
+func (q *quotaTracker) count() {
+	day := time.Now().UTC().Format("2006-01-02")
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if day != q.day {
+		q.day, q.callsToday = day, 0
+	}
+	q.calls++
+	q.callsToday++
+}
+
+// credentialKey identifies the credential of a request without keeping it
+func credentialKey(req *http.Request) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(req.Header.Get("Authorization") + "\x00" + req.Header.Get("X-Auth-Key")))
+	return h.Sum64()
+}
+
+// observe reads the rate limit headers and the 429 status of a credential's response
+func (q *quotaTracker) observe(key uint64, resp *http.Response) {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.windows == nil {
+		q.windows = make(map[uint64]*rateWindow)
+	}
+	window := q.windows[key]
+	if window == nil {
+		window = &rateWindow{}
+		q.windows[key] = window
+	}
+	if limit, remaining, reset, ok := parseRateLimit(resp.Header); ok {
+		window.limit, window.remaining, window.reset = limit, remaining, now.Add(reset)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		q.rateLimited++
+		window.blockedUntil = now.Add(retryAfter(resp.Header))
+	}
+}
+
+// retryAfter returns the wait a 429 asks for (defaultRetryAfter if it does not say)
+func retryAfter(header http.Header) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header.Get("Retry-After"))); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return defaultRetryAfter
+}
+
+// check returns the budget level and logs when it changes
+func (q *quotaTracker) check() int {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	left := func(remaining, limit int) int {
+		switch {
+		case remaining <= 0:
+			return quotaExhausted
+		case remaining*100 < limit*q.degradeBelow:
+			return quotaLow
+		}
+		return quotaOK
+	}
+
+	// The rate limits are per credential: the best one counts, since the
+	// credentials are used in turn (see radarcreds.go)
+	level, first := quotaOK, true
+	for _, window := range q.windows {
+		windowLevel := quotaOK
+		switch {
+		case now.Before(window.blockedUntil):
+			windowLevel = quotaExhausted
+		case window.limit > 0 && now.Before(window.reset):
+			windowLevel = left(window.remaining, window.limit)
+		}
+		if first || windowLevel < level {
+			level, first = windowLevel, false
+		}
+	}
+	if q.dailyBudget > 0 {
+		today := q.callsToday
+		if q.day != now.UTC().Format("2006-01-02") {
+			today = 0
+		}
+		level = max(level, left(q.dailyBudget-today, q.dailyBudget))
+	}
+
+	if level != q.level {
+		switch level {
+		case quotaExhausted:
+			log.Printf("🪫 Cloudflare Radar budget exhausted (%d calls today): serving cached traffic only", q.callsToday)
+		case quotaLow:
+			log.Printf("🔋 Cloudflare Radar budget low (%d calls today): fetching less often", q.callsToday)
+		default:
+			log.Println("🔋 Cloudflare Radar budget recovered: fetching as usual")
+		}
+		q.level = level
+	}
+	return level
+}
+
+// skip reports whether a fetch is left out to save the budget: always while
+// it is exhausted (an error if nothing is cached), and while it is low until
+// the cached data is older than degradedCacheFactor/2 times cacheFor, so it
+// is served up to degradedCacheFactor times longer than usual
+func (q *quotaTracker) skip(what string, cached bool, fetched time.Time, cacheFor time.Duration) (bool, error) {
+	switch level := q.check(); {
+	case level == quotaExhausted && !cached:
+		return true, fmt.Errorf("cloudflare radar budget exhausted, no cached %s", what)
+	case level == quotaExhausted:
+		log.Printf("🪫 Skipping the %s fetch: Cloudflare Radar budget exhausted, serving data of %s ago", what, time.Since(fetched).Truncate(time.Second))
+		return true, nil
+	case level == quotaLow && cached && time.Since(fetched) < cacheFor*degradedCacheFactor/2:
+		log.Printf("🔋 Skipping the %s fetch: Cloudflare Radar budget low, serving data of %s ago", what, time.Since(fetched).Truncate(time.Second))
+		return true, nil
+	}
+	return false, nil
+}
+
+// low reports whether the budget is low or exhausted
+func (q *quotaTracker) low() bool {
+	return q.check() != quotaOK
+}
+
+// parseRateLimit reads the rate limit of a response: the RateLimit and
+// RateLimit-Policy structured fields Cloudflare sends ("default";r=1180;t=30
+// and "burst";q=1200;w=300), or the RateLimit-* and X-RateLimit-* headers
+func parseRateLimit(header http.Header) (limit, remaining int, reset time.Duration, ok bool) {
+	field := func(value, key string) (int, bool) {
+		for _, part := range strings.Split(value, ";") {
+			if k, v, found := strings.Cut(strings.TrimSpace(part), "="); found && k == key {
+				n, err := strconv.Atoi(v)
+				return n, err == nil
+			}
+		}
+		return 0, false
+	}
+	number := func(names ...string) (int, bool) {
+		for _, name := range names {
+			if n, err := strconv.Atoi(strings.TrimSpace(header.Get(name))); err == nil {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+
+	if value := header.Get("Ratelimit"); strings.Contains(value, "r=") {
+		remaining, ok = field(value, "r")
+		seconds, _ := field(value, "t")
+		limit, _ = field(header.Get("Ratelimit-Policy"), "q")
+		reset = time.Duration(seconds) * time.Second
+	} else if remaining, ok = number("Ratelimit-Remaining", "X-Ratelimit-Remaining"); ok {
+		limit, _ = number("Ratelimit-Limit", "X-Ratelimit-Limit")
+		seconds, _ := number("Ratelimit-Reset", "X-Ratelimit-Reset")
+		if seconds > 1e9 { // Unix time rather than seconds
+			seconds -= int(time.Now().Unix())
+		}
+		reset = time.Duration(seconds) * time.Second
+	}
+	if !ok || limit <= 0 {
+		return 0, 0, 0, false
+	}
+	if reset <= 0 {
+		reset = time.Minute
+	}
+	return limit, remaining, reset, true
+}
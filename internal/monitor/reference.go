@@ -0,0 +1,36 @@
+package monitor
+
+import "github.com/netblocks/netblocks/internal/models"
+
+// splitReference separates the external reference ASNs (see reference_asns)
+// from the country's ASNs
+func (m *Monitor) splitReference(statuses map[string]*models.ASNStatus) (country, reference map[string]*models.ASNStatus) {
+	if len(m.reference) == 0 {
+		return statuses, nil
+	}
+	country = make(map[string]*models.ASNStatus, len(statuses))
+	reference = make(map[string]*models.ASNStatus, len(m.reference))
+	for asn, status := range statuses {
+		if m.reference[asn] {
+			reference[asn] = status
+		} else {
+			country[asn] = status
+		}
+	}
+	return country, reference
+}
+
+// withoutReference drops the external reference ASNs from Radar's ASN traffic,
+// e.g. Cloudflare WARP users showing up as AS13335
+func (m *Monitor) withoutReference(traffic []*models.ASTrafficData) []*models.ASTrafficData {
+	if len(m.reference) == 0 {
+		return traffic
+	}
+	kept := traffic[:0:0]
+	for _, item := range traffic {
+		if !m.reference[item.ASN] {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,232 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"log"
+	"sync"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Chart rendering defaults
+const (
+	defaultMaxRenders    = 2
+	defaultMaxQueued     = 16
+	defaultRenderBudget  = 64 << 20
+	maxPooledBufferBytes = 4 << 20 // Larger scratch buffers are left to the GC
+)
+
+// errRenderBusy is returned when more renders wait than chart_rendering allows
+var errRenderBusy = errors.New("chart renderer busy, try again shortly")
+
+// canvasBytes estimates the memory of a render: the RGBA canvas and about as
+// much again for the rasterizer and the PNG encoder
+func canvasBytes(width, height int) int64 {
+	return int64(width) * int64(height) * 4 * 2
+}
+
+// Estimated memory of each chart (see canvasBytes)
+var (
+	trafficChartBytes = canvasBytes(1000, 400)
+	asnChartBytes     = canvasBytes(1600, 600)
+	heatmapBytes      = canvasBytes(800, 1200)
+	sparklineBytes    = canvasBytes(730, 600)
+	statusImageBytes  = canvasBytes(1200, 700) + canvasBytes(600, 350)
+	provinceMapBytes  = canvasBytes(900, 760)
+	failureChartBytes = canvasBytes(780, 520)
+)
+
+// chartRenderer runs the chart renders of the process in a bounded pool:
+// at most maxConcurrent at once within a memory budget, at most maxQueued
+// waiting. Concurrent renders of the same chart share one result
+type chartRenderer struct {
+	slots chan struct{} // One token per render in progress
+
+	mu        sync.Mutex
+	changed   *sync.Cond // Signalled when memory is released
+	maxQueued int
+	waiting   int
+	budget    int64
+	reserved  int64 // Estimated memory of the renders in progress
+	inflight  map[string]*sharedRender
+	counts    RenderStats
+
+	buffers sync.Pool // Scratch *bytes.Buffer reused across renders
+}
+
+// RenderStats counts the chart renders of the process
+type RenderStats struct {
+	Rendered int // Renders completed
+	Shared   int // Requests served by a render of the same chart in progress
+	Rejected int // Requests refused because too many renders were waiting
+	Waiting  int // Renders waiting for a slot or memory now
+}
+
+// stats returns the render counters
+func (r *chartRenderer) stats() RenderStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.counts
+	stats.Waiting = r.waiting
+	return stats
+}
+
+// sharedRender is a render whose result the callers of the same key wait for
+type sharedRender struct {
+	done   chan struct{}
+	buffer *bytes.Buffer
+	err    error
+}
+
+// charts renders the charts of every monitor in this process
+var charts = newChartRenderer(nil)
+
+func newChartRenderer(settings *config.ChartRendering) *chartRenderer {
+	concurrent, queued, budget := defaultMaxRenders, defaultMaxQueued, int64(defaultRenderBudget)
+	if settings != nil {
+		if settings.MaxConcurrent > 0 {
+			concurrent = settings.MaxConcurrent
+		}
+		if settings.MaxQueued > 0 {
+			queued = settings.MaxQueued
+		}
+		if settings.MemoryBudgetMB > 0 {
+			budget = int64(settings.MemoryBudgetMB) << 20
+		}
+	}
+	r := &chartRenderer{slots: make(chan struct{}, concurrent), maxQueued: queued, budget: budget,
+		inflight: make(map[string]*sharedRender)}
+	r.changed = sync.NewCond(&r.mu)
+	r.buffers.New = func() any { return new(bytes.Buffer) }
+	return r
+}
+
+// SetChartRendering replaces the bounds of the chart renders (call before
+// the monitors start)
+func SetChartRendering(settings *config.ChartRendering) {
+	charts = newChartRenderer(settings)
+}
+
+// render runs a render within the bounds and returns its PNG copied into a
+// buffer of its exact size; the render's own buffer goes back to the pool.
+// Renders of a key already in progress wait for its result instead
+func (r *chartRenderer) render(ctx context.Context, key string, estimate int64, fn func() (*bytes.Buffer, error)) (*bytes.Buffer, error) {
+	r.mu.Lock()
+	if shared, ok := r.inflight[key]; ok {
+		r.counts.Shared++
+		r.mu.Unlock()
+		select {
+		case <-shared.done:
+			return shared.buffer, shared.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	shared := &sharedRender{done: make(chan struct{})}
+	r.inflight[key] = shared
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.inflight, key)
+		r.mu.Unlock()
+		close(shared.done)
+	}()
+	shared.buffer, shared.err = r.run(ctx, key, estimate, fn)
+	return shared.buffer, shared.err
+}
+
+// run waits for a slot and the memory of a render, then runs it
+func (r *chartRenderer) run(ctx context.Context, name string, estimate int64, fn func() (*bytes.Buffer, error)) (*bytes.Buffer, error) {
+	r.mu.Lock()
+	if r.waiting >= r.maxQueued {
+		r.counts.Rejected++
+		r.mu.Unlock()
+		log.Printf("⚠️  Chart renderer busy: %s not rendered (%d waiting)", name, r.maxQueued)
+		return nil, errRenderBusy
+	}
+	r.waiting++
+	r.mu.Unlock()
+
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		r.mu.Lock()
+		r.waiting--
+		r.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.slots }()
+
+	// A render larger than the whole budget runs alone
+	if estimate > r.budget {
+		estimate = r.budget
+	}
+	// A cancelled render stops waiting for memory: the broadcast wakes it up
+	stop := context.AfterFunc(ctx, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.changed.Broadcast()
+	})
+	defer stop()
+	r.mu.Lock()
+	for r.reserved+estimate > r.budget && ctx.Err() == nil {
+		r.changed.Wait()
+	}
+	r.waiting--
+	if err := ctx.Err(); err != nil {
+		r.mu.Unlock()
+		return nil, err
+	}
+	r.reserved += estimate
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.reserved -= estimate
+		r.counts.Rendered++
+		r.mu.Unlock()
+		r.changed.Broadcast()
+	}()
+
+	buffer, err := fn()
+	if err != nil || buffer == nil {
+		return buffer, err
+	}
+	out := bytes.NewBuffer(append(make([]byte, 0, buffer.Len()), buffer.Bytes()...))
+	r.release(buffer)
+	return out, nil
+}
+
+// scratch returns an empty buffer from the pool
+func (r *chartRenderer) scratch() *bytes.Buffer {
+	buffer := r.buffers.Get().(*bytes.Buffer)
+	buffer.Reset()
+	return buffer
+}
+
+// release returns a buffer nobody reads any more to the pool
+func (r *chartRenderer) release(buffer *bytes.Buffer) {
+	if buffer == nil || buffer.Cap() > maxPooledBufferBytes {
+		return
+	}
+	r.buffers.Put(buffer)
+}
+
+// statusCanvases reuses the canvas of the composite status image
+var statusCanvases sync.Pool
+
+// statusCanvas returns a canvas of the size of the status image from the pool
+func statusCanvas(width, height int) *image.RGBA {
+	if canvas, ok := statusCanvases.Get().(*image.RGBA); ok && canvas.Rect.Dx() == width && canvas.Rect.Dy() == height {
+		return canvas
+	}
+	return image.NewRGBA(image.Rect(0, 0, width, height))
+}
+
+// renderChart renders a chart of this monitor in the shared renderer; name
+// tells the charts of a monitor apart, e.g. "traffic/7d"
+func (m *Monitor) renderChart(ctx context.Context, name string, estimate int64, fn func() (*bytes.Buffer, error)) (*bytes.Buffer, error) {
+	return charts.render(ctx, m.config.Country+"/"+name, estimate, fn)
+}
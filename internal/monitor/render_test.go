@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+func TestRenderWaitingForMemoryHonorsContext(t *testing.T) {
+	r := newChartRenderer(&config.ChartRendering{MaxConcurrent: 2, MemoryBudgetMB: 1})
+
+	// The first render holds the whole budget until released
+	started, release := make(chan struct{}), make(chan struct{})
+	go r.render(context.Background(), "big", 1<<20, func() (*bytes.Buffer, error) {
+		close(started)
+		<-release
+		return bytes.NewBufferString("png"), nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.render(ctx, "small", 1<<10, func() (*bytes.Buffer, error) {
+			t.Error("render ran without memory")
+			return nil, nil
+		})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("err = %v, want the context's", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("render still waiting for memory after its context ended")
+	}
+	if stats := r.stats(); stats.Waiting != 0 {
+		t.Fatalf("waiting = %d after the render gave up", stats.Waiting)
+	}
+}
@@ -0,0 +1,183 @@
+package monitor
+
+import (
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/clock"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// defaultReplayStep is how much dump time passes between replay snapshots
+const defaultReplayStep = 5 * time.Minute
+
+// Replayer rebuilds the ASN and prefix connectivity of a past period from MRT
+// RIB and update dumps (RouteViews, RIS) with the state machine of the live
+// feeds, driven by a clock that follows the dumped timestamps
+type Replayer struct {
+	client *RISLiveClient
+	clock  *clock.Fake
+	step   time.Duration
+}
+
+// ReplayStep is the routing state at one point of a replay
+type ReplayStep struct {
+	Time     time.Time                       `json:"time"`
+	Updates  int                             `json:"updates"` // Dumped updates and routes read since the previous step
+	ASNs     map[string]*models.ASNStatus    `json:"asns"`
+	Prefixes map[string]*models.PrefixStatus `json:"prefixes,omitempty"`
+}
+
+// NewReplayer creates a replayer following the ASNs, reference ASNs and
+// prefixes of the config with its stale times, with a snapshot every step (0: 5m)
+func NewReplayer(cfg *config.Config, step time.Duration) *Replayer {
+	if step <= 0 {
+		step = defaultReplayStep
+	}
+	r := &Replayer{client: newRISLiveClient(nil, ""), clock: clock.NewFake(time.Unix(0, 0)), step: step}
+	r.client.SetClock(r.clock)
+	r.client.SetCountry(cfg.Country)
+	r.client.SetASNNames(cfg.GetASNName)
+	r.client.SetWithdrawalStorm(cfg.WithdrawalStorm)
+	if s, err := newSchedule(cfg); err == nil {
+		r.client.SetStaleAfter(s.asnStale)
+		r.client.SetStaleOverrides(s.asnStaleOverrides)
+	}
+	r.client.mu.Lock()
+	for _, asn := range append(append([]string(nil), cfg.IranASNs...), cfg.ReferenceASNs...) {
+		r.client.trackASN(asn)
+	}
+	r.client.mu.Unlock()
+	for _, prefix := range cfg.BGPPrefixes {
+		_, _, _ = r.client.trackPrefix(prefix) // Validated with the config
+	}
+	return r
+}
+
+// SetStaleAfter changes how long an ASN may stay silent before it is considered offline
+func (r *Replayer) SetStaleAfter(d time.Duration) {
+	r.client.SetStaleAfter(d)
+}
+
+// Replay reads the dumps (plain, .gz or .bz2) merged in time order and calls
+// emit with the state at every step boundary and at the last record. Start
+// with a RIB dump (rib.*, bview.*) so routes announced before the period are known
+func (r *Replayer) Replay(paths []string, emit func(*ReplayStep)) error {
+	sources := make([]*replaySource, 0, len(paths))
+	for _, path := range paths {
+		source := &replaySource{path: path, updates: make(chan *RISUpdateMessage, 256)}
+		go source.read()
+		sources = append(sources, source)
+	}
+
+	var next, last time.Time
+	count := 0
+	for {
+		// The earliest pending record of all dumps
+		var earliest *replaySource
+		for _, source := range sources {
+			if source.peek() && (earliest == nil || source.head.Timestamp < earliest.head.Timestamp) {
+				earliest = source
+			}
+		}
+		if earliest == nil {
+			break
+		}
+		update := earliest.head
+		earliest.head = nil
+
+		at := time.Unix(int64(update.Timestamp), 0)
+		if next.IsZero() {
+			r.clock.Set(at)
+			next = at.Truncate(r.step).Add(r.step)
+		}
+		for !at.Before(next) {
+			r.clock.Set(next)
+			emit(r.snapshot(count))
+			count = 0
+			next = next.Add(r.step)
+		}
+		r.clock.Set(at)
+		r.client.handleUpdate(update)
+		count++
+		last = at
+	}
+	if count > 0 {
+		r.clock.Set(last)
+		emit(r.snapshot(count))
+	}
+
+	var errs []error
+	for _, source := range sources {
+		if source.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.path, source.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// snapshot returns the routing state at the replay clock
+func (r *Replayer) snapshot(updates int) *ReplayStep {
+	return &ReplayStep{
+		Time:     r.clock.Now().UTC(),
+		Updates:  updates,
+		ASNs:     r.client.CheckConnectivity(),
+		Prefixes: r.client.PrefixStatuses(),
+	}
+}
+
+// replaySource reads one dump in the background, a record ahead of the merge
+type replaySource struct {
+	path    string
+	updates chan *RISUpdateMessage
+	head    *RISUpdateMessage // Next record, nil if not read yet
+	err     error             // Read error, set before updates is closed
+}
+
+func (s *replaySource) read() {
+	defer close(s.updates)
+	f, err := os.Open(s.path)
+	if err != nil {
+		s.err = err
+		return
+	}
+	defer f.Close()
+	body, err := decompressMRT(f, s.path)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.err = readMRT(body, "", func(update *RISUpdateMessage) {
+		s.updates <- update
+	})
+}
+
+// peek reads the next record into head; false once the dump is exhausted
+func (s *replaySource) peek() bool {
+	if s.head == nil {
+		update, ok := <-s.updates
+		if !ok {
+			return false
+		}
+		s.head = update
+	}
+	return true
+}
+
+// decompressMRT unpacks a dump by the extension of its name (.gz, .bz2)
+func decompressMRT(r io.Reader, name string) (io.Reader, error) {
+	switch {
+	case strings.HasSuffix(name, ".bz2"):
+		return bzip2.NewReader(r), nil
+	case strings.HasSuffix(name, ".gz"):
+		return gzip.NewReader(r)
+	}
+	return r, nil
+}
@@ -0,0 +1,214 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// RIPEstat settings
+const (
+	defaultRIPEstatURL        = "https://stat.ripe.net/data"
+	defaultRIPEstatInterval   = 15 * time.Minute
+	defaultRIPEstatQuietAfter = 5 * time.Minute
+	defaultRIPEstatMinPeers   = 10
+	ripestatStaleAfter        = 3 // Fetch intervals after which a visibility no longer decides
+)
+
+// SourceRIPEstat marks ASN statuses decided by RIPEstat instead of RIS Live
+const SourceRIPEstat = "ripestat"
+
+// RIPEstatMonitor polls the routing status of the monitored ASNs on RIPEstat,
+// a second BGP data source: while RIS Live is down or quiet, its visibility
+// counts decide the ASN connectivity instead of every ASN going stale
+type RIPEstatMonitor struct {
+	base       string
+	interval   time.Duration
+	quietAfter time.Duration
+	minPeers   int
+	client     *http.Client
+
+	mu         sync.Mutex
+	visibility map[string]*models.ASNVisibility // Last routing status by ASN
+	fallback   bool                             // Whether RIPEstat decided in the last check
+	lastErr    error
+}
+
+// NewRIPEstatMonitor creates the RIPEstat client of the config (nil if ripestat is not set)
+func NewRIPEstatMonitor(cfg *config.Config) *RIPEstatMonitor {
+	settings := cfg.RIPEstat
+	if settings == nil {
+		return nil
+	}
+	r := &RIPEstatMonitor{
+		base:       strings.TrimSuffix(settings.URL, "/"),
+		interval:   defaultRIPEstatInterval,
+		quietAfter: defaultRIPEstatQuietAfter,
+		minPeers:   settings.MinPeers,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		visibility: make(map[string]*models.ASNVisibility),
+	}
+	if r.base == "" {
+		r.base = defaultRIPEstatURL
+	}
+	if d, err := time.ParseDuration(settings.Interval); err == nil && d > 0 {
+		r.interval = d
+	}
+	if d, err := time.ParseDuration(settings.QuietAfter); err == nil && d > 0 {
+		r.quietAfter = d
+	}
+	if r.minPeers <= 0 {
+		r.minPeers = defaultRIPEstatMinPeers
+	}
+	return r
+}
+
+// Fetch updates the routing status of the ASNs, one request each
+func (r *RIPEstatMonitor) Fetch(ctx context.Context, asns []string) error {
+	sort.Strings(asns)
+	var failed int
+	var lastErr error
+	for _, asn := range asns {
+		visibility, err := r.routingStatus(ctx, asn)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failed++
+			lastErr = fmt.Errorf("%s: %w", asn, err)
+			continue
+		}
+		r.mu.Lock()
+		r.visibility[asn] = visibility
+		r.mu.Unlock()
+	}
+	if failed > 0 {
+		lastErr = fmt.Errorf("%d of %d ASNs failed, last %w", failed, len(asns), lastErr)
+		log.Printf("⚠️  RIPEstat fetch failed: %v", lastErr)
+	}
+	r.mu.Lock()
+	r.lastErr = lastErr
+	r.mu.Unlock()
+	return lastErr
+}
+
+// routingStatus fetches the visibility and announced prefixes of an ASN
+func (r *RIPEstatMonitor) routingStatus(ctx context.Context, asn string) (*models.ASNVisibility, error) {
+	query := url.Values{"resource": {asn}, "sourceapp": {"netblocks"}}
+	req, err := http.NewRequestWithContext(ctx, "GET", r.base+"/routing-status/data.json?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("RIPEstat API status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	type family struct {
+		PeersSeeing int `json:"ris_peers_seeing"`
+		TotalPeers  int `json:"total_ris_peers"`
+	}
+	var payload struct {
+		Status string `json:"status"`
+		Data   struct {
+			Visibility struct {
+				V4 family `json:"v4"`
+				V6 family `json:"v6"`
+			} `json:"visibility"`
+			AnnouncedSpace struct {
+				V4 struct {
+					Prefixes int `json:"prefixes"`
+				} `json:"v4"`
+				V6 struct {
+					Prefixes int `json:"prefixes"`
+				} `json:"v6"`
+			} `json:"announced_space"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode RIPEstat response: %w", err)
+	}
+	if payload.Status != "ok" {
+		return nil, fmt.Errorf("RIPEstat status %q", payload.Status)
+	}
+
+	data := payload.Data
+	seen := data.Visibility.V4
+	if data.Visibility.V6.PeersSeeing > seen.PeersSeeing {
+		seen = data.Visibility.V6
+	}
+	return &models.ASNVisibility{
+		PeersSeeing: seen.PeersSeeing,
+		TotalPeers:  seen.TotalPeers,
+		Prefixes:    data.AnnouncedSpace.V4.Prefixes + data.AnnouncedSpace.V6.Prefixes,
+		CheckedAt:   time.Now(),
+	}, nil
+}
+
+// Err returns the error of the last fetch
+func (r *RIPEstatMonitor) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// apply attaches the RIPEstat visibility to the ASN statuses of RIS Live and,
+// while the feed has been quiet for quietAfter, lets it decide Connected
+func (r *RIPEstatMonitor) apply(statuses map[string]*models.ASNStatus, quietFor time.Duration, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quiet := quietFor >= r.quietAfter
+	if quiet != r.fallback {
+		if quiet {
+			log.Printf("⚠️  No RIS Live update for %s - ASN connectivity from RIPEstat visibility", quietFor.Round(time.Second))
+		} else {
+			log.Printf("✅ RIS Live updates again - ASN connectivity from RIS Live")
+		}
+		r.fallback = quiet
+	}
+
+	for asn, status := range statuses {
+		visibility, ok := r.visibility[asn]
+		if !ok {
+			continue
+		}
+		copied := *visibility
+		status.Visibility = &copied
+		if !quiet || now.Sub(visibility.CheckedAt) > ripestatStaleAfter*r.interval {
+			continue
+		}
+		status.Connected = visibility.Prefixes > 0 && visibility.PeersSeeing >= r.minPeers
+		status.Source = SourceRIPEstat
+		if status.Connected && visibility.CheckedAt.After(status.LastSeen) {
+			status.LastSeen = visibility.CheckedAt
+		}
+	}
+}
+
+// fetchRIPEstat fetches the routing status of every ASN followed on RIS Live
+func (m *Monitor) fetchRIPEstat(ctx context.Context) error {
+	statuses := m.bgpClient.GetASNStatuses()
+	asns := make([]string, 0, len(statuses))
+	for asn := range statuses {
+		asns = append(asns, asn)
+	}
+	return m.ripestat.Fetch(ctx, asns)
+}
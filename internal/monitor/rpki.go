@@ -0,0 +1,234 @@
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// RPKI settings
+const (
+	defaultRPKIURL      = "https://rpki.cloudflare.com/rpki.json"
+	defaultRPKIInterval = time.Hour
+	rpkiStaleAfter      = 6         // Fetch intervals after which the ROAs no longer decide
+	rpkiMaxInvalids     = 10        // Invalid prefixes listed per ASN
+	rpkiMaxBody         = 256 << 20 // Largest export read (the full table is about 50 MB)
+)
+
+// RPKIValidator validates the origin of the announcements of the monitored
+// ASNs and prefixes against the ROAs of an RPKI validator's JSON export:
+// during a disruption, an invalid origin tells a hijack or spoofed
+// announcement apart from legitimate re-routing
+type RPKIValidator struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	mu        sync.RWMutex
+	roas      map[netip.Prefix][]roa // By ROA prefix
+	count     int                    // ROAs in roas
+	fetchedAt time.Time
+	lastErr   error
+}
+
+// roa is a validated ROA payload: the origin ASN authorized for a prefix and
+// its more specifics up to maxLength
+type roa struct {
+	asn       uint32
+	maxLength int
+}
+
+// NewRPKIValidator creates the RPKI validator of the config (nil if rpki is not set)
+func NewRPKIValidator(cfg *config.Config) *RPKIValidator {
+	settings := cfg.RPKI
+	if settings == nil {
+		return nil
+	}
+	v := &RPKIValidator{
+		url:      settings.URL,
+		interval: defaultRPKIInterval,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+	if v.url == "" {
+		v.url = defaultRPKIURL
+	}
+	if d, err := time.ParseDuration(settings.Interval); err == nil && d > 0 {
+		v.interval = d
+	}
+	return v
+}
+
+// Fetch downloads the ROAs, keeping the previous ones if it fails
+func (v *RPKIValidator) Fetch(ctx context.Context) error {
+	roas, count, err := v.download(ctx)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.lastErr = err
+	if err != nil {
+		log.Printf("⚠️  RPKI fetch failed: %v", err)
+		return err
+	}
+	v.roas, v.count = roas, count
+	v.fetchedAt = time.Now()
+	log.Printf("✅ Fetched %d RPKI ROAs", count)
+	return nil
+}
+
+func (v *RPKIValidator) download(ctx context.Context) (map[netip.Prefix][]roa, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", v.url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("RPKI export status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		ROAs []struct {
+			Prefix    string          `json:"prefix"`
+			MaxLength int             `json:"maxLength"`
+			ASN       json.RawMessage `json:"asn"` // 13335 (Cloudflare) or "AS13335" (RIPE NCC validator, Routinator)
+		} `json:"roas"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, rpkiMaxBody)).Decode(&payload); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode RPKI export: %w", err)
+	}
+	if len(payload.ROAs) == 0 {
+		return nil, 0, fmt.Errorf("RPKI export has no ROAs")
+	}
+
+	roas := make(map[netip.Prefix][]roa, len(payload.ROAs))
+	count := 0
+	for _, entry := range payload.ROAs {
+		prefix, err := netip.ParsePrefix(entry.Prefix)
+		if err != nil {
+			continue
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.Trim(string(entry.ASN), `"`), "AS"), 10, 32)
+		if err != nil {
+			continue
+		}
+		maxLength := entry.MaxLength
+		if maxLength < prefix.Bits() {
+			maxLength = prefix.Bits()
+		}
+		prefix = prefix.Masked()
+		roas[prefix] = append(roas[prefix], roa{asn: uint32(asn), maxLength: maxLength})
+		count++
+	}
+	return roas, count, nil
+}
+
+// Count returns how many ROAs the last successful fetch had
+func (v *RPKIValidator) Count() int {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.count
+}
+
+// Err returns the error of the last fetch
+func (v *RPKIValidator) Err() error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.lastErr
+}
+
+// validate returns the RPKI state of a route to prefix from origin (e.g.
+// AS58224) as in RFC 6811, "" if the prefix or origin cannot be parsed
+// (called with mu held)
+func (v *RPKIValidator) validate(prefix, origin string) string {
+	route, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return ""
+	}
+	asn, err := strconv.ParseUint(strings.TrimPrefix(origin, "AS"), 10, 32)
+	if err != nil {
+		return ""
+	}
+	route = route.Masked()
+
+	covered := false
+	for bits := route.Bits(); bits >= 0; bits-- {
+		covering, _ := route.Addr().Prefix(bits)
+		for _, r := range v.roas[covering] {
+			covered = true
+			if r.asn == uint32(asn) && route.Bits() <= r.maxLength && r.asn != 0 {
+				return models.RPKIValid
+			}
+		}
+	}
+	if covered {
+		return models.RPKIInvalid
+	}
+	return models.RPKINotFound
+}
+
+// apply attaches the RPKI validity of their announcements to the ASN statuses
+// and of their last origin to the prefix statuses, unless the ROAs are missing or stale
+func (v *RPKIValidator) apply(statuses map[string]*models.ASNStatus, announcements map[string]string, prefixes map[string]*models.PrefixStatus, now time.Time) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.roas == nil || now.Sub(v.fetchedAt) > rpkiStaleAfter*v.interval {
+		return
+	}
+
+	summaries := make(map[string]*models.RPKISummary)
+	for prefix, origin := range announcements {
+		status, ok := statuses[origin]
+		if !ok {
+			continue
+		}
+		summary, ok := summaries[origin]
+		if !ok {
+			summary = &models.RPKISummary{CheckedAt: v.fetchedAt}
+			summaries[origin] = summary
+			status.RPKI = summary
+		}
+		switch v.validate(prefix, origin) {
+		case models.RPKIValid:
+			summary.Valid++
+		case models.RPKIInvalid:
+			summary.Invalid++
+			summary.Invalids = append(summary.Invalids, prefix)
+		case models.RPKINotFound:
+			summary.NotFound++
+		}
+	}
+	for _, summary := range summaries {
+		sort.Strings(summary.Invalids)
+		if len(summary.Invalids) > rpkiMaxInvalids {
+			summary.Invalids = summary.Invalids[:rpkiMaxInvalids]
+		}
+	}
+
+	for _, status := range prefixes {
+		if status.OriginRoute != "" && status.Announced {
+			status.RPKI = v.validate(status.OriginRoute, status.Origin)
+		}
+	}
+}
+
+// CheckRPKI fetches the ROAs once and returns how many there are (for doctor)
+func (v *RPKIValidator) CheckRPKI(ctx context.Context) (int, error) {
+	_, count, err := v.download(ctx)
+	return count, err
+}
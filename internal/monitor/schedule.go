@@ -0,0 +1,169 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/clock"
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Default fetch cadences of the data sources; DNS follows the monitoring interval
+const (
+	defaultTrafficInterval    = 10 * time.Minute
+	defaultASNTrafficInterval = 15 * time.Minute
+)
+
+// Default bounds of the adaptive intervals
+const (
+	defaultMinInterval           = time.Minute
+	defaultMinTrafficInterval    = 5 * time.Minute
+	defaultMinASNTrafficInterval = 5 * time.Minute
+	defaultMinBGPStale           = 10 * time.Minute
+)
+
+// schedule is how often the monitor fetches each data source; cycles read
+// what the last fetch cached
+type schedule struct {
+	traffic    time.Duration // Cloudflare Radar country traffic
+	asnTraffic time.Duration // Cloudflare Radar traffic per ASN
+	dns        time.Duration // Checks of all DNS servers
+
+	asnStale          time.Duration            // Silence before an ASN counts as offline
+	asnStaleOverrides map[string]time.Duration // asnStale of single ASNs
+
+	// Shortest intervals during incidents (adaptive_intervals)
+	minCycle      time.Duration // Monitoring cycle and DNS checks
+	minTraffic    time.Duration
+	minASNTraffic time.Duration
+	minBGPStale   time.Duration // Silence before an ASN counts as offline
+}
+
+// newSchedule reads the per-source intervals of the config
+func newSchedule(cfg *config.Config) (schedule, error) {
+	s := schedule{traffic: defaultTrafficInterval, asnTraffic: defaultASNTrafficInterval, dns: cfg.Interval, asnStale: asnStaleAfter,
+		minCycle: defaultMinInterval, minTraffic: defaultMinTrafficInterval, minASNTraffic: defaultMinASNTrafficInterval, minBGPStale: defaultMinBGPStale}
+	for _, source := range []struct {
+		name, value string
+		interval    *time.Duration
+	}{
+		{"traffic_interval", cfg.TrafficInterval, &s.traffic},
+		{"asn_traffic_interval", cfg.ASNTrafficInterval, &s.asnTraffic},
+		{"dns_interval", cfg.DNSInterval, &s.dns},
+		{"asn_stale_after", cfg.ASNStaleAfter, &s.asnStale},
+	} {
+		if source.value == "" {
+			continue
+		}
+		interval, err := time.ParseDuration(source.value)
+		if err != nil || interval < time.Minute {
+			return schedule{}, fmt.Errorf("invalid %s %q (at least 1m)", source.name, source.value)
+		}
+		*source.interval = interval
+	}
+	if s.dns <= 0 {
+		s.dns = 5 * time.Minute
+	}
+	for asn, value := range cfg.ASNStaleOverrides {
+		if !strings.HasPrefix(asn, "AS") {
+			return schedule{}, fmt.Errorf("invalid asn_stale_overrides key %q (use e.g. AS44244)", asn)
+		}
+		stale, err := time.ParseDuration(value)
+		if err != nil || stale < time.Minute {
+			return schedule{}, fmt.Errorf("invalid asn_stale_overrides %s %q (at least 1m)", asn, value)
+		}
+		if s.asnStaleOverrides == nil {
+			s.asnStaleOverrides = make(map[string]time.Duration)
+		}
+		s.asnStaleOverrides[asn] = stale
+	}
+	if adaptive := cfg.AdaptiveIntervals; adaptive != nil {
+		for _, bound := range []struct {
+			value string
+			min   *time.Duration
+		}{
+			{adaptive.MinInterval, &s.minCycle},
+			{adaptive.MinTrafficInterval, &s.minTraffic},
+			{adaptive.MinASNTrafficInterval, &s.minASNTraffic},
+			{adaptive.MinBGPStale, &s.minBGPStale},
+		} {
+			if d, err := time.ParseDuration(bound.value); err == nil && d > 0 {
+				*bound.min = d
+			}
+		}
+	}
+	return s, nil
+}
+
+// retune wakes the fetch loops to read their intervals again when a campaign
+// starts or ends or the adaptive intervals change
+type retune struct {
+	mu      sync.Mutex
+	changed chan struct{} // Closed at the next change
+}
+
+func newRetune() *retune {
+	return &retune{changed: make(chan struct{})}
+}
+
+// wait returns a channel closed at the next change
+func (r *retune) wait() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.changed
+}
+
+// notify wakes the loops waiting for a change
+func (r *retune) notify() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// interval returns how often a source is fetched: every base, more often
+// during an incident (adaptive_intervals, not below floor) and while a campaign
+// whose profile sets a shorter interval (pick) runs
+func (m *Monitor) interval(base, floor time.Duration, pick func(*campaignProfile) time.Duration) func() time.Duration {
+	return func() time.Duration {
+		d := m.adaptive.shorten(base, floor)
+		if _, profile := m.campaigns.running(); profile != nil {
+			if short := pick(profile); short > 0 && short < d {
+				d = short
+			}
+		}
+		return d
+	}
+}
+
+// runEvery calls fetch every interval until ctx is done; the interval is read
+// again at every retune. A fetch in progress when ctx is done runs to
+// completion (see Drain), and a panicking fetch is reported under name and
+// retried at the next interval
+func (m *Monitor) runEvery(ctx context.Context, name string, interval func() time.Duration, fetch func(ctx context.Context)) {
+	for {
+		changed := m.retune.wait()
+		ticker := m.clock.NewTicker(interval())
+		if !m.tick(ctx, ticker, changed, name, fetch) {
+			return
+		}
+	}
+}
+
+// tick runs fetch on every tick until the next retune (true) or ctx is done (false)
+func (m *Monitor) tick(ctx context.Context, ticker clock.Ticker, changed <-chan struct{}, name string, fetch func(ctx context.Context)) bool {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-changed:
+			return true
+		case <-ticker.C():
+			m.work.run(name, fetch)
+		}
+	}
+}
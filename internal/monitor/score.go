@@ -0,0 +1,71 @@
+package monitor
+
+import (
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// CalculateNationalScore combines traffic level, ASN connectivity and DNS
+// availability into a single 0-100 connectivity score
+// Traffic is weighted highest since it reflects what users actually experience;
+// when traffic data is unavailable the score falls back to ASN and DNS only.
+// RIPE Atlas anchors, measured from vantage points we don't run, count for a
+// tenth when they are followed
+func CalculateNationalScore(result *models.MonitoringResult) float64 {
+	if result == nil {
+		return 0
+	}
+
+	asnPct := connectedPercent(result)
+	dnsPct := alivePercent(result)
+
+	score := 0.5*asnPct + 0.5*dnsPct
+	if result.TrafficData != nil {
+		score = 0.4*result.TrafficData.CurrentLevel + 0.3*asnPct + 0.3*dnsPct
+	}
+	if len(result.AtlasAnchors) > 0 {
+		score = 0.9*score + 0.1*atlasReachablePercent(result.AtlasAnchors)
+	}
+	return score
+}
+
+// connectedPercent returns the percentage of monitored ASNs currently connected
+func connectedPercent(result *models.MonitoringResult) float64 {
+	if len(result.ASNStatuses) == 0 {
+		return 0
+	}
+	connected := 0
+	for _, status := range result.ASNStatuses {
+		if status.Connected {
+			connected++
+		}
+	}
+	return float64(connected) / float64(len(result.ASNStatuses)) * 100.0
+}
+
+// alivePercent returns the percentage of monitored DNS servers currently alive
+func alivePercent(result *models.MonitoringResult) float64 {
+	if len(result.DNSStatuses) == 0 {
+		return 0
+	}
+	alive := 0
+	for _, status := range result.DNSStatuses {
+		if status.Alive {
+			alive++
+		}
+	}
+	return float64(alive) / float64(len(result.DNSStatuses)) * 100.0
+}
+
+// ScoreStatus returns a status label and emoji for a national score
+func ScoreStatus(score float64) (string, string) {
+	switch {
+	case score >= 80:
+		return "Normal", "🟢"
+	case score >= 50:
+		return "Degraded", "🟡"
+	case score >= 20:
+		return "Severe Disruption", "🟠"
+	default:
+		return "Shutdown", "🔴"
+	}
+}
@@ -0,0 +1,185 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// sloMinSamples is how many checks an SLO window needs before it is judged
+const sloMinSamples = 12
+
+// SLOTracker evaluates the slos against the hourly availability history and
+// raises an event when one is breached or met again
+type SLOTracker struct {
+	slos     []config.SLO
+	windows  []time.Duration
+	breached map[string]bool // By SLO name
+}
+
+// NewSLOTracker creates the tracker of the configured slos (nil if there are none)
+func NewSLOTracker(cfg *config.Config) *SLOTracker {
+	if len(cfg.SLOs) == 0 {
+		return nil
+	}
+	t := &SLOTracker{breached: make(map[string]bool)}
+	for _, slo := range cfg.SLOs {
+		window, _ := slo.WindowDuration() // Checked by config.LoadConfig
+		t.slos = append(t.slos, slo)
+		t.windows = append(t.windows, window)
+	}
+	return t
+}
+
+// Evaluate returns how the targets of each SLO fared over its window: the
+// targets are the DNS servers or ASNs of the result matching the SLO, their
+// samples come from the history
+func (t *SLOTracker) Evaluate(store *history.Store, result *models.MonitoringResult, now time.Time) []models.SLOStatus {
+	if t == nil || store == nil || result == nil {
+		return nil
+	}
+	statuses := make([]models.SLOStatus, 0, len(t.slos))
+	for i, slo := range t.slos {
+		since := now.UTC().Truncate(time.Hour).Add(-t.windows[i])
+		var targets map[string][]history.Bucket
+		var keys []string
+		if slo.Target == config.SLOTargetASN {
+			targets = store.ASNAvailability(since)
+			keys = sloASNs(slo, result.ASNStatuses)
+		} else {
+			targets = store.DNSAvailability(since)
+			keys = sloDNSServers(slo, result.DNSStatuses)
+		}
+
+		status := models.SLOStatus{
+			Name:                  slo.Name,
+			Provider:              slo.Provider,
+			Target:                slo.Target,
+			Window:                slo.Window,
+			Targets:               len(keys),
+			AvailabilityObjective: slo.Availability,
+			LatencyObjective:      slo.LatencyMS,
+		}
+		if status.Target == "" {
+			status.Target = config.SLOTargetDNS
+		}
+		if status.Window == "" {
+			status.Window = "7d"
+		}
+
+		// Latency is the mean over the answered checks that have one
+		up, latencyWeight := 0, 0
+		var latencySum float64
+		for _, key := range keys {
+			for _, b := range targets[key] {
+				status.Samples += b.Total
+				up += b.Up
+				if b.Latency > 0 {
+					latencySum += b.Latency * float64(b.Up)
+					latencyWeight += b.Up
+				}
+			}
+		}
+		if status.Samples < sloMinSamples {
+			status.Pending = true
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Availability = 100 * float64(up) / float64(status.Samples)
+		if latencyWeight > 0 {
+			status.LatencyMS = latencySum / float64(latencyWeight)
+		}
+		if slo.Availability > 0 && status.Availability < slo.Availability {
+			status.Breached = append(status.Breached, "availability")
+		}
+		if slo.LatencyMS > 0 && status.LatencyMS > float64(slo.LatencyMS) {
+			status.Breached = append(status.Breached, "latency")
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Observe returns a warning when an SLO is breached and an info event once it
+// is met again; pending SLOs keep their state
+func (t *SLOTracker) Observe(statuses []models.SLOStatus, now time.Time) []models.Event {
+	if t == nil {
+		return nil
+	}
+	var events []models.Event
+	for _, status := range statuses {
+		if status.Pending {
+			continue
+		}
+		breached := len(status.Breached) > 0
+		if breached == t.breached[status.Name] {
+			continue
+		}
+		t.breached[status.Name] = breached
+		if breached {
+			events = append(events, models.Event{Timestamp: now, Kind: "slo", Target: status.Name, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("SLO %s breached: %s", status.Name, DescribeSLO(status))})
+		} else {
+			events = append(events, models.Event{Timestamp: now, Kind: "slo", Target: status.Name, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("SLO %s met again: %s", status.Name, DescribeSLO(status))})
+		}
+	}
+	return events
+}
+
+// DescribeSLO summarizes the measured values of an SLO against its objectives,
+// e.g. "availability 98.20% (objective 99%) over 7d on 4 DNS servers"
+func DescribeSLO(status models.SLOStatus) string {
+	var parts []string
+	if status.AvailabilityObjective > 0 {
+		parts = append(parts, fmt.Sprintf("availability %.2f%% (objective %g%%)", status.Availability, status.AvailabilityObjective))
+	}
+	if status.LatencyObjective > 0 {
+		parts = append(parts, fmt.Sprintf("latency %.0fms (objective %dms)", status.LatencyMS, status.LatencyObjective))
+	}
+	targets := "DNS servers"
+	if status.Target == config.SLOTargetASN {
+		targets = "ASNs"
+	}
+	return fmt.Sprintf("%s over %s on %d %s", strings.Join(parts, ", "), status.Window, status.Targets, targets)
+}
+
+// sloDNSServers returns the keys of the DNS servers of the SLO's provider and type
+func sloDNSServers(slo config.SLO, statuses map[string]*models.DNSStatus) []string {
+	provider := strings.ToLower(slo.Provider)
+	var keys []string
+	for key, status := range statuses {
+		name := strings.ToLower(dnsProvider(status.Name))
+		if name != provider && !strings.HasPrefix(name, provider+" ") {
+			continue
+		}
+		if slo.DNSType != "" && status.Type != slo.DNSType && status.Type != "both" {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sloASNs returns the ASNs of an SLO: the listed ones, or those whose name
+// contains the provider
+func sloASNs(slo config.SLO, statuses map[string]*models.ASNStatus) []string {
+	if len(slo.ASNs) > 0 {
+		return slo.ASNs
+	}
+	provider := strings.ToLower(slo.Provider)
+	var asns []string
+	for asn, status := range statuses {
+		if strings.Contains(strings.ToLower(status.Name), provider) {
+			asns = append(asns, asn)
+		}
+	}
+	sort.Strings(asns)
+	return asns
+}
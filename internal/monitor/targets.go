@@ -0,0 +1,212 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/targetlist"
+)
+
+const (
+	defaultTargetListInterval   = time.Hour
+	defaultASNDiscoveryInterval = 24 * time.Hour
+	targetListTimeout           = 30 * time.Second
+	asnNamesInterval            = 24 * time.Hour // How often ASNs new to the lists or with an expired name are looked up
+)
+
+// targetLists are the remote lists replacing the configured ASNs and DNS servers
+type targetLists struct {
+	asns          *targetlist.List      // nil if asn_list_url is not set
+	dns           *targetlist.List      // nil if dns_list_url is not set
+	discovery     *targetlist.Discovery // nil if asn_discovery is not set
+	discoverEvery time.Duration
+	discoverAt    time.Time // When the ASNs are discovered next
+	interval      time.Duration
+}
+
+// newTargetLists returns the remote lists of the config, or nil if none is set
+func newTargetLists(cfg *config.Config) (*targetLists, error) {
+	if cfg.ASNListURL == "" && cfg.DNSListURL == "" && cfg.ASNDiscovery == nil {
+		return nil, nil
+	}
+	lists := &targetLists{interval: defaultTargetListInterval}
+	if cfg.ASNDiscovery != nil {
+		lists.discovery = targetlist.NewDiscovery(cfg.ASNDiscovery, cfg.Country, cfg.IranASNs)
+		lists.discoverEvery = defaultASNDiscoveryInterval
+		if d, err := time.ParseDuration(cfg.ASNDiscovery.Interval); err == nil && d > 0 {
+			lists.discoverEvery = d
+		}
+	}
+	if cfg.ASNListURL == "" && cfg.DNSListURL == "" {
+		return lists, nil
+	}
+	if cfg.TargetListInterval != "" {
+		interval, err := time.ParseDuration(cfg.TargetListInterval)
+		if err != nil || interval < time.Minute {
+			return nil, fmt.Errorf("invalid target_list_interval %q (at least 1m)", cfg.TargetListInterval)
+		}
+		lists.interval = interval
+	}
+	if cfg.TargetListKey == "" {
+		log.Printf("⚠️  No target_list_key set - remote target lists are used unsigned")
+	}
+	if cfg.ASNListURL != "" {
+		lists.asns = targetlist.New(cfg.ASNListURL, cfg.TargetListKey)
+	}
+	if cfg.DNSListURL != "" {
+		lists.dns = targetlist.New(cfg.DNSListURL, cfg.TargetListKey)
+	}
+	return lists, nil
+}
+
+// startupASNs discovers the ASNs a first time, falling back to the cached
+// ones (nil: neither is available, the configured list stays)
+func (t *targetLists) startupASNs(ctx context.Context) []string {
+	t.discoverAt = time.Now().Add(t.discoverEvery)
+	asns, err := t.discovery.Discover(ctx)
+	if asns != nil {
+		if err != nil {
+			log.Printf("⚠️  Discovered ASNs not cached: %v", err)
+		}
+		log.Printf("🎯 Using %d ASNs discovered on RIPEstat", len(asns))
+		return asns
+	}
+	cached, discoveredAt, cacheErr := t.discovery.Cached()
+	if cacheErr != nil {
+		log.Printf("⚠️  ASN discovery failed (%v) and no cache (%v) - using the configured ASNs", err, cacheErr)
+		return nil
+	}
+	t.discovery.Use(cached)
+	log.Printf("⚠️  ASN discovery failed (%v) - using %d ASNs cached in %s, discovered %s", err, len(cached), t.discovery.CachePath(), discoveredAt.Format(time.RFC3339))
+	return cached
+}
+
+// fetchASNs returns the remote or, when due, the discovered ASN list; nil if
+// it is unchanged or unavailable
+func (t *targetLists) fetchASNs(ctx context.Context) []string {
+	if t.discovery != nil {
+		if time.Now().Before(t.discoverAt) {
+			return nil
+		}
+		t.discoverAt = time.Now().Add(t.discoverEvery)
+		asns, err := t.discovery.Discover(ctx)
+		if err != nil {
+			log.Printf("⚠️  ASN discovery on RIPEstat failed: %v", err)
+		}
+		return asns
+	}
+	if t.asns == nil {
+		return nil
+	}
+	data, err := t.asns.Fetch(ctx)
+	if err == nil && data != nil {
+		var asns []string
+		if asns, err = targetlist.ParseASNs(data); err == nil {
+			return asns
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️  Keeping current ASNs - remote list %s unusable: %v", t.asns.URL(), err)
+	}
+	return nil
+}
+
+// fetchDNS returns the remote DNS server list, or nil if it is unchanged or unavailable
+func (t *targetLists) fetchDNS(ctx context.Context) []config.DNSServer {
+	if t.dns == nil {
+		return nil
+	}
+	data, err := t.dns.Fetch(ctx)
+	if err == nil && data != nil {
+		var servers []config.DNSServer
+		if servers, err = targetlist.ParseDNSServers(data); err == nil {
+			return servers
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️  Keeping current DNS servers - remote list %s unusable: %v", t.dns.URL(), err)
+	}
+	return nil
+}
+
+// refreshTargets checks the remote lists every interval and applies changes
+// to the BGP subscriptions and the DNS monitor
+func (m *Monitor) refreshTargets(ctx context.Context) {
+	ticker := time.NewTicker(m.targets.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, targetListTimeout)
+		asns, servers := m.targets.fetchASNs(fetchCtx), m.targets.fetchDNS(fetchCtx)
+		cancel()
+		if asns != nil {
+			m.setASNs(asns)
+		}
+		if servers != nil {
+			servers = reachableDNSServers(m.config, servers)
+			m.setDNSServers(servers)
+			log.Printf("🎯 DNS server list updated: %d servers", len(servers))
+		}
+	}
+}
+
+// setASNs subscribes to new ASNs and unsubscribes from dropped ones, except
+// the extra ASNs of a running campaign
+func (m *Monitor) setASNs(asns []string) {
+	current := m.bgpClient.GetASNStatuses()
+	wanted := make(map[string]bool, len(asns))
+	m.campaigns.mu.Lock()
+	m.campaigns.baseASNs = wanted
+	m.campaigns.mu.Unlock()
+	added, removed := 0, 0
+	for _, asn := range asns {
+		wanted[asn] = true
+		if _, ok := current[asn]; ok {
+			continue
+		}
+		if err := m.bgpClient.SubscribeToASN(asn); err != nil {
+			log.Printf("Warning: Failed to subscribe to ASN %s: %v", asn, err)
+			continue
+		}
+		added++
+	}
+	for asn := range current {
+		if wanted[asn] || m.reference[asn] || m.campaigns.extraASN(asn) {
+			continue
+		}
+		if err := m.bgpClient.UnsubscribeFromASN(asn); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		removed++
+	}
+	if added > 0 || removed > 0 {
+		log.Printf("🎯 ASN list updated: %d added, %d removed (%d ASNs)", added, removed, len(asns))
+	}
+}
+
+// resolveASNNames looks up the names of the monitored ASNs that have none yet
+// or whose name expired
+func (m *Monitor) resolveASNNames(ctx context.Context) (string, error) {
+	statuses := m.bgpClient.GetASNStatuses()
+	asns := make([]string, 0, len(statuses))
+	for asn := range statuses {
+		asns = append(asns, asn)
+	}
+	found, err := m.names.Resolve(ctx, asns)
+	if found > 0 {
+		log.Printf("🏷  Looked up %d ASN names", found)
+	}
+	if err != nil {
+		log.Printf("⚠️  ASN name lookup incomplete: %v", err)
+		return "", err
+	}
+	return fmt.Sprintf("%d names looked up", found), nil
+}
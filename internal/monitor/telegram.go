@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// defaultTelegramEndpoints are the Bot API, the web client, the link domain and
+// the MTProto data centers 1 and 2 the apps connect to
+var defaultTelegramEndpoints = []string{
+	"api.telegram.org:443",
+	"web.telegram.org:443",
+	"t.me:443",
+	"149.154.175.53:443",
+	"149.154.167.51:443",
+}
+
+// defaultTelegramTimeout bounds the connect and handshake of each endpoint
+const defaultTelegramTimeout = 10 * time.Second
+
+// TelegramChecker connects to the Telegram endpoints: from the monitoring host
+// abroad it is a control, from an in-country probe it shows whether Telegram is
+// reachable domestically. Hostnames get a TLS handshake with their name, so SNI
+// filtering shows up as well as blocked addresses
+type TelegramChecker struct {
+	endpoints []string
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	results []*models.TelegramEndpoint
+}
+
+// NewTelegramChecker creates the checker of the config (nil if telegram_check is not set)
+func NewTelegramChecker(cfg *config.Config) *TelegramChecker {
+	settings := cfg.TelegramCheck
+	if settings == nil {
+		return nil
+	}
+	t := &TelegramChecker{endpoints: settings.Endpoints, timeout: defaultTelegramTimeout}
+	if len(t.endpoints) == 0 {
+		t.endpoints = defaultTelegramEndpoints
+	}
+	if d, err := time.ParseDuration(settings.Timeout); err == nil && d > 0 {
+		t.timeout = d
+	}
+	return t
+}
+
+// Check connects to every endpoint in parallel and keeps the outcomes
+func (t *TelegramChecker) Check(ctx context.Context) []*models.TelegramEndpoint {
+	results := make([]*models.TelegramEndpoint, len(t.endpoints))
+	var wg sync.WaitGroup
+	for i, address := range t.endpoints {
+		wg.Add(1)
+		go func(i int, address string) {
+			defer wg.Done()
+			results[i] = t.connect(ctx, address)
+		}(i, address)
+	}
+	wg.Wait()
+
+	reachable := 0
+	for _, result := range results {
+		if result.Reachable {
+			reachable++
+		}
+	}
+	log.Printf("✈️  Telegram endpoints reachable: %d/%d", reachable, len(results))
+
+	t.mu.Lock()
+	t.results = results
+	t.mu.Unlock()
+	return results
+}
+
+// Endpoints returns copies of the outcomes of the last check
+func (t *TelegramChecker) Endpoints() []*models.TelegramEndpoint {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	endpoints := make([]*models.TelegramEndpoint, len(t.results))
+	for i, result := range t.results {
+		copied := *result
+		endpoints[i] = &copied
+	}
+	return endpoints
+}
+
+// connect dials an endpoint and, for a hostname, completes a TLS handshake
+func (t *TelegramChecker) connect(ctx context.Context, address string) *models.TelegramEndpoint {
+	result := &models.TelegramEndpoint{Address: address, CheckedAt: time.Now()}
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	start := time.Now()
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+
+	host, _, _ := net.SplitHostPort(address)
+	if net.ParseIP(host) == nil {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			result.Error = "TLS: " + err.Error()
+			return result
+		}
+	}
+	result.Reachable = true
+	result.Latency = time.Since(start)
+	return result
+}
@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Throttling shows as resolvers that still answer, but slowly: the median DNS
+// latency of a province or provider is compared with its learned baseline while
+// availability stays high
+const (
+	throttleFactor     = 2.0 // Median latency at least this many times the usual one is suspicious
+	throttleClear      = 1.5 // Below this many times the usual latency the suspicion clears
+	throttleMinZ       = 3.0 // Standard deviations (of log latency) a suspicious median must also exceed
+	throttleMinSigma   = 0.1 // Floor of the log latency standard deviation
+	throttleHold       = 3   // Consecutive suspicious cycles before throttling is reported
+	throttleMinAlive   = 0.8 // Below this share of alive servers an outage rather than throttling is going on
+	throttleMinServers = 3   // Groups need this many alive servers for a meaningful median
+)
+
+// latencySeries tracks the log median latency of one group of DNS servers
+type latencySeries struct {
+	baseline
+	suspect  int // Consecutive suspicious cycles
+	alarmed  bool
+	alarmFor int
+}
+
+// observe adds a cycle's median latency and reports whether throttling is
+// suspected (true, false) or cleared (false, true)
+func (s *latencySeries) observe(median time.Duration) (throttled, cleared bool) {
+	value := math.Log(float64(median.Microseconds()) + 1)
+	if s.samples < anomalyWarmup {
+		s.learn(value)
+		return false, false
+	}
+
+	usual := math.Exp(s.mean)
+	ratio := float64(median.Microseconds()+1) / usual
+	if s.alarmed {
+		s.alarmFor++
+		switch {
+		case ratio < throttleClear:
+			s.alarmed, s.suspect = false, 0
+			s.learn(value)
+			return false, true
+		case s.alarmFor >= anomalyRelearnAfter:
+			// Slow resolvers became the norm: learn the new level
+			*s = latencySeries{}
+			s.learn(value)
+		}
+		return false, false
+	}
+
+	sigma := math.Max(math.Sqrt(s.variance), throttleMinSigma)
+	if ratio >= throttleFactor && (value-s.mean)/sigma >= throttleMinZ {
+		s.suspect++
+		if s.suspect >= throttleHold {
+			s.alarmed, s.alarmFor = true, 0
+			return true, false
+		}
+		return false, false
+	}
+	s.suspect = 0
+	s.learn(value)
+	return false, false
+}
+
+// usual returns the baseline latency
+func (s *latencySeries) usual() time.Duration {
+	return time.Duration(math.Exp(s.mean)-1) * time.Microsecond
+}
+
+// ThrottlingDetector raises events when the DNS latency of a province or
+// provider rises well beyond its baseline while its servers stay reachable
+type ThrottlingDetector struct {
+	series map[string]*latencySeries // "province:<name>" or "provider:<name>"
+}
+
+// NewThrottlingDetector creates a detector without history
+func NewThrottlingDetector() *ThrottlingDetector {
+	return &ThrottlingDetector{series: make(map[string]*latencySeries)}
+}
+
+// Observe feeds one cycle's DNS statuses and returns the resulting events
+func (d *ThrottlingDetector) Observe(result *models.MonitoringResult) []models.Event {
+	if result == nil || len(result.DNSStatuses) == 0 {
+		return nil
+	}
+	now := result.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	type group struct {
+		total     int
+		latencies []time.Duration
+	}
+	groups := make(map[string]*group)
+	add := func(key string, status *models.DNSStatus) {
+		g, ok := groups[key]
+		if !ok {
+			g = &group{}
+			groups[key] = g
+		}
+		g.total++
+		if status.Alive && status.ResponseTime > 0 {
+			g.latencies = append(g.latencies, status.ResponseTime)
+		}
+	}
+	for _, status := range result.DNSStatuses {
+		if province := config.GetDNSCity(status.Name); province != "Other" && !strings.Contains(province, ".") {
+			add("province:"+province, status)
+		}
+		if provider := dnsProvider(status.Name); provider != "" {
+			add("provider:"+provider, status)
+		}
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var events []models.Event
+	for _, key := range keys {
+		g := groups[key]
+		alive := len(g.latencies)
+		if alive < throttleMinServers || float64(alive)/float64(g.total) < throttleMinAlive {
+			continue
+		}
+		sort.Slice(g.latencies, func(i, j int) bool { return g.latencies[i] < g.latencies[j] })
+		median := g.latencies[alive/2]
+
+		s, ok := d.series[key]
+		if !ok {
+			s = &latencySeries{}
+			d.series[key] = s
+		}
+		usual := s.usual()
+		throttled, cleared := s.observe(median)
+
+		scope := strings.SplitN(key, ":", 2)[1]
+		switch {
+		case throttled:
+			events = append(events, models.Event{Timestamp: now, Kind: "dns", Target: "latency:" + key, Severity: models.SeverityWarning,
+				Message: fmt.Sprintf("Throttling suspected for %s: median DNS latency %s, usually %s (%d/%d servers alive)",
+					scope, median.Round(time.Millisecond), usual.Round(time.Millisecond), alive, g.total)})
+		case cleared:
+			events = append(events, models.Event{Timestamp: now, Kind: "dns", Target: "latency:" + key, Severity: models.SeverityInfo,
+				Message: fmt.Sprintf("DNS latency for %s is back to normal (median %s)", scope, median.Round(time.Millisecond))})
+		}
+	}
+	return events
+}
+
+// dnsProvider returns the operator part of a DNS server name
+// (e.g. "TCI Recursive DNS (Tehran)" -> "TCI")
+func dnsProvider(name string) string {
+	if idx := strings.Index(name, " ("); idx != -1 {
+		name = name[:idx]
+	}
+	name = strings.TrimSuffix(name, " DNS")
+	name = strings.TrimSuffix(name, " Recursive")
+	return strings.TrimSpace(name)
+}
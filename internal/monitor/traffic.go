@@ -18,14 +18,37 @@ import (
 
 // TrafficMonitor monitors Iran's internet traffic using Cloudflare Radar API
 type TrafficMonitor struct {
-	client           *http.Client
-	lastUpdate       time.Time
-	cachedData       *TrafficData
-	mu               sync.RWMutex
-	baseline         float64
-	cloudflareToken  string  // API Token (preferred)
-	cloudflareEmail  string  // Legacy: API Key email
-	cloudflareKey    string  // Legacy: API Key
+	client          *http.Client
+	lastUpdate      time.Time
+	cachedData      *TrafficData
+	mu              sync.RWMutex
+	baseline        float64
+	cloudflareToken string            // API Token (preferred)
+	cloudflareEmail string            // Legacy: API Key email
+	cloudflareKey   string            // Legacy: API Key
+	credentials     *radarCredentials // Tokens (or the legacy key) used in turn
+	location        string            // Radar location (ISO 3166-1 alpha-2 country code)
+	iso3            string            // Location retried when location returns no data (ISO 3166-1 alpha-3 code, empty: none)
+	thresholds      config.TrafficThresholds
+	radarURL        string        // Base URL of the Cloudflare Radar API
+	cacheFor        time.Duration // How long fetched traffic is served before fetching again
+	cachedASN       []*models.ASTrafficData
+	asnUpdate       time.Time
+	asnCacheFor     time.Duration           // How long fetched ASN traffic is served before fetching again
+	asnLimit        int                     // ASNs requested from Radar
+	asnVariation    int                     // Index of the ASN endpoint variation that last worked
+	series          config.TrafficSeries    // Radar dataset and aggregation interval of the traffic series
+	asnName         func(asn string) string // Display name of an ASN Radar gives none for
+}
+
+// RadarURL is the base URL of the Cloudflare Radar API
+const RadarURL = "https://api.cloudflare.com/client/v4/radar"
+
+// radarDatasets maps the configured datasets to their Radar time series
+var radarDatasets = map[string]string{
+	config.DatasetHTTPRequests: "/http/timeseries?",
+	config.DatasetHTTPBytes:    "/netflows/timeseries?product=HTTP&",
+	config.DatasetNetflows:     "/netflows/timeseries?product=ALL&",
 }
 
 // TrafficData represents Iran's internet traffic statistics
@@ -53,26 +76,87 @@ type CloudflareRadarResponse struct {
 // Accepts either API Token (cloudflareToken) or API Key (cloudflareEmail + cloudflareKey)
 // API Token is preferred for security
 func NewTrafficMonitor(cloudflareToken, cloudflareEmail, cloudflareKey string) *TrafficMonitor {
-	log.Printf("NewTrafficMonitor: token set=%v (len=%d), email set=%v, key set=%v", 
+	log.Printf("NewTrafficMonitor: token set=%v (len=%d), email set=%v, key set=%v",
 		cloudflareToken != "", len(cloudflareToken),
 		cloudflareEmail != "", cloudflareKey != "")
-	
+
 	return &TrafficMonitor{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		baseline:        100.0, // Will be calculated from data
+		client:          radarClient, // Shared connection pool (see radarclient.go)
+		baseline:        100.0,       // Will be calculated from data
 		cloudflareToken: cloudflareToken,
 		cloudflareEmail: cloudflareEmail,
 		cloudflareKey:   cloudflareKey,
+		credentials:     newRadarCredentials([]string{cloudflareToken}, cloudflareEmail, cloudflareKey),
+		location:        "IR",
+		iso3:            "IRN",
+		thresholds:      config.DefaultTrafficThresholds(),
+		radarURL:        RadarURL,
+		cacheFor:        5 * time.Minute,
+		asnCacheFor:     5 * time.Minute,
+		asnLimit:        20,
+		series:          config.TrafficSeries{Dataset: config.DatasetHTTPRequests, Interval: "1h"},
+		asnName:         config.GetASNName,
 	}
 }
 
+// SetCountry sets the Radar location, its ISO3 fallback and the thresholds of
+// the traffic status
+func (tm *TrafficMonitor) SetCountry(location, iso3 string, thresholds config.TrafficThresholds) {
+	tm.location = location
+	tm.iso3 = iso3
+	tm.thresholds = thresholds
+}
+
+// SetASNNames sets how the ASNs Radar gives no name for are named, e.g. with
+// the names of the country's profile (see config.Config.GetASNName)
+func (tm *TrafficMonitor) SetASNNames(name func(asn string) string) {
+	tm.asnName = name
+}
+
+// SetRadarURL replaces the Radar API base URL, e.g. with a server replaying
+// recorded responses
+func (tm *TrafficMonitor) SetRadarURL(url string) {
+	tm.radarURL = url
+}
+
+// SetSeries selects the Radar dataset and aggregation interval of the traffic series
+func (tm *TrafficMonitor) SetSeries(series config.TrafficSeries) {
+	tm.series = series
+}
+
+// seriesURL returns the Radar URL of the traffic series for a location
+// (query is appended, e.g. "dateRange=7d&aggInterval=1h")
+func (tm *TrafficMonitor) seriesURL(location, query string) string {
+	return RadarSeriesURL(tm.radarURL, tm.series.Dataset, location, query)
+}
+
+// RadarSeriesURL returns the URL of the Radar time series of a dataset for a
+// location under a Radar base URL (query is appended as in seriesURL)
+func RadarSeriesURL(radarURL, dataset, location, query string) string {
+	return radarURL + radarDatasets[dataset] + "location=" + location + "&" + query + "&format=json"
+}
+
+// seriesPoints is the number of points of the series covering 24 hours
+func (tm *TrafficMonitor) seriesPoints() int {
+	if tm.series.Interval == "15m" {
+		return 96
+	}
+	return 24
+}
+
+// SetCacheDurations sets how long fetched traffic and ASN traffic are served
+// before a read fetches them again (default: 5 minutes each)
+func (tm *TrafficMonitor) SetCacheDurations(traffic, asnTraffic time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.cacheFor, tm.asnCacheFor = traffic, asnTraffic
+}
+
 // GetTrafficData returns cached or fresh traffic data
 func (tm *TrafficMonitor) GetTrafficData(ctx context.Context) (*TrafficData, error) {
 	tm.mu.RLock()
-	// Return cached data if fresh (less than 5 minutes old)
-	if tm.cachedData != nil && time.Since(tm.lastUpdate) < 5*time.Minute {
+	// Return cached data if fresh
+	if tm.cachedData != nil && time.Since(tm.lastUpdate) < tm.cacheFor {
 		data := tm.cachedData
 		tm.mu.RUnlock()
 		return data, nil
@@ -85,14 +169,21 @@ func (tm *TrafficMonitor) GetTrafficData(ctx context.Context) (*TrafficData, err
 
 // FetchFromCloudflare fetches traffic data from Cloudflare Radar API
 func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData, error) {
-	// Cloudflare Radar API endpoint for Iran HTTP traffic bandwidth
-	// Using timeseries endpoint - returns HTTP request volume/time over time.
+	// Cloudflare Radar time series of the configured dataset (see SetSeries),
+	// HTTP requests by default: /radar/http/timeseries (NOT timeseries_groups).
 	// Request 7d to maximize data availability, then slice last 24h locally.
-	// The correct endpoint is /radar/http/timeseries (NOT timeseries_groups).
 	// dateRange: valid values are "1d", "7d", "14d", "24h", etc.
-	// location: IR for Iran (fallback to IRN if IR returns no data)
-	// aggInterval: aggregation interval like "1h", "1d", etc.
-	url := "https://api.cloudflare.com/client/v4/radar/http/timeseries?location=IR&dateRange=7d&aggInterval=1h&format=json"
+	// location: the country's ISO2 code, e.g. IR for Iran (fallback to ISO3 if it returns no data)
+	// aggInterval: aggregation interval, "15m" or "1h"
+	url := tm.seriesURL(tm.location, "dateRange=7d&aggInterval="+tm.series.Interval)
+
+	// Serve cached data longer while the API budget runs low
+	tm.mu.RLock()
+	cached, fetched, cacheFor := tm.cachedData, tm.lastUpdate, tm.cacheFor
+	tm.mu.RUnlock()
+	if skip, err := radarQuota.skip("traffic", cached != nil, fetched, cacheFor); skip {
+		return cached, err
+	}
 
 	log.Printf("Fetching Cloudflare Radar data from: %s", url)
 
@@ -103,23 +194,21 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 	}
 
 	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
-	
-	// Add Cloudflare authentication headers
+
+	// Add Cloudflare authentication headers (see radarcreds.go)
 	authMethod := "none"
 	if tm.cloudflareToken != "" {
-		req.Header.Set("Authorization", "Bearer "+tm.cloudflareToken)
+		usable, total := tm.credentials.usable()
 		authMethod = "Bearer Token"
-		log.Printf("Using Cloudflare Bearer Token authentication (token length: %d)", len(tm.cloudflareToken))
+		log.Printf("Using Cloudflare Bearer Token authentication (%d of %d tokens usable)", usable, total)
 	} else if tm.cloudflareEmail != "" && tm.cloudflareKey != "" {
-		req.Header.Set("X-Auth-Email", tm.cloudflareEmail)
-		req.Header.Set("X-Auth-Key", tm.cloudflareKey)
 		authMethod = "API Key"
 		log.Printf("Using Cloudflare API Key authentication (email: %s)", tm.cloudflareEmail)
 	} else {
 		log.Printf("WARNING: No Cloudflare credentials available - request will likely fail")
 	}
 
-	resp, err := tm.client.Do(req)
+	resp, err := tm.do(req)
 	if err != nil {
 		log.Printf("Error making HTTP request to Cloudflare: %v (auth method: %s)", err, authMethod)
 		return nil, err
@@ -137,7 +226,7 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("Cloudflare API returned non-200 status. Response body: %s", string(bodyBytes))
-		
+
 		// Try to parse error response
 		var errorResp struct {
 			Success bool `json:"success"`
@@ -151,7 +240,7 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 				log.Printf("Cloudflare API error %d: %s", err.Code, err.Message)
 			}
 		}
-		
+
 		return nil, fmt.Errorf("cloudflare API status %d", resp.StatusCode)
 	}
 
@@ -176,12 +265,14 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 
 	timestamps, values, found := extractSeries(apiResp.Result)
 	if !found || len(values) == 0 {
-		// Retry with IRN location (some Radar datasets use ISO3)
-		retryURL := "https://api.cloudflare.com/client/v4/radar/http/timeseries?location=IRN&dateRange=7d&aggInterval=1h&format=json"
-		log.Printf("Cloudflare API returned empty data for IR, retrying with IRN: %s", retryURL)
-		retryData, ok := tm.fetchWithURL(ctx, retryURL)
-		if ok {
-			return retryData, nil
+		// Retry with the ISO3 location (some Radar datasets use ISO3)
+		if tm.iso3 != "" && !strings.EqualFold(tm.iso3, tm.location) {
+			retryURL := tm.seriesURL(tm.iso3, "dateRange=7d&aggInterval="+tm.series.Interval)
+			log.Printf("Cloudflare API returned empty data for %s, retrying with %s: %s", tm.location, tm.iso3, retryURL)
+			retryData, ok := tm.fetchWithURL(ctx, retryURL)
+			if ok {
+				return retryData, nil
+			}
 		}
 
 		log.Printf("Cloudflare API returned empty or unrecognized data structure")
@@ -189,8 +280,8 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 		return nil, fmt.Errorf("no traffic data in response")
 	}
 
-	// Keep only the last 24 data points (24 hours) to match chart expectations
-	timestamps, values = sliceLast24(timestamps, values)
+	// Keep only the data points of the last 24 hours to match chart expectations
+	timestamps, values = sliceLast(timestamps, values, tm.seriesPoints())
 	log.Printf("Cloudflare API success - received %d data points (last 24h)", len(values))
 
 	// Process the data
@@ -200,7 +291,7 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 		return nil, err
 	}
 
-	log.Printf("Traffic data processed successfully - Current Level: %.1f%%, Status: %s %s", 
+	log.Printf("Traffic data processed successfully - Current Level: %.1f%%, Status: %s %s",
 		data.CurrentLevel, data.StatusEmoji, data.Status)
 
 	// Cache the data
@@ -212,6 +303,13 @@ func (tm *TrafficMonitor) FetchFromCloudflare(ctx context.Context) (*TrafficData
 	return data, nil
 }
 
+// LastSuccess returns when traffic data was last fetched successfully (zero if never)
+func (tm *TrafficMonitor) LastSuccess() time.Time {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return tm.lastUpdate
+}
+
 // min helper function
 func min(a, b int) int {
 	if a < b {
@@ -235,10 +333,10 @@ type radarSerie struct {
 }
 
 type radarResult struct {
-	Serie0    *radarSerie  `json:"serie_0"`
-	Serie0Alt *radarSerie  `json:"serie0"`
-	Series    []radarSerie `json:"series"`
-	Data      *radarSerie  `json:"data"`
+	Serie0     *radarSerie  `json:"serie_0"`
+	Serie0Alt  *radarSerie  `json:"serie0"`
+	Series     []radarSerie `json:"series"`
+	Data       *radarSerie  `json:"data"`
 	Timeseries []radarSerie `json:"timeseries"`
 	// Some responses return timestamps/values directly under result
 	Timestamps []string  `json:"timestamps"`
@@ -450,11 +548,12 @@ func firstOf(m map[string]interface{}, keys ...string) interface{} {
 	return nil
 }
 
-func sliceLast24(timestamps []string, values []float64) ([]string, []float64) {
-	if len(values) <= 24 || len(timestamps) <= 24 {
+// sliceLast keeps the last n points of a series
+func sliceLast(timestamps []string, values []float64, n int) ([]string, []float64) {
+	if len(values) <= n || len(timestamps) <= n {
 		return timestamps, values
 	}
-	start := len(values) - 24
+	start := len(values) - n
 	if len(timestamps) > start {
 		return timestamps[start:], values[start:]
 	}
@@ -469,14 +568,8 @@ func (tm *TrafficMonitor) fetchWithURL(ctx context.Context, url string) (*Traffi
 		return nil, false
 	}
 	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
-	if tm.cloudflareToken != "" {
-		req.Header.Set("Authorization", "Bearer "+tm.cloudflareToken)
-	} else if tm.cloudflareEmail != "" && tm.cloudflareKey != "" {
-		req.Header.Set("X-Auth-Email", tm.cloudflareEmail)
-		req.Header.Set("X-Auth-Key", tm.cloudflareKey)
-	}
 
-	resp, err := tm.client.Do(req)
+	resp, err := tm.do(req)
 	if err != nil {
 		return nil, false
 	}
@@ -500,7 +593,7 @@ func (tm *TrafficMonitor) fetchWithURL(ctx context.Context, url string) (*Traffi
 		return nil, false
 	}
 
-	ts, vals = sliceLast24(ts, vals)
+	ts, vals = sliceLast(ts, vals, tm.seriesPoints())
 	data, err := tm.processData(vals, ts)
 	if err != nil {
 		return nil, false
@@ -508,7 +601,6 @@ func (tm *TrafficMonitor) fetchWithURL(ctx context.Context, url string) (*Traffi
 	return data, true
 }
 
-
 // processData processes the Cloudflare API response into TrafficData
 func (tm *TrafficMonitor) processData(values []float64, timestamps []string) (*TrafficData, error) {
 	if len(values) == 0 {
@@ -593,70 +685,95 @@ func (tm *TrafficMonitor) determineStatus(current, baseline float64) (string, st
 	ratio := current / baseline
 
 	switch {
-	case ratio > 0.7:
+	case ratio > tm.thresholds.Degraded:
 		return "Normal", "🟢"
-	case ratio > 0.3:
+	case ratio > tm.thresholds.Throttled:
 		return "Degraded", "🟡"
-	case ratio > 0.1:
+	case ratio > tm.thresholds.Shutdown:
 		return "Throttled", "🟠"
 	default:
 		return "Shutdown", "🔴"
 	}
 }
 
-// Start begins background monitoring
-// Note: Initial fetch should already be done in PerformInitialCheck
-func (tm *TrafficMonitor) Start(ctx context.Context) {
-	ticker := time.NewTicker(10 * time.Minute)
-	defer ticker.Stop()
-
-	// Skip initial fetch here - it's already done in PerformInitialCheck
-	// This ensures Cloudflare data is fetched FIRST before bot starts
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			log.Println("📡 Periodic Cloudflare Radar data fetch...")
-			_, _ = tm.FetchFromCloudflare(ctx)
-		}
+// SetASNLimit sets how many ASNs are requested from Radar (default: 20)
+func (tm *TrafficMonitor) SetASNLimit(limit int) {
+	tm.asnLimit = limit
+}
+
+// GetASNTrafficData returns cached or fresh traffic per ASN
+func (tm *TrafficMonitor) GetASNTrafficData(ctx context.Context) ([]*models.ASTrafficData, error) {
+	tm.mu.RLock()
+	if tm.cachedASN != nil && time.Since(tm.asnUpdate) < tm.asnCacheFor {
+		data := tm.cachedASN
+		tm.mu.RUnlock()
+		return data, nil
 	}
+	tm.mu.RUnlock()
+
+	return tm.FetchASNTrafficFromCloudflare(ctx)
 }
 
 // FetchASNTrafficFromCloudflare fetches ASN-level traffic data from Cloudflare Radar API
-// Returns top 10 Iranian ASNs by traffic volume
+// Returns the top ASNs by traffic volume (see SetASNLimit)
 // Follows the same pattern as FetchFromCloudflare for consistency
 // Tries multiple endpoint variations to find the correct one
 func (tm *TrafficMonitor) FetchASNTrafficFromCloudflare(ctx context.Context) ([]*models.ASTrafficData, error) {
 	// Try multiple endpoint variations (similar to Iran traffic retry logic)
 	// Based on Cloudflare Radar API docs: /radar/netflows/top/ases for top ASNs
-	// Request top 10 ASNs using limit parameter
+	// Request the top ASNs using limit parameter
+	limit := strconv.Itoa(tm.asnLimit)
 	endpointVariations := []string{
-		// Try 1: Netflows top ASes (documented endpoint) - request top 10
-		"https://api.cloudflare.com/client/v4/radar/netflows/top/ases?location=IR&dateRange=1d&limit=10&format=json",
-		// Try 2: HTTP top ASes - request top 10
-		"https://api.cloudflare.com/client/v4/radar/http/top/ases?location=IR&dateRange=1d&limit=10&format=json",
+		// Try 1: Netflows top ASes (documented endpoint) - request the top ASNs
+		tm.radarURL + "/netflows/top/ases?location=" + tm.location + "&dateRange=1d&limit=" + limit + "&format=json",
+		// Try 2: HTTP top ASes - request the top ASNs
+		tm.radarURL + "/http/top/ases?location=" + tm.location + "&dateRange=1d&limit=" + limit + "&format=json",
 		// Try 3: Query parameter with dimension
-		"https://api.cloudflare.com/client/v4/radar/http/top?dimension=asn&location=IR&dateRange=1d&format=json",
+		tm.radarURL + "/http/top?dimension=asn&location=" + tm.location + "&dateRange=1d&format=json",
 		// Try 4: Summary endpoint with dimension
-		"https://api.cloudflare.com/client/v4/radar/http/summary?dimension=asn&location=IR&dateRange=1d&format=json",
+		tm.radarURL + "/http/summary?dimension=asn&location=" + tm.location + "&dateRange=1d&format=json",
 		// Try 5: Summary/asn path
-		"https://api.cloudflare.com/client/v4/radar/http/summary/asn?location=IR&dateRange=1d&format=json",
+		tm.radarURL + "/http/summary/asn?location=" + tm.location + "&dateRange=1d&format=json",
 		// Try 6: Netflows endpoint (old variant)
-		"https://api.cloudflare.com/client/v4/radar/netflows/top/asn?location=IR&dateRange=1d&format=json",
+		tm.radarURL + "/netflows/top/asn?location=" + tm.location + "&dateRange=1d&format=json",
 		// Try 7: Netflows summary
-		"https://api.cloudflare.com/client/v4/radar/netflows/summary?dimension=asn&location=IR&dateRange=1d&format=json",
+		tm.radarURL + "/netflows/summary?dimension=asn&location=" + tm.location + "&dateRange=1d&format=json",
 		// Try 8: Original (if API is fixed later)
-		"https://api.cloudflare.com/client/v4/radar/http/top/asn?location=IR&dateRange=1d&format=json",
+		tm.radarURL + "/http/top/asn?location=" + tm.location + "&dateRange=1d&format=json",
+	}
+
+	tm.mu.RLock()
+	cached, fetched, cacheFor, working := tm.cachedASN, tm.asnUpdate, tm.asnCacheFor, tm.asnVariation
+	tm.mu.RUnlock()
+	if skip, err := radarQuota.skip("ASN traffic", cached != nil, fetched, cacheFor); skip {
+		return cached, err
+	}
+
+	// Try the variation that worked last first; while the API budget is low,
+	// only that one
+	order := []int{working}
+	for i := range endpointVariations {
+		if i != working {
+			order = append(order, i)
+		}
+	}
+	if radarQuota.low() {
+		log.Printf("🔋 Cloudflare Radar budget low: trying only ASN endpoint variation %d", working+1)
+		order = order[:1]
 	}
 
 	// Try each endpoint variation
-	for i, url := range endpointVariations {
+	for _, i := range order {
+		url := endpointVariations[i]
 		log.Printf("Trying ASN endpoint variation %d/%d: %s", i+1, len(endpointVariations), url)
 		result, err := tm.fetchASNTrafficWithURL(ctx, url)
 		if err == nil && len(result) > 0 {
 			log.Printf("✅ Successfully fetched ASN traffic data using endpoint variation %d", i+1)
+			tm.mu.Lock()
+			tm.cachedASN = result
+			tm.asnUpdate = time.Now()
+			tm.asnVariation = i
+			tm.mu.Unlock()
 			return result, nil
 		}
 		if err != nil {
@@ -679,18 +796,9 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 	}
 
 	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
-	
-	// Add Cloudflare authentication headers - match working pattern exactly
-	if tm.cloudflareToken != "" {
-		req.Header.Set("Authorization", "Bearer "+tm.cloudflareToken)
-	} else if tm.cloudflareEmail != "" && tm.cloudflareKey != "" {
-		req.Header.Set("X-Auth-Email", tm.cloudflareEmail)
-		req.Header.Set("X-Auth-Key", tm.cloudflareKey)
-	} else {
-		return nil, fmt.Errorf("no Cloudflare credentials available")
-	}
 
-	resp, err := tm.client.Do(req)
+	// Authenticated with the next usable credential (see radarcreds.go)
+	resp, err := tm.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error making HTTP request: %w", err)
 	}
@@ -743,15 +851,15 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 	// Define a structure to hold parsed ASN items
 	// Note: Cloudflare API returns clientASN/clientASName for top/ases endpoints
 	type asnItem struct {
-		ASN         interface{} `json:"asn"`          // Standard field
-		ClientASN   interface{} `json:"clientASN"`    // Used by /top/ases endpoints
-		ClientASName string     `json:"clientASName"` // Used by /top/ases endpoints
-		Value       interface{} `json:"value"`        // Can be string or float64
-		Change      float64     `json:"change,omitempty"`
+		ASN          interface{} `json:"asn"`          // Standard field
+		ClientASN    interface{} `json:"clientASN"`    // Used by /top/ases endpoints
+		ClientASName string      `json:"clientASName"` // Used by /top/ases endpoints
+		Value        interface{} `json:"value"`        // Can be string or float64
+		Change       float64     `json:"change,omitempty"`
 	}
-	
+
 	var summaryData []asnItem
-	
+
 	// Try structure with top_0 field first (used by /top/ases endpoints)
 	var resultTop0 struct {
 		Top0 []asnItem `json:"top_0"`
@@ -789,7 +897,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 			}
 		}
 	}
-	
+
 	// If still no data, try to parse as raw map to see structure
 	if len(summaryData) == 0 {
 		log.Printf("⚠️  Could not parse ASN traffic result with expected structures")
@@ -800,7 +908,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 			}
 			log.Printf("Response result: %s", resultStr)
 		}
-		
+
 		// Try to parse as raw map to see structure
 		var rawResult map[string]interface{}
 		if jsonErr := json.Unmarshal(apiResp.Result, &rawResult); jsonErr == nil {
@@ -873,7 +981,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 	}
 
 	log.Printf("Total ASN traffic from API: %f, Found %d ASNs in response", totalTraffic, len(summaryData))
-	
+
 	// Log first few ASNs from API for debugging
 	log.Printf("First 10 ASNs from Cloudflare Radar API response:")
 	for i, item := range summaryData {
@@ -903,7 +1011,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 		var asnNum int
 		var asnStr, asnNumStr string
 		var asnValue interface{}
-		
+
 		// Prefer ClientASN if available (from /top/ases endpoints)
 		if item.ClientASN != nil {
 			asnValue = item.ClientASN
@@ -913,7 +1021,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 			log.Printf("ASN item missing both ASN and ClientASN fields - skipping")
 			continue
 		}
-		
+
 		// Parse ASN value
 		switch v := asnValue.(type) {
 		case float64:
@@ -963,7 +1071,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 		// Get ASN name - prefer ClientASName if available, otherwise use config
 		asnName := item.ClientASName
 		if asnName == "" {
-			asnName = config.GetASNName(asnStr)
+			asnName = tm.asnName(asnStr)
 			if asnName == "Unknown" {
 				asnName = asnStr
 			}
@@ -973,17 +1081,17 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 		status, emoji := tm.determineASNStatus(percentage)
 
 		asnTrafficList = append(asnTrafficList, &models.ASTrafficData{
-			ASN:          asnStr,
-			Name:         asnName,
+			ASN:           asnStr,
+			Name:          asnName,
 			TrafficVolume: value,
 			Percentage:    percentage,
-			Status:       status,
-			StatusEmoji:  emoji,
-			LastUpdate:   time.Now(),
+			Status:        status,
+			StatusEmoji:   emoji,
+			LastUpdate:    time.Now(),
 		})
 	}
 
-	// Sort by traffic volume (highest first) and take top 10
+	// Sort by traffic volume (highest first) and take the top ASNs
 	if len(asnTrafficList) > 1 {
 		for i := 0; i < len(asnTrafficList)-1; i++ {
 			for j := i + 1; j < len(asnTrafficList); j++ {
@@ -994,9 +1102,9 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 		}
 	}
 
-	// Limit to top 10
-	if len(asnTrafficList) > 10 {
-		asnTrafficList = asnTrafficList[:10]
+	// Limit to the requested number
+	if len(asnTrafficList) > tm.asnLimit {
+		asnTrafficList = asnTrafficList[:tm.asnLimit]
 	}
 
 	if len(asnTrafficList) == 0 {
@@ -1009,7 +1117,7 @@ func (tm *TrafficMonitor) fetchASNTrafficWithURL(ctx context.Context, url string
 	for i := 0; i < min(3, len(asnTrafficList)); i++ {
 		topNames = append(topNames, asnTrafficList[i].Name)
 	}
-	log.Printf("ASN traffic data processed successfully - %d ASNs from Cloudflare Radar (top ASNs: %v)", 
+	log.Printf("ASN traffic data processed successfully - %d ASNs from Cloudflare Radar (top ASNs: %v)",
 		len(asnTrafficList), topNames)
 	return asnTrafficList, nil
 }
@@ -1027,4 +1135,3 @@ func (tm *TrafficMonitor) determineASNStatus(percentage float64) (string, string
 		return "Very Low", "⚪"
 	}
 }
-
@@ -0,0 +1,401 @@
+package monitor
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/pkg/checker"
+)
+
+// Transaction check settings
+const (
+	transactionChecker = "transaction" // Checker name of the outcomes
+	defaultStepTimeout = 10 * time.Second
+	transactionMaxBody = 1 << 20 // Body read for the content step
+	mismatchMaxLines   = 50      // Lines of a mismatch diff
+	mismatchMaxBody    = 4 << 10 // Body kept of a mismatch without a reference
+	diffMaxLines       = 2000    // Lines of each body compared
+)
+
+// TransactionChecker runs the configured transactions every cycle, each as
+// DNS resolve, TCP connect, TLS handshake (https), HTTP GET and content check,
+// stopping at the first step that fails
+type TransactionChecker struct {
+	transactions []config.Transaction
+
+	mu         sync.Mutex
+	mismatches map[string]*models.ContentMismatch // Of the last check, by transaction
+	recorded   map[string]string                  // SHA-256 of the mismatch last recorded, by transaction
+}
+
+// newTransactionChecker creates the checker of the transactions
+func newTransactionChecker(transactions []config.Transaction) *TransactionChecker {
+	return &TransactionChecker{
+		transactions: transactions,
+		mismatches:   make(map[string]*models.ContentMismatch),
+		recorded:     make(map[string]string),
+	}
+}
+
+// Name implements checker.Checker
+func (c *TransactionChecker) Name() string {
+	return transactionChecker
+}
+
+// Check runs the transactions in parallel and keeps the content mismatches
+func (c *TransactionChecker) Check(ctx context.Context, _ checker.Input) ([]checker.Outcome, error) {
+	outcomes := make([]checker.Outcome, len(c.transactions))
+	mismatches := make([]*models.ContentMismatch, len(c.transactions))
+	var wg sync.WaitGroup
+	for i, t := range c.transactions {
+		wg.Add(1)
+		go func(i int, t config.Transaction) {
+			defer wg.Done()
+			outcomes[i], mismatches[i] = runTransaction(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mismatches = make(map[string]*models.ContentMismatch)
+	for i, t := range c.transactions {
+		if mismatches[i] != nil {
+			c.mismatches[t.Name] = mismatches[i]
+		} else if outcomes[i].Up {
+			delete(c.recorded, t.Name)
+		}
+	}
+	return outcomes, nil
+}
+
+// Mismatch returns the content mismatch of a transaction in the last check (nil if none)
+func (c *TransactionChecker) Mismatch(name string) *models.ContentMismatch {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mismatches[name]
+}
+
+// firstSeen reports whether a mismatch differs from the last one recorded of
+// its transaction, so the same injected page is recorded once
+func (c *TransactionChecker) firstSeen(name string, mismatch *models.ContentMismatch) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.recorded[name] == mismatch.SHA256 {
+		return false
+	}
+	c.recorded[name] = mismatch.SHA256
+	return true
+}
+
+// recordMismatches logs the new content mismatches of a cycle's checks and
+// appends them to the evidence log
+func (m *Monitor) recordMismatches(c *TransactionChecker, checks []*models.CustomCheck) {
+	for _, check := range checks {
+		if check.Checker != transactionChecker || check.Mismatch == nil || !c.firstSeen(check.Name, check.Mismatch) {
+			continue
+		}
+		log.Printf("🧪 Content mismatch on %s: sha256 %s, expected %s", check.Name, check.Mismatch.SHA256, check.Mismatch.Expected)
+		if m.evidence != nil {
+			if err := m.evidence.Append("content_mismatch", check.Mismatch.DetectedAt, check); err != nil {
+				log.Printf("⚠️  Failed to record content mismatch: %v", err)
+			}
+		}
+	}
+}
+
+// transactionRun records the steps of a transaction as they complete
+type transactionRun struct {
+	timeout time.Duration
+	done    []string // e.g. "dns 12ms"
+}
+
+// step runs one step with its own timeout and records its duration
+func (r *transactionRun) step(ctx context.Context, name string, fn func(ctx context.Context) (string, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+	note, err := fn(ctx)
+	if err != nil {
+		return err
+	}
+	entry := fmt.Sprintf("%s %s", name, time.Since(start).Round(time.Millisecond))
+	if note != "" {
+		entry = fmt.Sprintf("%s %s in %s", name, note, time.Since(start).Round(time.Millisecond))
+	}
+	r.done = append(r.done, entry)
+	return nil
+}
+
+// runTransaction runs the steps of a transaction and reports the first that
+// failed, with the content if it failed the integrity step
+func runTransaction(ctx context.Context, t config.Transaction) (checker.Outcome, *models.ContentMismatch) {
+	outcome := checker.Outcome{Name: t.Name}
+	run := &transactionRun{timeout: defaultStepTimeout}
+	if d, err := time.ParseDuration(t.Timeout); err == nil && d > 0 {
+		run.timeout = d
+	}
+	target, err := url.Parse(t.URL)
+	if err != nil {
+		outcome.Step, outcome.Detail = config.StepHTTP, err.Error()
+		return outcome, nil
+	}
+	host, port := target.Hostname(), target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	fail := func(step string, err error) (checker.Outcome, *models.ContentMismatch) {
+		outcome.Step = step
+		outcome.Detail = step + ": " + strings.TrimPrefix(err.Error(), step+": ")
+		if len(run.done) > 0 {
+			outcome.Detail += " (after " + strings.Join(run.done, ", ") + ")"
+		}
+		return outcome, nil
+	}
+
+	// DNS: an address in the URL skips the step
+	address := host
+	if net.ParseIP(host) == nil {
+		err := run.step(ctx, config.StepDNS, func(ctx context.Context) (string, error) {
+			resolver := net.DefaultResolver
+			if t.Resolver != "" {
+				resolver = &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, network, t.Resolver)
+				}}
+			}
+			addrs, err := resolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return "", err
+			}
+			if len(addrs) == 0 {
+				return "", fmt.Errorf("no address for %s", host)
+			}
+			address = addrs[0].IP.String()
+			return address, nil
+		})
+		if err != nil {
+			return fail(config.StepDNS, err)
+		}
+	}
+
+	var conn net.Conn
+	err = run.step(ctx, config.StepTCP, func(ctx context.Context) (string, error) {
+		var d net.Dialer
+		var err error
+		conn, err = d.DialContext(ctx, "tcp", net.JoinHostPort(address, port))
+		return "", err
+	})
+	if err != nil {
+		return fail(config.StepTCP, err)
+	}
+	defer conn.Close()
+
+	if target.Scheme == "https" {
+		err = run.step(ctx, config.StepTLS, func(ctx context.Context) (string, error) {
+			tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return "", err
+			}
+			conn = tlsConn
+			return "", nil
+		})
+		if err != nil {
+			return fail(config.StepTLS, err)
+		}
+	}
+
+	var resp *http.Response
+	err = run.step(ctx, config.StepHTTP, func(ctx context.Context) (string, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.URL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+		req.Close = true
+		if err := req.Write(conn); err != nil {
+			return "", err
+		}
+		resp, err = http.ReadResponse(bufio.NewReader(conn), req)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case t.Status != 0 && resp.StatusCode != t.Status:
+			return "", fmt.Errorf("status %d, expected %d", resp.StatusCode, t.Status)
+		case t.Status == 0 && resp.StatusCode >= 400:
+			return "", fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return fmt.Sprintf("%d", resp.StatusCode), nil
+	})
+	if err != nil {
+		return fail(config.StepHTTP, err)
+	}
+	defer resp.Body.Close()
+
+	if t.Expect == "" && t.SHA256 == "" && t.Reference == "" {
+		outcome.Up = true
+		outcome.Detail = strings.Join(run.done, ", ")
+		return outcome, nil
+	}
+
+	var body []byte
+	err = run.step(ctx, config.StepContent, func(ctx context.Context) (string, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		var err error
+		if body, err = io.ReadAll(io.LimitReader(resp.Body, transactionMaxBody)); err != nil {
+			return "", err
+		}
+		if t.Expect != "" && !strings.Contains(string(body), t.Expect) {
+			return "", fmt.Errorf("%q not found in %d bytes", t.Expect, len(body))
+		}
+		return "", nil
+	})
+	if err != nil {
+		return fail(config.StepContent, err)
+	}
+
+	if t.SHA256 != "" || t.Reference != "" {
+		var mismatch *models.ContentMismatch
+		err = run.step(ctx, config.StepIntegrity, func(ctx context.Context) (string, error) {
+			var err error
+			mismatch, err = verifyContent(ctx, t, body)
+			if err != nil {
+				return "", err
+			}
+			if mismatch != nil {
+				return "", fmt.Errorf("sha256 %.12s, expected %.12s", mismatch.SHA256, mismatch.Expected)
+			}
+			return "", nil
+		})
+		if err != nil {
+			outcome, _ := fail(config.StepIntegrity, err)
+			return outcome, mismatch
+		}
+	}
+
+	outcome.Up = true
+	outcome.Detail = strings.Join(run.done, ", ")
+	return outcome, nil
+}
+
+// verifyContent compares a fetched body with the configured hash or the
+// reference copy; it returns the mismatch, or an error if the reference could
+// not be fetched
+func verifyContent(ctx context.Context, t config.Transaction, body []byte) (*models.ContentMismatch, error) {
+	sum := sha256.Sum256(body)
+	got := hex.EncodeToString(sum[:])
+
+	var reference []byte
+	expected := strings.ToLower(t.SHA256)
+	if expected == "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.Reference, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("reference: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("reference returned %s", resp.Status)
+		}
+		if reference, err = io.ReadAll(io.LimitReader(resp.Body, transactionMaxBody)); err != nil {
+			return nil, fmt.Errorf("reference: %w", err)
+		}
+		sum := sha256.Sum256(reference)
+		expected = hex.EncodeToString(sum[:])
+	}
+	if got == expected {
+		return nil, nil
+	}
+
+	mismatch := &models.ContentMismatch{URL: t.URL, SHA256: got, Expected: expected, Reference: t.Reference, DetectedAt: time.Now()}
+	if reference != nil {
+		mismatch.Diff = lineDiff(string(reference), string(body), mismatchMaxLines)
+	} else {
+		mismatch.Body = string(body[:min(len(body), mismatchMaxBody)])
+	}
+	return mismatch, nil
+}
+
+// lineDiff lists the lines of a missing from b (-) and added in b (+), in
+// order, up to maxLines; long inputs are compared on their first diffMaxLines lines
+func lineDiff(a, b string, maxLines int) string {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(x) > diffMaxLines {
+		x = x[:diffMaxLines]
+	}
+	if len(y) > diffMaxLines {
+		y = y[:diffMaxLines]
+	}
+
+	// Longest common subsequence lengths of the suffixes
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	add := func(line string) bool {
+		if len(lines) == maxLines {
+			lines = append(lines, "...")
+			return false
+		}
+		lines = append(lines, line)
+		return true
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i, j = i+1, j+1
+			continue
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			if !add("-" + x[i]) {
+				return strings.Join(lines, "\n")
+			}
+			i++
+		default:
+			if !add("+" + y[j]) {
+				return strings.Join(lines, "\n")
+			}
+			j++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
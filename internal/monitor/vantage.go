@@ -0,0 +1,92 @@
+package monitor
+
+import (
+	"os"
+	"sort"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/version"
+)
+
+// probeVantage builds this probe's vantage from the config, defaulting the probe ID to the hostname
+func probeVantage(cfg *config.Config) *models.Vantage {
+	probeID := cfg.ProbeID
+	if probeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil || hostname == "" {
+			hostname = "local"
+		}
+		probeID = hostname
+	}
+	return &models.Vantage{
+		ProbeID: probeID,
+		Country: cfg.ProbeCountry,
+		Network: cfg.ProbeNetwork,
+		Build:   version.String(),
+	}
+}
+
+// CompareVantages lists the ASNs and DNS servers whose status differs between
+// the results of several probes (one result per vantage)
+func CompareVantages(results []*models.MonitoringResult) []models.VantageDisagreement {
+	type votes struct {
+		name     string
+		up, down []string
+	}
+	asnVotes := make(map[string]*votes)
+	dnsVotes := make(map[string]*votes)
+
+	vote := func(all map[string]*votes, key, name, probe string, up bool) {
+		v, ok := all[key]
+		if !ok {
+			v = &votes{name: name}
+			all[key] = v
+		}
+		if up {
+			v.up = append(v.up, probe)
+		} else {
+			v.down = append(v.down, probe)
+		}
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		probe := "unknown"
+		if result.Vantage != nil {
+			probe = result.Vantage.ProbeID
+		}
+		for asn, status := range result.ASNStatuses {
+			vote(asnVotes, asn, status.Name, probe, status.Connected)
+		}
+		for key, status := range result.DNSStatuses {
+			vote(dnsVotes, key, status.Name, probe, status.Alive)
+		}
+	}
+
+	var disagreements []models.VantageDisagreement
+	collect := func(kind string, all map[string]*votes) {
+		for key, v := range all {
+			if len(v.up) == 0 || len(v.down) == 0 {
+				continue
+			}
+			sort.Strings(v.up)
+			sort.Strings(v.down)
+			disagreements = append(disagreements, models.VantageDisagreement{
+				Kind: kind, Target: key, Name: v.name, Up: v.up, Down: v.down,
+			})
+		}
+	}
+	collect("asn", asnVotes)
+	collect("dns", dnsVotes)
+
+	sort.Slice(disagreements, func(i, j int) bool {
+		if disagreements[i].Kind != disagreements[j].Kind {
+			return disagreements[i].Kind < disagreements[j].Kind
+		}
+		return disagreements[i].Target < disagreements[j].Target
+	})
+	return disagreements
+}
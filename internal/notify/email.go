@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Email sends events as one plain-text mail per cycle
+type Email struct {
+	cfg *config.SMTPConfig
+}
+
+// NewEmail creates an email notifier for the given mail server
+func NewEmail(cfg *config.SMTPConfig) *Email {
+	return &Email{cfg: cfg}
+}
+
+// Name returns the action name of the notifier
+func (e *Email) Name() string {
+	return ActionEmail
+}
+
+// Notify mails the events to the configured recipients
+// net/smtp has no context support; the dial timeout bounds a dead server
+func (e *Email) Notify(_ context.Context, events []models.Event) error {
+	if len(e.cfg.To) == 0 {
+		return fmt.Errorf("smtp.to is empty")
+	}
+
+	subject := fmt.Sprintf("NetBlocks: %s", events[0].Message)
+	if len(events) > 1 {
+		subject = fmt.Sprintf("NetBlocks: %d alerts", len(events))
+	}
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	for _, event := range events {
+		fmt.Fprintf(&body, "[%s] %s %s: %s\r\n", strings.ToUpper(event.Severity),
+			event.Timestamp.UTC().Format("2006-01-02 15:04 UTC"), event.Target, event.Message)
+	}
+	for _, narrative := range models.Narratives(events) {
+		fmt.Fprintf(&body, "\r\n%s\r\n", narrative)
+	}
+
+	host, _, err := net.SplitHostPort(e.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp.addr %q: %w", e.cfg.Addr, err)
+	}
+	conn, err := net.DialTimeout("tcp", e.cfg.Addr, notifyTimeout)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(e.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range e.cfg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(body.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
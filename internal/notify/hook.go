@@ -0,0 +1,31 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/netblocks/netblocks/internal/hook"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Hook runs an event hook with the events as JSON ({"events": [...]}, the
+// webhook body) on stdin
+type Hook struct {
+	hook *hook.Hook
+}
+
+// NewHook creates a notifier for an event hook, routed to by the hook's name
+func NewHook(h *hook.Hook) *Hook {
+	return &Hook{hook: h}
+}
+
+// Name returns the action name of the notifier
+func (h *Hook) Name() string {
+	return h.hook.Name()
+}
+
+// Notify runs the hook with the events
+func (h *Hook) Notify(ctx context.Context, events []models.Event) error {
+	return h.hook.Run(ctx, struct {
+		Events []models.Event `json:"events"`
+	}{events}, nil)
+}
@@ -0,0 +1,257 @@
+// Package notify routes events to the notification channels named in alert rule actions
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/hook"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Action names usable in rule actions
+const (
+	ActionTelegram       = "telegram"        // Default for events without actions
+	ActionTelegramAdmins = "telegram_admins" // Direct messages to telegram_admins
+	ActionWebhook        = "webhook"
+	ActionEmail          = "email"
+)
+
+// severityRank orders severities from least to most severe
+var severityRank = map[string]int{
+	models.SeverityInfo:     0,
+	models.SeverityWarning:  1,
+	models.SeverityCritical: 2,
+}
+
+// atLeast returns the events at least as severe as min
+func atLeast(events []models.Event, min string) []models.Event {
+	var kept []models.Event
+	for _, event := range events {
+		if severityRank[event.Severity] >= severityRank[min] {
+			kept = append(kept, event)
+		}
+	}
+	return kept
+}
+
+// notifyTimeout bounds one delivery to one notifier
+const notifyTimeout = 30 * time.Second
+
+// Notifier delivers events to one notification channel
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, events []models.Event) error
+}
+
+// Func adapts an event handler (e.g. the Telegram bot's) to a Notifier
+type Func struct {
+	name    string
+	handler func([]models.Event)
+}
+
+// NewFunc creates a notifier named name that calls handler
+func NewFunc(name string, handler func([]models.Event)) *Func {
+	return &Func{name: name, handler: handler}
+}
+
+// Name returns the action name of the notifier
+func (f *Func) Name() string {
+	return f.name
+}
+
+// Notify passes the events to the handler
+func (f *Func) Notify(_ context.Context, events []models.Event) error {
+	f.handler(events)
+	return nil
+}
+
+// Dispatcher routes each event to the notifiers named in its actions and
+// to those of the routes it matches
+type Dispatcher struct {
+	mu        sync.RWMutex
+	notifiers map[string]Notifier
+	mirrors   map[string][]string // Action -> further actions receiving the same events
+	routes    []route
+	templates map[string]*template.Template // Action -> message template
+}
+
+// NewDispatcher creates a dispatcher with the routes, templates and webhook, email,
+// paging and event hook notifiers of the config
+func NewDispatcher(cfg *config.Config) (*Dispatcher, error) {
+	routes, err := parseRoutes(cfg.Routes)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := parseTemplates(cfg.NotifierTemplates)
+	if err != nil {
+		return nil, err
+	}
+	d := &Dispatcher{notifiers: make(map[string]Notifier), mirrors: make(map[string][]string), routes: routes, templates: templates}
+	if len(cfg.AlertWebhooks) > 0 {
+		d.Register(NewWebhook(ActionWebhook, cfg.AlertWebhooks))
+	}
+	if cfg.SMTP != nil {
+		d.Register(NewEmail(cfg.SMTP))
+	}
+	if cfg.PagerDuty != nil {
+		d.Register(NewPagerDuty(cfg.PagerDuty))
+	}
+	if cfg.Opsgenie != nil {
+		d.Register(NewOpsgenie(cfg.Opsgenie))
+	}
+	if cfg.Signal != nil {
+		d.Register(NewSignal(cfg.Signal))
+	}
+	if cfg.SMS != nil {
+		sms, err := NewSMS(cfg.SMS)
+		if err != nil {
+			return nil, err
+		}
+		d.Register(sms)
+	}
+	for _, webhook := range cfg.Webhooks {
+		if webhook.Name == "" || webhook.URL == "" {
+			return nil, fmt.Errorf("webhooks need a name and a url")
+		}
+		if _, taken := d.notifiers[webhook.Name]; taken || webhook.Name == ActionTelegram || webhook.Name == ActionTelegramAdmins {
+			return nil, fmt.Errorf("webhook name %q is already an action", webhook.Name)
+		}
+		d.Register(NewWebhook(webhook.Name, []string{webhook.URL}))
+	}
+	hooks, err := hook.Load(cfg.Hooks, hook.StageEvent)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hooks {
+		if _, taken := d.notifiers[h.Name()]; taken || h.Name() == ActionTelegram || h.Name() == ActionTelegramAdmins {
+			return nil, fmt.Errorf("hook name %q is already an action", h.Name())
+		}
+		d.Register(NewHook(h))
+	}
+	return d, nil
+}
+
+// Register adds (or replaces) a notifier
+func (d *Dispatcher) Register(n Notifier) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notifiers[n.Name()] = n
+}
+
+// Mirror also delivers every event routed to action to the mirror action
+// (e.g. a Matrix room mirroring Telegram)
+func (d *Dispatcher) Mirror(action, mirror string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mirrors[action] = append(d.mirrors[action], mirror)
+}
+
+// CheckActions reports rule, escalation, route and template actions without a configured notifier
+// The Telegram actions are always available; they are registered once the bot is running
+func (d *Dispatcher) CheckActions(cfg *config.Config) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	check := func(owner string, actions []string) error {
+		for _, action := range actions {
+			if _, ok := d.notifiers[action]; !ok && action != ActionTelegram && action != ActionTelegramAdmins {
+				return fmt.Errorf("%s: action %q is not configured", owner, action)
+			}
+		}
+		return nil
+	}
+	for _, rule := range cfg.Rules {
+		if err := check(fmt.Sprintf("rule %q", rule.Name), rule.Actions); err != nil {
+			return err
+		}
+	}
+	for _, policy := range cfg.EscalationPolicies {
+		for _, step := range policy.Steps {
+			if err := check(fmt.Sprintf("escalation policy %q", policy.Name), step.Actions); err != nil {
+				return err
+			}
+		}
+	}
+	for i, route := range cfg.Routes {
+		if err := check(fmt.Sprintf("route %d", i+1), route.Actions); err != nil {
+			return err
+		}
+	}
+	for action := range cfg.NotifierTemplates {
+		if err := check("notifier_templates", []string{action}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandleEvents delivers the events of one cycle; it can be used as the monitor's event handler
+func (d *Dispatcher) HandleEvents(events []models.Event) {
+	d.dispatch(events, true)
+}
+
+// Deliver passes events to the notifiers of their actions only, without routes or
+// templates; it is used for events already routed by the monitor process
+func (d *Dispatcher) Deliver(events []models.Event) {
+	d.dispatch(events, false)
+}
+
+// dispatch groups the events by target action and notifies each action once
+func (d *Dispatcher) dispatch(events []models.Event, routing bool) {
+	routed := make(map[string][]models.Event)
+	var order []string
+	d.mu.RLock()
+	for _, event := range events {
+		actions := event.Actions
+		if len(actions) == 0 {
+			actions = []string{ActionTelegram}
+		}
+		for _, r := range d.routes {
+			if routing && r.matches(event) {
+				actions = append(actions[:len(actions):len(actions)], r.actions...)
+			}
+		}
+		// Each notifier gets an event once, even if it is also a mirror
+		var targets []string
+		seen := make(map[string]bool)
+		for _, action := range actions {
+			for _, target := range append([]string{action}, d.mirrors[action]...) {
+				if !seen[target] {
+					seen[target] = true
+					targets = append(targets, target)
+				}
+			}
+		}
+		for _, action := range targets {
+			if _, ok := routed[action]; !ok {
+				order = append(order, action)
+			}
+			routed[action] = append(routed[action], event)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, action := range order {
+		d.mu.RLock()
+		n, ok := d.notifiers[action]
+		d.mu.RUnlock()
+		if !ok {
+			log.Printf("⚠️  No notifier for action %q; dropping %d event(s)", action, len(routed[action]))
+			continue
+		}
+		batch := routed[action]
+		if tmpl, ok := d.templates[action]; ok && routing {
+			batch = applyTemplate(tmpl, batch)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		if err := n.Notify(ctx, batch); err != nil {
+			log.Printf("⚠️  Failed to notify via %s: %v", action, err)
+		}
+		cancel()
+	}
+}
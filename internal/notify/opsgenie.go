@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// ActionOpsgenie opens and closes Opsgenie alerts
+const ActionOpsgenie = "opsgenie"
+
+// Opsgenie Alert API base URLs per region
+const (
+	opsgenieURL   = "https://api.opsgenie.com/v2/alerts"
+	opsgenieEUURL = "https://api.eu.opsgenie.com/v2/alerts"
+)
+
+// opsgeniePriority maps event severities to Opsgenie priorities
+var opsgeniePriority = map[string]string{
+	models.SeverityCritical: "P1",
+	models.SeverityWarning:  "P3",
+	models.SeverityInfo:     "P5",
+}
+
+// Opsgenie creates and closes Opsgenie alerts, deduplicated by alias
+type Opsgenie struct {
+	apiKey     string
+	url        string
+	tags       []string
+	httpClient *http.Client
+}
+
+// NewOpsgenie creates an Opsgenie notifier for the configured integration
+func NewOpsgenie(cfg *config.OpsgenieConfig) *Opsgenie {
+	base := opsgenieURL
+	if cfg.Region == "eu" {
+		base = opsgenieEUURL
+	}
+	return &Opsgenie{apiKey: cfg.APIKey, url: base, tags: cfg.Tags, httpClient: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Name returns the action name of the notifier
+func (o *Opsgenie) Name() string {
+	return ActionOpsgenie
+}
+
+// opsgenieAlert is a create alert request
+type opsgenieAlert struct {
+	Message     string            `json:"message"` // At most 130 characters
+	Alias       string            `json:"alias"`   // Deduplication key
+	Description string            `json:"description,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Source      string            `json:"source"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+}
+
+// Notify creates an alert per event (Opsgenie counts repeats of an open alias),
+// or closes it for resolved events
+func (o *Opsgenie) Notify(ctx context.Context, events []models.Event) error {
+	headers := map[string]string{"Authorization": "GenieKey " + o.apiKey}
+	for _, event := range events {
+		alias := dedupKey(event)
+		target := o.url
+		var payload interface{}
+		if event.Resolved {
+			target = fmt.Sprintf("%s/%s/close?identifierType=alias", o.url, url.PathEscape(alias))
+			payload = map[string]string{"source": "netblocks", "note": event.Message}
+		} else {
+			description := event.Message
+			if event.Narrative != "" {
+				description += "\n\n" + event.Narrative
+			}
+			payload = opsgenieAlert{
+				Message:     truncate(event.Message, 130),
+				Alias:       alias,
+				Description: description,
+				Priority:    opsgeniePriority[event.Severity],
+				Source:      "netblocks",
+				Entity:      event.Target,
+				Tags:        o.tags,
+				Details: map[string]string{
+					"kind":     event.Kind,
+					"incident": event.Incident,
+					"time":     event.Timestamp.UTC().Format(time.RFC3339),
+				},
+			}
+		}
+
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to encode Opsgenie request: %w", err)
+		}
+		if err := postJSON(ctx, o.httpClient, target, body, headers); err != nil {
+			return fmt.Errorf("opsgenie %s: %w", alias, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// ActionPagerDuty opens and resolves PagerDuty incidents
+const ActionPagerDuty = "pagerduty"
+
+// pagerDutyURL is the PagerDuty Events API v2 endpoint
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers and resolves PagerDuty alerts through the Events API v2
+type PagerDuty struct {
+	routingKey string
+	url        string
+	httpClient *http.Client
+}
+
+// NewPagerDuty creates a PagerDuty notifier for the configured service
+func NewPagerDuty(cfg *config.PagerDutyConfig) *PagerDuty {
+	return &PagerDuty{routingKey: cfg.RoutingKey, url: pagerDutyURL, httpClient: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Name returns the action name of the notifier
+func (p *PagerDuty) Name() string {
+	return ActionPagerDuty
+}
+
+// pagerDutyEvent is an Events API v2 request
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"` // "trigger" or "resolve"
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"` // Only for triggers
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"` // "critical", "error", "warning" or "info"
+	Timestamp     string            `json:"timestamp"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// Notify triggers an alert per event, or resolves it for resolved events;
+// repeated triggers of one incident (escalation steps) are merged by PagerDuty
+func (p *PagerDuty) Notify(ctx context.Context, events []models.Event) error {
+	for _, event := range events {
+		request := pagerDutyEvent{RoutingKey: p.routingKey, EventAction: "trigger", DedupKey: dedupKey(event)}
+		if event.Resolved {
+			request.EventAction = "resolve"
+		} else {
+			request.Payload = &pagerDutyPayload{
+				Summary:   truncate(event.Message, 1024),
+				Source:    "netblocks",
+				Severity:  event.Severity,
+				Timestamp: event.Timestamp.UTC().Format(time.RFC3339),
+				Component: event.Target,
+				CustomDetails: map[string]string{
+					"kind":     event.Kind,
+					"target":   event.Target,
+					"incident": event.Incident,
+				},
+			}
+			if event.Narrative != "" {
+				request.Payload.CustomDetails["narrative"] = event.Narrative
+			}
+		}
+
+		body, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failed to encode PagerDuty event: %w", err)
+		}
+		if err := postJSON(ctx, p.httpClient, p.url, body, nil); err != nil {
+			return fmt.Errorf("pagerduty %s %s: %w", request.EventAction, request.DedupKey, err)
+		}
+	}
+	return nil
+}
+
+// dedupKey identifies the paging incident of an event: our incident ID when the
+// event belongs to an escalating incident, otherwise the rule (or target) itself
+func dedupKey(event models.Event) string {
+	if event.Incident != "" {
+		return "netblocks-" + event.Incident
+	}
+	return "netblocks-" + event.Kind + "-" + event.Target
+}
+
+// truncate shortens text to at most n bytes without splitting a UTF-8 character
+func truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	cut := n - len("…")
+	for cut > 0 && text[cut]&0xC0 == 0x80 {
+		cut--
+	}
+	return text[:cut] + "…"
+}
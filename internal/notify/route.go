@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// eventKinds are the event kinds routes can select
+var eventKinds = map[string]bool{"asn": true, "asn_share": true, "prefix": true, "dns": true, "traffic": true, "national": true, "rule": true, "correlated": true, "check": true, "subsystem": true, "annotation": true, "atlas": true, "campaign": true, "telegram": true, "slo": true}
+
+// templateFuncs are available in notifier templates besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// route is a parsed routing entry
+type route struct {
+	actions     []string
+	minSeverity string
+	kinds       map[string]bool // Empty for all kinds
+}
+
+// matches reports whether an event is routed by r
+func (r route) matches(event models.Event) bool {
+	if r.minSeverity != "" && severityRank[event.Severity] < severityRank[r.minSeverity] {
+		return false
+	}
+	return len(r.kinds) == 0 || r.kinds[event.Kind]
+}
+
+// parseRoutes validates the configured routes
+func parseRoutes(configs []config.RouteConfig) ([]route, error) {
+	var routes []route
+	for i, rc := range configs {
+		if len(rc.Actions) == 0 {
+			return nil, fmt.Errorf("route %d has no actions", i+1)
+		}
+		r := route{actions: rc.Actions, minSeverity: strings.ToLower(rc.MinSeverity)}
+		if _, ok := severityRank[r.minSeverity]; r.minSeverity != "" && !ok {
+			return nil, fmt.Errorf("route %d: unknown min_severity %q", i+1, rc.MinSeverity)
+		}
+		for _, kind := range rc.Kinds {
+			if !eventKinds[kind] {
+				return nil, fmt.Errorf("route %d: unknown event kind %q", i+1, kind)
+			}
+			if r.kinds == nil {
+				r.kinds = make(map[string]bool)
+			}
+			r.kinds[kind] = true
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// parseTemplates parses the notifier message templates by action
+func parseTemplates(texts map[string]string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	for action, text := range texts {
+		tmpl, err := template.New(action).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template for %q: %w", action, err)
+		}
+		templates[action] = tmpl
+	}
+	return templates, nil
+}
+
+// applyTemplate replaces the event messages with the action's template output;
+// events whose template fails keep their original message
+func applyTemplate(tmpl *template.Template, events []models.Event) []models.Event {
+	rendered := make([]models.Event, len(events))
+	for i, event := range events {
+		rendered[i] = event
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, event); err != nil {
+			log.Printf("⚠️  Template for %s failed: %v", tmpl.Name(), err)
+			continue
+		}
+		rendered[i].Message = strings.TrimSpace(buf.String())
+	}
+	return rendered
+}
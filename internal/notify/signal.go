@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// ActionSignal posts alerts to Signal groups
+const ActionSignal = "signal"
+
+// Signal posts alerts through a signal-cli-rest-api bridge
+type Signal struct {
+	url         string
+	number      string
+	recipients  []string
+	minSeverity string
+	httpClient  *http.Client
+}
+
+// NewSignal creates a Signal notifier for the configured bridge
+func NewSignal(cfg *config.SignalConfig) *Signal {
+	minSeverity := cfg.MinSeverity
+	if _, ok := severityRank[minSeverity]; !ok {
+		minSeverity = models.SeverityCritical
+	}
+	return &Signal{
+		url:         strings.TrimSuffix(cfg.URL, "/") + "/v2/send",
+		number:      cfg.Number,
+		recipients:  cfg.Recipients,
+		minSeverity: minSeverity,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name returns the action name of the notifier
+func (s *Signal) Name() string {
+	return ActionSignal
+}
+
+// Notify sends the events at or above the minimum severity as one message
+func (s *Signal) Notify(ctx context.Context, events []models.Event) error {
+	events = atLeast(events, s.minSeverity)
+	if len(events) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"message":    formatPlainAlerts(events),
+		"number":     s.number,
+		"recipients": s.recipients,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode Signal message: %w", err)
+	}
+	return postJSON(ctx, s.httpClient, s.url, body, nil)
+}
+
+// formatPlainAlerts formats events as plain text for channels without markup
+func formatPlainAlerts(events []models.Event) string {
+	title := "NetBlocks: network changes"
+	for _, event := range events {
+		if event.Severity == models.SeverityCritical {
+			title = "🚨 NetBlocks: critical network alert"
+		}
+	}
+	var builder strings.Builder
+	builder.WriteString(title + "\n")
+	for _, event := range events {
+		builder.WriteString(fmt.Sprintf("\n%s %s (%s UTC)", strings.ToUpper(event.Severity), event.Message, event.Timestamp.UTC().Format("15:04")))
+	}
+	for _, narrative := range models.Narratives(events) {
+		builder.WriteString("\n\n" + narrative)
+	}
+	return builder.String()
+}
@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// ActionSMS texts critical alerts
+const ActionSMS = "sms"
+
+// SMS providers
+const (
+	smsTwilio = "twilio" // Twilio Messages API (and compatible providers)
+	smsHTTP   = "http"   // Generic gateway accepting {"from", "to", "message"} JSON
+)
+
+// twilioURL is the default Twilio API base URL
+const twilioURL = "https://api.twilio.com"
+
+// maxSMSLength keeps a text within two concatenated SMS segments
+const maxSMSLength = 300
+
+// SMS texts critical alerts to phone numbers, for people without working data
+// connections during a shutdown
+type SMS struct {
+	cfg        *config.SMSConfig
+	httpClient *http.Client
+}
+
+// NewSMS creates an SMS notifier for the configured gateway
+func NewSMS(cfg *config.SMSConfig) (*SMS, error) {
+	switch cfg.Provider {
+	case "", smsTwilio:
+		if cfg.AccountSID == "" || cfg.AuthToken == "" {
+			return nil, fmt.Errorf("sms: twilio needs account_sid and auth_token")
+		}
+	case smsHTTP:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("sms: the http provider needs url")
+		}
+	default:
+		return nil, fmt.Errorf("sms: unknown provider %q", cfg.Provider)
+	}
+	return &SMS{cfg: cfg, httpClient: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// Name returns the action name of the notifier
+func (s *SMS) Name() string {
+	return ActionSMS
+}
+
+// Notify texts the critical events as one short message to every recipient
+func (s *SMS) Notify(ctx context.Context, events []models.Event) error {
+	events = atLeast(events, models.SeverityCritical)
+	if len(events) == 0 {
+		return nil
+	}
+	messages := make([]string, len(events))
+	for i, event := range events {
+		messages[i] = event.Message
+	}
+	text := truncate("NetBlocks ALERT: "+strings.Join(messages, "; "), maxSMSLength)
+
+	var failed []string
+	for _, to := range s.cfg.To {
+		var err error
+		if s.cfg.Provider == smsHTTP {
+			err = s.sendHTTP(ctx, to, text)
+		} else {
+			err = s.sendTwilio(ctx, to, text)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", to, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// sendTwilio sends one text through the Twilio Messages API
+func (s *SMS) sendTwilio(ctx context.Context, to, text string) error {
+	base := twilioURL
+	if s.cfg.URL != "" {
+		base = strings.TrimSuffix(s.cfg.URL, "/")
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", base, url.PathEscape(s.cfg.AccountSID))
+	form := url.Values{"To": {to}, "From": {s.cfg.From}, "Body": {text}}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.cfg.AccountSID, s.cfg.AuthToken)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("twilio returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sendHTTP sends one text through a generic JSON gateway
+func (s *SMS) sendHTTP(ctx context.Context, to, text string) error {
+	body, err := json.Marshal(map[string]string{"from": s.cfg.From, "to": to, "message": text})
+	if err != nil {
+		return err
+	}
+	var headers map[string]string
+	if s.cfg.AuthToken != "" {
+		headers = map[string]string{"Authorization": "Bearer " + s.cfg.AuthToken}
+	}
+	return postJSON(ctx, s.httpClient, s.cfg.URL, body, headers)
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Webhook posts events as JSON ({"events": [...]}) to each configured URL
+type Webhook struct {
+	name       string
+	urls       []string
+	httpClient *http.Client
+}
+
+// NewWebhook creates a webhook notifier for the given URLs, routed to by the action name
+func NewWebhook(name string, urls []string) *Webhook {
+	return &Webhook{name: name, urls: urls, httpClient: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Name returns the action name of the notifier
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+// Notify posts the events to every URL; failing URLs are reported together
+func (w *Webhook) Notify(ctx context.Context, events []models.Event) error {
+	body, err := json.Marshal(struct {
+		Events []models.Event `json:"events"`
+	}{events})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	var failed []string
+	for _, url := range w.urls {
+		if err := postJSON(ctx, w.httpClient, url, body, nil); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// postJSON posts a JSON body with extra headers and fails on non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
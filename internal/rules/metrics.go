@@ -0,0 +1,221 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// metric reads one value from a monitoring result for a metric reference; ok
+// is false when the data is unavailable (e.g. no traffic data or no DNS server
+// matches the filter)
+type metric func(result *models.MonitoringResult, ref value) (v interface{}, ok bool)
+
+// metrics are the names usable in conditions
+var metrics = map[string]metric{
+	"traffic.level":     trafficLevel,
+	"traffic.change":    trafficChange,
+	"traffic.status":    trafficStatus,
+	"national_score":    nationalScore,
+	"asn_connected_pct": asnConnectedPct,
+	"asn_down_count":    asnDownCount,
+	"dns_alive_pct":     dnsAlivePct,
+	"dns_down_count":    dnsDownCount,
+	"dns_error_count":   dnsErrorCount,
+}
+
+// MetricNames lists the metrics available in rule conditions
+func MetricNames() []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func trafficLevel(result *models.MonitoringResult, _ value) (interface{}, bool) {
+	if result.TrafficData == nil {
+		return nil, false
+	}
+	return result.TrafficData.CurrentLevel, true
+}
+
+func trafficChange(result *models.MonitoringResult, _ value) (interface{}, bool) {
+	if result.TrafficData == nil {
+		return nil, false
+	}
+	return result.TrafficData.ChangePercent, true
+}
+
+func trafficStatus(result *models.MonitoringResult, _ value) (interface{}, bool) {
+	if result.TrafficData == nil {
+		return nil, false
+	}
+	return result.TrafficData.Status, true
+}
+
+func nationalScore(result *models.MonitoringResult, _ value) (interface{}, bool) {
+	return result.NationalScore, true
+}
+
+// matchingASNs returns the ASN statuses selected by an optional asn=AS1|AS2 argument
+func matchingASNs(result *models.MonitoringResult, args map[string]string) []*models.ASNStatus {
+	var wanted map[string]bool
+	if list := args["asn"]; list != "" {
+		wanted = make(map[string]bool)
+		for _, asn := range strings.Split(list, "|") {
+			wanted[strings.ToUpper(strings.TrimSpace(asn))] = true
+		}
+	}
+	var statuses []*models.ASNStatus
+	for asn, status := range result.ASNStatuses {
+		if wanted == nil || wanted[strings.ToUpper(asn)] {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+func asnConnectedPct(result *models.MonitoringResult, ref value) (interface{}, bool) {
+	statuses := matchingASNs(result, ref.args)
+	if len(statuses) == 0 {
+		return nil, false
+	}
+	connected := 0
+	for _, status := range statuses {
+		if status.Connected {
+			connected++
+		}
+	}
+	return float64(connected) / float64(len(statuses)) * 100.0, true
+}
+
+func asnDownCount(result *models.MonitoringResult, ref value) (interface{}, bool) {
+	statuses := matchingASNs(result, ref.args)
+	if len(statuses) == 0 {
+		return nil, false
+	}
+	down := 0
+	for _, status := range statuses {
+		if !status.Connected {
+			down++
+		}
+	}
+	return float64(down), true
+}
+
+// matchingDNS returns the DNS statuses selected by optional province, city
+// and name (substring) arguments; a province of the config covers its cities,
+// any other province or city is matched against the place in the server name
+func matchingDNS(result *models.MonitoringResult, ref value) []*models.DNSStatus {
+	place := ref.args["province"]
+	if place == "" {
+		place = ref.args["city"]
+	}
+	name := strings.ToLower(ref.args["name"])
+
+	var statuses []*models.DNSStatus
+	for _, status := range result.DNSStatuses {
+		city := config.GetDNSCity(status.Name)
+		switch {
+		case ref.province != nil:
+			if !ref.province.Matches(city) {
+				continue
+			}
+		case place != "":
+			if !strings.EqualFold(city, place) {
+				continue
+			}
+		}
+		if name != "" && !strings.Contains(strings.ToLower(status.Name), name) {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func dnsAlivePct(result *models.MonitoringResult, ref value) (interface{}, bool) {
+	statuses := matchingDNS(result, ref)
+	if len(statuses) == 0 {
+		return nil, false
+	}
+	alive := 0
+	for _, status := range statuses {
+		if status.Alive {
+			alive++
+		}
+	}
+	return float64(alive) / float64(len(statuses)) * 100.0, true
+}
+
+func dnsDownCount(result *models.MonitoringResult, ref value) (interface{}, bool) {
+	statuses := matchingDNS(result, ref)
+	if len(statuses) == 0 {
+		return nil, false
+	}
+	down := 0
+	for _, status := range statuses {
+		if !status.Alive {
+			down++
+		}
+	}
+	return float64(down), true
+}
+
+// dnsErrorCount counts the DNS servers whose last check failed with one of the
+// error codes of code=TIMEOUT|REFUSED (any code without it)
+func dnsErrorCount(result *models.MonitoringResult, ref value) (interface{}, bool) {
+	statuses := matchingDNS(result, ref)
+	if len(statuses) == 0 {
+		return nil, false
+	}
+	var codes map[models.DNSErrorCode]bool
+	if list := ref.args["code"]; list != "" {
+		codes = make(map[models.DNSErrorCode]bool)
+		for _, code := range strings.Split(list, "|") {
+			codes[models.DNSErrorCode(strings.ToUpper(strings.TrimSpace(code)))] = true
+		}
+	}
+	count := 0
+	for _, status := range statuses {
+		if status.ErrorCode != "" && (codes == nil || codes[status.ErrorCode]) {
+			count++
+		}
+	}
+	return float64(count), true
+}
+
+// validateArgs rejects arguments a metric doesn't understand
+func validateArgs(v value) error {
+	allowed := map[string][]string{
+		"asn_connected_pct": {"asn"},
+		"asn_down_count":    {"asn"},
+		"dns_alive_pct":     {"province", "city", "name"},
+		"dns_down_count":    {"province", "city", "name"},
+		"dns_error_count":   {"code", "province", "city", "name"},
+	}[v.metric]
+	for arg := range v.args {
+		ok := false
+		for _, a := range allowed {
+			ok = ok || a == arg
+		}
+		if !ok {
+			return fmt.Errorf("%s does not take argument %q", v.metric, arg)
+		}
+	}
+	return nil
+}
+
+func sortedArgKeys(args map[string]string) []string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,291 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Condition grammar:
+//
+//	condition  = expr [ "for" duration ]
+//	expr       = and { "or" and }
+//	and        = comparison { "and" comparison }
+//	comparison = value ( "<" | "<=" | ">" | ">=" | "==" | "!=" ) value | "(" expr ")"
+//	value      = number | string | metric | metric "(" [ arg { "," arg } ] ")"
+//	arg        = name "=" word
+//
+// Examples: `dns_alive_pct(province=Tehran) < 50 for 10m`, `traffic.status == Shutdown`
+
+// token is one lexical token of a condition
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64 // For numbers
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+	tokAssign
+)
+
+// lex splits a condition into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")"})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokComma, text: ","})
+			i++
+		case r == '<' || r == '>' || r == '=' || r == '!':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{kind: tokOp, text: string(runes[i : i+2])})
+				i += 2
+				continue
+			}
+			switch r {
+			case '=':
+				tokens = append(tokens, token{kind: tokAssign, text: "="})
+			case '!':
+				return nil, fmt.Errorf("unexpected '!' at position %d", i)
+			default:
+				tokens = append(tokens, token{kind: tokOp, text: string(r)})
+			}
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			tokens = append(tokens, token{kind: tokString, text: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()<>=!,\"'", runes[i]) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected %q at position %d", r, i)
+			}
+			text := string(runes[start:i])
+			if n, err := strconv.ParseFloat(strings.TrimSuffix(text, "%"), 64); err == nil {
+				tokens = append(tokens, token{kind: tokNumber, text: text, value: n})
+			} else {
+				tokens = append(tokens, token{kind: tokWord, text: text})
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+// node is a parsed boolean expression
+type node interface{}
+
+type orNode struct{ left, right node }
+type andNode struct{ left, right node }
+
+type comparison struct {
+	left, right value
+	op          string
+}
+
+// value is a literal or a metric reference
+type value struct {
+	metric   string            // Empty for literals
+	args     map[string]string // Metric arguments
+	text     string            // Literal text (string literals and bare words)
+	number   float64
+	numeric  bool
+	province *config.Province // Province of the config named by the province argument
+}
+
+// String formats a value as written in the condition
+func (v value) String() string {
+	if v.metric == "" {
+		return v.text
+	}
+	if len(v.args) == 0 {
+		return v.metric
+	}
+	keys := sortedArgKeys(v.args)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+v.args[k])
+	}
+	return v.metric + "(" + strings.Join(parts, ", ") + ")"
+}
+
+// parser is a recursive descent parser over tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// parseCondition parses a full condition and its optional "for" duration
+func parseCondition(input string) (node, time.Duration, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var hold time.Duration
+	if t := p.peek(); t.kind == tokWord && strings.EqualFold(t.text, "for") {
+		p.next()
+		d := p.next()
+		hold, err = time.ParseDuration(d.text)
+		if err != nil || hold < 0 {
+			return nil, 0, fmt.Errorf("invalid duration %q after 'for'", d.text)
+		}
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, 0, fmt.Errorf("unexpected %q", t.text)
+	}
+	return expr, hold, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokWord && strings.EqualFold(t.text, "or"); t = p.peek() {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for t := p.peek(); t.kind == tokWord && strings.EqualFold(t.text, "and"); t = p.peek() {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return expr, nil
+	}
+
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected a comparison operator after %s", left)
+	}
+	right, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if left.metric == "" && right.metric == "" {
+		return nil, fmt.Errorf("comparison %s %s %s has no metric", left, op.text, right)
+	}
+	return comparison{left: left, op: op.text, right: right}, nil
+}
+
+func (p *parser) parseValue() (value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return value{text: t.text, number: t.value, numeric: true}, nil
+	case tokString:
+		return value{text: t.text}, nil
+	case tokWord:
+		if _, known := metrics[t.text]; !known {
+			// Bare words are literals, e.g. `traffic.status == Shutdown`
+			return value{text: t.text}, nil
+		}
+		v := value{metric: t.text, args: make(map[string]string)}
+		if p.peek().kind != tokLParen {
+			return v, nil
+		}
+		p.next()
+		for p.peek().kind != tokRParen {
+			name := p.next()
+			if name.kind != tokWord || p.next().kind != tokAssign {
+				return value{}, fmt.Errorf("arguments of %s must be name=value", t.text)
+			}
+			arg := p.next()
+			if arg.kind != tokWord && arg.kind != tokString && arg.kind != tokNumber {
+				return value{}, fmt.Errorf("missing value for argument %s of %s", name.text, t.text)
+			}
+			v.args[strings.ToLower(name.text)] = arg.text
+			if p.peek().kind == tokComma {
+				p.next()
+			}
+			if p.peek().kind == tokEOF {
+				return value{}, fmt.Errorf("missing ')' after arguments of %s", t.text)
+			}
+		}
+		p.next()
+		return v, nil
+	case tokEOF:
+		return value{}, fmt.Errorf("unexpected end of condition")
+	default:
+		return value{}, fmt.Errorf("expected a value, got %q", t.text)
+	}
+}
@@ -0,0 +1,264 @@
+// Package rules evaluates operator-defined alert rules against each monitoring cycle
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Rule is a parsed alert rule
+type Rule struct {
+	Name      string
+	Condition string
+	Severity  string
+	Actions   []string // Notifiers of the rule's events (empty: telegram)
+	Message   string
+	expr      node
+	hold      time.Duration // How long the condition must hold before firing
+}
+
+// ruleState tracks how long a rule's condition has held
+type ruleState struct {
+	since  time.Time // When the condition became true (zero while false)
+	firing bool
+}
+
+// Engine evaluates rules once per monitoring cycle
+type Engine struct {
+	rules []*Rule
+	state map[string]*ruleState
+}
+
+// NewEngine parses the configured rules; any invalid rule is an error
+// The province arguments of the rules name the provinces of the config
+func NewEngine(configs []config.RuleConfig, provinces []config.Province) (*Engine, error) {
+	e := &Engine{state: make(map[string]*ruleState)}
+	for i, rc := range configs {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if _, dup := e.state[name]; dup {
+			return nil, fmt.Errorf("duplicate rule name %q", name)
+		}
+
+		expr, hold, err := parseCondition(rc.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", name, err)
+		}
+		if err := validate(expr); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", name, err)
+		}
+		expr = bindProvinces(expr, provinces)
+
+		severity := rc.Severity
+		switch severity {
+		case "":
+			severity = models.SeverityWarning
+		case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+		default:
+			return nil, fmt.Errorf("rule %q: unknown severity %q", name, severity)
+		}
+		e.rules = append(e.rules, &Rule{
+			Name:      name,
+			Condition: rc.Condition,
+			Severity:  severity,
+			Actions:   rc.Actions,
+			Message:   rc.Message,
+			expr:      expr,
+			hold:      hold,
+		})
+		e.state[name] = &ruleState{}
+	}
+	return e, nil
+}
+
+// Rules returns the parsed rules
+func (e *Engine) Rules() []*Rule {
+	return e.rules
+}
+
+// validate checks the metric arguments of every comparison
+func validate(n node) error {
+	switch n := n.(type) {
+	case orNode:
+		if err := validate(n.left); err != nil {
+			return err
+		}
+		return validate(n.right)
+	case andNode:
+		if err := validate(n.left); err != nil {
+			return err
+		}
+		return validate(n.right)
+	case comparison:
+		for _, v := range []value{n.left, n.right} {
+			if v.metric != "" {
+				if err := validateArgs(v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// bindProvinces resolves the province arguments naming a province of the config
+func bindProvinces(n node, provinces []config.Province) node {
+	switch n := n.(type) {
+	case orNode:
+		return orNode{bindProvinces(n.left, provinces), bindProvinces(n.right, provinces)}
+	case andNode:
+		return andNode{bindProvinces(n.left, provinces), bindProvinces(n.right, provinces)}
+	case comparison:
+		for _, v := range []*value{&n.left, &n.right} {
+			name := v.args["province"]
+			for i := range provinces {
+				if name != "" && strings.EqualFold(provinces[i].Name, name) {
+					v.province = &provinces[i]
+				}
+			}
+		}
+		return n
+	}
+	return n
+}
+
+// Evaluate checks every rule against a result and returns the events of rules
+// that started firing (after holding for their duration) or recovered
+func (e *Engine) Evaluate(result *models.MonitoringResult) []models.Event {
+	if result == nil {
+		return nil
+	}
+	now := result.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var events []models.Event
+	for _, rule := range e.rules {
+		state := e.state[rule.Name]
+		var observed []string
+		holds := eval(rule.expr, result, &observed)
+
+		if !holds {
+			if state.firing {
+				events = append(events, models.Event{Timestamp: now, Kind: "rule", Target: rule.Name,
+					Severity: models.SeverityInfo, Actions: rule.Actions, Resolved: true,
+					Message: fmt.Sprintf("Rule %q resolved", rule.Name)})
+			}
+			*state = ruleState{}
+			continue
+		}
+
+		if state.since.IsZero() {
+			state.since = now
+		}
+		if state.firing || now.Sub(state.since) < rule.hold {
+			continue
+		}
+		state.firing = true
+
+		message := rule.Message
+		if message == "" {
+			message = fmt.Sprintf("Rule %q: %s", rule.Name, rule.Condition)
+		}
+		if len(observed) > 0 {
+			message += " (" + strings.Join(observed, ", ") + ")"
+		}
+		events = append(events, models.Event{Timestamp: now, Kind: "rule", Target: rule.Name,
+			Severity: rule.Severity, Actions: rule.Actions, Message: message})
+	}
+	return events
+}
+
+// eval evaluates an expression; metric values seen are appended to observed
+// Comparisons on unavailable data are false
+func eval(n node, result *models.MonitoringResult, observed *[]string) bool {
+	switch n := n.(type) {
+	case orNode:
+		return eval(n.left, result, observed) || eval(n.right, result, observed)
+	case andNode:
+		return eval(n.left, result, observed) && eval(n.right, result, observed)
+	case comparison:
+		left, ok := resolve(n.left, result, observed)
+		if !ok {
+			return false
+		}
+		right, ok := resolve(n.right, result, observed)
+		if !ok {
+			return false
+		}
+		return compare(left, n.op, right)
+	}
+	return false
+}
+
+// resolve returns the value of a literal or metric
+func resolve(v value, result *models.MonitoringResult, observed *[]string) (interface{}, bool) {
+	if v.metric == "" {
+		if v.numeric {
+			return v.number, true
+		}
+		return v.text, true
+	}
+	out, ok := metrics[v.metric](result, v)
+	if !ok {
+		return nil, false
+	}
+	switch x := out.(type) {
+	case float64:
+		*observed = append(*observed, fmt.Sprintf("%s = %s", v, strconv.FormatFloat(x, 'f', 1, 64)))
+	default:
+		*observed = append(*observed, fmt.Sprintf("%s = %v", v, x))
+	}
+	return out, true
+}
+
+// compare applies an operator to two numbers, or to two strings (== and != only, case-insensitive)
+func compare(left interface{}, op string, right interface{}) bool {
+	lf, lnum := toNumber(left)
+	rf, rnum := toNumber(right)
+	if lnum && rnum {
+		switch op {
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		case ">=":
+			return lf >= rf
+		case "==":
+			return lf == rf
+		case "!=":
+			return lf != rf
+		}
+		return false
+	}
+
+	ls, rs := fmt.Sprint(left), fmt.Sprint(right)
+	switch op {
+	case "==":
+		return strings.EqualFold(ls, rs)
+	case "!=":
+		return !strings.EqualFold(ls, rs)
+	}
+	return false
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSuffix(x, "%"), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,194 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+var testProvinces = []config.Province{
+	{Name: "Tehran", Capital: "Tehran", Cities: []string{"Shemiran"}},
+	{Name: "Isfahan", Capital: "Isfahan", Cities: []string{"Kashan"}},
+}
+
+// testResult returns a result with traffic at level and status, and DNS
+// servers alive by name
+func testResult(level float64, status string, dns map[string]bool) *models.MonitoringResult {
+	result := &models.MonitoringResult{
+		Timestamp:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		ASNStatuses: map[string]*models.ASNStatus{},
+		DNSStatuses: map[string]*models.DNSStatus{},
+		TrafficData: &models.TrafficData{CurrentLevel: level, Status: status},
+	}
+	for name, alive := range dns {
+		result.DNSStatuses[name] = &models.DNSStatus{Server: name, Name: name, Alive: alive}
+	}
+	return result
+}
+
+// holds reports whether a condition holds for a result
+func holds(t *testing.T, condition string, result *models.MonitoringResult) bool {
+	t.Helper()
+	e, err := NewEngine([]config.RuleConfig{{Name: "test", Condition: condition}}, testProvinces)
+	if err != nil {
+		t.Fatalf("NewEngine(%q): %v", condition, err)
+	}
+	var observed []string
+	return eval(e.rules[0].expr, result, &observed)
+}
+
+func TestEvaluateConditions(t *testing.T) {
+	tehran := map[string]bool{
+		"Shecan (Tehran)":       false,
+		"Electro (Shemiran)":    false,
+		"Pishgaman (Tehran)":    true,
+		"Asiatech (Isfahan)":    true,
+		"Radar Game (Kashan)":   true,
+		"Begzar (Tehran - Alt)": false,
+	}
+	tests := []struct {
+		name      string
+		condition string
+		result    *models.MonitoringResult
+		want      bool
+	}{
+		{"province below threshold", "dns_alive_pct(province=Tehran) < 50 for 10m", testResult(100, "Normal", tehran), true},
+		{"province above threshold", "dns_alive_pct(province=Isfahan) < 50 for 10m", testResult(100, "Normal", tehran), false},
+		{"province outside the config", "dns_alive_pct(province=Kashan) == 100", testResult(100, "Normal", tehran), true},
+		{"city", "dns_down_count(city=Shemiran) == 1", testResult(100, "Normal", tehran), true},
+		{"status shutdown", "traffic.status == Shutdown", testResult(2, "Shutdown", nil), true},
+		{"status case-insensitive", "traffic.status == shutdown", testResult(2, "Shutdown", nil), true},
+		{"status normal", "traffic.status == Shutdown", testResult(95, "Normal", nil), false},
+		{"and binds tighter than or", "traffic.level < 10 or traffic.level > 90 and national_score > 50", testResult(5, "Shutdown", nil), true},
+		{"parentheses group or", "(traffic.level < 10 or traffic.level > 90) and national_score > 50", testResult(5, "Shutdown", nil), false},
+		{"parentheses on the right", "national_score < 50 and (traffic.level > 90 or traffic.status == Shutdown)", testResult(5, "Shutdown", nil), true},
+		{"literal on the left", "50 > traffic.level", testResult(5, "Shutdown", nil), true},
+		{"percent literal", "traffic.level <= 5%", testResult(5, "Shutdown", nil), true},
+		{"no traffic data", "traffic.level < 10", &models.MonitoringResult{}, false},
+		{"no traffic data negated", "traffic.status != Normal", &models.MonitoringResult{}, false},
+		{"no matching DNS", "dns_alive_pct(province=Fars) < 50", testResult(100, "Normal", tehran), false},
+		{"no data or data", "traffic.level < 10 or national_score == 0", &models.MonitoringResult{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := holds(t, tt.condition, tt.result); got != tt.want {
+				t.Errorf("%q = %v, want %v", tt.condition, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEngineErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.RuleConfig
+		want string
+	}{
+		{"unknown argument", config.RuleConfig{Condition: "dns_alive_pct(asn=AS44244) < 50"}, `does not take argument "asn"`},
+		{"argument of a plain metric", config.RuleConfig{Condition: "traffic.level(province=Tehran) < 50"}, `does not take argument "province"`},
+		{"argument without value", config.RuleConfig{Condition: "dns_alive_pct(province=) < 50"}, "missing value"},
+		{"unclosed arguments", config.RuleConfig{Condition: "dns_alive_pct(province=Tehran < 50"}, "must be name=value"},
+		{"unclosed parenthesis", config.RuleConfig{Condition: "(traffic.level < 10"}, "missing ')'"},
+		{"no metric", config.RuleConfig{Condition: "Shutdown == Shutdown"}, "has no metric"},
+		{"missing operator", config.RuleConfig{Condition: "traffic.level 10"}, "expected a comparison operator"},
+		{"invalid duration", config.RuleConfig{Condition: "traffic.level < 10 for soon"}, "invalid duration"},
+		{"trailing tokens", config.RuleConfig{Condition: "traffic.level < 10 10"}, "unexpected"},
+		{"unknown severity", config.RuleConfig{Condition: "traffic.level < 10", Severity: "page"}, "unknown severity"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewEngine([]config.RuleConfig{tt.rule}, testProvinces)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("NewEngine(%q) error = %v, want %q", tt.rule.Condition, err, tt.want)
+			}
+		})
+	}
+
+	rules := []config.RuleConfig{{Name: "dup", Condition: "traffic.level < 10"}, {Name: "dup", Condition: "national_score < 10"}}
+	if _, err := NewEngine(rules, nil); err == nil || !strings.Contains(err.Error(), "duplicate rule name") {
+		t.Fatalf("duplicate names: error = %v", err)
+	}
+}
+
+func TestEvaluateHoldAndResolve(t *testing.T) {
+	e, err := NewEngine([]config.RuleConfig{{
+		Name:      "tehran-dns",
+		Condition: "dns_alive_pct(province=Tehran) < 50 for 10m",
+		Severity:  models.SeverityCritical,
+	}}, testProvinces)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	down := map[string]bool{"Shecan (Tehran)": false, "Electro (Shemiran)": true, "Begzar (Tehran)": false}
+	up := map[string]bool{"Shecan (Tehran)": true, "Electro (Shemiran)": true, "Begzar (Tehran)": false}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		at       time.Duration
+		dns      map[string]bool
+		fires    bool
+		resolves bool
+	}{
+		{0, down, false, false},
+		{5 * time.Minute, down, false, false},
+		{10 * time.Minute, down, true, false},
+		{15 * time.Minute, down, false, false}, // Fires once while it holds
+		{20 * time.Minute, up, false, true},
+		{25 * time.Minute, down, false, false}, // The hold starts over
+		{30 * time.Minute, up, false, false},   // Never fired: nothing to resolve
+		{35 * time.Minute, down, false, false},
+		{45 * time.Minute, down, true, false},
+	}
+	for _, step := range steps {
+		result := testResult(100, "Normal", step.dns)
+		result.Timestamp = start.Add(step.at)
+		events := e.Evaluate(result)
+
+		var fired, resolved bool
+		for _, event := range events {
+			if event.Target != "tehran-dns" {
+				t.Fatalf("at %s: event for %q", step.at, event.Target)
+			}
+			if event.Resolved {
+				resolved = true
+				continue
+			}
+			fired = true
+			if event.Severity != models.SeverityCritical {
+				t.Fatalf("at %s: severity = %q, want critical", step.at, event.Severity)
+			}
+			if !strings.Contains(event.Message, "dns_alive_pct(province=Tehran) = 33.3") {
+				t.Fatalf("at %s: message %q lacks the observed value", step.at, event.Message)
+			}
+		}
+		if fired != step.fires || resolved != step.resolves {
+			t.Fatalf("at %s: fired = %v, resolved = %v, want %v, %v", step.at, fired, resolved, step.fires, step.resolves)
+		}
+	}
+}
+
+func TestEvaluateNoData(t *testing.T) {
+	e, err := NewEngine([]config.RuleConfig{{Name: "shutdown", Condition: "traffic.status == Shutdown"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	result := testResult(2, "Shutdown", nil)
+	result.Timestamp = start
+	if events := e.Evaluate(result); len(events) != 1 || events[0].Resolved {
+		t.Fatalf("events = %+v, want the rule to fire", events)
+	}
+
+	// Losing the traffic data resolves the rule rather than keeping it firing
+	missing := &models.MonitoringResult{Timestamp: start.Add(time.Minute)}
+	if events := e.Evaluate(missing); len(events) != 1 || !events[0].Resolved {
+		t.Fatalf("events = %+v, want the rule to resolve", events)
+	}
+	if events := e.Evaluate(nil); events != nil {
+		t.Fatalf("events for no result = %+v", events)
+	}
+}
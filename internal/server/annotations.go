@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// AnnotationsPath lists the annotations of a period (GET, e.g. ?period=7d) and,
+// with the API token, adds one on the current time (POST {"text": "..."})
+const AnnotationsPath = "/api/v1/annotations"
+
+// maxAnnotationBody bounds the request body of a new annotation
+const maxAnnotationBody = 4 << 10
+
+// SetAnnotations serves the annotations of the monitor's history and, if token
+// is set, accepts new ones authenticated with "Authorization: Bearer <token>"
+// (call before Start)
+func (s *Server) SetAnnotations(add func(text, author string) (models.Annotation, error), token string) {
+	s.annotate = add
+	s.apiToken = token
+	s.mux.HandleFunc(AnnotationsPath, s.handleAnnotations)
+}
+
+// handleAnnotations handles GET and POST /api/v1/annotations; the optional "by"
+// query parameter of a POST names the author (default: "api")
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	if s.monitor == nil || s.monitor.History() == nil {
+		http.Error(w, "history is disabled on this instance", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		period := r.URL.Query().Get("period")
+		if period == "" {
+			period = "7d"
+		}
+		notes, err := s.monitor.Annotations(period)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if notes == nil {
+			notes = []models.Annotation{}
+		}
+		writeJSON(w, notes)
+	case http.MethodPost:
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.apiToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.apiToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAnnotationBody)).Decode(&body); err != nil {
+			http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		by := "api"
+		if name := r.URL.Query().Get("by"); name != "" {
+			by = "api:" + name
+		}
+		note, err := s.annotate(body.Text, by)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Charts and history responses show the new annotation right away
+		s.cache.clear()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(note)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long rendered API responses are served from memory
+// when no TTL is configured
+const DefaultCacheTTL = 30 * time.Second
+
+// cachedResponse is a rendered response and its validators
+type cachedResponse struct {
+	mu          sync.Mutex // Held while rendering, so concurrent misses render once
+	body        []byte
+	contentType string
+	etag        string
+	modified    time.Time // Last-Modified: when the data of the body was produced
+	expires     time.Time
+}
+
+// responseCache serves rendered JSON and chart bytes for ttl, so a burst of
+// public requests during an incident costs one render per path and TTL instead
+// of one monitor update (and upstream fetches) per request. Conditional
+// requests (If-None-Match, If-Modified-Since) are answered with 304 Not Modified.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration // 0 renders every request but still sets validators
+	entries map[string]*cachedResponse
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: make(map[string]*cachedResponse)}
+}
+
+// setTTL changes the TTL; entries rendered before keep their expiry
+func (c *responseCache) setTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// clear drops all entries, e.g. after a write that changes rendered responses
+func (c *responseCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cachedResponse)
+}
+
+// renderer produces a response body and when its data was produced; a
+// *statusError selects the HTTP status of a failure (default 503)
+type renderer func() (body []byte, contentType string, modified time.Time, err error)
+
+// statusError is a render failure answered with a specific status
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return e.message
+}
+
+// serve answers r from the entry of key, rendering it when missing or expired
+// Failed renders are not cached, and keys that never rendered are dropped
+func (c *responseCache) serve(w http.ResponseWriter, r *http.Request, key string, render renderer) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &cachedResponse{}
+		c.entries[key] = entry
+	}
+	ttl := c.ttl
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	now := time.Now()
+	if entry.body == nil || !now.Before(entry.expires) {
+		body, contentType, modified, err := render()
+		if err != nil {
+			if entry.body == nil {
+				c.mu.Lock()
+				delete(c.entries, key)
+				c.mu.Unlock()
+			}
+			entry.mu.Unlock()
+			status := http.StatusServiceUnavailable
+			if se, ok := err.(*statusError); ok {
+				status = se.status
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		// An unchanged body keeps its Last-Modified time
+		if etag != entry.etag || entry.modified.IsZero() {
+			if modified.IsZero() {
+				modified = now
+			}
+			entry.modified = modified.UTC().Truncate(time.Second)
+		}
+		entry.body, entry.contentType, entry.etag = body, contentType, etag
+		entry.expires = now.Add(ttl)
+	}
+	body, contentType, etag, modified := entry.body, entry.contentType, entry.etag, entry.modified
+	entry.mu.Unlock()
+
+	h := w.Header()
+	h.Set("ETag", etag)
+	h.Set("Last-Modified", modified.Format(http.TimeFormat))
+	h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(ttl/time.Second)))
+	if notModified(r, etag, modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", contentType)
+	_, _ = w.Write(body)
+}
+
+// notModified evaluates the conditional headers of r; If-None-Match takes
+// precedence over If-Modified-Since (RFC 9110)
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		return etagMatches(match, etag)
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		return !modified.After(since)
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match list contains etag (weak comparison)
+func etagMatches(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag || candidate == "W/"+etag {
+			return true
+		}
+	}
+	return false
+}
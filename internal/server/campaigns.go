@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// CampaignPath shows the running measurement campaign and the configured ones
+// (GET) and, with the API token, starts (POST {"name", "duration", "reason"})
+// or stops (DELETE) a campaign
+const CampaignPath = "/api/v1/campaign"
+
+// maxCampaignBody bounds the request body of a campaign start
+const maxCampaignBody = 4 << 10
+
+// campaignResponse is the running campaign and the configured profiles
+type campaignResponse struct {
+	Campaign *models.Campaign `json:"campaign"` // null if none is running
+	Profiles []string         `json:"profiles"`
+}
+
+// SetCampaigns serves the measurement campaigns of the monitor and, if token
+// is set, accepts starts and stops authenticated with "Authorization: Bearer
+// <token>" (call before Start)
+func (s *Server) SetCampaigns(token string) {
+	s.apiToken = token
+	s.mux.HandleFunc(CampaignPath, s.handleCampaign)
+}
+
+// handleCampaign handles GET, POST and DELETE /api/v1/campaign; the optional
+// "by" query parameter of a POST or DELETE names the operator (default: "api")
+func (s *Server) handleCampaign(w http.ResponseWriter, r *http.Request) {
+	if s.monitor == nil {
+		http.Error(w, "campaigns need the monitor of this instance", http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		writeJSON(w, campaignResponse{Campaign: s.monitor.Campaign(), Profiles: s.monitor.CampaignProfiles()})
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if s.apiToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.apiToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	by := "api"
+	if name := r.URL.Query().Get("by"); name != "" {
+		by = "api:" + name
+	}
+
+	if r.Method == http.MethodDelete {
+		campaign, err := s.monitor.StopCampaign(by)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		s.cache.clear()
+		writeJSON(w, campaign)
+		return
+	}
+
+	var body struct {
+		Name     string `json:"name"`
+		Duration string `json:"duration"` // Default: the campaign's
+		Reason   string `json:"reason"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCampaignBody)).Decode(&body); err != nil {
+		http.Error(w, "invalid JSON body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var duration time.Duration
+	if body.Duration != "" {
+		d, err := time.ParseDuration(body.Duration)
+		if err != nil {
+			http.Error(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		duration = d
+	}
+	campaign, err := s.monitor.StartCampaign(body.Name, duration, by, body.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// The status shows the campaign right away
+	s.cache.clear()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(campaign)
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// ChartsPath serves the charts of the current result as PNG for websites to
+// hotlink, e.g. /charts/traffic.png; they carry the cache headers of the API
+const ChartsPath = "/charts/"
+
+// APIChartsPath serves the same charts below the API, e.g. /api/v1/charts/traffic.png
+const APIChartsPath = "/api/v1/charts/"
+
+// chartAliases are short chart names mapped to their image keys (see models.MonitoringResult.Images)
+var chartAliases = map[string]string{
+	"asn": "asn_traffic",
+}
+
+func (s *Server) handleChart(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(path.Base(r.URL.Path), ".png")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if key, ok := chartAliases[name]; ok {
+		name = key
+	}
+	s.cache.serve(w, r, r.URL.Path, func() ([]byte, string, time.Time, error) {
+		result, err := s.results()
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		image, ok := result.Images()[name]
+		if !ok || *image == nil || (*image).Len() == 0 {
+			return nil, "", time.Time{}, &statusError{status: http.StatusNotFound, message: "chart " + name + " is not available"}
+		}
+		return (*image).Bytes(), "image/png", result.Timestamp, nil
+	})
+}
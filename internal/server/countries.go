@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// CountriesPath lists the countries served under /api/v1/<country>/
+const CountriesPath = "/api/v1/countries"
+
+// AddCountry serves a country's status, history, snapshot and map under its own
+// namespace, e.g. /api/v1/ir/status; mon is the country's monitor and geo its
+// province map, or nil for the country this server already serves at /api/v1/
+// (call before Start)
+func (s *Server) AddCountry(country string, mon *monitor.Monitor, geo *ProvinceMap) {
+	country = strings.ToLower(country)
+	sub := s
+	if mon != nil {
+		sub = &Server{monitor: mon, cache: s.cache, geo: geo}
+	}
+	if s.countries == nil {
+		s.mux.HandleFunc(CountriesPath, s.handleCountries)
+	}
+	s.countries = append(s.countries, country)
+
+	prefix := "/api/v1/" + country
+	s.mux.HandleFunc(prefix+"/", func(w http.ResponseWriter, r *http.Request) {
+		route := strings.TrimPrefix(r.URL.Path, prefix)
+		if strings.HasPrefix(route, "/charts/") {
+			sub.handleChart(w, r)
+			return
+		}
+		switch route {
+		case "/status":
+			sub.handleStatus(w, r)
+		case "/history":
+			sub.handleHistory(w, r)
+		case "/snapshot":
+			sub.handleSnapshot(w, r)
+		case "/map.geojson":
+			sub.handleMap(w, r)
+		case "/export":
+			s.gated(sub.handleExport)(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func (s *Server) handleCountries(w http.ResponseWriter, r *http.Request) {
+	countries := append([]string(nil), s.countries...)
+	sort.Strings(countries)
+	writeJSON(w, countries)
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// ExportPath serves the persisted history of a period as a JSON or CSV download,
+// e.g. /api/v1/export?format=csv&period=7d; it is gated (see SetProtection)
+const ExportPath = "/api/v1/export"
+
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = history.FormatJSON
+	}
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "24h"
+	}
+	if format != history.FormatJSON && format != history.FormatCSV {
+		http.Error(w, fmt.Sprintf("unsupported export format %q (use json or csv)", format), http.StatusBadRequest)
+		return
+	}
+	if _, err := monitor.ParseChartPeriod(period); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.monitor == nil || s.monitor.History() == nil {
+		http.Error(w, "history is disabled on this instance", http.StatusNotFound)
+		return
+	}
+
+	contentType := "application/json"
+	if format == history.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="netblocks-history-%s.%s"`, period, format))
+	s.cache.serve(w, r, r.URL.Path+"?format="+format+"&period="+period, func() ([]byte, string, time.Time, error) {
+		data, _, err := s.monitor.ExportHistory(format, period)
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		return data, contentType, time.Time{}, nil
+	})
+}
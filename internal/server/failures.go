@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// FailuresPath serves why DNS checks failed: in the last cycle and per day or
+// hour of a period, e.g. /api/v1/failures?period=7d&step=day
+const FailuresPath = "/api/v1/failures"
+
+// failuresResponse breaks the failed and anomalous DNS checks down by error code
+type failuresResponse struct {
+	Period  string                  `json:"period"`
+	Step    string                  `json:"step"` // day or hour
+	Cycle   cycleFailures           `json:"cycle"`
+	Buckets []history.FailureBucket `json:"buckets"` // Oldest first, without the buckets that had no failure
+}
+
+type cycleFailures struct {
+	Timestamp time.Time                   `json:"timestamp"`
+	Counts    map[models.DNSErrorCode]int `json:"counts"`
+	Total     int                         `json:"total"`
+	Checks    int                         `json:"checks"` // DNS servers checked in the cycle
+}
+
+func (s *Server) handleFailures(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "7d"
+	}
+	hours, err := monitor.ParseChartPeriod(period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	step := r.URL.Query().Get("step")
+	var bucket time.Duration
+	switch step {
+	case "", "day":
+		step, bucket = "day", 24*time.Hour
+	case "hour":
+		bucket = time.Hour
+	default:
+		http.Error(w, fmt.Sprintf("unknown step %q: use day or hour", step), http.StatusBadRequest)
+		return
+	}
+
+	if s.monitor == nil || s.monitor.History() == nil {
+		http.Error(w, "history is disabled on this instance", http.StatusNotFound)
+		return
+	}
+
+	s.cache.serve(w, r, r.URL.Path+"?period="+period+"&step="+step, func() ([]byte, string, time.Time, error) {
+		result, err := s.results()
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		resp := failuresResponse{
+			Period: period,
+			Step:   step,
+			Cycle:  cycleFailures{Timestamp: result.Timestamp, Counts: monitor.FailureCauses(result), Checks: len(result.DNSStatuses)},
+		}
+		for _, count := range resp.Cycle.Counts {
+			resp.Cycle.Total += count
+		}
+		start := time.Now().UTC().Truncate(time.Hour).Add(-time.Duration(hours-1) * time.Hour)
+		resp.Buckets = append([]history.FailureBucket{}, s.monitor.History().DNSFailures(start.Truncate(bucket), bucket)...)
+		return renderJSON(resp, result.Timestamp)
+	})
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// MapPath serves the per-province health as GeoJSON for mapping tools and the dashboard map
+const MapPath = "/api/v1/map.geojson"
+
+// ProvinceMap places the health of the DNS servers of each province on a map:
+// on the province polygons of map_boundaries, or at the province capitals
+type ProvinceMap struct {
+	provinces []config.Province
+	shapes    *monitor.ProvinceShapes // nil without map_boundaries
+}
+
+// NewProvinceMap loads the provinces of cfg and the polygons of map_boundaries,
+// if set; it returns nil if the country has no provinces
+func NewProvinceMap(cfg *config.Config) (*ProvinceMap, error) {
+	if len(cfg.Provinces) == 0 {
+		return nil, nil
+	}
+	m := &ProvinceMap{provinces: cfg.Provinces}
+	if cfg.MapBoundaries != "" {
+		shapes, err := monitor.LoadProvinceShapes(cfg.MapBoundaries, cfg.Provinces)
+		if err != nil {
+			return nil, fmt.Errorf("map_boundaries: %w", err)
+		}
+		m.shapes = shapes
+	}
+	return m, nil
+}
+
+// geoFeatureCollection is a GeoJSON FeatureCollection (RFC 7946) with the time
+// and national score of the result as foreign members
+type geoFeatureCollection struct {
+	Type          string       `json:"type"`
+	Timestamp     time.Time    `json:"timestamp"`
+	NationalScore float64      `json:"national_score"`
+	Features      []geoFeature `json:"features"`
+}
+
+type geoFeature struct {
+	Type       string             `json:"type"`
+	Geometry   json.RawMessage    `json:"geometry"`
+	Properties provinceProperties `json:"properties"`
+}
+
+// provinceProperties are the health attributes of a province
+type provinceProperties struct {
+	Name          string   `json:"name"`
+	Capital       string   `json:"capital,omitempty"`
+	Status        string   `json:"status"` // normal, degraded, disrupted or unknown
+	DNSTotal      int      `json:"dns_total"`
+	DNSAlive      int      `json:"dns_alive"`
+	DNSAlivePct   *float64 `json:"dns_alive_pct"`   // null without DNS servers
+	AvgResponseMs *float64 `json:"avg_response_ms"` // Mean response time of the alive servers, null if none
+	Fill          string   `json:"fill"`
+	FillOpacity   float64  `json:"fill-opacity"`
+}
+
+// build turns the province health of a result into GeoJSON features
+func (m *ProvinceMap) build(result *models.MonitoringResult) geoFeatureCollection {
+	collection := geoFeatureCollection{
+		Type:          "FeatureCollection",
+		Timestamp:     result.Timestamp,
+		NationalScore: result.NationalScore,
+		Features:      make([]geoFeature, 0, len(m.provinces)),
+	}
+	for _, health := range monitor.ProvinceHealths(m.provinces, result) {
+		color := monitor.ProvinceColor(health.Status)
+		props := provinceProperties{
+			Name:        health.Province.Name,
+			Capital:     health.Province.Capital,
+			Status:      health.Status,
+			DNSTotal:    health.DNSTotal,
+			DNSAlive:    health.DNSAlive,
+			Fill:        fmt.Sprintf("#%02x%02x%02x", color.R, color.G, color.B),
+			FillOpacity: 0.6,
+		}
+		if health.DNSTotal > 0 {
+			score := health.Score
+			props.DNSAlivePct = &score
+		}
+		if health.DNSAlive > 0 {
+			ms := float64(health.AvgResponse.Milliseconds())
+			props.AvgResponseMs = &ms
+		}
+
+		geometry, ok := m.shapes.Geometry(health.Province.Name)
+		if !ok {
+			geometry, _ = json.Marshal(map[string]interface{}{"type": "Point", "coordinates": []float64{health.Province.Lon, health.Province.Lat}})
+		}
+		collection.Features = append(collection.Features, geoFeature{Type: "Feature", Geometry: geometry, Properties: props})
+	}
+	return collection
+}
+
+// SetMap serves the province map of the country at MapPath (call before Start)
+func (s *Server) SetMap(m *ProvinceMap) {
+	s.geo = m
+}
+
+func (s *Server) handleMap(w http.ResponseWriter, r *http.Request) {
+	if s.geo == nil {
+		http.Error(w, "no provinces are configured for this country", http.StatusNotFound)
+		return
+	}
+	s.cache.serve(w, r, r.URL.Path, func() ([]byte, string, time.Time, error) {
+		result, err := s.results()
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		body, _, modified, err := renderJSON(s.geo.build(result), result.Timestamp)
+		return body, "application/geo+json", modified, err
+	})
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/escalation"
+)
+
+// incidentsPath lists open incidents; POST incidentsPath/<id>/ack acknowledges one
+const incidentsPath = "/api/v1/incidents"
+
+// SetIncidents serves open incidents and, if token is set, acknowledgements
+// authenticated with "Authorization: Bearer <token>" (call before Start)
+func (s *Server) SetIncidents(list func() ([]escalation.Incident, error), ack func(id, by string) error, token string) {
+	s.incidents = list
+	s.ack = ack
+	s.apiToken = token
+	s.mux.HandleFunc(incidentsPath, s.handleIncidents)
+	s.mux.HandleFunc(incidentsPath+"/", s.handleAck)
+}
+
+func (s *Server) handleIncidents(w http.ResponseWriter, r *http.Request) {
+	incidents, err := s.incidents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if incidents == nil {
+		incidents = []escalation.Incident{}
+	}
+	writeJSON(w, incidents)
+}
+
+// handleAck handles POST /api/v1/incidents/<id>/ack; the optional "by" query
+// parameter names who acknowledged (default: "api")
+func (s *Server) handleAck(w http.ResponseWriter, r *http.Request) {
+	id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, incidentsPath+"/"), "/ack")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if s.apiToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.apiToken)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	by := "api"
+	if name := r.URL.Query().Get("by"); name != "" {
+		by = "api:" + name
+	}
+	if err := s.ack(id, by); err != nil {
+		if errors.Is(err, escalation.ErrUnknownIncident) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
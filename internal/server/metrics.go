@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// MetricsPath serves the monitor's cycle and data source metrics in the
+// Prometheus text format
+const MetricsPath = "/metrics"
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if s.monitor == nil {
+		http.Error(w, "metrics are served by the monitor process", http.StatusNotFound)
+		return
+	}
+	stats := s.monitor.Stats()
+	cycles := stats.Cycles
+
+	var b strings.Builder
+	metric := func(name, kind, help string, value float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+	}
+	metric("netblocks_cycles_total", "counter", "Completed monitoring cycles.", float64(cycles.Count))
+	metric("netblocks_cycles_failed_total", "counter", "Monitoring cycles with at least one failed data source, chart or store.", float64(cycles.Failed))
+	metric("netblocks_cycle_events_total", "counter", "Changes detected over all cycles.", float64(cycles.Events))
+	metric("netblocks_cycle_duration_seconds_sum", "counter", "Total duration of all monitoring cycles.", cycles.TotalDuration.Seconds())
+	metric("netblocks_cycle_duration_seconds_max", "gauge", "Longest monitoring cycle.", cycles.MaxDuration.Seconds())
+	if last := cycles.Last; last != nil {
+		metric("netblocks_last_cycle_duration_seconds", "gauge", "Duration of the last monitoring cycle.", last.Duration.Seconds())
+		metric("netblocks_last_cycle_timestamp_seconds", "gauge", "Start of the last monitoring cycle (Unix time).", float64(last.Start.Unix()))
+		metric("netblocks_last_cycle_failures", "gauge", "Failed data sources, charts and stores in the last cycle.", float64(len(last.Failures)))
+		metric("netblocks_asns_down", "gauge", "ASNs without BGP updates in the last cycle.", float64(last.ASNsDown))
+		metric("netblocks_dns_down", "gauge", "DNS servers not answering in the last cycle.", float64(last.DNSDown))
+		if len(last.DNSErrors) > 0 {
+			codes := make([]string, 0, len(last.DNSErrors))
+			for code := range last.DNSErrors {
+				codes = append(codes, string(code))
+			}
+			sort.Strings(codes)
+			b.WriteString("# HELP netblocks_dns_errors Failed and anomalous DNS checks of the last cycle by error code.\n# TYPE netblocks_dns_errors gauge\n")
+			for _, code := range codes {
+				fmt.Fprintf(&b, "netblocks_dns_errors{code=%q} %d\n", code, last.DNSErrors[models.DNSErrorCode(code)])
+			}
+		}
+	}
+	metric("netblocks_dns_check_duration_seconds", "gauge", "Duration of the last full DNS check.", stats.DNSCycleDuration.Seconds())
+	metric("netblocks_ris_reconnects_total", "counter", "RIS Live WebSocket reconnects.", float64(stats.RISReconnects))
+	if !stats.LastCloudflareFetch.IsZero() {
+		metric("netblocks_cloudflare_last_fetch_timestamp_seconds", "gauge", "Last successful Cloudflare Radar fetch (Unix time).", float64(stats.LastCloudflareFetch.Unix()))
+	}
+
+	renders := stats.Renders
+	metric("netblocks_chart_renders_total", "counter", "Charts rendered.", float64(renders.Rendered))
+	metric("netblocks_chart_renders_shared_total", "counter", "Chart requests served by a render of the same chart in progress.", float64(renders.Shared))
+	metric("netblocks_chart_renders_rejected_total", "counter", "Chart requests refused because too many renders were waiting.", float64(renders.Rejected))
+	metric("netblocks_chart_renders_waiting", "gauge", "Chart renders waiting for a slot or memory.", float64(renders.Waiting))
+	radar := stats.Radar
+	metric("netblocks_cloudflare_calls_total", "counter", "Cloudflare Radar API calls.", float64(radar.Calls))
+	metric("netblocks_cloudflare_calls_today", "gauge", "Cloudflare Radar API calls this UTC day.", float64(radar.CallsToday))
+	metric("netblocks_cloudflare_rate_limited_total", "counter", "Cloudflare Radar API calls answered with 429.", float64(radar.RateLimited))
+	if radar.DailyBudget > 0 {
+		metric("netblocks_cloudflare_daily_budget_remaining", "gauge", "Cloudflare Radar API calls left of cloudflare_budget.daily_calls today.", float64(max(radar.DailyBudget-radar.CallsToday, 0)))
+	}
+	if radar.Limit > 0 {
+		metric("netblocks_cloudflare_rate_limit_remaining", "gauge", "Calls left in the Cloudflare Radar rate limit window of the last response.", float64(radar.Remaining))
+	}
+	metric("netblocks_cloudflare_tokens", "gauge", "Cloudflare credentials configured.", float64(stats.RadarTokens))
+	metric("netblocks_cloudflare_tokens_usable", "gauge", "Cloudflare credentials not paused after a 429, 401 or 403.", float64(stats.RadarTokensUsable))
+	degraded := 0.0
+	if radar.Degraded {
+		degraded = 1
+	}
+	metric("netblocks_cloudflare_degraded", "gauge", "1 while Radar fetches are spaced out to save the API budget.", degraded)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Defaults of the server protection
+const (
+	defaultBurst     = 20
+	maxLimiterIPs    = 10000            // Tracked clients before idle ones are dropped
+	turnstilePass    = 30 * time.Minute // How long a solved Turnstile challenge opens expensive endpoints for its IP
+	turnstileTimeout = 10 * time.Second
+)
+
+// TurnstileVerifyURL is Cloudflare's Turnstile token verification endpoint
+const TurnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+
+// SetProtection enables the access log, the per-IP rate limit and the gating of
+// expensive endpoints (call before Start)
+func (s *Server) SetProtection(p config.ServerProtection) {
+	s.protection = p
+	if p.RateLimit > 0 {
+		burst := p.Burst
+		if burst <= 0 {
+			burst = defaultBurst
+		}
+		s.limiter = newIPLimiter(p.RateLimit, burst)
+	}
+	if p.TurnstileSecret != "" {
+		s.passes = make(map[string]time.Time)
+	}
+}
+
+// serveHTTP rate limits and logs every request before handing it to the mux
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ip := s.clientIP(r)
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	if ok, retry := s.limiter.allow(ip, start); !ok {
+		rec.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		http.Error(rec, "too many requests", http.StatusTooManyRequests)
+	} else {
+		s.mux.ServeHTTP(rec, r)
+	}
+
+	if s.protection.AccessLog {
+		slog.Info("http request",
+			"method", r.Method, "path", r.URL.Path, "status", rec.status, "bytes", rec.bytes,
+			"duration", time.Since(start).Round(time.Microsecond), "client", ip, "user_agent", r.UserAgent())
+	}
+}
+
+// clientIP returns the IP of the client, taken from the proxy headers when trusted
+func (s *Server) clientIP(r *http.Request) string {
+	if s.protection.TrustProxy {
+		if ip := strings.TrimSpace(r.Header.Get("CF-Connecting-IP")); ip != "" {
+			return ip
+		}
+		// Clients can send X-Forwarded-For themselves: only the entries
+		// appended by the trusted proxies, from the right, are genuine
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(strings.Join(forwarded, ","), ",")
+			hops := s.protection.TrustedHops
+			if hops <= 0 {
+				hops = 1
+			}
+			if hops > len(entries) {
+				hops = len(entries)
+			}
+			if ip := strings.TrimSpace(entries[len(entries)-hops]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// gated opens an expensive endpoint to requests with an access token or a solved
+// Turnstile challenge; without tokens and Turnstile secret it is open to everyone
+func (s *Server) gated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.protection.Tokens) == 0 && s.protection.TurnstileSecret == "" {
+			next(w, r)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		for _, allowed := range s.protection.Tokens {
+			if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+				next(w, r)
+				return
+			}
+		}
+		if s.protection.TurnstileSecret != "" && s.passTurnstile(r) {
+			next(w, r)
+			return
+		}
+		http.Error(w, "this endpoint requires an access token or a solved Turnstile challenge", http.StatusForbidden)
+	}
+}
+
+// passTurnstile reports whether the client solved a Turnstile challenge, now
+// (CF-Turnstile-Response header or cf-turnstile-response parameter) or within turnstilePass
+func (s *Server) passTurnstile(r *http.Request) bool {
+	ip := s.clientIP(r)
+	now := time.Now()
+	s.passesMu.Lock()
+	until, ok := s.passes[ip]
+	s.passesMu.Unlock()
+	if ok && now.Before(until) {
+		return true
+	}
+
+	response := r.Header.Get("CF-Turnstile-Response")
+	if response == "" {
+		response = r.FormValue("cf-turnstile-response")
+	}
+	if response == "" {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), turnstileTimeout)
+	defer cancel()
+	ok, err := verifyTurnstile(ctx, s.protection.TurnstileSecret, response, ip)
+	if err != nil {
+		log.Printf("⚠️  Turnstile verification failed: %v", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	s.passesMu.Lock()
+	defer s.passesMu.Unlock()
+	for client, until := range s.passes {
+		if now.After(until) {
+			delete(s.passes, client)
+		}
+	}
+	s.passes[ip] = now.Add(turnstilePass)
+	return true
+}
+
+// verifyTurnstile checks a Turnstile response token with Cloudflare
+func verifyTurnstile(ctx context.Context, secret, response, ip string) (bool, error) {
+	form := url.Values{"secret": {secret}, "response": {response}, "remoteip": {ip}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, TurnstileVerifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Success, nil
+}
+
+// ipLimiter is a token bucket per client IP
+type ipLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens added per second
+	burst   float64
+	clients map[string]*ipBucket
+}
+
+type ipBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newIPLimiter(rate float64, burst int) *ipLimiter {
+	return &ipLimiter{rate: rate, burst: float64(burst), clients: make(map[string]*ipBucket)}
+}
+
+// allow takes a token of ip's bucket, or reports how long until one is available
+// A nil limiter allows everything
+func (l *ipLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.clients[ip]
+	if !ok {
+		if len(l.clients) >= maxLimiterIPs {
+			l.prune(now)
+		}
+		b = &ipBucket{tokens: l.burst, last: now}
+		l.clients[ip] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops clients whose buckets have refilled; they start full again anyway
+func (l *ipLimiter) prune(now time.Time) {
+	for ip, b := range l.clients {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// statusRecorder captures the status and size of a response for the access log
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/aggregator"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/escalation"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+	"github.com/netblocks/netblocks/internal/version"
+)
+
+//go:embed dashboard.html
+var dashboardHTML []byte
+
+// startedAt is the Last-Modified time of the embedded dashboard
+var startedAt = time.Now()
+
+// Server serves the web dashboard and the JSON API used by its interactive charts
+type Server struct {
+	httpServer *http.Server
+	mux        *http.ServeMux
+	monitor    *monitor.Monitor
+	aggregator *aggregator.Aggregator                               // Set in aggregator mode; status then merges probe submissions
+	provider   func() (*models.MonitoringResult, error)             // Replaces the monitor as status source (e.g. shared Redis state)
+	incidents  func() ([]escalation.Incident, error)                // Lists open incidents (set with SetIncidents)
+	ack        func(id, by string) error                            // Acknowledges an incident
+	annotate   func(text, author string) (models.Annotation, error) // Adds an annotation (set with SetAnnotations)
+	apiToken   string                                               // Bearer token of write calls; empty disables them
+	eventsMu   sync.Mutex
+	events     []models.Event // Recent public events served in the snapshot
+	countries  []string       // Country namespaces registered with AddCountry
+	cache      *responseCache // Rendered responses of the public API (see SetCacheTTL)
+	geo        *ProvinceMap   // Province map served at MapPath (see SetMap)
+	protection config.ServerProtection
+	limiter    *ipLimiter // Per-IP rate limit (nil: unlimited)
+	passesMu   sync.Mutex
+	passes     map[string]time.Time // Client IPs that solved a Turnstile challenge, until when
+}
+
+// NewServer creates a new dashboard server listening on addr (e.g. ":8080")
+// mon may be nil when a results provider is set; history is then unavailable
+func NewServer(addr string, mon *monitor.Monitor) *Server {
+	s := &Server{monitor: mon, cache: newResponseCache(DefaultCacheTTL)}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/", s.handleDashboard)
+	s.mux.HandleFunc("/api/v1/status", s.handleStatus)
+	s.mux.HandleFunc("/api/v1/history", s.handleHistory)
+	s.mux.HandleFunc(FailuresPath, s.handleFailures)
+	s.mux.HandleFunc(SnapshotPath, s.handleSnapshot)
+	s.mux.HandleFunc(MapPath, s.handleMap)
+	s.mux.HandleFunc(ChartsPath, s.handleChart)
+	s.mux.HandleFunc(APIChartsPath, s.handleChart)
+	s.mux.HandleFunc(ExportPath, s.gated(s.handleExport))
+	s.mux.HandleFunc(MetricsPath, s.handleMetrics)
+	s.mux.HandleFunc(VersionPath, s.handleVersion)
+
+	s.httpServer = &http.Server{
+		Addr:              addr,
+		Handler:           http.HandlerFunc(s.serveHTTP),
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+	}
+	return s
+}
+
+// SetAggregator accepts probe submissions and serves the merged status (call before Start)
+func (s *Server) SetAggregator(agg *aggregator.Aggregator) {
+	s.aggregator = agg
+	s.mux.HandleFunc(aggregator.SubmitPath, agg.HandleSubmit)
+}
+
+// SetResultsProvider serves the status from provider instead of the monitor
+func (s *Server) SetResultsProvider(provider func() (*models.MonitoringResult, error)) {
+	s.provider = provider
+}
+
+// SetCacheTTL sets how long rendered status, history, snapshot and chart
+// responses are served from memory (default: DefaultCacheTTL; 0 renders every request)
+func (s *Server) SetCacheTTL(ttl time.Duration) {
+	s.cache.setTTL(ttl)
+}
+
+// Start serves HTTP until the context is cancelled
+func (s *Server) Start(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("🌐 Dashboard server listening on %s", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("❌ Dashboard server error: %v", err)
+	}
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.cache.serve(w, r, "/", func() ([]byte, string, time.Time, error) {
+		return dashboardHTML, "text/html; charset=utf-8", startedAt, nil
+	})
+}
+
+// handleStatus serves the status as JSON, or with ?format=plain as short
+// sentences for screen readers (?lang=fa for Persian)
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+	case "plain":
+		lang := i18n.Normalize(r.URL.Query().Get("lang"))
+		s.cache.serve(w, r, r.URL.Path+"?format=plain&lang="+lang, func() ([]byte, string, time.Time, error) {
+			result, err := s.results()
+			if err != nil {
+				return nil, "", time.Time{}, err
+			}
+			return []byte(monitor.PlainStatus(result, lang) + "\n"), "text/plain; charset=utf-8", result.Timestamp, nil
+		})
+		return
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q: use json or plain", format), http.StatusBadRequest)
+		return
+	}
+	s.cache.serve(w, r, r.URL.Path, func() ([]byte, string, time.Time, error) {
+		result, err := s.results()
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+		return renderJSON(result, result.Timestamp)
+	})
+}
+
+// results returns the current status, merged with probe submissions in aggregator mode
+func (s *Server) results() (*models.MonitoringResult, error) {
+	if s.provider != nil {
+		return s.provider()
+	}
+	if s.aggregator != nil {
+		return s.aggregator.Merge(s.monitor.LatestResults()), nil
+	}
+	return s.monitor.LatestResults(), nil
+}
+
+// VersionPath serves the build info of the running server (see internal/version)
+const VersionPath = "/api/v1/version"
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, version.Get())
+}
+
+// historyResponse is the payload consumed by the dashboard charts
+type historyResponse struct {
+	Period      string               `json:"period"`
+	Traffic     []trafficPoint       `json:"traffic"`
+	Uptime      *uptimeResponse      `json:"uptime"`
+	Annotations []models.Annotation  `json:"annotations"`
+	AtlasProbes []models.AtlasProbes `json:"atlas_probes"` // Connected RIPE Atlas probes in the country (empty without ripe_atlas)
+}
+
+type trafficPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     float64   `json:"level"`
+}
+
+// uptimeResponse is a heatmap in ECharts form: cells are [hourIndex, rowIndex, availability%]
+// Hours without samples have no cell
+type uptimeResponse struct {
+	Rows  []string     `json:"rows"`
+	Hours []time.Time  `json:"hours"`
+	Cells [][3]float64 `json:"cells"`
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "7d"
+	}
+	hours, err := monitor.ParseChartPeriod(period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.monitor == nil || s.monitor.History() == nil {
+		http.Error(w, "history is disabled on this instance", http.StatusNotFound)
+		return
+	}
+
+	s.cache.serve(w, r, r.URL.Path+"?period="+period, func() ([]byte, string, time.Time, error) {
+		return renderJSON(s.history(period, hours), time.Time{})
+	})
+}
+
+// history builds the chart payload of the last hours
+func (s *Server) history(period string, hours int) historyResponse {
+	end := time.Now().UTC().Truncate(time.Hour)
+	start := end.Add(-time.Duration(hours-1) * time.Hour)
+
+	resp := historyResponse{
+		Period:      period,
+		Traffic:     []trafficPoint{},
+		Uptime:      &uptimeResponse{Rows: []string{}, Cells: [][3]float64{}},
+		Annotations: append([]models.Annotation{}, s.monitor.History().Annotations(start)...),
+		AtlasProbes: append([]models.AtlasProbes{}, s.monitor.AtlasProbes(start)...),
+	}
+	for _, p := range s.monitor.History().Traffic(start) {
+		resp.Traffic = append(resp.Traffic, trafficPoint{Timestamp: p.Timestamp, Level: p.Level})
+	}
+
+	hourIndex := make(map[int64]int, hours)
+	for h := 0; h < hours; h++ {
+		hour := start.Add(time.Duration(h) * time.Hour)
+		hourIndex[hour.Unix()] = h
+		resp.Uptime.Hours = append(resp.Uptime.Hours, hour)
+	}
+	for rowIdx, row := range s.monitor.UptimeRows(start) {
+		resp.Uptime.Rows = append(resp.Uptime.Rows, row.Label)
+		for _, b := range row.Buckets {
+			h, ok := hourIndex[b.Hour.UTC().Truncate(time.Hour).Unix()]
+			if !ok || b.Total == 0 {
+				continue
+			}
+			resp.Uptime.Cells = append(resp.Uptime.Cells, [3]float64{float64(h), float64(rowIdx), b.Ratio() * 100.0})
+		}
+	}
+	return resp
+}
+
+// renderJSON encodes a cached JSON response produced at modified
+func renderJSON(v interface{}, modified time.Time) ([]byte, string, time.Time, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("⚠️  Failed to encode JSON response: %v", err)
+		return nil, "", time.Time{}, &statusError{status: http.StatusInternalServerError, message: "failed to encode response"}
+	}
+	return append(body, '\n'), "application/json", modified, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("⚠️  Failed to encode JSON response: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// SnapshotPath serves the current result with its charts and recent public events to mirrors
+const SnapshotPath = "/api/v1/snapshot"
+
+// maxSnapshotEvents bounds the recent events kept for mirrors
+const maxSnapshotEvents = 100
+
+// ActionSnapshot is the notifier action that records events for the snapshot
+const ActionSnapshot = "snapshot"
+
+// RecordEvents keeps events for the snapshot; it backs the snapshot action,
+// which mirrors the events sent to the public Telegram channels
+func (s *Server) RecordEvents(events []models.Event) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.events = append(s.events, events...)
+	if len(s.events) > maxSnapshotEvents {
+		s.events = append([]models.Event(nil), s.events[len(s.events)-maxSnapshotEvents:]...)
+	}
+}
+
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.cache.serve(w, r, r.URL.Path, func() ([]byte, string, time.Time, error) {
+		result, err := s.results()
+		if err != nil {
+			return nil, "", time.Time{}, err
+		}
+
+		s.eventsMu.Lock()
+		snapshot := models.NewSnapshot(result, s.events)
+		s.eventsMu.Unlock()
+		return renderJSON(snapshot, result.Timestamp)
+	})
+}
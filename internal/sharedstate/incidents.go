@@ -0,0 +1,97 @@
+package sharedstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/escalation"
+	"github.com/redis/go-redis/v9"
+)
+
+// ack is a queued incident acknowledgement
+type ack struct {
+	ID string `json:"id"`
+	By string `json:"by"`
+}
+
+// PublishIncidents stores the open incidents for the bot and API processes
+func (s *Store) PublishIncidents(incidents []escalation.Incident) error {
+	data, err := json.Marshal(incidents)
+	if err != nil {
+		return fmt.Errorf("failed to encode incidents: %w", err)
+	}
+	return s.client.Set(context.Background(), s.key(keyIncidents), data, 0).Err()
+}
+
+// LoadIncidents returns the open incidents last published by the monitor
+func (s *Store) LoadIncidents() ([]escalation.Incident, error) {
+	data, err := s.client.Get(context.Background(), s.key(keyIncidents)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var incidents []escalation.Incident
+	if err := json.Unmarshal(data, &incidents); err != nil {
+		return nil, fmt.Errorf("failed to decode shared incidents: %w", err)
+	}
+	return incidents, nil
+}
+
+// PushAck queues an acknowledgement for the monitor's escalation manager
+// Unknown IDs are checked against the published incidents, since the
+// monitor's answer is not returned to the caller
+func (s *Store) PushAck(id, by string) error {
+	incidents, err := s.LoadIncidents()
+	if err != nil {
+		return err
+	}
+	known := false
+	for _, incident := range incidents {
+		known = known || incident.ID == id
+	}
+	if !known {
+		return escalation.ErrUnknownIncident
+	}
+
+	data, err := json.Marshal(ack{ID: id, By: by})
+	if err != nil {
+		return err
+	}
+	return s.client.RPush(context.Background(), s.key(keyAcks), data).Err()
+}
+
+// ConsumeAcks passes queued acknowledgements to handler until the context is cancelled
+func (s *Store) ConsumeAcks(ctx context.Context, handler func(id, by string) error) {
+	for ctx.Err() == nil {
+		items, err := s.client.BLPop(ctx, popTimeout, s.key(keyAcks)).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to read shared acknowledgements: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(popTimeout):
+			}
+			continue
+		}
+
+		// BLPOP replies with [key, value]
+		if len(items) != 2 {
+			continue
+		}
+		var a ack
+		if err := json.Unmarshal([]byte(items[1]), &a); err != nil {
+			log.Printf("⚠️  Dropping malformed acknowledgement: %v", err)
+			continue
+		}
+		if err := handler(a.ID, a.By); err != nil {
+			log.Printf("⚠️  Acknowledgement of incident %s failed: %v", a.ID, err)
+		}
+	}
+}
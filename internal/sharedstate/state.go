@@ -0,0 +1,230 @@
+package sharedstate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis keys, relative to the configured prefix
+const (
+	keyResult      = "result"      // Latest MonitoringResult (JSON)
+	keyImagePrefix = "image:"      // PNG charts, which are not part of the result JSON
+	keySubscribers = "subscribers" // Set of subscribed Telegram chat IDs
+	keyEvents      = "events"      // Queue of detected events (JSON), consumed by the bot
+	keyIncidents   = "incidents"   // Open escalation incidents (JSON), published by the monitor
+	keyAcks        = "acks"        // Queue of incident acknowledgements, consumed by the monitor
+)
+
+// maxQueuedEvents caps the event queue while no bot is consuming it
+const maxQueuedEvents = 1000
+
+// popTimeout is how long one blocking event pop waits
+const popTimeout = 5 * time.Second
+
+// resultTTL expires a published result whose monitor stopped publishing
+const resultTTL = time.Hour
+
+// Store shares the current result, subscriptions and events between the
+// monitor, bot and API server processes through Redis
+type Store struct {
+	client *redis.Client // Pooled, so blocking pops get connections of their own
+	prefix string
+}
+
+// Open connects to Redis and checks the connection
+func Open(addr, password string, db int, prefix string) (*Store, error) {
+	s := &Store{
+		client: redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db}),
+		prefix: prefix,
+	}
+	if err := s.client.Ping(context.Background()).Err(); err != nil {
+		s.client.Close()
+		return nil, fmt.Errorf("failed to connect to redis at %s: %w", addr, err)
+	}
+	return s, nil
+}
+
+func (s *Store) key(name string) string {
+	return s.prefix + name
+}
+
+// PublishResult stores the latest result and its charts
+func (s *Store) PublishResult(result *models.MonitoringResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	// The result and its charts are replaced in one MULTI/EXEC, so readers
+	// never see a result with the charts of another cycle
+	ctx := context.Background()
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for name, image := range result.Images() {
+			if *image == nil {
+				pipe.Del(ctx, s.key(keyImagePrefix+name))
+				continue
+			}
+			pipe.Set(ctx, s.key(keyImagePrefix+name), (*image).Bytes(), resultTTL)
+		}
+		pipe.Set(ctx, s.key(keyResult), data, resultTTL)
+		return nil
+	})
+	return err
+}
+
+// LoadResult returns the latest published result with its charts
+func (s *Store) LoadResult() (*models.MonitoringResult, error) {
+	// The result and every chart it may have are read in one MULTI/EXEC, so
+	// they come from the same PublishResult
+	names := imageNames()
+	var resultCmd *redis.StringCmd
+	imageCmds := make(map[string]*redis.StringCmd, len(names))
+	ctx := context.Background()
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		resultCmd = pipe.Get(ctx, s.key(keyResult))
+		for _, name := range names {
+			imageCmds[name] = pipe.Get(ctx, s.key(keyImagePrefix+name))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	data, err := resultCmd.Bytes()
+	if err == redis.Nil {
+		return nil, fmt.Errorf("no result published yet")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.MonitoringResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode shared result: %w", err)
+	}
+
+	for name, image := range result.Images() {
+		png, err := imageCmds[name].Bytes()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		*image = bytes.NewBuffer(png)
+	}
+	result.ShareASNChart()
+	return &result, nil
+}
+
+// imageNames returns the keys of every chart a result can have
+func imageNames() []string {
+	full := &models.MonitoringResult{
+		TrafficData:   &models.TrafficData{},
+		ASTrafficData: []*models.ASTrafficData{{}},
+	}
+	names := make([]string, 0, len(full.Images()))
+	for name := range full.Images() {
+		names = append(names, name)
+	}
+	return names
+}
+
+// AddSubscriber adds a chat to the shared subscriber set
+func (s *Store) AddSubscriber(chatID int64) error {
+	return s.client.SAdd(context.Background(), s.key(keySubscribers), strconv.FormatInt(chatID, 10)).Err()
+}
+
+// RemoveSubscriber removes a chat from the shared subscriber set
+func (s *Store) RemoveSubscriber(chatID int64) error {
+	return s.client.SRem(context.Background(), s.key(keySubscribers), strconv.FormatInt(chatID, 10)).Err()
+}
+
+// Subscribers returns all shared subscriber chat IDs
+func (s *Store) Subscribers() ([]int64, error) {
+	members, err := s.client.SMembers(context.Background(), s.key(keySubscribers)).Result()
+	if err != nil {
+		return nil, err
+	}
+	var chats []int64
+	for _, member := range members {
+		chatID, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		chats = append(chats, chatID)
+	}
+	return chats, nil
+}
+
+// PushEvents queues detected events for the bot
+// Events survive bot restarts; the oldest are dropped beyond maxQueuedEvents
+func (s *Store) PushEvents(events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	ctx := context.Background()
+	if err := s.client.RPush(ctx, s.key(keyEvents), data).Err(); err != nil {
+		return err
+	}
+	return s.client.LTrim(ctx, s.key(keyEvents), -maxQueuedEvents, -1).Err()
+}
+
+// ConsumeEvents delivers queued events to handler until the context is cancelled
+func (s *Store) ConsumeEvents(ctx context.Context, handler func([]models.Event)) {
+	for ctx.Err() == nil {
+		items, err := s.client.BLPop(ctx, popTimeout, s.key(keyEvents)).Result()
+		if err == redis.Nil || ctx.Err() != nil {
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to read shared events: %v", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(popTimeout):
+			}
+			continue
+		}
+
+		// BLPOP replies with [key, value]
+		if len(items) != 2 {
+			continue
+		}
+		var events []models.Event
+		if err := json.Unmarshal([]byte(items[1]), &events); err != nil {
+			log.Printf("⚠️  Dropping malformed shared events: %v", err)
+			continue
+		}
+		handler(events)
+	}
+}
+
+// Run publishes a fresh result every interval until the context is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration, results func() *models.MonitoringResult) {
+	log.Printf("🗄  Publishing results to Redis every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.PublishResult(results()); err != nil {
+			log.Printf("⚠️  Failed to publish shared result: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
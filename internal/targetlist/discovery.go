@@ -0,0 +1,171 @@
+package targetlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+const defaultDiscoveryURL = "https://stat.ripe.net/data"
+
+// asnNumber matches the ASNs in RIPEstat's "{AsnSingle(12880), ...}" lists
+var asnNumber = regexp.MustCompile(`\d+`)
+
+// Discovery finds the ASNs of a country on RIPEstat (country-asns) and keeps
+// the last result in a cache file. ASNs drop out only when they are no longer
+// registered in the country: one that stops being routed stays, as during a
+// shutdown the missing ASNs are the ones to watch
+type Discovery struct {
+	base       string
+	country    string
+	cachePath  string
+	httpClient *http.Client
+	known      map[string]bool // ASNs of the list in use
+}
+
+// discoveryCache is the cache file of a discovery
+type discoveryCache struct {
+	Country      string    `json:"country"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+	ASNs         []string  `json:"asns"`
+}
+
+// NewDiscovery creates the ASN discovery of a country (ISO code); current is
+// the list in use, whose ASNs stay while they are registered in the country
+func NewDiscovery(settings *config.ASNDiscovery, country string, current []string) *Discovery {
+	d := &Discovery{
+		base:       strings.TrimSuffix(settings.URL, "/"),
+		country:    strings.ToUpper(country),
+		cachePath:  settings.CachePath,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	if d.base == "" {
+		d.base = defaultDiscoveryURL
+	}
+	if d.cachePath == "" {
+		d.cachePath = fmt.Sprintf("asns-%s.json", strings.ToLower(country))
+	}
+	d.Use(current)
+	return d
+}
+
+// CachePath returns the path of the cache file
+func (d *Discovery) CachePath() string {
+	return d.cachePath
+}
+
+// Use sets the list in use, e.g. the cached one after a failed discovery
+func (d *Discovery) Use(asns []string) {
+	d.known = make(map[string]bool, len(asns))
+	for _, asn := range asns {
+		d.known[asn] = true
+	}
+}
+
+// Discover fetches the routed ASNs of the country, adds the ASNs of the list
+// in use that are still registered there, makes the result the list in use
+// and writes it to the cache file
+func (d *Discovery) Discover(ctx context.Context) ([]string, error) {
+	query := url.Values{"resource": {d.country}, "lod": {"1"}, "sourceapp": {"netblocks"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.base+"/country-asns/data.json?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+
+	var payload struct {
+		Status string `json:"status"`
+		Data   struct {
+			Countries []struct {
+				Routed    json.RawMessage `json:"routed"`
+				NonRouted json.RawMessage `json:"non_routed"`
+			} `json:"countries"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxListBytes)).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode RIPEstat response: %w", err)
+	}
+	if payload.Status != "ok" || len(payload.Data.Countries) == 0 {
+		return nil, fmt.Errorf("RIPEstat status %q, %d countries", payload.Status, len(payload.Data.Countries))
+	}
+
+	seen := make(map[string]bool)
+	var asns []string
+	country := payload.Data.Countries[0]
+	for _, number := range asnNumber.FindAllString(string(country.Routed), -1) {
+		if asn := "AS" + number; !seen[asn] {
+			seen[asn] = true
+			asns = append(asns, asn)
+		}
+	}
+	if len(asns) == 0 {
+		return nil, fmt.Errorf("RIPEstat lists no routed ASN in %s", d.country)
+	}
+	for _, number := range asnNumber.FindAllString(string(country.NonRouted), -1) {
+		if asn := "AS" + number; d.known[asn] && !seen[asn] {
+			seen[asn] = true
+			asns = append(asns, asn)
+		}
+	}
+	sortASNs(asns)
+	d.Use(asns)
+
+	cache, err := json.MarshalIndent(discoveryCache{Country: d.country, DiscoveredAt: time.Now().UTC(), ASNs: asns}, "", "  ")
+	if err != nil {
+		return asns, err
+	}
+	tmp := d.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, cache, 0o644); err != nil {
+		return asns, fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, d.cachePath); err != nil {
+		return asns, fmt.Errorf("failed to replace %s: %w", d.cachePath, err)
+	}
+	return asns, nil
+}
+
+// Cached returns the ASNs of the cache file and when they were discovered; an
+// error if there is no cache of this country
+func (d *Discovery) Cached() ([]string, time.Time, error) {
+	data, err := os.ReadFile(d.cachePath)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var cache discoveryCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", d.cachePath, err)
+	}
+	if cache.Country != d.country || len(cache.ASNs) == 0 {
+		return nil, time.Time{}, fmt.Errorf("%s holds no ASNs of %s", d.cachePath, d.country)
+	}
+	return cache.ASNs, cache.DiscoveredAt, nil
+}
+
+// sortASNs orders ASNs by number
+func sortASNs(asns []string) {
+	sort.Slice(asns, func(i, j int) bool {
+		a, b := strings.TrimPrefix(asns[i], "AS"), strings.TrimPrefix(asns[j], "AS")
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+}
@@ -0,0 +1,249 @@
+package targetlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// ASN name lookup settings
+const (
+	defaultPeeringDBURL = "https://www.peeringdb.com/api"
+	defaultNamesMaxAge  = 30 * 24 * time.Hour
+	peeringDBBatch      = 50 // ASNs per PeeringDB query
+)
+
+// holderSuffix matches the country code RIPEstat appends to AS holders, e.g. ", IR"
+var holderSuffix = regexp.MustCompile(`,\s*[A-Z]{2}$`)
+
+// NameResolver looks the names of ASNs up on RIPEstat or PeeringDB and keeps
+// them in a cache file; the names are published to config.GetASNName, which
+// falls back to the built-in names for ASNs that could not be looked up
+type NameResolver struct {
+	source     string
+	ripestat   string
+	peeringdb  string
+	cachePath  string
+	maxAge     time.Duration
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	names map[string]resolvedName
+}
+
+// resolvedName is a looked-up ASN name in the cache file
+type resolvedName struct {
+	Name       string    `json:"name"`
+	Source     string    `json:"source"` // ripestat or peeringdb
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+// NewNameResolver creates the ASN name lookup of a country (ISO code);
+// settings may be nil for the defaults. It returns nil for the static source
+func NewNameResolver(settings *config.ASNNameLookup, country string) *NameResolver {
+	if settings == nil {
+		settings = &config.ASNNameLookup{}
+	}
+	if settings.Source == config.ASNNamesStatic {
+		return nil
+	}
+	r := &NameResolver{
+		source:     settings.Source,
+		ripestat:   defaultDiscoveryURL,
+		peeringdb:  defaultPeeringDBURL,
+		cachePath:  settings.CachePath,
+		maxAge:     defaultNamesMaxAge,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		names:      make(map[string]resolvedName),
+	}
+	if r.source == "" {
+		r.source = config.ASNNamesRIPEstat
+	}
+	if r.cachePath == "" {
+		r.cachePath = fmt.Sprintf("asn-names-%s.json", strings.ToLower(country))
+	}
+	if d, err := time.ParseDuration(settings.MaxAge); err == nil && d > 0 {
+		r.maxAge = d
+	}
+	return r
+}
+
+// Load reads the cache file and publishes its names; a missing file is not an error
+func (r *NameResolver) Load() (int, error) {
+	data, err := os.ReadFile(r.cachePath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var names map[string]resolvedName
+	if err := json.Unmarshal(data, &names); err != nil {
+		return 0, fmt.Errorf("%s: %w", r.cachePath, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for asn, name := range names {
+		if name.Name == "" {
+			continue
+		}
+		r.names[asn] = name
+		config.SetResolvedASNName(asn, name.Name)
+	}
+	return len(r.names), nil
+}
+
+// Resolve looks up the ASNs without a name or with one older than the max
+// age, publishes the names found and writes the cache file. It returns how
+// many names were found; lookups that fail keep the previous name
+func (r *NameResolver) Resolve(ctx context.Context, asns []string) (int, error) {
+	now := time.Now()
+	r.mu.Lock()
+	var missing []string
+	for _, asn := range asns {
+		if name, ok := r.names[asn]; !ok || now.Sub(name.ResolvedAt) > r.maxAge {
+			missing = append(missing, asn)
+		}
+	}
+	r.mu.Unlock()
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	found := make(map[string]resolvedName)
+	var lookupErr error
+	if r.source == config.ASNNamesPeeringDB {
+		lookupErr = r.lookupPeeringDB(ctx, missing, found)
+	}
+	for _, asn := range missing {
+		if _, ok := found[asn]; ok {
+			continue
+		}
+		if ctx.Err() != nil {
+			lookupErr = ctx.Err()
+			break
+		}
+		name, err := r.lookupRIPEstat(ctx, asn)
+		if err != nil {
+			lookupErr = err
+			continue
+		}
+		if name != "" {
+			found[asn] = resolvedName{Name: name, Source: config.ASNNamesRIPEstat, ResolvedAt: now}
+		}
+	}
+
+	r.mu.Lock()
+	for asn, name := range found {
+		r.names[asn] = name
+		config.SetResolvedASNName(asn, name.Name)
+	}
+	r.mu.Unlock()
+	if len(found) > 0 {
+		if err := r.save(); err != nil {
+			log.Printf("⚠️  Failed to write ASN name cache: %v", err)
+		}
+	}
+	return len(found), lookupErr
+}
+
+// lookupRIPEstat returns the holder of an ASN on RIPEstat, "" if it has none
+func (r *NameResolver) lookupRIPEstat(ctx context.Context, asn string) (string, error) {
+	query := url.Values{"resource": {asn}, "sourceapp": {"netblocks"}}
+	var payload struct {
+		Status string `json:"status"`
+		Data   struct {
+			Holder string `json:"holder"`
+		} `json:"data"`
+	}
+	if err := r.getJSON(ctx, r.ripestat+"/as-overview/data.json?"+query.Encode(), &payload); err != nil {
+		return "", fmt.Errorf("RIPEstat as-overview of %s: %w", asn, err)
+	}
+	if payload.Status != "ok" {
+		return "", fmt.Errorf("RIPEstat as-overview of %s: status %q", asn, payload.Status)
+	}
+	return cleanHolder(payload.Data.Holder), nil
+}
+
+// lookupPeeringDB adds the names of the ASNs PeeringDB has a network of to found
+func (r *NameResolver) lookupPeeringDB(ctx context.Context, asns []string, found map[string]resolvedName) error {
+	for start := 0; start < len(asns); start += peeringDBBatch {
+		end := min(start+peeringDBBatch, len(asns))
+		numbers := make([]string, 0, end-start)
+		for _, asn := range asns[start:end] {
+			numbers = append(numbers, strings.TrimPrefix(asn, "AS"))
+		}
+		query := url.Values{"asn__in": {strings.Join(numbers, ",")}, "fields": {"asn,name"}}
+		var payload struct {
+			Data []struct {
+				ASN  int    `json:"asn"`
+				Name string `json:"name"`
+			} `json:"data"`
+		}
+		if err := r.getJSON(ctx, r.peeringdb+"/net?"+query.Encode(), &payload); err != nil {
+			return fmt.Errorf("PeeringDB: %w", err)
+		}
+		for _, network := range payload.Data {
+			if name := strings.TrimSpace(network.Name); name != "" {
+				found[fmt.Sprintf("AS%d", network.ASN)] = resolvedName{Name: name, Source: config.ASNNamesPeeringDB, ResolvedAt: time.Now()}
+			}
+		}
+	}
+	return nil
+}
+
+func (r *NameResolver) getJSON(ctx context.Context, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxListBytes)).Decode(v)
+}
+
+// save writes the cache file
+func (r *NameResolver) save() error {
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.names, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := r.cachePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, r.cachePath); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", r.cachePath, err)
+	}
+	return nil
+}
+
+// cleanHolder shortens a RIPEstat AS holder such as "TCI - Iran
+// Telecommunication Company PJS, IR" to the organization name
+func cleanHolder(holder string) string {
+	holder = holderSuffix.ReplaceAllString(strings.TrimSpace(holder), "")
+	if handle, name, ok := strings.Cut(holder, " - "); ok && !strings.Contains(handle, " ") && name != "" {
+		holder = name
+	}
+	return strings.TrimSpace(holder)
+}
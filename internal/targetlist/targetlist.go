@@ -0,0 +1,143 @@
+// Package targetlist fetches remotely maintained ASN and DNS server lists, so
+// the community can update the monitored targets without a redeploy
+package targetlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/evidence"
+)
+
+// SignatureSuffix is appended to a list URL to get its detached signature
+// (base64 Ed25519 signature of the list's SHA-256, see `netblocks-cli sign`)
+const SignatureSuffix = ".sig"
+
+// maxListBytes bounds a downloaded list
+const maxListBytes = 4 << 20
+
+// List is a remote target list; it remembers the ETag of the last accepted
+// version so unchanged lists are not downloaded again
+type List struct {
+	url        string
+	publicKey  string // Base64 Ed25519 key the list must be signed with; empty accepts unsigned lists
+	httpClient *http.Client
+	etag       string
+}
+
+// New creates a list fetched from url and verified with publicKey (may be empty)
+func New(url, publicKey string) *List {
+	return &List{url: url, publicKey: publicKey, httpClient: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// URL returns the list's URL
+func (l *List) URL() string {
+	return l.url
+}
+
+// Fetch downloads the list; it returns nil data if the list did not change
+// since the last successful fetch, and an error if the signature does not match
+func (l *List) Fetch(ctx context.Context) ([]byte, error) {
+	data, etag, err := l.get(ctx, l.url, l.etag)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	if l.publicKey != "" {
+		signature, _, err := l.get(ctx, l.url+SignatureSuffix, "")
+		if err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		if err := evidence.VerifyDetached(l.publicKey, data, string(signature)); err != nil {
+			return nil, fmt.Errorf("rejected %s: %w", l.url, err)
+		}
+	}
+	l.etag = etag
+	return data, nil
+}
+
+// get downloads url, returning nil data if the server answers 304 Not Modified
+func (l *List) get(ctx context.Context, url, etag string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "NetBlocks-Monitor/1.0")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil, etag, nil
+	case http.StatusOK:
+	default:
+		return nil, "", fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxListBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("ETag"), nil
+}
+
+// ParseASNs parses a JSON array of ASNs ("AS44244" or 44244)
+func ParseASNs(data []byte) ([]string, error) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("ASN list must be a JSON array: %w", err)
+	}
+	var asns []string
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		var asn string
+		if err := json.Unmarshal(entry, &asn); err != nil {
+			asn = string(entry)
+		}
+		number := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(asn)), "AS")
+		if _, err := strconv.ParseUint(number, 10, 32); err != nil {
+			return nil, fmt.Errorf("invalid ASN %s", entry)
+		}
+		if asn = "AS" + number; !seen[asn] {
+			seen[asn] = true
+			asns = append(asns, asn)
+		}
+	}
+	if len(asns) == 0 {
+		return nil, fmt.Errorf("ASN list is empty")
+	}
+	return asns, nil
+}
+
+// ParseDNSServers parses a JSON array of DNS servers in the dns_servers format
+func ParseDNSServers(data []byte) ([]config.DNSServer, error) {
+	var servers []config.DNSServer
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return nil, fmt.Errorf("DNS server list must be a JSON array of servers: %w", err)
+	}
+	for _, server := range servers {
+		if net.ParseIP(server.Address) == nil {
+			return nil, fmt.Errorf("invalid DNS server address %q", server.Address)
+		}
+		switch server.Type {
+		case "", "recursive", "authoritative", "both":
+		default:
+			return nil, fmt.Errorf("invalid type %q of DNS server %s", server.Type, server.Address)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("DNS server list is empty")
+	}
+	return servers, nil
+}
@@ -0,0 +1,132 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// ChannelAccess is what the bot may do in a configured channel or group
+type ChannelAccess struct {
+	ID      string // Configured ID (@name or numeric)
+	ChatID  int64  // Resolved numeric chat ID (0 if the chat could not be resolved)
+	Title   string
+	Type    string // "channel", "group", "supergroup" or "private"
+	CanPost bool
+	Problem string // Why the bot cannot post and how to fix it (empty if it can)
+}
+
+// Diagnose verifies the bot token and checks every configured channel; it
+// returns the bot's username
+func Diagnose(token string, cfg *config.Config) (string, []ChannelAccess, error) {
+	api, err := tgbotapi.NewBotAPI(token)
+	if err != nil {
+		return "", nil, err
+	}
+	var access []ChannelAccess
+	for _, ch := range loadChannels(cfg, time.UTC) {
+		access = append(access, checkChannel(api, ch.id))
+	}
+	if cfg.TelegramArchiveChannel != "" {
+		access = append(access, checkChannel(api, normalizeChannelID(cfg.TelegramArchiveChannel)))
+	}
+	return api.Self.UserName, access, nil
+}
+
+// checkChannel resolves a channel ID with getChat and checks the bot's membership
+func checkChannel(api *tgbotapi.BotAPI, id string) ChannelAccess {
+	access := ChannelAccess{ID: id}
+	chatConfig := tgbotapi.ChatConfig{SuperGroupUsername: id}
+	if numeric, err := strconv.ParseInt(id, 10, 64); err == nil {
+		chatConfig = tgbotapi.ChatConfig{ChatID: numeric}
+	}
+
+	chat, err := api.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: chatConfig})
+	if err != nil {
+		access.Problem = fmt.Sprintf("cannot see %s (%v): check the username, or add the bot to the chat "+
+			"(private channels need the numeric ID, e.g. -1001234567890)", id, err)
+		return access
+	}
+	access.ChatID, access.Title, access.Type = chat.ID, chat.Title, chat.Type
+
+	member, err := api.GetChatMember(tgbotapi.GetChatMemberConfig{ChatConfigWithUser: tgbotapi.ChatConfigWithUser{
+		ChatID: chat.ID, UserID: api.Self.ID}})
+	if err != nil {
+		access.Problem = fmt.Sprintf("cannot read the bot's membership in %s (%v): add the bot to the chat", id, err)
+		return access
+	}
+	access.CanPost, access.Problem = canPost(chat.Type, member)
+	return access
+}
+
+// canPost applies Telegram's posting rules: channels only accept posts from
+// administrators with the "Post messages" right, groups from unrestricted members
+func canPost(chatType string, member tgbotapi.ChatMember) (bool, string) {
+	switch {
+	case member.IsCreator():
+		return true, ""
+	case member.Status == "left" || member.Status == "kicked":
+		return false, fmt.Sprintf("the bot is not a member (status %q): add it to the %s", member.Status, chatType)
+	case chatType == "channel" && !member.IsAdministrator():
+		return false, "the bot is not an administrator: make it a channel administrator with the \"Post messages\" right"
+	case chatType == "channel" && !member.CanPostMessages:
+		return false, "the bot is an administrator without the \"Post messages\" right: enable it in the channel's administrator settings"
+	case member.Status == "restricted" && !member.CanSendMessages:
+		return false, "the bot is restricted from sending messages: lift the restriction in the group's permissions"
+	case member.Status == "restricted" && !member.CanSendMediaMessages:
+		return true, "the bot cannot send media, so charts will fail: allow media in the group's permissions"
+	}
+	return true, ""
+}
+
+// Describe formats the access check of a channel as one line
+func (a ChannelAccess) Describe() string {
+	name := a.ID
+	if a.Title != "" {
+		name = fmt.Sprintf("%s (%s, %s %d)", a.ID, strings.TrimSpace(a.Title), a.Type, a.ChatID)
+	}
+	if a.Problem != "" {
+		return name + ": " + a.Problem
+	}
+	return name + ": bot can post"
+}
+
+// preflightChannels resolves every channel to its numeric chat ID and fails if
+// the bot may not post to one of them, instead of failing on every send later
+func preflightChannels(api *tgbotapi.BotAPI, channels []*channelTarget) error {
+	var problems []string
+	for _, ch := range channels {
+		access := checkChannel(api, ch.id)
+		ch.chatID = access.ChatID
+		switch {
+		case !access.CanPost:
+			problems = append(problems, access.Describe())
+		case access.Problem != "":
+			log.Printf("⚠️  Channel %s", access.Describe())
+		default:
+			log.Printf("✅ Channel %s resolved to chat %d - bot can post", ch.id, ch.chatID)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the bot cannot post to %d channel(s):\n  %s\nFix the rights (check with `netblocks-cli doctor`) or remove the channel from the config",
+			len(problems), strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// preflightArchive resolves the archive channel to its numeric chat ID and
+// fails if the bot may not post to it
+func preflightArchive(api *tgbotapi.BotAPI, id string) (string, error) {
+	access := checkChannel(api, id)
+	if !access.CanPost {
+		return "", fmt.Errorf("the bot cannot post to the archive channel %s\nFix the rights (check with `netblocks-cli doctor`) or remove telegram_archive_channel from the config",
+			access.Describe())
+	}
+	log.Printf("✅ Archive channel %s resolved to chat %d - bot can post", id, access.ChatID)
+	return strconv.FormatInt(access.ChatID, 10), nil
+}
@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// maxArchiveEvents bounds the events kept between two archive posts
+const maxArchiveEvents = 500
+
+// archiveLog collects the events delivered since the last archive post
+type archiveLog struct {
+	mu       sync.Mutex
+	events   []models.Event
+	lastSent time.Time // Timestamp of the last archived result
+}
+
+// record keeps events for the next archive post
+func (a *archiveLog) record(events []models.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, events...)
+	if len(a.events) > maxArchiveEvents {
+		a.events = append([]models.Event(nil), a.events[len(a.events)-maxArchiveEvents:]...)
+	}
+}
+
+// take returns the events since the last archive post and clears them
+func (a *archiveLog) take() []models.Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	events := a.events
+	a.events = nil
+	return events
+}
+
+// RunArchive posts the full JSON snapshot of every new result (measurements,
+// charts and the events since the previous post) as a document to
+// telegram_archive_channel, until ctx is done: an off-site copy of the data
+// that survives the loss of the server
+func (b *Bot) RunArchive(ctx context.Context) {
+	if b.archive == "" {
+		<-ctx.Done()
+		return
+	}
+	interval := b.config.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	log.Printf("🗄  Archiving a snapshot to %s every %v", b.archive, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.postArchive()
+		}
+	}
+}
+
+// postArchive sends the snapshot of the current result unless it was archived already
+func (b *Bot) postArchive() {
+	result, err := b.onStatusUpdate()
+	if err != nil || result == nil {
+		log.Printf("⚠️  No result to archive: %v", err)
+		return
+	}
+	b.archiveLog.mu.Lock()
+	unchanged := result.Timestamp.Equal(b.archiveLog.lastSent)
+	b.archiveLog.mu.Unlock()
+	if unchanged {
+		return
+	}
+
+	snapshot := models.NewSnapshot(result, b.archiveLog.take())
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Printf("❌ Failed to encode archive snapshot: %v", err)
+		return
+	}
+	digest := sha256.Sum256(data)
+	stamp := result.Timestamp.UTC()
+	name := fmt.Sprintf("netblocks-%s-%s.json", strings.ToLower(b.config.Country), stamp.Format("20060102-1504"))
+	caption := fmt.Sprintf("🗄 %s snapshot %s UTC\nNational score: %.0f · %d event(s)\nSHA-256: `%s`",
+		b.config.CountryName, stamp.Format("2006-01-02 15:04"), result.NationalScore, len(snapshot.Events), hex.EncodeToString(digest[:]))
+
+	if _, err := b.sendDocument(b.archive, 0, name, data, caption); err != nil && !errors.Is(err, errSpooled) {
+		log.Printf("❌ Failed to archive snapshot to %s: %v", b.archive, err)
+		// Keep the events for the next attempt
+		b.archiveLog.record(snapshot.Events)
+		return
+	}
+	b.archiveLog.mu.Lock()
+	b.archiveLog.lastSent = result.Timestamp
+	b.archiveLog.mu.Unlock()
+}
@@ -3,29 +3,68 @@ package telegram
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/clock"
 	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/crash"
+	"github.com/netblocks/netblocks/internal/escalation"
+	"github.com/netblocks/netblocks/internal/history"
+	"github.com/netblocks/netblocks/internal/i18n"
 	"github.com/netblocks/netblocks/internal/models"
 	"github.com/netblocks/netblocks/internal/monitor"
+	"github.com/netblocks/netblocks/internal/sharedstate"
+	"github.com/netblocks/netblocks/internal/version"
 )
 
 // Bot represents the Telegram bot
 type Bot struct {
-	api            *tgbotapi.BotAPI
-	config         *config.Config
-	updateInterval time.Duration
-	intervalMu     sync.RWMutex   // Mutex for updateInterval
-	onStatusUpdate func() (*models.MonitoringResult, error)
-	subscribedChats map[int64]bool // Track users who have interacted with the bot
-	chatsMu         sync.RWMutex   // Mutex for subscribedChats
-	channelID       string         // Channel username or ID for periodic updates
+	api               *tgbotapi.BotAPI
+	config            *config.Config
+	updateInterval    time.Duration
+	intervalMu        sync.RWMutex // Mutex for updateInterval
+	onStatusUpdate    func() (*models.MonitoringResult, error)
+	subscribedChats   map[int64]bool                                                                         // Track users who have interacted with the bot
+	chatsMu           sync.RWMutex                                                                           // Mutex for subscribedChats
+	channels          []*channelTarget                                                                       // Channels receiving periodic updates, each with its own profile
+	digest            *cronSchedule                                                                          // When the digest with the SLO report is posted (nil if digest_schedule is not set)
+	nextDigest        time.Time                                                                              // Next digest post (zero until the loop starts)
+	chartProvider     func(period string) (*bytes.Buffer, string, error)                                     // Renders traffic charts and their text alternative for /chart
+	prefs             *prefsStore                                                                            // Per-chat preferences (quiet hours)
+	location          *time.Location                                                                         // Local time zone for quiet hours
+	pendingAlerts     map[int64][]models.Event                                                               // Non-critical changes waiting for the next batch
+	channelAlerts     map[string][]models.Event                                                              // Non-critical changes waiting for alerts-profile channels
+	alertsMu          sync.Mutex                                                                             // Mutex for pendingAlerts
+	alertBatch        time.Duration                                                                          // How often batched alerts are flushed
+	stats             *botStats                                                                              // Counters reported by /botstats
+	statsProvider     func() monitor.Stats                                                                   // Reads monitor counters for /botstats
+	limiter           *sendLimiter                                                                           // Spaces out sends to stay within Telegram rate limits
+	exportProvider    func(format, period string) ([]byte, string, error)                                    // Exports history (and a signature note) for /export
+	sharedState       *sharedstate.Store                                                                     // Persists subscriptions in Redis (nil if not configured)
+	incidentsProvider func() ([]escalation.Incident, error)                                                  // Lists open incidents for /incidents (nil if escalation is disabled)
+	ackHandler        func(id, by string) error                                                              // Acknowledges an incident (/ack and alert buttons)
+	readiness         []monitor.ReadinessStep                                                                // Outcome of the monitor's initial checks, shown in the startup message
+	clock             clock.Clock                                                                            // Time source of the periodic sends and quiet hours
+	pollerOnce        sync.Once                                                                              // Starts the long polling once, even if Start is restarted
+	updates           tgbotapi.UpdatesChannel                                                                // Updates received by the long polling
+	spool             *sendSpool                                                                             // Queues sends while Telegram is unreachable (nil if not configured)
+	annotate          func(text, author string) (models.Annotation, error)                                   // Records an annotation for /note (nil without the monitor)
+	annotations       func(period string) ([]models.Annotation, error)                                       // Lists annotations for /note
+	campaignStart     func(name string, duration time.Duration, by, reason string) (*models.Campaign, error) // Starts a campaign for /campaign (nil without the monitor)
+	campaignStop      func(by string) (*models.Campaign, error)                                              // Stops the running campaign
+	campaign          func() *models.Campaign                                                                // Returns the running campaign
+	campaignProfiles  []string                                                                               // Names of the configured campaigns
+	confirms          *confirmations                                                                         // Detections awaiting confirmation (nil unless confirm_incidents)
+	archive           string                                                                                 // Resolved chat ID of telegram_archive_channel (empty if not configured)
+	archiveLog        archiveLog                                                                             // Events waiting for the next archive post
 }
 
 // NewBot creates a new Telegram bot
@@ -33,21 +72,25 @@ func NewBot(token string, cfg *config.Config, onStatusUpdate func() (*models.Mon
 	if token == "" {
 		return nil, fmt.Errorf("telegram bot token is empty")
 	}
-	
+
 	log.Printf("🔑 Initializing Telegram bot with token: %s...", token[:10]+"...")
-	
+
 	api, err := tgbotapi.NewBotAPI(token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot API client: %w", err)
 	}
+	return newBot(api, cfg, onStatusUpdate)
+}
 
+// newBot creates the bot around a Bot API client, e.g. one of a fake Bot API in tests
+func newBot(api *tgbotapi.BotAPI, cfg *config.Config, onStatusUpdate func() (*models.MonitoringResult, error)) (*Bot, error) {
 	// Test the connection by getting bot info
 	botInfo, err := api.GetMe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to verify bot token (GetMe failed): %w", err)
 	}
 
-	log.Printf("✅ Successfully authorized as bot: @%s (ID: %d, Name: %s)", 
+	log.Printf("✅ Successfully authorized as bot: @%s (ID: %d, Name: %s)",
 		botInfo.UserName, botInfo.ID, botInfo.FirstName)
 
 	// Default to 20 minutes if not set
@@ -56,71 +99,148 @@ func NewBot(token string, cfg *config.Config, onStatusUpdate func() (*models.Mon
 		updateInterval = 20 * time.Minute
 	}
 
-	// Normalize channel ID/username format
-	channelID := cfg.TelegramChannel
-	if channelID != "" {
-		// Handle t.me/channelname format -> @channelname
-		if strings.HasPrefix(channelID, "t.me/") {
-			channelID = "@" + strings.TrimPrefix(channelID, "t.me/")
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		log.Printf("⚠️  Unknown timezone %q, using UTC for quiet hours and schedules: %v", cfg.Timezone, err)
+		location = time.UTC
+	}
+
+	// Normalize channel IDs/usernames and their content profiles, then resolve
+	// them and check the bot may post before anything is sent
+	channels := loadChannels(cfg, location)
+	if len(channels) == 0 {
+		log.Printf("⚠️  No channel configured - channel updates disabled")
+	}
+	if err := preflightChannels(api, channels); err != nil {
+		return nil, err
+	}
+
+	var archive string
+	if cfg.TelegramArchiveChannel != "" {
+		archive, err = preflightArchive(api, normalizeChannelID(cfg.TelegramArchiveChannel))
+		if err != nil {
+			return nil, err
 		}
-		// Ensure it starts with @ if it's a username
-		if !strings.HasPrefix(channelID, "@") && !strings.HasPrefix(channelID, "-") {
-			// If it doesn't start with @ or - (negative chat ID), assume it's a username
-			channelID = "@" + channelID
+	}
+
+	prefs, err := loadPrefs(cfg.ChatPrefsPath)
+	if err != nil {
+		log.Printf("⚠️  Failed to load chat preferences (starting empty): %v", err)
+		prefs, _ = loadPrefs("")
+		prefs.path = cfg.ChatPrefsPath
+	}
+
+	var confirms *confirmations
+	if cfg.ConfirmIncidents {
+		if len(cfg.TelegramAdmins) == 0 {
+			log.Printf("⚠️  confirm_incidents needs telegram_admins to confirm detections - critical alerts stay unlabeled")
+		} else {
+			confirms = newConfirmations()
 		}
-		log.Printf("📢 Channel configured: %s", channelID)
-	} else {
-		log.Printf("⚠️  No channel configured - channel updates disabled")
+	}
+
+	var spool *sendSpool
+	if cfg.TelegramSpool != nil {
+		spool, err = newSendSpool(cfg.TelegramSpool)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open the Telegram spool: %w", err)
+		}
+	}
+
+	alertBatch := time.Duration(cfg.AlertBatchMinutes) * time.Minute
+	if alertBatch <= 0 {
+		alertBatch = 15 * time.Minute
 	}
 
 	bot := &Bot{
-		api:              api,
-		config:           cfg,
-		updateInterval:   updateInterval,
-		onStatusUpdate:   onStatusUpdate,
-		subscribedChats:  make(map[int64]bool),
-		channelID:        channelID,
+		api:             api,
+		config:          cfg,
+		updateInterval:  updateInterval,
+		onStatusUpdate:  onStatusUpdate,
+		subscribedChats: make(map[int64]bool),
+		channels:        channels,
+		digest:          digestSchedule(cfg, location),
+		prefs:           prefs,
+		location:        location,
+		pendingAlerts:   make(map[int64][]models.Event),
+		channelAlerts:   make(map[string][]models.Event),
+		alertBatch:      alertBatch,
+		stats:           &botStats{startedAt: time.Now()},
+		limiter:         newSendLimiter(),
+		clock:           clock.Real,
+		spool:           spool,
+		confirms:        confirms,
+		archive:         archive,
 	}
 
 	log.Printf("✅ Bot initialized successfully")
 	return bot, nil
 }
 
-// SendStartupMessage sends a startup notification to the channel
-func (b *Bot) SendStartupMessage(ctx context.Context) {
-	if b.channelID == "" {
-		return
+// SetChartProvider sets the function used to render traffic charts (and their text alternative) for the /chart command
+func (b *Bot) SetChartProvider(provider func(period string) (*bytes.Buffer, string, error)) {
+	b.chartProvider = provider
+}
+
+// SetSharedState restores subscriptions from Redis and keeps them there, so the
+// bot can be redeployed without losing its subscribers
+func (b *Bot) SetSharedState(state *sharedstate.Store) error {
+	chats, err := state.Subscribers()
+	if err != nil {
+		return err
 	}
-	
-	startupMsg := fmt.Sprintf("🚀 *NetBlocks Bot Started*\n\n✅ Bot is now monitoring Iranian networks\n📊 Monitoring %d ASNs and %d+ DNS servers\n⏰ Updates will be sent every 20 minutes\n\nBot started at: `%s`",
-		len(b.config.IranASNs),
-		len(b.config.DNSServers),
-		time.Now().Format("2006-01-02 15:04:05"))
-	
-	log.Printf("📤 Sending startup message to channel: %s", b.channelID)
-	b.sendMessage(b.channelID, startupMsg)
+
+	b.chatsMu.Lock()
+	defer b.chatsMu.Unlock()
+	b.sharedState = state
+	for _, chatID := range chats {
+		b.subscribedChats[chatID] = true
+	}
+	log.Printf("✅ Restored %d subscription(s) from shared state", len(chats))
+	return nil
+}
+
+// SetExportProvider sets the function used to export history for the /export command
+func (b *Bot) SetExportProvider(provider func(format, period string) ([]byte, string, error)) {
+	b.exportProvider = provider
+}
+
+// SetReadiness sets the outcome of the monitor's initial checks for the startup message
+func (b *Bot) SetReadiness(steps []monitor.ReadinessStep) {
+	b.readiness = steps
+}
+
+// SetClock replaces the system clock of the periodic sends, e.g. with a fake
+// one in tests (call before SendPeriodicUpdates); Telegram flood waits still
+// follow the system clock
+func (b *Bot) SetClock(clk clock.Clock) {
+	b.clock = clk
 }
 
 // Start starts the bot
+// A restarted Start (see crash.Supervise) keeps reading from the same poller
 func (b *Bot) Start(ctx context.Context) {
 	log.Println("🤖 Starting Telegram bot update handler...")
-	
-	// Delete any pending webhook to ensure we use long polling
-	deleteWebhookConfig := tgbotapi.DeleteWebhookConfig{
-		DropPendingUpdates: true,
-	}
-	_, err := b.api.Request(deleteWebhookConfig)
-	if err != nil {
-		log.Printf("⚠️ Warning: Failed to delete webhook (may not exist): %v", err)
-	} else {
-		log.Println("✅ Cleared any existing webhooks, using long polling")
-	}
-	
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
 
-	log.Println("📡 Connecting to Telegram API for updates...")
-	updates := b.api.GetUpdatesChan(u)
+	b.pollerOnce.Do(func() {
+		// Delete any pending webhook to ensure we use long polling
+		deleteWebhookConfig := tgbotapi.DeleteWebhookConfig{
+			DropPendingUpdates: true,
+		}
+		_, err := b.api.Request(deleteWebhookConfig)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to delete webhook (may not exist): %v", err)
+		} else {
+			log.Println("✅ Cleared any existing webhooks, using long polling")
+		}
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+
+		log.Println("📡 Connecting to Telegram API for updates...")
+		b.updates = b.api.GetUpdatesChan(u)
+	})
+	updates := b.updates
 	log.Println("✅ Telegram bot update channel initialized successfully!")
 	log.Println("⏳ Waiting for incoming messages...")
 
@@ -129,47 +249,109 @@ func (b *Bot) Start(ctx context.Context) {
 		case <-ctx.Done():
 			log.Println("🛑 Bot context cancelled, stopping update handler...")
 			return
-		case update := <-updates:
+		case update, ok := <-updates:
+			if !ok {
+				log.Println("⚠️  Telegram update channel closed, stopping update handler...")
+				return
+			}
 			if update.Message == nil {
-				// Handle callback queries (button presses) if needed
+				// Handle callback queries (button presses from /settings)
 				if update.CallbackQuery != nil {
-					log.Printf("📥 Received callback query from user %d", update.CallbackQuery.From.ID)
-					// You can add callback handling here if needed
+					log.Printf("📥 Received callback query from user %d: %s", update.CallbackQuery.From.ID, update.CallbackQuery.Data)
+					crash.Go("telegram callback", func() { b.handleCallback(update.CallbackQuery) })
 				}
 				continue
 			}
 
-			log.Printf("📥 Received message from user %d (@%s): %s", 
-				update.Message.From.ID, 
+			log.Printf("📥 Received message from user %d (@%s): %s",
+				update.Message.From.ID,
 				update.Message.From.UserName,
 				update.Message.Text)
-			
+
 			// Handle message in a goroutine to avoid blocking
-			go b.handleMessage(update.Message)
+			crash.Go("telegram message", func() { b.handleMessage(update.Message) })
 		}
 	}
 }
 
 func (b *Bot) handleMessage(msg *tgbotapi.Message) {
-	// Add user to subscribed chats when they interact with the bot
-	b.addSubscribedChat(msg.Chat.ID)
-	
+	isGroup := msg.Chat.IsGroup() || msg.Chat.IsSuperGroup()
+
+	// Groups only get pushes after an admin opts in with /subscribe;
+	// private chats are subscribed when the user interacts with the bot
+	if !isGroup {
+		b.addSubscribedChat(msg.Chat.ID)
+	}
+
 	// Handle empty messages
 	if msg.Text == "" {
 		log.Printf("⚠️ Received message with empty text from user %d", msg.Chat.ID)
 		return
 	}
-	
-	command := strings.ToLower(strings.TrimSpace(msg.Text))
+
+	// Normalize "/status@botname args" to "/status args"; in groups, ignore
+	// regular chatter and commands addressed to other bots
+	text, addressed := b.commandText(msg)
+	if isGroup && !addressed {
+		return
+	}
+	command := strings.ToLower(text)
 	log.Printf("🔍 Processing command: %s", command)
-	
+
 	switch {
 	case strings.HasPrefix(command, "/start"):
 		log.Println("📤 Sending welcome message...")
 		b.sendWelcome(msg.Chat.ID)
 	case strings.HasPrefix(command, "/status"):
 		log.Println("📤 Sending status update...")
-		b.sendStatus(msg.Chat.ID)
+		parts := strings.Fields(command)
+		b.sendStatus(msg.Chat.ID, len(parts) > 1 && parts[1] == "plain")
+	case strings.HasPrefix(command, "/chart"):
+		period := "24h"
+		if parts := strings.Fields(command); len(parts) > 1 {
+			period = parts[1]
+		}
+		log.Printf("📤 Sending %s traffic chart...", period)
+		b.sendChart(msg.Chat.ID, period)
+	case strings.HasPrefix(command, "/quiet"):
+		b.handleQuietHours(msg.Chat.ID, strings.Fields(command)[1:])
+	case strings.HasPrefix(command, "/asn"):
+		log.Println("📤 Sending ASN sparklines...")
+		b.sendASNSparklines(msg.Chat.ID)
+	case strings.HasPrefix(command, "/botstats"):
+		log.Println("📤 Sending bot stats...")
+		b.sendBotStats(msg.Chat.ID, senderID(msg))
+	case strings.HasPrefix(command, "/incidents"):
+		b.sendIncidents(msg.Chat.ID, senderID(msg))
+	case strings.HasPrefix(command, "/confirm"):
+		b.handleConfirm(msg, confidenceConfirmed, strings.Fields(text)[1:])
+	case strings.HasPrefix(command, "/deny"):
+		b.handleConfirm(msg, confidenceDenied, strings.Fields(text)[1:])
+	case strings.HasPrefix(command, "/ack"):
+		b.handleAck(msg, strings.Fields(text)[1:])
+	case strings.HasPrefix(command, "/export"):
+		log.Println("📤 Sending history export...")
+		b.sendExport(msg.Chat.ID, strings.Fields(command)[1:])
+	case strings.HasPrefix(command, "/settings"):
+		log.Println("📤 Sending settings menu...")
+		b.sendSettings(msg.Chat.ID)
+	case strings.HasPrefix(command, "/subscribe"):
+		b.handleGroupSubscription(msg, true)
+	case strings.HasPrefix(command, "/unsubscribe"):
+		b.handleGroupSubscription(msg, false)
+	case strings.HasPrefix(command, "/notes"):
+		b.handleNote(msg.Chat.ID, senderID(msg), actor(msg.From), "")
+	case strings.HasPrefix(command, "/note"):
+		// Keep the note's original case - only the command is lowercased
+		note := strings.TrimSpace(text[len("/note"):])
+		b.handleNote(msg.Chat.ID, senderID(msg), actor(msg.From), note)
+	case strings.HasPrefix(command, "/campaign"):
+		// Keep the reason's original case - only the command is lowercased
+		b.handleCampaign(msg.Chat.ID, senderID(msg), actor(msg.From), strings.Fields(text)[1:])
+	case strings.HasPrefix(command, "/broadcast"):
+		// Keep the announcement's original case - only the command is lowercased
+		announcement := strings.TrimSpace(text[len("/broadcast"):])
+		b.handleBroadcast(msg.Chat.ID, senderID(msg), announcement)
 	case strings.HasPrefix(command, "/interval"):
 		parts := strings.Fields(command)
 		if len(parts) > 1 {
@@ -191,14 +373,22 @@ func (b *Bot) handleMessage(msg *tgbotapi.Message) {
 func (b *Bot) addSubscribedChat(chatID int64) {
 	b.chatsMu.Lock()
 	defer b.chatsMu.Unlock()
+	if b.subscribedChats[chatID] {
+		return
+	}
 	b.subscribedChats[chatID] = true
+	if b.sharedState != nil {
+		if err := b.sharedState.AddSubscriber(chatID); err != nil {
+			log.Printf("⚠️  Failed to store subscription of chat %d: %v", chatID, err)
+		}
+	}
 }
 
 // getSubscribedChats returns a copy of all subscribed chat IDs
 func (b *Bot) getSubscribedChats() []int64 {
 	b.chatsMu.RLock()
 	defer b.chatsMu.RUnlock()
-	
+
 	chats := make([]int64, 0, len(b.subscribedChats))
 	for chatID := range b.subscribedChats {
 		chats = append(chats, chatID)
@@ -208,7 +398,7 @@ func (b *Bot) getSubscribedChats() []int64 {
 
 func (b *Bot) sendWelcome(chatID int64) {
 	intervalMinutes := int(b.getUpdateInterval().Minutes())
-	
+
 	text := fmt.Sprintf(`🤖 Welcome to NetBlocks Monitor Bot!
 
 I monitor:
@@ -217,11 +407,18 @@ I monitor:
 
 Commands:
 /status - Get current monitoring status
+/asn - ASN connectivity over the last 24 hours
+/chart [24h|7d|30d] - Iran traffic chart for a period
+/quiet <start>-<end> - Set quiet hours (e.g., /quiet 0-8)
+/settings - Language, verbosity, alerts and watchlist
+/export json|csv [period] - Download history as a file
 /interval <minutes> - Set periodic update interval
 /help - Show help message
 
-You will receive automatic updates every %d minutes. Use /interval to change this.`, intervalMinutes)
-	
+You will receive automatic updates every %d minutes. Use /interval to change this.
+
+Version: %s`, intervalMinutes, version.String())
+
 	b.sendMessage(chatID, text)
 }
 
@@ -230,12 +427,26 @@ func (b *Bot) sendHelp(chatID int64) {
 
 /start - Start the bot and see welcome message
 /status - Get current status of all monitored systems
+/status plain - Status in short sentences without emoji, for screen readers
+/asn - Per-ASN 24h connectivity sparklines
+/chart [24h|7d|30d] - Traffic chart for the given period (e.g., /chart 7d)
+/quiet <start>-<end> - No non-critical pushes during these local hours (e.g., /quiet 0-8)
+/quiet off - Disable quiet hours
+/settings - Language, verbosity, plain text, alert threshold and ASN watchlist
+/export json|csv [24h|7d|30d] - Download history as a file (e.g., /export csv 7d)
 /interval <minutes> - Set monitoring check interval (e.g., /interval 5)
+/subscribe, /unsubscribe - Turn periodic updates on/off for a group (group admins)
+/botstats - Bot health report (administrators only)
+/incidents, /ack <id> - List and acknowledge escalating incidents (administrators only)
+/confirm <ref>, /deny <ref> - Confirm or deny a possible disruption (administrators only)
+/broadcast <text> - Announce to all subscribers and channels (administrators only)
+/note <text>, /notes - Annotate the current time on charts, the timeline and exports; list the last 24h's notes (administrators only)
+/campaign start <name> [duration] [reason], /campaign stop - Intensify the measurements for a while (administrators only)
 /help - Show this help message
 
 Example:
 /interval 20 - Set interval to 20 minutes (default)`
-	
+
 	b.sendMessage(chatID, text)
 }
 
@@ -247,13 +458,13 @@ func (b *Bot) handleSetInterval(chatID int64, intervalStr string) {
 	}
 
 	newInterval := time.Duration(minutes) * time.Minute
-	
+
 	b.intervalMu.Lock()
 	b.updateInterval = newInterval
 	b.intervalMu.Unlock()
-	
+
 	b.config.Interval = newInterval
-	
+
 	// Save config
 	if err := config.SaveConfig("config.json", b.config); err != nil {
 		log.Printf("Failed to save config: %v", err)
@@ -273,7 +484,9 @@ func (b *Bot) getUpdateInterval() time.Duration {
 	return interval
 }
 
-func (b *Bot) sendStatus(chatID int64) {
+// sendStatus sends the current status; plain sends it as for the chat's plain
+// setting, in short sentences for screen readers
+func (b *Bot) sendStatus(chatID int64, plain bool) {
 	if b.onStatusUpdate == nil {
 		b.sendMessage(chatID, "❌ Status update function not available")
 		return
@@ -285,6 +498,10 @@ func (b *Bot) sendStatus(chatID int64) {
 		return
 	}
 
+	if plain {
+		b.sendPlainStatus(chatID, result, normalizeLang(b.prefs.get(chatID).Language))
+		return
+	}
 	// Split status into multiple messages to avoid Telegram's 4096 character limit
 	b.sendStatusMessages(chatID, result)
 }
@@ -292,35 +509,36 @@ func (b *Bot) sendStatus(chatID int64) {
 // formatStatus formats the complete status (for logging)
 func (b *Bot) formatStatus(result *models.MonitoringResult) string {
 	var builder strings.Builder
-	
+
 	builder.WriteString("📊 NetBlocks Monitoring Status\n")
 	builder.WriteString(fmt.Sprintf("⏰ Last Update: %s\n\n", result.Timestamp.Format("2006-01-02 15:04:05")))
-	
+
 	// ASN Status
-	asnText := b.formatASNStatus(result)
+	asnText := b.formatASNStatus(result, langEnglish)
 	builder.WriteString(asnText)
 	builder.WriteString("\n")
-	
+
 	// DNS Status
-	dnsText := b.formatDNSStatus(result)
+	dnsText := b.formatDNSStatus(result, langEnglish)
 	builder.WriteString(dnsText)
-	
+
 	return builder.String()
 }
 
-// formatASNStatus formats ASN connectivity status
-func (b *Bot) formatASNStatus(result *models.MonitoringResult) string {
+// formatASNStatus formats ASN connectivity status with headings in the given language
+func (b *Bot) formatASNStatus(result *models.MonitoringResult, lang string) string {
 	var builder strings.Builder
-	
-	builder.WriteString("🌐 *ASN Connectivity*\n")
+	locale := i18n.For(lang)
+
+	builder.WriteString(fmt.Sprintf("🌐 *%s*\n", tr(lang, "ASN Connectivity")))
 	builder.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 	connectedCount := 0
 	totalCount := len(result.ASNStatuses)
-	
+
 	// Sort ASNs for better readability (connected first, then by name)
 	type asnEntry struct {
-		asn      string
-		status   *models.ASNStatus
+		asn       string
+		status    *models.ASNStatus
 		connected bool
 	}
 	var entries []asnEntry
@@ -330,7 +548,7 @@ func (b *Bot) formatASNStatus(result *models.MonitoringResult) string {
 			connectedCount++
 		}
 	}
-	
+
 	// Sort: connected first, then by ASN
 	for i := 0; i < len(entries)-1; i++ {
 		for j := i + 1; j < len(entries); j++ {
@@ -343,26 +561,55 @@ func (b *Bot) formatASNStatus(result *models.MonitoringResult) string {
 			}
 		}
 	}
-	
+
 	for _, entry := range entries {
 		icon := "🔴"
 		if entry.status.Connected {
 			icon = "🟢"
 		}
-		lastSeen := "Never"
+		lastSeen := tr(lang, "Never")
 		if !entry.status.LastSeen.IsZero() {
-			lastSeen = entry.status.LastSeen.Format("15:04:05")
+			lastSeen = locale.Time(entry.status.LastSeen, "15:04:05")
 		}
 		// Display ASN with readable name if available
 		asnDisplay := entry.asn
 		if entry.status.Name != "" {
 			asnDisplay = fmt.Sprintf("%s - %s", entry.asn, entry.status.Name)
 		}
-		builder.WriteString(fmt.Sprintf("%s `%s`\n   └─ Last seen: %s\n", icon, asnDisplay, lastSeen))
+		builder.WriteString(fmt.Sprintf("%s `%s`\n   └─ %s: %s\n", icon, asnDisplay, tr(lang, "Last seen"), lastSeen))
+		if entry.status.Flapping {
+			builder.WriteString(fmt.Sprintf("   └─ 〰️ %s: %s %s\n", tr(lang, "Flapping"), locale.Int(entry.status.Flaps), tr(lang, "changes")))
+		}
+		if rpki := entry.status.RPKI; rpki != nil {
+			rpkiIcon := "🔏"
+			if rpki.Invalid > 0 {
+				rpkiIcon = "⚠️"
+			}
+			builder.WriteString(fmt.Sprintf("   └─ %s RPKI: %s %s, %s %s, %s %s\n", rpkiIcon,
+				locale.Int(rpki.Valid), tr(lang, "valid"), locale.Int(rpki.Invalid), tr(lang, "invalid"), locale.Int(rpki.NotFound), tr(lang, "not found")))
+		}
 	}
-	
-	builder.WriteString(fmt.Sprintf("\n📈 *Summary:* %d/%d Connected\n", connectedCount, totalCount))
-	
+
+	builder.WriteString(fmt.Sprintf("\n📈 *%s:* %s/%s %s\n", tr(lang, "Summary"), locale.Int(connectedCount), locale.Int(totalCount), tr(lang, "Connected")))
+
+	// Global CDNs as an external reference, outside the summary above
+	if len(result.ReferenceASNs) > 0 {
+		references := make([]string, 0, len(result.ReferenceASNs))
+		for asn := range result.ReferenceASNs {
+			references = append(references, asn)
+		}
+		sort.Strings(references)
+		builder.WriteString(fmt.Sprintf("\n🛰 *%s* _(%s)_\n", tr(lang, "External Reference"), tr(lang, "not counted in the summary")))
+		for _, asn := range references {
+			status := result.ReferenceASNs[asn]
+			icon := "🔴"
+			if status.Connected {
+				icon = "🟢"
+			}
+			builder.WriteString(fmt.Sprintf("%s `%s - %s`\n", icon, asn, status.Name))
+		}
+	}
+
 	return builder.String()
 }
 
@@ -375,26 +622,6 @@ type dnsEntry struct {
 	alive   bool
 }
 
-// parseCityFromName extracts city from DNS server name (e.g., "DNS (Tehran)" -> "Tehran")
-func parseCityFromName(name string) string {
-	// Look for city in parentheses, e.g., "(Tehran)", "(Tehran - Primary)", "(Madrid, Spain)"
-	start := strings.LastIndex(name, "(")
-	end := strings.LastIndex(name, ")")
-	if start != -1 && end != -1 && end > start {
-		city := name[start+1 : end]
-		// Remove qualifiers like "- Primary" or ", Spain" - keep only city name
-		if idx := strings.Index(city, " - "); idx != -1 {
-			city = city[:idx]
-		}
-		if idx := strings.Index(city, ","); idx != -1 {
-			city = city[:idx]
-		}
-		return strings.TrimSpace(city)
-	}
-	// Default to "Other" if no city found
-	return "Other"
-}
-
 // parseTypeFromName determines if DNS server is authoritative or recursive
 func parseTypeFromName(name string) string {
 	nameLower := strings.ToLower(name)
@@ -411,24 +638,24 @@ func parseTypeFromName(name string) string {
 	return "recursive"
 }
 
-// formatDNSStatus formats DNS server status organized by city and type
-func (b *Bot) formatDNSStatus(result *models.MonitoringResult) string {
+// formatDNSStatus formats DNS server status organized by city and type with headings in the given language
+func (b *Bot) formatDNSStatus(result *models.MonitoringResult, lang string) string {
 	var builder strings.Builder
-	
-	builder.WriteString("🔍 *DNS Servers Status*\n")
+
+	builder.WriteString(fmt.Sprintf("🔍 *%s*\n", tr(lang, "DNS Servers Status")))
 	builder.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
-	
+
 	aliveCount := 0
 	dnsTotal := len(result.DNSStatuses)
-	
+
 	// Group DNS servers by city and type
 	entries := make([]dnsEntry, 0, dnsTotal)
 	cityTypeMap := make(map[string]map[string][]dnsEntry) // city -> type -> entries
-	
+
 	for addr, status := range result.DNSStatuses {
-		city := parseCityFromName(status.Name)
+		city := config.GetDNSCity(status.Name)
 		dnsType := parseTypeFromName(status.Name)
-		
+
 		entry := dnsEntry{
 			addr:    addr,
 			status:  status,
@@ -436,20 +663,20 @@ func (b *Bot) formatDNSStatus(result *models.MonitoringResult) string {
 			dnsType: dnsType,
 			alive:   status.Alive,
 		}
-		
+
 		if status.Alive {
 			aliveCount++
 		}
-		
+
 		entries = append(entries, entry)
-		
+
 		// Group by city and type
 		if cityTypeMap[city] == nil {
 			cityTypeMap[city] = make(map[string][]dnsEntry)
 		}
 		cityTypeMap[city][dnsType] = append(cityTypeMap[city][dnsType], entry)
 	}
-	
+
 	// Define city display order (most important cities first)
 	cityOrder := []string{
 		"Tehran", "Esfahan", "Isfahan", "Shiraz", "Mashhad", "Tabriz",
@@ -458,9 +685,9 @@ func (b *Bot) formatDNSStatus(result *models.MonitoringResult) string {
 		"Mazandaran", "Qazvin", "Semnan", "South Khorasan", "Yazd", "Zanjan",
 		"England", "Madrid", "Spain", "Other",
 	}
-	
+
 	citySeen := make(map[string]bool)
-	
+
 	// First, print cities in order
 	for _, city := range cityOrder {
 		if types, exists := cityTypeMap[city]; exists {
@@ -468,43 +695,44 @@ func (b *Bot) formatDNSStatus(result *models.MonitoringResult) string {
 			printCitySection(&builder, city, types)
 		}
 	}
-	
+
 	// Then, print any remaining cities not in the order
 	for city, types := range cityTypeMap {
 		if !citySeen[city] {
 			printCitySection(&builder, city, types)
 		}
 	}
-	
+
 	builder.WriteString("\n")
 	builder.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-	builder.WriteString(fmt.Sprintf("📈 *Summary:* %d/%d Alive\n", aliveCount, dnsTotal))
-	
+	locale := i18n.For(lang)
+	builder.WriteString(fmt.Sprintf("📈 *%s:* %s/%s %s\n", tr(lang, "Summary"), locale.Int(aliveCount), locale.Int(dnsTotal), tr(lang, "Alive")))
+
 	return builder.String()
 }
 
 // printCitySection prints all DNS servers for a city, grouped by type
 func printCitySection(builder *strings.Builder, city string, types map[string][]dnsEntry) {
 	builder.WriteString(fmt.Sprintf("🏙️  *%s*\n", city))
-	
+
 	// Print authoritative first, then recursive
 	typeOrder := []string{"authoritative", "recursive"}
-	
+
 	for _, dnsType := range typeOrder {
 		entries, exists := types[dnsType]
 		if !exists || len(entries) == 0 {
 			continue
 		}
-		
+
 		typeEmoji := "📡"
 		typeLabel := "Authoritative"
 		if dnsType == "recursive" {
 			typeEmoji = "🔄"
 			typeLabel = "Recursive"
 		}
-		
+
 		builder.WriteString(fmt.Sprintf("   %s *%s DNS*\n", typeEmoji, typeLabel))
-		
+
 		// Sort entries: alive first, then by name
 		for i := 0; i < len(entries)-1; i++ {
 			for j := i + 1; j < len(entries); j++ {
@@ -517,14 +745,18 @@ func printCitySection(builder *strings.Builder, city string, types map[string][]
 				}
 			}
 		}
-		
+
 		// Print each server
 		for _, entry := range entries {
 			icon := "🔴"
 			if entry.status.Alive {
 				icon = "🟢"
+				// Answers, but not as its type requires (e.g. a recursive refusing recursion)
+				if entry.status.Verdict == models.VerdictFail {
+					icon = "🟡"
+				}
 			}
-			
+
 			// Clean up name (remove city from display since we're already showing it)
 			displayName := entry.status.Name
 			cityInParen := fmt.Sprintf("(%s", city)
@@ -536,13 +768,19 @@ func printCitySection(builder *strings.Builder, city string, types map[string][]
 					displayName = strings.TrimSpace(displayName)
 				}
 			}
-			
+
 			responseTime := entry.status.ResponseTime.Milliseconds()
-			builder.WriteString(fmt.Sprintf("      %s *%s*\n         └─ `%s` - %dms\n",
-				icon, displayName, entry.addr, responseTime))
+			latency := fmt.Sprintf("%dms", responseTime)
+			if entry.status.UncachedTime > 0 {
+				latency += fmt.Sprintf(" (uncached %dms)", entry.status.UncachedTime.Milliseconds())
+			}
+			builder.WriteString(fmt.Sprintf("      %s *%s*\n         └─ `%s` - %s\n",
+				icon, displayName, entry.addr, latency))
 			if entry.status.Error != "" && !entry.status.Alive {
 				// Only show error if server is offline
 				builder.WriteString(fmt.Sprintf("         └─ ⚠️ %s\n", entry.status.Error))
+			} else if entry.status.Verdict == models.VerdictFail {
+				builder.WriteString(fmt.Sprintf("         └─ ⚠️ %s: %s\n", entry.status.Type, entry.status.Reason))
 			}
 		}
 		builder.WriteString("\n")
@@ -552,68 +790,39 @@ func printCitySection(builder *strings.Builder, city string, types map[string][]
 // sendMessage sends a message to a chat (user or channel)
 // chatID can be an int64 for users or a string for channel username (e.g., "@channel")
 func (b *Bot) sendMessage(chatID interface{}, text string) {
-	const maxMessageLength = 4096
-	
+	b.sendMessageToTopic(chatID, 0, text)
+}
+
+// sendMessageToTopic sends a message into a forum topic (threadID 0 = no topic)
+// Messages over Telegram's 4096 character limit are split into parts
+func (b *Bot) sendMessageToTopic(chatID interface{}, threadID int, text string) {
 	// Split message if it's too long
 	if len(text) <= maxMessageLength {
-		var msg tgbotapi.MessageConfig
-		
-		// Handle both int64 (user chat ID) and string (channel username)
-		switch id := chatID.(type) {
-		case int64:
-			msg = tgbotapi.NewMessage(id, text)
-		case string:
-			msg = tgbotapi.NewMessageToChannel(id, text)
-		default:
-			log.Printf("Error: invalid chatID type: %T", chatID)
-			return
-		}
-		
-		msg.ParseMode = tgbotapi.ModeMarkdown
-		sentMsg, err := b.api.Send(msg)
-		if err != nil {
+		sentMsg, err := b.sendText(chatID, threadID, text)
+		if errors.Is(err, errSpooled) {
+			// Logged when queued (see spool.go)
+		} else if err != nil {
+			// Channel rights are checked at startup (see preflightChannels)
 			log.Printf("❌ ERROR sending message to %v: %v", chatID, err)
-			// For channels, provide helpful error message
-			if channelName, ok := chatID.(string); ok {
-				log.Printf("⚠️  CHANNEL ERROR DETAILS:")
-				log.Printf("   Channel: %v", channelName)
-				log.Printf("   Error: %v", err)
-				log.Printf("⚠️  TROUBLESHOOTING:")
-				log.Printf("   1. Make sure the bot is added as an administrator to the channel")
-				log.Printf("   2. Bot must have 'Post messages' permission")
-				log.Printf("   3. If using username (@channel), try numeric channel ID (e.g., -1001234567890)")
-				log.Printf("   4. Check if channel exists and is accessible")
-			}
 		} else {
 			log.Printf("✅ Successfully sent message to %v (message ID: %d, chat ID: %d)", chatID, sentMsg.MessageID, sentMsg.Chat.ID)
 		}
 		return
 	}
-	
+
 	// Split into chunks
 	lines := strings.Split(text, "\n")
 	var currentChunk strings.Builder
 	chunkNum := 1
-	
+
 	for _, line := range lines {
 		// Check if adding this line would exceed the limit
 		potentialLength := currentChunk.Len() + len(line) + 1 // +1 for newline
-		if potentialLength > maxMessageLength-50 { // Leave some margin
+		if potentialLength > maxMessageLength-50 {            // Leave some margin
 			// Send current chunk
 			if currentChunk.Len() > 0 {
 				chunkText := fmt.Sprintf("📄 *Part %d*\n\n%s", chunkNum, currentChunk.String())
-				var msg tgbotapi.MessageConfig
-				switch id := chatID.(type) {
-				case int64:
-					msg = tgbotapi.NewMessage(id, chunkText)
-				case string:
-					msg = tgbotapi.NewMessageToChannel(id, chunkText)
-				default:
-					log.Printf("Error: invalid chatID type: %T", chatID)
-					continue
-				}
-				msg.ParseMode = tgbotapi.ModeMarkdown
-				sentMsg, err := b.api.Send(msg)
+				sentMsg, err := b.sendText(chatID, threadID, chunkText)
 				if err != nil {
 					log.Printf("❌ Error sending message chunk to %v: %v", chatID, err)
 				} else {
@@ -626,22 +835,11 @@ func (b *Bot) sendMessage(chatID interface{}, text string) {
 		currentChunk.WriteString(line)
 		currentChunk.WriteString("\n")
 	}
-	
+
 	// Send remaining chunk
 	if currentChunk.Len() > 0 {
 		chunkText := fmt.Sprintf("📄 *Part %d*\n\n%s", chunkNum, currentChunk.String())
-		var msg tgbotapi.MessageConfig
-		switch id := chatID.(type) {
-		case int64:
-			msg = tgbotapi.NewMessage(id, chunkText)
-		case string:
-			msg = tgbotapi.NewMessageToChannel(id, chunkText)
-		default:
-			log.Printf("Error: invalid chatID type: %T", chatID)
-			return
-		}
-		msg.ParseMode = tgbotapi.ModeMarkdown
-		sentMsg, err := b.api.Send(msg)
+		sentMsg, err := b.sendText(chatID, threadID, chunkText)
 		if err != nil {
 			log.Printf("❌ Error sending final chunk to %v: %v", chatID, err)
 		} else {
@@ -650,32 +848,66 @@ func (b *Bot) sendMessage(chatID interface{}, text string) {
 	}
 }
 
-// sendStatusMessages sends status in multiple messages
-// ORDER: Header -> ASN status -> DNS status -> Traffic Chart (diagram LAST)
+// sendStatusMessages sends the status to a chat using its /settings language and verbosity
 // chatID can be int64 (user) or string (channel username)
 func (b *Bot) sendStatusMessages(chatID interface{}, result *models.MonitoringResult) {
-	// Send header
-	header := fmt.Sprintf("📊 *NetBlocks Monitoring Status*\n⏰ Last Update: `%s`\n", 
-		result.Timestamp.Format("2006-01-02 15:04:05"))
-	b.sendMessage(chatID, header)
-	
-	// Send ASN status (after diagram)
-	asnText := b.formatASNStatus(result)
-	if asnText != "" {
-		b.sendMessage(chatID, asnText)
-	}
-	
-	// Send DNS status (after diagram and ASN)
-	dnsText := b.formatDNSStatus(result)
-	if dnsText != "" {
-		b.sendMessage(chatID, dnsText)
+	profile, lang := profileFull, langEnglish
+	if id, ok := chatID.(int64); ok {
+		prefs := b.prefs.get(id)
+		profile, lang = prefs.profile(), normalizeLang(prefs.Language)
+	}
+	b.sendStatusPost(chatID, result, profile, lang)
+}
+
+// sendPlainStatus sends the status as short sentences without emoji, charts
+// or formatting, which screen readers read out well
+func (b *Bot) sendPlainStatus(chatID interface{}, result *models.MonitoringResult, lang string) {
+	b.sendMessageToTopic(chatID, b.topicFor(chatID, sectionHeader), escapeMarkdown(monitor.PlainStatus(result, lang)))
+}
+
+// sendStatusPost sends status in multiple messages
+// ORDER: Header -> ASN status -> DNS status -> Traffic Chart (diagram LAST)
+// The charts profile skips the ASN/DNS text sections, the plain profile sends
+// sentences only; headings use lang
+// In a forum supergroup each section goes to its configured topic (telegram_topics)
+func (b *Bot) sendStatusPost(chatID interface{}, result *models.MonitoringResult, profile, lang string) {
+	if profile == profilePlain {
+		b.sendPlainStatus(chatID, result, lang)
+		return
+	}
+
+	// Send header - as the composite status image when available so followers
+	// get the whole picture even without reading the long messages
+	locale := i18n.For(lang)
+	header := fmt.Sprintf("📊 *%s*\n⏰ %s: `%s`\n", tr(lang, "NetBlocks Monitoring Status"), tr(lang, "Last Update"),
+		locale.Time(result.Timestamp, "2006-01-02 15:04:05"))
+	if result.StatusImage != nil && result.StatusImage.Len() > 0 {
+		status, emoji := monitor.ScoreStatus(result.NationalScore)
+		header += fmt.Sprintf("%s *%s:* %s/%s (%s)\n", emoji, tr(lang, "National Score"), locale.Number(result.NationalScore, 0), locale.Int(100), tr(lang, status))
+		b.sendStatusImage(chatID, header, result.StatusImage, monitor.DescribeStatus(result))
+	} else {
+		b.sendMessageToTopic(chatID, b.topicFor(chatID, sectionHeader), header)
+	}
+
+	if profile != profileCharts {
+		// Send ASN status (after diagram)
+		asnText := b.formatASNStatus(result, lang)
+		if asnText != "" {
+			b.sendMessageToTopic(chatID, b.topicFor(chatID, sectionASN), asnText)
+		}
+
+		// Send DNS status (after diagram and ASN)
+		dnsText := b.formatDNSStatus(result, lang)
+		if dnsText != "" {
+			b.sendMessageToTopic(chatID, b.topicFor(chatID, sectionDNS), dnsText)
+		}
 	}
 
 	// Send traffic chart (diagram after other data)
 	if result.TrafficData != nil {
 		if result.TrafficData.ChartBuffer != nil && result.TrafficData.ChartBuffer.Len() > 0 {
 			log.Printf("📈 Sending Iran traffic chart (after ASN/DNS data)")
-			b.sendTrafficChart(chatID, result.TrafficData)
+			b.sendTrafficChart(chatID, result.TrafficData, lang)
 		} else {
 			log.Printf("⚠️  Traffic chart buffer is empty - skipping chart")
 		}
@@ -689,13 +921,25 @@ func (b *Bot) sendStatusMessages(chatID interface{}, result *models.MonitoringRe
 		firstItem := result.ASTrafficData[0]
 		if firstItem.ChartBuffer != nil && firstItem.ChartBuffer.Len() > 0 {
 			log.Printf("📊 Sending ASN traffic chart (after Iran traffic chart)")
-			b.sendASNTrafficChart(chatID, result.ASTrafficData, firstItem.ChartBuffer)
+			b.sendASNTrafficChart(chatID, result.ASTrafficData, firstItem.ChartBuffer, lang)
 		} else {
 			log.Printf("⚠️  ASN traffic chart buffer is empty - skipping chart")
 		}
 	} else {
 		log.Printf("⚠️  ASN traffic data is nil or empty - no ASN chart available")
 	}
+
+	// Send 7-day uptime heatmap last (scope and duration of outages)
+	if result.UptimeChart != nil && result.UptimeChart.Len() > 0 {
+		log.Printf("🗓 Sending uptime heatmap (after ASN traffic chart)")
+		b.sendUptimeChart(chatID, result, lang)
+	}
+
+	// Province map after the heatmap: where in the country the outages are
+	if result.ProvinceMap != nil && result.ProvinceMap.Len() > 0 {
+		log.Printf("🗺 Sending province map (after uptime heatmap)")
+		b.sendProvinceMap(chatID, result, lang)
+	}
 }
 
 // SendPeriodicUpdates sends periodic status updates to all subscribed users
@@ -707,18 +951,23 @@ func (b *Bot) SendPeriodicUpdates(ctx context.Context) {
 	log.Println("⏳ Waiting for initial monitoring data collection...")
 	// Start immediately - monitoring data is already collected from PerformInitialCheck
 	// Check every second for interval changes and time elapsed
-	checkTicker := time.NewTicker(1 * time.Second)
+	checkTicker := b.clock.NewTicker(1 * time.Second)
 	defer checkTicker.Stop()
-	
-	lastUpdateTime := time.Now()
-	lastChannelUpdateTime := time.Time{} // Start with zero time so channel gets immediate update
+
+	lastUpdateTime := b.clock.Now()
 	lastInterval := b.getUpdateInterval()
-	channelInterval := 19 * time.Minute // Channel updates every 20 minutes
-	
+	lastAlertFlush := b.clock.Now()
+
 	log.Printf("Periodic updates started - will send to subscribed users every %v", lastInterval)
-	if b.channelID != "" {
-		log.Printf("✅ Channel updates will be sent every %v to: %s", channelInterval, b.channelID)
-		log.Printf("📋 Channel will receive first status update after monitoring data is ready")
+	if len(b.channels) > 0 {
+		for _, ch := range b.channels {
+			if ch.profile == profileAlerts {
+				log.Printf("✅ Channel %s receives alerts only", ch.id)
+			} else {
+				log.Printf("✅ Channel updates (%s) will be sent %s to: %s", ch.profile, ch.describeSchedule(), ch.id)
+			}
+		}
+		log.Printf("📋 Channels will receive first status update after monitoring data is ready")
 	} else {
 		log.Printf("⚠️  No channel configured - skipping channel updates")
 	}
@@ -727,11 +976,16 @@ func (b *Bot) SendPeriodicUpdates(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case <-checkTicker.C:
+		case <-checkTicker.C():
+			// Deliver batched minor changes once per batch window
+			if b.clock.Since(lastAlertFlush) >= b.alertBatch {
+				b.flushPendingAlerts()
+				lastAlertFlush = b.clock.Now()
+			}
+
 			currentInterval := b.getUpdateInterval()
-			timeSinceLastUpdate := time.Since(lastUpdateTime)
-			timeSinceLastChannelUpdate := time.Since(lastChannelUpdateTime)
-			
+			timeSinceLastUpdate := b.clock.Since(lastUpdateTime)
+
 			// Check if interval changed
 			if currentInterval != lastInterval {
 				log.Printf("Periodic update interval changed from %v to %v", lastInterval, currentInterval)
@@ -741,24 +995,29 @@ func (b *Bot) SendPeriodicUpdates(ctx context.Context) {
 				if timeSinceLastUpdate >= currentInterval {
 					lastUpdateTime = time.Time{} // Force immediate update
 				} else {
-					lastUpdateTime = time.Now() // Reset to wait for new interval
+					lastUpdateTime = b.clock.Now() // Reset to wait for new interval
 				}
 			}
-			
-			// Check if it's time to send channel update (every 20 minutes)
-			shouldSendChannelUpdate := false
-			if b.channelID != "" {
-				// If lastChannelUpdateTime is zero (startup), send immediately
-				if lastChannelUpdateTime.IsZero() || timeSinceLastChannelUpdate >= channelInterval {
-					shouldSendChannelUpdate = true
-					if lastChannelUpdateTime.IsZero() {
-						log.Printf("🚀 Sending initial channel update to: %s", b.channelID)
-					} else {
-						log.Printf("⏰ Channel update interval reached: %v elapsed", timeSinceLastChannelUpdate)
-					}
+
+			// Check which channels are due for a status post (each has its own interval or schedule)
+			// Channels Telegram asked to back off (HTTP 429) wait until the backoff ends
+			var dueChannels []*channelTarget
+			now := b.clock.Now()
+			for _, ch := range b.channels {
+				if ch.profile == profileAlerts || !ch.due(now) || b.limiter.blocked(ch.id, now) {
+					continue
+				}
+				// If lastPost is zero (startup), send immediately
+				if ch.lastPost.IsZero() {
+					log.Printf("🚀 Sending initial channel update to: %s", ch.id)
+				} else {
+					log.Printf("⏰ Channel update due for %s (%s)", ch.id, ch.describeSchedule())
 				}
+				dueChannels = append(dueChannels, ch)
 			}
-			
+			shouldSendChannelUpdate := len(dueChannels) > 0
+			shouldSendDigest := b.digestDue(now)
+
 			// Check if it's time to send user updates
 			shouldSendUserUpdate := false
 			if timeSinceLastUpdate >= currentInterval {
@@ -767,32 +1026,44 @@ func (b *Bot) SendPeriodicUpdates(ctx context.Context) {
 					shouldSendUserUpdate = true
 				}
 			}
-			
+
 			// Perform analysis if we need to send any updates
-			if shouldSendChannelUpdate || shouldSendUserUpdate {
+			if shouldSendChannelUpdate || shouldSendUserUpdate || shouldSendDigest {
 				if b.onStatusUpdate != nil {
 					result, err := b.onStatusUpdate()
 					if err != nil {
 						log.Printf("Error getting status for periodic update: %v", err)
 						continue
 					}
-					
-					// Send to channel if it's time (every 20 minutes)
-					if shouldSendChannelUpdate {
-						log.Printf("📢 Sending periodic update to channel: %s (interval: %v)", b.channelID, channelInterval)
-						b.sendStatusMessages(b.channelID, result)
-						lastChannelUpdateTime = time.Now()
-						log.Printf("✅ Channel update sent successfully to: %s", b.channelID)
+
+					if shouldSendDigest {
+						b.postDigest(result)
+					}
+
+					// Send to each channel whose interval elapsed, using its profile and language
+					for _, ch := range dueChannels {
+						log.Printf("📢 Sending periodic update to channel: %s (profile: %s, %s)", ch.id, ch.profile, ch.describeSchedule())
+						if !b.postChannelStatus(ch, result) {
+							// The post was cut short by a long flood wait; repeat it once that ends
+							log.Printf("⏳ Channel update to %s rate limited - retrying after %s", ch.id, b.limiter.backoffUntil(ch.id).Format("15:04:05"))
+							continue
+						}
+						ch.posted(b.clock.Now())
+						log.Printf("✅ Channel update sent successfully to: %s", ch.id)
 					}
-					
+
 					// Send to subscribed users if it's time
 					if shouldSendUserUpdate {
 						subscribedChats := b.getSubscribedChats()
 						log.Printf("Sending periodic update to %d subscribed user(s) (interval: %v)", len(subscribedChats), currentInterval)
 						for _, chatID := range subscribedChats {
+							// Periodic status is non-critical - hold it during quiet hours
+							if b.inQuietHours(chatID) {
+								continue
+							}
 							b.sendStatusMessages(chatID, result)
 						}
-						lastUpdateTime = time.Now()
+						lastUpdateTime = b.clock.Now()
 					}
 				}
 			}
@@ -800,80 +1071,245 @@ func (b *Bot) SendPeriodicUpdates(ctx context.Context) {
 	}
 }
 
-// sendTrafficChart sends the traffic chart as a photo with caption
-func (b *Bot) sendTrafficChart(chatID interface{}, data *models.TrafficData) {
-	if data == nil || data.ChartBuffer == nil || data.ChartBuffer.Len() == 0 {
+// HandleEvents delivers detected changes to subscribed chats
+// Critical events are sent immediately, even during quiet hours; everything
+// else is queued and delivered as a single batched message per batch window
+func (b *Bot) HandleEvents(events []models.Event) {
+	var critical []models.Event
+	var minor []models.Event
+	for _, event := range events {
+		if event.Severity == models.SeverityCritical {
+			critical = append(critical, event)
+		} else {
+			minor = append(minor, event)
+		}
+	}
+	// With confirm_incidents, critical alerts go out as possible disruptions
+	// until an administrator confirms or denies them (see confirm.go)
+	pending := b.openConfirmation(critical)
+	if b.archive != "" {
+		b.archiveLog.record(events)
+	}
+
+	// Each chat only gets events above its severity threshold and on its watchlist (/settings)
+	for _, chatID := range b.getSubscribedChats() {
+		prefs := b.prefs.get(chatID)
+
+		if chatMinor := filterEvents(prefs, minor); len(chatMinor) > 0 {
+			b.alertsMu.Lock()
+			b.pendingAlerts[chatID] = queueAlerts(b.pendingAlerts[chatID], chatMinor)
+			b.alertsMu.Unlock()
+		}
+
+		if chatCritical := filterEvents(prefs, critical); len(chatCritical) > 0 {
+			b.sendMessage(chatID, b.criticalAlert(pending, chatID, 0, normalizeLang(prefs.Language), chatCritical))
+		}
+	}
+
+	for _, ch := range b.channels {
+		// Alerts channels get the same treatment as subscribers; status channels
+		// only get critical alerts, and only in a dedicated forum topic
+		threadID := b.topicFor(ch.id, sectionAlerts)
+		if ch.profile == profileAlerts && len(minor) > 0 {
+			b.alertsMu.Lock()
+			b.channelAlerts[ch.id] = queueAlerts(b.channelAlerts[ch.id], minor)
+			b.alertsMu.Unlock()
+		}
+		if len(critical) > 0 && (ch.profile == profileAlerts || threadID != 0) {
+			b.sendMessageToTopic(ch.id, threadID, b.criticalAlert(pending, ch.id, threadID, ch.lang, critical))
+		}
+	}
+	if pending != nil {
+		b.askConfirmation(pending)
+	}
+}
+
+// maxPendingAlerts caps each chat's and channel's queue of batched changes, so
+// a long quiet window or flood wait only keeps the most recent ones
+const maxPendingAlerts = 200
+
+// queueAlerts appends events to a queue and drops the oldest beyond maxPendingAlerts
+func queueAlerts(queue, events []models.Event) []models.Event {
+	queue = append(queue, events...)
+	if over := len(queue) - maxPendingAlerts; over > 0 {
+		queue = append([]models.Event(nil), queue[over:]...)
+	}
+	return queue
+}
+
+// filterEvents returns the events a chat wants according to its preferences
+func filterEvents(prefs ChatPrefs, events []models.Event) []models.Event {
+	var wanted []models.Event
+	for _, event := range events {
+		if prefs.wants(event) {
+			wanted = append(wanted, event)
+		}
+	}
+	return wanted
+}
+
+// flushPendingAlerts sends each chat its queued minor changes as one message
+// Chats in quiet hours keep their queue until the quiet window ends
+func (b *Bot) flushPendingAlerts() {
+	b.alertsMu.Lock()
+	ready := make(map[int64][]models.Event)
+	for chatID, events := range b.pendingAlerts {
+		if len(events) == 0 || b.inQuietHours(chatID) {
+			continue
+		}
+		ready[chatID] = events
+		delete(b.pendingAlerts, chatID)
+	}
+	readyChannels := b.channelAlerts
+	b.channelAlerts = make(map[string][]models.Event)
+	b.alertsMu.Unlock()
+
+	for chatID, events := range ready {
+		lang := normalizeLang(b.prefs.get(chatID).Language)
+		title := fmt.Sprintf("🔔 *"+tr(lang, "%s network change(s) since last update")+"*", i18n.For(lang).Int(len(events)))
+		b.sendMessage(chatID, formatAlerts(title, events))
+	}
+	for _, ch := range b.channels {
+		events := readyChannels[ch.id]
+		if len(events) == 0 {
+			continue
+		}
+		if b.limiter.blocked(ch.id, time.Now()) {
+			// Keep the batch until Telegram lets the channel post again
+			b.alertsMu.Lock()
+			b.channelAlerts[ch.id] = queueAlerts(events, b.channelAlerts[ch.id])
+			b.alertsMu.Unlock()
+			continue
+		}
+		title := fmt.Sprintf("🔔 *"+tr(ch.lang, "%s network change(s) since last update")+"*", i18n.For(ch.lang).Int(len(events)))
+		b.sendMessageToTopic(ch.id, b.topicFor(ch.id, sectionAlerts), formatAlerts(title, events))
+	}
+}
+
+// maxAlertLines caps the number of individual changes listed in one alert message
+const maxAlertLines = 30
+
+// formatAlerts formats a list of events under a title, most severe first
+func formatAlerts(title string, events []models.Event) string {
+	sorted := make([]models.Event, len(events))
+	copy(sorted, events)
+	rank := map[string]int{models.SeverityCritical: 0, models.SeverityWarning: 1, models.SeverityInfo: 2}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank[sorted[i].Severity] < rank[sorted[j].Severity]
+	})
+
+	var builder strings.Builder
+	builder.WriteString(title + "\n\n")
+	for i, event := range sorted {
+		if i >= maxAlertLines {
+			builder.WriteString(fmt.Sprintf("…and %d more\n", len(sorted)-maxAlertLines))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("%s %s `%s`", monitor.SeverityEmoji(event.Severity),
+			event.Message, event.Timestamp.Format("15:04")))
+		if event.Incident != "" {
+			builder.WriteString(fmt.Sprintf(" (incident `%s`)", event.Incident))
+		}
+		builder.WriteString("\n")
+	}
+	for _, narrative := range models.Narratives(sorted) {
+		builder.WriteString("\n📝 " + narrative + "\n")
+	}
+	return builder.String()
+}
+
+// inQuietHours reports whether a chat is currently inside its quiet hours
+func (b *Bot) inQuietHours(chatID int64) bool {
+	prefs := b.prefs.get(chatID)
+	return prefs.QuietHours.Contains(b.clock.Now().In(b.location))
+}
+
+// handleQuietHours handles /quiet [start-end|off]
+func (b *Bot) handleQuietHours(chatID int64, args []string) {
+	if len(args) == 0 {
+		prefs := b.prefs.get(chatID)
+		if prefs.QuietHours == nil {
+			b.sendMessage(chatID, "🔔 Quiet hours are off.\nUsage: /quiet <start>-<end> (e.g., /quiet 0-8) or /quiet off")
+		} else {
+			b.sendMessage(chatID, fmt.Sprintf("🌙 Quiet hours: %s (%s). Only critical alerts are sent during this time.",
+				prefs.QuietHours.String(), b.location.String()))
+		}
 		return
 	}
-	
-	caption := monitor.FormatTrafficStatus(data)
-	
-	fileBytes := tgbotapi.FileBytes{
-		Name:  "iran_traffic_24h.png",
-		Bytes: data.ChartBuffer.Bytes(),
-	}
-	
-	var photo tgbotapi.PhotoConfig
-	switch id := chatID.(type) {
-	case int64:
-		photo = tgbotapi.NewPhoto(id, fileBytes)
-	case string:
-		photo = tgbotapi.NewPhotoToChannel(id, fileBytes)
-	default:
+
+	var quiet *QuietHours
+	if args[0] != "off" {
+		var start, end int
+		if _, err := fmt.Sscanf(args[0], "%d-%d", &start, &end); err != nil ||
+			start < 0 || start > 23 || end < 0 || end > 23 || start == end {
+			b.sendMessage(chatID, "❌ Invalid quiet hours. Use /quiet <start>-<end> with hours 0-23 (e.g., /quiet 0-8)")
+			return
+		}
+		quiet = &QuietHours{Start: start, End: end}
+	}
+
+	if err := b.prefs.update(chatID, func(p *ChatPrefs) { p.QuietHours = quiet }); err != nil {
+		log.Printf("Failed to save chat preferences: %v", err)
+	}
+
+	if quiet == nil {
+		b.sendMessage(chatID, "🔔 Quiet hours disabled.")
 		return
 	}
-	
-	photo.Caption = caption
-	photo.ParseMode = tgbotapi.ModeMarkdown
-	
-	_, _ = b.api.Send(photo)
+	b.sendMessage(chatID, fmt.Sprintf("🌙 Quiet hours set to %s (%s). Critical alerts will still be delivered; other changes are batched until quiet hours end.",
+		quiet.String(), b.location.String()))
+}
+
+// sendTrafficChart sends the traffic chart as a photo with caption
+func (b *Bot) sendTrafficChart(chatID interface{}, data *models.TrafficData, lang string) {
+	if data == nil || data.ChartBuffer == nil || data.ChartBuffer.Len() == 0 {
+		return
+	}
+
+	caption := monitor.FormatTrafficStatus(data, lang)
+	altText := "Last 24h: " + monitor.DescribeSeries(data.Trend24h)
+
+	_ = b.sendChartPhoto(chatID, b.topicFor(chatID, sectionTraffic), "iran_traffic_24h.png", data.ChartBuffer.Bytes(), caption, altText)
 }
 
 // sendASNTrafficChart sends the ASN traffic chart as a photo with caption
 // Follows the exact same pattern as sendTrafficChart for consistency
-func (b *Bot) sendASNTrafficChart(chatID interface{}, data []*models.ASTrafficData, chartBuffer *bytes.Buffer) {
+func (b *Bot) sendASNTrafficChart(chatID interface{}, data []*models.ASTrafficData, chartBuffer *bytes.Buffer, lang string) {
 	if len(data) == 0 || chartBuffer == nil || chartBuffer.Len() == 0 {
 		log.Printf("⚠️  ASN traffic chart data or buffer is empty - skipping send")
 		return
 	}
-	
+
 	// Create caption with summary - similar to FormatTrafficStatus
 	var caption strings.Builder
-	caption.WriteString(fmt.Sprintf("📊 *Top %d Iranian ASNs by Traffic*\n\n", len(data)))
-	
-	// Show top 5 ASNs in caption
-	maxShow := 5
-	if len(data) < maxShow {
-		maxShow = len(data)
-	}
-	
-	for i := 0; i < maxShow; i++ {
-		item := data[i]
-		caption.WriteString(fmt.Sprintf("%s *%s*\n   └─ %.2f%% of total traffic\n",
-			item.StatusEmoji, item.Name, item.Percentage))
-	}
-	
-	// Use same pattern as sendTrafficChart
-	fileBytes := tgbotapi.FileBytes{
-		Name:  "asn_traffic_top10.png",
-		Bytes: chartBuffer.Bytes(),
-	}
-	
-	var photo tgbotapi.PhotoConfig
-	switch id := chatID.(type) {
-	case int64:
-		photo = tgbotapi.NewPhoto(id, fileBytes)
-	case string:
-		photo = tgbotapi.NewPhotoToChannel(id, fileBytes)
-	default:
-		log.Printf("Error: invalid chatID type for ASN chart: %T", chatID)
-		return
+	locale := i18n.For(lang)
+	caption.WriteString(fmt.Sprintf("📊 *"+tr(lang, "Top %s Iranian ASNs by Traffic")+"*\n\n", locale.Int(monitor.RankedASNCount(data))))
+
+	// Show the top ASNs (asn_traffic.caption) and the pinned ones in caption
+	maxShow := b.config.ASNTrafficLimits().Caption
+	for i, item := range data {
+		if i >= maxShow && !item.Pinned {
+			continue
+		}
+		pin := ""
+		if item.Pinned {
+			pin = " 📌"
+		}
+		caption.WriteString(fmt.Sprintf("%s *%s*%s\n   └─ %s %s\n",
+			item.StatusEmoji, item.Name, pin, locale.Percent(item.Percentage, 2), tr(lang, "of total traffic")))
+		if monitor.ShareMoved(item) {
+			emoji, text := "📈", "up from %s to %s in %sh"
+			if item.Percentage < item.ShareChange.From {
+				emoji, text = "📉", "down from %s to %s in %sh"
+			}
+			caption.WriteString(fmt.Sprintf("   └─ %s "+tr(lang, text)+"\n", emoji,
+				monitor.FormatShareIn(item.ShareChange.From, locale), monitor.FormatShareIn(item.Percentage, locale), locale.Int(item.ShareChange.Hours)))
+		}
 	}
-	
-	photo.Caption = caption.String()
-	photo.ParseMode = tgbotapi.ModeMarkdown
-	
-	_, err := b.api.Send(photo)
+
+	// Use same pattern as sendTrafficChart
+	err := b.sendChartPhoto(chatID, b.topicFor(chatID, sectionTraffic), "asn_traffic_top10.png", chartBuffer.Bytes(), caption.String(),
+		monitor.DescribeASNTraffic(data))
 	if err != nil {
 		log.Printf("Error sending ASN traffic chart: %v", err)
 	} else {
@@ -881,3 +1317,171 @@ func (b *Bot) sendASNTrafficChart(chatID interface{}, data []*models.ASTrafficDa
 	}
 }
 
+// sendStatusImage sends the composite status image with the header as caption
+// Falls back to a plain text header if the photo upload fails
+func (b *Bot) sendStatusImage(chatID interface{}, caption string, image *bytes.Buffer, altText string) {
+	threadID := b.topicFor(chatID, sectionHeader)
+	if err := b.sendChartPhoto(chatID, threadID, "status.png", image.Bytes(), caption, altText); err != nil && !errors.Is(err, errSpooled) {
+		log.Printf("Error sending status image: %v - falling back to text header", err)
+		b.sendMessageToTopic(chatID, threadID, caption)
+	}
+}
+
+// sendChart sends the Iran traffic chart for the requested period
+func (b *Bot) sendChart(chatID int64, period string) {
+	if _, err := monitor.ParseChartPeriod(period); err != nil {
+		b.sendMessage(chatID, "Usage: /chart [24h|7d|30d]\nExample: /chart 7d")
+		return
+	}
+	if b.chartProvider == nil {
+		b.sendMessage(chatID, "❌ Charts are not available")
+		return
+	}
+
+	chartBuffer, altText, err := b.chartProvider(period)
+	if err != nil || chartBuffer == nil || chartBuffer.Len() == 0 {
+		log.Printf("Error generating %s chart: %v", period, err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ %s traffic chart is not available yet", period))
+		return
+	}
+
+	caption := fmt.Sprintf("📈 *Iran Internet Traffic - Last %s*", period)
+	if err := b.sendChartPhoto(chatID, 0, fmt.Sprintf("iran_traffic_%s.png", period), chartBuffer.Bytes(), caption, altText); err != nil {
+		log.Printf("Error sending %s traffic chart: %v", period, err)
+	}
+}
+
+// sendExport sends the history of a period as a JSON or CSV document
+func (b *Bot) sendExport(chatID int64, args []string) {
+	usage := "Usage: /export json|csv [24h|7d|30d]\nExample: /export csv 7d"
+	if len(args) == 0 || len(args) > 2 {
+		b.sendMessage(chatID, usage)
+		return
+	}
+	format, period := args[0], "24h"
+	if len(args) > 1 {
+		period = args[1]
+	}
+	if format != history.FormatJSON && format != history.FormatCSV {
+		b.sendMessage(chatID, usage)
+		return
+	}
+	if _, err := monitor.ParseChartPeriod(period); err != nil {
+		b.sendMessage(chatID, usage)
+		return
+	}
+	if b.exportProvider == nil {
+		b.sendMessage(chatID, "❌ Export is not available")
+		return
+	}
+
+	data, note, err := b.exportProvider(format, period)
+	if err != nil {
+		log.Printf("Error exporting %s history as %s: %v", period, format, err)
+		b.sendMessage(chatID, fmt.Sprintf("❌ Export failed: %v", err))
+		return
+	}
+
+	name := fmt.Sprintf("netblocks_%s_%s.%s", period, time.Now().UTC().Format("20060102_1504"), format)
+	caption := fmt.Sprintf("📦 *NetBlocks history - Last %s* (%s)", period, strings.ToUpper(format))
+	if note != "" {
+		caption += "\n\n🔏 " + note
+	}
+	if _, err := b.sendDocument(chatID, 0, name, data, caption); err != nil {
+		log.Printf("Error sending export to %d: %v", chatID, err)
+	}
+}
+
+// sendASNSparklines sends per-ASN 24h availability as text sparklines followed by the sparkline strip image
+func (b *Bot) sendASNSparklines(chatID int64) {
+	if b.onStatusUpdate == nil {
+		b.sendMessage(chatID, "❌ Status update function not available")
+		return
+	}
+
+	result, err := b.onStatusUpdate()
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ Error getting status: %v", err))
+		return
+	}
+
+	b.sendMessage(chatID, b.formatASNSparklines(result))
+
+	// The text sparklines above already are the text alternative of the strip image
+	if result.ASNSparklines != nil && result.ASNSparklines.Len() > 0 && !b.prefs.get(chatID).TextOnly {
+		caption := "📈 *ASN Availability - Last 24h*"
+		if _, err := b.sendPhoto(chatID, 0, "asn_sparklines_24h.png", result.ASNSparklines.Bytes(), caption); err != nil {
+			log.Printf("Error sending ASN sparklines: %v", err)
+		}
+	}
+}
+
+// formatASNSparklines formats each ASN with a Unicode sparkline of its last 24 hours
+func (b *Bot) formatASNSparklines(result *models.MonitoringResult) string {
+	var builder strings.Builder
+
+	builder.WriteString("🌐 *ASN Connectivity - Last 24h*\n")
+	builder.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+
+	asns := make([]string, 0, len(result.ASNStatuses))
+	for asn := range result.ASNStatuses {
+		asns = append(asns, asn)
+	}
+	sort.Strings(asns)
+
+	for _, asn := range asns {
+		status := result.ASNStatuses[asn]
+		icon := "🔴"
+		if status.Connected {
+			icon = "🟢"
+		}
+		asnDisplay := asn
+		if status.Name != "" {
+			asnDisplay = fmt.Sprintf("%s - %s", asn, status.Name)
+		}
+
+		uptime := "n/a"
+		sum, count := 0.0, 0
+		for _, v := range status.Uptime24h {
+			if v >= 0 {
+				sum += v
+				count++
+			}
+		}
+		if count > 0 {
+			uptime = fmt.Sprintf("%.0f%%", sum/float64(count)*100.0)
+		}
+
+		builder.WriteString(fmt.Sprintf("%s `%s`\n   └─ `%s` %s\n", icon, asnDisplay, monitor.Sparkline(status.Uptime24h), uptime))
+	}
+
+	builder.WriteString("\n_Each character is one hour, oldest first; · = no data_\n")
+	return builder.String()
+}
+
+// sendProvinceMap sends the map of the provinces with the affected ones as text alternative
+func (b *Bot) sendProvinceMap(chatID interface{}, result *models.MonitoringResult, lang string) {
+	caption := fmt.Sprintf("🗺 *%s*\n%s", tr(lang, "Connectivity by Province"),
+		tr(lang, "Share of each province's DNS servers alive; gray provinces have none"))
+	altText := monitor.DescribeProvinces(monitor.ProvinceHealths(b.config.Provinces, result))
+	if err := b.sendChartPhoto(chatID, b.topicFor(chatID, sectionHeader), "provinces.png", result.ProvinceMap.Bytes(), caption, altText); err != nil {
+		log.Printf("Error sending province map: %v", err)
+	} else {
+		log.Printf("✅ Province map sent successfully")
+	}
+}
+
+// sendUptimeChart sends the 7-day ASN/DNS availability heatmap as a photo with caption
+func (b *Bot) sendUptimeChart(chatID interface{}, result *models.MonitoringResult, lang string) {
+	if result.UptimeChart == nil || result.UptimeChart.Len() == 0 {
+		return
+	}
+
+	caption := fmt.Sprintf("🗓 *%s*\n%s", tr(lang, "ASN / DNS Availability - Last 7 Days"),
+		tr(lang, "Each row is an ASN or a city's DNS servers, each column one hour (UTC)"))
+	if err := b.sendChartPhoto(chatID, b.topicFor(chatID, sectionHeader), "uptime_heatmap_7d.png", result.UptimeChart.Bytes(), caption, result.UptimeSummary); err != nil {
+		log.Printf("Error sending uptime heatmap: %v", err)
+	} else {
+		log.Printf("✅ Uptime heatmap sent successfully")
+	}
+}
@@ -0,0 +1,79 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// SetCampaignHandlers sets the functions behind /campaign: start and stop a
+// measurement campaign, return the running one, and the configured profiles
+func (b *Bot) SetCampaignHandlers(start func(name string, duration time.Duration, by, reason string) (*models.Campaign, error), stop func(by string) (*models.Campaign, error), running func() *models.Campaign, profiles []string) {
+	b.campaignStart = start
+	b.campaignStop = stop
+	b.campaign = running
+	b.campaignProfiles = profiles
+}
+
+// handleCampaign handles /campaign (show the running campaign),
+// /campaign start <name> [duration] [reason] and /campaign stop; administrators only
+func (b *Bot) handleCampaign(chatID int64, userID int64, author string, args []string) {
+	if !b.isAdmin(userID) {
+		b.sendMessage(chatID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	if b.campaignStart == nil || len(b.campaignProfiles) == 0 {
+		b.sendMessage(chatID, "❌ No measurement campaigns are configured (see campaigns in the config)")
+		return
+	}
+
+	switch {
+	case len(args) == 0:
+		var builder strings.Builder
+		if active := b.campaign(); active != nil {
+			fmt.Fprintf(&builder, "📈 *Campaign %s* running until %s (%s", escapeMarkdown(active.Name),
+				active.Ends.In(b.location).Format("Jan 2 15:04 MST"), active.Trigger)
+			if active.StartedBy != "" {
+				fmt.Fprintf(&builder, ", %s", escapeMarkdown(active.StartedBy))
+			}
+			builder.WriteString(")\n")
+			if active.Reason != "" {
+				fmt.Fprintf(&builder, "%s\n", escapeMarkdown(active.Reason))
+			}
+		} else {
+			builder.WriteString("No measurement campaign is running.\n")
+		}
+		fmt.Fprintf(&builder, "\nCampaigns: %s\nUsage: /campaign start <name> [duration] [reason], /campaign stop\nExample: /campaign start %s 12h Election day",
+			escapeMarkdown(strings.Join(b.campaignProfiles, ", ")), escapeMarkdown(b.campaignProfiles[0]))
+		b.sendMessage(chatID, builder.String())
+
+	case strings.EqualFold(args[0], "start") && len(args) > 1:
+		name, rest := args[1], args[2:]
+		var duration time.Duration
+		if len(rest) > 0 {
+			if d, err := time.ParseDuration(rest[0]); err == nil {
+				duration, rest = d, rest[1:]
+			}
+		}
+		campaign, err := b.campaignStart(name, duration, author, strings.Join(rest, " "))
+		if err != nil {
+			b.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+		b.sendMessage(chatID, fmt.Sprintf("📈 Campaign %s started, running until %s.", escapeMarkdown(campaign.Name),
+			campaign.Ends.In(b.location).Format("Jan 2 15:04 MST")))
+
+	case strings.EqualFold(args[0], "stop"):
+		campaign, err := b.campaignStop(author)
+		if err != nil {
+			b.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+		b.sendMessage(chatID, fmt.Sprintf("📉 Campaign %s stopped.", escapeMarkdown(campaign.Name)))
+
+	default:
+		b.sendMessage(chatID, "Usage: /campaign start <name> [duration] [reason], /campaign stop")
+	}
+}
@@ -0,0 +1,262 @@
+package telegram
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// Channel content profiles
+const (
+	profileFull   = "full"   // Header, ASN, DNS, charts and heatmap
+	profileAlerts = "alerts" // Change alerts only, no periodic status posts
+	profileCharts = "charts" // Status image and charts, no long text sections
+	profilePlain  = "plain"  // Short sentences without emoji or charts, for screen readers
+)
+
+// What a channel gets when the status did not change since its last full post
+const (
+	unchangedPost    = "post"    // The full status again
+	unchangedSkip    = "skip"    // Nothing
+	unchangedCompact = "compact" // A one-line "no change" note
+)
+
+// defaultChannelInterval is how often status posts go to a channel without an interval
+const defaultChannelInterval = 19 * time.Minute
+
+// channelTarget is a configured channel with its profile and posting state
+type channelTarget struct {
+	id        string
+	chatID    int64 // Numeric chat ID resolved at startup (0 if unresolved)
+	profile   string
+	lang      string
+	interval  time.Duration
+	schedule  *cronSchedule // Replaces the interval when set
+	unchanged string        // What to post when the status did not change (unchanged* constants)
+	topics    map[string]int
+	lastPost  time.Time // Zero until the first post so channels get an immediate update
+	nextPost  time.Time // Next scheduled post (schedule only)
+	lastFull  time.Time // Last full status post
+	lastHash  string    // Fingerprint of the status in the last full post
+}
+
+// due reports whether a status post is due; a post held back by a rate limit
+// stays due until it went out
+func (ch *channelTarget) due(now time.Time) bool {
+	if ch.lastPost.IsZero() {
+		return true
+	}
+	if ch.schedule != nil {
+		return !ch.nextPost.IsZero() && !now.Before(ch.nextPost)
+	}
+	return now.Sub(ch.lastPost) >= ch.interval
+}
+
+// posted records a completed status post
+func (ch *channelTarget) posted(now time.Time) {
+	ch.lastPost = now
+	if ch.schedule != nil {
+		ch.nextPost = ch.schedule.next(now)
+	}
+}
+
+// describeSchedule describes when the channel gets status posts
+func (ch *channelTarget) describeSchedule() string {
+	if ch.schedule != nil {
+		return "schedule " + ch.schedule.expr
+	}
+	return "every " + ch.interval.String()
+}
+
+// loadChannels builds the channel list from telegram_channel (full profile,
+// telegram_topics) and telegram_channels, skipping invalid or duplicate entries
+// Schedules are evaluated in location
+func loadChannels(cfg *config.Config, location *time.Location) []*channelTarget {
+	entries := cfg.TelegramChannels
+	if cfg.TelegramChannel != "" {
+		legacy := config.ChannelConfig{ID: cfg.TelegramChannel, Profile: profileFull, Topics: cfg.TelegramTopics,
+			Interval: cfg.TelegramChannelInterval, Schedule: cfg.TelegramChannelSchedule, Unchanged: cfg.TelegramChannelUnchanged}
+		entries = append([]config.ChannelConfig{legacy}, entries...)
+	}
+
+	var channels []*channelTarget
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		id := normalizeChannelID(entry.ID)
+		if id == "" || seen[id] {
+			continue
+		}
+
+		profile := strings.ToLower(entry.Profile)
+		switch profile {
+		case "":
+			profile = profileFull
+		case profileFull, profileAlerts, profileCharts, profilePlain:
+		default:
+			log.Printf("⚠️  Unknown profile %q for channel %s - skipping", entry.Profile, id)
+			continue
+		}
+
+		interval := defaultChannelInterval
+		if entry.Interval != "" {
+			parsed, err := time.ParseDuration(entry.Interval)
+			if err != nil || parsed <= 0 {
+				log.Printf("⚠️  Invalid interval %q for channel %s - using %v", entry.Interval, id, defaultChannelInterval)
+			} else {
+				interval = parsed
+			}
+		}
+
+		var schedule *cronSchedule
+		if entry.Schedule != "" {
+			parsed, err := parseSchedule(entry.Schedule, location)
+			if err != nil {
+				log.Printf("⚠️  Invalid schedule for channel %s - using interval %v: %v", id, interval, err)
+			} else {
+				schedule = parsed
+			}
+		}
+
+		unchanged := strings.ToLower(entry.Unchanged)
+		switch unchanged {
+		case "":
+			unchanged = unchangedPost
+		case unchangedPost, unchangedSkip, unchangedCompact:
+		default:
+			log.Printf("⚠️  Unknown unchanged mode %q for channel %s - posting the full status", entry.Unchanged, id)
+			unchanged = unchangedPost
+		}
+
+		language := entry.Language
+		if language == "" {
+			language = cfg.Language
+		}
+
+		seen[id] = true
+		ch := &channelTarget{
+			id:        id,
+			profile:   profile,
+			lang:      normalizeLang(language),
+			interval:  interval,
+			schedule:  schedule,
+			unchanged: unchanged,
+			topics:    entry.Topics,
+		}
+		channels = append(channels, ch)
+		log.Printf("📢 Channel configured: %s (profile: %s, language: %s, %s)", id, profile, ch.lang, ch.describeSchedule())
+	}
+	return channels
+}
+
+// normalizeChannelID converts t.me/name and bare names to @name; numeric IDs are kept
+func normalizeChannelID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return ""
+	}
+	// Handle t.me/channelname format -> @channelname
+	if strings.HasPrefix(id, "t.me/") {
+		id = "@" + strings.TrimPrefix(id, "t.me/")
+	}
+	// If it doesn't start with @ or - (negative chat ID), assume it's a username
+	if !strings.HasPrefix(id, "@") && !strings.HasPrefix(id, "-") {
+		id = "@" + id
+	}
+	return id
+}
+
+// resolveChat returns the numeric chat ID resolved for a configured channel, so
+// sends skip Telegram's username lookup; other IDs are returned unchanged
+func (b *Bot) resolveChat(id string) string {
+	if ch := b.channel(id); ch != nil && ch.chatID != 0 {
+		return strconv.FormatInt(ch.chatID, 10)
+	}
+	return id
+}
+
+// channel returns the configured channel with the given ID, or nil
+func (b *Bot) channel(id string) *channelTarget {
+	for _, ch := range b.channels {
+		if ch.id == id {
+			return ch
+		}
+	}
+	return nil
+}
+
+// postChannelStatus sends a due status post to a channel; when nothing changed
+// since its last full post, the channel's unchanged mode may skip it or send a
+// compact note instead. It reports false if a flood wait cut the post short
+func (b *Bot) postChannelStatus(ch *channelTarget, result *models.MonitoringResult) bool {
+	now := b.clock.Now()
+	fingerprint := statusFingerprint(result)
+	unchanged := ch.unchanged != unchangedPost && !ch.lastFull.IsZero() && fingerprint == ch.lastHash
+
+	switch {
+	case unchanged && ch.unchanged == unchangedSkip:
+		log.Printf("⏭  Status unchanged since %s - skipping post to %s", ch.lastFull.Format("15:04"), ch.id)
+		return true
+	case unchanged:
+		status, emoji := monitor.ScoreStatus(result.NationalScore)
+		locale := i18n.For(ch.lang)
+		note := fmt.Sprintf("%s *"+tr(ch.lang, "No change in the last %s")+"*\n%s: %s/%s (%s) `%s`", emoji,
+			locale.Digits(formatElapsed(now.Sub(ch.lastFull))), tr(ch.lang, "National Score"), locale.Number(result.NationalScore, 0),
+			locale.Int(100), tr(ch.lang, status), locale.Time(result.Timestamp, "15:04"))
+		b.sendMessageToTopic(ch.id, b.topicFor(ch.id, sectionHeader), note)
+	default:
+		b.sendStatusPost(ch.id, result, ch.profile, ch.lang)
+	}
+
+	if until := b.limiter.backoffUntil(ch.id); until.After(time.Now()) {
+		return false
+	}
+	if !unchanged {
+		ch.lastFull, ch.lastHash = now, fingerprint
+	}
+	return true
+}
+
+// statusFingerprint hashes the state shown in a status post (which targets are up,
+// the national score and traffic status) while ignoring what changes every cycle,
+// such as timestamps, latencies and the exact traffic level
+func statusFingerprint(result *models.MonitoringResult) string {
+	var lines []string
+	for asn, status := range result.ASNStatuses {
+		lines = append(lines, fmt.Sprintf("asn %s %t", asn, status.Connected))
+	}
+	for key, status := range result.DNSStatuses {
+		lines = append(lines, fmt.Sprintf("dns %s %t", key, status.Alive))
+	}
+	sort.Strings(lines)
+	status, _ := monitor.ScoreStatus(result.NationalScore)
+	lines = append(lines, "score "+status)
+	if result.TrafficData != nil {
+		lines = append(lines, "traffic "+result.TrafficData.Status)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// formatElapsed formats a duration as "45m", "2h" or "1h 20m"
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Minute)
+	hours, minutes := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+}
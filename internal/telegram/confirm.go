@@ -0,0 +1,212 @@
+package telegram
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Confidence of a detected disruption (confirm_incidents)
+const (
+	confidenceSuspected = "suspected"
+	confidenceConfirmed = "confirmed"
+	confidenceDenied    = "denied"
+)
+
+// confirmPrefix is the callback data prefix of the confirmation buttons
+// ("confirm:<confidence>:<ID>")
+const confirmPrefix = "confirm:"
+
+// confirmationTTL is how long a detection can be confirmed or denied
+const confirmationTTL = 24 * time.Hour
+
+// confirmation is a critical alert published as a possible disruption,
+// waiting for an administrator to confirm or deny it
+type confirmation struct {
+	id         string
+	events     []models.Event
+	detected   time.Time
+	confidence string
+	by         string        // Who confirmed or denied it
+	targets    []alertTarget // Where the alert was posted, for the follow-up
+}
+
+// alertTarget is a chat (and forum topic) a critical alert was posted to
+type alertTarget struct {
+	chat   interface{}
+	thread int
+	lang   string
+}
+
+// confirmations holds the detections of the last confirmationTTL
+type confirmations struct {
+	mu   sync.Mutex
+	open map[string]*confirmation
+}
+
+func newConfirmations() *confirmations {
+	return &confirmations{open: make(map[string]*confirmation)}
+}
+
+// add opens a suspected detection of events
+func (c *confirmations) add(events []models.Event, now time.Time) *confirmation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, pending := range c.open {
+		if now.Sub(pending.detected) > confirmationTTL {
+			delete(c.open, id)
+		}
+	}
+	pending := &confirmation{id: confirmationID(), events: events, detected: now, confidence: confidenceSuspected}
+	c.open[pending.id] = pending
+	return pending
+}
+
+// target records a chat the alert of a detection was posted to
+func (c *confirmations) target(pending *confirmation, target alertTarget) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending.targets = append(pending.targets, target)
+}
+
+// resolve settles a suspected detection and returns it with the chats to update
+func (c *confirmations) resolve(id, confidence, by string) (confirmation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending, ok := c.open[id]
+	if !ok {
+		return confirmation{}, fmt.Errorf("unknown or expired detection %s", id)
+	}
+	if pending.confidence != confidenceSuspected {
+		return confirmation{}, fmt.Errorf("detection %s was already %s by %s", id, pending.confidence, pending.by)
+	}
+	pending.confidence, pending.by = confidence, by
+	settled := *pending
+	settled.targets = append([]alertTarget(nil), pending.targets...)
+	return settled, nil
+}
+
+func confirmationID() string {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%06x", time.Now().UnixNano()&0xffffff)
+	}
+	return hex.EncodeToString(b)
+}
+
+// openConfirmation starts the confirmation of the unresolved critical events,
+// or returns nil if confirm_incidents is off or all events are resolutions
+func (b *Bot) openConfirmation(critical []models.Event) *confirmation {
+	if b.confirms == nil {
+		return nil
+	}
+	var detected []models.Event
+	for _, event := range critical {
+		if !event.Resolved {
+			detected = append(detected, event)
+		}
+	}
+	if len(detected) == 0 {
+		return nil
+	}
+	return b.confirms.add(detected, b.clock.Now())
+}
+
+// criticalAlert formats the critical alert of a chat; with a pending
+// confirmation it is phrased as a possible disruption and the chat is
+// recorded for the follow-up
+func (b *Bot) criticalAlert(pending *confirmation, chat interface{}, thread int, lang string, events []models.Event) string {
+	if pending == nil {
+		return formatAlerts(fmt.Sprintf("🚨 *%s*", tr(lang, "Critical Network Alert")), events)
+	}
+	b.confirms.target(pending, alertTarget{chat: chat, thread: thread, lang: lang})
+	text := formatAlerts(fmt.Sprintf("⚠️ *%s*", tr(lang, "Possible Network Disruption")), events)
+	return text + "\n🟡 _" + fmt.Sprintf(tr(lang, "Unconfirmed: detected automatically, pending verification (ref %s)"), pending.id) + "_"
+}
+
+// askConfirmation sends the administrators a detection with confirm and deny buttons
+func (b *Bot) askConfirmation(pending *confirmation) {
+	markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("✅ Confirm", confirmPrefix+confidenceConfirmed+":"+pending.id),
+		tgbotapi.NewInlineKeyboardButtonData("❌ Deny", confirmPrefix+confidenceDenied+":"+pending.id),
+	))
+	text := formatAlerts(fmt.Sprintf("🔎 *Confirm detection* `%s`", pending.id), pending.events) +
+		"\nPublished as a possible disruption. Confirm it once verified (local sources, other observatories), or deny it; the chats that got the alert are updated either way."
+	for _, adminID := range b.config.TelegramAdmins {
+		if _, err := b.sendKeyboard(adminID, text, markup); err != nil {
+			log.Printf("Error asking admin %d to confirm detection %s: %v", adminID, pending.id, err)
+		}
+	}
+}
+
+// handleConfirmCallback settles the detection of a pressed button (administrators only)
+func (b *Bot) handleConfirmCallback(query *tgbotapi.CallbackQuery) {
+	if !b.isAdmin(query.From.ID) {
+		b.answerCallback(query.ID, "Only bot administrators can confirm detections")
+		return
+	}
+	confidence, id, _ := strings.Cut(strings.TrimPrefix(query.Data, confirmPrefix), ":")
+	settled, err := b.settle(id, confidence, query.From)
+	if err != nil {
+		b.answerCallback(query.ID, "❌ "+err.Error())
+		return
+	}
+	b.answerCallback(query.ID, fmt.Sprintf("Detection %s %s - %d chat(s) updated", id, settled.confidence, len(settled.targets)))
+}
+
+// handleConfirm handles /confirm <ID> and /deny <ID> (administrators only)
+func (b *Bot) handleConfirm(msg *tgbotapi.Message, confidence string, args []string) {
+	if !b.isAdmin(senderID(msg)) {
+		b.sendMessage(msg.Chat.ID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	if len(args) != 1 {
+		b.sendMessage(msg.Chat.ID, "Usage: /confirm <ref> or /deny <ref>\nThe ref is shown on possible disruption alerts.")
+		return
+	}
+	settled, err := b.settle(args[0], confidence, msg.From)
+	if err != nil {
+		b.sendMessage(msg.Chat.ID, "❌ "+err.Error())
+		return
+	}
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Detection `%s` %s - %d chat(s) updated.", settled.id, settled.confidence, len(settled.targets)))
+}
+
+// settle confirms or denies a detection and posts the follow-up to every chat
+// that got its alert
+func (b *Bot) settle(id, confidence string, from *tgbotapi.User) (confirmation, error) {
+	if b.confirms == nil {
+		return confirmation{}, fmt.Errorf("confirm_incidents is not enabled")
+	}
+	if confidence != confidenceConfirmed && confidence != confidenceDenied {
+		return confirmation{}, fmt.Errorf("unknown confidence %q", confidence)
+	}
+	settled, err := b.confirms.resolve(id, confidence, actor(from))
+	if err != nil {
+		return confirmation{}, err
+	}
+	log.Printf("🔎 Detection %s %s by %s", id, confidence, settled.by)
+
+	go func() {
+		for _, target := range settled.targets {
+			reported := i18n.For(target.lang).Time(settled.detected.In(b.location), "15:04")
+			var text string
+			if confidence == confidenceConfirmed {
+				text = formatAlerts(fmt.Sprintf("🚨 *%s*", tr(target.lang, "Confirmed Network Disruption")), settled.events) +
+					"\n✅ " + fmt.Sprintf(tr(target.lang, "The disruption reported at %s has been confirmed (ref %s)"), reported, settled.id)
+			} else {
+				text = "ℹ️ " + fmt.Sprintf(tr(target.lang, "Update: the possible disruption reported at %s could not be confirmed (ref %s)"), reported, settled.id)
+			}
+			b.sendMessageToTopic(target.chat, target.thread, text)
+		}
+	}()
+	return settled, nil
+}
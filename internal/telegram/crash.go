@@ -0,0 +1,49 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/crash"
+)
+
+// maxCrashStack caps the stack trace in a crash message; the full trace is in the report file
+const maxCrashStack = 2500
+
+// ReportCrash sends a recovered panic to the admins (see crash.SetNotifier)
+func (b *Bot) ReportCrash(report crash.Report, suppressed int) {
+	if len(b.config.TelegramAdmins) == 0 {
+		return
+	}
+	text := formatCrash(report, suppressed)
+	for _, adminID := range b.config.TelegramAdmins {
+		if _, err := b.sendText(adminID, 0, text); err != nil {
+			log.Printf("Error sending crash report to admin %d: %v", adminID, err)
+		}
+	}
+}
+
+// formatCrash formats a crash report with the top of its stack trace
+func formatCrash(report crash.Report, suppressed int) string {
+	// Panic values and traces go in code spans and blocks to keep the Markdown valid
+	clean := func(s string) string { return strings.ReplaceAll(s, "`", "'") }
+	stack := report.Stack
+	if len(stack) > maxCrashStack {
+		stack = stack[:maxCrashStack] + "\n…"
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("💥 *Crash in %s*\n\n", clean(report.Goroutine)))
+	builder.WriteString(fmt.Sprintf("Panic: `%s`\n", clean(report.Panic)))
+	builder.WriteString(fmt.Sprintf("Build: `%s`\n", report.Build))
+	builder.WriteString(fmt.Sprintf("Time: `%s`\n", report.Time.Format("2006-01-02 15:04:05")))
+	if report.Path != "" {
+		builder.WriteString(fmt.Sprintf("Report: `%s`\n", clean(report.Path)))
+	}
+	if suppressed > 0 {
+		builder.WriteString(fmt.Sprintf("➕ %d further crash(es) here since the last report\n", suppressed))
+	}
+	builder.WriteString("\n```\n" + clean(stack) + "\n```")
+	return builder.String()
+}
@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/i18n"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// digestSchedule parses digest_schedule in location (nil if it is not set or invalid)
+func digestSchedule(cfg *config.Config, location *time.Location) *cronSchedule {
+	if cfg.DigestSchedule == "" {
+		return nil
+	}
+	schedule, err := parseSchedule(cfg.DigestSchedule, location)
+	if err != nil {
+		log.Printf("⚠️  Invalid digest_schedule - digest disabled: %v", err)
+		return nil
+	}
+	log.Printf("📰 Digest scheduled %s", schedule.expr)
+	return schedule
+}
+
+// digestDue reports whether the next digest is due, scheduling the first one
+// from now on so a restart does not post one right away
+func (b *Bot) digestDue(now time.Time) bool {
+	if b.digest == nil {
+		return false
+	}
+	if b.nextDigest.IsZero() {
+		b.nextDigest = b.digest.next(now)
+		return false
+	}
+	return !b.nextDigest.IsZero() && !now.Before(b.nextDigest)
+}
+
+// postDigest sends the digest to every channel receiving status posts
+func (b *Bot) postDigest(result *models.MonitoringResult) {
+	b.nextDigest = b.digest.next(b.clock.Now())
+	for _, ch := range b.channels {
+		if ch.profile == profileAlerts {
+			continue
+		}
+		log.Printf("📰 Sending digest to channel: %s", ch.id)
+		threadID := b.topicFor(ch.id, sectionHeader)
+		b.sendMessageToTopic(ch.id, threadID, formatDigest(result, b.config.CountryName, ch.lang))
+		if result.ASNSparklines != nil && result.ASNSparklines.Len() > 0 {
+			caption := fmt.Sprintf("📈 *%s*", tr(ch.lang, "ASN Availability - Last 24h"))
+			if _, err := b.sendPhoto(ch.id, threadID, "asn_sparklines_24h.png", result.ASNSparklines.Bytes(), caption); err != nil {
+				log.Printf("Error sending ASN sparklines with the digest: %v", err)
+			}
+		}
+	}
+}
+
+// formatDigest writes the digest: the week's availability and the SLO report
+func formatDigest(result *models.MonitoringResult, country, lang string) string {
+	locale := i18n.For(lang)
+	var text strings.Builder
+	fmt.Fprintf(&text, "📰 *%s* - %s\n", tr(lang, "Digest"), escapeMarkdown(country))
+	fmt.Fprintf(&text, "%s: %s\n", tr(lang, "Last Update"), locale.Time(result.Timestamp, "2006-01-02 15:04"))
+	if result.UptimeSummary != "" {
+		fmt.Fprintf(&text, "\n%s\n", escapeMarkdown(result.UptimeSummary))
+	}
+	if len(result.SLOs) > 0 {
+		fmt.Fprintf(&text, "\n*%s*\n", tr(lang, "SLO Report"))
+		for _, status := range result.SLOs {
+			text.WriteString(formatSLOStatus(status, lang))
+			text.WriteString("\n")
+		}
+	}
+	return text.String()
+}
+
+// formatSLOStatus writes one line of the SLO report, e.g.
+// "❌ Shatel recursive: availability 98.2% (objective 99%), 7d, 4 DNS servers"
+func formatSLOStatus(status models.SLOStatus, lang string) string {
+	locale := i18n.For(lang)
+	emoji := "✅"
+	switch {
+	case status.Pending:
+		emoji = "⏳"
+	case len(status.Breached) > 0:
+		emoji = "❌"
+	}
+	line := fmt.Sprintf("%s %s: ", emoji, escapeMarkdown(status.Name))
+	if status.Pending {
+		return line + tr(lang, "not enough checks yet")
+	}
+
+	var parts []string
+	if status.AvailabilityObjective > 0 {
+		parts = append(parts, fmt.Sprintf("%s %s (%s %s)", tr(lang, "availability"), locale.Percent(status.Availability, 2),
+			tr(lang, "objective"), locale.Percent(status.AvailabilityObjective, 1)))
+	}
+	if status.LatencyObjective > 0 {
+		parts = append(parts, fmt.Sprintf("%s %sms (%s %sms)", tr(lang, "latency"), locale.Number(status.LatencyMS, 0),
+			tr(lang, "objective"), locale.Int(status.LatencyObjective)))
+	}
+	targets := tr(lang, "DNS servers")
+	if status.Target == config.SLOTargetASN {
+		targets = "ASNs"
+	}
+	return line + fmt.Sprintf("%s, %s, %s %s", strings.Join(parts, ", "), locale.Digits(status.Window), locale.Int(status.Targets), targets)
+}
@@ -0,0 +1,35 @@
+package telegram
+
+import (
+	"log"
+	"time"
+)
+
+// drainPoll is how often Drain checks whether the sends in progress finished
+const drainPoll = 50 * time.Millisecond
+
+// Drain sends the batched alerts right away and waits up to timeout for all
+// sends in progress (status posts, alerts, replies) to finish, and reports
+// whether they did. Call it after cancelling the context of Start
+// Batches of chats in quiet hours and of channels in a flood wait stay unsent
+func (b *Bot) Drain(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	done := make(chan struct{})
+	go func() {
+		b.flushPendingAlerts()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+
+	for b.limiter.pending() > 0 {
+		if time.Now().After(deadline) {
+			log.Printf("⚠️  %d Telegram send(s) still in progress after %v, giving up", b.limiter.pending(), timeout)
+			return false
+		}
+		time.Sleep(drainPoll)
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"log"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandText returns the message text with any "@botname" suffix removed from
+// the command, and whether the message is a command meant for this bot
+// Commands without a mention count as addressed; non-commands never do
+func (b *Bot) commandText(msg *tgbotapi.Message) (string, bool) {
+	text := strings.TrimSpace(msg.Text)
+	if !msg.IsCommand() {
+		return text, false
+	}
+
+	withAt := msg.CommandWithAt()
+	if i := strings.Index(withAt, "@"); i != -1 {
+		if !strings.EqualFold(withAt[i+1:], b.api.Self.UserName) {
+			return text, false
+		}
+	}
+
+	text = "/" + msg.Command()
+	if args := strings.TrimSpace(msg.CommandArguments()); args != "" {
+		text += " " + args
+	}
+	return text, true
+}
+
+// removeSubscribedChat stops periodic pushes to a chat and drops its queued changes
+func (b *Bot) removeSubscribedChat(chatID int64) {
+	b.chatsMu.Lock()
+	delete(b.subscribedChats, chatID)
+	if b.sharedState != nil {
+		if err := b.sharedState.RemoveSubscriber(chatID); err != nil {
+			log.Printf("⚠️  Failed to remove subscription of chat %d: %v", chatID, err)
+		}
+	}
+	b.chatsMu.Unlock()
+
+	b.alertsMu.Lock()
+	delete(b.pendingAlerts, chatID)
+	b.alertsMu.Unlock()
+}
+
+// isChatAdmin reports whether a user is an administrator or the creator of a chat
+func (b *Bot) isChatAdmin(chatID int64, userID int64) bool {
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chatID, UserID: userID},
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to check admin status of user %d in chat %d: %v", userID, chatID, err)
+		return false
+	}
+	return member.IsAdministrator() || member.IsCreator()
+}
+
+// handleGroupSubscription handles /subscribe and /unsubscribe in group chats
+// Only group admins can opt a group in or out of periodic pushes
+func (b *Bot) handleGroupSubscription(msg *tgbotapi.Message, subscribe bool) {
+	chatID := msg.Chat.ID
+	if !msg.Chat.IsGroup() && !msg.Chat.IsSuperGroup() {
+		b.sendMessage(chatID, "ℹ️ Private chats receive periodic updates automatically. Use /quiet to pause them at night.")
+		return
+	}
+
+	userID := senderID(msg)
+	if userID == 0 || !b.isChatAdmin(chatID, userID) {
+		b.sendMessage(chatID, "❌ Only group administrators can change periodic updates for this group.")
+		return
+	}
+
+	if subscribe {
+		b.addSubscribedChat(chatID)
+		log.Printf("✅ Group %d subscribed to periodic updates by user %d", chatID, userID)
+		b.sendMessage(chatID, "✅ This group will now receive periodic status updates and alerts. Use /unsubscribe to stop.")
+		return
+	}
+
+	b.removeSubscribedChat(chatID)
+	log.Printf("🔕 Group %d unsubscribed from periodic updates by user %d", chatID, userID)
+	b.sendMessage(chatID, "🔕 This group will no longer receive periodic updates. Commands like /status still work.")
+}
@@ -0,0 +1,19 @@
+package telegram
+
+import "github.com/netblocks/netblocks/internal/i18n"
+
+// Supported post languages
+const (
+	langEnglish = i18n.English
+	langPersian = i18n.Persian
+)
+
+// normalizeLang maps a configured language to a supported one, defaulting to English
+func normalizeLang(lang string) string {
+	return i18n.Normalize(lang)
+}
+
+// tr returns the translation of an English heading, or the heading itself
+func tr(lang, text string) string {
+	return i18n.T(lang, text)
+}
@@ -0,0 +1,160 @@
+package telegram
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Telegram's photo limits and the defaults of telegram_images
+const (
+	maxPhotoBytes       = 10 << 20 // Larger photos are rejected
+	maxPhotoSides       = 10000    // Width plus height
+	maxPhotoAspect      = 20       // Longest side over shortest side
+	defaultMaxDimension = 2560     // Telegram keeps no more than this
+	defaultTargetKB     = 1024
+	minDimension        = 640 // Photos are not downscaled below this to meet the target size
+	maxResizeAttempts   = 4
+)
+
+// imageSettings returns telegram_images with defaults for unset fields
+func (b *Bot) imageSettings() config.TelegramImages {
+	settings := config.TelegramImages{MaxDimension: defaultMaxDimension, TargetKB: defaultTargetKB, Compression: config.CompressionBest}
+	if custom := b.config.TelegramImages; custom != nil {
+		if custom.MaxDimension > 0 {
+			settings.MaxDimension = custom.MaxDimension
+		}
+		if custom.TargetKB > 0 {
+			settings.TargetKB = custom.TargetKB
+		}
+		if custom.Compression != "" {
+			settings.Compression = custom.Compression
+		}
+	}
+	return settings
+}
+
+// preparePhoto fits a PNG to the photo settings: images within them are sent
+// as they are, larger ones are recompressed and downscaled toward the target
+// size. asDocument reports images Telegram would reject as photos, sent
+// unchanged as documents instead
+func (b *Bot) preparePhoto(name string, data []byte) (prepared []byte, asDocument bool) {
+	bounds, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != "png" {
+		return data, len(data) > maxPhotoBytes
+	}
+	if !photoShape(bounds.Width, bounds.Height) {
+		log.Printf("🖼  %s is %dx%d, beyond Telegram's photo dimensions - sending it as a document", name, bounds.Width, bounds.Height)
+		return data, true
+	}
+
+	settings := b.imageSettings()
+	target := settings.TargetKB << 10
+	longest := max(bounds.Width, bounds.Height)
+	if longest <= settings.MaxDimension && len(data) <= target {
+		return data, false
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, len(data) > maxPhotoBytes
+	}
+	encoder := png.Encoder{CompressionLevel: compressionLevel(settings.Compression)}
+	scale := math.Min(1, float64(settings.MaxDimension)/float64(longest))
+	prepared = data
+	for attempt := 0; attempt < maxResizeAttempts; attempt++ {
+		resized := img
+		if scale < 1 {
+			resized = downscale(img, scale)
+		}
+		var buffer bytes.Buffer
+		if err := encoder.Encode(&buffer, resized); err != nil {
+			break
+		}
+		if buffer.Len() < len(prepared) || scale < 1 {
+			prepared = buffer.Bytes()
+		}
+		// Areas shrink with the square of the scale, PNG sizes about alike
+		if len(prepared) <= target || float64(longest)*scale <= minDimension {
+			break
+		}
+		scale = math.Max(scale*math.Sqrt(float64(target)/float64(len(prepared)))*0.95, minDimension/float64(longest))
+	}
+	if len(prepared) != len(data) {
+		log.Printf("🖼  %s recompressed from %d KB to %d KB", name, len(data)>>10, len(prepared)>>10)
+	}
+	if len(prepared) > maxPhotoBytes {
+		log.Printf("🖼  %s is still %d KB, beyond Telegram's photo size - sending it as a document", name, len(prepared)>>10)
+		return data, true
+	}
+	return prepared, false
+}
+
+// photoShape reports whether Telegram accepts an image of these dimensions as a photo
+func photoShape(width, height int) bool {
+	if width <= 0 || height <= 0 || width+height > maxPhotoSides {
+		return false
+	}
+	return max(width, height) <= maxPhotoAspect*min(width, height)
+}
+
+// compressionLevel maps telegram_images.compression to a PNG compression level
+func compressionLevel(compression string) png.CompressionLevel {
+	switch compression {
+	case config.CompressionDefault:
+		return png.DefaultCompression
+	case config.CompressionSpeed:
+		return png.BestSpeed
+	}
+	return png.BestCompression
+}
+
+// downscale shrinks an image by scale (< 1), averaging the source pixels
+// each target pixel covers so chart lines and text stay legible
+func downscale(src image.Image, scale float64) *image.RGBA {
+	bounds := src.Bounds()
+	width := max(1, int(float64(bounds.Dx())*scale))
+	height := max(1, int(float64(bounds.Dy())*scale))
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n >> 8)
+			dst.Pix[offset+1] = uint8(g / n >> 8)
+			dst.Pix[offset+2] = uint8(b / n >> 8)
+			dst.Pix[offset+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+// photoRejected reports whether Telegram refused an upload as a photo, so it
+// may go through as a document
+func photoRejected(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	for _, reason := range []string{"PHOTO_INVALID_DIMENSIONS", "PHOTO_SAVE_FILE_INVALID", "IMAGE_PROCESS_FAILED", "too big", "Too Large"} {
+		if strings.Contains(message, reason) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/escalation"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// ackPrefix is the callback data prefix of incident acknowledgement buttons ("ack:<incident ID>")
+const ackPrefix = "ack:"
+
+// SetIncidentHandlers enables /incidents, /ack and the acknowledge buttons of on-call alerts
+func (b *Bot) SetIncidentHandlers(list func() ([]escalation.Incident, error), ack func(id, by string) error) {
+	b.incidentsProvider = list
+	b.ackHandler = ack
+}
+
+// NotifyAdmins sends events to every telegram_admins user by direct message,
+// with an acknowledge button per incident; it backs the telegram_admins action
+func (b *Bot) NotifyAdmins(events []models.Event) {
+	if len(b.config.TelegramAdmins) == 0 {
+		log.Printf("⚠️  No telegram_admins configured; dropping %d on-call event(s)", len(events))
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	seen := make(map[string]bool)
+	for _, event := range events {
+		if event.Incident == "" || event.Resolved || seen[event.Incident] || b.ackHandler == nil {
+			continue
+		}
+		seen[event.Incident] = true
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Acknowledge "+event.Incident, ackPrefix+event.Incident)))
+	}
+
+	text := formatAlerts("📟 *On-call Alert*", events)
+	for _, adminID := range b.config.TelegramAdmins {
+		var err error
+		if len(rows) > 0 {
+			_, err = b.sendKeyboard(adminID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+		} else {
+			_, err = b.sendText(adminID, 0, text)
+		}
+		if err != nil {
+			log.Printf("Error notifying admin %d: %v", adminID, err)
+		}
+	}
+}
+
+// handleAckCallback acknowledges the incident of a pressed button (administrators only)
+func (b *Bot) handleAckCallback(query *tgbotapi.CallbackQuery) {
+	if !b.isAdmin(query.From.ID) {
+		b.answerCallback(query.ID, "Only bot administrators can acknowledge incidents")
+		return
+	}
+	id := strings.TrimPrefix(query.Data, ackPrefix)
+	if err := b.acknowledge(id, query.From); err != nil {
+		b.answerCallback(query.ID, "❌ "+err.Error())
+		return
+	}
+	b.answerCallback(query.ID, "✅ Incident "+id+" acknowledged")
+}
+
+// handleAck handles /ack <incident ID> (administrators only)
+func (b *Bot) handleAck(msg *tgbotapi.Message, args []string) {
+	if !b.isAdmin(senderID(msg)) {
+		b.sendMessage(msg.Chat.ID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	if len(args) != 1 {
+		b.sendMessage(msg.Chat.ID, "Usage: /ack <incident ID>\nSee /incidents for open incidents.")
+		return
+	}
+	if err := b.acknowledge(args[0], msg.From); err != nil {
+		b.sendMessage(msg.Chat.ID, "❌ "+err.Error())
+		return
+	}
+	b.sendMessage(msg.Chat.ID, fmt.Sprintf("✅ Incident `%s` acknowledged - escalation stopped.", args[0]))
+}
+
+// acknowledge passes an acknowledgement to the escalation manager
+func (b *Bot) acknowledge(id string, from *tgbotapi.User) error {
+	if b.ackHandler == nil {
+		return fmt.Errorf("escalation is not enabled")
+	}
+	return b.ackHandler(id, actor(from))
+}
+
+// actor names a Telegram user in acknowledgements and annotations, e.g. "telegram:@name"
+func actor(from *tgbotapi.User) string {
+	if from == nil {
+		return "telegram"
+	}
+	if from.UserName != "" {
+		return "telegram:@" + from.UserName
+	}
+	return fmt.Sprintf("telegram:%d", from.ID)
+}
+
+// sendIncidents handles /incidents: the open incidents and their acknowledgement (administrators only)
+func (b *Bot) sendIncidents(chatID int64, userID int64) {
+	if !b.isAdmin(userID) {
+		b.sendMessage(chatID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	if b.incidentsProvider == nil {
+		b.sendMessage(chatID, "Escalation is not enabled.")
+		return
+	}
+	incidents, err := b.incidentsProvider()
+	if err != nil {
+		b.sendMessage(chatID, "❌ Failed to load incidents: "+err.Error())
+		return
+	}
+	b.sendMessage(chatID, formatIncidents(incidents))
+}
+
+// formatIncidents formats the /incidents report
+func formatIncidents(incidents []escalation.Incident) string {
+	if len(incidents) == 0 {
+		return "✅ No open incidents."
+	}
+	var builder strings.Builder
+	builder.WriteString("📟 *Open Incidents*\n\n")
+	for _, incident := range incidents {
+		builder.WriteString(fmt.Sprintf("`%s` %s\n", incident.ID, incident.Message))
+		builder.WriteString(fmt.Sprintf("   Opened %s ago, %d escalation step(s) notified", time.Since(incident.OpenedAt).Truncate(time.Minute), incident.Notified))
+		if incident.AckedAt != nil {
+			builder.WriteString(fmt.Sprintf(", acknowledged by %s", incident.AckedBy))
+		} else {
+			builder.WriteString(fmt.Sprintf(" - /ack %s", incident.ID))
+		}
+		builder.WriteString("\n")
+		if incident.Narrative != "" {
+			builder.WriteString("   📝 " + incident.Narrative + "\n")
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// SetAnnotationHandlers sets the functions behind /note: add records an
+// annotation on the current time, list returns those of a period
+func (b *Bot) SetAnnotationHandlers(add func(text, author string) (models.Annotation, error), list func(period string) ([]models.Annotation, error)) {
+	b.annotate = add
+	b.annotations = list
+}
+
+// handleNote handles /note <text> (annotate now) and /notes or an empty /note
+// (list the last 24 hours' annotations); administrators only
+func (b *Bot) handleNote(chatID int64, userID int64, author, text string) {
+	if !b.isAdmin(userID) {
+		b.sendMessage(chatID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	if b.annotate == nil {
+		b.sendMessage(chatID, "❌ Annotations are not available (they need the monitor's history)")
+		return
+	}
+
+	if text == "" {
+		notes, err := b.annotations("24h")
+		if err != nil {
+			b.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+			return
+		}
+		if len(notes) == 0 {
+			b.sendMessage(chatID, "No annotations in the last 24 hours.\nUsage: /note <text>\nExample: /note Power outage in the Tehran data center")
+			return
+		}
+		var builder strings.Builder
+		builder.WriteString("📝 *Annotations (last 24h)*\n\n")
+		for _, note := range notes {
+			fmt.Fprintf(&builder, "• %s - %s (%s)\n", note.Time.In(b.location).Format("Jan 2 15:04"), escapeMarkdown(note.Text), escapeMarkdown(note.Author))
+		}
+		b.sendMessage(chatID, builder.String())
+		return
+	}
+
+	note, err := b.annotate(text, author)
+	if err != nil {
+		b.sendMessage(chatID, fmt.Sprintf("❌ %v", err))
+		return
+	}
+	b.sendMessage(chatID, fmt.Sprintf("📝 Annotation added at %s. It shows on charts and in exports.", note.Time.In(b.location).Format("Jan 2 15:04 MST")))
+}
+
+// escapeMarkdown escapes the characters of user text that legacy Markdown would interpret
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[").Replace(text)
+}
@@ -0,0 +1,111 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/clock"
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// fakeBotAPI answers the Bot API methods the bot calls and counts the
+// messages it sends
+type fakeBotAPI struct {
+	mu    sync.Mutex
+	sends int
+}
+
+func (f *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	var result any
+	switch method {
+	case "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "first_name": "NetBlocks", "username": "netblocks_test_bot"}
+	case "getChat":
+		result = map[string]any{"id": -1001, "type": "channel", "title": "Test"}
+	case "getChatMember":
+		result = map[string]any{"status": "creator", "user": map[string]any{"id": 1, "is_bot": true, "first_name": "NetBlocks"}}
+	default:
+		if strings.HasPrefix(method, "send") {
+			f.mu.Lock()
+			f.sends++
+			f.mu.Unlock()
+		}
+		result = map[string]any{"message_id": 1, "date": 0, "chat": map[string]any{"id": -1001, "type": "channel"}}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func (f *fakeBotAPI) sent() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sends
+}
+
+// waitFor polls cond until it holds or a second passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestChannelPostFollowsClock(t *testing.T) {
+	fake := &fakeBotAPI{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint("123456:test-token", server.URL+"/bot%s/%s")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		ChatPrefsPath:    filepath.Join(t.TempDir(), "chat_prefs.json"),
+		TelegramChannels: []config.ChannelConfig{{ID: "@netblocks_test", Profile: profilePlain, Interval: "30m"}},
+	}
+	result := &models.MonitoringResult{
+		Timestamp:   start,
+		ASNStatuses: map[string]*models.ASNStatus{"AS44244": {ASN: "AS44244", Name: "Irancell", Connected: true}},
+		DNSStatuses: map[string]*models.DNSStatus{},
+	}
+	bot, err := newBot(api, cfg, func() (*models.MonitoringResult, error) { return result, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := clock.NewFake(start)
+	bot.SetClock(clk)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.SendPeriodicUpdates(ctx)
+	waitFor(t, "the check ticker", func() bool { return clk.Tickers() == 1 })
+
+	// The first post goes out at once
+	clk.Advance(time.Second)
+	waitFor(t, "the first post", func() bool { return fake.sent() > 0 })
+	first := fake.sent()
+
+	// Nothing more until the channel interval elapsed
+	clk.Advance(29 * time.Minute)
+	time.Sleep(50 * time.Millisecond)
+	if got := fake.sent(); got != first {
+		t.Fatalf("posted %d messages before the interval elapsed, want %d", got, first)
+	}
+
+	clk.Advance(time.Minute)
+	waitFor(t, "the second post", func() bool { return fake.sent() > first })
+}
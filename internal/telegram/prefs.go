@@ -0,0 +1,156 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// QuietHours is a daily local-time window in which only critical alerts are pushed
+// Start and End are hours (0-23); End is exclusive and Start > End wraps past midnight
+type QuietHours struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Contains reports whether t (already in local time) falls inside the quiet window
+func (q *QuietHours) Contains(t time.Time) bool {
+	if q == nil || q.Start == q.End {
+		return false
+	}
+	hour := t.Hour()
+	if q.Start < q.End {
+		return hour >= q.Start && hour < q.End
+	}
+	return hour >= q.Start || hour < q.End
+}
+
+// String formats the window as "00:00-08:00"
+func (q *QuietHours) String() string {
+	return fmt.Sprintf("%02d:00-%02d:00", q.Start, q.End)
+}
+
+// ChatPrefs holds the notification preferences of a single chat
+type ChatPrefs struct {
+	QuietHours  *QuietHours `json:"quiet_hours,omitempty"`
+	Language    string      `json:"language,omitempty"`     // "en" (default) or "fa"
+	Verbosity   string      `json:"verbosity,omitempty"`    // "full" (default) or "compact" (images and charts only)
+	MinSeverity string      `json:"min_severity,omitempty"` // Lowest alert severity pushed: info (default), warning or critical
+	Watchlist   []string    `json:"watchlist,omitempty"`    // ASNs to get per-ASN alerts for; empty means all
+	TextOnly    bool        `json:"text_only,omitempty"`    // Send text alternatives instead of chart images
+	Plain       bool        `json:"plain,omitempty"`        // Send the status as short sentences without emoji or charts, for screen readers
+}
+
+// Chat verbosity levels
+const (
+	verbosityFull    = "full"
+	verbosityCompact = "compact"
+)
+
+// severityRank orders severities from least to most severe
+var severityRank = map[string]int{
+	models.SeverityInfo:     0,
+	models.SeverityWarning:  1,
+	models.SeverityCritical: 2,
+}
+
+// wants reports whether an event passes the chat's severity threshold and ASN watchlist
+// Country-wide events (Target "IR") are never filtered by the watchlist
+func (p ChatPrefs) wants(event models.Event) bool {
+	if p.MinSeverity != "" && severityRank[event.Severity] < severityRank[p.MinSeverity] {
+		return false
+	}
+	if event.Kind == "asn" && event.Target != "IR" && len(p.Watchlist) > 0 {
+		return p.watches(event.Target)
+	}
+	return true
+}
+
+// watches reports whether an ASN is on the chat's watchlist
+func (p ChatPrefs) watches(asn string) bool {
+	for _, watched := range p.Watchlist {
+		if watched == asn {
+			return true
+		}
+	}
+	return false
+}
+
+// profile maps the chat's verbosity and plain setting to a status post profile
+func (p ChatPrefs) profile() string {
+	if p.Plain {
+		return profilePlain
+	}
+	if p.Verbosity == verbosityCompact {
+		return profileCharts
+	}
+	return profileFull
+}
+
+// prefsStore persists per-chat preferences to a JSON file
+type prefsStore struct {
+	path  string
+	mu    sync.RWMutex
+	prefs map[int64]*ChatPrefs
+}
+
+// loadPrefs loads chat preferences from path; a missing file yields an empty store
+func loadPrefs(path string) (*prefsStore, error) {
+	store := &prefsStore{
+		path:  path,
+		prefs: make(map[int64]*ChatPrefs),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &store.prefs); err != nil {
+		return nil, fmt.Errorf("failed to parse chat preferences %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// get returns a copy of the preferences of a chat (zero value if none are set)
+func (p *prefsStore) get(chatID int64) ChatPrefs {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if prefs, ok := p.prefs[chatID]; ok {
+		copied := *prefs
+		copied.Watchlist = append([]string(nil), prefs.Watchlist...)
+		return copied
+	}
+	return ChatPrefs{}
+}
+
+// update modifies the preferences of a chat and saves the file
+func (p *prefsStore) update(chatID int64, fn func(*ChatPrefs)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	prefs, ok := p.prefs[chatID]
+	if !ok {
+		prefs = &ChatPrefs{}
+		p.prefs[chatID] = prefs
+	}
+	fn(prefs)
+
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(p.prefs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0644)
+}
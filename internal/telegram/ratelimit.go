@@ -0,0 +1,117 @@
+package telegram
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Telegram allows about 30 messages per second overall and one per second per chat
+const (
+	globalSendGap  = time.Second / 30
+	perChatSendGap = time.Second
+	maxRetryAfter  = time.Minute // Longer flood waits are reported as failures instead of blocking
+	maxSendTries   = 3           // Attempts of one send while Telegram keeps asking to back off
+)
+
+// sendLimiter spaces out API sends so bursts (status posts, broadcasts) stay within Telegram's limits
+type sendLimiter struct {
+	mu       sync.Mutex
+	next     time.Time            // Earliest time for the next send to any chat
+	nextChat map[string]time.Time // Earliest time for the next send per chat
+	backoff  map[string]time.Time // End of the last flood wait Telegram requested per chat
+	inFlight int                  // Sends in progress, including their waits (see Bot.Drain)
+}
+
+func newSendLimiter() *sendLimiter {
+	return &sendLimiter{nextChat: make(map[string]time.Time), backoff: make(map[string]time.Time)}
+}
+
+// hold records a flood wait: no send goes to chatID before until
+func (l *sendLimiter) hold(chatID string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.backoff[chatID] = until
+	if until.After(l.nextChat[chatID]) {
+		l.nextChat[chatID] = until
+	}
+}
+
+// backoffUntil returns the end of the last flood wait of chatID (zero if there was none)
+func (l *sendLimiter) backoffUntil(chatID string) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.backoff[chatID]
+}
+
+// blocked reports whether chatID is still in a flood wait at now
+func (l *sendLimiter) blocked(chatID string, now time.Time) bool {
+	return l.backoffUntil(chatID).After(now)
+}
+
+// track adds delta to the number of sends in progress
+func (l *sendLimiter) track(delta int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight += delta
+}
+
+// pending returns the number of sends in progress
+func (l *sendLimiter) pending() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// wait blocks until a message may be sent to chatID and reserves that slot
+func (l *sendLimiter) wait(chatID string) {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.next.After(at) {
+		at = l.next
+	}
+	if chatNext := l.nextChat[chatID]; chatNext.After(at) {
+		at = chatNext
+	}
+	l.next = at.Add(globalSendGap)
+	l.nextChat[chatID] = at.Add(perChatSendGap)
+	l.mu.Unlock()
+
+	if delay := at.Sub(now); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// limited runs a send through the limiter and retries while Telegram asks to back off (HTTP 429)
+// Every flood wait is recorded, so callers can hold back further posts to the chat;
+// waits longer than maxRetryAfter fail the send right away
+func (b *Bot) limited(chatID string, send func() (*tgbotapi.APIResponse, error)) (*tgbotapi.APIResponse, error) {
+	b.limiter.track(1)
+	defer b.limiter.track(-1)
+
+	var resp *tgbotapi.APIResponse
+	var err error
+	for try := 1; try <= maxSendTries; try++ {
+		b.limiter.wait(chatID)
+		start := time.Now()
+		resp, err = send()
+		b.stats.recordCall(time.Since(start), err)
+
+		var apiErr *tgbotapi.Error
+		if !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+			return resp, err
+		}
+		retryAfter := time.Duration(apiErr.RetryAfter) * time.Second
+		b.limiter.hold(chatID, time.Now().Add(retryAfter))
+		if retryAfter > maxRetryAfter {
+			log.Printf("⏳ Telegram rate limit hit for %s - holding back sends for %v", chatID, retryAfter)
+			return resp, err
+		}
+		log.Printf("⏳ Telegram rate limit hit for %s - retrying in %v (try %d/%d)", chatID, retryAfter, try, maxSendTries)
+	}
+	return resp, err
+}
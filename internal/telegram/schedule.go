@@ -0,0 +1,138 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleMacros are the shorthand schedules accepted besides five-field expressions
+var scheduleMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// cronFieldRanges are the value ranges of minute, hour, day of month, month and day of week
+var cronFieldRanges = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// cronSchedule is a cron expression (minute hour day-of-month month day-of-week)
+// evaluated in a fixed time zone
+type cronSchedule struct {
+	expr     string
+	fields   [5]uint64 // Allowed values of each field as bit sets
+	anyDay   bool      // Day of month is "*"
+	anyWeek  bool      // Day of week is "*"
+	location *time.Location
+}
+
+// parseSchedule parses a cron expression such as "0 * * * *" (hourly) or "*/30 6-23 * * *"
+// Fields accept "*", values, ranges, lists and steps; Sunday is 0 (or 7)
+func parseSchedule(expr string, location *time.Location) (*cronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if macro, ok := scheduleMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("schedule %q needs 5 fields (minute hour day month weekday)", expr)
+	}
+
+	s := &cronSchedule{expr: expr, location: location, anyDay: parts[2] == "*", anyWeek: parts[4] == "*"}
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFieldRanges[i][0], cronFieldRanges[i][1], i == 4)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", expr, err)
+		}
+		s.fields[i] = bits
+	}
+	return s, nil
+}
+
+// parseCronField returns the values allowed by one comma-separated field
+func parseCronField(field string, min, max int, weekday bool) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+			rangePart, step = item[:i], n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value %q", item)
+				}
+			} else if step > 1 {
+				hi = max // "5/15" means from 5 to the end in steps of 15
+			}
+		}
+		if weekday && hi == 7 && lo <= 7 {
+			// Sunday may be written as 7
+			bits |= 1
+			if lo == 7 {
+				continue
+			}
+			hi = 6
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// matchesDay applies the cron rule that a restricted day of month and day of
+// week match if either does
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	day := s.fields[2]&(1<<uint(t.Day())) != 0
+	week := s.fields[4]&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.anyDay && s.anyWeek:
+		return true
+	case s.anyDay:
+		return week
+	case s.anyWeek:
+		return day
+	default:
+		return day || week
+	}
+}
+
+// next returns the first scheduled minute after t (zero if there is none within five years)
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.In(s.location).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.fields[3]&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, s.location)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, s.location)
+		case s.fields[1]&(1<<uint(t.Hour())) == 0:
+			// Not t.Truncate(time.Hour): zones like Asia/Tehran are offset by half an hour
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, s.location)
+		case s.fields[0]&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
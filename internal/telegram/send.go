@@ -0,0 +1,197 @@
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Sections of a status post; each can be routed to its own forum topic
+const (
+	sectionHeader  = "header"
+	sectionASN     = "asn"
+	sectionDNS     = "dns"
+	sectionTraffic = "traffic"
+	sectionAlerts  = "alerts"
+)
+
+// topicFor returns the forum topic (message_thread_id) configured for a section
+// Topics only apply to configured channels/supergroups; other chats get 0 (no topic)
+func (b *Bot) topicFor(chatID interface{}, section string) int {
+	id, ok := chatID.(string)
+	if !ok {
+		return 0
+	}
+	if ch := b.channel(id); ch != nil {
+		return ch.topics[section]
+	}
+	return 0
+}
+
+// chatIDParam converts an int64 chat ID or a string channel username to the API parameter
+func chatIDParam(chatID interface{}) (string, error) {
+	switch id := chatID.(type) {
+	case int64:
+		return strconv.FormatInt(id, 10), nil
+	case string:
+		return id, nil
+	default:
+		return "", fmt.Errorf("invalid chatID type: %T", chatID)
+	}
+}
+
+// sendText sends a single Markdown text message, optionally into a forum topic
+// The bundled API client predates forum topics, so the request is built by hand
+// All sends go through the rate limiter (see ratelimit.go), keyed by the configured ID,
+// and are queued on disk while Telegram is unreachable (see spool.go)
+func (b *Bot) sendText(chatID interface{}, threadID int, text string) (tgbotapi.Message, error) {
+	id, err := chatIDParam(chatID)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	params := tgbotapi.Params{
+		"chat_id":    b.resolveChat(id),
+		"text":       text,
+		"parse_mode": tgbotapi.ModeMarkdown,
+	}
+	params.AddNonZero("message_thread_id", threadID)
+
+	resp, err := b.post(id, "sendMessage", params, nil)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var message tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
+
+// sendPhoto uploads PNG bytes as a photo with a Markdown caption, optionally into a forum topic
+// Large images are fitted to telegram_images first; images Telegram rejects as
+// photos are sent as documents instead (see images.go)
+func (b *Bot) sendPhoto(chatID interface{}, threadID int, name string, data []byte, caption string) (tgbotapi.Message, error) {
+	id, err := chatIDParam(chatID)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	data, asDocument := b.preparePhoto(name, data)
+	if asDocument {
+		return b.sendDocument(chatID, threadID, name, data, caption)
+	}
+
+	params := tgbotapi.Params{
+		"chat_id": b.resolveChat(id),
+	}
+	if caption != "" {
+		params["caption"] = caption
+		params["parse_mode"] = tgbotapi.ModeMarkdown
+	}
+	params.AddNonZero("message_thread_id", threadID)
+
+	file := tgbotapi.RequestFile{
+		Name: "photo",
+		Data: tgbotapi.FileBytes{Name: name, Bytes: data},
+	}
+	resp, err := b.post(id, "sendPhoto", params, &file)
+	if photoRejected(err) {
+		log.Printf("🖼  Telegram rejected %s as a photo (%v) - sending it as a document", name, err)
+		return b.sendDocument(chatID, threadID, name, data, caption)
+	}
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var message tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
+
+// sendDocument uploads a file as a document attachment with a Markdown caption, optionally into a forum topic
+// chatID can be an int64 for users or a string for channels
+func (b *Bot) sendDocument(chatID interface{}, threadID int, name string, data []byte, caption string) (tgbotapi.Message, error) {
+	id, err := chatIDParam(chatID)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	params := tgbotapi.Params{
+		"chat_id":    b.resolveChat(id),
+		"caption":    caption,
+		"parse_mode": tgbotapi.ModeMarkdown,
+	}
+	params.AddNonZero("message_thread_id", threadID)
+	file := tgbotapi.RequestFile{
+		Name: "document",
+		Data: tgbotapi.FileBytes{Name: name, Bytes: data},
+	}
+	resp, err := b.post(id, "sendDocument", params, &file)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var message tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
+
+// Telegram's message and photo caption limits
+const (
+	maxMessageLength = 4096
+	maxCaptionLength = 1024
+)
+
+// sendChartPhoto sends a chart with its text alternative (key numbers) appended to the caption
+// Chats with the text-only preference get the caption and text alternative as a message instead
+// If both don't fit in one caption, the text alternative follows as a separate message
+func (b *Bot) sendChartPhoto(chatID interface{}, threadID int, name string, data []byte, caption, altText string) error {
+	text := caption
+	if altText != "" {
+		text += "\n\n📝 " + altText
+	}
+
+	if id, ok := chatID.(int64); ok && b.prefs.get(id).TextOnly {
+		b.sendMessageToTopic(chatID, threadID, text)
+		return nil
+	}
+
+	if utf8.RuneCountInString(text) <= maxCaptionLength {
+		_, err := b.sendPhoto(chatID, threadID, name, data, text)
+		return err
+	}
+	if _, err := b.sendPhoto(chatID, threadID, name, data, caption); err != nil && !errors.Is(err, errSpooled) {
+		return err
+	}
+	b.sendMessageToTopic(chatID, threadID, "📝 "+altText)
+	return nil
+}
+
+// sendKeyboard sends a Markdown text message with an inline keyboard
+func (b *Bot) sendKeyboard(chatID int64, text string, markup tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
+	id, err := chatIDParam(chatID)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	params := tgbotapi.Params{
+		"chat_id":    b.resolveChat(id),
+		"text":       text,
+		"parse_mode": tgbotapi.ModeMarkdown,
+	}
+	if err := params.AddInterface("reply_markup", markup); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	resp, err := b.limited(id, func() (*tgbotapi.APIResponse, error) {
+		return b.api.MakeRequest("sendMessage", params)
+	})
+	b.recordSend(err)
+	if err != nil {
+		return tgbotapi.Message{}, err
+	}
+	var message tgbotapi.Message
+	err = json.Unmarshal(resp.Result, &message)
+	return message, err
+}
@@ -0,0 +1,289 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+// Callback data prefixes of the /settings keyboard ("set:<action>[:<value>]")
+const (
+	settingsPrefix     = "set:"
+	settingsLang       = "lang"
+	settingsVerbosity  = "verb"
+	settingsSeverity   = "sev"
+	settingsImages     = "img"   // Charts as images or text only
+	settingsPlain      = "plain" // Status with emoji and charts or as plain sentences
+	settingsWatchlist  = "watch" // Open a watchlist page ("watch[:<page>]")
+	settingsToggleASN  = "asn"   // Toggle one ASN on the watchlist ("asn:<ASN>:<page>")
+	settingsClearWatch = "clear" // Empty the watchlist (watch all ASNs)
+	settingsBack       = "back"  // Return to the main page
+)
+
+// watchlistColumns is the number of ASN buttons per keyboard row
+const watchlistColumns = 3
+
+// watchlistRows is the number of ASN rows per watchlist page; Telegram
+// refuses inline keyboards of more than 100 buttons
+const watchlistRows = 8
+
+// sendSettings sends the /settings menu for a chat
+func (b *Bot) sendSettings(chatID int64) {
+	prefs := b.prefs.get(chatID)
+	if _, err := b.sendKeyboard(chatID, formatSettings(prefs), settingsKeyboard(prefs)); err != nil {
+		log.Printf("Error sending settings menu to %d: %v", chatID, err)
+	}
+}
+
+// formatSettings describes the current settings of a chat
+func formatSettings(prefs ChatPrefs) string {
+	var builder strings.Builder
+	builder.WriteString("⚙️ *Settings*\n\n")
+	builder.WriteString(fmt.Sprintf("🌐 Language: `%s`\n", normalizeLang(prefs.Language)))
+	verbosity := prefs.Verbosity
+	if verbosity == "" {
+		verbosity = verbosityFull
+	}
+	builder.WriteString(fmt.Sprintf("📝 Verbosity: `%s`\n", verbosity))
+	severity := prefs.MinSeverity
+	if severity == "" {
+		severity = models.SeverityInfo
+	}
+	builder.WriteString(fmt.Sprintf("🔔 Alerts from: `%s`\n", severity))
+	images := "on"
+	if prefs.TextOnly {
+		images = "off (text only)"
+	}
+	builder.WriteString(fmt.Sprintf("🖼 Chart images: `%s`\n", images))
+	style := "rich"
+	if prefs.Plain {
+		style = "plain (for screen readers)"
+	}
+	builder.WriteString(fmt.Sprintf("🔈 Status style: `%s`\n", style))
+	if len(prefs.Watchlist) == 0 {
+		builder.WriteString("👁 Watchlist: `all ASNs`\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("👁 Watchlist: `%s`\n", strings.Join(prefs.Watchlist, ", ")))
+	}
+	builder.WriteString("\n_Tap a button to change a setting._")
+	return builder.String()
+}
+
+// settingsKeyboard builds the main settings page; the active choice is marked with ✅
+func settingsKeyboard(prefs ChatPrefs) tgbotapi.InlineKeyboardMarkup {
+	choice := func(label, action, value string, active bool) tgbotapi.InlineKeyboardButton {
+		if active {
+			label = "✅ " + label
+		}
+		return tgbotapi.NewInlineKeyboardButtonData(label, settingsPrefix+action+":"+value)
+	}
+
+	lang := normalizeLang(prefs.Language)
+	compact := prefs.Verbosity == verbosityCompact
+	severity := prefs.MinSeverity
+	if severity == "" {
+		severity = models.SeverityInfo
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			choice("English", settingsLang, langEnglish, lang == langEnglish),
+			choice("فارسی", settingsLang, langPersian, lang == langPersian),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			choice("Full", settingsVerbosity, verbosityFull, !compact),
+			choice("Compact", settingsVerbosity, verbosityCompact, compact),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			choice("Info+", settingsSeverity, models.SeverityInfo, severity == models.SeverityInfo),
+			choice("Warning+", settingsSeverity, models.SeverityWarning, severity == models.SeverityWarning),
+			choice("Critical", settingsSeverity, models.SeverityCritical, severity == models.SeverityCritical),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			choice("🖼 Images", settingsImages, "on", !prefs.TextOnly),
+			choice("📝 Text only", settingsImages, "off", prefs.TextOnly),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			choice("Rich status", settingsPlain, "off", !prefs.Plain),
+			choice("Plain status", settingsPlain, "on", prefs.Plain),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("👁 Watchlist (%d)", len(prefs.Watchlist)), settingsPrefix+settingsWatchlist),
+		),
+	)
+}
+
+// watchlistKeyboard builds a page of the watchlist with one toggle per
+// monitored ASN, and buttons to the previous and next pages
+func (b *Bot) watchlistKeyboard(prefs ChatPrefs, page int) tgbotapi.InlineKeyboardMarkup {
+	asns, names := b.watchlistASNs(prefs)
+	perPage := watchlistRows * watchlistColumns
+	pages := (len(asns) + perPage - 1) / perPage
+	page = max(0, min(page, pages-1))
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, asn := range asns[page*perPage : min((page+1)*perPage, len(asns))] {
+		label := asn
+		if name := names[asn]; name != "" && name != "Unknown" {
+			label = fmt.Sprintf("%s %s", asn, name)
+		}
+		if prefs.watches(asn) {
+			label = "✅ " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("%s%s:%s:%d", settingsPrefix, settingsToggleASN, asn, page)))
+		if len(row) == watchlistColumns {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	if pages > 1 {
+		var nav []tgbotapi.InlineKeyboardButton
+		if page > 0 {
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀️ Prev", fmt.Sprintf("%s%s:%d", settingsPrefix, settingsWatchlist, page-1)))
+		}
+		if page < pages-1 {
+			nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("Next ▶️", fmt.Sprintf("%s%s:%d", settingsPrefix, settingsWatchlist, page+1)))
+		}
+		rows = append(rows, nav)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🧹 Watch all", settingsPrefix+settingsClearWatch),
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", settingsPrefix+settingsBack),
+	))
+	return tgbotapi.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// watchlistASNs returns the ASNs the monitor tracks, including those it
+// discovered, and the chat's watched ASNs it no longer tracks, sorted, with
+// their names; before the first cycle the configured ASNs stand in
+func (b *Bot) watchlistASNs(prefs ChatPrefs) ([]string, map[string]string) {
+	names := make(map[string]string)
+	if b.onStatusUpdate != nil {
+		if result, err := b.onStatusUpdate(); err == nil && result != nil {
+			for asn, status := range result.ASNStatuses {
+				names[asn] = status.Name
+			}
+		}
+	}
+	if len(names) == 0 {
+		for _, asn := range b.config.IranASNs {
+			names[asn] = b.config.GetASNName(asn)
+		}
+	}
+	for _, asn := range prefs.Watchlist {
+		if _, ok := names[asn]; !ok {
+			names[asn] = b.config.GetASNName(asn)
+		}
+	}
+
+	asns := make([]string, 0, len(names))
+	for asn := range names {
+		asns = append(asns, asn)
+	}
+	sort.Strings(asns)
+	return asns, names
+}
+
+// handleCallback handles inline keyboard button presses
+func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
+	if strings.HasPrefix(query.Data, ackPrefix) {
+		b.handleAckCallback(query)
+		return
+	}
+	if strings.HasPrefix(query.Data, confirmPrefix) {
+		b.handleConfirmCallback(query)
+		return
+	}
+	if query.Message == nil || !strings.HasPrefix(query.Data, settingsPrefix) {
+		b.answerCallback(query.ID, "")
+		return
+	}
+	chatID := query.Message.Chat.ID
+
+	// In groups only admins may change the group's settings
+	if (query.Message.Chat.IsGroup() || query.Message.Chat.IsSuperGroup()) && !b.isChatAdmin(chatID, query.From.ID) {
+		b.answerCallback(query.ID, "Only group administrators can change settings")
+		return
+	}
+
+	action, value, _ := strings.Cut(strings.TrimPrefix(query.Data, settingsPrefix), ":")
+	watchlistPage := false
+	page := 0
+	err := b.prefs.update(chatID, func(prefs *ChatPrefs) {
+		switch action {
+		case settingsLang:
+			prefs.Language = normalizeLang(value)
+		case settingsVerbosity:
+			if value == verbosityCompact {
+				prefs.Verbosity = verbosityCompact
+			} else {
+				prefs.Verbosity = ""
+			}
+		case settingsSeverity:
+			if _, ok := severityRank[value]; ok && value != models.SeverityInfo {
+				prefs.MinSeverity = value
+			} else {
+				prefs.MinSeverity = ""
+			}
+		case settingsImages:
+			prefs.TextOnly = value == "off"
+		case settingsPlain:
+			prefs.Plain = value == "on"
+		case settingsWatchlist:
+			watchlistPage = true
+			page, _ = strconv.Atoi(value)
+		case settingsToggleASN:
+			watchlistPage = true
+			var pageValue string
+			value, pageValue, _ = strings.Cut(value, ":")
+			page, _ = strconv.Atoi(pageValue)
+			if prefs.watches(value) {
+				kept := prefs.Watchlist[:0]
+				for _, asn := range prefs.Watchlist {
+					if asn != value {
+						kept = append(kept, asn)
+					}
+				}
+				prefs.Watchlist = kept
+			} else {
+				prefs.Watchlist = append(prefs.Watchlist, value)
+			}
+		case settingsClearWatch:
+			watchlistPage = true
+			prefs.Watchlist = nil
+		}
+	})
+	if err != nil {
+		log.Printf("Error saving settings for chat %d: %v", chatID, err)
+		b.answerCallback(query.ID, "❌ Failed to save settings")
+		return
+	}
+
+	prefs := b.prefs.get(chatID)
+	markup := settingsKeyboard(prefs)
+	if watchlistPage {
+		markup = b.watchlistKeyboard(prefs, page)
+	}
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, query.Message.MessageID, formatSettings(prefs), markup)
+	edit.ParseMode = tgbotapi.ModeMarkdown
+	if _, err := b.api.Request(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		log.Printf("Error updating settings menu in chat %d: %v", chatID, err)
+	}
+	b.answerCallback(query.ID, "")
+}
+
+// answerCallback acknowledges a button press so Telegram stops the loading spinner
+func (b *Bot) answerCallback(queryID, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(queryID, text)); err != nil {
+		log.Printf("Error answering callback query: %v", err)
+	}
+}
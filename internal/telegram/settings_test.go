@@ -0,0 +1,51 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/models"
+)
+
+func TestWatchlistKeyboardPages(t *testing.T) {
+	result := &models.MonitoringResult{ASNStatuses: make(map[string]*models.ASNStatus)}
+	for i := 0; i < 150; i++ {
+		asn := fmt.Sprintf("AS%d", 10000+i)
+		result.ASNStatuses[asn] = &models.ASNStatus{ASN: asn}
+	}
+	b := &Bot{
+		config:         &config.Config{IranASNs: []string{"AS1"}},
+		onStatusUpdate: func() (*models.MonitoringResult, error) { return result, nil },
+	}
+
+	seen := make(map[string]bool)
+	for page := 0; page < 7; page++ {
+		buttons := 0
+		for _, row := range b.watchlistKeyboard(ChatPrefs{}, page).InlineKeyboard {
+			for _, button := range row {
+				buttons++
+				value, ok := strings.CutPrefix(*button.CallbackData, settingsPrefix+settingsToggleASN+":")
+				if !ok {
+					continue
+				}
+				asn, back, _ := strings.Cut(value, ":")
+				if back != strconv.Itoa(page) {
+					t.Fatalf("toggle of %s on page %d returns to page %s", asn, page, back)
+				}
+				seen[asn] = true
+			}
+		}
+		if buttons > 100 {
+			t.Fatalf("page %d has %d buttons, over Telegram's limit", page, buttons)
+		}
+	}
+	if len(seen) != len(result.ASNStatuses) {
+		t.Fatalf("pages list %d ASNs, want the %d tracked ones", len(seen), len(result.ASNStatuses))
+	}
+	if seen["AS1"] {
+		t.Fatal("configured ASN listed although the monitor reported its ASNs")
+	}
+}
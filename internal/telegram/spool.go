@@ -0,0 +1,337 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/config"
+)
+
+// Spool settings
+const (
+	defaultSpoolMaxAge = 2 * time.Hour
+	spoolRetry         = 30 * time.Second // How often delivery of the spool is retried
+	spoolExt           = ".json"
+)
+
+// errSpooled is returned by sends that were queued on disk instead of delivered
+var errSpooled = errors.New("Telegram unreachable, queued for delivery")
+
+// spoolEntry is a send queued while Telegram was unreachable
+type spoolEntry struct {
+	Method   string          `json:"method"` // sendMessage, sendPhoto or sendDocument
+	Chat     string          `json:"chat"`   // Configured chat ID, the rate limiter key
+	Params   tgbotapi.Params `json:"params"`
+	FileName string          `json:"file_name,omitempty"` // Photo or document upload, if any
+	File     []byte          `json:"file,omitempty"`
+	Queued   time.Time       `json:"queued"`
+}
+
+// sendSpool keeps the messages and charts that could not reach Telegram as one
+// file per send, named by queue time so a directory listing is the send order
+type sendSpool struct {
+	dir    string
+	maxAge time.Duration
+	mu     sync.Mutex
+	seq    int
+	queued int // Entries on disk
+}
+
+// newSendSpool opens the spool directory; entries left by a previous run are
+// delivered like new ones
+func newSendSpool(settings *config.TelegramSpool) (*sendSpool, error) {
+	maxAge := defaultSpoolMaxAge
+	if settings.MaxAge != "" {
+		d, err := time.ParseDuration(settings.MaxAge)
+		if err != nil {
+			return nil, fmt.Errorf("invalid telegram_spool.max_age: %w", err)
+		}
+		maxAge = d
+	}
+	if err := os.MkdirAll(settings.Dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &sendSpool{dir: settings.Dir, maxAge: maxAge}
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	s.queued = len(names)
+	if s.queued > 0 {
+		log.Printf("📥 %d Telegram send(s) queued in %s by a previous run", s.queued, s.dir)
+	}
+	return s, nil
+}
+
+// active reports whether sends are queued; new sends then queue behind them to keep the order
+func (s *sendSpool) active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queued > 0
+}
+
+// add writes an entry behind the queued ones and drops the entries past the age cutoff
+func (s *sendSpool) add(entry spoolEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", entry.Queued.UnixNano(), s.seq%1000000, spoolExt)
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	s.queued++
+	s.pruneLocked(entry.Queued)
+	return nil
+}
+
+// pruneLocked removes the entries queued longer than maxAge before now
+func (s *sendSpool) pruneLocked(now time.Time) {
+	names, err := s.list()
+	if err != nil {
+		return
+	}
+	for _, name := range names {
+		if queued, ok := spoolTime(name); !ok || now.Sub(queued) <= s.maxAge {
+			break
+		}
+		if os.Remove(filepath.Join(s.dir, name)) == nil {
+			s.queued--
+		}
+	}
+}
+
+// list returns the entry file names, oldest first
+func (s *sendSpool) list() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range dirEntries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), spoolExt) && !strings.HasPrefix(e.Name(), ".") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load reads an entry
+func (s *sendSpool) load(name string) (spoolEntry, error) {
+	var entry spoolEntry
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
+
+// remove deletes a delivered or dropped entry and reports whether it is gone
+func (s *sendSpool) remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(filepath.Join(s.dir, name))
+	if err == nil {
+		s.queued--
+		return true
+	}
+	if os.IsNotExist(err) {
+		return true
+	}
+	log.Printf("⚠️  Failed to remove spooled send %s: %v", name, err)
+	return false
+}
+
+// spoolTime returns the queue time encoded in an entry file name
+func spoolTime(name string) (time.Time, bool) {
+	stamp, _, ok := strings.Cut(name, "-")
+	if !ok {
+		return time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// unreachable reports whether a send failed before Telegram answered (DNS,
+// connect or TLS failures, timeouts), as opposed to an API error
+func unreachable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// post sends an API request through the rate limiter. With a spool configured,
+// requests that cannot reach Telegram, and all requests while others are
+// queued, are written to the spool and errSpooled is returned
+func (b *Bot) post(chat, method string, params tgbotapi.Params, file *tgbotapi.RequestFile) (*tgbotapi.APIResponse, error) {
+	entry := func() spoolEntry {
+		entry := spoolEntry{Method: method, Chat: chat, Params: params, Queued: time.Now()}
+		if file != nil {
+			if data, ok := file.Data.(tgbotapi.FileBytes); ok {
+				entry.FileName, entry.File = data.Name, data.Bytes
+			}
+		}
+		return entry
+	}
+
+	if b.spool != nil && b.spool.active() {
+		if err := b.spool.add(entry()); err != nil {
+			log.Printf("❌ Failed to queue %s to %s: %v", method, chat, err)
+			return nil, err
+		}
+		return nil, errSpooled
+	}
+
+	resp, err := b.limited(chat, func() (*tgbotapi.APIResponse, error) {
+		return request(b.api, method, params, file)
+	})
+	if b.spool == nil || !unreachable(err) {
+		b.recordSend(err)
+		return resp, err
+	}
+	if spoolErr := b.spool.add(entry()); spoolErr != nil {
+		log.Printf("❌ Failed to queue %s to %s: %v", method, chat, spoolErr)
+		b.recordSend(err)
+		return resp, err
+	}
+	log.Printf("📥 Telegram unreachable (%v) - queued %s to %s, delivering when it is back", err, method, chat)
+	return nil, errSpooled
+}
+
+// request makes a plain or an upload API request
+func request(api *tgbotapi.BotAPI, method string, params tgbotapi.Params, file *tgbotapi.RequestFile) (*tgbotapi.APIResponse, error) {
+	if file == nil {
+		return api.MakeRequest(method, params)
+	}
+	return api.UploadFiles(method, params, []tgbotapi.RequestFile{*file})
+}
+
+// RunSpool delivers the queued sends in order whenever Telegram is reachable
+// again, until ctx is done (only start it with telegram_spool configured)
+func (b *Bot) RunSpool(ctx context.Context) {
+	if b.spool == nil {
+		<-ctx.Done()
+		return
+	}
+	ticker := time.NewTicker(spoolRetry)
+	defer ticker.Stop()
+	for {
+		if b.spool.active() {
+			b.deliverSpool(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverSpool sends the queued entries oldest first, until the spool is empty
+// or Telegram is still unreachable; entries past the age cutoff are dropped
+func (b *Bot) deliverSpool(ctx context.Context) {
+	delivered, failed, stale := 0, 0, 0
+	defer func() {
+		if delivered+failed+stale > 0 {
+			log.Printf("📤 Spool: %d queued send(s) delivered, %d failed, %d dropped as stale (older than %v)",
+				delivered, failed, stale, b.spool.maxAge)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		names, err := b.spool.list()
+		if err != nil {
+			log.Printf("❌ Failed to read the Telegram spool: %v", err)
+			return
+		}
+		if len(names) == 0 {
+			return
+		}
+		for _, name := range names {
+			if ctx.Err() != nil {
+				return
+			}
+			entry, err := b.spool.load(name)
+			if err != nil {
+				log.Printf("⚠️  Dropping unreadable spooled send %s: %v", name, err)
+				if !b.spool.remove(name) {
+					return
+				}
+				continue
+			}
+			if time.Since(entry.Queued) > b.spool.maxAge {
+				if !b.spool.remove(name) {
+					return
+				}
+				stale++
+				continue
+			}
+
+			var file *tgbotapi.RequestFile
+			if entry.File != nil {
+				field := strings.TrimPrefix(strings.ToLower(entry.Method), "send")
+				file = &tgbotapi.RequestFile{Name: field, Data: tgbotapi.FileBytes{Name: entry.FileName, Bytes: entry.File}}
+			}
+			b.markDelayed(&entry)
+			_, err = b.limited(entry.Chat, func() (*tgbotapi.APIResponse, error) {
+				return request(b.api, entry.Method, entry.Params, file)
+			})
+			if unreachable(err) {
+				log.Printf("📥 Telegram still unreachable (%v), %d send(s) stay queued", err, len(names))
+				return
+			}
+			b.recordSend(err)
+			if err != nil {
+				log.Printf("❌ Dropping queued %s to %s: %v", entry.Method, entry.Chat, err)
+				failed++
+			} else {
+				delivered++
+			}
+			if !b.spool.remove(name) {
+				return // Delivering it again would duplicate the post
+			}
+		}
+	}
+}
+
+// markDelayed notes the queue time on a late message or caption, so readers
+// do not take a status from an outage for the current one
+func (b *Bot) markDelayed(entry *spoolEntry) {
+	note := fmt.Sprintf("⏳ _Delayed: queued %s while Telegram was unreachable_\n\n", entry.Queued.In(b.location).Format("Jan 2 15:04 MST"))
+	switch {
+	case entry.Params["text"] != "":
+		if len(entry.Params["text"])+len(note) <= maxMessageLength {
+			entry.Params["text"] = note + entry.Params["text"]
+		}
+	case entry.Params["caption"] != "":
+		if utf8.RuneCountInString(entry.Params["caption"]+note) <= maxCaptionLength {
+			entry.Params["caption"] = note + entry.Params["caption"]
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/netblocks/netblocks/internal/config"
+	"github.com/netblocks/netblocks/internal/version"
+)
+
+// SendStartupMessage posts the startup diagnostics to every configured channel,
+// or privately to the admins (see config startup_message)
+func (b *Bot) SendStartupMessage(ctx context.Context) {
+	switch b.config.StartupMessage {
+	case config.StartupOff:
+		log.Println("🔕 Startup message disabled")
+	case config.StartupAdmins:
+		if len(b.config.TelegramAdmins) == 0 {
+			log.Println("⚠️  startup_message is \"admins\" but no telegram_admins are configured")
+			return
+		}
+		var schedules []string
+		for _, ch := range b.channels {
+			schedules = append(schedules, fmt.Sprintf("📢 `%s`: %s", ch.id, b.channelSchedule(ch)))
+		}
+		text := b.formatStartup(strings.Join(schedules, "\n"))
+		for _, adminID := range b.config.TelegramAdmins {
+			log.Printf("📤 Sending startup message to admin %d", adminID)
+			if _, err := b.sendText(adminID, 0, text); err != nil {
+				log.Printf("Error sending startup message to admin %d: %v", adminID, err)
+			}
+		}
+	default:
+		for _, ch := range b.channels {
+			log.Printf("📤 Sending startup message to channel: %s", ch.id)
+			b.sendMessage(ch.id, b.formatStartup(b.channelSchedule(ch)))
+		}
+	}
+}
+
+// channelSchedule describes when a channel receives posts
+func (b *Bot) channelSchedule(ch *channelTarget) string {
+	if ch.profile == profileAlerts {
+		return "🔔 Alerts will be posted as network changes are detected"
+	}
+	return fmt.Sprintf("⏰ Updates will be sent %s", ch.describeSchedule())
+}
+
+// formatStartup formats the startup diagnostics: build, targets, enabled
+// subsystems and the outcome of the initial data source checks
+func (b *Bot) formatStartup(schedule string) string {
+	cfg := b.config
+	var builder strings.Builder
+	builder.WriteString("🚀 *NetBlocks Bot Started*\n\n")
+	builder.WriteString(fmt.Sprintf("✅ Bot is now monitoring networks in %s\n", cfg.CountryName))
+	builder.WriteString(fmt.Sprintf("🏷 Version: `%s`\n", version.String()))
+
+	builder.WriteString("\n*Targets:*\n")
+	builder.WriteString(fmt.Sprintf("📊 %d ASNs", len(cfg.IranASNs)))
+	if len(cfg.ReferenceASNs) > 0 {
+		builder.WriteString(fmt.Sprintf(" (+%d external reference)", len(cfg.ReferenceASNs)))
+	}
+	builder.WriteString(fmt.Sprintf(" and %d DNS servers\n", len(cfg.DNSServers)))
+	if cfg.ASNListURL != "" || cfg.DNSListURL != "" {
+		builder.WriteString("🔄 Targets are refreshed from remote lists\n")
+	}
+	if cfg.ASNDiscovery != nil {
+		builder.WriteString("🔄 ASNs are discovered on RIPEstat\n")
+	}
+	if len(cfg.Countries) > 0 {
+		builder.WriteString(fmt.Sprintf("🌍 %d further countries\n", len(cfg.Countries)))
+	}
+
+	if subsystems := enabledSubsystems(cfg); len(subsystems) > 0 {
+		builder.WriteString("\n*Subsystems:*\n")
+		builder.WriteString(strings.Join(subsystems, ", "))
+		builder.WriteString("\n")
+	}
+
+	if len(b.readiness) > 0 {
+		builder.WriteString("\n*Data sources:*")
+		for _, step := range b.readiness {
+			mark := "✅"
+			if !step.Ready {
+				mark = "⏳"
+			}
+			// Details can hold error text, so they go in code spans to keep the Markdown valid
+			detail := strings.ReplaceAll(step.Detail, "`", "'")
+			builder.WriteString(fmt.Sprintf("\n%s %s: `%s` (%s)", mark, step.Name, detail, step.Duration.Round(100*time.Millisecond)))
+		}
+		builder.WriteString("\n")
+	}
+
+	if schedule != "" {
+		builder.WriteString("\n" + schedule + "\n")
+	}
+	builder.WriteString(fmt.Sprintf("\nBot started at: `%s`", time.Now().Format("2006-01-02 15:04:05")))
+	return builder.String()
+}
+
+// enabledSubsystems names the optional subsystems the config turns on
+func enabledSubsystems(cfg *config.Config) []string {
+	var subsystems []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			subsystems = append(subsystems, name)
+		}
+	}
+	switch {
+	case cfg.HistoryDSN != "":
+		add(true, "history (PostgreSQL)")
+	case cfg.HistoryPath != "":
+		add(true, "history (file)")
+	}
+	add(cfg.ServerAddr != "", "dashboard")
+	add(len(cfg.AggregatorProbes) > 0, fmt.Sprintf("aggregator (%d probes)", len(cfg.AggregatorProbes)))
+	add(cfg.AggregatorURL != "", "probe")
+	add(cfg.RedisAddr != "", "shared state (Redis)")
+	add(cfg.MirrorURL != "", "mirror")
+	add(cfg.SigningKeyPath != "", "signed evidence")
+	add(len(cfg.Rules) > 0, fmt.Sprintf("alert rules (%d)", len(cfg.Rules)))
+	add(cfg.Correlation != nil, "correlation")
+	add(len(cfg.EscalationPolicies) > 0, "escalation")
+	add(cfg.Matrix != nil, "Matrix")
+	add(cfg.Signal != nil, "Signal")
+	add(cfg.SMS != nil, "SMS")
+	add(cfg.SMTP != nil, "email")
+	add(cfg.PagerDuty != nil, "PagerDuty")
+	add(cfg.Opsgenie != nil, "Opsgenie")
+	add(len(cfg.Webhooks)+len(cfg.AlertWebhooks) > 0, "webhooks")
+	add(len(cfg.Hooks) > 0, fmt.Sprintf("hooks (%d)", len(cfg.Hooks)))
+	add(len(cfg.CheckerPlugins) > 0, fmt.Sprintf("checker plugins (%d)", len(cfg.CheckerPlugins)))
+	return subsystems
+}
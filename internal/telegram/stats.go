@@ -0,0 +1,195 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/netblocks/netblocks/internal/models"
+	"github.com/netblocks/netblocks/internal/monitor"
+)
+
+// botStats counts bot activity for /botstats
+type botStats struct {
+	startedAt time.Time
+	sent      atomic.Int64 // Messages and photos delivered
+	failed    atomic.Int64 // Messages and photos the API rejected
+
+	apiMu sync.Mutex
+	api   models.TelegramAPI // How the Bot API calls went, a measurement of Telegram from this host
+}
+
+// recordCall records whether a Bot API call got an answer and its round trip;
+// API errors are answers too, only network failures make Telegram unreachable
+func (s *botStats) recordCall(latency time.Duration, err error) {
+	s.apiMu.Lock()
+	defer s.apiMu.Unlock()
+	now := time.Now()
+	s.api.Calls++
+	s.api.LastCall = now
+	if err != nil && unreachable(err) {
+		s.api.Reachable = false
+		s.api.Unanswered++
+		s.api.LastError = err.Error()
+		return
+	}
+	s.api.Reachable = true
+	s.api.Latency = latency
+	s.api.LastAnswer = now
+}
+
+// TelegramAPI returns how the bot's Bot API calls went (nil before the first call)
+func (b *Bot) TelegramAPI() *models.TelegramAPI {
+	b.stats.apiMu.Lock()
+	defer b.stats.apiMu.Unlock()
+	if b.stats.api.Calls == 0 {
+		return nil
+	}
+	api := b.stats.api
+	return &api
+}
+
+// recordSend counts the outcome of a single API send
+func (b *Bot) recordSend(err error) {
+	if err != nil {
+		b.stats.failed.Add(1)
+	} else {
+		b.stats.sent.Add(1)
+	}
+}
+
+// SetStatsProvider sets the function used to read monitor counters for /botstats
+func (b *Bot) SetStatsProvider(provider func() monitor.Stats) {
+	b.statsProvider = provider
+}
+
+// isAdmin reports whether a Telegram user ID is listed in telegram_admins
+func (b *Bot) isAdmin(userID int64) bool {
+	for _, id := range b.config.TelegramAdmins {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// sendBotStats replies with bot health: uptime, send counters, data source status and subscribers
+func (b *Bot) sendBotStats(chatID int64, userID int64) {
+	if !b.isAdmin(userID) {
+		log.Printf("⚠️  /botstats denied for user %d", userID)
+		b.sendMessage(chatID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	b.sendMessage(chatID, b.formatBotStats())
+}
+
+// formatBotStats formats the /botstats report
+func (b *Bot) formatBotStats() string {
+	var builder strings.Builder
+
+	builder.WriteString("🩺 *Bot Health*\n")
+	builder.WriteString("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
+	builder.WriteString(fmt.Sprintf("⏱ Uptime: `%s`\n", time.Since(b.stats.startedAt).Truncate(time.Second)))
+	builder.WriteString(fmt.Sprintf("📤 Messages sent: `%d`\n", b.stats.sent.Load()))
+	builder.WriteString(fmt.Sprintf("❌ Messages failed: `%d`\n", b.stats.failed.Load()))
+	builder.WriteString(fmt.Sprintf("👥 Subscribers: `%d`\n", len(b.getSubscribedChats())))
+	builder.WriteString(fmt.Sprintf("📢 Channels: `%d`\n", len(b.channels)))
+
+	if b.statsProvider != nil {
+		stats := b.statsProvider()
+		lastFetch := "never"
+		if !stats.LastCloudflareFetch.IsZero() {
+			lastFetch = fmt.Sprintf("%s (%s ago)", stats.LastCloudflareFetch.Format("2006-01-02 15:04:05"),
+				time.Since(stats.LastCloudflareFetch).Truncate(time.Second))
+		}
+		builder.WriteString("\n📡 *Data Sources*\n")
+		builder.WriteString(fmt.Sprintf("☁️ Last Cloudflare fetch: `%s`\n", lastFetch))
+		radar := stats.Radar
+		budget := ""
+		if radar.DailyBudget > 0 {
+			budget = fmt.Sprintf(" of %d", radar.DailyBudget)
+		}
+		builder.WriteString(fmt.Sprintf("🔋 Radar API calls today: `%d%s` (`%d` rate limited)\n", radar.CallsToday, budget, radar.RateLimited))
+		if radar.Limit > 0 {
+			builder.WriteString(fmt.Sprintf("⏳ Radar rate limit: `%d/%d` left\n", radar.Remaining, radar.Limit))
+		}
+		if stats.RadarTokens > 1 {
+			builder.WriteString(fmt.Sprintf("🔑 Cloudflare tokens usable: `%d/%d`\n", stats.RadarTokensUsable, stats.RadarTokens))
+		}
+		if radar.Degraded {
+			builder.WriteString("🪫 Radar budget low: serving cached traffic longer\n")
+		}
+		builder.WriteString(fmt.Sprintf("🔌 RIS Live reconnects: `%d`\n", stats.RISReconnects))
+		if api := b.TelegramAPI(); api != nil {
+			builder.WriteString(fmt.Sprintf("✈️ Telegram API: `%d` calls, `%d` unanswered, last answer in `%s`\n", api.Calls, api.Unanswered, api.Latency.Round(time.Millisecond)))
+		}
+		builder.WriteString(fmt.Sprintf("🖼 Charts rendered: `%d` (`%d` shared, `%d` refused while busy)\n", stats.Renders.Rendered, stats.Renders.Shared, stats.Renders.Rejected))
+		builder.WriteString(fmt.Sprintf("🔍 DNS cycle duration: `%s`\n", stats.DNSCycleDuration.Truncate(time.Millisecond)))
+
+		cycles := stats.Cycles
+		builder.WriteString("\n🔄 *Monitor Cycles*\n")
+		builder.WriteString(fmt.Sprintf("✅ Completed: `%d` (`%d` with failures)\n", cycles.Count, cycles.Failed))
+		if last := cycles.Last; last != nil {
+			builder.WriteString(fmt.Sprintf("⏲ Duration: last `%s`, avg `%s`, max `%s`\n", last.Duration.Truncate(time.Millisecond),
+				cycles.AvgDuration().Truncate(time.Millisecond), cycles.MaxDuration.Truncate(time.Millisecond)))
+			builder.WriteString(fmt.Sprintf("🕒 Last cycle: `%s` - %d change(s)\n", last.Start.Format("15:04:05"), last.Events))
+			if len(last.Failures) > 0 {
+				builder.WriteString(fmt.Sprintf("⚠️ Failed: `%s`\n", strings.Join(last.Failures, ", ")))
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+// senderID returns the Telegram user ID of a message's sender (0 for anonymous/channel posts)
+func senderID(msg *tgbotapi.Message) int64 {
+	if msg.From == nil {
+		return 0
+	}
+	return msg.From.ID
+}
+
+// handleBroadcast sends an operator announcement to all subscribers and channels
+// Delivery runs in the background through the rate-limited send path so the
+// update loop is not blocked; the admin gets a summary when it finishes
+func (b *Bot) handleBroadcast(chatID int64, userID int64, announcement string) {
+	if !b.isAdmin(userID) {
+		log.Printf("⚠️  /broadcast denied for user %d", userID)
+		b.sendMessage(chatID, "❌ This command is only available to bot administrators.")
+		return
+	}
+	if announcement == "" {
+		b.sendMessage(chatID, "Usage: /broadcast <text>\nExample: /broadcast Expect intermittent data while we migrate servers")
+		return
+	}
+
+	text := "📣 *Announcement*\n\n" + announcement
+	subscribers := b.getSubscribedChats()
+	log.Printf("📣 Broadcasting announcement from %d to %d subscriber(s) and %d channel(s)", userID, len(subscribers), len(b.channels))
+
+	go func() {
+		delivered, failed := 0, 0
+		count := func(err error) {
+			if err != nil {
+				failed++
+			} else {
+				delivered++
+			}
+		}
+		for _, id := range subscribers {
+			_, err := b.sendText(id, 0, text)
+			count(err)
+		}
+		for _, ch := range b.channels {
+			_, err := b.sendText(ch.id, b.topicFor(ch.id, sectionHeader), text)
+			count(err)
+		}
+		log.Printf("📣 Broadcast finished: %d delivered, %d failed", delivered, failed)
+		b.sendMessage(chatID, fmt.Sprintf("📣 Broadcast finished: %d delivered, %d failed", delivered, failed))
+	}()
+}
@@ -0,0 +1,85 @@
+// Package version reports which build of netblocks is running, so operators
+// of several instances can tell which code produced which data
+//
+// Release builds set the variables with ldflags (see the Makefile):
+//
+//	go build -ldflags "-X github.com/netblocks/netblocks/internal/version.Version=v1.4.0 \
+//		-X github.com/netblocks/netblocks/internal/version.Commit=$(git rev-parse HEAD) \
+//		-X github.com/netblocks/netblocks/internal/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Plain `go build` in a git checkout still records the commit and its time,
+// which are used when the variables are not set
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time with -ldflags "-X ..."
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`     // Build date, or the commit time of builds without ldflags
+	Modified  bool   `json:"modified,omitempty"` // Built from a checkout with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info, filling in what ldflags left unset from the
+// VCS information Go embeds
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// String formats the build info as "v1.4.0 (3f2c1a9, 2024-05-01T10:00:00Z)"
+func (i Info) String() string {
+	var details []string
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		if i.Modified {
+			commit += "-dirty"
+		}
+		details = append(details, commit)
+	}
+	if i.Date != "" {
+		details = append(details, i.Date)
+	}
+	if len(details) == 0 {
+		return i.Version
+	}
+	return fmt.Sprintf("%s (%s)", i.Version, strings.Join(details, ", "))
+}
+
+// String returns the formatted info of the running build
+func String() string {
+	return Get().String()
+}
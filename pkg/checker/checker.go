@@ -0,0 +1,43 @@
+// Package checker is the interface of checker extensions: measurement modules
+// that run every monitoring cycle and report custom outcomes
+//
+// Extensions are plugin executables, started and stopped by netblocks, that
+// serve their checker over gRPC (hashicorp/go-plugin) from main:
+//
+//	func main() {
+//		checker.Serve(func(config map[string]string) (checker.Checker, error) {
+//			return newVPNChecker(config)
+//		})
+//	}
+//
+// They run in a process of their own, so they can be built with any Go
+// version and dependencies; a plugin that crashes or overruns its timeout is
+// killed and started again for the next check
+package checker
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Input is what a checker gets each cycle
+type Input struct {
+	Country string          `json:"country"` // ISO code of the monitored country
+	Result  json.RawMessage `json:"result"`  // The cycle's result, as served by /api/v1/status
+}
+
+// Outcome is one check result reported by a checker
+type Outcome struct {
+	Name   string `json:"name"`
+	Up     bool   `json:"up"`
+	Detail string `json:"detail,omitempty"`
+	Step   string `json:"step,omitempty"` // First step of a multi-step check that failed, e.g. "tls"
+}
+
+// Checker is a measurement module run every cycle
+type Checker interface {
+	// Name identifies the checker; outcomes are reported as <name>/<outcome name>
+	Name() string
+	// Check measures and returns its outcomes; it must return when ctx is done
+	Check(ctx context.Context, input Input) ([]Outcome, error)
+}
@@ -0,0 +1,206 @@
+package checker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Handshake is the handshake of netblocks and its checker plugins; a plugin
+// binary started by hand prints a hint and exits instead of serving
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "NETBLOCKS_CHECKER_PLUGIN",
+	MagicCookieValue: "c1d5e0a4-netblocks-checker",
+}
+
+// PluginName is the name the checker is served and dispensed under
+const PluginName = "checker"
+
+// Factory creates the checker of a plugin from the config of its
+// checker_plugins entry
+type Factory func(config map[string]string) (Checker, error)
+
+// Serve runs a checker plugin over gRPC until netblocks stops it; call it from
+// the plugin's main
+func Serve(factory Factory) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         plugin.PluginSet{PluginName: &GRPCPlugin{Factory: factory}},
+		GRPCServer:      plugin.DefaultGRPCServer,
+	})
+}
+
+// GRPCPlugin carries a Checker over gRPC: plugins serve the checker of
+// Factory, netblocks dispenses a *Client (without a Factory)
+type GRPCPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	Factory Factory
+}
+
+// GRPCServer implements plugin.GRPCPlugin
+func (p *GRPCPlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	if p.Factory == nil {
+		return errors.New("checker plugin has no factory")
+	}
+	s.RegisterService(&serviceDesc, &server{factory: p.Factory})
+	return nil
+}
+
+// GRPCClient implements plugin.GRPCPlugin
+func (p *GRPCPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &Client{conn: conn}, nil
+}
+
+// The service is defined by hand with the well-known wrapper messages; the
+// config, input and outcomes travel as JSON like those of check hooks
+const (
+	serviceName     = "netblocks.checker.v1.Checker"
+	methodConfigure = "/" + serviceName + "/Configure"
+	methodCheck     = "/" + serviceName + "/Check"
+)
+
+// service is what the gRPC server of a plugin implements
+type service interface {
+	configure(ctx context.Context, config *wrapperspb.BytesValue) (*wrapperspb.StringValue, error)
+	check(ctx context.Context, input *wrapperspb.BytesValue) (*wrapperspb.BytesValue, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*service)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Configure", Handler: unaryHandler(methodConfigure, func(s service, ctx context.Context, in *wrapperspb.BytesValue) (interface{}, error) {
+			return s.configure(ctx, in)
+		})},
+		{MethodName: "Check", Handler: unaryHandler(methodCheck, func(s service, ctx context.Context, in *wrapperspb.BytesValue) (interface{}, error) {
+			return s.check(ctx, in)
+		})},
+	},
+	Metadata: "pkg/checker/plugin.go",
+}
+
+// unaryHandler adapts a service method to a gRPC method handler
+func unaryHandler(method string, call func(s service, ctx context.Context, in *wrapperspb.BytesValue) (interface{}, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := new(wrapperspb.BytesValue)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(service), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: method}
+		return interceptor(ctx, in, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(service), ctx, req.(*wrapperspb.BytesValue))
+		})
+	}
+}
+
+// server runs the plugin's checker, created by the first Configure
+type server struct {
+	factory Factory
+	mu      sync.Mutex
+	checker Checker
+}
+
+func (s *server) configure(_ context.Context, in *wrapperspb.BytesValue) (*wrapperspb.StringValue, error) {
+	var config map[string]string
+	if len(in.GetValue()) > 0 {
+		if err := json.Unmarshal(in.GetValue(), &config); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid config: %v", err)
+		}
+	}
+	c, err := s.factory(config)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if c == nil || c.Name() == "" {
+		return nil, status.Error(codes.FailedPrecondition, "checker has no name")
+	}
+	s.mu.Lock()
+	s.checker = c
+	s.mu.Unlock()
+	return wrapperspb.String(c.Name()), nil
+}
+
+func (s *server) check(ctx context.Context, in *wrapperspb.BytesValue) (out *wrapperspb.BytesValue, err error) {
+	s.mu.Lock()
+	c := s.checker
+	s.mu.Unlock()
+	if c == nil {
+		return nil, status.Error(codes.FailedPrecondition, "checker is not configured")
+	}
+	var input Input
+	if err := json.Unmarshal(in.GetValue(), &input); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid input: %v", err)
+	}
+
+	// A panicking check fails instead of ending the plugin
+	defer func() {
+		if r := recover(); r != nil {
+			out, err = nil, status.Errorf(codes.Internal, "check panicked: %v", r)
+		}
+	}()
+	outcomes, err := c.Check(ctx, input)
+	if err != nil {
+		return nil, status.Error(codes.Unknown, err.Error())
+	}
+	data, err := json.Marshal(outcomes)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode outcomes: %v", err)
+	}
+	return wrapperspb.Bytes(data), nil
+}
+
+// Client calls the checker of a plugin process
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+// Configure creates the plugin's checker from config and returns its name
+func (c *Client) Configure(ctx context.Context, config map[string]string) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	out := new(wrapperspb.StringValue)
+	if err := c.conn.Invoke(ctx, methodConfigure, wrapperspb.Bytes(data), out); err != nil {
+		return "", remoteError(err)
+	}
+	return out.GetValue(), nil
+}
+
+// Check runs the plugin's check
+func (c *Client) Check(ctx context.Context, input Input) ([]Outcome, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	out := new(wrapperspb.BytesValue)
+	if err := c.conn.Invoke(ctx, methodCheck, wrapperspb.Bytes(data), out); err != nil {
+		return nil, remoteError(err)
+	}
+	var outcomes []Outcome
+	if err := json.Unmarshal(out.GetValue(), &outcomes); err != nil {
+		return nil, fmt.Errorf("invalid outcomes: %w", err)
+	}
+	return outcomes, nil
+}
+
+// remoteError returns the message of an error the plugin returned, and
+// transport errors as they are
+func remoteError(err error) error {
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unavailable && s.Code() != codes.DeadlineExceeded && s.Code() != codes.Canceled {
+		return errors.New(s.Message())
+	}
+	return err
+}